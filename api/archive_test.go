@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestExportBucketPolls(t *testing.T) {
+	poll := archivePoll
+	defer func() { archivePoll = poll }()
+	archivePoll = time.Millisecond
+
+	var (
+		polls  int
+		failed bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := &cmn.ArchiveStatus{Action: cmn.ActExport, State: cmn.ArchiveRunning}
+		if r.Method == http.MethodGet {
+			if r.URL.Query().Get(cmn.URLParamWhat) != cmn.GetWhatArchive {
+				http.Error(w, "unexpected request", http.StatusBadRequest)
+				return
+			}
+			if polls++; polls == 3 {
+				status.State = cmn.ArchiveDone
+				status.Manifest = &cmn.ArchiveManifest{Bucket: "lb", Objects: 5}
+				if failed {
+					status.State, status.Manifest, status.Err = cmn.ArchiveFailed, nil, "t1: failed"
+				}
+			}
+		}
+		b, _ := json.Marshal(status)
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	manifest, err := ExportBucket(http.DefaultClient, srv.URL, "lb", cmn.ArchiveMsg{CloudBucket: "cb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != 3 || manifest == nil || manifest.Objects != 5 {
+		t.Errorf("expected the manifest after 3 polls, got %+v after %d", manifest, polls)
+	}
+
+	polls, failed = 0, true
+	if _, err = ExportBucket(http.DefaultClient, srv.URL, "lb", cmn.ArchiveMsg{CloudBucket: "cb"}); err == nil ||
+		!strings.Contains(err.Error(), "t1: failed") {
+		t.Errorf("expected the export to fail, err: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	polls, archivePoll = -1000, 5*time.Millisecond
+	if _, err = ExportBucketCtx(ctx, http.DefaultClient, srv.URL, "lb", cmn.ArchiveMsg{CloudBucket: "cb"}); err == nil || ctx.Err() == nil {
+		t.Errorf("expected the wait to stop with the context, err: %v", err)
+	}
+}
@@ -6,11 +6,13 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/NVIDIA/dfcpub/cmn"
 )
@@ -20,7 +22,17 @@ import (
 // Set the properties of a bucket, using the bucket name and the bucket properties to be set.
 // Validation of the properties passed in is performed by DFC Proxy.
 func SetBucketProps(httpClient *http.Client, proxyURL, bucket string, props cmn.BucketProps) error {
-	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	return SetBucketPropsCtx(context.Background(), httpClient, proxyURL, bucket, props)
+}
+
+// SetBucketPropsCtx is SetBucketProps with the context for cancellation and deadline
+func SetBucketPropsCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string, props cmn.BucketProps) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).SetBucketProps(ctx, bucket, props)
+}
+
+// SetBucketProps is the method counterpart of SetBucketProps - see NewClient
+func (c *Client) SetBucketProps(ctx context.Context, bucket string, props cmn.BucketProps) error {
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
 	if props.Checksum == "" {
 		props.Checksum = cmn.ChecksumInherit
 	}
@@ -30,7 +42,7 @@ func SetBucketProps(httpClient *http.Client, proxyURL, bucket string, props cmn.
 		return err
 	}
 
-	_, err = doHTTPRequest(httpClient, http.MethodPut, url, b)
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPut, url, b)
 	return err
 }
 
@@ -38,13 +50,23 @@ func SetBucketProps(httpClient *http.Client, proxyURL, bucket string, props cmn.
 //
 // Reset the properties of a bucket, identified by its name, to the global configuration.
 func ResetBucketProps(httpClient *http.Client, proxyURL, bucket string) error {
-	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	return ResetBucketPropsCtx(context.Background(), httpClient, proxyURL, bucket)
+}
+
+// ResetBucketPropsCtx is ResetBucketProps with the context for cancellation and deadline
+func ResetBucketPropsCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).ResetBucketProps(ctx, bucket)
+}
+
+// ResetBucketProps is the method counterpart of ResetBucketProps - see NewClient
+func (c *Client) ResetBucketProps(ctx context.Context, bucket string) error {
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
 	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActResetProps})
 	if err != nil {
 		return err
 	}
 
-	_, err = doHTTPRequest(httpClient, http.MethodPut, url, b)
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPut, url, b)
 	return err
 }
 
@@ -54,7 +76,17 @@ func ResetBucketProps(httpClient *http.Client, proxyURL, bucket string) error {
 // Converts the string type fields returned from the HEAD request to their
 // corresponding counterparts in the BucketProps struct
 func HeadBucket(httpClient *http.Client, proxyURL, bucket string) (*cmn.BucketProps, error) {
-	r, err := httpClient.Head(proxyURL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket))
+	return HeadBucketCtx(context.Background(), httpClient, proxyURL, bucket)
+}
+
+// HeadBucketCtx is HeadBucket with the context for cancellation and deadline
+func HeadBucketCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string) (*cmn.BucketProps, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).HeadBucket(ctx, bucket)
+}
+
+// HeadBucket is the method counterpart of HeadBucket - see NewClient
+func (c *Client) HeadBucket(ctx context.Context, bucket string) (*cmn.BucketProps, error) {
+	r, err := doHead(ctx, c.HTTPClient, c.URL+cmn.URLPath(cmn.Version, cmn.Buckets, bucket))
 	if err != nil {
 		return nil, err
 	}
@@ -104,14 +136,41 @@ func HeadBucket(httpClient *http.Client, proxyURL, bucket string) (*cmn.BucketPr
 		lruprops.LRUEnabled = b
 	}
 
+	publicRead, _ := strconv.ParseBool(r.Header.Get(cmn.HeaderBucketPublicRead))
+	debugSample, _ := strconv.ParseFloat(r.Header.Get(cmn.HeaderBucketDebugSample), 64)
+	passthroughSize, _ := strconv.ParseInt(r.Header.Get(cmn.HeaderBucketPassthroughSize), 10, 64)
+	var pinned *cmn.Pinned
+	if s := r.Header.Get(cmn.HeaderBucketPinned); s != "" {
+		pinned = &cmn.Pinned{}
+		if err := json.Unmarshal([]byte(s), pinned); err != nil {
+			return nil, fmt.Errorf("Failed to parse %s %q, err: %v", cmn.HeaderBucketPinned, s, err)
+		}
+	}
+	var prefetchBudget *cmn.PrefetchBudget
+	if s := r.Header.Get(cmn.HeaderBucketPrefetchBudget); s != "" {
+		prefetchBudget = &cmn.PrefetchBudget{}
+		if err := json.Unmarshal([]byte(s), prefetchBudget); err != nil {
+			return nil, fmt.Errorf("Failed to parse %s %q, err: %v", cmn.HeaderBucketPrefetchBudget, s, err)
+		}
+	}
+
 	return &cmn.BucketProps{
-		CloudProvider: r.Header.Get(cmn.HeaderCloudProvider),
-		Versioning:    r.Header.Get(cmn.HeaderVersioning),
-		NextTierURL:   r.Header.Get(cmn.HeaderNextTierURL),
-		ReadPolicy:    r.Header.Get(cmn.HeaderReadPolicy),
-		WritePolicy:   r.Header.Get(cmn.HeaderWritePolicy),
-		CksumConf:   cksumconf,
-		LRUConf:     lruprops,
+		CloudProvider:   r.Header.Get(cmn.HeaderCloudProvider),
+		Versioning:      r.Header.Get(cmn.HeaderVersioning),
+		NextTierURL:     r.Header.Get(cmn.HeaderNextTierURL),
+		ReadPolicy:      r.Header.Get(cmn.HeaderReadPolicy),
+		WritePolicy:     r.Header.Get(cmn.HeaderWritePolicy),
+		CksumConf:       cksumconf,
+		LRUConf:         lruprops,
+		ListProps:       r.Header.Get(cmn.HeaderBucketListProps),
+		ListTimeFormat:  r.Header.Get(cmn.HeaderBucketListTimeFormat),
+		PublicRead:      publicRead,
+		SyncPolicy:      r.Header.Get(cmn.HeaderBucketSyncPolicy),
+		DebugSample:     debugSample,
+		AtimePolicy:     r.Header.Get(cmn.HeaderBucketAtimePolicy),
+		PassthroughSize: passthroughSize,
+		PrefetchBudget:  prefetchBudget,
+		Pinned:          pinned,
 	}, nil
 }
 
@@ -120,10 +179,20 @@ func HeadBucket(httpClient *http.Client, proxyURL, bucket string) (*cmn.BucketPr
 // If localOnly is false, returns two lists, one for local buckets and one for cloud buckets.
 // Otherwise, i.e. localOnly is true, still returns two lists, but the one for cloud buckets is empty
 func GetBucketNames(httpClient *http.Client, proxyURL string, localOnly bool) (*cmn.BucketNames, error) {
+	return GetBucketNamesCtx(context.Background(), httpClient, proxyURL, localOnly)
+}
+
+// GetBucketNamesCtx is GetBucketNames with the context for cancellation and deadline
+func GetBucketNamesCtx(ctx context.Context, httpClient *http.Client, proxyURL string, localOnly bool) (*cmn.BucketNames, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetBucketNames(ctx, localOnly)
+}
+
+// GetBucketNames is the method counterpart of GetBucketNames - see NewClient
+func (c *Client) GetBucketNames(ctx context.Context, localOnly bool) (*cmn.BucketNames, error) {
 	var bucketNames cmn.BucketNames
-	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Buckets, "*") +
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, "*") +
 		fmt.Sprintf("?%s=%t", cmn.URLParamLocal, localOnly)
-	b, err := doHTTPRequest(httpClient, http.MethodGet, url, nil)
+	b, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -133,7 +202,7 @@ func GetBucketNames(httpClient *http.Client, proxyURL string, localOnly bool) (*
 			return nil, fmt.Errorf("Failed to unmarshal bucket names, err: %v - [%s]", err, string(b))
 		}
 	} else {
-		return nil, fmt.Errorf("Empty response instead of empty bucket list from %s", proxyURL)
+		return nil, fmt.Errorf("Empty response instead of empty bucket list from %s", c.URL)
 	}
 	return &bucketNames, nil
 }
@@ -142,12 +211,22 @@ func GetBucketNames(httpClient *http.Client, proxyURL string, localOnly bool) (*
 //
 // CreateLocalBucket sends a HTTP request to a proxy to create a local bucket with the given name
 func CreateLocalBucket(httpClient *http.Client, proxyURL, bucket string) error {
+	return CreateLocalBucketCtx(context.Background(), httpClient, proxyURL, bucket)
+}
+
+// CreateLocalBucketCtx is CreateLocalBucket with the context for cancellation and deadline
+func CreateLocalBucketCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).CreateLocalBucket(ctx, bucket)
+}
+
+// CreateLocalBucket is the method counterpart of CreateLocalBucket - see NewClient
+func (c *Client) CreateLocalBucket(ctx context.Context, bucket string) error {
 	msg, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActCreateLB})
 	if err != nil {
 		return err
 	}
-	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
-	_, err = doHTTPRequest(httpClient, http.MethodPost, url, msg)
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, msg)
 	return err
 }
 
@@ -155,13 +234,23 @@ func CreateLocalBucket(httpClient *http.Client, proxyURL, bucket string) error {
 //
 // DestroyLocalBucket sends a HTTP request to a proxy to remove a local bucket with the given name
 func DestroyLocalBucket(httpClient *http.Client, proxyURL, bucket string) error {
+	return DestroyLocalBucketCtx(context.Background(), httpClient, proxyURL, bucket)
+}
+
+// DestroyLocalBucketCtx is DestroyLocalBucket with the context for cancellation and deadline
+func DestroyLocalBucketCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).DestroyLocalBucket(ctx, bucket)
+}
+
+// DestroyLocalBucket is the method counterpart of DestroyLocalBucket - see NewClient
+func (c *Client) DestroyLocalBucket(ctx context.Context, bucket string) error {
 	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActDestroyLB})
 	if err != nil {
 		return err
 	}
 
-	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
-	_, err = doHTTPRequest(httpClient, http.MethodDelete, url, b)
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodDelete, url, b)
 	return err
 }
 
@@ -169,11 +258,428 @@ func DestroyLocalBucket(httpClient *http.Client, proxyURL, bucket string) error
 //
 // RenameLocalBucket changes the name of a bucket from oldBucketName to newBucketName
 func RenameLocalBucket(httpClient *http.Client, proxyURL, oldBucketName, newBucketName string) error {
+	return RenameLocalBucketCtx(context.Background(), httpClient, proxyURL, oldBucketName, newBucketName)
+}
+
+// RenameLocalBucketCtx is RenameLocalBucket with the context for cancellation and deadline
+func RenameLocalBucketCtx(ctx context.Context, httpClient *http.Client, proxyURL, oldBucketName, newBucketName string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).RenameLocalBucket(ctx, oldBucketName, newBucketName)
+}
+
+// RenameLocalBucket is the method counterpart of RenameLocalBucket - see NewClient
+func (c *Client) RenameLocalBucket(ctx context.Context, oldBucketName, newBucketName string) error {
 	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActRenameLB, Name: newBucketName})
 	if err != nil {
 		return err
 	}
-	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Buckets, oldBucketName)
-	_, err = doHTTPRequest(httpClient, http.MethodPost, url, b)
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, oldBucketName)
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, b)
+	return err
+}
+
+// RenameBucket API operation for DFC
+//
+// RenameBucket atomically renames a local bucket from oldBucketName to newBucketName.
+// Unlike RenameLocalBucket, the targets rename the bucket in place (no copies); if any
+// target fails, the bucket keeps its old name on all targets. The objects are then moved
+// to their new locations by rebalance, and remain accessible in the meantime
+func RenameBucket(httpClient *http.Client, proxyURL, oldBucketName, newBucketName string) error {
+	return RenameBucketCtx(context.Background(), httpClient, proxyURL, oldBucketName, newBucketName)
+}
+
+// RenameBucketCtx is RenameBucket with the context for cancellation and deadline
+func RenameBucketCtx(ctx context.Context, httpClient *http.Client, proxyURL, oldBucketName, newBucketName string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).RenameBucket(ctx, oldBucketName, newBucketName)
+}
+
+// RenameBucket is the method counterpart of RenameBucket - see NewClient
+func (c *Client) RenameBucket(ctx context.Context, oldBucketName, newBucketName string) error {
+	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActRenameBucket, Name: newBucketName})
+	if err != nil {
+		return err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, oldBucketName)
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, b)
+	return err
+}
+
+// PinObjects API operation for DFC
+//
+// Pins the bucket's objects by name and/or by name prefix: LRU and object expiration never
+// evict them. The pinned objects are returned by HeadBucket (BucketProps.Pinned)
+func PinObjects(httpClient *http.Client, proxyURL, bucket string, pinned cmn.Pinned) error {
+	return PinObjectsCtx(context.Background(), httpClient, proxyURL, bucket, pinned)
+}
+
+// PinObjectsCtx is PinObjects with the context for cancellation and deadline
+func PinObjectsCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string, pinned cmn.Pinned) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).PinObjects(ctx, bucket, pinned)
+}
+
+// PinObjects is the method counterpart of PinObjects - see NewClient
+func (c *Client) PinObjects(ctx context.Context, bucket string, pinned cmn.Pinned) error {
+	return pinAction(ctx, c.HTTPClient, c.URL, bucket, cmn.ActPin, pinned)
+}
+
+// UnpinObjects API operation for DFC
+//
+// Unpins the objects and prefixes pinned by PinObjects
+func UnpinObjects(httpClient *http.Client, proxyURL, bucket string, pinned cmn.Pinned) error {
+	return UnpinObjectsCtx(context.Background(), httpClient, proxyURL, bucket, pinned)
+}
+
+// UnpinObjectsCtx is UnpinObjects with the context for cancellation and deadline
+func UnpinObjectsCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string, pinned cmn.Pinned) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).UnpinObjects(ctx, bucket, pinned)
+}
+
+// UnpinObjects is the method counterpart of UnpinObjects - see NewClient
+func (c *Client) UnpinObjects(ctx context.Context, bucket string, pinned cmn.Pinned) error {
+	return pinAction(ctx, c.HTTPClient, c.URL, bucket, cmn.ActUnpin, pinned)
+}
+
+func pinAction(ctx context.Context, httpClient *http.Client, proxyURL, bucket, action string, pinned cmn.Pinned) error {
+	b, err := json.Marshal(cmn.ActionMsg{Action: action, Value: pinned})
+	if err != nil {
+		return err
+	}
+	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	_, err = doHTTPRequest(ctx, httpClient, http.MethodPost, url, b)
+	return err
+}
+
+// CreateBucketSnapshot API operation for DFC
+//
+// Creates a named, read-only snapshot of the local bucket: the bucket's objects as of now,
+// readable with GetSnapshotObject for as long as the snapshot exists, regardless of the
+// subsequent PUTs and DELETEs. The snapshot takes no space other than that of the objects
+// that are overwritten or deleted afterwards.
+func CreateBucketSnapshot(httpClient *http.Client, proxyURL, bucket, snapshot string) error {
+	return CreateBucketSnapshotCtx(context.Background(), httpClient, proxyURL, bucket, snapshot)
+}
+
+// CreateBucketSnapshotCtx is CreateBucketSnapshot with the context for cancellation and deadline
+func CreateBucketSnapshotCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, snapshot string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).CreateBucketSnapshot(ctx, bucket, snapshot)
+}
+
+// CreateBucketSnapshot is the method counterpart of CreateBucketSnapshot - see NewClient
+func (c *Client) CreateBucketSnapshot(ctx context.Context, bucket, snapshot string) error {
+	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActSnapshot, Name: snapshot})
+	if err != nil {
+		return err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, b)
+	return err
+}
+
+// ListBucketSnapshots API operation for DFC
+//
+// Returns the snapshots of the local bucket, sorted by name
+func ListBucketSnapshots(httpClient *http.Client, proxyURL, bucket string) ([]cmn.BucketSnapshot, error) {
+	return ListBucketSnapshotsCtx(context.Background(), httpClient, proxyURL, bucket)
+}
+
+// ListBucketSnapshotsCtx is ListBucketSnapshots with the context for cancellation and deadline
+func ListBucketSnapshotsCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string) ([]cmn.BucketSnapshot, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).ListBucketSnapshots(ctx, bucket)
+}
+
+// ListBucketSnapshots is the method counterpart of ListBucketSnapshots - see NewClient
+func (c *Client) ListBucketSnapshots(ctx context.Context, bucket string) ([]cmn.BucketSnapshot, error) {
+	var snaps []cmn.BucketSnapshot
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Snapshots, bucket)
+	b, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &snaps); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal bucket snapshots, err: %v - [%s]", err, string(b))
+	}
+	return snaps, nil
+}
+
+// DeleteBucketSnapshot API operation for DFC
+//
+// Deletes the snapshot of the local bucket
+func DeleteBucketSnapshot(httpClient *http.Client, proxyURL, bucket, snapshot string) error {
+	return DeleteBucketSnapshotCtx(context.Background(), httpClient, proxyURL, bucket, snapshot)
+}
+
+// DeleteBucketSnapshotCtx is DeleteBucketSnapshot with the context for cancellation and deadline
+func DeleteBucketSnapshotCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, snapshot string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).DeleteBucketSnapshot(ctx, bucket, snapshot)
+}
+
+// DeleteBucketSnapshot is the method counterpart of DeleteBucketSnapshot - see NewClient
+func (c *Client) DeleteBucketSnapshot(ctx context.Context, bucket, snapshot string) error {
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Snapshots, bucket, snapshot)
+	_, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodDelete, url, nil)
+	return err
+}
+
+// GetBucketColdSummary API operation for DFC
+//
+// Returns the number and the total size of the bucket's objects that have not been accessed
+// within the last `days` days, as well as the objects that are currently eligible for LRU eviction.
+// The totals are cluster-wide; a per-target breakdown is included as well.
+// Note that targets cache the computed results for the duration of the capacity_upd_time.
+func GetBucketColdSummary(httpClient *http.Client, proxyURL, bucket string, days int) (*cmn.BucketColdSummary, error) {
+	return GetBucketColdSummaryCtx(context.Background(), httpClient, proxyURL, bucket, days)
+}
+
+// GetBucketColdSummaryCtx is GetBucketColdSummary with the context for cancellation and deadline
+func GetBucketColdSummaryCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string, days int) (*cmn.BucketColdSummary, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetBucketColdSummary(ctx, bucket, days)
+}
+
+// GetBucketColdSummary is the method counterpart of GetBucketColdSummary - see NewClient
+func (c *Client) GetBucketColdSummary(ctx context.Context, bucket string, days int) (*cmn.BucketColdSummary, error) {
+	var summary cmn.BucketColdSummary
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket) +
+		fmt.Sprintf("?%s=%s&%s=%d", cmn.URLParamWhat, cmn.GetWhatColdData, cmn.URLParamDays, days)
+	b, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &summary); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal cold data summary, err: %v - [%s]", err, string(b))
+	}
+	return &summary, nil
+}
+
+// BeginPutGroup API operation for DFC
+//
+// Opens a group of PUTs into the given local bucket and returns the group ID. Objects PUT with
+// PutGroupObject remain invisible until the group is committed with CommitPutGroup. Cloud
+// buckets and the buckets that have a next tier are not supported.
+func BeginPutGroup(httpClient *http.Client, proxyURL, bucket string) (string, error) {
+	return BeginPutGroupCtx(context.Background(), httpClient, proxyURL, bucket)
+}
+
+// BeginPutGroupCtx is BeginPutGroup with the context for cancellation and deadline
+func BeginPutGroupCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string) (string, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).BeginPutGroup(ctx, bucket)
+}
+
+// BeginPutGroup is the method counterpart of BeginPutGroup - see NewClient
+func (c *Client) BeginPutGroup(ctx context.Context, bucket string) (string, error) {
+	var grpmsg cmn.PutGroupMsg
+	msg, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActBeginGroup})
+	if err != nil {
+		return "", err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	b, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, msg)
+	if err != nil {
+		return "", err
+	}
+	if err = json.Unmarshal(b, &grpmsg); err != nil {
+		return "", fmt.Errorf("Failed to unmarshal PUT group, err: %v - [%s]", err, string(b))
+	}
+	return grpmsg.GroupID, nil
+}
+
+// CommitPutGroup API operation for DFC
+//
+// Makes visible all the objects PUT within the group. objnames must list all of them: if any
+// object is missing in the cluster, the entire group is aborted and an error is returned.
+// If any target fails to commit, the group is rolled back - the overwritten objects get
+// their prior versions back - and the returned error is *cmn.PutGroupCommit that lists the
+// objects that failed to roll back and remain committed, if any.
+func CommitPutGroup(httpClient *http.Client, proxyURL, bucket, groupID string, objnames []string) error {
+	return CommitPutGroupCtx(context.Background(), httpClient, proxyURL, bucket, groupID, objnames)
+}
+
+// CommitPutGroupCtx is CommitPutGroup with the context for cancellation and deadline
+func CommitPutGroupCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, groupID string, objnames []string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).CommitPutGroup(ctx, bucket, groupID, objnames)
+}
+
+// CommitPutGroup is the method counterpart of CommitPutGroup - see NewClient
+func (c *Client) CommitPutGroup(ctx context.Context, bucket, groupID string, objnames []string) error {
+	msg, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActCommitGroup, Value: cmn.PutGroupMsg{GroupID: groupID, Objnames: objnames}})
+	if err != nil {
+		return err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	b, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, msg)
+	if err != nil {
+		return err
+	}
+	result := &cmn.PutGroupCommit{}
+	if err = json.Unmarshal(b, result); err != nil {
+		return fmt.Errorf("Failed to unmarshal PUT group commit, err: %v - [%s]", err, string(b))
+	}
+	if len(result.Errors) != 0 {
+		return result
+	}
+	return nil
+}
+
+// AbortPutGroup API operation for DFC
+//
+// Discards all the objects PUT within the group
+func AbortPutGroup(httpClient *http.Client, proxyURL, bucket, groupID string) error {
+	return AbortPutGroupCtx(context.Background(), httpClient, proxyURL, bucket, groupID)
+}
+
+// AbortPutGroupCtx is AbortPutGroup with the context for cancellation and deadline
+func AbortPutGroupCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, groupID string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).AbortPutGroup(ctx, bucket, groupID)
+}
+
+// AbortPutGroup is the method counterpart of AbortPutGroup - see NewClient
+func (c *Client) AbortPutGroup(ctx context.Context, bucket, groupID string) error {
+	msg, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActAbortGroup, Value: cmn.PutGroupMsg{GroupID: groupID}})
+	if err != nil {
+		return err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, msg)
 	return err
 }
+
+// GetChecksums API operation for DFC
+//
+// Returns the checksums and sizes of the bucket's objects - either listed by name or, if
+// objnames is empty, selected by the name prefix - without reading the objects. Objects that
+// are not stored in the cluster (e.g., Cloud objects that are not cached) are omitted.
+func GetChecksums(httpClient *http.Client, proxyURL, bucket string, objnames []string, prefix string) (cmn.ObjChecksums, error) {
+	return GetChecksumsCtx(context.Background(), httpClient, proxyURL, bucket, objnames, prefix)
+}
+
+// GetChecksumsCtx is GetChecksums with the context for cancellation and deadline
+func GetChecksumsCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string, objnames []string, prefix string) (cmn.ObjChecksums, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetChecksums(ctx, bucket, objnames, prefix)
+}
+
+// GetChecksums is the method counterpart of GetChecksums - see NewClient
+func (c *Client) GetChecksums(ctx context.Context,
+	bucket string, objnames []string, prefix string) (cmn.ObjChecksums, error) {
+	msg, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActChecksums, Value: cmn.ChecksumsMsg{Objnames: objnames, Prefix: prefix}})
+	if err != nil {
+		return nil, err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	b, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, msg)
+	if err != nil {
+		return nil, err
+	}
+	cksums := make(cmn.ObjChecksums)
+	if err = json.Unmarshal(b, &cksums); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal checksums, err: %v - [%s]", err, string(b))
+	}
+	return cksums, nil
+}
+
+// archivePoll is how often ExportBucket and ImportBucket poll the export (import) status
+var archivePoll = time.Second
+
+// ExportBucket API operation for DFC
+//
+// Archives the local bucket as tar shards (of about msg.ShardSize bytes each) in the Cloud
+// bucket msg.CloudBucket under msg.Prefix, along with the manifest that is returned.
+// The export runs in the background: the call starts it and polls GetBucketArchive until
+// all targets are done (or ctx is done - the export keeps running then).
+func ExportBucket(httpClient *http.Client, proxyURL, bucket string, msg cmn.ArchiveMsg) (*cmn.ArchiveManifest, error) {
+	return ExportBucketCtx(context.Background(), httpClient, proxyURL, bucket, msg)
+}
+
+// ExportBucketCtx is ExportBucket with the context for cancellation and deadline
+func ExportBucketCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string, msg cmn.ArchiveMsg) (*cmn.ArchiveManifest, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).ExportBucket(ctx, bucket, msg)
+}
+
+// ExportBucket is the method counterpart of ExportBucket - see NewClient
+func (c *Client) ExportBucket(ctx context.Context, bucket string, msg cmn.ArchiveMsg) (*cmn.ArchiveManifest, error) {
+	status, err := c.archiveBucket(ctx, bucket, cmn.ActExport, msg)
+	if err != nil {
+		return nil, err
+	}
+	return status.Manifest, nil
+}
+
+// ImportBucket API operation for DFC
+//
+// Restores the local bucket from the export found in the Cloud bucket msg.CloudBucket
+// under msg.Prefix; returns the number and the total size of the imported objects.
+// Like ExportBucket, starts the import and polls GetBucketArchive until it is done.
+func ImportBucket(httpClient *http.Client, proxyURL, bucket string, msg cmn.ArchiveMsg) (*cmn.ArchiveResult, error) {
+	return ImportBucketCtx(context.Background(), httpClient, proxyURL, bucket, msg)
+}
+
+// ImportBucketCtx is ImportBucket with the context for cancellation and deadline
+func ImportBucketCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string, msg cmn.ArchiveMsg) (*cmn.ArchiveResult, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).ImportBucket(ctx, bucket, msg)
+}
+
+// ImportBucket is the method counterpart of ImportBucket - see NewClient
+func (c *Client) ImportBucket(ctx context.Context, bucket string, msg cmn.ArchiveMsg) (*cmn.ArchiveResult, error) {
+	status, err := c.archiveBucket(ctx, bucket, cmn.ActImport, msg)
+	if err != nil {
+		return nil, err
+	}
+	return status.Result, nil
+}
+
+// GetBucketArchive API operation for DFC
+//
+// Returns the status of the bucket's last export or import
+func GetBucketArchive(httpClient *http.Client, proxyURL, bucket string) (*cmn.ArchiveStatus, error) {
+	return GetBucketArchiveCtx(context.Background(), httpClient, proxyURL, bucket)
+}
+
+// GetBucketArchiveCtx is GetBucketArchive with the context for cancellation and deadline
+func GetBucketArchiveCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string) (*cmn.ArchiveStatus, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetBucketArchive(ctx, bucket)
+}
+
+// GetBucketArchive is the method counterpart of GetBucketArchive - see NewClient
+func (c *Client) GetBucketArchive(ctx context.Context, bucket string) (*cmn.ArchiveStatus, error) {
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket) + fmt.Sprintf("?%s=%s", cmn.URLParamWhat, cmn.GetWhatArchive)
+	b, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalArchiveStatus(b)
+}
+
+// archiveBucket starts the export (import) and waits for it to finish
+func (c *Client) archiveBucket(ctx context.Context, bucket, action string, msg cmn.ArchiveMsg) (*cmn.ArchiveStatus, error) {
+	msg.Manifest = nil
+	jsbytes, err := json.Marshal(cmn.ActionMsg{Action: action, Value: msg})
+	if err != nil {
+		return nil, err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	b, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, jsbytes)
+	if err != nil {
+		return nil, err
+	}
+	status, err := unmarshalArchiveStatus(b)
+	for err == nil && status.State == cmn.ArchiveRunning {
+		select {
+		case <-time.After(archivePoll):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		status, err = c.GetBucketArchive(ctx, bucket)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status.State != cmn.ArchiveDone {
+		return nil, fmt.Errorf("%s %s: %s", action, bucket, status.Err)
+	}
+	return status, nil
+}
+
+func unmarshalArchiveStatus(b []byte) (*cmn.ArchiveStatus, error) {
+	status := &cmn.ArchiveStatus{}
+	if err := json.Unmarshal(b, status); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal export (import) status, err: %v - [%s]", err, string(b))
+	}
+	return status, nil
+}
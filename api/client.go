@@ -0,0 +1,287 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// TLSArgs are the client-side TLS options used to access a cluster over HTTPS
+type TLSArgs struct {
+	// CA bundle (PEM) to verify the cluster's certificate with; system roots when empty
+	CAFile string
+	// alternatively, a ready-to-use pool of CA certificates (takes precedence over CAFile)
+	CAPool *x509.CertPool
+	// client certificate and key (PEM) - for mutual TLS
+	Certificate string
+	Key         string
+	// do not verify the cluster's certificate - development only
+	SkipVerify bool
+}
+
+// NewTLSConfig returns the TLS configuration built out of the given options
+func NewTLSConfig(args TLSArgs) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: args.SkipVerify}
+	if args.CAPool != nil {
+		config.RootCAs = args.CAPool
+	} else if args.CAFile != "" {
+		pem, err := ioutil.ReadFile(args.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read CA file %s, err: %v", args.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No PEM certificates found in CA file %s", args.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if args.Certificate != "" || args.Key != "" {
+		cert, err := tls.LoadX509KeyPair(args.Certificate, args.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client certificate %s, err: %v", args.Certificate, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// NewHTTPClient returns http.Client to be passed to the api functions. With non-nil
+// TLS options the client accesses the cluster over HTTPS - the cluster's URL must then
+// start with "https://". Zero timeout means no timeout
+func NewHTTPClient(timeout time.Duration, tlsArgs *TLSArgs) (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: 100,
+	}
+	if tlsArgs != nil {
+		config, err := NewTLSConfig(*tlsArgs)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = config
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// Client is the cluster's client configured by NewClient: each api function X(httpClient,
+// proxyURL, ...) has its method counterpart Client.X(ctx, ...), and the functions remain
+// the thin wrappers that they are
+type Client struct {
+	HTTPClient *http.Client
+	URL        string           // primary proxy as of NewClient - see FailoverTransport
+	Version    string           // REST API version - see NewBaseParams
+	Smap       *ClusterMap      // as of NewClient
+	Hints      *cmn.ClientHints // as advertised by the cluster; nil if the cluster predates them
+}
+
+// ClusterMap is the cluster map (Smap) as seen by the clients: the public URLs of the
+// proxies and targets by their daemon IDs
+type ClusterMap struct {
+	Version int64
+	Primary string // URL
+	Proxies map[string]string
+	Targets map[string]string
+}
+
+// ClientOptions are the NewClient options
+type ClientOptions struct {
+	Timeout time.Duration // see NewHTTPClient
+	TLS     *TLSArgs
+}
+
+// NewClient bootstraps the client out of the URL of any of the cluster's proxies. The client
+// discovers the primary proxy and the cluster map and configures itself with the hints that
+// the primary advertises (cmn.ClientHints): fails over between the proxies (see
+// FailoverTransport), retries the idempotent requests that fail with 503 (Service
+// Unavailable), times out connecting as advised, and uses the latest REST API version
+// supported by both the cluster and this package
+func NewClient(clusterURL string, opts ...ClientOptions) (*Client, error) {
+	return NewClientCtx(context.Background(), clusterURL, opts...)
+}
+
+// NewClientCtx is NewClient with the context for cancellation and deadline
+func NewClientCtx(ctx context.Context, clusterURL string, opts ...ClientOptions) (*Client, error) {
+	var opt ClientOptions
+	if len(opts) != 0 {
+		opt = opts[0]
+	}
+	httpClient, err := NewHTTPClient(opt.Timeout, opt.TLS)
+	if err != nil {
+		return nil, err
+	}
+	smap, err := getClusterMap(ctx, httpClient, clusterURL)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{HTTPClient: httpClient, URL: smap.Primary, Version: cmn.Version, Smap: smap}
+	hints, err := getClientHints(ctx, httpClient, c.URL)
+	if err != nil {
+		return nil, err
+	}
+	var proxyURLs []string
+	if hints != nil {
+		c.Hints, proxyURLs = hints, hints.ProxyURLs
+		if d, err := time.ParseDuration(hints.DialTimeout); err == nil && d > 0 {
+			transport := httpClient.Transport.(*http.Transport)
+			transport.DialContext = (&net.Dialer{Timeout: d, KeepAlive: 30 * time.Second}).DialContext
+		}
+	} else {
+		proxyURLs = append(proxyURLs, smap.Primary)
+		for _, u := range smap.Proxies {
+			if u != smap.Primary {
+				proxyURLs = append(proxyURLs, u)
+			}
+		}
+	}
+	if httpClient.Transport, err = NewFailoverTransport(httpClient.Transport, proxyURLs...); err != nil {
+		return nil, err
+	}
+	if hints != nil && hints.MaxRetries > 0 {
+		backoff, _ := time.ParseDuration(hints.RetryBackoff)
+		httpClient.Transport = &retryTransport{base: httpClient.Transport, retries: hints.MaxRetries, backoff: backoff}
+	}
+	if hints == nil {
+		bp, err := NewBaseParamsCtx(ctx, httpClient, c.URL)
+		if err != nil {
+			return nil, err
+		}
+		c.HTTPClient, c.Version = bp.Client, bp.Version
+		return c, nil
+	}
+	for _, v := range hints.APIVersions {
+		if v == cmn.VersionV2 {
+			c.HTTPClient, c.Version = withAPIVersion(httpClient, v), v
+		}
+	}
+	return c, nil
+}
+
+// getClusterMap gets the cluster map from the given proxy - primary or not
+func getClusterMap(ctx context.Context, httpClient *http.Client, proxyURL string) (*ClusterMap, error) {
+	type snode struct {
+		DaemonID  string `json:"daemon_id"`
+		PublicNet struct {
+			DirectURL string `json:"direct_url"`
+		} `json:"public_net"`
+	}
+	var raw struct {
+		Tmap    map[string]*snode `json:"tmap"`
+		Pmap    map[string]*snode `json:"pmap"`
+		ProxySI *snode            `json:"proxy_si"`
+		Version int64             `json:"version"`
+	}
+	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Daemon) + "?" + cmn.URLParamWhat + "=" + cmn.GetWhatSmap
+	b, err := doHTTPRequest(ctx, httpClient, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal cluster map, err: %v", err)
+	}
+	if raw.ProxySI == nil || raw.ProxySI.PublicNet.DirectURL == "" {
+		return nil, fmt.Errorf("%s: cluster map with no primary proxy", proxyURL)
+	}
+	smap := &ClusterMap{
+		Version: raw.Version,
+		Primary: raw.ProxySI.PublicNet.DirectURL,
+		Proxies: make(map[string]string, len(raw.Pmap)),
+		Targets: make(map[string]string, len(raw.Tmap)),
+	}
+	for id, si := range raw.Pmap {
+		smap.Proxies[id] = si.PublicNet.DirectURL
+	}
+	for id, si := range raw.Tmap {
+		smap.Targets[id] = si.PublicNet.DirectURL
+	}
+	return smap, nil
+}
+
+// getClientHints returns nil hints if the cluster predates them
+func getClientHints(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.ClientHints, error) {
+	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Daemon) + "?" + cmn.URLParamWhat + "=" + cmn.GetWhatHints
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get client hints, err: %v", err)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read response, err: %v", err)
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, nil // unrecognized "what"
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, httpError(resp, b)
+	}
+	hints := &cmn.ClientHints{}
+	if err = json.Unmarshal(b, hints); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal client hints, err: %v - [%s]", err, string(b))
+	}
+	return hints, nil
+}
+
+// retryTransport retries the idempotent requests that fail with 503 (Service Unavailable),
+// e.g. while the cluster is electing a new primary proxy; waits for the duration of the
+// server's Retry-After if specified, and for twice as long as the previous time otherwise
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	backoff time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retriable := isIdempotent(req.Method) && (req.Body == nil || req.GetBody != nil)
+	wait := t.backoff
+	for i := 0; ; i++ {
+		r := req
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r2 := *req
+			r2.Header = cloneHeader(req.Header)
+			r2.Body = body
+			r = &r2
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil || resp.StatusCode != http.StatusServiceUnavailable || !retriable || i == t.retries {
+			return resp, err
+		}
+		d := wait
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			d = time.Duration(secs) * time.Second
+		}
+		resp.Body.Close()
+		select {
+		case <-time.After(d):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		wait *= 2
+	}
+}
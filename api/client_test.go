@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestNewClient(t *testing.T) {
+	var (
+		primary, other *httptest.Server
+		unavailable    int32 = 2
+		versions             = make(chan string, 8)
+	)
+	smap := func(w http.ResponseWriter) {
+		fmt.Fprintf(w, `{"version": 7, "proxy_si": {"daemon_id": "p1", "public_net": {"direct_url": %q}},
+			"pmap": {"p1": {"public_net": {"direct_url": %q}}, "p2": {"public_net": {"direct_url": %q}}},
+			"tmap": {"t1": {"public_net": {"direct_url": "http://t1:8081"}}}}`, primary.URL, primary.URL, other.URL)
+	}
+	primary = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		versions <- r.Header.Get(cmn.HeaderDFCAPIVersion)
+		switch r.URL.Query().Get(cmn.URLParamWhat) {
+		case cmn.GetWhatSmap:
+			smap(w)
+		case cmn.GetWhatHints:
+			fmt.Fprintf(w, `{"proxy_urls": [%q, %q], "api_versions": ["v1", "v2"], "dial_timeout": "1s",
+				"max_retries": 3, "retry_backoff": "1ms"}`, primary.URL, other.URL)
+		default:
+			if atomic.AddInt32(&unavailable, -1) >= 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer primary.Close()
+	other = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		smap(w)
+	}))
+	defer other.Close()
+
+	// bootstrapped off a non-primary proxy
+	c, err := NewClient(other.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.URL != primary.URL || c.Smap.Version != 7 || len(c.Smap.Proxies) != 2 || c.Smap.Targets["t1"] != "http://t1:8081" {
+		t.Fatalf("unexpected client %+v, cluster map %+v", c, c.Smap)
+	}
+	if c.Version != cmn.VersionV2 || c.Hints == nil || c.Hints.MaxRetries != 3 {
+		t.Fatalf("expected v2 and the hints, got %s, %+v", c.Version, c.Hints)
+	}
+	for len(versions) > 0 {
+		<-versions
+	}
+
+	// 503 twice - retried
+	b, err := doHTTPRequest(context.Background(), c.HTTPClient, http.MethodGet, c.URL+"/v1/buckets/b", nil)
+	if err != nil || string(b) != "ok" {
+		t.Fatalf("expected ok, got %q, %v", b, err)
+	}
+	if n := len(versions); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+	for len(versions) > 0 {
+		if v := <-versions; v != cmn.VersionV2 {
+			t.Errorf("expected %s requests, got %q", cmn.VersionV2, v)
+		}
+	}
+
+	// not retried: POST is not idempotent
+	atomic.StoreInt32(&unavailable, 1)
+	if _, err = doHTTPRequest(context.Background(), c.HTTPClient, http.MethodPost, c.URL+"/v1/buckets/b", nil); err == nil {
+		t.Error("expected POST to fail with 503")
+	}
+}
@@ -0,0 +1,645 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// GetDiskHistory API operation for DFC
+//
+// Returns the recent iostat reports of all targets' disks, oldest first. The optional disk
+// name limits the result to a single disk, and a non-zero `since` - to the reports taken within
+// the specified duration. Targets keep the reports for the duration of the iostat_history.
+func GetDiskHistory(httpClient *http.Client, proxyURL, disk string, since time.Duration) (*cmn.ClusterDiskHistory, error) {
+	return GetDiskHistoryCtx(context.Background(), httpClient, proxyURL, disk, since)
+}
+
+// GetDiskHistoryCtx is GetDiskHistory with the context for cancellation and deadline
+func GetDiskHistoryCtx(ctx context.Context, httpClient *http.Client, proxyURL, disk string, since time.Duration) (*cmn.ClusterDiskHistory, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetDiskHistory(ctx, disk, since)
+}
+
+// GetDiskHistory is the method counterpart of GetDiskHistory - see NewClient
+func (c *Client) GetDiskHistory(ctx context.Context, disk string, since time.Duration) (*cmn.ClusterDiskHistory, error) {
+	var hist cmn.ClusterDiskHistory
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatDiskHist)
+	if disk != "" {
+		query.Set(cmn.URLParamDisk, disk)
+	}
+	if since > 0 {
+		query.Set(cmn.URLParamSince, since.String())
+	}
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&hist); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal disk history, err: %v", err)
+	}
+	return &hist, nil
+}
+
+// GetClusterDashboard API operation for DFC
+//
+// Returns the cluster overview: proxies' and targets' health, capacity, ops/sec, rebalance
+// status, and alerts. Ops/sec are computed since the previous call served by the same proxy.
+func GetClusterDashboard(httpClient *http.Client, proxyURL string) (*cmn.ClusterDashboard, error) {
+	return GetClusterDashboardCtx(context.Background(), httpClient, proxyURL)
+}
+
+// GetClusterDashboardCtx is GetClusterDashboard with the context for cancellation and deadline
+func GetClusterDashboardCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.ClusterDashboard, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetClusterDashboard(ctx)
+}
+
+// GetClusterDashboard is the method counterpart of GetClusterDashboard - see NewClient
+func (c *Client) GetClusterDashboard(ctx context.Context) (*cmn.ClusterDashboard, error) {
+	var dash cmn.ClusterDashboard
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatDashboard)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&dash); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal dashboard, err: %v", err)
+	}
+	return &dash, nil
+}
+
+// GetClusterSLO API operation for DFC
+//
+// Returns the state of the configured service level objectives (slo.objectives): cluster-wide,
+// evaluated over the events of all daemons, and as reported by each of the daemons.
+func GetClusterSLO(httpClient *http.Client, proxyURL string) (*cmn.ClusterSLOReport, error) {
+	return GetClusterSLOCtx(context.Background(), httpClient, proxyURL)
+}
+
+// GetClusterSLOCtx is GetClusterSLO with the context for cancellation and deadline
+func GetClusterSLOCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.ClusterSLOReport, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetClusterSLO(ctx)
+}
+
+// GetClusterSLO is the method counterpart of GetClusterSLO - see NewClient
+func (c *Client) GetClusterSLO(ctx context.Context) (*cmn.ClusterSLOReport, error) {
+	var report cmn.ClusterSLOReport
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatSLO)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal SLO report, err: %v", err)
+	}
+	return &report, nil
+}
+
+// GetClusterReplStats API operation for DFC
+//
+// Returns the replication traffic - bytes and requests, including retries - per destination
+// URL: summed over all targets, and as reported by each of the targets.
+func GetClusterReplStats(httpClient *http.Client, proxyURL string) (*cmn.ClusterReplStats, error) {
+	return GetClusterReplStatsCtx(context.Background(), httpClient, proxyURL)
+}
+
+// GetClusterReplStatsCtx is GetClusterReplStats with the context for cancellation and deadline
+func GetClusterReplStatsCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.ClusterReplStats, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetClusterReplStats(ctx)
+}
+
+// GetClusterReplStats is the method counterpart of GetClusterReplStats - see NewClient
+func (c *Client) GetClusterReplStats(ctx context.Context) (*cmn.ClusterReplStats, error) {
+	var stats cmn.ClusterReplStats
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatReplStats)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal replication stats, err: %v", err)
+	}
+	return &stats, nil
+}
+
+// GetPrefetchUsages API operation for DFC
+//
+// Returns today's (UTC) usage of the buckets' prefetch budgets - Cloud requests and bytes
+// consumed by prefetching, and the objects trimmed - summed over all targets, and as reported
+// by each of the targets along with its share of the budget.
+func GetPrefetchUsages(httpClient *http.Client, proxyURL string) (*cmn.ClusterPrefetchUsages, error) {
+	return GetPrefetchUsagesCtx(context.Background(), httpClient, proxyURL)
+}
+
+// GetPrefetchUsagesCtx is GetPrefetchUsages with the context for cancellation and deadline
+func GetPrefetchUsagesCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.ClusterPrefetchUsages, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetPrefetchUsages(ctx)
+}
+
+// GetPrefetchUsages is the method counterpart of GetPrefetchUsages - see NewClient
+func (c *Client) GetPrefetchUsages(ctx context.Context) (*cmn.ClusterPrefetchUsages, error) {
+	var usages cmn.ClusterPrefetchUsages
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatPrefetch)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&usages); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal prefetch usages, err: %v", err)
+	}
+	return &usages, nil
+}
+
+// GetClusterBucketStats API operation for DFC
+//
+// Returns the IO per bucket - GETs and bytes read, PUTs and bytes written, DELETEs - summed
+// over all targets, and as reported by each of the targets along with its period. With
+// reset, the targets start counting anew once they have reported (chargeback periods).
+func GetClusterBucketStats(httpClient *http.Client, proxyURL string, reset bool) (*cmn.ClusterBucketStats, error) {
+	return GetClusterBucketStatsCtx(context.Background(), httpClient, proxyURL, reset)
+}
+
+// GetClusterBucketStatsCtx is GetClusterBucketStats with the context for cancellation and deadline
+func GetClusterBucketStatsCtx(ctx context.Context, httpClient *http.Client, proxyURL string, reset bool) (*cmn.ClusterBucketStats, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetClusterBucketStats(ctx, reset)
+}
+
+// GetClusterBucketStats is the method counterpart of GetClusterBucketStats - see NewClient
+func (c *Client) GetClusterBucketStats(ctx context.Context, reset bool) (*cmn.ClusterBucketStats, error) {
+	var stats cmn.ClusterBucketStats
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatBucketIO)
+	if reset {
+		query.Set(cmn.URLParamReset, "true")
+	}
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal bucket stats, err: %v", err)
+	}
+	return &stats, nil
+}
+
+// GetClusterStatsSnapshot API operation for DFC
+//
+// Returns the snapshots of the counters of the proxy and of each target - a consistent copy,
+// with the latencies as the number and the sum of the samples - since the daemon's start or
+// the last reset. With reset, the daemons start the snapshots anew once they have reported,
+// so that the pollers could compute the rates over their own intervals.
+func GetClusterStatsSnapshot(httpClient *http.Client, proxyURL string, reset bool) (*cmn.ClusterStatsSnapshot, error) {
+	return GetClusterStatsSnapshotCtx(context.Background(), httpClient, proxyURL, reset)
+}
+
+// GetClusterStatsSnapshotCtx is GetClusterStatsSnapshot with the context for cancellation and deadline
+func GetClusterStatsSnapshotCtx(ctx context.Context, httpClient *http.Client, proxyURL string, reset bool) (*cmn.ClusterStatsSnapshot, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetClusterStatsSnapshot(ctx, reset)
+}
+
+// GetClusterStatsSnapshot is the method counterpart of GetClusterStatsSnapshot - see NewClient
+func (c *Client) GetClusterStatsSnapshot(ctx context.Context, reset bool) (*cmn.ClusterStatsSnapshot, error) {
+	var snapshot cmn.ClusterStatsSnapshot
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatStats)
+	query.Set(cmn.URLParamSnapshot, "true")
+	if reset {
+		query.Set(cmn.URLParamReset, "true")
+	}
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal stats snapshot, err: %v", err)
+	}
+	return &snapshot, nil
+}
+
+// GetClusterStatsAggregate API operation for DFC
+//
+// Returns the targets' stats aggregated across the cluster: the totals, the rates over the
+// interval since the previous aggregate (by any poller), the minimum, the maximum, and the
+// average across the targets, and the outlier targets - see cmn.ClusterStatsAggregate. With
+// reset, the targets start their snapshots anew once they have reported.
+func GetClusterStatsAggregate(httpClient *http.Client, proxyURL string, reset bool) (*cmn.ClusterStatsAggregate, error) {
+	return GetClusterStatsAggregateCtx(context.Background(), httpClient, proxyURL, reset)
+}
+
+// GetClusterStatsAggregateCtx is GetClusterStatsAggregate with the context for cancellation and deadline
+func GetClusterStatsAggregateCtx(ctx context.Context, httpClient *http.Client, proxyURL string, reset bool) (*cmn.ClusterStatsAggregate, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetClusterStatsAggregate(ctx, reset)
+}
+
+// GetClusterStatsAggregate is the method counterpart of GetClusterStatsAggregate - see NewClient
+func (c *Client) GetClusterStatsAggregate(ctx context.Context, reset bool) (*cmn.ClusterStatsAggregate, error) {
+	var aggregate cmn.ClusterStatsAggregate
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatStats)
+	query.Set(cmn.URLParamAggregate, "true")
+	if reset {
+		query.Set(cmn.URLParamReset, "true")
+	}
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&aggregate); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal stats aggregate, err: %v", err)
+	}
+	return &aggregate, nil
+}
+
+// GetClusterHotObjects API operation for DFC
+//
+// Returns up to topN most frequently accessed objects of the cluster, the hottest first, and
+// the hottest objects of each mountpath as reported by each of the targets. The access counts
+// are approximate and decay over time. Zero topN means the default (10).
+func GetClusterHotObjects(httpClient *http.Client, proxyURL string, topN int) (*cmn.ClusterHotObjects, error) {
+	return GetClusterHotObjectsCtx(context.Background(), httpClient, proxyURL, topN)
+}
+
+// GetClusterHotObjectsCtx is GetClusterHotObjects with the context for cancellation and deadline
+func GetClusterHotObjectsCtx(ctx context.Context, httpClient *http.Client, proxyURL string, topN int) (*cmn.ClusterHotObjects, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetClusterHotObjects(ctx, topN)
+}
+
+// GetClusterHotObjects is the method counterpart of GetClusterHotObjects - see NewClient
+func (c *Client) GetClusterHotObjects(ctx context.Context, topN int) (*cmn.ClusterHotObjects, error) {
+	var hot cmn.ClusterHotObjects
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatHotObjects)
+	if topN > 0 {
+		query.Set(cmn.URLParamTopN, strconv.Itoa(topN))
+	}
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&hot); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal hot objects, err: %v", err)
+	}
+	return &hot, nil
+}
+
+// GetDiskHealth API operation for DFC
+//
+// Returns the latest SMART report of each of the targets' disks. Targets poll the disks
+// every smart.interval while smart.enabled.
+func GetDiskHealth(httpClient *http.Client, proxyURL string) (*cmn.ClusterDiskHealth, error) {
+	return GetDiskHealthCtx(context.Background(), httpClient, proxyURL)
+}
+
+// GetDiskHealthCtx is GetDiskHealth with the context for cancellation and deadline
+func GetDiskHealthCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.ClusterDiskHealth, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetDiskHealth(ctx)
+}
+
+// GetDiskHealth is the method counterpart of GetDiskHealth - see NewClient
+func (c *Client) GetDiskHealth(ctx context.Context) (*cmn.ClusterDiskHealth, error) {
+	var health cmn.ClusterDiskHealth
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatDiskHealth)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal disk health, err: %v", err)
+	}
+	return &health, nil
+}
+
+// GetKeepaliveLatency API operation for DFC
+//
+// Returns the keepalive latency matrix: for each proxy and target, the round trip min, max,
+// and average of its keepalives to each peer, per stats period (stats_time), along with
+// the recent periods' history.
+func GetKeepaliveLatency(httpClient *http.Client, proxyURL string) (*cmn.ClusterKeepaliveLatency, error) {
+	return GetKeepaliveLatencyCtx(context.Background(), httpClient, proxyURL)
+}
+
+// GetKeepaliveLatencyCtx is GetKeepaliveLatency with the context for cancellation and deadline
+func GetKeepaliveLatencyCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.ClusterKeepaliveLatency, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetKeepaliveLatency(ctx)
+}
+
+// GetKeepaliveLatency is the method counterpart of GetKeepaliveLatency - see NewClient
+func (c *Client) GetKeepaliveLatency(ctx context.Context) (*cmn.ClusterKeepaliveLatency, error) {
+	var lat cmn.ClusterKeepaliveLatency
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatKeepalive)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&lat); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal keepalive latency, err: %v", err)
+	}
+	return &lat, nil
+}
+
+// StatsHistoryInput selects the stats history returned by GetStatsHistory
+type StatsHistoryInput struct {
+	// Metrics are the names of the stats, e.g. "get.n", "get.lat"; all stats if empty
+	Metrics []string
+	// Resolution is one of 1m (default), 10m, and 1h
+	Resolution time.Duration
+	// Since limits the samples to those taken within the duration; all samples if zero
+	Since time.Duration
+	// Until limits the samples to those taken before that long ago; up to now if zero
+	Until time.Duration
+}
+
+// GetStatsHistory API operation for DFC
+//
+// Returns the downsampled stats of all proxies and targets: one sample per resolution period,
+// oldest first, with the counters summed up and the latencies averaged over the period.
+// Daemons keep the history for the duration of the stats_history.
+func GetStatsHistory(httpClient *http.Client, proxyURL string, in StatsHistoryInput) (*cmn.ClusterStatsHistory, error) {
+	return GetStatsHistoryCtx(context.Background(), httpClient, proxyURL, in)
+}
+
+// GetStatsHistoryCtx is GetStatsHistory with the context for cancellation and deadline
+func GetStatsHistoryCtx(ctx context.Context, httpClient *http.Client, proxyURL string, in StatsHistoryInput) (*cmn.ClusterStatsHistory, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetStatsHistory(ctx, in)
+}
+
+// GetStatsHistory is the method counterpart of GetStatsHistory - see NewClient
+func (c *Client) GetStatsHistory(ctx context.Context, in StatsHistoryInput) (*cmn.ClusterStatsHistory, error) {
+	var hist cmn.ClusterStatsHistory
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatStatsHist)
+	if len(in.Metrics) != 0 {
+		query.Set(cmn.URLParamMetric, strings.Join(in.Metrics, ","))
+	}
+	if in.Resolution > 0 {
+		query.Set(cmn.URLParamResolution, in.Resolution.String())
+	}
+	if in.Since > 0 {
+		query.Set(cmn.URLParamSince, in.Since.String())
+	}
+	if in.Until > 0 {
+		query.Set(cmn.URLParamUntil, in.Until.String())
+	}
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&hist); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal stats history, err: %v", err)
+	}
+	return &hist, nil
+}
+
+// GetRebalancePlan API operation for DFC
+//
+// Returns what global rebalance would move between the targets given the current cluster
+// map, and the estimated duration. The request is served by the primary proxy, and the
+// plan's ID confirms rebalance if the cluster is configured to require confirmation.
+func GetRebalancePlan(httpClient *http.Client, proxyURL string) (*cmn.ClusterRebalancePlan, error) {
+	return GetRebalancePlanCtx(context.Background(), httpClient, proxyURL)
+}
+
+// GetRebalancePlanCtx is GetRebalancePlan with the context for cancellation and deadline
+func GetRebalancePlanCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.ClusterRebalancePlan, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetRebalancePlan(ctx)
+}
+
+// GetRebalancePlan is the method counterpart of GetRebalancePlan - see NewClient
+func (c *Client) GetRebalancePlan(ctx context.Context) (*cmn.ClusterRebalancePlan, error) {
+	var plan cmn.ClusterRebalancePlan
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatRebPlan)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&plan); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal rebalance plan, err: %v", err)
+	}
+	return &plan, nil
+}
+
+// GetClusterSummary API operation for DFC
+//
+// Returns the number of objects and bytes stored in the cluster: in total, per provider, and
+// local vs cached (Cloud) buckets. The targets maintain the counts as the objects come and go,
+// so that the summary is returned without walking the buckets.
+func GetClusterSummary(httpClient *http.Client, proxyURL string) (*cmn.ClusterSummary, error) {
+	return GetClusterSummaryCtx(context.Background(), httpClient, proxyURL)
+}
+
+// GetClusterSummaryCtx is GetClusterSummary with the context for cancellation and deadline
+func GetClusterSummaryCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.ClusterSummary, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetClusterSummary(ctx)
+}
+
+// GetClusterSummary is the method counterpart of GetClusterSummary - see NewClient
+func (c *Client) GetClusterSummary(ctx context.Context) (*cmn.ClusterSummary, error) {
+	var summary cmn.ClusterSummary
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatSummary)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal cluster summary, err: %v", err)
+	}
+	return &summary, nil
+}
+
+// ReplaceTarget API operation for DFC
+//
+// Makes the target newID - one that has joined the cluster under a new ID, e.g. after losing its
+// configuration directory - take over the ID of the (no longer alive) target oldID, so that the
+// objects stay in place. The replacing target persists oldID and restarts.
+func ReplaceTarget(httpClient *http.Client, proxyURL, oldID, newID string) error {
+	return ReplaceTargetCtx(context.Background(), httpClient, proxyURL, oldID, newID)
+}
+
+// ReplaceTargetCtx is ReplaceTarget with the context for cancellation and deadline
+func ReplaceTargetCtx(ctx context.Context, httpClient *http.Client, proxyURL, oldID, newID string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).ReplaceTarget(ctx, oldID, newID)
+}
+
+// ReplaceTarget is the method counterpart of ReplaceTarget - see NewClient
+func (c *Client) ReplaceTarget(ctx context.Context, oldID, newID string) error {
+	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActReplaceTarget, Name: oldID, Value: newID})
+	if err != nil {
+		return err
+	}
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPut, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), b)
+	return err
+}
+
+// StartRollingUpgrade API operation for DFC
+//
+// Starts restarting the cluster's daemons one at a time (see cmn.RollingUpgradeMsg): the
+// primary proxy puts each daemon in maintenance mode, waits for its requests in flight, reports
+// it ready to restart (cmn.UpgradeReady), and waits for it to restart before moving on to the
+// next one. Returns the initial status; the progress is GetRollingUpgrade.
+func StartRollingUpgrade(httpClient *http.Client, proxyURL string, msg *cmn.RollingUpgradeMsg) (*cmn.RollingUpgradeStatus, error) {
+	return StartRollingUpgradeCtx(context.Background(), httpClient, proxyURL, msg)
+}
+
+// StartRollingUpgradeCtx is StartRollingUpgrade with the context for cancellation and deadline
+func StartRollingUpgradeCtx(ctx context.Context, httpClient *http.Client, proxyURL string, msg *cmn.RollingUpgradeMsg) (*cmn.RollingUpgradeStatus, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).StartRollingUpgrade(ctx, msg)
+}
+
+// StartRollingUpgrade is the method counterpart of StartRollingUpgrade - see NewClient
+func (c *Client) StartRollingUpgrade(ctx context.Context, msg *cmn.RollingUpgradeMsg) (*cmn.RollingUpgradeStatus, error) {
+	if msg == nil {
+		msg = &cmn.RollingUpgradeMsg{}
+	}
+	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActRollingUpgrade, Value: msg})
+	if err != nil {
+		return nil, err
+	}
+	if b, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPut, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), b); err != nil {
+		return nil, err
+	}
+	status := &cmn.RollingUpgradeStatus{}
+	if err = json.Unmarshal(b, status); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal rolling upgrade status, err: %v", err)
+	}
+	return status, nil
+}
+
+// GetRollingUpgrade API operation for DFC
+//
+// Returns the progress of the current (or the last) rolling upgrade
+func GetRollingUpgrade(httpClient *http.Client, proxyURL string) (*cmn.RollingUpgradeStatus, error) {
+	return GetRollingUpgradeCtx(context.Background(), httpClient, proxyURL)
+}
+
+// GetRollingUpgradeCtx is GetRollingUpgrade with the context for cancellation and deadline
+func GetRollingUpgradeCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*cmn.RollingUpgradeStatus, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetRollingUpgrade(ctx)
+}
+
+// GetRollingUpgrade is the method counterpart of GetRollingUpgrade - see NewClient
+func (c *Client) GetRollingUpgrade(ctx context.Context) (*cmn.RollingUpgradeStatus, error) {
+	var status cmn.RollingUpgradeStatus
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatUpgrade)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal rolling upgrade status, err: %v", err)
+	}
+	return &status, nil
+}
+
+// AbortRollingUpgrade API operation for DFC
+//
+// Stops the rolling upgrade in progress and takes the daemon being upgraded out of
+// maintenance mode; the daemons already restarted stay restarted
+func AbortRollingUpgrade(httpClient *http.Client, proxyURL string) error {
+	return AbortRollingUpgradeCtx(context.Background(), httpClient, proxyURL)
+}
+
+// AbortRollingUpgradeCtx is AbortRollingUpgrade with the context for cancellation and deadline
+func AbortRollingUpgradeCtx(ctx context.Context, httpClient *http.Client, proxyURL string) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).AbortRollingUpgrade(ctx)
+}
+
+// AbortRollingUpgrade is the method counterpart of AbortRollingUpgrade - see NewClient
+func (c *Client) AbortRollingUpgrade(ctx context.Context) error {
+	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActAbortUpgrade})
+	if err != nil {
+		return err
+	}
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPut, c.URL+cmn.URLPath(cmn.Version, cmn.Cluster), b)
+	return err
+}
+
+// SetDaemonMaintenance API operation for DFC
+//
+// Puts the daemon at daemonURL in maintenance mode (enable) or takes it out of it: in
+// maintenance mode, the daemon rejects new object and bucket requests with 503 while
+// completing the ones in flight - see GetDaemonMaintenance
+func SetDaemonMaintenance(httpClient *http.Client, daemonURL string, enable bool) error {
+	return SetDaemonMaintenanceCtx(context.Background(), httpClient, daemonURL, enable)
+}
+
+// SetDaemonMaintenanceCtx is SetDaemonMaintenance with the context for cancellation and deadline
+func SetDaemonMaintenanceCtx(ctx context.Context, httpClient *http.Client, daemonURL string, enable bool) error {
+	return (&Client{HTTPClient: httpClient, URL: daemonURL}).SetDaemonMaintenance(ctx, enable)
+}
+
+// SetDaemonMaintenance is the method counterpart of SetDaemonMaintenance - see NewClient;
+// the client's URL is that of the daemon
+func (c *Client) SetDaemonMaintenance(ctx context.Context, enable bool) error {
+	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActMaintenance, Value: enable})
+	if err != nil {
+		return err
+	}
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPut, c.URL+cmn.URLPath(cmn.Version, cmn.Daemon), b)
+	return err
+}
+
+// GetDaemonMaintenance API operation for DFC
+//
+// Returns the maintenance mode of the daemon at daemonURL, including its requests in flight
+// and whether it is ready to restart
+func GetDaemonMaintenance(httpClient *http.Client, daemonURL string) (*cmn.MaintenanceStatus, error) {
+	return GetDaemonMaintenanceCtx(context.Background(), httpClient, daemonURL)
+}
+
+// GetDaemonMaintenanceCtx is GetDaemonMaintenance with the context for cancellation and deadline
+func GetDaemonMaintenanceCtx(ctx context.Context, httpClient *http.Client, daemonURL string) (*cmn.MaintenanceStatus, error) {
+	return (&Client{HTTPClient: httpClient, URL: daemonURL}).GetDaemonMaintenance(ctx)
+}
+
+// GetDaemonMaintenance is the method counterpart of GetDaemonMaintenance - see NewClient;
+// the client's URL is that of the daemon
+func (c *Client) GetDaemonMaintenance(ctx context.Context) (*cmn.MaintenanceStatus, error) {
+	var status cmn.MaintenanceStatus
+	query := url.Values{}
+	query.Set(cmn.URLParamWhat, cmn.GetWhatMaint)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, c.URL+cmn.URLPath(cmn.Version, cmn.Daemon), nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal maintenance status, err: %v", err)
+	}
+	return &status, nil
+}
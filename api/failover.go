@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// FailoverTransport is the http.RoundTripper that makes the cluster's proxies
+// interchangeable: the requests addressed to any of the known proxies are sent to the
+// current one and, if it cannot be reached, to the next one, and so on. The list of the
+// proxies is refreshed out of each proxy's response (cmn.HeaderDFCProxyURLs), and so it
+// is sufficient to configure a client with a single proxy URL - the proxies that join
+// the cluster later are learned and the proxies that leave it are forgotten.
+//
+// A request is retried with the next proxy only when it has not reached the current
+// one (failed to connect) or if it is idempotent (e.g., GET, PUT, DELETE - but not POST);
+// and, regardless of the method, when the proxy has rejected it as being in maintenance
+// mode (503 with cmn.HeaderDFCMaintenance), e.g. while it is restarted by the rolling
+// upgrade - unless no other proxies are left. The requests to other hosts, e.g. redirects
+// to storage targets, are passed through as is.
+type FailoverTransport struct {
+	Base http.RoundTripper
+
+	mtx  sync.Mutex
+	urls []*url.URL // known proxies: the current one first
+}
+
+// NewFailoverTransport returns the transport that starts with the given proxy URLs;
+// nil base means http.DefaultTransport
+func NewFailoverTransport(base http.RoundTripper, proxyURLs ...string) (*FailoverTransport, error) {
+	if len(proxyURLs) == 0 {
+		return nil, errors.New("No proxy URLs")
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	f := &FailoverTransport{Base: base}
+	if err := f.setURLs(proxyURLs); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// NewFailoverHTTPClient returns http.Client (see NewHTTPClient) that fails over between
+// the cluster's proxies; any of the proxy URLs can be passed to the api functions
+func NewFailoverHTTPClient(timeout time.Duration, tlsArgs *TLSArgs, proxyURLs ...string) (*http.Client, error) {
+	client, err := NewHTTPClient(timeout, tlsArgs)
+	if err != nil {
+		return nil, err
+	}
+	if client.Transport, err = NewFailoverTransport(client.Transport, proxyURLs...); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// ProxyURLs returns the known proxy URLs, the current one first
+func (f *FailoverTransport) ProxyURLs() []string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	urls := make([]string, len(f.urls))
+	for i, u := range f.urls {
+		urls[i] = u.String()
+	}
+	return urls
+}
+
+func (f *FailoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	proxies := f.proxies(req.URL)
+	if proxies == nil {
+		return f.Base.RoundTrip(req)
+	}
+	var (
+		retriable = req.Body == nil || req.GetBody != nil
+		lastErr   error
+		rejected  bool // by the previous proxy in maintenance mode
+	)
+	for i, proxy := range proxies {
+		if i > 0 && (!retriable || !(isIdempotent(req.Method) || isConnError(lastErr) || rejected)) {
+			break
+		}
+		r := req
+		if i > 0 || proxy.Host != req.URL.Host || proxy.Scheme != req.URL.Scheme {
+			r = cloneRequest(req, proxy)
+		}
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				break
+			}
+			r.Body = body
+		}
+		resp, err := f.Base.RoundTrip(r)
+		if err == nil {
+			if retriable && i < len(proxies)-1 && inMaintenance(resp) {
+				resp.Body.Close()
+				lastErr, rejected = nil, true
+				continue
+			}
+			f.update(proxy, resp.Header.Get(cmn.HeaderDFCProxyURLs))
+			return resp, nil
+		}
+		lastErr, rejected = err, false
+	}
+	return nil, lastErr
+}
+
+// proxies returns the known proxies, the current one first - or nil if the request
+// is not addressed to a proxy
+func (f *FailoverTransport) proxies(u *url.URL) []*url.URL {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for _, proxy := range f.urls {
+		if proxy.Host == u.Host && proxy.Scheme == u.Scheme {
+			return append([]*url.URL(nil), f.urls...)
+		}
+	}
+	return nil
+}
+
+// update makes the proxy that has responded the current one and refreshes the list
+// of the proxies out of its response
+func (f *FailoverTransport) update(current *url.URL, header string) {
+	var urls []string
+	if header != "" {
+		urls = strings.Split(header, ",")
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if len(urls) > 0 {
+		list := make([]*url.URL, 0, len(urls))
+		for _, s := range urls {
+			if u, err := url.Parse(strings.TrimSpace(s)); err == nil && u.Host != "" {
+				list = append(list, u)
+			}
+		}
+		if len(list) > 0 {
+			f.urls = list
+		}
+	}
+	for i, u := range f.urls {
+		if u.Host == current.Host && u.Scheme == current.Scheme {
+			f.urls = append(append([]*url.URL{u}, f.urls[:i]...), f.urls[i+1:]...)
+			return
+		}
+	}
+	// the responding proxy is not listed - keep it anyway, to be used for the next request
+	f.urls = append([]*url.URL{current}, f.urls...)
+}
+
+func (f *FailoverTransport) setURLs(proxyURLs []string) error {
+	urls := make([]*url.URL, 0, len(proxyURLs))
+	for _, s := range proxyURLs {
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("Invalid proxy URL %q", s)
+		}
+		urls = append(urls, u)
+	}
+	f.urls = urls
+	return nil
+}
+
+func cloneRequest(req *http.Request, proxy *url.URL) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	u := *req.URL
+	u.Scheme, u.Host = proxy.Scheme, proxy.Host
+	r.URL = &u
+	r.Host = ""
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	return r
+}
+
+// isIdempotent as per RFC 7231
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// inMaintenance returns true if the proxy has rejected the request as being in maintenance mode
+func inMaintenance(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get(cmn.HeaderDFCMaintenance) != ""
+}
+
+// isConnError returns true if the request has failed to connect, and so it has not
+// reached the proxy
+func isConnError(err error) bool {
+	if oe, ok := err.(*net.OpError); ok {
+		return oe.Op == "dial"
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestFailoverTransport(t *testing.T) {
+	var p1, p2 *httptest.Server
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(cmn.HeaderDFCProxyURLs, p1.URL+","+p2.URL)
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write([]byte(name + ":" + string(body)))
+		}
+	}
+	p1 = httptest.NewServer(handler("p1"))
+	p2 = httptest.NewServer(handler("p2"))
+	defer p2.Close()
+
+	// configured with a single proxy - learns the other one
+	client, err := NewFailoverHTTPClient(0, nil, p1.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := client.Transport.(*FailoverTransport)
+	do := func(method, url, body string) string {
+		b, err := doHTTPRequest(context.Background(), client, method, url, []byte(body))
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+		return string(b)
+	}
+	if got := do(http.MethodGet, p1.URL+"/v1/daemon", ""); got != "p1:" {
+		t.Errorf("expected p1, got %q", got)
+	}
+	if urls := f.ProxyURLs(); strings.Join(urls, ",") != p1.URL+","+p2.URL {
+		t.Errorf("expected both proxies, got %v", urls)
+	}
+
+	// p1 goes down: the requests addressed to p1 are sent to p2 - with the body
+	p1.Close()
+	if got := do(http.MethodPut, p1.URL+"/v1/objects/b/o", "data"); got != "p2:data" {
+		t.Errorf("expected p2, got %q", got)
+	}
+	if urls := f.ProxyURLs(); urls[0] != p2.URL {
+		t.Errorf("expected p2 to be the current proxy, got %v", urls)
+	}
+	if got := do(http.MethodGet, p1.URL+"/v1/daemon", ""); got != "p2:" {
+		t.Errorf("expected p2, got %q", got)
+	}
+
+	// p2 in maintenance mode: even POST goes to p3 while the other proxies remain
+	var maint bool
+	p3 := httptest.NewServer(handler("p3"))
+	defer p3.Close()
+	p2.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(cmn.HeaderDFCProxyURLs, p2.URL+","+p3.URL)
+		if maint {
+			w.Header().Set(cmn.HeaderDFCMaintenance, "true")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte("p2:" + string(body)))
+	})
+	if got := do(http.MethodGet, p2.URL+"/v1/daemon", ""); got != "p2:" {
+		t.Errorf("expected p2, got %q", got)
+	}
+	maint = true
+	if got := do(http.MethodPost, p2.URL+"/v1/buckets/b", "msg"); got != "p3:msg" {
+		t.Errorf("expected p3, got %q", got)
+	}
+
+	// not a proxy: passed through
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	if _, err := doHTTPRequest(context.Background(), client, http.MethodGet, "http://"+addr+"/v1/daemon", nil); err == nil {
+		t.Error("expected the request to a non-proxy to fail")
+	}
+}
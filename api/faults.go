@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// InjectFault API operation for DFC
+//
+// Injects the fault into the target's datapath. Requires the target built with the
+// "faultinject" build tag - intended for testing only.
+func InjectFault(httpClient *http.Client, targetURL string, spec *cmn.FaultSpec) error {
+	return InjectFaultCtx(context.Background(), httpClient, targetURL, spec)
+}
+
+// InjectFaultCtx is InjectFault with the context for cancellation and deadline
+func InjectFaultCtx(ctx context.Context, httpClient *http.Client, targetURL string, spec *cmn.FaultSpec) error {
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	_, err = doHTTPRequest(ctx, httpClient, http.MethodPut, targetURL+cmn.URLPath(cmn.Version, cmn.Faults), b)
+	return err
+}
+
+// GetFaults API operation for DFC
+//
+// Returns the faults currently injected into the target's datapath, including the number
+// of times each fault has been triggered.
+func GetFaults(httpClient *http.Client, targetURL string) ([]cmn.FaultSpec, error) {
+	return GetFaultsCtx(context.Background(), httpClient, targetURL)
+}
+
+// GetFaultsCtx is GetFaults with the context for cancellation and deadline
+func GetFaultsCtx(ctx context.Context, httpClient *http.Client, targetURL string) ([]cmn.FaultSpec, error) {
+	var faults []cmn.FaultSpec
+	b, err := doHTTPRequest(ctx, httpClient, http.MethodGet, targetURL+cmn.URLPath(cmn.Version, cmn.Faults), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &faults); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal faults, err: %v", err)
+	}
+	return faults, nil
+}
+
+// ClearFaults API operation for DFC
+//
+// Removes all faults injected into the target's datapath.
+func ClearFaults(httpClient *http.Client, targetURL string) error {
+	return ClearFaultsCtx(context.Background(), httpClient, targetURL)
+}
+
+// ClearFaultsCtx is ClearFaults with the context for cancellation and deadline
+func ClearFaultsCtx(ctx context.Context, httpClient *http.Client, targetURL string) error {
+	_, err := doHTTPRequest(ctx, httpClient, http.MethodDelete, targetURL+cmn.URLPath(cmn.Version, cmn.Faults), nil)
+	return err
+}
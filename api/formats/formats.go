@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+// Package formats renders the results of the api calls - cluster stats, cluster maps,
+// xaction stats, and bucket lists - as text tables, JSON, or CSV. The columns and
+// the order of the rows are stable, so that the output can be parsed by scripts.
+package formats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Format is the output format
+type Format string
+
+const (
+	Table Format = "table" // aligned columns with a header, for humans
+	JSON  Format = "json"  // array of objects keyed by the column names
+	CSV   Format = "csv"   // RFC 4180 with a header
+)
+
+// ParseFormat returns the format by name; empty string means Table
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "":
+		return Table, nil
+	case Table, JSON, CSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid output format %q (expecting one of: %s, %s, %s)", s, Table, JSON, CSV)
+}
+
+// Rows is the tabular form of a result: column names (snake_case, used as JSON keys and
+// as the CSV header; upper-cased in the table header) and the rows of values. Values are
+// strings, integers, floats, booleans, or time.Time (formatted as RFC3339)
+type Rows struct {
+	Columns []string
+	Values  [][]interface{}
+}
+
+// Render writes the rows to w in the given format
+func (r *Rows) Render(w io.Writer, f Format) error {
+	switch f {
+	case Table, "":
+		return r.renderTable(w)
+	case JSON:
+		return r.renderJSON(w)
+	case CSV:
+		return r.renderCSV(w)
+	}
+	return fmt.Errorf("invalid output format %q", f)
+}
+
+func (r *Rows) renderTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	header := make([]string, len(r.Columns))
+	for i, col := range r.Columns {
+		header[i] = strings.ToUpper(col)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range r.Values {
+		fmt.Fprintln(tw, strings.Join(r.strings(row), "\t"))
+	}
+	return tw.Flush()
+}
+
+func (r *Rows) renderCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(r.Columns); err != nil {
+		return err
+	}
+	for _, row := range r.Values {
+		if err := cw.Write(r.strings(row)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *Rows) renderJSON(w io.Writer) error {
+	objects := make([]map[string]interface{}, 0, len(r.Values))
+	for _, row := range r.Values {
+		obj := make(map[string]interface{}, len(r.Columns))
+		for i, col := range r.Columns {
+			v := row[i]
+			if t, ok := v.(time.Time); ok {
+				v = formatTime(t)
+			}
+			obj[col] = v
+		}
+		objects = append(objects, obj)
+	}
+	b, err := json.MarshalIndent(objects, "", "  ") // encoding/json sorts the keys
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func (r *Rows) strings(row []interface{}) []string {
+	out := make([]string, len(row))
+	for i, v := range row {
+		switch v := v.(type) {
+		case time.Time:
+			out[i] = formatTime(v)
+		case float64:
+			out[i] = fmt.Sprintf("%.6g", v)
+		default:
+			out[i] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
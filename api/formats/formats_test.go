@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestRender(t *testing.T) {
+	rows := &Rows{
+		Columns: []string{"name", "size", "cached", "time"},
+		Values: [][]interface{}{
+			{"obj1", int64(1024), true, time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)},
+			{"dir/obj, 2", int64(0), false, time.Time{}},
+		},
+	}
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{Table, "NAME        SIZE  CACHED  TIME\n" +
+			"obj1        1024  true    2018-10-01T12:00:00Z\n" +
+			"dir/obj, 2  0     false   \n"},
+		{CSV, "name,size,cached,time\n" +
+			"obj1,1024,true,2018-10-01T12:00:00Z\n" +
+			"\"dir/obj, 2\",0,false,\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := rows.Render(&buf, tt.format); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("%s: expected\n%q, got\n%q", tt.format, tt.expected, buf.String())
+		}
+	}
+
+	var (
+		buf     bytes.Buffer
+		objects []map[string]interface{}
+	)
+	if err := rows.Render(&buf, JSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &objects); err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0]["size"] != float64(1024) || objects[0]["time"] != "2018-10-01T12:00:00Z" {
+		t.Errorf("unexpected JSON output: %s", buf.String())
+	}
+
+	if _, err := ParseFormat("yaml"); err == nil {
+		t.Error("expected an error parsing unsupported format")
+	}
+	if f, err := ParseFormat(""); err != nil || f != Table {
+		t.Errorf("expected the default format %q, got %q, err: %v", Table, f, err)
+	}
+}
+
+func TestResultRows(t *testing.T) {
+	node := func(id string) *cluster.Snode {
+		return &cluster.Snode{DaemonID: id, PublicNet: cluster.NetInfo{DirectURL: "http://" + id}}
+	}
+	smap := &cluster.Smap{
+		Pmap:      map[string]*cluster.Snode{"p2": node("p2"), "p1": node("p1")},
+		Tmap:      map[string]*cluster.Snode{"t2": node("t2"), "t1": node("t1")},
+		NonElects: cmn.SimpleKVs{"p2": ""},
+		ProxySI:   node("p1"),
+	}
+	rows := SmapRows(smap)
+	ids := make([]interface{}, 0, 4)
+	for _, row := range rows.Values {
+		ids = append(ids, row[0])
+	}
+	if len(ids) != 4 || ids[0] != "p1" || ids[1] != "p2" || ids[2] != "t1" || ids[3] != "t2" {
+		t.Errorf("unexpected order of daemons: %v", ids)
+	}
+	if rows.Values[0][3] != true || rows.Values[1][4] != true || rows.Values[2][4] != false {
+		t.Errorf("unexpected primary/non-electable: %v", rows.Values)
+	}
+
+	var cs stats.ClusterStats
+	raw := `{"proxy": {"get.n": 10, "err.n": 1},
+		"target": {"t2": {"core": {"put.n": 5}, "capacity": {"/mp2": {"used": 2}, "/mp1": {"used": 1}}},
+			"t1": {"core": {"get.n": 7}}}}`
+	if err := jsoniter.Unmarshal([]byte(raw), &cs); err != nil {
+		t.Fatal(err)
+	}
+	rows = ClusterStatsRows(&cs)
+	expected := [][]interface{}{
+		{"proxy", "proxy", "err.n", int64(1)},
+		{"proxy", "proxy", "get.n", int64(10)},
+		{"t1", "target", "get.n", int64(7)},
+		{"t2", "target", "put.n", int64(5)},
+	}
+	if len(rows.Values) != len(expected) {
+		t.Fatalf("expected %d rows, got %v", len(expected), rows.Values)
+	}
+	for i, row := range expected {
+		for j := range row {
+			if rows.Values[i][j] != row[j] {
+				t.Errorf("row %d: expected %v, got %v", i, row, rows.Values[i])
+				break
+			}
+		}
+	}
+	if rows = CapacityRows(&cs); len(rows.Values) != 2 || rows.Values[0][1] != "/mp1" {
+		t.Errorf("unexpected capacity rows: %v", rows.Values)
+	}
+
+	list := &cmn.BucketList{Entries: []*cmn.BucketEntry{{Name: "a/", Type: cmn.BucketEntryDir}, {Name: "b", Size: 3}}}
+	if rows = BucketListRows(list); rows.Values[0][1] != cmn.BucketEntryDir || rows.Values[1][1] != cmn.BucketEntryFile {
+		t.Errorf("unexpected bucket list rows: %v", rows.Values)
+	}
+}
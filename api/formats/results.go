@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package formats
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+const (
+	daemonProxy  = "proxy"
+	daemonTarget = "target"
+)
+
+// ClusterStatsRows returns the stats of the proxy and the targets (GET /v1/cluster?what=stats),
+// one stat per row, ordered by daemon and stat name. The proxy's daemon ID is unknown - it
+// is reported as "proxy"; latencies are in the units of the cluster's stats_latency_unit
+func ClusterStatsRows(cs *stats.ClusterStats) *Rows {
+	rows := &Rows{Columns: []string{"daemon_id", "type", "stat", "value"}}
+	if cs.Proxy != nil {
+		for _, name := range sortedStats(cs.Proxy) {
+			rows.Values = append(rows.Values, []interface{}{daemonProxy, daemonProxy, name, cs.Proxy.Tracker[name].Value})
+		}
+	}
+	for _, id := range keys(cs.Target) {
+		ts := cs.Target[id]
+		if ts == nil || ts.Core == nil {
+			continue
+		}
+		for _, name := range sortedStats(&ts.Core.ProxyCoreStats) {
+			rows.Values = append(rows.Values, []interface{}{id, daemonTarget, name, ts.Core.Tracker[name].Value})
+		}
+	}
+	return rows
+}
+
+// CapacityRows returns the targets' mountpath capacities (bytes) out of the cluster stats,
+// ordered by target and mountpath
+func CapacityRows(cs *stats.ClusterStats) *Rows {
+	rows := &Rows{Columns: []string{"daemon_id", "mountpath", "used", "avail", "used_pct", "reserved", "headroom"}}
+	for _, id := range keys(cs.Target) {
+		ts := cs.Target[id]
+		if ts == nil {
+			continue
+		}
+		for _, mpath := range keys(ts.Capacity) {
+			c := ts.Capacity[mpath]
+			rows.Values = append(rows.Values, []interface{}{id, mpath, c.Used, c.Avail, c.Usedpct, c.Reserved, c.Headroom})
+		}
+	}
+	return rows
+}
+
+// SmapRows returns the daemons of the cluster map: proxies first, each group ordered by ID
+func SmapRows(smap *cluster.Smap) *Rows {
+	rows := &Rows{Columns: []string{"daemon_id", "type", "url", "primary", "non_electable"}}
+	add := func(kind string, nodes map[string]*cluster.Snode) {
+		for _, id := range keys(nodes) {
+			var (
+				si           = nodes[id]
+				primary      = kind == daemonProxy && smap.ProxySI != nil && smap.ProxySI.DaemonID == id
+				_, nonelect  = smap.NonElects[id]
+				nonElectable = kind == daemonProxy && nonelect
+			)
+			rows.Values = append(rows.Values, []interface{}{id, kind, si.PublicNet.DirectURL, primary, nonElectable})
+		}
+	}
+	add(daemonProxy, smap.Pmap)
+	add(daemonTarget, smap.Tmap)
+	return rows
+}
+
+// RebalanceRows returns the targets' rebalance stats (GET /v1/cluster?what=xaction&props=rebalance):
+// one row per xaction, ordered by target and xaction start time
+func RebalanceRows(targets map[string]stats.RebalanceTargetStats) *Rows {
+	rows := &Rows{Columns: []string{"daemon_id", "xaction_id", "status", "start_time", "end_time",
+		"sent_objects", "sent_bytes", "recv_objects", "recv_bytes"}}
+	for _, id := range keys(targets) {
+		ts := targets[id]
+		for _, x := range sortedXactions(ts.Xactions) {
+			rows.Values = append(rows.Values, []interface{}{id, x.Id, x.Status, x.StartTime, x.EndTime,
+				ts.NumSentFiles, ts.NumSentBytes, ts.NumRecvFiles, ts.NumRecvBytes})
+		}
+	}
+	return rows
+}
+
+// PrefetchRows returns the targets' prefetch stats (GET /v1/cluster?what=xaction&props=prefetch):
+// one row per xaction, ordered by target and xaction start time
+func PrefetchRows(targets map[string]stats.PrefetchTargetStats) *Rows {
+	rows := &Rows{Columns: []string{"daemon_id", "xaction_id", "status", "start_time", "end_time",
+		"prefetched_objects", "prefetched_bytes"}}
+	for _, id := range keys(targets) {
+		ts := targets[id]
+		for _, x := range sortedXactions(ts.Xactions) {
+			rows.Values = append(rows.Values, []interface{}{id, x.Id, x.Status, x.StartTime, x.EndTime,
+				ts.NumFilesPrefetched, ts.NumBytesPrefetched})
+		}
+	}
+	return rows
+}
+
+// BucketListRows returns the bucket list entries in the order of the list (by name); the
+// properties that were not requested (cmn.GetMsg.GetProps) are empty
+func BucketListRows(list *cmn.BucketList) *Rows {
+	rows := &Rows{Columns: []string{"name", "type", "size", "version", "checksum", "ctime", "atime",
+		"cached", "status", "target_url"}}
+	for _, e := range list.Entries {
+		typ := e.Type
+		if typ == "" {
+			typ = cmn.BucketEntryFile
+		}
+		rows.Values = append(rows.Values, []interface{}{e.Name, typ, e.Size, e.Version, e.Checksum, e.Ctime, e.Atime,
+			e.IsCached, e.Status, e.TargetURL})
+	}
+	return rows
+}
+
+func sortedStats(s *stats.ProxyCoreStats) []string {
+	return keys(s.Tracker)
+}
+
+// keys returns the sorted keys of a map with string keys
+func keys(m interface{}) []string {
+	v := reflect.ValueOf(m)
+	out := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		out = append(out, key.String())
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedXactions(xactions []stats.XactionDetails) []stats.XactionDetails {
+	sorted := append([]stats.XactionDetails(nil), xactions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartTime.Before(sorted[j].StartTime) })
+	return sorted
+}
@@ -0,0 +1,287 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ListMsg builds the list-bucket request (cmn.GetMsg) and validates it client-side: DFC
+// takes the request at its word, so that, e.g., a misspelled property or a page marker
+// outside of the prefix silently result in missing fields or in an empty list. Each With*
+// method records the problems, if any; Build returns them all. E.g.:
+//
+//	msg, err := api.NewListMsg().WithPrefix("train/").WithProps(cmn.GetPropsSize).WithPageSize(500).Build()
+type ListMsg struct {
+	msg    cmn.GetMsg
+	props  []string
+	filter *listTimeFilter
+	errs   []string
+}
+
+// listTimeFilter selects the entries by time: [after, before); zero - unbounded
+type listTimeFilter struct {
+	prop          string // cmn.GetPropsCtime or cmn.GetPropsAtime
+	after, before time.Time
+}
+
+// the list properties, as per cmn.GetMsg.GetProps enum
+var listProps = map[string]bool{
+	cmn.GetPropsChecksum: true, cmn.GetPropsSize: true, cmn.GetPropsAtime: true, cmn.GetPropsCtime: true,
+	cmn.GetPropsIsCached: true, cmn.GetPropsBucket: true, cmn.GetPropsVersion: true, cmn.GetTargetURL: true,
+	cmn.GetPropsStatus: true,
+}
+
+// the time formats by name - see WithTimeFormat
+var listTimeFormats = map[string]string{
+	"RFC822":     cmn.RFC822,
+	"RFC822Z":    cmn.RFC822Z,
+	"RFC1123":    cmn.RFC1123,
+	"RFC1123Z":   cmn.RFC1123Z,
+	"RFC3339":    cmn.RFC3339,
+	"Stamp":      cmn.Stamp,
+	"StampMilli": cmn.StampMilli,
+}
+
+// formatted and parsed to validate the time layouts (unlike the layouts' reference time,
+// formats into something different than the layout itself)
+var layoutCheckTime = time.Date(2018, time.November, 20, 10, 30, 45, 0, time.UTC)
+
+// NewListMsg returns the builder of the list-bucket request that lists all objects
+func NewListMsg() *ListMsg {
+	return &ListMsg{}
+}
+
+func (m *ListMsg) errorf(format string, a ...interface{}) *ListMsg {
+	m.errs = append(m.errs, fmt.Sprintf(format, a...))
+	return m
+}
+
+// WithPrefix lists only the objects whose names start with the prefix
+func (m *ListMsg) WithPrefix(prefix string) *ListMsg {
+	if strings.HasPrefix(prefix, "/") {
+		return m.errorf("prefix %q starts with %q: object names never do", prefix, "/")
+	}
+	m.msg.GetPrefix = prefix
+	return m
+}
+
+// WithPageSize limits the list to a single page of up to n entries; BucketList.PageMarker
+// then continues the list (see WithMarker)
+func (m *ListMsg) WithPageSize(n int) *ListMsg {
+	if n <= 0 {
+		return m.errorf("page size %d: expecting a positive number", n)
+	}
+	m.msg.GetPageSize = n
+	return m
+}
+
+// WithProps adds the properties to list along with the names, e.g. cmn.GetPropsSize
+func (m *ListMsg) WithProps(props ...string) *ListMsg {
+	for _, prop := range props {
+		prop = strings.TrimSpace(prop)
+		if !listProps[prop] {
+			m.errorf("unknown property %q", prop)
+			continue
+		}
+		m.addProp(prop)
+	}
+	return m
+}
+
+func (m *ListMsg) addProp(prop string) {
+	for _, p := range m.props {
+		if p == prop {
+			return
+		}
+	}
+	m.props = append(m.props, prop)
+}
+
+// WithMarker continues the list after the marker - BucketList.PageMarker of the previous page
+func (m *ListMsg) WithMarker(marker string) *ListMsg {
+	m.msg.GetPageMarker = marker
+	return m
+}
+
+// WithDelimiter groups the names by the delimiter after the prefix - see cmn.GetMsg.GetDelimiter
+func (m *ListMsg) WithDelimiter(delimiter string) *ListMsg {
+	if delimiter != cmn.ListDelimiter {
+		return m.errorf("delimiter %q: only %q is supported", delimiter, cmn.ListDelimiter)
+	}
+	m.msg.GetDelimiter = delimiter
+	return m
+}
+
+// WithTimeFormat formats the times (ctime and atime) as per the layout (see package time)
+// or its name, e.g. "RFC3339"
+func (m *ListMsg) WithTimeFormat(format string) *ListMsg {
+	if layout, ok := listTimeFormats[format]; ok {
+		format = layout
+	}
+	if layoutCheckTime.Format(format) == format {
+		return m.errorf("time format %q has no time elements (expecting a layout, e.g. %q)", format, time.RFC3339)
+	}
+	m.msg.GetTimeFormat = format
+	return m
+}
+
+// WithStrict fails the list if any of the targets fails to respond - see cmn.GetMsg.GetStrict
+func (m *ListMsg) WithStrict() *ListMsg {
+	m.msg.GetStrict = true
+	return m
+}
+
+// WithTimeFilter lists only the objects created (cmn.GetPropsCtime) or last accessed
+// (cmn.GetPropsAtime) within [after, before); zero after or before - unbounded. The filter is
+// applied client-side - see ListBucket and Filter: the objects with no such time (e.g., atime
+// is not tracked) are filtered out, the directories (WithDelimiter) are not. Adds the
+// property and, unless specified, the time format to the request
+func (m *ListMsg) WithTimeFilter(prop string, after, before time.Time) *ListMsg {
+	if prop != cmn.GetPropsCtime && prop != cmn.GetPropsAtime {
+		return m.errorf("time filter property %q: expecting %q or %q", prop, cmn.GetPropsCtime, cmn.GetPropsAtime)
+	}
+	if after.IsZero() && before.IsZero() {
+		return m.errorf("time filter: expecting either time bound")
+	}
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return m.errorf("time filter: %v is not before %v", after, before)
+	}
+	m.filter = &listTimeFilter{prop: prop, after: after, before: before}
+	return m
+}
+
+// Build validates the request and returns it; the errors are those of all the With* calls
+// and of their combinations
+func (m *ListMsg) Build() (*cmn.GetMsg, error) {
+	msg := m.msg
+	errs := append([]string(nil), m.errs...)
+	props := m.props
+	if m.filter != nil {
+		props = append(props[:len(props):len(props)], m.filter.prop)
+		switch {
+		case msg.GetTimeFormat == "":
+			msg.GetTimeFormat = time.RFC3339Nano
+		case !hasDate(msg.GetTimeFormat):
+			errs = append(errs, fmt.Sprintf("time filter: time format %q has no date", msg.GetTimeFormat))
+		}
+	}
+	if len(props) > 0 {
+		sorted := append([]string(nil), props...)
+		sort.Strings(sorted)
+		msg.GetProps = strings.Join(uniq(sorted), ", ")
+	}
+	// the objects are listed in the order of their names, after the marker
+	if marker, prefix := msg.GetPageMarker, msg.GetPrefix; marker != "" && prefix != "" &&
+		!strings.HasPrefix(marker, prefix) && marker > prefix {
+		errs = append(errs, fmt.Sprintf("page marker %q is past the prefix %q: the list would be empty", marker, prefix))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid list request: %s", strings.Join(errs, "; "))
+	}
+	return &msg, nil
+}
+
+// Filter returns the entries that pass the time filter (see WithTimeFilter), if any
+func (m *ListMsg) Filter(msg *cmn.GetMsg, entries []*cmn.BucketEntry) []*cmn.BucketEntry {
+	if m.filter == nil {
+		return entries
+	}
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if entry.Type == cmn.BucketEntryDir || m.filter.match(msg.GetTimeFormat, entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+func (f *listTimeFilter) match(layout string, entry *cmn.BucketEntry) bool {
+	s := entry.Ctime
+	if f.prop == cmn.GetPropsAtime {
+		s = entry.Atime
+	}
+	if s == "" {
+		return false
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return false
+	}
+	return (f.after.IsZero() || !t.Before(f.after)) && (f.before.IsZero() || t.Before(f.before))
+}
+
+// hasDate returns true if the time layout includes the date, so that the times can be compared
+func hasDate(layout string) bool {
+	t, err := time.Parse(layout, layoutCheckTime.Format(layout))
+	return err == nil && t.Year() == layoutCheckTime.Year() && t.YearDay() == layoutCheckTime.YearDay()
+}
+
+func uniq(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ListBucket API operation for DFC
+//
+// Lists the bucket as per the request (see NewListMsg): all of its objects or, with the page
+// size, a single page
+func ListBucket(httpClient *http.Client, proxyURL, bucket string, lmsg *ListMsg) (*cmn.BucketList, error) {
+	return ListBucketCtx(context.Background(), httpClient, proxyURL, bucket, lmsg)
+}
+
+// ListBucketCtx is ListBucket with the context for cancellation and deadline
+func ListBucketCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket string, lmsg *ListMsg) (*cmn.BucketList, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).ListBucket(ctx, bucket, lmsg)
+}
+
+// ListBucket is the method counterpart of ListBucket - see NewClient
+func (c *Client) ListBucket(ctx context.Context, bucket string, lmsg *ListMsg) (*cmn.BucketList, error) {
+	msg, err := lmsg.Build()
+	if err != nil {
+		return nil, err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	list := &cmn.BucketList{Entries: make([]*cmn.BucketEntry, 0, cmn.DefaultPageSize)}
+	for {
+		b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActListObjects, Value: msg})
+		if err != nil {
+			return nil, err
+		}
+		if b, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, b); err != nil {
+			return nil, err
+		}
+		page := &cmn.BucketList{}
+		if err = json.Unmarshal(b, page); err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal bucket list, err: %v - [%s]", err, string(b))
+		}
+		list.Entries = append(list.Entries, lmsg.Filter(msg, page.Entries)...)
+		for id, reason := range page.Errors {
+			if list.Errors == nil {
+				list.Errors = make(map[string]string, len(page.Errors))
+			}
+			list.Errors[id] = reason
+		}
+		list.PageMarker = page.PageMarker
+		if page.PageMarker == "" || msg.GetPageSize != 0 {
+			return list, nil
+		}
+		msg.GetPageMarker = page.PageMarker
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestListMsgBuild(t *testing.T) {
+	msg, err := NewListMsg().WithPrefix("train/").WithProps(cmn.GetPropsSize, cmn.GetPropsChecksum, cmn.GetPropsSize).
+		WithPageSize(500).WithMarker("train/0100").WithDelimiter("/").WithTimeFormat("RFC3339").WithStrict().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cmn.GetMsg{GetPrefix: "train/", GetProps: "checksum, size", GetPageSize: 500, GetPageMarker: "train/0100",
+		GetDelimiter: "/", GetTimeFormat: time.RFC3339, GetStrict: true}
+	if *msg != expected {
+		t.Errorf("expected %+v, got %+v", expected, *msg)
+	}
+
+	// the time filter adds the property and the time format
+	if msg, err = NewListMsg().WithTimeFilter(cmn.GetPropsAtime, time.Now(), time.Time{}).Build(); err != nil {
+		t.Fatal(err)
+	}
+	if msg.GetProps != cmn.GetPropsAtime || msg.GetTimeFormat != time.RFC3339Nano {
+		t.Errorf("unexpected %+v", *msg)
+	}
+
+	for _, tc := range []struct {
+		m      *ListMsg
+		errstr string
+	}{
+		{NewListMsg().WithProps("szie"), `unknown property "szie"`},
+		{NewListMsg().WithPageSize(-1), "page size -1"},
+		{NewListMsg().WithPrefix("/train"), "starts with"},
+		{NewListMsg().WithDelimiter(","), "only \"/\""},
+		{NewListMsg().WithTimeFormat("iso"), "no time elements"},
+		{NewListMsg().WithPrefix("a/").WithMarker("b/1"), "past the prefix"},
+		{NewListMsg().WithTimeFilter(cmn.GetPropsSize, time.Now(), time.Time{}), "time filter property"},
+		{NewListMsg().WithTimeFilter(cmn.GetPropsCtime, time.Time{}, time.Time{}), "either time bound"},
+		{NewListMsg().WithTimeFilter(cmn.GetPropsCtime, time.Now(), time.Now().Add(-time.Hour)), "is not before"},
+		{NewListMsg().WithTimeFormat(time.Kitchen).WithTimeFilter(cmn.GetPropsCtime, time.Now(), time.Time{}), "has no date"},
+	} {
+		if _, err := tc.m.Build(); err == nil || !strings.Contains(err.Error(), tc.errstr) {
+			t.Errorf("expected error %q, got %v", tc.errstr, err)
+		}
+	}
+	// all of the errors are reported
+	if _, err := NewListMsg().WithPageSize(0).WithProps("szie").Build(); err == nil || !strings.Contains(err.Error(), "szie") ||
+		!strings.Contains(err.Error(), "page size") {
+		t.Errorf("expected both errors, got %v", err)
+	}
+	// the marker may sort before the prefix
+	if _, err := NewListMsg().WithPrefix("b/").WithMarker("a/1").Build(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestListBucket(t *testing.T) {
+	var (
+		now   = time.Now().UTC().Truncate(time.Second)
+		pages = [][]*cmn.BucketEntry{
+			{{Name: "a", Ctime: now.Add(-2 * time.Hour).Format(time.RFC3339Nano)}, {Name: "b", Ctime: now.Format(time.RFC3339Nano)}},
+			{{Name: "c", Ctime: now.Add(-time.Minute).Format(time.RFC3339Nano)}, {Name: "d"}, {Name: "e/", Type: cmn.BucketEntryDir}},
+		}
+		requests []cmn.GetMsg
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			getMsg cmn.GetMsg
+			msg    = cmn.ActionMsg{Value: &getMsg}
+		)
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil || msg.Action != cmn.ActListObjects {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, getMsg)
+		list := cmn.BucketList{Entries: pages[0], PageMarker: "b"}
+		if getMsg.GetPageMarker == "b" {
+			list = cmn.BucketList{Entries: pages[1]}
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+	defer srv.Close()
+
+	list, err := ListBucket(http.DefaultClient, srv.URL, "bucket",
+		NewListMsg().WithTimeFilter(cmn.GetPropsCtime, now.Add(-time.Hour), time.Time{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(list.Entries))
+	for _, entry := range list.Entries {
+		names = append(names, entry.Name)
+	}
+	if strings.Join(names, ",") != "b,c,e/" || list.PageMarker != "" {
+		t.Errorf("expected b, c, and e/, got %v (marker %q)", names, list.PageMarker)
+	}
+	if len(requests) != 2 || requests[0].GetProps != cmn.GetPropsCtime || requests[1].GetPageMarker != "b" {
+		t.Errorf("unexpected requests %+v", requests)
+	}
+
+	// a single page
+	requests = nil
+	if list, err = ListBucket(http.DefaultClient, srv.URL, "bucket", NewListMsg().WithPageSize(2)); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || len(list.Entries) != 2 || list.PageMarker != "b" {
+		t.Errorf("expected a single page, got %d requests, %d entries, marker %q", len(requests), len(list.Entries), list.PageMarker)
+	}
+
+	// invalid requests are not sent
+	requests = nil
+	if _, err = ListBucket(http.DefaultClient, srv.URL, "bucket", NewListMsg().WithProps("szie")); err == nil || len(requests) != 0 {
+		t.Errorf("expected the invalid request not sent, err: %v", err)
+	}
+}
@@ -6,12 +6,16 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/NVIDIA/dfcpub/cmn"
 )
@@ -22,13 +26,41 @@ type GetObjectInput struct {
 	Writer io.Writer
 	// Map of strings as keys and string slices as values used for url formulation
 	Query url.Values
+	// Progress, if set, is called as the object is received - see ProgressFunc
+	Progress ProgressFunc
+	// MaxRate, if positive, limits the rate at which the object is received, in bytes/s
+	MaxRate int64
+}
+
+// PutObjectInput is used to hold optional parameters for PutObject
+type PutObjectInput struct {
+	// The expected checksum of the object's content: the target computes it while receiving
+	// the object and fails the PUT with 422 (Unprocessable Entity) on mismatch. CksumType is
+	// one of cmn.ChecksumXXHash, cmn.ChecksumCRC32C, cmn.ChecksumMD5, and cmn.ChecksumSHA256;
+	// empty CksumValue is computed from the content prior to sending it
+	CksumType  string
+	CksumValue string
+	// Progress, if set, is called as the object is sent - see ProgressFunc
+	Progress ProgressFunc
+	// MaxRate, if positive, limits the rate at which the object is sent, in bytes/s
+	MaxRate int64
 }
 
 // HeadObject API operation for DFC
 //
 // Returns the size and version of the object specified by bucket/object
 func HeadObject(httpClient *http.Client, proxyURL, bucket, object string) (*cmn.ObjectProps, error) {
-	r, err := httpClient.Head(proxyURL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object))
+	return HeadObjectCtx(context.Background(), httpClient, proxyURL, bucket, object)
+}
+
+// HeadObjectCtx is HeadObject with the context for cancellation and deadline
+func HeadObjectCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, object string) (*cmn.ObjectProps, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).HeadObject(ctx, bucket, object)
+}
+
+// HeadObject is the method counterpart of HeadObject - see NewClient
+func (c *Client) HeadObject(ctx context.Context, bucket, object string) (*cmn.ObjectProps, error) {
+	r, err := doHead(ctx, c.HTTPClient, c.URL+cmn.URLPath(cmn.Version, cmn.Objects, bucket, object))
 	if err != nil {
 		return nil, err
 	}
@@ -48,18 +80,32 @@ func HeadObject(httpClient *http.Client, proxyURL, bucket, object string) (*cmn.
 		return nil, err
 	}
 
-	return &cmn.ObjectProps{
+	props := &cmn.ObjectProps{
 		Size:    size,
 		Version: r.Header.Get(cmn.HeaderVersion),
-	}, nil
+	}
+	if s := r.Header.Get(cmn.HeaderDFCObjAccessCount); s != "" {
+		props.AccessCount, _ = strconv.ParseInt(s, 10, 64)
+	}
+	return props, nil
 }
 
 // DeleteObject API operation for DFC
 //
 // Deletes an object specified by bucket/object
 func DeleteObject(httpClient *http.Client, proxyURL, bucket, object string) (err error) {
-	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
-	_, err = doHTTPRequest(httpClient, http.MethodDelete, url, nil)
+	return DeleteObjectCtx(context.Background(), httpClient, proxyURL, bucket, object)
+}
+
+// DeleteObjectCtx is DeleteObject with the context for cancellation and deadline
+func DeleteObjectCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, object string) (err error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).DeleteObject(ctx, bucket, object)
+}
+
+// DeleteObject is the method counterpart of DeleteObject - see NewClient
+func (c *Client) DeleteObject(ctx context.Context, bucket, object string) (err error) {
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
+	_, err = doHTTPRequest(ctx, c.HTTPClient, http.MethodDelete, url, nil)
 	return err
 }
 
@@ -74,22 +120,56 @@ func DeleteObject(httpClient *http.Client, proxyURL, bucket, object string) (err
 // for copying over the contents from the io.Reader to the io.Writer values.
 // Otherwise, a temporary buffer is allocated in io.CopyBuffer.
 func GetObject(httpClient *http.Client, proxyURL, bucket, object string, options ...GetObjectInput) (n int64, err error) {
+	return GetObjectCtx(context.Background(), httpClient, proxyURL, bucket, object, options...)
+}
+
+// GetObjectCtx is GetObject with the context for cancellation and deadline
+func GetObjectCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, object string, options ...GetObjectInput) (n int64, err error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetObject(ctx, bucket, object, options...)
+}
+
+// GetObject is the method counterpart of GetObject - see NewClient
+func (c *Client) GetObject(ctx context.Context, bucket, object string, options ...GetObjectInput) (n int64, err error) {
+	return c.getObject(ctx, c.URL+cmn.URLPath(cmn.Version, cmn.Objects, bucket, object), options...)
+}
+
+// GetSnapshotObject API operation for DFC
+//
+// Same as GetObject, but reads the object as of the given snapshot of the (local) bucket -
+// see CreateBucketSnapshot.
+func GetSnapshotObject(httpClient *http.Client, proxyURL, bucket, snapshot, object string, options ...GetObjectInput) (n int64, err error) {
+	return GetSnapshotObjectCtx(context.Background(), httpClient, proxyURL, bucket, snapshot, object, options...)
+}
+
+// GetSnapshotObjectCtx is GetSnapshotObject with the context for cancellation and deadline
+func GetSnapshotObjectCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, snapshot, object string,
+	options ...GetObjectInput) (n int64, err error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetSnapshotObject(ctx, bucket, snapshot, object, options...)
+}
+
+// GetSnapshotObject is the method counterpart of GetSnapshotObject - see NewClient
+func (c *Client) GetSnapshotObject(ctx context.Context, bucket, snapshot, object string, options ...GetObjectInput) (n int64, err error) {
+	return c.getObject(ctx, c.URL+cmn.URLPath(cmn.Version, cmn.Snapshots, bucket, snapshot, object), options...)
+}
+
+func (c *Client) getObject(ctx context.Context, reqURL string, options ...GetObjectInput) (n int64, err error) {
 	var (
-		w = ioutil.Discard
-		q url.Values
+		w    = ioutil.Discard
+		q    url.Values
+		opts GetObjectInput
 	)
 	if len(options) != 0 {
-		w, q = getObjectOptParams(options[0])
+		opts = options[0]
+		w, q = getObjectOptParams(opts)
 	}
-	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
-	resp, err := doHTTPRequestGetResp(httpClient, http.MethodGet, url, nil, q)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, reqURL, nil, q)
 	if err != nil {
 		return 0, err
 	}
 	defer resp.Body.Close()
 
 	buf, slab := Mem2.AllocFromSlab2(cmn.DefaultBufSize)
-	n, err = io.CopyBuffer(w, resp.Body, buf)
+	n, err = io.CopyBuffer(w, newTransferReader(ctx, resp.Body, resp.ContentLength, opts.Progress, opts.MaxRate), buf)
 	slab.Free(buf)
 
 	if err != nil {
@@ -109,17 +189,30 @@ func GetObject(httpClient *http.Client, proxyURL, bucket, object string, options
 //
 // Returns InvalidCksumError when the expected and actual checksum values are different.
 func GetObjectWithValidation(httpClient *http.Client, proxyURL, bucket, object string, options ...GetObjectInput) (int64, error) {
+	return GetObjectWithValidationCtx(context.Background(), httpClient, proxyURL, bucket, object, options...)
+}
+
+// GetObjectWithValidationCtx is GetObjectWithValidation with the context for cancellation and deadline
+func GetObjectWithValidationCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, object string, options ...GetObjectInput) (int64, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).GetObjectWithValidation(ctx, bucket, object, options...)
+}
+
+// GetObjectWithValidation is the method counterpart of GetObjectWithValidation - see NewClient
+func (c *Client) GetObjectWithValidation(ctx context.Context,
+	bucket, object string, options ...GetObjectInput) (int64, error) {
 	var (
 		n    int64
 		hash string
 		w    = ioutil.Discard
 		q    url.Values
+		opts GetObjectInput
 	)
 	if len(options) != 0 {
-		w, q = getObjectOptParams(options[0])
+		opts = options[0]
+		w, q = getObjectOptParams(opts)
 	}
-	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
-	resp, err := doHTTPRequestGetResp(httpClient, http.MethodGet, url, nil, q)
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodGet, url, nil, q)
 	if err != nil {
 		return 0, err
 	}
@@ -127,19 +220,199 @@ func GetObjectWithValidation(httpClient *http.Client, proxyURL, bucket, object s
 	hdrHash := resp.Header.Get(cmn.HeaderDFCChecksumVal)
 	hdrHashType := resp.Header.Get(cmn.HeaderDFCChecksumType)
 
-	if hdrHashType == cmn.ChecksumXXHash {
+	if h := cmn.NewCksumHash(hdrHashType); h != nil {
 		buf, slab := Mem2.AllocFromSlab2(cmn.DefaultBufSize)
-		n, hash, err = cmn.ReadWriteWithHash(resp.Body, w, buf)
+		r := newTransferReader(ctx, resp.Body, resp.ContentLength, opts.Progress, opts.MaxRate)
+		n, err = io.CopyBuffer(io.MultiWriter(h, w), r, buf)
 		slab.Free(buf)
 
 		if err != nil {
-			return 0, fmt.Errorf("Failed to calculate xxHash from HTTP response body, err: %v", err)
+			return 0, fmt.Errorf("Failed to calculate %s from HTTP response body, err: %v", hdrHashType, err)
 		}
-		if hash != hdrHash {
+		if hash = cmn.CksumHex(h); hash != hdrHash {
 			return 0, cmn.NewInvalidCksumError(hdrHash, hash)
 		}
 	} else {
-		return 0, fmt.Errorf("Can't validate hash types other than %s, object's hash type: %s", cmn.ChecksumXXHash, hdrHashType)
+		return 0, fmt.Errorf("Can't validate hash types other than %s and %s, object's hash type: %s",
+			cmn.ChecksumXXHash, cmn.ChecksumCRC32C, hdrHashType)
+	}
+	return n, nil
+}
+
+// PutObject API operation for DFC
+//
+// PUTs the object, optionally verified by its checksum - see PutObjectInput
+func PutObject(httpClient *http.Client, proxyURL, bucket, object string, b []byte, options ...PutObjectInput) error {
+	return PutObjectCtx(context.Background(), httpClient, proxyURL, bucket, object, b, options...)
+}
+
+// PutObjectCtx is PutObject with the context for cancellation and deadline
+func PutObjectCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, object string, b []byte,
+	options ...PutObjectInput) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).PutObject(ctx, bucket, object, b, options...)
+}
+
+// PutObject is the method counterpart of PutObject - see NewClient
+func (c *Client) PutObject(ctx context.Context, bucket, object string, b []byte, options ...PutObjectInput) error {
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("Failed to create request, err: %v", err)
+	}
+	var opts PutObjectInput
+	if len(options) != 0 {
+		opts = options[0]
+	}
+	if opts.Progress != nil || opts.MaxRate > 0 {
+		setPutBody(ctx, req, b, opts.Progress, opts.MaxRate)
+	}
+	if opts.CksumType != "" {
+		cksumType, cksumValue := opts.CksumType, opts.CksumValue
+		if cksumValue == "" {
+			if cksumValue, err = computeCksum(cksumType, b); err != nil {
+				return err
+			}
+		}
+		req.Header.Set(cmn.HeaderDFCChecksumType, cksumType)
+		req.Header.Set(cmn.HeaderDFCChecksumVal, cksumValue)
+	}
+	resp, err := c.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("Failed to PUT, err: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return httpError(resp, b)
+	}
+	return nil
+}
+
+// PutGroupObject API operation for DFC
+//
+// PUTs an object within the group opened by BeginPutGroup
+func PutGroupObject(httpClient *http.Client, proxyURL, bucket, object, groupID string, b []byte) error {
+	return PutGroupObjectCtx(context.Background(), httpClient, proxyURL, bucket, object, groupID, b)
+}
+
+// PutGroupObjectCtx is PutGroupObject with the context for cancellation and deadline
+func PutGroupObjectCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, object, groupID string, b []byte) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).PutGroupObject(ctx, bucket, object, groupID, b)
+}
+
+// PutGroupObject is the method counterpart of PutGroupObject - see NewClient
+func (c *Client) PutGroupObject(ctx context.Context, bucket, object, groupID string, b []byte) error {
+	query := url.Values{cmn.URLParamPutGroup: []string{groupID}}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodPut, url, b, query)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// PutObjectOnce API operation for DFC
+//
+// PUTs the object with the idempotency key: the PUT can be retried with the same key
+// (e.g., upon a timeout) and the cluster executes it at most once
+func PutObjectOnce(httpClient *http.Client, proxyURL, bucket, object, key string, b []byte) error {
+	return PutObjectOnceCtx(context.Background(), httpClient, proxyURL, bucket, object, key, b)
+}
+
+// PutObjectOnceCtx is PutObjectOnce with the context for cancellation and deadline
+func PutObjectOnceCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, object, key string, b []byte) error {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).PutObjectOnce(ctx, bucket, object, key, b)
+}
+
+// PutObjectOnce is the method counterpart of PutObjectOnce - see NewClient
+func (c *Client) PutObjectOnce(ctx context.Context, bucket, object, key string, b []byte) error {
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("Failed to create request, err: %v", err)
+	}
+	req.Header.Set(cmn.HeaderDFCIdempotencyKey, key)
+	resp, err := c.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("Failed to PUT, err: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return httpError(resp, b)
+	}
+	return nil
+}
+
+// SelectObject API operation for DFC
+//
+// Streams the rows (and, optionally, only the fields) of the CSV or JSON-lines object
+// that match the select message. The target filters the object in place, so that only
+// the selected data is transferred. Writes the result to w and returns its length.
+func SelectObject(httpClient *http.Client, proxyURL, bucket, object string, msg cmn.SelectMsg, w io.Writer) (int64, error) {
+	return SelectObjectCtx(context.Background(), httpClient, proxyURL, bucket, object, msg, w)
+}
+
+// SelectObjectCtx is SelectObject with the context for cancellation and deadline
+func SelectObjectCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, object string, msg cmn.SelectMsg, w io.Writer) (int64, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).SelectObject(ctx, bucket, object, msg, w)
+}
+
+// SelectObject is the method counterpart of SelectObject - see NewClient
+func (c *Client) SelectObject(ctx context.Context, bucket, object string, msg cmn.SelectMsg, w io.Writer) (int64, error) {
+	b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActSelect, Value: msg})
+	if err != nil {
+		return 0, err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
+	resp, err := doHTTPRequestGetResp(ctx, c.HTTPClient, http.MethodPost, url, b)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	buf, slab := Mem2.AllocFromSlab2(cmn.DefaultBufSize)
+	n, err := io.CopyBuffer(w, resp.Body, buf)
+	slab.Free(buf)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to Copy HTTP response body, err: %v", err)
 	}
 	return n, nil
 }
+
+// PresignObject API operation for DFC
+//
+// Returns the object's URL that allows the given method (GET - and HEAD - or PUT) with
+// no credentials until it expires, e.g. for a browser to read or write the object directly.
+// The expiration is up to the cluster's presign.max_expiry, which is also the default (zero).
+func PresignObject(httpClient *http.Client, proxyURL, bucket, object, method string, expires time.Duration) (*cmn.PresignedURL, error) {
+	return PresignObjectCtx(context.Background(), httpClient, proxyURL, bucket, object, method, expires)
+}
+
+// PresignObjectCtx is PresignObject with the context for cancellation and deadline
+func PresignObjectCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, object, method string, expires time.Duration) (*cmn.PresignedURL, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).PresignObject(ctx, bucket, object, method, expires)
+}
+
+// PresignObject is the method counterpart of PresignObject - see NewClient
+func (c *Client) PresignObject(ctx context.Context, bucket, object, method string, expires time.Duration) (*cmn.PresignedURL, error) {
+	psmsg := cmn.PresignMsg{Method: method}
+	if expires > 0 {
+		psmsg.Expires = expires.String()
+	}
+	msg, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActPresign, Value: psmsg})
+	if err != nil {
+		return nil, err
+	}
+	url := c.URL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
+	b, err := doHTTPRequest(ctx, c.HTTPClient, http.MethodPost, url, msg)
+	if err != nil {
+		return nil, err
+	}
+	presigned := &cmn.PresignedURL{}
+	if err = json.Unmarshal(b, presigned); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal presigned URL, err: %v - [%s]", err, string(b))
+	}
+	return presigned, nil
+}
@@ -0,0 +1,260 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// syncWorkSuffix is the suffix of the files being downloaded by SyncPrefixToDir
+const syncWorkSuffix = ".dfcsync"
+
+// SyncOptions are the optional parameters of SyncPrefixToDir
+type SyncOptions struct {
+	// Parallel is the number of objects fetched concurrently; defaults to 4
+	Parallel int
+	// NoDelete keeps the local files of the objects that are no longer in the bucket
+	NoDelete bool
+}
+
+// SyncResult is the outcome of SyncPrefixToDir
+type SyncResult struct {
+	Fetched     int64 // new and changed objects downloaded
+	FetchedSize int64
+	Unchanged   int64
+	Deleted     int64 // local files of the objects that are no longer in the bucket
+}
+
+// SyncPrefixToDir API operation for DFC
+//
+// Mirrors the bucket's objects with the given name prefix to localDir: the object "a/b/c"
+// is stored as localDir/a/b/c. New objects and objects whose checksums differ from the
+// local files are downloaded (and validated), and - unless opts.NoDelete - the local files
+// under the prefix whose objects are no longer in the bucket are removed.
+//
+// The objects are downloaded to temporary files that are renamed into place once complete,
+// so that an interrupted sync can simply be run again: the files that are already in sync
+// are skipped. Objects without the cluster's checksums (e.g., Cloud objects that are not
+// cached) are always downloaded. The sync does not stop upon the first failed object -
+// the number of failures and the first error are returned along with the partial result.
+func SyncPrefixToDir(httpClient *http.Client, proxyURL, bucket, prefix, localDir string, opts ...SyncOptions) (*SyncResult, error) {
+	return SyncPrefixToDirCtx(context.Background(), httpClient, proxyURL, bucket, prefix, localDir, opts...)
+}
+
+// SyncPrefixToDirCtx is SyncPrefixToDir with the context for cancellation and deadline
+func SyncPrefixToDirCtx(ctx context.Context, httpClient *http.Client, proxyURL, bucket, prefix, localDir string,
+	opts ...SyncOptions) (*SyncResult, error) {
+	return (&Client{HTTPClient: httpClient, URL: proxyURL}).SyncPrefixToDir(ctx, bucket, prefix, localDir, opts...)
+}
+
+// SyncPrefixToDir is the method counterpart of SyncPrefixToDir - see NewClient
+func (c *Client) SyncPrefixToDir(ctx context.Context,
+	bucket, prefix, localDir string, opts ...SyncOptions) (*SyncResult, error) {
+	var opt SyncOptions
+	if len(opts) != 0 {
+		opt = opts[0]
+	}
+	if opt.Parallel <= 0 {
+		opt.Parallel = 4
+	}
+	// strict: a partial list would delete the local files of the objects that were not listed
+	msg := &cmn.GetMsg{GetPrefix: prefix, GetProps: cmn.GetPropsSize, GetStrict: true}
+	entries, err := listBucket(ctx, c.HTTPClient, c.URL, bucket, msg)
+	if err != nil {
+		return nil, err
+	}
+	cksums, err := GetChecksumsCtx(ctx, c.HTTPClient, c.URL, bucket, nil, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(localDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var (
+		res     = &SyncResult{}
+		objects = make(map[string]bool, len(entries))
+		work    = make(chan string, opt.Parallel)
+		wg      = &sync.WaitGroup{}
+		mtx     sync.Mutex
+		failed  int
+		errFail error
+		root    = filepath.Clean(localDir) + string(filepath.Separator)
+	)
+	fail := func(objname string, err error) {
+		mtx.Lock()
+		if failed == 0 {
+			errFail = fmt.Errorf("%s: %v", objname, err)
+		}
+		failed++
+		mtx.Unlock()
+	}
+	for i := 0; i < opt.Parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for objname := range work {
+				cksum := cksums[objname]
+				fqn := filepath.Join(localDir, filepath.FromSlash(objname))
+				if !strings.HasPrefix(fqn, root) {
+					fail(objname, fmt.Errorf("name escapes %s", localDir))
+					continue
+				}
+				if localInSync(fqn, cksum) {
+					mtx.Lock()
+					res.Unchanged++
+					mtx.Unlock()
+					continue
+				}
+				n, err := syncObject(ctx, c.HTTPClient, c.URL, bucket, objname, fqn, cksum)
+				if err != nil {
+					fail(objname, err)
+					continue
+				}
+				mtx.Lock()
+				res.Fetched++
+				res.FetchedSize += n
+				mtx.Unlock()
+			}
+		}()
+	}
+	for _, entry := range entries {
+		if entry.Status != "" || entry.Type == "directory" || strings.HasSuffix(entry.Name, "/") {
+			continue
+		}
+		objects[entry.Name] = true
+		select {
+		case work <- entry.Name:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(work)
+	wg.Wait()
+	if err = ctx.Err(); err != nil {
+		return res, err
+	}
+
+	if !opt.NoDelete {
+		err = filepath.Walk(localDir, func(fqn string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(localDir, fqn)
+			if err != nil {
+				return err
+			}
+			objname := filepath.ToSlash(rel)
+			if strings.HasSuffix(objname, syncWorkSuffix) {
+				objname = strings.TrimSuffix(objname, syncWorkSuffix) // leftover of an interrupted sync
+				if strings.HasPrefix(objname, prefix) {
+					return os.Remove(fqn)
+				}
+				return nil
+			}
+			if !strings.HasPrefix(objname, prefix) || objects[objname] {
+				return nil
+			}
+			if err := os.Remove(fqn); err != nil {
+				return err
+			}
+			res.Deleted++
+			return nil
+		})
+		if err != nil {
+			return res, fmt.Errorf("Failed to remove deleted objects from %s, err: %v", localDir, err)
+		}
+	}
+	if failed > 0 {
+		return res, fmt.Errorf("Failed to sync %d object(s), first error: %v", failed, errFail)
+	}
+	return res, nil
+}
+
+// localInSync returns true if the local file has the object's size and checksum
+func localInSync(fqn string, cksum *cmn.ObjChecksum) bool {
+	if cksum == nil || !cmn.ValidCksumType(cksum.Type) || cksum.Value == "" {
+		return false
+	}
+	fi, err := os.Stat(fqn)
+	if err != nil || fi.Size() != cksum.Size {
+		return false
+	}
+	file, err := os.Open(fqn)
+	if err != nil {
+		return false
+	}
+	buf, slab := Mem2.AllocFromSlab2(cmn.DefaultBufSize)
+	hash, errstr := cmn.ComputeCksum(cksum.Type, file, buf)
+	slab.Free(buf)
+	file.Close()
+	return errstr == "" && hash == cksum.Value
+}
+
+// syncObject downloads the object to the work file and renames it into place
+func syncObject(ctx context.Context, httpClient *http.Client, proxyURL, bucket, objname, fqn string,
+	cksum *cmn.ObjChecksum) (n int64, err error) {
+	if err = os.MkdirAll(filepath.Dir(fqn), 0755); err != nil {
+		return
+	}
+	workfqn := fqn + syncWorkSuffix
+	file, err := os.Create(workfqn)
+	if err != nil {
+		return
+	}
+	if cksum != nil && cmn.ValidCksumType(cksum.Type) {
+		n, err = GetObjectWithValidationCtx(ctx, httpClient, proxyURL, bucket, objname, GetObjectInput{Writer: file})
+	} else {
+		n, err = GetObjectCtx(ctx, httpClient, proxyURL, bucket, objname, GetObjectInput{Writer: file})
+	}
+	if errc := file.Close(); err == nil {
+		err = errc
+	}
+	if err == nil {
+		err = os.Rename(workfqn, fqn)
+	}
+	if err != nil {
+		os.Remove(workfqn)
+	}
+	return
+}
+
+// listBucket returns all entries of the bucket's list, page by page
+func listBucket(ctx context.Context, httpClient *http.Client, proxyURL, bucket string, msg *cmn.GetMsg) ([]*cmn.BucketEntry, error) {
+	var (
+		entries = make([]*cmn.BucketEntry, 0, cmn.DefaultPageSize)
+		url     = proxyURL + cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+	)
+	for {
+		b, err := json.Marshal(cmn.ActionMsg{Action: cmn.ActListObjects, Value: msg})
+		if err != nil {
+			return nil, err
+		}
+		if b, err = doHTTPRequest(ctx, httpClient, http.MethodPost, url, b); err != nil {
+			return nil, err
+		}
+		page := &cmn.BucketList{}
+		if err = json.Unmarshal(b, page); err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal bucket list, err: %v - [%s]", err, string(b))
+		}
+		entries = append(entries, page.Entries...)
+		if page.PageMarker == "" {
+			return entries, nil
+		}
+		msg.GetPageMarker = page.PageMarker
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestSyncPrefixToDir(t *testing.T) {
+	var (
+		objects = map[string]string{"ds/a": "aaa", "ds/sub/b": "bbbb", "other/c": "c"}
+		gets    int32
+	)
+	cksum := func(content string) string {
+		hash, _ := cmn.ComputeXXHash(strings.NewReader(content), nil)
+		return hash
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			objname := strings.TrimPrefix(r.URL.Path, cmn.URLPath(cmn.Version, cmn.Objects, "b")+"/")
+			content, ok := objects[objname]
+			if !ok {
+				cmn.InvalidHandlerWithMsg(w, r, "not found", http.StatusNotFound)
+				return
+			}
+			atomic.AddInt32(&gets, 1)
+			w.Header().Set(cmn.HeaderDFCChecksumType, cmn.ChecksumXXHash)
+			w.Header().Set(cmn.HeaderDFCChecksumVal, cksum(content))
+			w.Write([]byte(content))
+			return
+		}
+		var msg cmn.ActionMsg
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &msg)
+		vb, _ := json.Marshal(msg.Value)
+		switch msg.Action {
+		case cmn.ActListObjects:
+			var getMsg cmn.GetMsg
+			json.Unmarshal(vb, &getMsg)
+			if !getMsg.GetStrict {
+				t.Error("expected strict list")
+			}
+			list := &cmn.BucketList{}
+			for name, content := range objects {
+				if strings.HasPrefix(name, getMsg.GetPrefix) {
+					list.Entries = append(list.Entries, &cmn.BucketEntry{Name: name, Size: int64(len(content))})
+				}
+			}
+			b, _ = json.Marshal(list)
+		case cmn.ActChecksums:
+			var csmsg cmn.ChecksumsMsg
+			json.Unmarshal(vb, &csmsg)
+			cksums := make(cmn.ObjChecksums)
+			for name, content := range objects {
+				if strings.HasPrefix(name, csmsg.Prefix) {
+					cksums[name] = &cmn.ObjChecksum{Type: cmn.ChecksumXXHash, Value: cksum(content), Size: int64(len(content))}
+				}
+			}
+			b, _ = json.Marshal(cksums)
+		}
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	read := func(name string) string {
+		b, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return string(b)
+	}
+	write := func(name, content string) {
+		fqn := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(fqn), 0755)
+		if err := ioutil.WriteFile(fqn, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// initial sync; a stale file and a leftover of an interrupted sync are removed
+	write("ds/gone", "x")
+	write("ds/sub/b"+syncWorkSuffix, "bb")
+	write("other/keep", "y")
+	res, err := SyncPrefixToDir(http.DefaultClient, srv.URL, "b", "ds/", dir, SyncOptions{Parallel: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Fetched != 2 || res.FetchedSize != 7 || res.Deleted != 1 || res.Unchanged != 0 {
+		t.Errorf("unexpected %+v", res)
+	}
+	if read("ds/a") != "aaa" || read("ds/sub/b") != "bbbb" || read("other/keep") != "y" {
+		t.Errorf("unexpected content of %s", dir)
+	}
+	for _, name := range []string{"ds/gone", "ds/sub/b" + syncWorkSuffix} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s removed, err %v", name, err)
+		}
+	}
+
+	// resync: only the changed object is fetched
+	objects["ds/a"] = "AAAA"
+	gets = 0
+	if res, err = SyncPrefixToDir(http.DefaultClient, srv.URL, "b", "ds/", dir); err != nil {
+		t.Fatal(err)
+	}
+	if res.Fetched != 1 || res.Unchanged != 1 || gets != 1 || read("ds/a") != "AAAA" {
+		t.Errorf("unexpected %+v (%d GETs)", res, gets)
+	}
+
+	// failures do not stop the sync
+	objects["ds/../../escape"] = "z"
+	if res, err = SyncPrefixToDir(http.DefaultClient, srv.URL, "b", "ds/", dir); err == nil {
+		t.Fatal("expected error")
+	}
+	if res == nil || res.Unchanged != 2 || !strings.Contains(err.Error(), "escape") {
+		t.Errorf("unexpected %+v, err %v", res, err)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// ProgressFunc is called as the object's content is transferred, with the number of bytes
+// transferred so far and the total - the object size, or -1 if unknown. When the request is
+// redirected or retried, the content is transferred again and the count restarts from zero.
+type ProgressFunc func(transferred, total int64)
+
+// rateChunk is the most that a rate-limited transfer reads at once, to keep it smooth
+const rateChunk = 32 * 1024
+
+// transferReader reports the progress of the transfer and limits its rate (bytes/s) - see
+// GetObjectInput and PutObjectInput
+type transferReader struct {
+	ctx      context.Context
+	r        io.Reader
+	progress ProgressFunc
+	rate     int64
+	total, n int64
+	started  time.Time
+}
+
+func newTransferReader(ctx context.Context, r io.Reader, total int64, progress ProgressFunc, rate int64) io.Reader {
+	if progress == nil && rate <= 0 {
+		return r
+	}
+	return &transferReader{ctx: ctx, r: r, progress: progress, rate: rate, total: total}
+}
+
+func (tr *transferReader) Read(p []byte) (n int, err error) {
+	if tr.rate > 0 {
+		if tr.started.IsZero() {
+			tr.started = time.Now()
+		}
+		if len(p) > rateChunk {
+			p = p[:rateChunk]
+		}
+	}
+	n, err = tr.r.Read(p)
+	if n == 0 {
+		return
+	}
+	tr.n += int64(n)
+	if tr.rate > 0 {
+		due := time.Duration(tr.n * int64(time.Second) / tr.rate)
+		if wait := due - time.Since(tr.started); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-tr.ctx.Done():
+				timer.Stop()
+				return n, tr.ctx.Err()
+			}
+		}
+	}
+	if tr.progress != nil {
+		tr.progress(tr.n, tr.total)
+	}
+	return
+}
+
+// setPutBody sets the request's body to the content read via transferReader, anew each
+// time the body is (re)sent - e.g., upon the proxy's redirect to the target
+func setPutBody(ctx context.Context, req *http.Request, b []byte, progress ProgressFunc, rate int64) {
+	total := int64(len(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(newTransferReader(ctx, bytes.NewReader(b), total, progress, rate)), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = total
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPutObjectProgress(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 100*1024)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if !bytes.Equal(b, content) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer target.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	defer proxy.Close()
+
+	var last, total int64
+	progress := func(transferred, size int64) { last, total = transferred, size }
+	err := PutObject(http.DefaultClient, proxy.URL, "bucket", "object", content, PutObjectInput{Progress: progress})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("expected progress %d/%d, got %d/%d", len(content), len(content), last, total)
+	}
+}
+
+func TestGetObjectRateLimit(t *testing.T) {
+	const size = 64 * 1024
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, size))
+	}))
+	defer srv.Close()
+
+	var calls int
+	opts := GetObjectInput{MaxRate: 256 * 1024, Progress: func(transferred, total int64) { calls++ }}
+	started := time.Now()
+	n, err := GetObject(http.DefaultClient, srv.URL, "bucket", "object", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != size {
+		t.Errorf("expected %d bytes, got %d", size, n)
+	}
+	// 64K at 256K/s takes at least 250ms
+	if elapsed := time.Since(started); elapsed < 200*time.Millisecond {
+		t.Errorf("expected the transfer limited to %d bytes/s, took %v", opts.MaxRate, elapsed)
+	}
+	if calls == 0 {
+		t.Error("expected progress to be reported")
+	}
+}
@@ -7,13 +7,21 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
+	"github.com/NVIDIA/dfcpub/cmn"
 	"github.com/NVIDIA/dfcpub/memsys"
 )
 
@@ -27,8 +35,8 @@ func init() {
 	go Mem2.Run()
 }
 
-func doHTTPRequest(httpClient *http.Client, method, url string, b []byte) ([]byte, error) {
-	resp, err := doHTTPRequestGetResp(httpClient, method, url, b)
+func doHTTPRequest(ctx context.Context, httpClient *http.Client, method, url string, b []byte) ([]byte, error) {
+	resp, err := doHTTPRequestGetResp(ctx, httpClient, method, url, b)
 	if err != nil {
 		return nil, err
 	}
@@ -36,15 +44,15 @@ func doHTTPRequest(httpClient *http.Client, method, url string, b []byte) ([]byt
 	return ioutil.ReadAll(resp.Body)
 }
 
-func doHTTPRequestGetResp(httpClient *http.Client, method, url string, b []byte, query ...url.Values) (*http.Response, error) {
+func doHTTPRequestGetResp(ctx context.Context, httpClient *http.Client, method, url string, b []byte, query ...url.Values) (*http.Response, error) {
 	req, err := http.NewRequest(method, url, bytes.NewBuffer(b))
-	if len(query) > 0 && len(query[0]) > 0 {
-		req.URL.RawQuery = query[0].Encode()
-	}
-
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create request, err: %v", err)
 	}
+	if len(query) > 0 && len(query[0]) > 0 {
+		req.URL.RawQuery = query[0].Encode()
+	}
+	req = req.WithContext(ctx)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
@@ -57,12 +65,35 @@ func doHTTPRequestGetResp(httpClient *http.Client, method, url string, b []byte,
 			return nil, fmt.Errorf("Failed to read response, err: %v", err)
 		}
 
-		return nil, fmt.Errorf("HTTP error = %d, message = %s", resp.StatusCode, string(b))
+		return nil, httpError(resp, b)
 	}
 	return resp, nil
 }
 
+// httpError returns the error response as *cmn.HTTPError if the cluster has returned it as
+// such (REST API v2 - see NewBaseParams), and as a plain error otherwise
+func httpError(resp *http.Response, b []byte) error {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		herr := &cmn.HTTPError{}
+		if err := json.Unmarshal(b, herr); err == nil && herr.Status != 0 {
+			return herr
+		}
+	}
+	return fmt.Errorf("HTTP error = %d, message = %s", resp.StatusCode, string(b))
+}
+
+// doHead is http.Client.Head with the context; unlike doHTTPRequestGetResp, leaves it to the
+// caller to handle HTTP errors
+func doHead(ctx context.Context, httpClient *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return httpClient.Do(req.WithContext(ctx))
+}
+
 func getObjectOptParams(options GetObjectInput) (w io.Writer, q map[string][]string) {
+	w = ioutil.Discard
 	if options.Writer != nil {
 		w = options.Writer
 	}
@@ -71,3 +102,24 @@ func getObjectOptParams(options GetObjectInput) (w io.Writer, q map[string][]str
 	}
 	return
 }
+
+// computeCksum returns the hex checksum of the PUT content - see PutObjectInput
+func computeCksum(cksumType string, b []byte) (string, error) {
+	switch cksumType {
+	case cmn.ChecksumXXHash, cmn.ChecksumCRC32C:
+		buf, slab := Mem2.AllocFromSlab2(cmn.DefaultBufSize)
+		defer slab.Free(buf)
+		cksum, errstr := cmn.ComputeCksum(cksumType, bytes.NewReader(b), buf)
+		if errstr != "" {
+			return "", errors.New(errstr)
+		}
+		return cksum, nil
+	case cmn.ChecksumMD5:
+		sum := md5.Sum(b)
+		return hex.EncodeToString(sum[:]), nil
+	case cmn.ChecksumSHA256:
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	return "", fmt.Errorf("Unsupported checksum type %q", cksumType)
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestContextCancel(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := GetObjectCtx(ctx, http.DefaultClient, srv.URL, "bucket", "object"); err == nil {
+		t.Fatal("expected the request to fail upon the deadline")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("expected the request canceled upon the deadline, took %v", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := HeadBucketCtx(ctx, http.DefaultClient, srv.URL, "bucket"); err == nil {
+		t.Error("expected the request with the canceled context to fail")
+	}
+}
+
+func TestPutObjectCksum(t *testing.T) {
+	content := []byte("the quick brown fox")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		sum := sha256.Sum256(b)
+		if r.Header.Get(cmn.HeaderDFCChecksumType) != cmn.ChecksumSHA256 ||
+			r.Header.Get(cmn.HeaderDFCChecksumVal) != hex.EncodeToString(sum[:]) {
+			http.Error(w, "Bad checksum", http.StatusUnprocessableEntity)
+		}
+	}))
+	defer srv.Close()
+
+	opts := PutObjectInput{CksumType: cmn.ChecksumSHA256}
+	if err := PutObject(http.DefaultClient, srv.URL, "bucket", "object", content, opts); err != nil {
+		t.Fatalf("expected the checksum computed and sent, err: %v", err)
+	}
+	opts.CksumValue = strings.Repeat("0", 64)
+	err := PutObject(http.DefaultClient, srv.URL, "bucket", "object", content, opts)
+	if err == nil || !strings.Contains(err.Error(), "422") {
+		t.Errorf("expected 422, got %v", err)
+	}
+	if err = PutObject(http.DefaultClient, srv.URL, "bucket", "object", content, PutObjectInput{CksumType: "sha1"}); err == nil {
+		t.Error("expected the unsupported checksum type to fail")
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// BaseParams are the parameters of the api calls that do not change from one call to
+// another: pass Client and URL to the api functions. Version is the REST API version
+// negotiated with the cluster - see NewBaseParams
+type BaseParams struct {
+	Client  *http.Client
+	URL     string
+	Version string
+}
+
+// versionTransport marks each request with the REST API version (cmn.HeaderDFCAPIVersion)
+type versionTransport struct {
+	base    http.RoundTripper
+	version string
+}
+
+func (t *versionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := *req // RoundTrip must not modify the request
+	r.Header = cloneHeader(req.Header)
+	r.Header.Set(cmn.HeaderDFCAPIVersion, t.version)
+	return t.base.RoundTrip(&r)
+}
+
+func cloneHeader(h http.Header) http.Header {
+	clone := make(http.Header, len(h)+1)
+	for k, v := range h {
+		clone[k] = append([]string(nil), v...)
+	}
+	return clone
+}
+
+// NewBaseParams negotiates the REST API version with the cluster - the latest version
+// supported by both the cluster and this package, or cmn.Version (v1) if the cluster
+// predates versioning. With cmn.VersionV2, the returned Client has all requests (including
+// the ones that follow redirects) served as per v2. The api functions return the cluster's
+// errors as *cmn.HTTPError, unless the cluster predates JSON errors
+func NewBaseParams(httpClient *http.Client, proxyURL string) (*BaseParams, error) {
+	return NewBaseParamsCtx(context.Background(), httpClient, proxyURL)
+}
+
+// NewBaseParamsCtx is NewBaseParams with the context for cancellation and deadline
+func NewBaseParamsCtx(ctx context.Context, httpClient *http.Client, proxyURL string) (*BaseParams, error) {
+	bp := &BaseParams{Client: httpClient, URL: proxyURL, Version: cmn.Version}
+	url := proxyURL + cmn.URLPath(cmn.Version, cmn.Daemon) + "?" + cmn.URLParamWhat + "=" + cmn.GetWhatVersions
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to negotiate API version, err: %v", err)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read response, err: %v", err)
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		return bp, nil // unrecognized "what": v1 only
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, httpError(resp, b)
+	}
+	versions := make([]string, 0, 2)
+	if err = json.Unmarshal(b, &versions); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal API versions, err: %v - [%s]", err, string(b))
+	}
+	for _, v := range versions {
+		if v == cmn.VersionV2 {
+			bp.Version = v
+			bp.Client = withAPIVersion(httpClient, v)
+		}
+	}
+	return bp, nil
+}
+
+// withAPIVersion returns a copy of the client that marks each request with the version
+func withAPIVersion(httpClient *http.Client, version string) *http.Client {
+	client := *httpClient
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &versionTransport{base: base, version: version}
+	return &client
+}
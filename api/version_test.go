@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	var versioned bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get(cmn.URLParamWhat) == cmn.GetWhatVersions {
+			if !versioned {
+				cmn.InvalidHandlerWithMsg(w, r, "unrecognized what")
+				return
+			}
+			w.Write([]byte(`["v1","v2"]`))
+			return
+		}
+		if !versioned {
+			http.Error(w, "no such bucket", http.StatusNotFound) // as of old
+			return
+		}
+		cmn.InvalidHandlerWithMsg(w, r, "no such bucket", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	// the cluster that predates versioning: v1, plain errors
+	bp, err := NewBaseParams(http.DefaultClient, srv.URL)
+	if err != nil || bp.Version != cmn.Version || bp.Client != http.DefaultClient {
+		t.Fatalf("expected v1, got %+v (err %v)", bp, err)
+	}
+	if _, err = GetChecksums(bp.Client, bp.URL, "nobucket", nil, ""); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := err.(*cmn.HTTPError); ok {
+		t.Errorf("expected plain error, got %#v", err)
+	}
+
+	// v2: typed errors
+	versioned = true
+	if bp, err = NewBaseParams(http.DefaultClient, srv.URL); err != nil || bp.Version != cmn.VersionV2 {
+		t.Fatalf("expected v2, got %+v (err %v)", bp, err)
+	}
+	_, err = GetChecksums(bp.Client, bp.URL, "nobucket", nil, "")
+	herr, ok := err.(*cmn.HTTPError)
+	if !ok {
+		t.Fatalf("expected *cmn.HTTPError, got %#v", err)
+	}
+	if herr.Status != http.StatusNotFound || herr.Message != "no such bucket" || herr.Method != http.MethodPost {
+		t.Errorf("unexpected %+v", herr)
+	}
+}
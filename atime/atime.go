@@ -5,13 +5,18 @@
 package atime
 
 import (
+	"container/heap"
 	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
 	"github.com/NVIDIA/dfcpub/cmn"
 	"github.com/NVIDIA/dfcpub/fs"
 	"github.com/NVIDIA/dfcpub/ios"
+	"github.com/NVIDIA/dfcpub/stats"
 )
 
 // ================================ Summary ===============================================
@@ -19,22 +24,30 @@ import (
 // The atime (access time) module provides atime.Runner - a long running task with the
 // purpose of updating object access times. The work is performed on a per local
 // filesystem bases, via mpathAtimeRunners (children). The atime.Runner's main responsibility
-// is to dispatch requests to the corresponding mpathAtimeRunner instance.
+// is to route requests to the corresponding mpathAtimeRunner instance.
 //
 // API exposed to the rest of the code includes the following operations:
 //
 //   * Run      - to run
-//   * Stop     - to stop
+//   * Stop     - to stop (with atime_drain - after flushing all cached access times)
 //   * Touch    - to request an access time update for a specified object
+//   * TouchRelaxed - ditto, unless the object has been accessed recently
 //   * Atime    - to request the most recent access time of a given object
-// The Touch and Atime requests are added to the request queue
-// and then are dispatched to the mpathAtimeRunner for a given filesystem.
+//   * AtimeFallback - ditto, falling back to (and caching) the access time on disk
+//   * AtimeBatch - ditto, for many objects at once
+//   * HotObjects - to request the most frequently accessed objects of a mountpath
+//   * AccessCount - to request the (approximate) number of accesses of a given object
+// The Touch and Atime requests are served by the caller's goroutine: each mpathAtimeRunner
+// keeps its maps in atimeShards shards, each with its own lock, so that concurrent requests
+// for different objects rarely contend. Only flushing (as well as the decay of the heat map
+// and the journal's sync and compaction) is done asynchronously, by the mpathAtimeRunner's
+// goroutine.
 //
 // Note: atime.Runner assumes that object in question either belongs to a
 // bucket that has LRU enabled or LRU is enabled through the global config when bucket properties
 // are not present. Thus, it is the responsibility of the caller to ensure
 // that LRU is enabled. Although this check is not necessary for the Atime method (a zero-valued
-// Response will be returned because it will not exist in any mpathAtimeRunner's atimemap),
+// Response will be returned because it will not exist in any mpathAtimeRunner's atime map),
 // it is recommended to do this check.
 //
 // The remaining operations are private to the atime.Runner and used only internally.
@@ -43,8 +56,17 @@ import (
 // that keeps track of object access times. Every so often atime.Runner
 // calls mpathAtimeRunners to flush access time maps.  Access times get flushed to
 // the disk when the number of stored access times reaches a certain threshold and when:
-//   * disk utilization is low, or
+//   * disk utilization - averaged over the recent iostat reports - is low, or
 //   * access time map is filled over a certain point (watermark)
+// With atime_flush.rate, the access times are written at most that many per second per
+// mountpath, in small batches between the requests.
+// In addition to the access times, each mpathAtimeRunner keeps a heat map: the number of
+// accesses (touches) of each object, halved every heatDecayTime - so that the objects that
+// are no longer read cool down and eventually drop out. The heat map is not flushed; it is
+// used by the readahead to pre-warm the page cache with the hottest objects. The accesses of
+// all objects - in the heat map or not - are also counted approximately (see sketch.go).
+// The access times that are yet to be flushed can be journaled on disk to survive
+// restarts - see journal.go.
 // This way, the atime.Runner and mpathAtimeRunner operation will impact the
 // datapath as little as possible.  As such, atime.Runner can be thought of as an
 // extension of the LRU, or any alternative
@@ -62,22 +84,23 @@ import (
 
 //================================= Constants ==============================================
 const (
-	setChSize                = 256
 	mpathRunnersMapSize      = 8
 	atimeCacheFlushThreshold = 4 * 1024
 	atimeLWM                 = 60
 	atimeHWM                 = 80
-)
-
-const (
-	atimeTouch = "touch"
-	atimeGet   = "get"
+	atimeShards              = 64                     // independently locked shards of each mountpath's maps
+	atimeFlushPace           = 100 * time.Millisecond // interval between the batches (atime_flush.rate)
+	heatDecayTime            = 10 * time.Minute       // the heat of all objects halves that often
+	heatMin                  = 0.5                    // the decayed objects colder than that are forgotten
 )
 
 //================================= Global Variables ==========================================
 // atimeSyncTime is used to determine how often flushes occur.
 var atimeSyncTime = time.Minute * 3
 
+// relaxedAtimeTime: TouchRelaxed does not record the access if the previous one is more recent
+var relaxedAtimeTime = time.Hour
+
 //
 // API types
 //
@@ -89,54 +112,83 @@ type (
 	// to flush files (read description above).
 	Runner struct {
 		cmn.Named
-		requestCh    chan *atimeRequest // Requests for file access times or set access times
-		stopCh       chan struct{}      // Control channel for stopping
+		stopCh       chan struct{} // Control channel for stopping
 		mpathReqCh   chan fs.ChangeReq
+		mu           sync.RWMutex                 // protects mpathRunners
 		mpathRunners map[string]*mpathAtimeRunner // mpath -> mpathAtimeRunner
 		mountpaths   *fs.MountedFS
 		maxMapSize   *uint64
-		riostat      *ios.IostatRunner
+		riostat      ios.DiskUtilProvider
+		Tracker      FlushTracker          // optional; must be set before Run
+		Journal      *cmn.AtimeJournalConf // ditto; journal.go
+		Drain        *cmn.AtimeDrainConf   // ditto; flush all cached atimes upon Stop
+		Flush        *cmn.AtimeFlushConf   // ditto; limits the rate of flushing
+		dropped      int64                 // touches of the objects outside of mountpaths (atomic)
+	}
+	// FlushTracker accounts for the access times flushed to disk, per mountpath, and for
+	// the rest of the atime stats: the in-memory map sizes, the misses, and the dropped touches
+	FlushTracker interface {
+		stats.Tracker
+		AddAtimeFlush(mpath string, n int64)
 	}
 	// The Response object is used to return the access time of
-	// an object in the atimemap and whether it actually existed in
-	// the atimemap of the mpathAtimeRunner it belongs to.
+	// an object in the atime map and whether it actually existed in
+	// the atime map of the mpathAtimeRunner it belongs to.
 	Response struct {
 		Ok         bool
 		AccessTime time.Time
 	}
+	// HotObject is an object and its heat: the number of accesses, decayed over time
+	HotObject struct {
+		FQN  string
+		Heat float64
+	}
 )
 
 //
 // private types
 //
 type (
-	// Each mpathAtimeRunner corresponds to a mpath. All atime request for any file belonging
-	// to this mpath are handled by mpathAtimeRunner. This includes requests for getting,
-	// setting and flushing atimes.
+	// Each mpathAtimeRunner corresponds to a mpath and keeps the access times (and the heat)
+	// of the files that belong to this mpath. Getting and setting atimes is done by the
+	// callers, under the lock of the file's shard; flushing - by the mpathAtimeRunner's
+	// goroutine.
 	mpathAtimeRunner struct {
 		mpath      string
 		fs         string
-		stopCh     chan struct{}        // Control channel for stopping
-		atimemap   map[string]time.Time // maps fqn:atime key-value pairs
-		getCh      chan *atimeRequest   // Requests for file access times
-		setCh      chan *atimeRequest   // Requests to set access times
-		flushCh    chan int             // Request to flush the file system
+		shards     [atimeShards]atimeShard
+		size       int64              // atimes cached in all shards (atomic)
+		heatSize   int64              // objects in all heat maps (atomic)
+		misses     int64              // lookups not found in the atime map since the last report (atomic)
+		stopCh     chan struct{}      // Control channel for stopping
+		flushCh    chan int           // Request to flush the file system
+		drainCh    chan *drainRequest // Request to flush all (Runner.Stop with atime_drain)
 		maxMapSize *uint64
-		riostat    *ios.IostatRunner
-	}
-
-	// Each request to atime.Runner via its API is encapsulated in an
-	// atimeRequest object. The responseCh is used to ensure each atime request gets its
-	// corresponding response.
-	// The accessTime field is used by Touch to set the atime of the requested object.
-	// The mpath field is used by atime.Runner to determine which mpathAtimeRunner to
-	// dispatch the request to.
-	atimeRequest struct {
-		fqn         string
-		accessTime  time.Time
-		responseCh  chan *Response
-		mpath       string
-		requestType string
+		riostat    ios.DiskUtilProvider
+		tracker    FlushTracker
+		reported   int        // atime map size as of the last report (stats.AtimeMapSize)
+		jmu        sync.Mutex // protects the journal: appended by the callers, synced and compacted by the runner
+		journal    *journal   // nil if not journaling
+		jconf      *cmn.AtimeJournalConf
+		fconf      *cmn.AtimeFlushConf
+		pending    int     // atimes yet to be flushed in batches at the limited rate
+		credit     float64 // atimes allowed to be flushed in the next batch
+		next       int     // shard to start the next flush with
+		counts     sketch  // access counters of all objects (see AccessCount)
+	}
+	atimeShard struct {
+		sync.Mutex
+		atimes map[string]time.Time // maps fqn:atime key-value pairs
+		heat   map[string]float64   // maps fqn:heat (see HotObjects)
+	}
+	drainRequest struct {
+		deadline time.Time
+		wg       *sync.WaitGroup
+	}
+	// flushed file, to be removed from the map unless touched in the meantime
+	flushEntry struct {
+		fqn   string
+		atime time.Time
 	}
 )
 
@@ -152,13 +204,12 @@ func (r *Runner) ReqDisableMountpath(mpath string) {}
 
 //================================ atime.Runner ==========================================
 
-func NewRunner(mountpaths *fs.MountedFS, maxMapSize *uint64, riostat *ios.IostatRunner) (r *Runner) {
+func NewRunner(mountpaths *fs.MountedFS, maxMapSize *uint64, riostat ios.DiskUtilProvider) (r *Runner) {
 	return &Runner{
 		stopCh:       make(chan struct{}, 4),
 		mpathReqCh:   make(chan fs.ChangeReq, 1),
 		mpathRunners: make(map[string]*mpathAtimeRunner, mpathRunnersMapSize),
 		mountpaths:   mountpaths,
-		requestCh:    make(chan *atimeRequest),
 		maxMapSize:   maxMapSize,
 		riostat:      riostat,
 	}
@@ -182,9 +233,14 @@ func (r *Runner) Run() error {
 	for {
 		select {
 		case <-ticker.C:
+			r.mu.RLock()
 			for _, runner := range r.mpathRunners {
 				runner.flush()
 			}
+			r.mu.RUnlock()
+			if n := atomic.SwapInt64(&r.dropped, 0); n > 0 && r.Tracker != nil {
+				r.Tracker.Add(stats.AtimeDropCount, n)
+			}
 		case mpathRequest := <-r.mpathReqCh:
 			switch mpathRequest.Action {
 			case fs.Add:
@@ -192,23 +248,18 @@ func (r *Runner) Run() error {
 			case fs.Remove:
 				r.removeMpathAtimeRunner(mpathRequest.Path)
 			}
-		case request := <-r.requestCh:
-			mpathRunner, ok := r.mpathRunners[request.mpath]
-			if ok {
-				if request.requestType == atimeTouch {
-					mpathRunner.setCh <- request
-				} else {
-					mpathRunner.getCh <- request
-				}
-			} else if request.requestType == atimeGet {
-				// invalid mpath so return a nil time for atime request
-				request.responseCh <- &Response{AccessTime: time.Time{}, Ok: false}
-			}
 		case <-r.stopCh:
-			ticker.Stop() // NOTE: not flushing cached atimes
+			ticker.Stop()
+			// NOTE: unless draining, the cached atimes are not flushed (journaled if atime_journal is enabled)
+			if r.Drain != nil && r.Drain.Enabled {
+				r.drain(r.Drain.Timeout)
+			}
+			r.mu.Lock()
 			for _, runner := range r.mpathRunners {
 				runner.stop()
 			}
+			r.mpathRunners = make(map[string]*mpathAtimeRunner) // from now on, Touch is a no-op
+			r.mu.Unlock()
 			return nil
 		}
 	}
@@ -227,67 +278,129 @@ func (r *Runner) Stop(err error) {
 // Note this method should only be called on objects belonging to buckets that have
 // LRU Enabled.
 func (r *Runner) Touch(fqn string, setTime ...time.Time) {
-	mpathInfo, _ := r.mountpaths.Path2MpathInfo(fqn)
-	if mpathInfo == nil {
-		return
-	}
-	var t time.Time
+	t := time.Now()
 	if len(setTime) == 1 {
 		t = setTime[0]
-	} else {
-		t = time.Now()
 	}
-	mpath := mpathInfo.Path
-	request := &atimeRequest{
-		accessTime:  t,
-		fqn:         fqn,
-		mpath:       mpath,
-		requestType: atimeTouch,
+	r.touch(fqn, t, false)
+}
+
+// TouchRelaxed is Touch of the objects that belong to the buckets with the relaxed atime
+// policy (cmn.AtimeRelaxed): the access is recorded only if the object's previous access
+// time - cached or, otherwise, on disk - is older than relaxedAtimeTime. The objects read
+// over and over again then do not occupy the atime maps and do not get flushed.
+func (r *Runner) TouchRelaxed(fqn string) {
+	r.touch(fqn, time.Now(), true)
+}
+
+func (r *Runner) touch(fqn string, t time.Time, relaxed bool) {
+	m := r.demux(fqn)
+	if m == nil {
+		atomic.AddInt64(&r.dropped, 1)
+		return
 	}
-	r.requestCh <- request
+	m.touch(fqn, t, relaxed)
 }
 
 // atime requests the most recent access time of a given file.
-// Note the atime method returns a channel. The caller of the function should
-// block until it can receive from the channel an Response object, which
-// indicates that the request has been fully processed. Note: caller of this
+// Note the atime method returns a channel, for compatibility: the Response object is
+// already there when the method returns. Note: caller of this
 // method does not necessarily need to check if the bucket the object belongs
 // to has LRU Enabled (a zero-valued Response will be returned)
-// Note, the caller can optionally provide a customRespCh where the response will
+// Note, the caller can optionally provide a customRespCh (buffered) where the response will
 // be written to. This reduces channel creation if atime is called repeatedly.
 // Example usage:
 //     Response := <-atimer.atime("/tmp/fqn123")
 //     accessTime, ok := Response.AccessTime, Response.Ok
 func (r *Runner) Atime(fqn string, customRespCh ...chan *Response) (responseCh chan *Response) {
+	return r.atime(fqn, false, customRespCh...)
+}
+
+// AtimeFallback is Atime for the callers that need the object's access time even if it is
+// not cached (e.g., right after restart): the access time of the file on disk is then read
+// and cached for the subsequent requests. The response is not Ok only if the file does not
+// belong to any mountpath or cannot be stat-ed.
+func (r *Runner) AtimeFallback(fqn string, customRespCh ...chan *Response) (responseCh chan *Response) {
+	return r.atime(fqn, true, customRespCh...)
+}
+
+func (r *Runner) atime(fqn string, fallback bool, customRespCh ...chan *Response) (responseCh chan *Response) {
 	if len(customRespCh) == 1 {
 		responseCh = customRespCh[0]
 	} else {
 		responseCh = make(chan *Response, 1)
 	}
-	var mpath string
-	if mpathInfo, _ := r.mountpaths.Path2MpathInfo(fqn); mpathInfo != nil {
-		mpath = mpathInfo.Path
-		request := &atimeRequest{
-			responseCh:  responseCh,
-			fqn:         fqn,
-			mpath:       mpath,
-			requestType: atimeGet,
+	var response Response
+	if m := r.demux(fqn); m != nil {
+		response.AccessTime, response.Ok = m.lookup(fqn, fallback)
+	}
+	responseCh <- &response
+	return responseCh
+}
+
+// AtimeBatch returns the most recent access times of the given files - same as Atime but
+// for many files at once (e.g., LRU). The files that are not in the atime maps, including
+// those that do not belong to any mountpath, are returned not Ok.
+func (r *Runner) AtimeBatch(fqns []string) map[string]*Response {
+	var (
+		responses = make(map[string]*Response, len(fqns))
+		all       = make([]Response, len(fqns))
+	)
+	for i, fqn := range fqns {
+		if m := r.demux(fqn); m != nil {
+			all[i].AccessTime, all[i].Ok = m.lookup(fqn, false)
 		}
-		r.requestCh <- request
-		return request.responseCh
+		responses[fqn] = &all[i]
 	}
+	return responses
+}
 
-	// No mpath exists for the file
-	responseCh <- &Response{AccessTime: time.Time{}, Ok: false}
-	return responseCh
+// HotObjects returns up to topN most frequently accessed objects of the given mountpath,
+// the hottest first - nil if the mountpath is unknown. Only the accesses recorded via Touch
+// and TouchRelaxed count, and the counts decay over time (see heatDecayTime).
+func (r *Runner) HotObjects(mpath string, topN int) []HotObject {
+	r.mu.RLock()
+	m, ok := r.mpathRunners[mpath]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return m.hottest(topN)
+}
+
+// AccessCount returns the approximate number of accesses of the given object, decayed over
+// time the same way as the heat (see sketch.go); zero if the object does not belong to any
+// mountpath. Same as with HotObjects, only the accesses recorded via Touch and TouchRelaxed
+// count.
+func (r *Runner) AccessCount(fqn string) uint32 {
+	m := r.demux(fqn)
+	if m == nil {
+		return 0
+	}
+	return m.counts.count(fqn)
 }
 
 //
 // private methods
 //
 
+// demux returns the mpathAtimeRunner of the file, if any
+func (r *Runner) demux(fqn string) *mpathAtimeRunner {
+	mpathInfo, _ := r.mountpaths.Path2MpathInfo(fqn)
+	if mpathInfo == nil {
+		return nil
+	}
+	r.mu.RLock()
+	m := r.mpathRunners[mpathInfo.Path]
+	r.mu.RUnlock()
+	return m
+}
+
 func (r *Runner) addMpathAtimeRunner(mpath string) {
-	if _, ok := r.mpathRunners[mpath]; ok {
+	r.mu.RLock()
+	_, ok := r.mpathRunners[mpath]
+	r.mu.RUnlock()
+	if ok {
 		glog.Warningf("Attempt to add already existing mountpath %q", mpath)
 		return
 	}
@@ -297,49 +410,365 @@ func (r *Runner) addMpathAtimeRunner(mpath string) {
 		return
 	}
 
-	r.mpathRunners[mpath] = r.newMpathAtimeRunner(mpath, mpathInfo.FileSystem, r.maxMapSize, r.riostat)
-	go r.mpathRunners[mpath].run()
+	m := r.newMpathAtimeRunner(mpath, mpathInfo.FileSystem, r.maxMapSize, r.riostat)
+	m.tracker, m.fconf = r.Tracker, r.Flush
+	if r.Journal != nil && r.Journal.Enabled {
+		if j, atimes, err := openJournal(mpath); err != nil {
+			glog.Errorf("Failed to open atime journal of %s (not journaling), err: %v", mpath, err)
+		} else {
+			m.journal, m.jconf = j, r.Journal
+			for fqn, atime := range atimes {
+				m.shard(fqn).atimes[fqn] = atime
+			}
+			m.size = int64(len(atimes))
+		}
+	}
+	r.mu.Lock()
+	r.mpathRunners[mpath] = m
+	r.mu.Unlock()
+	go m.run()
+}
+
+// drain flushes the access times cached by all mpathAtimeRunners, in parallel, for at most
+// the given time - so that a planned restart does not lose them
+func (r *Runner) drain(timeout time.Duration) {
+	var (
+		wg      = &sync.WaitGroup{}
+		started = time.Now()
+		request = &drainRequest{deadline: started.Add(timeout), wg: wg}
+	)
+	r.mu.RLock()
+	glog.Infof("Flushing cached access times of %d mountpath(s), timeout %v", len(r.mpathRunners), timeout)
+	for _, runner := range r.mpathRunners {
+		wg.Add(1)
+		runner.drainCh <- request
+	}
+	r.mu.RUnlock()
+	wg.Wait()
+	glog.Infof("Flushed cached access times in %v", time.Since(started))
 }
 
 func (r *Runner) removeMpathAtimeRunner(mpath string) {
+	r.mu.Lock()
 	mpathRunner, ok := r.mpathRunners[mpath]
+	delete(r.mpathRunners, mpath)
+	r.mu.Unlock()
 	if !ok {
 		glog.Errorf("Invalid mountpath %q", mpath)
 		return
 	}
 	mpathRunner.stop()
-	delete(r.mpathRunners, mpath)
 }
 
 //================================= mpathAtimeRunner ===========================================
 
-func (r *Runner) newMpathAtimeRunner(mpath, fs string, maxMapSize *uint64, riostat *ios.IostatRunner) *mpathAtimeRunner {
-	return &mpathAtimeRunner{
+func (r *Runner) newMpathAtimeRunner(mpath, fs string, maxMapSize *uint64, riostat ios.DiskUtilProvider) *mpathAtimeRunner {
+	m := &mpathAtimeRunner{
 		mpath:      mpath,
 		fs:         fs,
 		stopCh:     make(chan struct{}, 1),
-		atimemap:   make(map[string]time.Time),
-		getCh:      make(chan *atimeRequest),
-		setCh:      make(chan *atimeRequest, setChSize),
 		flushCh:    make(chan int),
+		drainCh:    make(chan *drainRequest, 1),
 		maxMapSize: maxMapSize,
 		riostat:    riostat,
 	}
+	for i := range m.shards {
+		m.shards[i].atimes = make(map[string]time.Time)
+		m.shards[i].heat = make(map[string]float64)
+	}
+	return m
 }
 
 func (m *mpathAtimeRunner) run() {
+	var (
+		timer *time.Timer
+		syncC <-chan time.Time
+		pacer *time.Ticker
+		paceC <-chan time.Time
+		decay = time.NewTicker(heatDecayTime)
+	)
+	defer decay.Stop()
+	if m.journal != nil {
+		timer = time.NewTimer(m.jconf.SyncTime) // reset each time: sync_time can change at runtime
+		defer timer.Stop()
+		syncC = timer.C
+	}
 	for {
 		select {
-		case request := <-m.getCh:
-			accessTime, ok := m.atimemap[request.fqn]
-			request.responseCh <- &Response{ok, accessTime}
-		case request := <-m.setCh:
-			m.atimemap[request.fqn] = request.accessTime
 		case numToFlush := <-m.flushCh:
-			m.handleFlush(numToFlush)
+			if numToFlush == 0 && m.flushRate() > 0 {
+				m.pending, m.credit = m.getNumberItemsToFlush(), 0
+				break
+			}
+			m.handleFlush(numToFlush, time.Time{})
+			m.compactJournal()
+			m.report(m.len())
+		case <-paceC:
+			m.flushPaced()
+			if m.pending == 0 {
+				m.compactJournal()
+			}
+			m.report(m.len())
+		case request := <-m.drainCh:
+			m.pending = 0
+			m.handleFlush(m.len(), request.deadline)
+			if n := m.len(); n > 0 {
+				glog.Warningf("%s: %d access time(s) not flushed in time", m.mpath, n)
+			}
+			m.compactJournal()
+			m.report(m.len())
+			request.wg.Done()
+		case <-decay.C:
+			m.decayHeat()
+			m.counts.decay()
+		case <-syncC:
+			m.jmu.Lock()
+			if m.journal != nil {
+				m.journal.sync()
+			}
+			m.jmu.Unlock()
+			timer.Reset(m.jconf.SyncTime)
 		case <-m.stopCh:
+			if pacer != nil {
+				pacer.Stop()
+			}
+			m.jmu.Lock()
+			if m.journal != nil {
+				m.journal.close()
+				m.journal = nil
+			}
+			m.jmu.Unlock()
+			m.report(0) // the map goes away with the mountpath
 			return
 		}
+		if m.pending > 0 && pacer == nil {
+			pacer = time.NewTicker(atimeFlushPace)
+			paceC = pacer.C
+		} else if m.pending == 0 && pacer != nil {
+			pacer.Stop()
+			pacer, paceC = nil, nil
+		}
+	}
+}
+
+// shard returns the shard of the file (FNV-1a of the fqn)
+func (m *mpathAtimeRunner) shard(fqn string) *atimeShard {
+	h := uint32(2166136261)
+	for i := 0; i < len(fqn); i++ {
+		h ^= uint32(fqn[i])
+		h *= 16777619
+	}
+	return &m.shards[h%atimeShards]
+}
+
+// len returns the number of the cached atimes
+func (m *mpathAtimeRunner) len() int { return int(atomic.LoadInt64(&m.size)) }
+
+// touch records the access: the atime (unless relaxed and the previous access is recent),
+// the heat, and the journal record
+func (m *mpathAtimeRunner) touch(fqn string, accessTime time.Time, relaxed bool) {
+	s := m.shard(fqn)
+	s.Lock()
+	m.heat(s, fqn)
+	prev, cached := s.atimes[fqn]
+	s.Unlock()
+	m.counts.add(fqn)
+	if relaxed {
+		if !cached {
+			prev, _ = m.ondisk(fqn)
+		}
+		if accessTime.Sub(prev) < relaxedAtimeTime {
+			return
+		}
+	}
+	s.Lock()
+	if _, ok := s.atimes[fqn]; !ok {
+		atomic.AddInt64(&m.size, 1)
+	}
+	s.atimes[fqn] = accessTime
+	s.Unlock()
+
+	m.jmu.Lock()
+	if m.journal != nil {
+		m.journal.append(fqn, accessTime)
+	}
+	m.jmu.Unlock()
+}
+
+// ondisk returns the access time of the file on disk
+func (m *mpathAtimeRunner) ondisk(fqn string) (time.Time, bool) {
+	finfo, err := os.Stat(fqn)
+	if err != nil {
+		return time.Time{}, false
+	}
+	accessTime, _, _ := ios.GetAmTimes(finfo)
+	return accessTime, true
+}
+
+func (m *mpathAtimeRunner) flushRate() int64 {
+	if m.fconf == nil {
+		return 0
+	}
+	return m.fconf.Rate
+}
+
+// flushPaced flushes the next batch of the pending atimes - as many as atime_flush.rate
+// allows per atimeFlushPace
+func (m *mpathAtimeRunner) flushPaced() {
+	n := m.pending
+	if rate := m.flushRate(); rate > 0 { // unless disabled at runtime
+		m.credit += float64(rate) * atimeFlushPace.Seconds()
+		if int(m.credit) < n {
+			n = int(m.credit)
+		}
+		m.credit -= float64(n)
+	}
+	if n > 0 {
+		m.handleFlush(n, time.Time{})
+		m.pending -= n
+	}
+	if m.len() == 0 {
+		m.pending = 0
+	}
+}
+
+// lookup returns the cached access time of the file; if not cached and fallback is set, the
+// access time of the file on disk - cached unless the map is full. The latter is not
+// journaled, and the flush does not rewrite it (see handleFlush)
+func (m *mpathAtimeRunner) lookup(fqn string, fallback bool) (time.Time, bool) {
+	s := m.shard(fqn)
+	s.Lock()
+	accessTime, ok := s.atimes[fqn]
+	s.Unlock()
+	if ok {
+		return accessTime, true
+	}
+	atomic.AddInt64(&m.misses, 1)
+	if !fallback {
+		return time.Time{}, false
+	}
+	if accessTime, ok = m.ondisk(fqn); !ok {
+		return time.Time{}, false
+	}
+	if uint64(m.len()) < *m.maxMapSize {
+		s.Lock()
+		if _, ok := s.atimes[fqn]; !ok {
+			s.atimes[fqn] = accessTime
+			atomic.AddInt64(&m.size, 1)
+		}
+		s.Unlock()
+	}
+	return accessTime, true
+}
+
+// heat counts the access - unless the object is not in the heat map and the map is full;
+// must be called under the shard's lock
+func (m *mpathAtimeRunner) heat(s *atimeShard, fqn string) {
+	if _, ok := s.heat[fqn]; ok {
+		s.heat[fqn]++
+	} else if uint64(atomic.LoadInt64(&m.heatSize)) < *m.maxMapSize {
+		s.heat[fqn] = 1
+		atomic.AddInt64(&m.heatSize, 1)
+	}
+}
+
+func (m *mpathAtimeRunner) decayHeat() {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.Lock()
+		for fqn, heat := range s.heat {
+			if heat /= 2; heat < heatMin {
+				delete(s.heat, fqn)
+				atomic.AddInt64(&m.heatSize, -1)
+			} else {
+				s.heat[fqn] = heat
+			}
+		}
+		s.Unlock()
+	}
+}
+
+// hottest returns up to topN hottest objects, the hottest first
+// hottest keeps the topN objects in a min-heap with the coldest of them on top: the heat
+// map is walked once, and only the objects hotter than the top get in
+func (m *mpathAtimeRunner) hottest(topN int) []HotObject {
+	if topN <= 0 {
+		return []HotObject{}
+	}
+	size := cmn.Min(topN, int(atomic.LoadInt64(&m.heatSize)))
+	h := make(hotMinHeap, 0, size)
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.Lock()
+		for fqn, heat := range s.heat {
+			if len(h) < topN {
+				heap.Push(&h, HotObject{FQN: fqn, Heat: heat})
+			} else if heat > h[0].Heat {
+				h[0] = HotObject{FQN: fqn, Heat: heat}
+				heap.Fix(&h, 0)
+			}
+		}
+		s.Unlock()
+	}
+	hot := []HotObject(h)
+	sort.Slice(hot, func(i, j int) bool { return hot[i].Heat > hot[j].Heat })
+	return hot
+}
+
+// hotMinHeap keeps the hottest objects found so far with the coldest on top
+type hotMinHeap []HotObject
+
+func (h hotMinHeap) Len() int            { return len(h) }
+func (h hotMinHeap) Less(i, j int) bool  { return h[i].Heat < h[j].Heat }
+func (h hotMinHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hotMinHeap) Push(x interface{}) { *h = append(*h, x.(HotObject)) }
+func (h *hotMinHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// compactJournal compacts the journal to the cached atimes, if most of its records are
+// obsolete; the callers' appends wait
+func (m *mpathAtimeRunner) compactJournal() {
+	m.jmu.Lock()
+	defer m.jmu.Unlock()
+	if m.journal == nil || !m.journal.obsolete(m.len()) {
+		return
+	}
+	atimes := make(map[string]time.Time, m.len())
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.Lock()
+		for fqn, atime := range s.atimes {
+			atimes[fqn] = atime
+		}
+		s.Unlock()
+	}
+	if err := m.journal.compact(atimes); err != nil {
+		glog.Errorf("Failed to compact atime journal of %s (not journaling), err: %v", m.mpath, err)
+		m.journal = nil
+	}
+}
+
+// report adds the changes since the last report to the atime stats - on every flush rather
+// than on every request, to keep the stats off the GET path
+func (m *mpathAtimeRunner) report(size int) {
+	if m.tracker == nil {
+		return
+	}
+	nvs := make([]stats.NamedVal64, 0, 2)
+	if size != m.reported {
+		nvs = append(nvs, stats.NamedVal64{Name: stats.AtimeMapSize, Val: int64(size - m.reported)})
+		m.reported = size
+	}
+	if misses := atomic.SwapInt64(&m.misses, 0); misses > 0 {
+		nvs = append(nvs, stats.NamedVal64{Name: stats.AtimeMissCount, Val: misses})
+	}
+	if len(nvs) > 0 {
+		m.tracker.AddMany(nvs...)
 	}
 }
 
@@ -351,9 +780,10 @@ func (m *mpathAtimeRunner) stop() {
 
 // getNumberItemsToFlush estimates the number of timestamps that must be flushed
 // the atime map, by taking into account the max utilitization of the corresponding
-// local mpath (or, more exactly, the corresponding local mpath's disks).
+// local mpath (or, more exactly, the corresponding local mpath's disks) averaged over
+// the recent iostat reports - a momentary idle window does not trigger a flush storm.
 func (m *mpathAtimeRunner) getNumberItemsToFlush() (n int) {
-	atimeMapSize := len(m.atimemap)
+	atimeMapSize := m.len()
 	if atimeMapSize <= atimeCacheFlushThreshold {
 		return
 	}
@@ -361,7 +791,7 @@ func (m *mpathAtimeRunner) getNumberItemsToFlush() (n int) {
 	filling := cmn.MinU64(100, uint64(atimeMapSize)*100/max)
 
 	maxDiskUtil := float32(-1)
-	util, ok := m.riostat.MaxUtilFS(m.fs)
+	util, ok := m.riostat.MaxUtilFSAvg(m.fs)
 	if ok {
 		maxDiskUtil = util
 	}
@@ -391,11 +821,15 @@ func (m *mpathAtimeRunner) flush(numToFlush ...int) {
 }
 
 // handleFlush tries to change access and modification time for at most n files in
-// the atime map, and removes them from the map.
-func (m *mpathAtimeRunner) handleFlush(n int) {
+// the atime map, and removes them from the map; stops at the deadline, if any.
+// The shards are visited round-robin, starting with the one after the last flushed; the
+// files are written without holding the shard's lock, and those touched in the meantime
+// stay in the map.
+func (m *mpathAtimeRunner) handleFlush(n int, deadline time.Time) {
 	var (
-		i     int
-		mtime time.Time
+		i       int
+		flushed int64
+		batch   []flushEntry
 	)
 	if n == 0 {
 		n = m.getNumberItemsToFlush()
@@ -403,35 +837,65 @@ func (m *mpathAtimeRunner) handleFlush(n int) {
 	if n <= 0 {
 		return
 	}
-	for fqn, atime := range m.atimemap {
-		finfo, err := os.Stat(fqn)
-		if err != nil {
-			if os.IsNotExist(err) {
-				delete(m.atimemap, fqn)
-				i++
-			} else {
-				glog.Warningf("failing to touch %s, err: %v", fqn, err)
+	for k := 0; k < atimeShards && i < n; k++ {
+		s := &m.shards[m.next]
+		m.next = (m.next + 1) % atimeShards
+		batch = batch[:0]
+		s.Lock()
+		for fqn, atime := range s.atimes {
+			if len(batch) >= n-i {
+				break
 			}
-			goto cont
+			batch = append(batch, flushEntry{fqn, atime})
 		}
-		mtime = finfo.ModTime()
-		if err = os.Chtimes(fqn, atime, mtime); err != nil {
-			if os.IsNotExist(err) {
-				delete(m.atimemap, fqn)
+		s.Unlock()
+		for j, e := range batch {
+			done, written := m.flushOne(e.fqn, e.atime)
+			if done {
+				s.Lock()
+				if atime, ok := s.atimes[e.fqn]; ok && atime.Equal(e.atime) {
+					delete(s.atimes, e.fqn)
+					atomic.AddInt64(&m.size, -1)
+				}
+				s.Unlock()
 				i++
-			} else {
-				glog.Warningf("can't touch %s, err: %v", fqn, err) // FIXME: carry on forever?
 			}
-		} else {
-			delete(m.atimemap, fqn)
-			i++
-			if glog.V(4) {
-				glog.Infof("touch %s at %v", fqn, atime)
+			if written {
+				flushed++
+			}
+			if !deadline.IsZero() && j%64 == 63 && time.Now().After(deadline) {
+				k = atimeShards // stop
+				break
 			}
 		}
-	cont:
-		if i >= n {
-			break
+	}
+	if flushed > 0 && m.tracker != nil {
+		m.tracker.AddAtimeFlush(m.mpath, flushed)
+	}
+}
+
+// flushOne writes the access time of the file; done - unless failed to (and is to retry)
+func (m *mpathAtimeRunner) flushOne(fqn string, atime time.Time) (done, written bool) {
+	finfo, err := os.Stat(fqn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, false
 		}
+		glog.Warningf("failing to touch %s, err: %v", fqn, err)
+		return false, false
+	}
+	if ondisk, _, _ := ios.GetAmTimes(finfo); ondisk.Equal(atime) {
+		return true, false // nothing to write - e.g., cached by lookup
+	}
+	if err = os.Chtimes(fqn, atime, finfo.ModTime()); err != nil {
+		if os.IsNotExist(err) {
+			return true, false
+		}
+		glog.Warningf("can't touch %s, err: %v", fqn, err) // FIXME: carry on forever?
+		return false, false
+	}
+	if glog.V(4) {
+		glog.Infof("touch %s at %v", fqn, atime)
 	}
+	return true, true
 }
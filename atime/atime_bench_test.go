@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package atime tracks object access times in the system while providing a number of performance enhancements.
+package atime
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+// To run:
+//        go test -run=NONE -bench=Atime -benchtime=5s
+// The benchmarks access 1M+ objects of a single mountpath from GOMAXPROCS goroutines.
+
+const benchObjects = 1 << 20
+
+func newBenchRunner() (*Runner, []string) {
+	mapSize := uint64(2 * benchObjects)
+	atimer := NewRunner(fs.Mountpaths, &mapSize, riostat)
+	go atimer.Run()
+	atimer.ReqAddMountpath("/tmp")
+	time.Sleep(50 * time.Millisecond)
+
+	fqns := make([]string, benchObjects)
+	for i := range fqns {
+		fqns[i] = "/tmp/local/bench/fqn" + strconv.Itoa(i)
+	}
+	return atimer, fqns
+}
+
+func BenchmarkAtimeTouchParallel(b *testing.B) {
+	atimer, fqns := newBenchRunner()
+	defer atimer.Stop(nil)
+	var next int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := atomic.AddInt64(&next, 1)
+			atimer.Touch(fqns[i%benchObjects])
+		}
+	})
+}
+
+func BenchmarkAtimeGetParallel(b *testing.B) {
+	atimer, fqns := newBenchRunner()
+	defer atimer.Stop(nil)
+	for _, fqn := range fqns {
+		atimer.Touch(fqn)
+	}
+	var next int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		respCh := make(chan *Response, 1)
+		for pb.Next() {
+			i := atomic.AddInt64(&next, 1)
+			if response := <-atimer.Atime(fqns[i%benchObjects], respCh); !response.Ok {
+				b.Fatalf("%s: not found", fqns[i%benchObjects])
+			}
+		}
+	})
+}
+
+// 4 reads per write, as in the GET-heavy workloads
+func BenchmarkAtimeMixedParallel(b *testing.B) {
+	atimer, fqns := newBenchRunner()
+	defer atimer.Stop(nil)
+	var next int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		respCh := make(chan *Response, 1)
+		for pb.Next() {
+			i := atomic.AddInt64(&next, 1)
+			fqn := fqns[i%benchObjects]
+			if i%5 == 0 {
+				atimer.Touch(fqn)
+			} else {
+				<-atimer.Atime(fqn, respCh)
+			}
+		}
+	})
+}
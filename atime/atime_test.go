@@ -6,13 +6,19 @@ package atime
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/NVIDIA/dfcpub/cmn"
 	"github.com/NVIDIA/dfcpub/fs"
 	"github.com/NVIDIA/dfcpub/ios"
+	"github.com/NVIDIA/dfcpub/stats"
 )
 
 var (
@@ -41,17 +47,11 @@ func TestAtimerunnerStop(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 	atimer.Stop(fmt.Errorf("test"))
 
-	waitCh := make(chan struct{})
-	go func() {
-		atimer.Touch(fileName)
-		waitCh <- struct{}{}
-	}()
+	time.Sleep(50 * time.Millisecond)
 
-	select {
-	case <-waitCh:
+	atimer.Touch(fileName)
+	if atimeResponse := <-atimer.Atime(fileName); atimeResponse.Ok {
 		t.Error("Touch was successful so atimerunner did not stop")
-	case <-time.After(50 * time.Millisecond):
-		break
 	}
 }
 
@@ -67,7 +67,7 @@ func TestAtimerunnerTouch(t *testing.T) {
 	timeBeforeTouch := time.Now()
 	atimer.Touch(fileName)
 	time.Sleep(50 * time.Millisecond) // wait for runner to process
-	if len(atimer.mpathRunners) != 1 || len(atimer.mpathRunners[mpath].atimemap) != 1 {
+	if len(atimer.mpathRunners) != 1 || atimer.mpathRunners[mpath].len() != 1 {
 		t.Error("One file must be present in the map")
 	}
 	atimeResponse := <-atimer.Atime(fileName)
@@ -87,6 +87,89 @@ func TestAtimerunnerTouch(t *testing.T) {
 	atimer.Stop(fmt.Errorf("test"))
 }
 
+func TestAtimerunnerAtimeFallback(t *testing.T) {
+	mpath := "/tmp"
+	if err := os.MkdirAll("/tmp/local", 0755); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("/tmp/local", "fallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "fqn1")
+	if err := ioutil.WriteFile(fileName, []byte("fallback"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	atime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(fileName, atime, atime); err != nil {
+		t.Fatal(err)
+	}
+
+	atimer := NewRunner(fs.Mountpaths, &maxMapSize, riostat)
+	go atimer.Run()
+	defer atimer.Stop(fmt.Errorf("test"))
+	atimer.ReqAddMountpath(mpath)
+	time.Sleep(50 * time.Millisecond)
+
+	if atimeResponse := <-atimer.Atime(fileName); atimeResponse.Ok {
+		t.Error("Access time must not be cached")
+	}
+	atimeResponse := <-atimer.AtimeFallback(fileName)
+	if !atimeResponse.Ok || !atimeResponse.AccessTime.Equal(atime) {
+		t.Errorf("Expected access time on disk %v, got %+v", atime, atimeResponse)
+	}
+	// cached by the fallback
+	if atimeResponse := <-atimer.Atime(fileName); !atimeResponse.Ok || !atimeResponse.AccessTime.Equal(atime) {
+		t.Errorf("Expected cached access time %v, got %+v", atime, atimeResponse)
+	}
+	if atimeResponse := <-atimer.AtimeFallback(filepath.Join(dir, "nonexisting")); atimeResponse.Ok {
+		t.Error("Access time of nonexisting file must not be Ok")
+	}
+
+	// nothing to write: flushed without changing the file
+	m := atimer.mpathRunners[mpath]
+	m.flush(1)
+	time.Sleep(50 * time.Millisecond)
+	if atimeResponse := <-atimer.Atime(fileName); atimeResponse.Ok {
+		t.Error("Access time must be flushed")
+	}
+}
+
+func TestAtimerunnerHotObjects(t *testing.T) {
+	mpath := "/tmp"
+	mapSize := uint64(100) // other tests change maxMapSize
+	atimer := NewRunner(fs.Mountpaths, &mapSize, riostat)
+	go atimer.Run()
+	defer atimer.Stop(fmt.Errorf("test"))
+	atimer.ReqAddMountpath(mpath)
+	time.Sleep(50 * time.Millisecond)
+
+	accesses := map[string]int{"/tmp/local/hot": 5, "/tmp/local/warm": 3, "/tmp/local/cold": 1}
+	for fqn, n := range accesses {
+		for i := 0; i < n; i++ {
+			atimer.Touch(fqn)
+		}
+	}
+	time.Sleep(50 * time.Millisecond) // wait for runner to process
+	if hot := atimer.HotObjects("/nonexisting", 2); hot != nil {
+		t.Errorf("Expected no hot objects of unknown mountpath, got %+v", hot)
+	}
+	hot := atimer.HotObjects(mpath, 2)
+	if len(hot) != 2 || hot[0].FQN != "/tmp/local/hot" || hot[0].Heat != 5 || hot[1].FQN != "/tmp/local/warm" {
+		t.Fatalf("Expected the 2 hottest objects, got %+v", hot)
+	}
+
+	// decayed: the object accessed once is forgotten, the rest keep their order
+	m := atimer.mpathRunners[mpath]
+	m.decayHeat()
+	m.decayHeat()
+	hot = atimer.HotObjects(mpath, 10)
+	if len(hot) != 2 || hot[0].Heat != 1.25 || hot[1].Heat != 0.75 {
+		t.Errorf("Expected 2 decayed hot objects, got %+v", hot)
+	}
+}
+
 func TestAtimerunnerTouchNoMpath(t *testing.T) {
 	q := fs.Mountpaths
 	fs.Mountpaths = fs.NewMountedFS("local", "cloud")
@@ -146,7 +229,7 @@ func TestAtimerunnerMultipleTouchSameFile(t *testing.T) {
 
 	atimer.Touch(fileName)
 	time.Sleep(50 * time.Millisecond) // wait for runner to process
-	if len(atimer.mpathRunners) != 1 || len(atimer.mpathRunners[mpath].atimemap) != 1 {
+	if len(atimer.mpathRunners) != 1 || atimer.mpathRunners[mpath].len() != 1 {
 		t.Error("One mpathAtimeRunner and one file must be present in the atimemap")
 	}
 
@@ -188,7 +271,7 @@ func TestAtimerunnerTouchMultipleFile(t *testing.T) {
 
 	atimer.Touch(fileName1)
 	time.Sleep(50 * time.Millisecond) // wait for runner to process
-	if len(atimer.mpathRunners) != 1 || len(atimer.mpathRunners[mpath].atimemap) != 1 {
+	if len(atimer.mpathRunners) != 1 || atimer.mpathRunners[mpath].len() != 1 {
 		t.Error("One file must be present in the map")
 	}
 	atimeResponse := <-atimer.Atime(fileName1)
@@ -198,7 +281,7 @@ func TestAtimerunnerTouchMultipleFile(t *testing.T) {
 
 	atimer.Touch(fileName2)
 	time.Sleep(50 * time.Millisecond) // wait for runner to process
-	if len(atimer.mpathRunners) != 1 || len(atimer.mpathRunners[mpath].atimemap) != 2 {
+	if len(atimer.mpathRunners) != 1 || atimer.mpathRunners[mpath].len() != 2 {
 		t.Error("Two files must be present in the map")
 	}
 
@@ -210,6 +293,43 @@ func TestAtimerunnerTouchMultipleFile(t *testing.T) {
 	atimer.Stop(fmt.Errorf("test"))
 }
 
+func TestAtimerunnerBatch(t *testing.T) {
+	mpath := "/tmp"
+	fileName1 := "/tmp/cloud/bck1/fqn1"
+	fileName2 := "/tmp/local/bck2/fqn2"
+	notTouched := "/tmp/local/bck2/fqn3"
+	noMpath := "/nompath/local/bck2/fqn4"
+	atime1 := time.Now().Add(-time.Hour)
+
+	atimer := NewRunner(fs.Mountpaths, &maxMapSize, riostat)
+	go atimer.Run()
+	if responses := atimer.AtimeBatch([]string{fileName1}); len(responses) != 1 || responses[fileName1].Ok {
+		t.Errorf("Expected not ok without mountpath runners, got %+v", responses)
+	}
+	atimer.ReqAddMountpath(mpath)
+	time.Sleep(50 * time.Millisecond)
+
+	atimer.Touch(fileName1, atime1)
+	atimer.Touch(fileName2)
+	time.Sleep(50 * time.Millisecond) // wait for runner to process
+
+	responses := atimer.AtimeBatch([]string{fileName1, fileName2, notTouched, noMpath})
+	if len(responses) != 4 {
+		t.Fatalf("Expected 4 responses, got %d", len(responses))
+	}
+	if r := responses[fileName1]; !r.Ok || !r.AccessTime.Equal(atime1) {
+		t.Errorf("File [%s]: expected access time %v, got %+v", fileName1, atime1, r)
+	}
+	if !responses[fileName2].Ok {
+		t.Errorf("File [%s] is not present in atime map", fileName2)
+	}
+	if responses[notTouched].Ok || responses[noMpath].Ok {
+		t.Error("Files not present in the atime maps must not be ok")
+	}
+
+	atimer.Stop(fmt.Errorf("test"))
+}
+
 func TestAtimerunnerFlush(t *testing.T) {
 	mpath := "/tmp"
 	fileName1 := "/tmp/cloud/bck1/fqn1"
@@ -228,19 +348,226 @@ func TestAtimerunnerFlush(t *testing.T) {
 
 	atimer.mpathRunners[mpath].flush(1)
 	time.Sleep(50 * time.Millisecond) // wait for runner to process
-	if len(atimer.mpathRunners) != 1 || len(atimer.mpathRunners[mpath].atimemap) != 2 {
+	if len(atimer.mpathRunners) != 1 || atimer.mpathRunners[mpath].len() != 2 {
 		t.Error("Invalid number of files in atimerunner")
 	}
 
 	atimer.mpathRunners[mpath].flush(2)
 	time.Sleep(50 * time.Millisecond) // wait for runner to process
-	if len(atimer.mpathRunners) != 1 || len(atimer.mpathRunners[mpath].atimemap) != 0 {
+	if len(atimer.mpathRunners) != 1 || atimer.mpathRunners[mpath].len() != 0 {
 		t.Error("Invalid number of files in atimerunner")
 	}
 
 	atimer.Stop(fmt.Errorf("test"))
 }
 
+func TestAtimerunnerDrain(t *testing.T) {
+	mpath := "/tmp"
+	if err := os.MkdirAll("/tmp/local", 0755); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("/tmp/local", "drain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	atime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	fqns := make([]string, 3)
+	for i := range fqns {
+		fqns[i] = filepath.Join(dir, "fqn"+strconv.Itoa(i))
+		if err := ioutil.WriteFile(fqns[i], []byte("drain"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	atimer := NewRunner(fs.Mountpaths, &maxMapSize, riostat)
+	atimer.Drain = &cmn.AtimeDrainConf{Enabled: true, Timeout: time.Minute}
+	stopped := make(chan struct{})
+	go func() {
+		atimer.Run()
+		close(stopped)
+	}()
+	atimer.ReqAddMountpath(mpath)
+	time.Sleep(50 * time.Millisecond)
+	for _, fqn := range fqns {
+		atimer.Touch(fqn, atime)
+	}
+	time.Sleep(50 * time.Millisecond) // wait for runner to process
+
+	atimer.Stop(fmt.Errorf("test"))
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("atimerunner did not stop")
+	}
+	for _, fqn := range fqns {
+		finfo, err := os.Stat(fqn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _, _ := ios.GetAmTimes(finfo); !got.Equal(atime) {
+			t.Errorf("File [%s]: expected access time %v flushed upon stop, got %v", fqn, atime, got)
+		}
+	}
+}
+
+func TestAtimerunnerFlushPaced(t *testing.T) {
+	dir, err := ioutil.TempDir("", "paced")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	atimer := NewRunner(fs.Mountpaths, &maxMapSize, riostat)
+	m := atimer.newMpathAtimeRunner(dir, "", &maxMapSize, riostat)
+	m.fconf = &cmn.AtimeFlushConf{Rate: 25} // 2.5 per atimeFlushPace
+	atime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < 10; i++ {
+		fqn := filepath.Join(dir, "fqn"+strconv.Itoa(i))
+		if err := ioutil.WriteFile(fqn, []byte("paced"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		m.shard(fqn).atimes[fqn] = atime
+		m.size++
+	}
+	m.pending = 8
+
+	for _, expected := range []int{8, 6, 3, 1, 0} {
+		if m.pending != expected || m.len() != expected+2 {
+			t.Fatalf("expected %d pending, %d cached; got %d, %d", expected, expected+2, m.pending, m.len())
+		}
+		m.flushPaced()
+	}
+	if m.len() != 2 {
+		t.Errorf("expected 2 atimes not flushed, got %d", m.len())
+	}
+
+	// unlimited at runtime: all pending at once
+	m.fconf.Rate, m.pending = 0, 2
+	m.flushPaced()
+	if m.pending != 0 || m.len() != 0 {
+		t.Errorf("expected all flushed, got %d pending, %d cached", m.pending, m.len())
+	}
+}
+
+func TestAtimerunnerTouchRelaxed(t *testing.T) {
+	mpath := "/tmp"
+	if err := os.MkdirAll("/tmp/local", 0755); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("/tmp/local", "relaxed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		recent = filepath.Join(dir, "recent")
+		old    = filepath.Join(dir, "old")
+	)
+	for _, fqn := range []string{recent, old} {
+		if err := ioutil.WriteFile(fqn, []byte("relaxed"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(old, time.Now().Add(-2*relaxedAtimeTime), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	atimer := NewRunner(fs.Mountpaths, &maxMapSize, riostat)
+	go atimer.Run()
+	atimer.ReqAddMountpath(mpath)
+	time.Sleep(50 * time.Millisecond)
+
+	atimer.TouchRelaxed(recent)
+	atimer.TouchRelaxed(old)
+	time.Sleep(50 * time.Millisecond) // wait for runner to process
+	if (<-atimer.Atime(recent)).Ok {
+		t.Errorf("File [%s] accessed recently must not be tracked", recent)
+	}
+	first := <-atimer.Atime(old)
+	if !first.Ok {
+		t.Fatalf("File [%s] is not present in atime map", old)
+	}
+
+	atimer.TouchRelaxed(old)
+	time.Sleep(50 * time.Millisecond) // wait for runner to process
+	if second := <-atimer.Atime(old); !second.AccessTime.Equal(first.AccessTime) {
+		t.Errorf("File [%s]: expected access time %v unchanged, got %v", old, first.AccessTime, second.AccessTime)
+	}
+	atimer.Stop(fmt.Errorf("test"))
+}
+
+type testTracker struct {
+	sync.Mutex
+	stats map[string]int64
+}
+
+func (t *testTracker) Add(name string, val int64) {
+	t.Lock()
+	t.stats[name] += val
+	t.Unlock()
+}
+
+func (t *testTracker) AddMany(nvs ...stats.NamedVal64) {
+	for _, nv := range nvs {
+		t.Add(nv.Name, nv.Val)
+	}
+}
+
+func (t *testTracker) AddErrorHTTP(method string, val int64) {}
+
+func (t *testTracker) AddBucket(bucket string, nvs ...stats.NamedVal64) { t.AddMany(nvs...) }
+
+func (t *testTracker) AddAtimeFlush(mpath string, n int64) { t.Add(stats.AtimeFlushCount, n) }
+
+func (t *testTracker) get(name string) int64 {
+	t.Lock()
+	defer t.Unlock()
+	return t.stats[name]
+}
+
+func TestAtimerunnerStats(t *testing.T) {
+	mpath := "/tmp"
+	tracker := &testTracker{stats: make(map[string]int64)}
+
+	atimer := NewRunner(fs.Mountpaths, &maxMapSize, riostat)
+	atimer.Tracker = tracker
+	go atimer.Run()
+	atimer.ReqAddMountpath(mpath)
+	time.Sleep(50 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		atimer.Touch("/tmp/local/bck1/fqn" + strconv.Itoa(i))
+	}
+	atimer.Touch("/nompath/local/bck1/fqn1")
+	time.Sleep(50 * time.Millisecond) // wait for runner to process
+	<-atimer.Atime("/tmp/local/bck1/fqn1")
+	<-atimer.Atime("/tmp/local/bck1/fqn5")
+	atimer.AtimeBatch([]string{"/tmp/local/bck1/fqn2", "/tmp/local/bck1/fqn6"})
+
+	// the files do not exist: removed from the map but not flushed
+	atimer.mpathRunners[mpath].flush(1)
+	time.Sleep(50 * time.Millisecond) // wait for runner to process
+	if n := tracker.get(stats.AtimeMapSize); n != 2 {
+		t.Errorf("Expected map size 2, got %d", n)
+	}
+	if n := tracker.get(stats.AtimeMissCount); n != 2 {
+		t.Errorf("Expected 2 misses, got %d", n)
+	}
+	if n := tracker.get(stats.AtimeFlushCount); n != 0 {
+		t.Errorf("Expected nothing flushed, got %d", n)
+	}
+	if n := atomic.LoadInt64(&atimer.dropped); n != 1 {
+		t.Errorf("Expected 1 dropped touch, got %d", n)
+	}
+
+	atimer.Stop(fmt.Errorf("test"))
+	time.Sleep(50 * time.Millisecond) // wait for runners to stop
+	if n := tracker.get(stats.AtimeMapSize); n != 0 {
+		t.Errorf("Expected map size 0 upon stopping, got %d", n)
+	}
+}
+
 // TestAtimerunnerGetNumberItemsToFlushSimple tests the number of items to flush.
 func TestAtimerunnerGetNumberItemsToFlushSimple(t *testing.T) {
 	mpath := "/tmp"
@@ -275,16 +602,10 @@ func TestAtimerunnerGetNumberItemsToFlushDiskIdle(t *testing.T) {
 	maxMapSize = 1
 	statsPeriod = time.Second
 
-	q := riostat.Disk
-	defer func() { riostat.Disk = q }()
-
-	riostat.Disk = map[string]cmn.SimpleKVs{
-		"disk1": cmn.SimpleKVs{
-			"%util": "21.34",
-		},
-	}
+	diskutil := ios.NewStaticDiskUtil()
+	diskutil.SetUtil("", 21.34)
 
-	atimer := NewRunner(fs.Mountpaths, &maxMapSize, riostat)
+	atimer := NewRunner(fs.Mountpaths, &maxMapSize, diskutil)
 
 	go atimer.Run()
 	defer func() { atimer.Stop(nil) }()
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package atime tracks object access times in the system while providing a number of performance enhancements.
+package atime
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+)
+
+// Atime journal (atime_journal): the access times that are yet to be flushed to the objects
+// live in memory only, and would be lost upon restart - taking the LRU eviction order with
+// them. With the journal enabled, each mpathAtimeRunner appends every access time it
+// receives to the journal in the root of its mountpath, and fsyncs the journal every
+// atime_journal.sync_time. When the mountpath is (re)added - e.g., upon the target's start -
+// the journal is replayed into the atime map (the last record of an object wins) and
+// compacted to the map's contents. The journal is also compacted once the flushes have made
+// most of its records obsolete. A record torn by a crash is skipped.
+//
+// Record: "<unix nanoseconds> <fqn>\n"
+
+// JournalName is the name of the atime journal in the root of each mountpath
+const JournalName = ".dfc-atime.journal"
+
+type journal struct {
+	path    string
+	file    *os.File
+	w       *bufio.Writer
+	records int  // in the file, obsolete included
+	dirty   bool // appended since the last sync
+}
+
+// openJournal replays the mountpath's journal, if any, and compacts it to the replayed
+// access times
+func openJournal(mpath string) (j *journal, atimes map[string]time.Time, err error) {
+	j = &journal{path: filepath.Join(mpath, JournalName)}
+	if atimes, err = j.replay(); err != nil {
+		return nil, nil, err
+	}
+	if err = j.compact(atimes); err != nil {
+		return nil, nil, err
+	}
+	return
+}
+
+func (j *journal) replay() (map[string]time.Time, error) {
+	atimes := make(map[string]time.Time)
+	file, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return atimes, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	var (
+		scanner = bufio.NewScanner(file)
+		skipped int
+	)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.IndexByte(line, ' ')
+		if i <= 0 || i == len(line)-1 {
+			skipped++
+			continue
+		}
+		nanos, err := strconv.ParseInt(line[:i], 10, 64)
+		if err != nil {
+			skipped++
+			continue
+		}
+		atimes[line[i+1:]] = time.Unix(0, nanos)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if skipped > 0 {
+		glog.Warningf("%s: skipped %d malformed record(s)", j.path, skipped)
+	}
+	glog.Infof("%s: replayed %d access time(s)", j.path, len(atimes))
+	return atimes, nil
+}
+
+func (j *journal) append(fqn string, atime time.Time) {
+	if strings.IndexByte(fqn, '\n') >= 0 {
+		return // cannot be journaled
+	}
+	if _, err := fmt.Fprintf(j.w, "%d %s\n", atime.UnixNano(), fqn); err != nil {
+		glog.Errorf("%s: failed to append, err: %v", j.path, err)
+		return
+	}
+	j.records++
+	j.dirty = true
+}
+
+// sync writes the appended records and fsyncs the journal
+func (j *journal) sync() {
+	if !j.dirty {
+		return
+	}
+	j.dirty = false
+	if err := j.w.Flush(); err != nil {
+		glog.Errorf("%s: failed to write, err: %v", j.path, err)
+		return
+	}
+	if err := j.file.Sync(); err != nil {
+		glog.Errorf("%s: failed to sync, err: %v", j.path, err)
+	}
+}
+
+// compact replaces the journal with the given access times - via a temporary file that
+// is fsynced and renamed into place - and reopens it for appending
+func (j *journal) compact(atimes map[string]time.Time) error {
+	if j.file != nil {
+		j.sync()
+		j.file.Close()
+		j.file = nil
+	}
+	tmp := j.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for fqn, atime := range atimes {
+		if strings.IndexByte(fqn, '\n') < 0 {
+			fmt.Fprintf(w, "%d %s\n", atime.UnixNano(), fqn)
+		}
+	}
+	if err = w.Flush(); err == nil {
+		err = file.Sync()
+	}
+	if errc := file.Close(); err == nil {
+		err = errc
+	}
+	if err == nil {
+		err = os.Rename(tmp, j.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if j.file, err = os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		return err
+	}
+	j.w = bufio.NewWriter(j.file)
+	j.records, j.dirty = len(atimes), false
+	return nil
+}
+
+// obsolete returns true if most of the journal's records are no longer in the atime map
+func (j *journal) obsolete(mapSize int) bool {
+	return j.records > atimeCacheFlushThreshold && j.records > 2*mapSize
+}
+
+func (j *journal) close() {
+	if j.file == nil {
+		return
+	}
+	j.sync()
+	if err := j.file.Close(); err != nil {
+		glog.Errorf("%s: failed to close, err: %v", j.path, err)
+	}
+	j.file = nil
+}
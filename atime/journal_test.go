@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package atime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalReplay(t *testing.T) {
+	mpath, err := ioutil.TempDir("", "atime-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mpath)
+
+	j, atimes, err := openJournal(mpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(atimes) != 0 {
+		t.Fatalf("expected nothing to replay, got %v", atimes)
+	}
+	var (
+		fqn1  = filepath.Join(mpath, "local/bck1/fqn1")
+		fqn2  = filepath.Join(mpath, "local/bck1/fqn2")
+		first = time.Now().Add(-time.Hour)
+		last  = time.Now()
+	)
+	j.append(fqn1, first)
+	j.append(fqn2, first)
+	j.append(fqn1, last)
+	j.append("bad\nfqn", last)
+	j.close()
+
+	// a record torn by a crash
+	file, err := os.OpenFile(filepath.Join(mpath, JournalName), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("15392")
+	file.Close()
+
+	j, atimes, err = openJournal(mpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.close()
+	if len(atimes) != 2 {
+		t.Fatalf("expected 2 access times replayed, got %v", atimes)
+	}
+	if !atimes[fqn1].Equal(last) || !atimes[fqn2].Equal(first) {
+		t.Errorf("expected the last record of each object to win, got %v", atimes)
+	}
+	if j.records != 2 {
+		t.Errorf("expected the journal compacted to 2 records, got %d", j.records)
+	}
+	if j.obsolete(len(atimes)) {
+		t.Error("expected the compacted journal not to be obsolete")
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package atime tracks object access times in the system while providing a number of performance enhancements.
+package atime
+
+import (
+	"sync/atomic"
+
+	"github.com/OneOfOne/xxhash"
+)
+
+// ================================ Summary ===============================================
+//
+// Access counters. Unlike the heat map, which is limited to atime_cache_max objects, each
+// mpathAtimeRunner counts the accesses of all its objects in a count-min sketch: sketchDepth
+// rows of sketchWidth counters, each object incrementing one counter per row (the rows
+// hash the object differently). The object's count is the minimum of its counters: never
+// less than the actual number of accesses, and more than that only if all its counters are
+// shared with other objects - rarely so, for as long as the number of the mountpath's
+// recently accessed objects is well below sketchWidth. The memory is fixed: 1MiB per
+// mountpath. The counters decay along with the heat map - halved every heatDecayTime - so
+// that an object "accessed once recently" and an object "accessed thousands of times" are
+// told apart, while the objects that are no longer read cool down.
+//
+// ================================ Summary ===============================================
+
+const (
+	sketchDepth = 4
+	sketchWidth = 64 * 1024 // power of two
+)
+
+// sketch is the count-min sketch; the counters are updated atomically, without locks
+type sketch struct {
+	counters [sketchDepth * sketchWidth]uint32
+}
+
+// indexes returns the object's counter in each row: double hashing out of a single 64-bit
+// xxhash - the two halves
+func (s *sketch) indexes(fqn string) (idx [sketchDepth]uint32) {
+	h := xxhash.ChecksumString64(fqn)
+	h1, h2 := uint32(h), uint32(h>>32)|1
+	for i := range idx {
+		idx[i] = uint32(i)*sketchWidth + (h1+uint32(i)*h2)&(sketchWidth-1)
+	}
+	return
+}
+
+func (s *sketch) add(fqn string) {
+	for _, i := range s.indexes(fqn) {
+		atomic.AddUint32(&s.counters[i], 1)
+	}
+}
+
+func (s *sketch) count(fqn string) (n uint32) {
+	for j, i := range s.indexes(fqn) {
+		if c := atomic.LoadUint32(&s.counters[i]); j == 0 || c < n {
+			n = c
+		}
+	}
+	return
+}
+
+// decay halves all counters; the accesses counted in the meantime may be halved or not
+func (s *sketch) decay() {
+	for i := range s.counters {
+		for {
+			c := atomic.LoadUint32(&s.counters[i])
+			if c == 0 || atomic.CompareAndSwapUint32(&s.counters[i], c, c/2) {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package atime tracks object access times in the system while providing a number of performance enhancements.
+package atime
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func TestSketch(t *testing.T) {
+	var (
+		s        = &sketch{}
+		accesses = make(map[string]uint32, 10000)
+	)
+	for i := 0; i < 10000; i++ {
+		fqn := "/tmp/local/bucket/obj" + strconv.Itoa(i)
+		n := uint32(1 + i%5)
+		if i < 10 {
+			n = 1000
+		}
+		for j := uint32(0); j < n; j++ {
+			s.add(fqn)
+		}
+		accesses[fqn] = n
+	}
+	var over int
+	for fqn, n := range accesses {
+		c := s.count(fqn)
+		if c < n {
+			t.Fatalf("%s: counted %d out of %d accesses", fqn, c, n)
+		}
+		if c > n {
+			over++
+		}
+	}
+	// with 10K objects in 64K-wide rows, an overcount takes a collision in each of the 4 rows
+	if over > len(accesses)/100 {
+		t.Errorf("expected few overcounts, got %d out of %d", over, len(accesses))
+	}
+	if c := s.count("/tmp/local/bucket/never"); c > 5 {
+		t.Errorf("expected (nearly) zero count of the object never accessed, got %d", c)
+	}
+
+	s.decay()
+	if c := s.count("/tmp/local/bucket/obj0"); c < 500 || c > 505 {
+		t.Errorf("expected the count halved, got %d", c)
+	}
+}
+
+func TestAtimerunnerAccessCount(t *testing.T) {
+	mpath := "/tmp"
+	mapSize := uint64(2) // the heat map holds 2 objects; the sketch counts all
+	atimer := NewRunner(fs.Mountpaths, &mapSize, riostat)
+	go atimer.Run()
+	defer atimer.Stop(fmt.Errorf("test"))
+	atimer.ReqAddMountpath(mpath)
+	time.Sleep(50 * time.Millisecond)
+
+	accesses := map[string]uint32{"/tmp/local/a": 3, "/tmp/local/b": 1, "/tmp/local/c": 7, "/tmp/local/d": 2}
+	for fqn, n := range accesses {
+		for i := uint32(0); i < n; i++ {
+			atimer.Touch(fqn)
+		}
+	}
+	for fqn, n := range accesses {
+		if c := atimer.AccessCount(fqn); c != n {
+			t.Errorf("%s: expected %d accesses, got %d", fqn, n, c)
+		}
+	}
+	if c := atimer.AccessCount("/nonexisting/local/a"); c != 0 {
+		t.Errorf("expected no accesses of the object outside of mountpaths, got %d", c)
+	}
+}
@@ -11,4 +11,10 @@ type Target interface {
 	RunLRU()
 	PrefetchQueueLen() int
 	Prefetch()
+	PruneEmptyDirs()
+	RunCompress()
+	RunExpire()
+	SaveMDCache()
+	UpdatePinned()
+	PruneIdempotencyKeys()
 }
@@ -22,21 +22,29 @@ const (
 const (
 	OnDiskUtil = uint64(1) << iota
 	OnFSUsed
+	OnQoS
 )
 
 type (
 	Throttler interface {
 		Sleep()
 	}
+	// QoS tells whether the background work on the filesystem is to give way to the
+	// foreground GETs and PUTs (see ios.IostatRunner)
+	QoS interface {
+		QoSThrottled(fs string) bool
+	}
 	Throttle struct {
 		// runtime
-		sleep         time.Duration
+		sleep         time.Duration // disk utilization and capacity
 		nextUtilCheck time.Time
 		nextCapCheck  time.Time
 		prevUtilPct   float32
 		prevFSUsedPct uint64
+		qosSleep      time.Duration // foreground QoS (see OnQoS)
 		// init-time
-		Riostat      *ios.IostatRunner
+		Riostat      ios.DiskUtilProvider
+		QoS          QoS
 		CapUsedHigh  *int64
 		DiskUtilLow  *int64
 		DiskUtilHigh *int64
@@ -50,14 +58,33 @@ type (
 var _ Throttler = &Throttle{}
 
 func (u *Throttle) Sleep() {
-	u.recompute()
-	if u.sleep > 0 {
-		time.Sleep(u.sleep)
+	if d := u.recompute(); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// recompute the disk and QoS sleep times and return the greater of the two
+func (u *Throttle) recompute() time.Duration {
+	u.recomputeDisk()
+	if (u.Flag & OnQoS) != 0 {
+		if !u.QoS.QoSThrottled(u.FS) {
+			u.qosSleep = 0
+		} else if u.qosSleep < initThrottleSleep {
+			u.qosSleep = initThrottleSleep
+		} else {
+			u.qosSleep *= 2
+			if u.qosSleep > maxThrottleSleep {
+				u.qosSleep = maxThrottleSleep
+			}
+		}
+	}
+	if u.qosSleep > u.sleep {
+		return u.qosSleep
 	}
+	return u.sleep
 }
 
-// recompute sleep time
-func (u *Throttle) recompute() {
+func (u *Throttle) recomputeDisk() {
 	var (
 		ok  bool
 		now = time.Now() // FIXME: this may cost if the caller's coming here every ms or so..
@@ -65,7 +92,7 @@ func (u *Throttle) recompute() {
 	if (u.Flag & OnFSUsed) != 0 {
 		usedFSPercentage := u.prevFSUsedPct
 		if now.After(u.nextCapCheck) {
-			usedFSPercentage, ok = ios.GetFSUsedPercentage(u.Path)
+			usedFSPercentage, ok = ios.FSUsedPercentage(u.Riostat, u.Path)
 			u.nextCapCheck = now.Add(fsCapCheckDuration)
 			if !ok {
 				glog.Errorf("Unable to retrieve used capacity for FS %s", u.FS)
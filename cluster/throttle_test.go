@@ -5,7 +5,6 @@
 package cluster
 
 import (
-	"strconv"
 	"testing"
 	"time"
 
@@ -25,7 +24,7 @@ type ctxT struct {
 	config cmn.Config
 }
 
-var riostat *ios.IostatRunner
+var riostat = ios.NewStaticDiskUtil()
 
 var ctx = &ctxT{}
 
@@ -61,21 +60,8 @@ func Test_Throttle(t *testing.T) {
 	ctx.config.Xaction.DiskUtilHighWM = 40
 	ctx.config.Periodic.StatsTime = time.Second
 
-	fs.Mountpaths = fs.NewMountedFS("local", "cloud")
-	fs.Mountpaths.Add("/")
-
-	riostat = ios.NewIostatRunner(fs.Mountpaths)
-	riostat.Setconf(&ctx.config)
-
-	go riostat.Run()
-	time.Sleep(time.Second)
-	defer func() { riostat.Stop(nil) }()
-
-	for disk := range riostat.Disk {
-		riostat.Disk[disk] = make(cmn.SimpleKVs, 0)
-		riostat.Disk[disk]["%util"] = strconv.Itoa(0)
-
-	}
+	riostat.SetUtil("", 0)
+	riostat.SetFSStats(1000, 400, 4096) // 60% used
 
 	testHighFSCapacityUsed(t, ctx.config.Xaction.DiskUtilLowWM-10)
 	testHighFSCapacityUsed(t, ctx.config.Xaction.DiskUtilLowWM+10)
@@ -87,7 +73,7 @@ func Test_Throttle(t *testing.T) {
 }
 
 func testHighFSCapacityUsed(t *testing.T, diskUtil int64) {
-	curCapacity, _ := ios.GetFSUsedPercentage("/")
+	curCapacity, _ := ios.FSUsedPercentage(riostat, "/")
 	oldLRUHWM := ctx.config.LRU.HighWM
 	ctx.config.LRU.HighWM = int64(curCapacity - 1)
 	thrctx := newThrottleContext()
@@ -277,7 +263,7 @@ func testMultipleLRUContexts(t *testing.T) {
 func testChangedFSUsedPercentageBeforeCapCheck(t *testing.T) {
 	thrctx := newThrottleContext()
 	oldLRUHWM := ctx.config.LRU.HighWM
-	curCapacity, _ := ios.GetFSUsedPercentage("/")
+	curCapacity, _ := ios.FSUsedPercentage(riostat, "/")
 	diskUtil := ctx.config.Xaction.DiskUtilHighWM + 10
 	ctx.config.LRU.HighWM = int64(curCapacity - 2)
 	*thrctx.CapUsedHigh = ctx.config.LRU.HighWM // NOTE: init time only
@@ -306,9 +292,7 @@ func testChangedDiskUtilBeforeUtilCheck(t *testing.T) {
 		t.Errorf(fmstr, 0, sleepDuration)
 	}
 
-	for disk := range riostat.Disk {
-		riostat.Disk[disk]["%util"] = "99"
-	}
+	riostat.SetUtil("", 99)
 
 	thrctx.recompute()
 	sleepDuration = thrctx.sleep
@@ -319,9 +303,7 @@ func testChangedDiskUtilBeforeUtilCheck(t *testing.T) {
 }
 
 func getSleepDuration(diskUtil int64, thrctx *Throttle) time.Duration {
-	for disk := range riostat.Disk {
-		riostat.Disk[disk]["%util"] = strconv.Itoa(int(diskUtil))
-	}
+	riostat.SetUtil("", float32(diskUtil))
 
 	thrctx.nextCapCheck = time.Time{}
 	thrctx.nextUtilCheck = time.Time{}
@@ -342,3 +324,38 @@ func newThrottleContext() *Throttle {
 		FS:           fileSystem,
 		Flag:         OnDiskUtil | OnFSUsed}
 }
+
+type qosMock bool
+
+func (q *qosMock) QoSThrottled(string) bool { return bool(*q) }
+
+func TestThrottleQoS(t *testing.T) {
+	throttled := qosMock(true)
+	thr := &Throttle{QoS: &throttled, FS: "/dev/sda1", Flag: OnQoS}
+	if d := thr.recompute(); d != initThrottleSleep {
+		t.Fatalf(fmstr, initThrottleSleep, d)
+	}
+	if d := thr.recompute(); d != 2*initThrottleSleep {
+		t.Fatalf(fmstr, 2*initThrottleSleep, d)
+	}
+	for i := 0; i < 20; i++ {
+		thr.recompute()
+	}
+	if d := thr.recompute(); d != maxThrottleSleep {
+		t.Fatalf(fmstr, maxThrottleSleep, d)
+	}
+	throttled = false
+	if d := thr.recompute(); d != 0 {
+		t.Errorf(fmstr, 0, d)
+	}
+
+	// the disk sleep state is kept apart from the QoS one
+	throttled = true
+	thr.sleep = 4 * initThrottleSleep
+	if d := thr.recompute(); d != 4*initThrottleSleep {
+		t.Errorf(fmstr, 4*initThrottleSleep, d)
+	}
+	if thr.sleep != 4*initThrottleSleep || thr.qosSleep != initThrottleSleep {
+		t.Errorf("disk sleep %v, QoS sleep %v: expected %v and %v", thr.sleep, thr.qosSleep, 4*initThrottleSleep, initThrottleSleep)
+	}
+}
@@ -7,18 +7,29 @@
 package cmn
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
 // string enum: http header, checksum, versioning
 const (
 	// http header
-	XattrXXHashVal  = "user.obj.dfchash"
-	XattrObjVersion = "user.obj.version"
+	XattrXXHashVal    = "user.obj.dfchash"
+	XattrObjVersion   = "user.obj.version"
+	XattrCompressed   = "user.obj.compressed"   // "zstd:<original size>" or CompressedNone
+	XattrExpires      = "user.obj.expires"      // expiration time (RFC3339), set with the object's TTL upon PUT
+	XattrCksumPending = "user.obj.cksumpending" // the checksum is yet to be computed in the background (async_checksum_put)
+	// compression (at rest)
+	CompressedZstd = "zstd"
+	CompressedNone = "none" // tried and found incompressible
 	// checksum hash function
 	ChecksumNone   = "none"
 	ChecksumXXHash = "xxhash"
+	ChecksumCRC32C = "crc32c" // CRC-32C (Castagnoli), hardware-accelerated - see cksum.go
 	ChecksumMD5    = "md5"
+	ChecksumSHA256 = "sha256" // client-supplied PUT checksums only
 	// buckets to inherit global checksum config
 	ChecksumInherit = "inherit"
 	// versioning
@@ -62,6 +73,33 @@ const (
 	ActNewPrimary  = "newprimary"
 	ActRevokeToken = "revoketoken"
 	ActElection    = "election"
+	ActBeginGroup  = "begingroup"  // open a (transactional) group of PUTs
+	ActCommitGroup = "commitgroup" // make all objects PUT within the group visible at once
+	ActAbortGroup  = "abortgroup"  // discard all objects PUT within the group
+	ActSelect      = "select"      // server-side select: return matching rows/fields of a CSV or JSON-lines object
+	ActListInval   = "listinval"   // target to target: drop the cached list pages of a Cloud bucket
+	ActChecksums   = "checksums"   // return the checksums and sizes of the objects without reading them
+	ActCompress    = "compress"    // compress the objects that have not been accessed for a while
+	ActExpire      = "expire"      // remove the objects whose TTL has expired
+	ActExport      = "export"      // archive a local bucket as tar shards in a Cloud bucket
+	ActImport      = "import"      // restore a local bucket from the tar shards of an export
+	ActPin         = "pin"         // never evict the objects (LRU, expiration) - see Pinned
+	ActUnpin       = "unpin"       // undo ActPin
+	ActPresign     = "presign"     // return the presigned URL of the object - see PresignMsg
+	ActSnapshot    = "snapshot"    // create a named read-only snapshot of a local bucket - see BucketSnapshot
+
+	// Atomic in-place rename of a local bucket (see api.RenameBucket)
+	ActRenameBucket      = "renamebck"
+	ActAbortRenameBucket = "abortrenamebck" // proxy to target: roll back the prepared rename
+
+	// Replace a failed target with the one that has joined under a different ID (see api.ReplaceTarget)
+	ActReplaceTarget = "replacetarget"
+
+	// Rolling upgrade (see api.StartRollingUpgrade): the daemon's maintenance mode (value: true
+	// or false), and restarting the daemons one by one (value: RollingUpgradeMsg)
+	ActMaintenance    = "maintenance"
+	ActRollingUpgrade = "rollingupgrade"
+	ActAbortUpgrade   = "abortupgrade"
 
 	// Actions for manipulating mountpaths (/v1/daemon/mountpaths)
 	ActMountpathEnable  = "enable"
@@ -94,13 +132,32 @@ const (
 	HeaderBucketDontEvictTime   = "LRUDontEvictTime"      // Enforces an eviction-free time period between [atime, atime+dontevicttime]
 	HeaderBucketCapUpdTime      = "LRUCapUpdTime"         // Minimum time to update the capacity
 	HeaderBucketLRUEnabled      = "LRUEnabled"            // LRU is run on a bucket only if this field is true
+	HeaderBucketListProps       = "ListProps"             // Default list-bucket properties (GetMsg.GetProps)
+	HeaderBucketListTimeFormat  = "ListTimeFormat"        // Default list-bucket time format (GetMsg.GetTimeFormat)
+	HeaderBucketPublicRead      = "PublicRead"            // true: anonymous read-only access is allowed
+	HeaderBucketSyncPolicy      = "SyncPolicy"            // PUT durability policy: none, fdatasync, fsync, or group
+	HeaderBucketDebugSample     = "DebugSample"           // fraction of the object requests sampled by the proxies
+	HeaderBucketPinned          = "BucketPinned"          // JSON-encoded Pinned; omitted if nothing is pinned
+	HeaderBucketAtimePolicy     = "AtimePolicy"           // access time tracking policy: track, relaxed, or ignore
+	HeaderBucketPassthroughSize = "PassthroughSize"       // GETs of the larger objects are not cached
+	HeaderBucketPrefetchBudget  = "PrefetchBudget"        // JSON-encoded PrefetchBudget; omitted if none
 	HeaderDFCChecksumType       = "DfcChecksumType"       // Checksum Type (xxhash, md5, none)
 	HeaderDFCChecksumVal        = "DfcChecksumVal"        // Checksum Value
+	HeaderDFCChecksumPending    = "DfcChecksumPending"    // "true": the object's checksum is yet to be computed (async_checksum_put)
 	HeaderDFCObjVersion         = "DfcObjVersion"         // Object version/generation
 	HeaderDFCObjAtime           = "DfcObjAtime"           // Object access time
+	HeaderDFCObjAccessCount     = "DfcObjAccessCount"     // Approximate number of recent accesses (see HotObject)
 	HeaderDFCReplicationSrc     = "DfcReplicationSrc"     // In replication PUT request specifies the source target
+	HeaderDFCProxyURLs          = "DfcProxyURLs"          // Comma-separated public URLs of the cluster's proxies, primary first
+	HeaderDFCIdempotencyKey     = "DfcIdempotencyKey"     // PUT: retries with the same key are not executed again (put_dedup)
+	HeaderDFCObjTTL             = "DfcObjTTL"             // PUT: the object is removed once the TTL (e.g. "24h") expires
+	HeaderDFCObjExpires         = "DfcObjExpires"         // Object expiration time (RFC3339)
 	HeaderSize                  = "Size"                  // Size of object in bytes
 	HeaderVersion               = "Version"               // Object version number
+	HeaderDFCAPIVersion         = "DfcAPIVersion"         // REST API version the request is to be served as per (VersionV2)
+	HeaderDFCRequestID          = "DfcRequestID"          // Request ID: the client's own, echoed - or assigned by the daemon (see HTTPError)
+	HeaderDFCNodeID             = "DfcNodeID"             // ID of the daemon that has served the request
+	HeaderDFCMaintenance        = "DfcMaintenance"        // "true": the request was rejected as the daemon is in maintenance mode
 )
 
 // URL Query "?name1=val1&name2=..."
@@ -111,6 +168,22 @@ const (
 	URLParamCheckCached = "check_cached" // true: check if object is cached in DFC
 	URLParamOffset      = "offset"       // Offset from where the object should be read
 	URLParamLength      = "length"       // the total number of bytes that need to be read from the offset
+	URLParamDays        = "days"         // number of days without access that makes an object "cold"
+	URLParamPutGroup    = "group"        // ID of the group of PUTs (see ActBeginGroup)
+	URLParamDisk        = "disk"         // name of the disk, e.g. "sda" (what=diskhistory)
+	URLParamSince       = "since"        // duration, e.g. "5m": return only the samples taken within (what=diskhistory|statshistory)
+	URLParamUntil       = "until"        // duration, e.g. "1h": return only the samples taken before that long ago (what=statshistory)
+	URLParamMetric      = "metric"       // comma-separated stats names, e.g. "get.n,get.lat" (what=statshistory)
+	URLParamResolution  = "resolution"   // "1m" | "10m" | "1h" (what=statshistory)
+	URLParamBucket      = "bucket"       // bucket name (what=reqsamples)
+	URLParamRefresh     = "refresh"      // true: list the Cloud bucket bypassing (and refreshing) the targets' list cache
+	URLParamReset       = "reset"        // true: reset the counters once returned (what=bucketstats, what=stats&snapshot=true)
+	URLParamTopN        = "topn"         // number of the objects to return (what=hotobjects)
+	URLParamBuckets     = "buckets"      // true: include the per-bucket breakdown of the counters (what=stats)
+	URLParamSnapshot    = "snapshot"     // true: return the snapshot of the counters (what=stats) - see StatsSnapshot
+	URLParamAggregate   = "aggregate"    // true: return the cluster-wide aggregates of the counters (what=stats) - see ClusterStatsAggregate
+	URLParamExpires     = "expires"      // presigned URL: expiration time, Unix seconds
+	URLParamSignature   = "signature"    // presigned URL: the signature - see PresignMsg
 	// internal use
 	URLParamLocal            = "loc" // true: bucket is local
 	URLParamFromID           = "fid" // source target ID
@@ -119,12 +192,15 @@ const (
 	URLParamPrimaryCandidate = "can" // ID of the candidate for the primary proxy
 	URLParamCached           = "cho" // true: return cached objects (names & metadata); false: list Cloud bucket
 	URLParamForce            = "frc" // true: force the operation (e.g., shutdown primary proxy and the entire cluster)
-	URLParamPrepare          = "prp" // true: request belongs to the "prepare" phase of the primary proxy election
+	URLParamPrepare          = "prp" // true: request belongs to the "prepare" phase of the primary proxy election (or of the group PUT commit)
+	URLParamFinalize         = "fin" // true: request belongs to the "finalize" phase of the group PUT commit
 	URLParamNonElectable     = "nel" // true: proxy is non-electable for the primary role
 	URLParamSmapVersion      = "vsm" // Smap version
 	URLParamBMDVersion       = "vbm" // version of the bucket-metadata
 	URLParamUnixTime         = "utm" // Unix time: number of nanoseconds elapsed since 01/01/70 UTC
 	URLParamReadahead        = "rah" // Proxy to target: readeahed
+	URLParamOrigin           = "org" // Proxy to target: the CORS origin of the redirected request
+	URLParamOriginSig        = "osg" // Proxy to target: the signature of URLParamOrigin (see dfc/cors.go)
 )
 
 // TODO: sort and some props are TBD
@@ -136,6 +212,12 @@ type GetMsg struct {
 	GetPrefix     string `json:"prefix"`      // object name filter: return only objects which name starts with prefix
 	GetPageMarker string `json:"pagemarker"`  // AWS/GCP: marker
 	GetPageSize   int    `json:"pagesize"`    // maximum number of entries returned by list bucket call
+	// GetDelimiter groups the names that contain the delimiter after the prefix into a single
+	// "directory" entry (S3-style common prefix), e.g. "a/b/" for "a/b/c" and "a/b/d" (prefix "a/")
+	GetDelimiter string `json:"delimiter,omitempty"`
+	// GetStrict fails the listing if any of the targets fails to respond; by default, the
+	// entries of the responsive targets are returned, and the failures - in BucketList.Errors
+	GetStrict bool `json:"strict,omitempty"`
 }
 
 // ListRangeMsgBase contains fields common to Range and List operations
@@ -150,6 +232,163 @@ type ListMsg struct {
 	Objnames []string `json:"objnames"`
 }
 
+// PutGroupMsg is returned by ActBeginGroup and is the value of ActCommitGroup and ActAbortGroup.
+// Commit must list the names of all the objects PUT within the group.
+type PutGroupMsg struct {
+	GroupID  string   `json:"group"`
+	Objnames []string `json:"objnames,omitempty"`
+}
+
+// PutGroupCommit is the result of ActCommitGroup. A failed commit is rolled back; the objects
+// that fail to roll back remain committed - those are listed.
+type PutGroupCommit struct {
+	GroupID   string            `json:"group"`
+	Committed []string          `json:"committed"`
+	Errors    map[string]string `json:"errors,omitempty"` // target ID => error
+}
+
+func (c *PutGroupCommit) Error() string {
+	errs := make([]string, 0, len(c.Errors))
+	for tid, errstr := range c.Errors {
+		errs = append(errs, tid+": "+errstr)
+	}
+	sort.Strings(errs)
+	return fmt.Sprintf("PUT group %s: failed to commit, %d object(s) remain committed, errors: %s",
+		c.GroupID, len(c.Committed), strings.Join(errs, "; "))
+}
+
+// ChecksumsMsg is the value of ActChecksums: the objects are either listed by name or, if
+// Objnames is empty, selected by the name prefix (empty prefix - all objects of the bucket)
+type ChecksumsMsg struct {
+	Objnames []string `json:"objnames,omitempty"`
+	Prefix   string   `json:"prefix,omitempty"`
+}
+
+// PrefetchBudget (BucketProps.PrefetchBudget) limits the Cloud requests - bucket list pages,
+// object GETs, and version checks - and the Cloud egress that prefetching a Cloud bucket may
+// consume per day (UTC). The budget is split evenly between the targets, each prefetching its
+// own (HRW) share of the objects. Zero: unlimited.
+type PrefetchBudget struct {
+	Requests int64 `json:"requests,omitempty"`
+	Bytes    int64 `json:"bytes,omitempty"`
+}
+
+// Pinned is the value of ActPin and ActUnpin, and the bucket property (BucketProps.Pinned)
+// that lists the bucket's objects that LRU and object expiration never evict: by name and
+// by name prefix. Objects are kept sorted.
+type Pinned struct {
+	Objects  []string `json:"objects,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+// ObjChecksum is the checksum and the size of a single object
+type ObjChecksum struct {
+	Type  string `json:"type"` // ChecksumXXHash etc.; ChecksumNone if the object is not checksummed
+	Value string `json:"value,omitempty"`
+	Size  int64  `json:"size"`
+}
+
+// ObjChecksums is the result of ActChecksums: object name => checksum; the objects that
+// are not stored in the cluster are omitted
+type ObjChecksums map[string]*ObjChecksum
+
+// ArchiveMsg is the value of ActExport and ActImport: the local bucket is
+// exported as tar shards (of up to ShardSize bytes each, 1GiB by default) to the Cloud
+// bucket under the Prefix, along with the manifest <prefix>/manifest.json - and imported
+// from the same
+type ArchiveMsg struct {
+	CloudBucket string           `json:"cloud_bucket"`
+	Prefix      string           `json:"prefix,omitempty"`
+	ShardSize   int64            `json:"shard_size,omitempty"`
+	Manifest    *ArchiveManifest `json:"manifest,omitempty"` // proxy => target: upload the manifest
+}
+
+// ArchiveShard is a single tar shard of an export
+type ArchiveShard struct {
+	Name    string `json:"name"` // object name in the Cloud bucket
+	Objects int64  `json:"objects"`
+	Size    int64  `json:"size"` // total size of the objects
+}
+
+// ArchiveManifest describes an export and is returned by ActExport
+type ArchiveManifest struct {
+	Bucket  string         `json:"bucket"`
+	Created time.Time      `json:"created"`
+	Objects int64          `json:"objects"`
+	Size    int64          `json:"size"`
+	Shards  []ArchiveShard `json:"shards"`
+}
+
+// ArchiveResult is the result of ActImport
+type ArchiveResult struct {
+	Objects int64 `json:"objects"`
+	Size    int64 `json:"size"`
+}
+
+// ArchiveStatus is the progress of the bucket's last export or import: returned by ActExport
+// and ActImport, which start it in the background, and by GET /v1/buckets/bucket-name?what=archive
+type ArchiveStatus struct {
+	Action   string           `json:"action"` // ActExport or ActImport
+	State    string           `json:"state"`  // ArchiveRunning, ArchiveDone, or ArchiveFailed
+	Started  time.Time        `json:"started"`
+	Finished time.Time        `json:"finished,omitempty"`
+	Manifest *ArchiveManifest `json:"manifest,omitempty"` // export, once done
+	Result   *ArchiveResult   `json:"result,omitempty"`   // import, once done
+	Err      string           `json:"error,omitempty"`
+}
+
+// ArchiveStatus.State enum
+const (
+	ArchiveRunning = "running"
+	ArchiveDone    = "done"
+	ArchiveFailed  = "failed"
+)
+
+// BucketSnapshot describes a snapshot of a local bucket (ActSnapshot); a list of those is
+// returned by GET /v1/snapshots/bucket-name
+type BucketSnapshot struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Objects int64     `json:"objects"`
+	Size    int64     `json:"size"` // total size of the objects, as stored
+}
+
+// PresignMsg is the value of ActPresign: the method (GET - which also allows HEAD - or PUT)
+// and the time (e.g. "1h") the presigned URL is valid for - up to presign.max_expiry
+// (configuration), which is also the default
+type PresignMsg struct {
+	Method  string `json:"method"`
+	Expires string `json:"expires,omitempty"`
+}
+
+// PresignedURL is the result of ActPresign: the object's URL that requires no credentials -
+// for the given method and until it expires
+type PresignedURL struct {
+	URL     string    `json:"url"`
+	Method  string    `json:"method"`
+	Expires time.Time `json:"expires"`
+}
+
+// SelectMsg is the value of ActSelect (a subset of S3 Select).
+// Where is a conjunction of conditions: "<field> <op> <value> [AND ...]" with op being
+// one of: =, !=, <, <=, >, >=, contains. Values are compared numerically if both sides are numbers.
+// Fields are JSON keys (dot-separated for nested objects) or CSV column names (with Header) or
+// 1-based column positions "_1", "_2", etc.
+type SelectMsg struct {
+	Format    string   `json:"format"`              // SelectFormatCSV | SelectFormatJSON
+	Fields    []string `json:"fields,omitempty"`    // fields to return (empty - entire rows)
+	Where     string   `json:"where,omitempty"`     // filter expression (empty - all rows)
+	Header    bool     `json:"header,omitempty"`    // CSV: the first record is the header
+	Delimiter string   `json:"delimiter,omitempty"` // CSV: field delimiter (default ",")
+	Limit     int64    `json:"limit,omitempty"`     // max number of rows to return (0 - unlimited)
+}
+
+// SelectMsg.Format enum
+const (
+	SelectFormatCSV  = "csv"
+	SelectFormatJSON = "json" // JSON lines: one JSON object per line
+)
+
 // RangeMsg contains a Prefix, Regex, and Range for a Range Operation
 type RangeMsg struct {
 	ListRangeMsgBase
@@ -159,9 +398,9 @@ type RangeMsg struct {
 }
 
 // MountpathList contains two lists:
-// * Available - the list of mountpaths that can be utilized by DFC
-// * Disabled - the list of disabled mountpaths, mountpaths that triggered
-//	            IO errors and after extra tests are found faulty
+//   - Available - the list of mountpaths that can be utilized by DFC
+//   - Disabled - the list of disabled mountpaths, mountpaths that triggered
+//     IO errors and after extra tests are found faulty
 type MountpathList struct {
 	Available []string `json:"available"`
 	Disabled  []string `json:"disabled"`
@@ -183,6 +422,34 @@ const (
 	GetWhatSmapVote   = "smapvote"
 	GetWhatMountpaths = "mountpaths"
 	GetWhatDaemonInfo = "daemoninfo"
+	GetWhatColdData   = "colddata"
+	GetWhatArchive    = "archive" // the bucket's last export or import - see ArchiveStatus
+	GetWhatDiskHist   = "diskhistory"
+	GetWhatStatsHist  = "statshistory"
+	GetWhatReqSamples = "reqsamples" // proxy: sampled object requests (BucketProps.DebugSample)
+	GetWhatRunners    = "runners"
+	GetWhatDashboard  = "dashboard"
+	GetWhatRebPlan    = "rebplan"
+	GetWhatSLO        = "slo"
+	GetWhatReplStats  = "replstats"
+	GetWhatBucketIO   = "bucketstats" // IO per bucket (chargeback); reset=true starts a new period
+	GetWhatVersions   = "apiversions" // REST API versions supported by the daemon
+	GetWhatSummary    = "summary"     // objects and bytes: per bucket (target), totals (proxy)
+	GetWhatHints      = "clienthints" // proxy: client configuration - see ClientHints
+	GetWhatHotObjects = "hotobjects"  // the most frequently accessed objects; topn=N per mountpath
+	GetWhatDiskHealth = "diskhealth"  // the disks' latest SMART reports
+	GetWhatKeepalive  = "keepalive"   // keepalive latency per peer and stats period
+	GetWhatIntraStats = "intrastats"  // intra-cluster HTTP client: requests and connections per peer
+	GetWhatPrefetch   = "prefetch"    // target: today's Cloud requests and bytes of prefetching, per bucket
+	GetWhatMaint      = "maintenance" // the daemon's maintenance mode - see MaintenanceStatus
+	GetWhatUpgrade    = "upgrade"     // primary proxy: the progress of the rolling upgrade - see RollingUpgradeStatus
+)
+
+// RunnerStatus.State enum
+const (
+	RunnerStarting = "starting"
+	RunnerRunning  = "running"
+	RunnerStopped  = "stopped"
 )
 
 // GetMsg.GetSort enum
@@ -235,7 +502,7 @@ type BucketEntry struct {
 	Size      int64  `json:"size"`                // size in bytes
 	Ctime     string `json:"ctime,omitempty"`     // formatted as per GetMsg.GetTimeFormat
 	Checksum  string `json:"checksum,omitempty"`  // checksum
-	Type      string `json:"type,omitempty"`      // "file" OR "directory"
+	Type      string `json:"type,omitempty"`      // "file" OR "directory" (GetMsg.GetDelimiter)
 	Atime     string `json:"atime,omitempty"`     // formatted as per GetMsg.GetTimeFormat
 	Bucket    string `json:"bucket,omitempty"`    // parent bucket name
 	Version   string `json:"version,omitempty"`   // version/generation ID. In GCP it is int64, in AWS it is a string
@@ -244,10 +511,632 @@ type BucketEntry struct {
 	Status    string `json:"status,omitempty"`    // empty - normal object, it can be "moved", "deleted" etc
 }
 
+// BucketEntry.Type
+const (
+	BucketEntryFile = "file"
+	BucketEntryDir  = "directory"
+)
+
+// ListDelimiter is the only delimiter supported by local buckets and by listing the cached
+// objects; Cloud buckets support any delimiter the Cloud provider does
+const ListDelimiter = "/"
+
 // BucketList represents the contents of a given bucket - somewhat analogous to the 'ls <bucket-name>'
 type BucketList struct {
 	Entries    []*BucketEntry `json:"entries"`
 	PageMarker string         `json:"pagemarker"`
+	// Errors is non-empty when the list is partial: target ID => the reason it is missing
+	// the target's entries (or, for Cloud buckets, the cached status of the target's objects)
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ColdDataStats contains the totals of the objects, of the objects that were
+// not accessed within the requested number of days ("cold"), and of the objects
+// that are older than LRU's dont_evict_time (eviction candidates)
+type ColdDataStats struct {
+	ObjCount   int64 `json:"obj_count"`
+	ObjSize    int64 `json:"obj_size"`
+	ColdCount  int64 `json:"cold_count"`
+	ColdSize   int64 `json:"cold_size"`
+	EvictCount int64 `json:"evict_count"`
+	EvictSize  int64 `json:"evict_size"`
+}
+
+// BucketColdSummary is the result of GET /v1/buckets/bucket-name?what=colddata.
+// Targets contains per-target breakdown, and the embedded ColdDataStats - the cluster-wide totals
+type BucketColdSummary struct {
+	ColdDataStats
+	Bucket  string                    `json:"bucket"`
+	Days    int                       `json:"days"`
+	Updated time.Time                 `json:"updated"` // the oldest of the target-computed (and cached) results
+	Targets map[string]*ColdDataStats `json:"targets,omitempty"`
+}
+
+// DiskSample is a single iostat report for a given disk: extended statistics by name,
+// e.g. "%util", "r/s", "w_await"
+type DiskSample struct {
+	Time    time.Time `json:"time"`
+	Metrics SimpleKVs `json:"metrics"`
+}
+
+// DiskHistory is the result of GET /v1/daemon?what=diskhistory: the target's recent
+// iostat reports, oldest first, by disk name
+type DiskHistory map[string][]DiskSample
+
+// ClusterDiskHistory is the result of GET /v1/cluster?what=diskhistory
+type ClusterDiskHistory struct {
+	Targets map[string]DiskHistory `json:"targets"`
+}
+
+// DiskHealth is the disk's latest SMART report; the counters that the disk does not
+// report are -1
+type DiskHealth struct {
+	Time        time.Time `json:"time"`
+	Source      string    `json:"source"`       // smartctl | sysfs
+	SMARTFailed bool      `json:"smart_failed"` // the disk's overall self-assessment
+	Reallocated int64     `json:"reallocated_sectors"`
+	Pending     int64     `json:"pending_sectors"`
+	MediaErrors int64     `json:"media_errors"`      // NVMe media errors; ATA offline uncorrectable sectors
+	Temperature int64     `json:"temperature"`       // Celsius
+	Failing     string    `json:"failing,omitempty"` // why the disk's mountpaths are disabled
+	Err         string    `json:"error,omitempty"`
+}
+
+// TargetDiskHealth is the result of GET /v1/daemon?what=diskhealth, by disk name
+type TargetDiskHealth map[string]DiskHealth
+
+// ClusterDiskHealth is the result of GET /v1/cluster?what=diskhealth
+type ClusterDiskHealth struct {
+	Targets map[string]TargetDiskHealth `json:"targets"`
+}
+
+// StatsSample summarizes the stats over a single period of the history's resolution that
+// starts at Time: counters are summed up, latencies averaged (in stats_latency_unit)
+type StatsSample struct {
+	Time   time.Time        `json:"time"`
+	Values map[string]int64 `json:"values"`
+}
+
+// StatsHistory is the result of GET /v1/daemon?what=statshistory: the daemon's downsampled
+// stats, oldest first
+type StatsHistory struct {
+	Resolution string        `json:"resolution"`
+	Samples    []StatsSample `json:"samples"`
+}
+
+// ClusterStatsHistory is the result of GET /v1/cluster?what=statshistory
+type ClusterStatsHistory struct {
+	Proxies map[string]*StatsHistory `json:"proxies"`
+	Targets map[string]*StatsHistory `json:"targets"`
+}
+
+// KeepaliveWindow summarizes the keepalives sent to (or, for the primary proxy, received
+// from) a peer within one stats period (periodic.stats_time): the number of the successful
+// ones and their round trip min, max, and average (ns), and the number of the failed ones
+type KeepaliveWindow struct {
+	Start  time.Time     `json:"start"`
+	N      int64         `json:"n"`
+	Failed int64         `json:"failed"`
+	Min    time.Duration `json:"min"`
+	Max    time.Duration `json:"max"`
+	Avg    time.Duration `json:"avg"`
+}
+
+// KeepalivePeer is the keepalive latency to a peer: the current (partial) window and the
+// completed ones, oldest first
+type KeepalivePeer struct {
+	Current KeepaliveWindow   `json:"current"`
+	History []KeepaliveWindow `json:"history"`
+}
+
+// KeepaliveLatency is the result of GET /v1/daemon?what=keepalive: the daemon's peers by ID
+// (the primary proxy pings all other daemons, the rest - the primary only)
+type KeepaliveLatency struct {
+	Time   time.Time                 `json:"time"`
+	Window string                    `json:"window"` // stats_time
+	Peers  map[string]*KeepalivePeer `json:"peers"`
+}
+
+// ClusterKeepaliveLatency is the result of GET /v1/cluster?what=keepalive - the latency
+// matrix: daemon ID => the daemon's keepalive latency to its peers
+type ClusterKeepaliveLatency struct {
+	Proxies map[string]*KeepaliveLatency `json:"proxies"`
+	Targets map[string]*KeepaliveLatency `json:"targets"`
+}
+
+// ReqSample is an object request sampled by the proxy (BucketProps.DebugSample): the time
+// spent in each of the request's phases on the proxy, and the target the request went to
+type ReqSample struct {
+	Time   time.Time     `json:"time"`
+	Method string        `json:"method"`
+	Bucket string        `json:"bucket"`
+	Object string        `json:"object"`
+	Target string        `json:"target"`
+	Phases []ReqPhase    `json:"phases"`
+	Total  time.Duration `json:"total"`
+}
+
+type ReqPhase struct {
+	Name    string        `json:"name"`
+	Latency time.Duration `json:"latency"`
+}
+
+// DaemonRunners is the result of GET /v1/daemon?what=runners: the lifecycle states
+// of the daemon's runners. The daemon is ready when all its runners are running
+type DaemonRunners struct {
+	Ready   bool           `json:"ready"`
+	Runners []RunnerStatus `json:"runners"`
+}
+
+type RunnerStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// ClusterDashboard is the result of GET /v1/cluster?what=dashboard: a point-in-time
+// overview of the cluster generated from the daemons' stats
+type ClusterDashboard struct {
+	Time        time.Time       `json:"time"`
+	Primary     string          `json:"primary"`
+	SmapVersion int64           `json:"smap_version"`
+	Rebalancing bool            `json:"rebalancing"`
+	Proxies     []DashboardNode `json:"proxies"`
+	Targets     []DashboardNode `json:"targets"`
+	Alerts      []string        `json:"alerts"`
+}
+
+// DashboardNode is a single daemon as seen by the dashboard; ops/sec are computed
+// over the interval since the previous dashboard request (zero on the first one)
+type DashboardNode struct {
+	DaemonID    string  `json:"daemon_id"`
+	URL         string  `json:"url"`
+	Online      bool    `json:"online"`
+	Rebalancing bool    `json:"rebalancing,omitempty"`
+	Emergency   bool    `json:"emergency,omitempty"` // capacity emergency mode: writes are refused
+	GetPerSec   float64 `json:"get_per_sec"`
+	PutPerSec   float64 `json:"put_per_sec"`
+	Errors      int64   `json:"errors"`
+	CPUIdle     string  `json:"cpu_idle,omitempty"`
+	Used        uint64  `json:"used,omitempty"`  // bytes, all mountpaths
+	Avail       uint64  `json:"avail,omitempty"` // ditto
+	UsedPct     int64   `json:"used_pct,omitempty"`
+}
+
+// RebalanceMove is the projected amount of data to move between a pair of targets
+type RebalanceMove struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Objects int64  `json:"objects"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// TargetRebalancePlan is the result of GET /v1/daemon?what=rebplan: what the target would
+// send to other targets if global rebalance started now, and the throughput the target
+// measured during its last rebalance (bytes/sec, zero if unknown)
+type TargetRebalancePlan struct {
+	SmapVersion int64           `json:"smap_version"`
+	Moves       []RebalanceMove `json:"moves"`
+	Throughput  int64           `json:"throughput"`
+}
+
+// ClusterRebalancePlan is the result of GET /v1/cluster?what=rebplan. When rebalance
+// requires confirmation (rebalance_conf.require_confirmation), the plan's ID must be
+// passed as the name of the rebalance action: {"action": "rebalance", "name": ID}
+type ClusterRebalancePlan struct {
+	ID          string          `json:"id"`
+	SmapVersion int64           `json:"smap_version"`
+	Moves       []RebalanceMove `json:"moves"`
+	Objects     int64           `json:"objects"`
+	Bytes       int64           `json:"bytes"`
+	// EstimatedTime is based on the throughput the targets measured during the last
+	// rebalance; zero if none of them has rebalanced yet
+	EstimatedTime time.Duration `json:"estimated_time"`
+}
+
+// SLOEvents is the number of good and bad events within a time window
+type SLOEvents struct {
+	Good int64 `json:"good"`
+	Bad  int64 `json:"bad"`
+}
+
+// SLOStatus is the state of a single service level objective (slo.objectives). The error
+// budget is the fraction of bad events the objective allows (1 - target). Burn rate is the
+// ratio of the actual fraction of bad events to the budget: at the burn rate of 1 the budget
+// is exhausted exactly at the end of the compliance window
+type SLOStatus struct {
+	Name       string        `json:"name"`
+	Target     float64       `json:"target"`
+	Window     time.Duration `json:"window"`
+	Events     SLOEvents     `json:"events"`      // within the compliance window
+	Events1h   SLOEvents     `json:"events_1h"`   // within the last hour
+	Events5m   SLOEvents     `json:"events_5m"`   // within the last 5 minutes
+	Compliance float64       `json:"compliance"`  // fraction of good events; 1 when there are no events
+	BudgetLeft float64       `json:"budget_left"` // fraction of the error budget left; negative when overspent
+	BurnRate1h float64       `json:"burn_rate_1h"`
+	BurnRate5m float64       `json:"burn_rate_5m"`
+	Met        bool          `json:"met"`
+}
+
+// SLOReport is the result of GET /v1/daemon?what=slo
+type SLOReport struct {
+	Time       time.Time   `json:"time"`
+	Objectives []SLOStatus `json:"objectives"`
+}
+
+// ClusterSLOReport is the result of GET /v1/cluster?what=slo: the objectives evaluated
+// over the events of all daemons, and the daemons' own reports
+type ClusterSLOReport struct {
+	SLOReport
+	Proxies map[string]*SLOReport `json:"proxies"`
+	Targets map[string]*SLOReport `json:"targets"`
+}
+
+// Eval computes compliance, error budget, and burn rates out of the event counts
+func (s *SLOStatus) Eval() {
+	budget := 1 - s.Target
+	s.Compliance, s.BurnRate1h, s.BurnRate5m = 1, 0, 0
+	if total := s.Events.Good + s.Events.Bad; total > 0 {
+		s.Compliance = float64(s.Events.Good) / float64(total)
+	}
+	s.BudgetLeft = 1
+	if budget > 0 {
+		s.BudgetLeft = 1 - (1-s.Compliance)/budget
+		s.BurnRate1h = s.Events1h.burnRate(budget)
+		s.BurnRate5m = s.Events5m.burnRate(budget)
+	}
+	s.Met = s.Compliance >= s.Target
+}
+
+func (e SLOEvents) burnRate(budget float64) float64 {
+	total := e.Good + e.Bad
+	if total == 0 {
+		return 0
+	}
+	return float64(e.Bad) / float64(total) / budget
+}
+
+// ReplDestStats is the replication traffic sent to a single destination: bytes sent and
+// requests made - including retries, which are also counted separately - and failed requests
+type ReplDestStats struct {
+	Bytes    int64 `json:"bytes"`
+	Requests int64 `json:"requests"`
+	Retries  int64 `json:"retries"`
+	Errors   int64 `json:"errors"`
+}
+
+// ReplStats is the result of GET /v1/daemon?what=replstats: destination URL => traffic
+type ReplStats map[string]*ReplDestStats
+
+// ClusterReplStats is the result of GET /v1/cluster?what=replstats: the traffic summed
+// over all targets, and the targets' own stats
+type ClusterReplStats struct {
+	Destinations ReplStats            `json:"destinations"`
+	Targets      map[string]ReplStats `json:"targets"`
+}
+
+// Add adds the traffic to the destination's stats
+func (s ReplStats) Add(dst string, d *ReplDestStats) {
+	acc, ok := s[dst]
+	if !ok {
+		acc = &ReplDestStats{}
+		s[dst] = acc
+	}
+	acc.Bytes += d.Bytes
+	acc.Requests += d.Requests
+	acc.Retries += d.Retries
+	acc.Errors += d.Errors
+}
+
+// PrefetchUsage is a target's consumption of a bucket's PrefetchBudget (or, if the bucket has
+// none, just the Cloud requests and bytes of prefetching it) over a single day (UTC)
+type PrefetchUsage struct {
+	Day         string `json:"day"`          // YYYY-MM-DD
+	Requests    int64  `json:"requests"`     // Cloud requests: made, and planned by the queued prefetches
+	Bytes       int64  `json:"bytes"`        // ditto, Cloud egress
+	MaxRequests int64  `json:"max_requests"` // the target's share of the budget; 0: unlimited
+	MaxBytes    int64  `json:"max_bytes"`    // ditto
+	Trimmed     int64  `json:"trimmed"`      // objects not prefetched as over the budget
+}
+
+// PrefetchUsages is the result of GET /v1/daemon?what=prefetch: bucket => today's usage
+type PrefetchUsages map[string]*PrefetchUsage
+
+// ClusterPrefetchUsages is the result of GET /v1/cluster?what=prefetch: the usage
+// summed over all targets, and the targets' own
+type ClusterPrefetchUsages struct {
+	Buckets PrefetchUsages            `json:"buckets"`
+	Targets map[string]PrefetchUsages `json:"targets"`
+}
+
+// Add adds the target's usage to the bucket's
+func (s PrefetchUsages) Add(bucket string, u *PrefetchUsage) {
+	acc, ok := s[bucket]
+	if !ok {
+		acc = &PrefetchUsage{Day: u.Day}
+		s[bucket] = acc
+	}
+	if u.Day > acc.Day {
+		*acc = PrefetchUsage{Day: u.Day} // (targets that have not prefetched the bucket today)
+	} else if u.Day < acc.Day {
+		return
+	}
+	acc.Requests += u.Requests
+	acc.Bytes += u.Bytes
+	acc.MaxRequests += u.MaxRequests
+	acc.MaxBytes += u.MaxBytes
+	acc.Trimmed += u.Trimmed
+}
+
+// StatsSnapshot is the result of GET /v1/daemon?what=stats&snapshot=true: a consistent copy
+// of the daemon's counters over its period (since, until) - since the daemon's start or the
+// last reset. Latencies are the number of samples and their sum (nanoseconds), so that the
+// pollers could compute the rates and the averages over their own intervals. Gauges - the
+// counters that go up and down, e.g. atime.map.size - are the current values, not reset.
+type StatsSnapshot struct {
+	Since     time.Time                  `json:"since"`
+	Until     time.Time                  `json:"until"`
+	Counters  map[string]int64           `json:"counters"`
+	Gauges    map[string]int64           `json:"gauges"`
+	Latencies map[string]LatencySnapshot `json:"latencies"`
+}
+
+// LatencySnapshot is the number of samples of a latency stat and their sum
+type LatencySnapshot struct {
+	Count int64 `json:"count"`
+	Sum   int64 `json:"sum"` // nanoseconds
+}
+
+// ClusterStatsSnapshot is the result of GET /v1/cluster?what=stats&snapshot=true: the
+// snapshot of the proxy and of each target
+type ClusterStatsSnapshot struct {
+	Proxy   *StatsSnapshot            `json:"proxy"`
+	Targets map[string]*StatsSnapshot `json:"targets"`
+}
+
+// ClusterStatsAggregate is the result of GET /v1/cluster?what=stats&aggregate=true: the
+// targets' counters, gauges, and latencies aggregated across the cluster over the interval
+// since the previous aggregate (by any poller) - see StatsAggregate. Outliers are the
+// targets that stand out of the rest.
+type ClusterStatsAggregate struct {
+	Since     time.Time                  `json:"since"` // the earliest of the targets' intervals
+	Until     time.Time                  `json:"until"`
+	Targets   int                        `json:"targets"` // the number of the targets aggregated
+	Counters  map[string]*StatsAggregate `json:"counters"`
+	Gauges    map[string]*StatsAggregate `json:"gauges"`
+	Latencies map[string]*StatsAggregate `json:"latencies"`
+	Outliers  []StatsOutlier             `json:"outliers,omitempty"`
+	Missing   []string                   `json:"missing,omitempty"` // IDs of the targets that did not respond
+}
+
+// StatsAggregate is a single stat across the targets. Total and Rate are cluster-wide:
+//   - counters: the total since the targets' start (or reset) and the number per second
+//     over the interval;
+//   - gauges: the sum of the targets' values (no rate);
+//   - latencies: the number of the samples since the targets' start (or reset), and the
+//     samples per second over the interval.
+//
+// Min, Max, and Avg are across the targets, of the rate (counters), of the value (gauges),
+// and of the average latency over the interval, in nanoseconds (latencies).
+type StatsAggregate struct {
+	Total     int64   `json:"total"`
+	Rate      float64 `json:"rate"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Avg       float64 `json:"avg"`
+	MinTarget string  `json:"min_target"`
+	MaxTarget string  `json:"max_target"`
+}
+
+// StatsOutlier is a target whose stat (see StatsAggregate.Min) is off the targets' median
+// by more than the factor - see StatsOutlierFactor
+type StatsOutlier struct {
+	Target string  `json:"target"`
+	Stat   string  `json:"stat"`
+	Kind   string  `json:"kind"` // "counter", "gauge", or "latency"
+	Value  float64 `json:"value"`
+	Median float64 `json:"median"`
+}
+
+// StatsOutlierFactor: a target is an outlier when its stat is above the median times the
+// factor or - counters and gauges - below the median divided by it; takes at least
+// StatsOutlierMinTargets targets
+const (
+	StatsOutlierFactor     = 2.0
+	StatsOutlierMinTargets = 3
+)
+
+// BucketIOStats is the IO of a single bucket: GETs and bytes read, PUTs and bytes written,
+// and DELETEs
+type BucketIOStats struct {
+	GetCount    int64 `json:"get.n"`
+	GetSize     int64 `json:"get.size"`
+	PutCount    int64 `json:"put.n"`
+	PutSize     int64 `json:"put.size"`
+	DeleteCount int64 `json:"delete.n"`
+}
+
+// BucketStats is the IO per bucket: bucket name => IO
+type BucketStats map[string]*BucketIOStats
+
+// TargetBucketStats is the result of GET /v1/daemon?what=bucketstats: the target's IO per
+// bucket in the period [Since, Until) - since the target's start or the last reset
+type TargetBucketStats struct {
+	Since   time.Time     `json:"since"`
+	Until   time.Time     `json:"until"`
+	Buckets BucketStats   `json:"buckets"`
+	Other   BucketIOStats `json:"other"` // buckets beyond the maximum number tracked by the target
+}
+
+// HotObject is a frequently accessed object: Count is the approximate number of its recent
+// accesses - never less than the actual number, and halved every 10 minutes, so that the
+// objects that are no longer read cool down
+type HotObject struct {
+	Bucket  string `json:"bucket"`
+	Objname string `json:"objname"`
+	Count   int64  `json:"count"`
+}
+
+// TargetHotObjects is the result of GET /v1/daemon?what=hotobjects: the hottest objects of
+// each mountpath, the hottest first
+type TargetHotObjects map[string][]HotObject
+
+// ClusterHotObjects is the result of GET /v1/cluster?what=hotobjects: the hottest objects of
+// the cluster, the hottest first, and the targets' own results
+type ClusterHotObjects struct {
+	Objects []HotObject                 `json:"objects"`
+	Targets map[string]TargetHotObjects `json:"targets"`
+}
+
+// ClusterBucketStats is the result of GET /v1/cluster?what=bucketstats: the IO per bucket
+// summed over all targets, and the targets' own stats (and periods)
+type ClusterBucketStats struct {
+	Buckets BucketStats                   `json:"buckets"`
+	Other   BucketIOStats                 `json:"other"`
+	Targets map[string]*TargetBucketStats `json:"targets"`
+}
+
+// Add adds the IO
+func (s *BucketIOStats) Add(d *BucketIOStats) {
+	s.GetCount += d.GetCount
+	s.GetSize += d.GetSize
+	s.PutCount += d.PutCount
+	s.PutSize += d.PutSize
+	s.DeleteCount += d.DeleteCount
+}
+
+// Add adds the IO to the bucket's stats
+func (s BucketStats) Add(bucket string, d *BucketIOStats) {
+	acc, ok := s[bucket]
+	if !ok {
+		acc = &BucketIOStats{}
+		s[bucket] = acc
+	}
+	acc.Add(d)
+}
+
+// StatsOtherBuckets is the StatsPerBucket entry that accumulates the counters of the buckets
+// rolled over (the least recently active, once the daemon tracks its maximum number of buckets)
+const StatsOtherBuckets = "*"
+
+// StatsPerBucket is the per-bucket breakdown of the request and error counters returned
+// by GET /v1/daemon?what=stats&buckets=true: bucket name => stats name => value; zeros omitted
+type StatsPerBucket map[string]map[string]int64
+
+// Add adds the other breakdown, e.g. of another target
+func (s StatsPerBucket) Add(other StatsPerBucket) {
+	for bucket, counters := range other {
+		acc, ok := s[bucket]
+		if !ok {
+			acc = make(map[string]int64, len(counters))
+			s[bucket] = acc
+		}
+		for name, val := range counters {
+			acc[name] += val
+		}
+	}
+}
+
+// ObjCount is the number of objects and their total size
+type ObjCount struct {
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// TargetCapacity is the result of GET /v1/daemon?what=summary: the target's objects per
+// local and Cloud bucket
+type TargetCapacity struct {
+	Local    map[string]*ObjCount `json:"local"`
+	Cloud    map[string]*ObjCount `json:"cloud"`
+	Counting bool                 `json:"counting"` // being recounted: the counts are approximate
+}
+
+// MaintenanceStatus is the result of GET /v1/daemon?what=maintenance. In maintenance mode
+// the daemon rejects new object and bucket requests (503, HeaderDFCMaintenance); once the
+// requests in flight are done, the daemon is Ready - safe to restart.
+type MaintenanceStatus struct {
+	Enabled  bool      `json:"enabled"`
+	Since    time.Time `json:"since"`    // in maintenance since
+	InFlight int64     `json:"inflight"` // object and bucket requests being served
+	Ready    bool      `json:"ready"`    // in maintenance, with no requests in flight
+	Started  time.Time `json:"started"`  // the daemon's (process) start time: changes upon restart
+}
+
+// RollingUpgradeMsg is the value of ActRollingUpgrade
+type RollingUpgradeMsg struct {
+	// Nodes are the IDs of the daemons to restart, in order; empty - all targets, then all
+	// proxies but the primary
+	Nodes []string `json:"nodes,omitempty"`
+	// DrainTimeout: how long the daemon's requests in flight may take to complete (default 2m)
+	DrainTimeout string `json:"drain_timeout,omitempty"`
+	// RestartTimeout: how long the daemon may take to restart and rejoin (default 10m)
+	RestartTimeout string `json:"restart_timeout,omitempty"`
+	// Shutdown: shut the drained daemon down for its supervisor (e.g., systemd) to restart it;
+	// otherwise, restarting the daemon in UpgradeReady phase is up to the operator
+	Shutdown bool `json:"shutdown,omitempty"`
+}
+
+// RollingUpgradeStatus is the result of GET /v1/cluster?what=upgrade: the progress of the
+// last rolling upgrade
+type RollingUpgradeStatus struct {
+	State    string         `json:"state"` // UpgradeRunning, UpgradeDone, UpgradeAborted, or UpgradeFailed
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished,omitempty"`
+	Current  string         `json:"current,omitempty"` // ID of the daemon being upgraded
+	Done     int            `json:"done"`              // daemons restarted
+	Nodes    []*UpgradeNode `json:"nodes"`
+	Err      string         `json:"error,omitempty"`
+}
+
+// UpgradeNode is the progress of the rolling upgrade of a single daemon
+type UpgradeNode struct {
+	ID    string    `json:"id"`
+	Role  string    `json:"role"`  // "target" or "proxy"
+	Phase string    `json:"phase"` // UpgradePending, UpgradeDraining, ...
+	Since time.Time `json:"since"` // in the phase since
+	Err   string    `json:"error,omitempty"`
+}
+
+// RollingUpgradeStatus.State and UpgradeNode.Phase enums: each daemon is pending, draining
+// (in maintenance, waiting for its requests in flight), ready (to be restarted - by the
+// operator), and done once it has restarted and rejoined; or failed, or aborted
+const (
+	UpgradeRunning  = "running"
+	UpgradeDone     = "done"
+	UpgradeAborted  = "aborted"
+	UpgradeFailed   = "failed"
+	UpgradePending  = "pending"
+	UpgradeDraining = "draining"
+	UpgradeReady    = "ready"
+)
+
+// ClusterSummary is the result of GET /v1/cluster?what=summary: the objects stored in the
+// cluster, in total and split by provider and by local vs cached (Cloud) buckets
+type ClusterSummary struct {
+	ObjCount
+	Local     ObjCount             `json:"local"`     // objects of the local buckets
+	Cached    ObjCount             `json:"cached"`    // cached objects of the Cloud buckets
+	Providers map[string]*ObjCount `json:"providers"` // e.g., "dfc" (local buckets), "aws"
+	Targets   int                  `json:"targets"`   // number of targets counted
+	Counting  []string             `json:"counting"`  // targets being recounted
+}
+
+// Add adds the objects
+func (c *ObjCount) Add(d *ObjCount) {
+	c.Objects += d.Objects
+	c.Bytes += d.Bytes
+}
+
+// NonNegative returns a copy of the counts, either of which is zero if negative (e.g.,
+// objects removed while being counted)
+func (c *ObjCount) NonNegative() *ObjCount {
+	out := *c
+	if out.Objects < 0 {
+		out.Objects = 0
+	}
+	if out.Bytes < 0 {
+		out.Bytes = 0
+	}
+	return &out
 }
 
 // BucketNames is used to transfer all bucket names known to the system
@@ -264,7 +1153,8 @@ const (
 // RESTful URL path: l1/l2/l3
 const (
 	// l1
-	Version = "v1"
+	Version   = "v1"
+	VersionV2 = "v2" // v1 with the breaking changes, e.g. errors always as JSON (HTTPError)
 	// l2
 	Buckets   = "buckets"
 	Objects   = "objects"
@@ -275,7 +1165,11 @@ const (
 	Metasync  = "metasync"
 	Health    = "health"
 	Vote      = "vote"
+	MsgBus    = "msgbus"
 	Transport = "transport"
+	Faults    = "faults" // fault injection - debug builds only (build tag "faultinject")
+	Metrics   = "metrics"
+	Snapshots = "snapshots" // bucket snapshots: /v1/snapshots/bucket-name[/snapshot-name[/object-name]]
 	// l3
 	SyncSmap   = "syncsmap"
 	Keepalive  = "keepalive"
@@ -297,11 +1191,65 @@ const (
 	XactionStatusCompleted  = "Completed"
 )
 
+// Fault injection points (FaultSpec.Point) and kinds (FaultSpec.Kind); the kinds supported
+// by a point are listed next to it
+const (
+	FaultPointGet       = "get"       // delay, eio, short_read
+	FaultPointPut       = "put"       // delay, eio
+	FaultPointReplicate = "replicate" // delay, drop
+	FaultPointCloud     = "cloud"     // delay, 503
+	FaultPointRebalance = "rebalance" // delay, eio
+
+	FaultDelay     = "delay"      // sleep for FaultSpec.Delay, then proceed as usual
+	FaultEIO       = "eio"        // fail as if the disk has returned EIO
+	FaultShortRead = "short_read" // send only the first half of the object
+	FaultDrop      = "drop"       // drop the replication request
+	Fault503       = "503"        // fail as if the Cloud has returned 503 Service Unavailable
+)
+
+// FaultSpec is a fault injected into the target's datapath (PUT /v1/faults): the fault
+// is triggered by the requests at the Point that match the (optional) Bucket and Prefix,
+// Count times or, if Count is zero, until cleared (DELETE /v1/faults)
+type FaultSpec struct {
+	Point    string        `json:"point"`
+	Kind     string        `json:"kind"`
+	DelayStr string        `json:"delay,omitempty"` // FaultDelay only
+	Bucket   string        `json:"bucket,omitempty"`
+	Prefix   string        `json:"prefix,omitempty"`
+	Count    int64         `json:"count,omitempty"`
+	Hits     int64         `json:"hits"` // number of times triggered - read-only
+	Delay    time.Duration `json:"-"`
+}
+
 const (
 	RWPolicyCloud    = "cloud"
 	RWPolicyNextTier = "next_tier"
 )
 
+// PUT durability policies (BucketProps.SyncPolicy)
+const (
+	SyncPolicyNone  = "none"      // page cache only (default)
+	SyncPolicyData  = "fdatasync" // fdatasync the object before acknowledging PUT
+	SyncPolicyFull  = "fsync"     // fsync the object and its directory before acknowledging PUT
+	SyncPolicyGroup = "group"     // sync the mountpath's filesystem once per batch of PUTs (disk_config.sync_group_time)
+)
+
+// Access time tracking policies (BucketProps.AtimePolicy); access times are tracked only if
+// LRU is enabled for the bucket
+const (
+	AtimeTrack   = "track"   // every access (default)
+	AtimeRelaxed = "relaxed" // an access only if the previous one is older than one hour
+	AtimeIgnore  = "ignore"  // never: LRU and the rest use the access times on disk, as of the last PUT
+)
+
+// Orders in which targets send misplaced objects during global rebalance (rebalance_conf.order)
+const (
+	RebOrderNone       = "none"        // as traversed (default)
+	RebOrderLargeFirst = "large_first" // the largest objects first
+	RebOrderSmallFirst = "small_first" // the smallest objects first
+	RebOrderMixed      = "mixed"       // one object of each size class in turn
+)
+
 // BucketProps defines the configuration of the bucket with regard to
 // its type, checksum, and LRU. These characteristics determine its behaviour
 // in response to operations on the bucket itself or the objects inside the bucket.
@@ -334,10 +1282,48 @@ type BucketProps struct {
 
 	// LRUConf is the embedded struct of the same name
 	LRUConf `json:"lru_props"`
+
+	// ListProps is the default GetMsg.GetProps for listing the bucket: applies when
+	// the list request does not specify the properties, e.g. "size, checksum, atime"
+	ListProps string `json:"list_props,omitempty"`
+
+	// ListTimeFormat is the default GetMsg.GetTimeFormat for listing the bucket
+	ListTimeFormat string `json:"list_time_format,omitempty"`
+
+	// PublicRead allows anonymous (unauthenticated) GET and HEAD of the bucket and its
+	// objects, and listing the bucket's objects. Writes still require a valid token.
+	// Applies only when authentication is enabled.
+	PublicRead bool `json:"public_read,omitempty"`
+
+	// SyncPolicy is the durability policy for PUT: "none" (default), "fdatasync", "fsync", or "group"
+	SyncPolicy string `json:"sync_policy,omitempty"`
+
+	// DebugSample is the fraction of the bucket's object requests, from 0 to 1, whose phase
+	// timings and target are recorded by the proxies (GET /v1/daemon?what=reqsamples)
+	DebugSample float64 `json:"debug_sample,omitempty"`
+
+	// AtimePolicy is the access time tracking policy: "track" (default), "relaxed", or "ignore"
+	AtimePolicy string `json:"atime_policy,omitempty"`
+
+	// PassthroughSize: GETs of the (Cloud) bucket's objects that are not cached and are larger
+	// than this many bytes are streamed from the Cloud without caching; 0 (default) - disabled
+	PassthroughSize int64 `json:"passthrough_size,omitempty"`
+
+	// PrefetchBudget limits the daily Cloud requests and bytes of prefetching the (Cloud) bucket
+	PrefetchBudget *PrefetchBudget `json:"prefetch_budget,omitempty"`
+
+	// Pinned lists the objects that LRU and object expiration never evict; it is not
+	// changed by setprops and resetprops - only by ActPin and ActUnpin
+	Pinned *Pinned `json:"pinned,omitempty"`
+
+	// Snapshots lists the names of the local bucket's snapshots; it is not changed by
+	// setprops and resetprops - only by creating and deleting snapshots
+	Snapshots []string `json:"snapshots,omitempty"`
 }
 
 // ObjectProps
 type ObjectProps struct {
-	Size    int
-	Version string
+	Size        int
+	Version     string
+	AccessCount int64 // see HotObject; zero if the object is not cached by the cluster
 }
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package cmn
+
+import (
+	"bytes"
+	"crypto/md5"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestStoredCksum(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	for _, kind := range []string{ChecksumXXHash, ChecksumCRC32C} {
+		val, errstr := ComputeCksum(kind, bytes.NewReader(data), nil)
+		if errstr != "" {
+			t.Fatal(errstr)
+		}
+		k, v := ParseStoredCksum(MakeStoredCksum(kind, val))
+		if k != kind || v != val {
+			t.Errorf("%s: expected %s, got %s:%s", kind, val, k, v)
+		}
+	}
+	// as computed by ComputeXXHash and stored prior to crc32c
+	val, _ := ComputeXXHash(bytes.NewReader(data), nil)
+	if k, v := ParseStoredCksum([]byte(val)); k != ChecksumXXHash || v != val {
+		t.Errorf("expected xxhash %s, got %s:%s", val, k, v)
+	}
+	if val, _ := ComputeCksum(ChecksumCRC32C, bytes.NewReader([]byte("123456789")), nil); val != "e3069283" {
+		t.Errorf("expected CRC-32C check value e3069283, got %s", val)
+	}
+	if _, errstr := ComputeCksum(ChecksumMD5, bytes.NewReader(data), nil); errstr == "" {
+		t.Error("expected md5 to be unsupported")
+	}
+}
+
+//
+// throughput: go test -run=NONE -bench=Cksum ./cmn
+//
+
+const benchCksumSize = MiB
+
+func benchCksum(b *testing.B, newHash func() io.Writer) {
+	data := make([]byte, benchCksumSize)
+	rand.Read(data)
+	b.SetBytes(benchCksumSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newHash().Write(data)
+	}
+}
+
+func BenchmarkCksumXXHash(b *testing.B) {
+	benchCksum(b, func() io.Writer { return NewCksumHash(ChecksumXXHash) })
+}
+
+func BenchmarkCksumCRC32C(b *testing.B) {
+	benchCksum(b, func() io.Writer { return NewCksumHash(ChecksumCRC32C) })
+}
+
+// for reference
+func BenchmarkCksumMD5(b *testing.B) {
+	benchCksum(b, func() io.Writer { return md5.New() })
+}
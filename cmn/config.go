@@ -14,27 +14,48 @@ import (
 // CONFIGURATION
 //
 type Config struct {
-	Confdir          string          `json:"confdir"`
-	CloudProvider    string          `json:"cloudprovider"`
-	CloudBuckets     string          `json:"cloud_buckets"`
-	LocalBuckets     string          `json:"local_buckets"`
-	Readahead        RahConf         `json:"readahead"`
-	Log              LogConf         `json:"log"`
-	Periodic         PeriodConf      `json:"periodic"`
-	Timeout          TimeoutConf     `json:"timeout"`
-	Proxy            ProxyConf       `json:"proxyconfig"`
-	LRU              LRUConf         `json:"lru_config"`
-	Xaction          XactionConf     `json:"xaction_config"`
-	Rebalance        RebalanceConf   `json:"rebalance_conf"`
-	Replication      ReplicationConf `json:"replication"`
-	Cksum            CksumConf       `json:"cksum_config"`
-	Ver              VersionConf     `json:"version_config"`
-	FSpaths          SimpleKVs       `json:"fspaths"`
-	TestFSP          TestfspathConf  `json:"test_fspaths"`
-	Net              NetConf         `json:"netconfig"`
-	FSHC             FSHCConf        `json:"fshc"`
-	Auth             AuthConf        `json:"auth"`
-	KeepaliveTracker KeepaliveConf   `json:"keepalivetracker"`
+	Confdir          string             `json:"confdir"`
+	CloudProvider    string             `json:"cloudprovider"`
+	CloudBuckets     string             `json:"cloud_buckets"`
+	LocalBuckets     string             `json:"local_buckets"`
+	Readahead        RahConf            `json:"readahead"`
+	Log              LogConf            `json:"log"`
+	Periodic         PeriodConf         `json:"periodic"`
+	Timeout          TimeoutConf        `json:"timeout"`
+	Proxy            ProxyConf          `json:"proxyconfig"`
+	LRU              LRUConf            `json:"lru_config"`
+	Xaction          XactionConf        `json:"xaction_config"`
+	Rebalance        RebalanceConf      `json:"rebalance_conf"`
+	Replication      ReplicationConf    `json:"replication"`
+	Cksum            CksumConf          `json:"cksum_config"`
+	Ver              VersionConf        `json:"version_config"`
+	FSpaths          SimpleKVs          `json:"fspaths"`
+	TestFSP          TestfspathConf     `json:"test_fspaths"`
+	Net              NetConf            `json:"netconfig"`
+	FSHC             FSHCConf           `json:"fshc"`
+	Auth             AuthConf           `json:"auth"`
+	KeepaliveTracker KeepaliveConf      `json:"keepalivetracker"`
+	Disk             DiskConf           `json:"disk_config"`
+	ColdGet          ColdGetConf        `json:"cold_get"`
+	SLO              SLOConf            `json:"slo"`
+	ListCache        ListCacheConf      `json:"list_cache"`
+	MmapRead         MmapReadConf       `json:"mmap_read"`
+	FDCache          FDCacheConf        `json:"fd_cache"`
+	MDCache          MDCacheConf        `json:"md_cache"`
+	DirectIO         DirectIOConf       `json:"direct_io"`
+	AtimeJournal     AtimeJournalConf   `json:"atime_journal"`
+	AtimeDrain       AtimeDrainConf     `json:"atime_drain"`
+	AtimeFlush       AtimeFlushConf     `json:"atime_flush"`
+	EndpointLimits   EndpointLimitsConf `json:"endpoint_limits"`
+	Compression      CompressionConf    `json:"compression"`
+	PutDedup         PutDedupConf       `json:"put_dedup"`
+	ObjTTL           ObjTTLConf         `json:"obj_ttl"`
+	QoS              QoSConf            `json:"qos"`
+	SMART            SMARTConf          `json:"smart"`
+	CORS             CORSConf           `json:"cors"`
+	Presign          PresignConf        `json:"presign"`
+	StatsD           StatsDConf         `json:"statsd"`
+	Metrics          MetricsConf        `json:"metrics"`
 }
 
 type RahConf struct {
@@ -43,8 +64,224 @@ type RahConf struct {
 	ByProxy   bool  `json:"rahbyproxy"`
 	Discard   bool  `json:"rahdiscard"`
 	Enabled   bool  `json:"rahenabled"`
+	// HotObjects is the number of the most frequently accessed objects of each mountpath
+	// to pre-warm in the page cache every minute; zero disables
+	HotObjects int `json:"rahhotobjects"`
 }
 
+// ColdGetConf configures parallel cold GET: objects larger than the part size are downloaded
+// from the Cloud with up to Concurrency ranged GETs in parallel (Concurrency <= 1 disables)
+type ColdGetConf struct {
+	PartSizeStr string `json:"part_size"`
+	Concurrency int    `json:"concurrency"`
+	PartSize    int64  `json:"-"`
+}
+
+// ListCacheConf configures the targets' cache of Cloud bucket list pages: a cached page is
+// served for up to TTL ("" or zero disables caching), and each bucket keeps at most MaxPages
+type ListCacheConf struct {
+	TTLStr   string        `json:"ttl"`
+	TTL      time.Duration `json:"-"`
+	MaxPages int           `json:"max_pages"`
+}
+
+// PutDedupConf configures the targets' records of the PUTs with idempotency keys: a PUT
+// retried with the same key within TTL ("" or zero disables) is not executed again; each
+// target keeps at most MaxKeys records (zero - the default of 64K)
+type PutDedupConf struct {
+	TTLStr  string        `json:"ttl"`
+	TTL     time.Duration `json:"-"`
+	MaxKeys int           `json:"max_keys"`
+}
+
+// MmapReadConf configures the memory-mapped GET path: objects up to MaxObjSize are served
+// out of their (cached) memory mappings, with at most CacheSize bytes mapped at any time
+type MmapReadConf struct {
+	Enabled       bool   `json:"enabled"`
+	MaxObjSizeStr string `json:"max_object_size"`
+	CacheSizeStr  string `json:"cache_size"`
+	MaxObjSize    int64  `json:"-"`
+	CacheSize     int64  `json:"-"`
+}
+
+// FDCacheConf configures the cache of the open file descriptors of the recently read
+// objects: up to Size descriptors are kept open (and shared) by the GETs
+type FDCacheConf struct {
+	Enabled bool `json:"enabled"`
+	Size    int  `json:"size"`
+}
+
+// MDCacheConf configures the cache of the recently accessed objects' metadata (xattrs):
+// up to Size entries, saved every SnapshotTime (and upon shutdown) to be loaded on restart
+type MDCacheConf struct {
+	Enabled         bool          `json:"enabled"`
+	Size            int           `json:"size"`
+	SnapshotTimeStr string        `json:"snapshot_time"`
+	SnapshotTime    time.Duration `json:"-"`
+}
+
+// DirectIOConf configures the GETs and PUTs of entire objects of at least MinObjSize to
+// bypass the page cache (O_DIRECT)
+type DirectIOConf struct {
+	Enabled       bool   `json:"enabled"`
+	MinObjSizeStr string `json:"min_object_size"`
+	MinObjSize    int64  `json:"-"`
+}
+
+// AtimeJournalConf configures the on-disk journal of the access times that are yet to be
+// flushed to the objects: the journal is fsynced every SyncTime and replayed upon restart
+type AtimeJournalConf struct {
+	Enabled     bool          `json:"enabled"`
+	SyncTimeStr string        `json:"sync_time"`
+	SyncTime    time.Duration `json:"-"`
+}
+
+// AtimeDrainConf configures flushing all the access times cached in memory upon the
+// target's shutdown, for at most Timeout
+type AtimeDrainConf struct {
+	Enabled    bool          `json:"enabled"`
+	TimeoutStr string        `json:"timeout"`
+	Timeout    time.Duration `json:"-"`
+}
+
+// AtimeFlushConf limits the rate at which the cached access times are written to the objects
+// of a given mountpath: at most Rate per second (zero means unlimited)
+type AtimeFlushConf struct {
+	Rate int64 `json:"rate"`
+}
+
+// EndpointLimitsConf limits the number of concurrent requests that the proxy handles for each
+// of its expensive endpoints (zero means unlimited); the requests over the limit wait for at
+// most QueueTimeout
+type EndpointLimitsConf struct {
+	List            int           `json:"list"`    // list bucket
+	Stats           int           `json:"stats"`   // GET /v1/cluster?what=stats
+	Xaction         int           `json:"xaction"` // GET /v1/cluster?what=xaction
+	QueueTimeoutStr string        `json:"queue_timeout"`
+	QueueTimeout    time.Duration `json:"-"`
+}
+
+// CompressionConf configures the compression of the cold data at rest: every RunTime the
+// target compresses the objects that have not been accessed within ColdTime; the objects
+// read again are decompressed back if DecompressHot
+type CompressionConf struct {
+	Enabled       bool          `json:"enabled"`
+	ColdTimeStr   string        `json:"cold_time"`
+	RunTimeStr    string        `json:"run_time"`
+	DecompressHot bool          `json:"decompress_hot"`
+	ColdTime      time.Duration `json:"-"`
+	RunTime       time.Duration `json:"-"`
+}
+
+// ObjTTLConf configures the removal of the objects PUT with a TTL: every RunTime the target
+// removes the objects that have expired (zero RunTime disables)
+type ObjTTLConf struct {
+	RunTimeStr string        `json:"run_time"`
+	RunTime    time.Duration `json:"-"`
+}
+
+// CORSConf configures Cross-Origin Resource Sharing on the object endpoints (/v1/objects) of
+// the proxies and the targets, so that browser-based clients could read and write objects
+// directly; no AllowedOrigins disables CORS
+type CORSConf struct {
+	AllowedOrigins []string      `json:"allowed_origins"` // e.g. "https://labeling.example.com"; "*" - any
+	MaxAgeStr      string        `json:"max_age"`         // how long browsers may cache the preflight responses
+	MaxAge         time.Duration `json:"-"`
+}
+
+// PresignConf configures the presigned object URLs - time-limited GETs and PUTs of a single
+// object that require no credentials. The URLs are signed (HMAC-SHA256) with the Secret,
+// which must be the same on all proxies; no Secret (and no auth secret) disables presigning
+type PresignConf struct {
+	Secret       string        `json:"secret"` // "" - auth.secret
+	MaxExpiryStr string        `json:"max_expiry"`
+	MaxExpiry    time.Duration `json:"-"`
+}
+
+// StatsDConf configures the daemon's StatsD client. The metrics are batched - packed into
+// packets of up to MaxPacketSize bytes, sent at least every FlushInterval (zero: each metric
+// is sent right away); with Tags (dogstatsd or influxdb), they are tagged with the daemon's
+// ID - rather than prefixed - and, where applicable, the bucket. The address is resolved
+// again every ResolveInterval, and right away while it does not resolve
+type StatsDConf struct {
+	Address            string        `json:"address"` // host:port
+	FlushIntervalStr   string        `json:"flush_interval"`
+	MaxPacketSize      int           `json:"max_packet_size"`
+	Tags               string        `json:"tags"` // "" - none, "dogstatsd", or "influxdb"
+	ResolveIntervalStr string        `json:"resolve_interval"`
+	FlushInterval      time.Duration `json:"-"`
+	ResolveInterval    time.Duration `json:"-"`
+}
+
+// MetricsConf selects the sink of the daemon's metrics (see stats.MetricsSink): "statsd"
+// (default, configured by StatsDConf), "graphite", "none", or a sink registered with
+// stats.RegisterSink
+type MetricsConf struct {
+	Sink     string       `json:"sink"`
+	Graphite GraphiteConf `json:"graphite"`
+}
+
+// GraphiteConf configures the Graphite sink: the metrics are aggregated and sent to carbon
+// every FlushInterval; with Tags (Graphite 1.1+), the daemon's ID and the bucket are sent as
+// tags rather than as part of the name
+type GraphiteConf struct {
+	Address          string        `json:"address"` // host:port of the carbon plaintext listener
+	FlushIntervalStr string        `json:"flush_interval"`
+	Tags             bool          `json:"tags"`
+	FlushInterval    time.Duration `json:"-"`
+}
+
+// QoSConf configures the per-mountpath budgets of the foreground GETs and PUTs: while a
+// mountpath's disks serve GETs or PUTs and do more than IOPS operations or MBps megabytes
+// per second (zero means unlimited), the background xactions that read and write the
+// mountpath - LRU, rebalance, and prefetch - are throttled
+type QoSConf struct {
+	Enabled bool  `json:"enabled"`
+	IOPS    int64 `json:"iops"`
+	MBps    int64 `json:"mbps"`
+}
+
+// SMARTConf configures the disk health poller: every Interval targets read the SMART
+// attributes of their mountpaths' disks, and a disk that fails its self-assessment or
+// reaches any of the limits (zero means no limit) has its mountpaths disabled by FSHC
+type SMARTConf struct {
+	Enabled          bool          `json:"enabled"`
+	IntervalStr      string        `json:"interval"`
+	Interval         time.Duration `json:"-"`
+	ReallocatedLimit int64         `json:"reallocated_sectors"`
+	MediaErrorsLimit int64         `json:"media_errors"`
+	TemperatureLimit int64         `json:"temperature"` // Celsius
+}
+
+// SLOConf defines the service level objectives that each daemon evaluates continuously
+// out of its own stats; the error budget is computed over the (sliding) compliance window
+type SLOConf struct {
+	WindowStr  string         `json:"window"`
+	Window     time.Duration  `json:"-"`
+	Objectives []SLOObjective `json:"objectives"`
+}
+
+// SLOObjective requires the Target fraction of events to be good, where the events are:
+//  - SLOKindLatency:   the samples of the latency stat Stat; good if below Threshold
+//  - SLOKindErrorRate: the requests counted by Stat (good) and by ErrStat (bad)
+// For instance, "GET p99 < 50ms" is {latency, get.lat, 50ms, 0.99}, and
+// "GET error rate < 0.1%" is {error_rate, get.n, err.get.n, 0.999}
+type SLOObjective struct {
+	Name         string        `json:"name"`
+	Kind         string        `json:"kind"`
+	Stat         string        `json:"stat"`
+	ErrStat      string        `json:"err_stat,omitempty"`
+	ThresholdStr string        `json:"threshold,omitempty"`
+	Threshold    time.Duration `json:"-"`
+	Target       float64       `json:"target"`
+}
+
+// SLOObjective.Kind
+const (
+	SLOKindLatency   = "latency"
+	SLOKindErrorRate = "error_rate"
+)
+
 type LogConf struct {
 	Dir      string `json:"logdir"`      // log directory
 	Level    string `json:"loglevel"`    // log level aka verbosity
@@ -53,11 +290,31 @@ type LogConf struct {
 }
 
 type PeriodConf struct {
-	StatsTimeStr     string `json:"stats_time"`
-	RetrySyncTimeStr string `json:"retry_sync_time"`
+	StatsTimeStr        string `json:"stats_time"`
+	StatsLatencyUnitStr string `json:"stats_latency_unit"` // ns | us | ms | s
+	RetrySyncTimeStr    string `json:"retry_sync_time"`
+	IostatHistoryStr    string `json:"iostat_history"` // how long to keep iostat reports (what=diskhistory)
+	StatsHistoryStr     string `json:"stats_history"`  // how long to keep the downsampled stats (what=statshistory)
+	// run the iostat (sysstat) binary rather than read /proc/diskstats
+	IostatExternal bool `json:"iostat_external"`
+	// stats log compaction: log in full every stats_log_full periods, and only the changed
+	// stats in between (0 or 1 - always in full); stats_log_level: per-module verbosity, e.g.
+	// "disk=0,core=2" - see stats/statslog.go
+	StatsLogFull     int    `json:"stats_log_full"`
+	StatsLogLevelStr string `json:"stats_log_level"`
+	// stats log format: "text" (default) or "json" - a single record per stats period, see
+	// stats.StatsRecord; stats_log_file: the dedicated stats log, rotated once it reaches
+	// stats_log_max_size bytes ("" - the daemon's log)
+	StatsLogFormat  string `json:"stats_log_format"`
+	StatsLogFile    string `json:"stats_log_file"`
+	StatsLogMaxSize int64  `json:"stats_log_max_size"`
 	// omitempty
-	StatsTime     time.Duration `json:"-"`
-	RetrySyncTime time.Duration `json:"-"`
+	StatsTime        time.Duration  `json:"-"`
+	StatsLatencyUnit time.Duration  `json:"-"`
+	RetrySyncTime    time.Duration  `json:"-"`
+	IostatHistory    time.Duration  `json:"-"`
+	StatsHistory     time.Duration  `json:"-"`
+	StatsLogLevel    map[string]int `json:"-"`
 }
 
 // timeoutconfig contains timeouts used for intra-cluster communication
@@ -83,8 +340,28 @@ type ProxyConf struct {
 	PrimaryURL   string `json:"primary_url"`
 	OriginalURL  string `json:"original_url"`
 	DiscoveryURL string `json:"discovery_url"`
+	// Discovery: find the primary at runtime - when there's no (reachable) static URL
+	Discovery DiscoveryConf `json:"discovery"`
 }
 
+// DiscoveryConf configures the discovery of the cluster's proxies (and, through them,
+// the primary) by a joining node:
+// - "dns_srv": Name is the DNS SRV record, e.g. "_dfc._tcp.example.com"
+// - "aws":     Name is the EC2 instance tag (instance metadata tags/instance/<Name>)
+// - "gcp":     Name is the GCE instance (or project) metadata attribute
+// The tag and the attribute hold a comma-separated list of proxy URLs
+type DiscoveryConf struct {
+	Provider string `json:"provider"` // "" (disabled), "dns_srv", "aws", or "gcp"
+	Name     string `json:"name"`
+}
+
+// DiscoveryConf.Provider
+const (
+	DiscoveryDNSSRV = "dns_srv"
+	DiscoveryAWS    = "aws"
+	DiscoveryGCP    = "gcp"
+)
+
 type LRUConf struct {
 	// LowWM: Self-throttling mechanisms are suspended if disk utilization is below LowWM
 	LowWM int64 `json:"lowwm"`
@@ -110,6 +387,10 @@ type LRUConf struct {
 
 	// LRUEnabled: LRU will only run when set to true
 	LRUEnabled bool `json:"lru_enabled"`
+
+	// HotAccessCount: LRU evicts the objects accessed at least that many times recently
+	// (see HotObject) only after all the colder ones; zero disables
+	HotAccessCount int64 `json:"hot_access_count"`
 }
 
 type XactionConf struct {
@@ -121,6 +402,11 @@ type RebalanceConf struct {
 	DestRetryTimeStr string        `json:"dest_retry_time"`
 	DestRetryTime    time.Duration `json:"-"` //
 	Enabled          bool          `json:"rebalancing_enabled"`
+	// RequireConfirm: user-requested global rebalance must carry the ID of the rebalance
+	// plan computed for the current cluster map (GET /v1/cluster?what=rebplan)
+	RequireConfirm bool `json:"require_confirmation"`
+	// Order: the order in which misplaced objects are sent - see RebOrderNone and others
+	Order string `json:"order"`
 }
 
 type ReplicationConf struct {
@@ -146,6 +432,10 @@ type CksumConf struct {
 
 	// EnableReadRangeChecksum: Return read range checksum otherwise return entire object checksum
 	EnableReadRangeChecksum bool `json:"enable_read_range_checksum"`
+
+	// AsyncPut: PUT is acknowledged once the object is written; the checksum is computed
+	// in the background (applies only when the client does not provide the checksum)
+	AsyncPut bool `json:"async_checksum_put"`
 }
 
 type VersionConf struct {
@@ -186,6 +476,19 @@ type HTTPConf struct {
 	Key           string `json:"server_key"`         // HTTPS: openssl key
 	MaxNumTargets int    `json:"max_num_targets"`    // estimated max num targets (to count idle conns)
 	UseHTTPS      bool   `json:"use_https"`          // use HTTPS instead of HTTP
+	// IntraClient: the client for the intra-cluster requests (see cmn.IntraClient)
+	IntraClient IntraClientConf `json:"intra_client"`
+}
+
+// IntraClientConf configures the HTTP client that proxies and targets use to talk to each
+// other; its request timeouts are timeout.default_timeout and timeout.default_long_timeout
+type IntraClientConf struct {
+	IdleConnsPerPeer int           `json:"idle_conns_per_peer"` // zero: 32
+	DialTimeoutStr   string        `json:"dial_timeout"`
+	DialTimeout      time.Duration `json:"-"`
+	Retries          int           `json:"retries"` // of the idempotent requests that fail to connect
+	RetryDelayStr    string        `json:"retry_delay"`
+	RetryDelay       time.Duration `json:"-"`
 }
 
 type FSHCConf struct {
@@ -194,6 +497,63 @@ type FSHCConf struct {
 	ErrorLimit    int  `json:"fshc_error_limit"` // max number of errors (exceeding any results in disabling mpath)
 }
 
+type DiskConf struct {
+	// ReservedSpaceStr: free space that must remain on each mountpath - PUT and cold GET
+	// are refused (507 Insufficient Storage) once the mountpath's available space drops
+	// below the reserve. Absolute size (e.g. "10GB") or percentage of the capacity (e.g. "5%")
+	ReservedSpaceStr string `json:"reserved_space"`
+
+	// ReservedSpace and ReservedSpacePct are the parsed values of ReservedSpaceStr (one of them is zero)
+	ReservedSpace    int64 `json:"-"`
+	ReservedSpacePct int64 `json:"-"`
+
+	// ReservedSpaceMpathStr: reserved space of the specific mountpaths, in the same format -
+	// overrides ReservedSpaceStr, e.g. {"/dfc/ssd": "20GB", "/dfc/hdd": "2%"}
+	ReservedSpaceMpathStr SimpleKVs `json:"reserved_space_mpath"`
+
+	// ReservedSpaceMpath: the parsed values of ReservedSpaceMpathStr
+	ReservedSpaceMpath map[string]ReservedSpace `json:"-"`
+
+	// SyncGroupTimeStr: how long a PUT to a bucket with the "group" sync policy waits for
+	// other PUTs to the same mountpath before the mountpath's filesystem is synced
+	SyncGroupTimeStr string        `json:"sync_group_time"`
+	SyncGroupTime    time.Duration `json:"-"`
+
+	// PruneDirsTimeStr: how often the target removes empty object directories - those that
+	// have not changed within the same period; "" or zero disables
+	PruneDirsTimeStr string        `json:"prune_dirs_time"`
+	PruneDirsTime    time.Duration `json:"-"`
+
+	// CriticalWM: once any mountpath's used capacity (percent) reaches the critical watermark,
+	// the target enters the capacity emergency mode - refuses client writes and runs LRU
+	// regardless of dont_evict_time - until all mountpaths are below lru_config.highwm.
+	// Must be above lru_config.highwm; zero disables
+	CriticalWM int64 `json:"critical_wm"`
+}
+
+// ReservedSpace is the parsed reserved space of a mountpath: size or percentage (one of them is zero)
+type ReservedSpace struct {
+	Bytes int64
+	Pct   int64
+}
+
+// ReservedBytes returns the space to keep free on the mountpath, given its filesystem's total size
+func (c *DiskConf) ReservedBytes(mpath string, total uint64) uint64 {
+	r, ok := c.ReservedSpaceMpath[mpath]
+	if !ok {
+		r = ReservedSpace{Bytes: c.ReservedSpace, Pct: c.ReservedSpacePct}
+	}
+	if r.Pct > 0 {
+		return total / 100 * uint64(r.Pct)
+	}
+	return uint64(r.Bytes)
+}
+
+// Reserved returns true if any space is reserved on any mountpath
+func (c *DiskConf) Reserved() bool {
+	return c.ReservedSpace != 0 || c.ReservedSpacePct != 0 || len(c.ReservedSpaceMpath) != 0
+}
+
 type AuthConf struct {
 	Secret  string `json:"secret"`
 	Enabled bool   `json:"enabled"`
@@ -210,6 +570,10 @@ type KeepaliveTrackerConf struct {
 }
 
 type KeepaliveConf struct {
-	Proxy  KeepaliveTrackerConf `json:"proxy"`  // how proxy tracks target keepalives
-	Target KeepaliveTrackerConf `json:"target"` // how target tracks primary proxies keepalives
+	Proxy             KeepaliveTrackerConf `json:"proxy"`           // how proxy tracks target keepalives
+	Target            KeepaliveTrackerConf `json:"target"`          // how target tracks primary proxies keepalives
+	SuspectAfter      int                  `json:"suspect_after"`   // consecutive failed keepalives before the primary removes a daemon
+	RebalanceGraceStr string               `json:"rebalance_grace"` // targets are not removed within this period after rebalance starts
+	RebalanceGrace    time.Duration        `json:"-"`
+	TargetAlertOnly   bool                 `json:"target_alert_only"` // never remove non-responding targets - log the alert instead
 }
@@ -46,7 +46,7 @@ func IsIOError(err error) bool {
 			e == syscall.ENXIO || // No such device
 			e == syscall.EBADF || // Bad file number
 			e == syscall.ENODEV || // No such device
-			e == syscall.EUCLEAN || // (mkdir)structure needs cleaning = broken filesystem
+			isFSCorrupted(e) || // (mkdir)structure needs cleaning = broken filesystem
 			e == syscall.EROFS || // readonly filesystem
 			e == syscall.EDQUOT || // quota exceeded
 			e == syscall.ESTALE || // stale file handle
@@ -0,0 +1,9 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package cmn provides common low-level types and utilities for all dfcpub projects
+package cmn
+
+import "syscall"
+
+func isFSCorrupted(e error) bool { return e == syscall.EUCLEAN }
@@ -16,6 +16,7 @@ import (
 	"strings"
 
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	jsoniter "github.com/json-iterator/go"
 )
 
 // URLPath returns a HTTP URL path by joining all segments with "/"
@@ -76,6 +77,74 @@ func MatchRESTItems(unescapedPath string, itemsAfter int, splitAfter bool, items
 	return apiItems, nil
 }
 
+// APIVersions are the REST API versions supported by this build, the latest last
+var APIVersions = []string{Version, VersionV2}
+
+// ClientHints is the result of GET /v1/daemon?what=clienthints: what the clients of the
+// cluster configure themselves with (see api.NewClient)
+type ClientHints struct {
+	ProxyURLs   []string `json:"proxy_urls"`   // public URLs of the proxies, primary first
+	APIVersions []string `json:"api_versions"` // REST API versions supported by the cluster
+	DialTimeout string   `json:"dial_timeout"` // to connect to the proxies and targets
+	// the idempotent requests that fail with 503 (Service Unavailable) are retried up to
+	// MaxRetries times, waiting RetryBackoff before the first retry and twice as long as the
+	// previous time before each next one
+	MaxRetries   int    `json:"max_retries"`
+	RetryBackoff string `json:"retry_backoff"`
+}
+
+// HTTPError is the error that the daemons return as JSON to all requests, except for the
+// v1 requests that opt out of it for the plain text of old (see WantsText); the requests
+// served as per the REST API v2 (see IsAPIV2) cannot opt out. RequestID is the request's
+// HeaderDFCRequestID - the client's own or, if none, assigned by the daemon - and NodeID
+// is the ID of the daemon that has failed the request; both are also returned as headers
+// with all responses, so that failures could be correlated with the daemons' logs
+type HTTPError struct {
+	Status    int    `json:"status"`
+	Message   string `json:"message"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	RequestID string `json:"request_id,omitempty"`
+	NodeID    string `json:"node_id,omitempty"`
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP error = %d, message = %s", e.Status, e.Message)
+}
+
+// IsAPIV2 returns true if the request is to be served as per the REST API v2: either sent
+// to a /v2 path or with the DfcAPIVersion header (which is how v2 clients follow redirects)
+func IsAPIV2(r *http.Request) bool {
+	return r.Header.Get(HeaderDFCAPIVersion) == VersionV2
+}
+
+// WantsText returns true if the request accepts plain text (and not JSON) responses
+func WantsText(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/plain") && !strings.Contains(accept, "application/json")
+}
+
+// httpError writes the error: msg as HTTPError or, with v1 and WantsText, errMsg as plain text
+func httpError(w http.ResponseWriter, r *http.Request, msg, errMsg string, status int) {
+	if !IsAPIV2(r) && WantsText(r) {
+		http.Error(w, errMsg, status)
+		return
+	}
+	herr := &HTTPError{
+		Status:    status,
+		Message:   msg,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		RequestID: r.Header.Get(HeaderDFCRequestID),
+		NodeID:    w.Header().Get(HeaderDFCNodeID),
+	}
+	b, _ := jsoniter.Marshal(herr)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
 // ErrHTTP returns a formatted error string for an HTTP request.
 func ErrHTTP(r *http.Request, msg string, status int) string {
 	return http.StatusText(status) + ": " + msg + ": " + r.Method + " " + r.URL.Path + " from " + r.RemoteAddr
@@ -93,7 +162,7 @@ func InvalidHandlerWithMsg(w http.ResponseWriter, r *http.Request, msg string, e
 	}
 
 	s := ErrHTTP(r, msg, status)
-	http.Error(w, s, status)
+	httpError(w, r, msg, s, status)
 }
 
 // InvalidHandlerDetailed writes detailed error (includes line and file) to response writer.
@@ -118,6 +187,10 @@ func InvalidHandlerDetailed(w http.ResponseWriter, r *http.Request, msg string,
 	stack += ")"
 	errMsg += "| " + stack
 
-	glog.Errorln(errMsg)
-	http.Error(w, errMsg, status)
+	if id := r.Header.Get(HeaderDFCRequestID); id != "" {
+		glog.Errorf("%s [request %s]", errMsg, id)
+	} else {
+		glog.Errorln(errMsg)
+	}
+	httpError(w, r, msg, errMsg, status)
 }
@@ -8,7 +8,12 @@
 package cmn
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	jsoniter "github.com/json-iterator/go"
 )
 
 func TestMatchRESTItemsSmoke(t *testing.T) {
@@ -64,3 +69,37 @@ func TestMatchRESTItemsTooShort(t *testing.T) {
 		t.Errorf("expected error, apiItems returned: %v", apiItems)
 	}
 }
+
+func TestHTTPErrorJSON(t *testing.T) {
+	do := func(accept string, v2 bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/v1/objects/b/o", nil)
+		r.Header.Set(HeaderDFCRequestID, "req-1")
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		if v2 {
+			r.Header.Set(HeaderDFCAPIVersion, VersionV2)
+		}
+		w := httptest.NewRecorder()
+		w.Header().Set(HeaderDFCNodeID, "t1")
+		InvalidHandlerWithMsg(w, r, "no such object", http.StatusNotFound)
+		return w
+	}
+	// v1: JSON, unless only plain text is accepted; v2: JSON
+	if w := do("text/plain", false); strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") ||
+		!strings.HasPrefix(w.Body.String(), "Not Found: no such object") {
+		t.Errorf("expected plain text, got %q", w.Body.String())
+	}
+	for _, w := range []*httptest.ResponseRecorder{do("", false), do("*/*", false), do("text/plain, application/json", false),
+		do("application/json", false), do("", true), do("text/plain", true)} {
+		herr := &HTTPError{}
+		if err := jsoniter.Unmarshal(w.Body.Bytes(), herr); err != nil {
+			t.Fatalf("expected JSON, got %q (err %v)", w.Body.String(), err)
+		}
+		expected := HTTPError{Status: http.StatusNotFound, Message: "no such object", Method: http.MethodGet,
+			Path: "/v1/objects/b/o", RequestID: "req-1", NodeID: "t1"}
+		if *herr != expected || w.Code != http.StatusNotFound {
+			t.Errorf("expected %+v, got %+v (%d)", expected, *herr, w.Code)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package cmn provides common low-level types and utilities for all dfcpub projects
+package cmn
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type (
+	// IntraClientArgs configure NewIntraClient
+	IntraClientArgs struct {
+		IdleConnsPerPeer int           // idle (keep-alive) connections kept per peer
+		DialTimeout      time.Duration // to establish a connection
+		Timeout          time.Duration // request timeout
+		LongTimeout      time.Duration // request timeout for the long-wait requests
+		Retries          int           // retries of the idempotent requests that fail to connect
+		RetryDelay       time.Duration // before the first retry; doubles with each next one
+		UseHTTPS         bool
+	}
+	// IntraPeerStats are the requests the IntraClient sent to a single peer (host:port)
+	IntraPeerStats struct {
+		Peer        string        `json:"peer,omitempty"` // daemon ID, if known
+		Requests    int64         `json:"requests"`
+		Errors      int64         `json:"errors"` // no response (HTTP error statuses are not counted)
+		Timeouts    int64         `json:"timeouts"`
+		Retries     int64         `json:"retries"`
+		NewConns    int64         `json:"new_conns"`
+		ReusedConns int64         `json:"reused_conns"`
+		Latency     time.Duration `json:"latency"` // average, until the response headers (ns)
+		latencySum  time.Duration
+	}
+	// IntraClient is the HTTP client that proxies and targets use to talk to each other
+	// (control plane calls, metasync, keepalives, rebalance, and reverse-proxied requests):
+	// a single pool of connections, up to IdleConnsPerPeer kept alive per peer; the default
+	// and the long request timeouts; retries of the idempotent requests that fail to
+	// connect; and the per-peer stats of requests and connections - see Stats
+	IntraClient struct {
+		args      IntraClientArgs
+		transport *http.Transport
+		rt        http.RoundTripper
+		client    *http.Client
+		long      *http.Client
+		mu        sync.Mutex
+		peers     map[string]*IntraPeerStats // by host:port
+	}
+	// intraRoundTripper counts the requests and the connections per peer
+	intraRoundTripper struct {
+		c *IntraClient
+	}
+)
+
+// NewIntraClient creates the client; the dial timeout defaults to 30s
+func NewIntraClient(args IntraClientArgs) *IntraClient {
+	if args.DialTimeout <= 0 {
+		args.DialTimeout = 30 * time.Second
+	}
+	defaultTransport := http.DefaultTransport.(*http.Transport)
+	c := &IntraClient{args: args, peers: make(map[string]*IntraPeerStats, 16)}
+	c.transport = &http.Transport{
+		Proxy: defaultTransport.Proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   args.DialTimeout,
+			KeepAlive: 30 * time.Second,
+			DualStack: true,
+		}).DialContext,
+		IdleConnTimeout:       defaultTransport.IdleConnTimeout,
+		ExpectContinueTimeout: defaultTransport.ExpectContinueTimeout,
+		TLSHandshakeTimeout:   defaultTransport.TLSHandshakeTimeout,
+		MaxIdleConnsPerHost:   args.IdleConnsPerPeer,
+		MaxIdleConns:          0, // no limit
+	}
+	if args.UseHTTPS {
+		c.transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	c.rt = &intraRoundTripper{c: c}
+	c.client = &http.Client{Transport: c.rt, Timeout: args.Timeout}
+	c.long = &http.Client{Transport: c.rt, Timeout: args.LongTimeout}
+	return c
+}
+
+// Transport returns the pooled (and counted) transport, e.g. for reverse proxies
+func (c *IntraClient) Transport() http.RoundTripper { return c.rt }
+
+// Timeout returns the default request timeout
+func (c *IntraClient) Timeout() time.Duration { return c.args.Timeout }
+
+// Do sends the request with the default timeout or, if long, with the long one (the
+// request's context may set a shorter deadline). Idempotent requests - GET and HEAD with
+// no body - that fail to connect or find the connection reset are retried up to Retries
+// times. As with http.Client, the caller must close the response body.
+func (c *IntraClient) Do(req *http.Request, long bool) (resp *http.Response, err error) {
+	client := c.client
+	if long {
+		client = c.long
+	}
+	delay := c.args.RetryDelay
+	for i := 0; ; i++ {
+		resp, err = client.Do(req)
+		if err != nil && isTimeout(err) {
+			// counted here rather than by the round tripper: the client's own Timeout does
+			// not expire the request's context and reaches the transport as a mere cancel
+			c.peer(req.URL.Host, func(s *IntraPeerStats) { s.Timeouts++ })
+		}
+		if err == nil || i >= c.args.Retries || !isRetriable(req, err) {
+			return
+		}
+		c.peer(req.URL.Host, func(s *IntraPeerStats) { s.Retries++ })
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-req.Context().Done():
+				return
+			}
+			delay *= 2
+		}
+	}
+}
+
+// Stats returns a copy of the per-peer stats, by host:port
+func (c *IntraClient) Stats() map[string]*IntraPeerStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]*IntraPeerStats, len(c.peers))
+	for host, s := range c.peers {
+		cp := *s
+		if cp.Requests > cp.Errors {
+			cp.Latency = cp.latencySum / time.Duration(cp.Requests-cp.Errors)
+		}
+		out[host] = &cp
+	}
+	return out
+}
+
+func (c *IntraClient) peer(host string, f func(s *IntraPeerStats)) {
+	c.mu.Lock()
+	s, ok := c.peers[host]
+	if !ok {
+		s = &IntraPeerStats{}
+		c.peers[host] = s
+	}
+	f(s)
+	c.mu.Unlock()
+}
+
+func (rt *intraRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		gotConn, reused bool
+		trace           = &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) { gotConn, reused = true, info.Reused },
+		}
+		started = time.Now()
+	)
+	resp, err := rt.c.transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	latency := time.Since(started)
+	rt.c.peer(req.URL.Host, func(s *IntraPeerStats) {
+		s.Requests++
+		if gotConn && reused {
+			s.ReusedConns++
+		} else if gotConn {
+			s.NewConns++
+		}
+		if err != nil {
+			s.Errors++ // timeouts are counted by Do
+			return
+		}
+		s.latencySum += latency
+	})
+	return resp, err
+}
+
+func isRetriable(req *http.Request, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+	return IsErrConnectionRefused(err) || isErrConnectionReset(err)
+}
+
+func isErrConnectionReset(err error) bool {
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	if err == io.EOF {
+		return true
+	}
+	if noerr, ok := err.(*net.OpError); ok {
+		if scerr, ok := noerr.Err.(*os.SyscallError); ok {
+			return scerr.Err == syscall.ECONNRESET
+		}
+	}
+	return false
+}
+
+// isTimeout returns true if the request timed out: connect or I/O timeout, the request's
+// context deadline, or http.Client.Timeout - the latter also by its message, for the errors
+// that do not implement net.Error
+func isTimeout(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		return true
+	}
+	return strings.Contains(err.Error(), "Client.Timeout exceeded")
+}
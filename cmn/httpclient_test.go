@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+// Package cmn provides common low-level types and utilities for all dfcpub projects
+
+package cmn
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntraClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sleep") != "" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	c := NewIntraClient(IntraClientArgs{IdleConnsPerPeer: 4, Timeout: 50 * time.Millisecond,
+		LongTimeout: time.Second, Retries: 2, RetryDelay: time.Millisecond})
+
+	get := func(query string, long bool) error {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/?"+query, nil)
+		resp, err := c.Do(req, long)
+		if err == nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err := get("", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the default timeout expires, the long one does not
+	if err := get("sleep=1", false); err == nil {
+		t.Error("expected the request to time out")
+	}
+	if err := get("sleep=1", true); err != nil {
+		t.Errorf("expected the long-timeout request to succeed, got %v", err)
+	}
+
+	u, _ := url.Parse(srv.URL)
+	s := c.Stats()[u.Host]
+	if s == nil || s.Requests != 5 || s.Errors != 1 || s.Timeouts != 1 || s.Retries != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if s.NewConns+s.ReusedConns != 5 || s.ReusedConns < 2 || s.Latency <= 0 {
+		t.Errorf("expected the connection to be reused, got %+v", s)
+	}
+}
+
+func TestIntraClientTimeouts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+	c := NewIntraClient(IntraClientArgs{Timeout: 20 * time.Millisecond, LongTimeout: time.Second})
+
+	// http.Client.Timeout: the request's own context never expires
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if _, err := c.Do(req, false); err == nil || !isTimeout(err) {
+		t.Fatalf("expected Client.Timeout, got %v", err)
+	}
+	if req.Context().Err() != nil {
+		t.Fatalf("expected the request's context intact, got %v", req.Context().Err())
+	}
+	// the request's context deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	if _, err := c.Do(req.WithContext(ctx), true); err == nil || !isTimeout(err) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	u, _ := url.Parse(srv.URL)
+	if s := c.Stats()[u.Host]; s == nil || s.Requests != 2 || s.Errors != 2 || s.Timeouts != 2 {
+		t.Errorf("expected 2 timeouts, got %+v", s)
+	}
+
+	// as reported by the older clients: neither context nor net.Error
+	err := &url.Error{Op: "Get", URL: srv.URL,
+		Err: errors.New("net/http: request canceled (Client.Timeout exceeded while awaiting headers)")}
+	if !isTimeout(err) {
+		t.Errorf("expected %v to be a timeout", err)
+	}
+	if isTimeout(&url.Error{Op: "Get", URL: srv.URL, Err: context.Canceled}) {
+		t.Error("expected canceled request not to be a timeout")
+	}
+}
+
+func TestIntraClientRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	addr := srv.URL
+	srv.Close() // connection refused from now on
+
+	c := NewIntraClient(IntraClientArgs{Timeout: time.Second, Retries: 2, RetryDelay: time.Millisecond})
+	req, _ := http.NewRequest(http.MethodGet, addr, nil)
+	if _, err := c.Do(req, false); err == nil || !IsErrConnectionRefused(err) {
+		t.Fatalf("expected connection refused, got %v", err)
+	}
+	// requests with body are not retried
+	req, _ = http.NewRequest(http.MethodPut, addr, strings.NewReader("body"))
+	if _, err := c.Do(req, false); err == nil {
+		t.Fatal("expected connection refused")
+	}
+	u, _ := url.Parse(addr)
+	if s := c.Stats()[u.Host]; s == nil || s.Requests != 4 || s.Errors != 4 || s.Retries != 2 {
+		t.Errorf("expected 3 GETs (2 retries) and a single PUT, got %+v", s)
+	}
+}
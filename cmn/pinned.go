@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package cmn
+
+import (
+	"sort"
+	"strings"
+)
+
+// IsPinned returns true if the object is pinned by name or by prefix
+func (p *Pinned) IsPinned(objname string) bool {
+	if i := sort.SearchStrings(p.Objects, objname); i < len(p.Objects) && p.Objects[i] == objname {
+		return true
+	}
+	for _, prefix := range p.Prefixes {
+		if strings.HasPrefix(objname, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pin returns the union of the two; the receiver is not modified
+func (p *Pinned) Pin(add *Pinned) *Pinned {
+	return &Pinned{
+		Objects:  unionSorted(p.Objects, add.Objects),
+		Prefixes: unionSorted(p.Prefixes, add.Prefixes),
+	}
+}
+
+// Unpin returns the pinned objects and prefixes less the removed; the receiver is not modified
+func (p *Pinned) Unpin(rem *Pinned) *Pinned {
+	return &Pinned{
+		Objects:  subtractSorted(p.Objects, rem.Objects),
+		Prefixes: subtractSorted(p.Prefixes, rem.Prefixes),
+	}
+}
+
+// IsEmpty returns true if nothing is pinned
+func (p *Pinned) IsEmpty() bool { return len(p.Objects) == 0 && len(p.Prefixes) == 0 }
+
+func unionSorted(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	out = append(append(out, a...), b...)
+	sort.Strings(out)
+	n := 0
+	for i, s := range out {
+		if i == 0 || s != out[n-1] {
+			out[n] = s
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	return out[:n]
+}
+
+func subtractSorted(a, b []string) []string {
+	var out []string
+	for _, s := range a {
+		if !StringInSlice(s, b) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package cmn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPinned(t *testing.T) {
+	p := &Pinned{}
+	p = p.Pin(&Pinned{Objects: []string{"c", "a"}, Prefixes: []string{"ckpt/"}})
+	p = p.Pin(&Pinned{Objects: []string{"b", "a"}})
+	if !reflect.DeepEqual(p.Objects, []string{"a", "b", "c"}) || len(p.Prefixes) != 1 {
+		t.Fatalf("expected sorted union without duplicates, got %+v", p)
+	}
+	for objname, pinned := range map[string]bool{"a": true, "b": true, "ab": false, "ckpt/1": true, "ckpt": false} {
+		if p.IsPinned(objname) != pinned {
+			t.Errorf("%s: expected pinned %t", objname, pinned)
+		}
+	}
+
+	q := p.Unpin(&Pinned{Objects: []string{"b", "x"}, Prefixes: []string{"ckpt/"}})
+	if !reflect.DeepEqual(q.Objects, []string{"a", "c"}) || len(q.Prefixes) != 0 {
+		t.Errorf("unexpected %+v after unpin", q)
+	}
+	if len(p.Objects) != 3 {
+		t.Errorf("expected the receiver unmodified, got %+v", p)
+	}
+	if q = q.Unpin(q); !q.IsEmpty() {
+		t.Errorf("expected nothing pinned, got %+v", q)
+	}
+}
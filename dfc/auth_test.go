@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestPublicReadAuth(t *testing.T) {
+	ctx.config.Auth.Enabled = true
+	defer func() { ctx.config.Auth.Enabled = false }()
+
+	p := &proxyrunner{}
+	p.statsif = &fakeStatsTracker{stats: make(map[string]int64)}
+	bucketmd := newBucketMD()
+	bucketmd.add("public", true, cmn.BucketProps{CloudProvider: cmn.ProviderDFC, PublicRead: true})
+	bucketmd.add("private", true, cmn.BucketProps{CloudProvider: cmn.ProviderDFC})
+	p.bmdowner = &bmdowner{}
+	p.bmdowner.put(bucketmd)
+
+	var body string
+	handler := p.checkHTTPAuth(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	})
+	listmsg := `{"action":"` + cmn.ActListObjects + `","value":{}}`
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		token  bool
+		status int
+	}{
+		{http.MethodGet, "/v1/objects/public/obj", "", false, http.StatusOK},
+		{http.MethodHead, "/v1/objects/public/obj", "", false, http.StatusOK},
+		{http.MethodHead, "/v1/buckets/public", "", false, http.StatusOK},
+		{http.MethodPost, "/v1/buckets/public", listmsg, false, http.StatusOK},
+		{http.MethodPost, "/v1/buckets/public", `{"action":"` + cmn.ActRename + `"}`, false, http.StatusUnauthorized},
+		{http.MethodPut, "/v1/objects/public/obj", "data", false, http.StatusUnauthorized},
+		{http.MethodDelete, "/v1/objects/public/obj", "", false, http.StatusUnauthorized},
+		{http.MethodGet, "/v1/objects/private/obj", "", false, http.StatusUnauthorized},
+		{http.MethodPost, "/v1/buckets/private", listmsg, false, http.StatusUnauthorized},
+		{http.MethodGet, "/v1/objects/unknown/obj", "", false, http.StatusUnauthorized},
+		{http.MethodGet, "/v1/buckets/*", "", false, http.StatusUnauthorized},
+		// a token, if present, must be valid
+		{http.MethodGet, "/v1/objects/public/obj", "", true, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		body = ""
+		r := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+		if tt.token {
+			r.Header.Set("Authorization", tokenStart+" invalid")
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+		if w.Code == http.StatusOK && body != tt.body {
+			t.Errorf("%s %s: expected request body %q, got %q", tt.method, tt.path, tt.body, body)
+		}
+	}
+}
@@ -8,8 +8,10 @@ package dfc
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -171,6 +173,9 @@ func (awsimpl *awsimpl) listbucket(ct context.Context, bucket string, msg *cmn.G
 	if msg.GetPageMarker != "" {
 		params.Marker = aws.String(msg.GetPageMarker)
 	}
+	if msg.GetDelimiter != "" {
+		params.Delimiter = aws.String(msg.GetDelimiter)
+	}
 	if msg.GetPageSize != 0 {
 		if msg.GetPageSize > awsMaxPageSize {
 			glog.Warningf("AWS maximum page size is %d (%d requested). Returning the first %d keys",
@@ -240,6 +245,12 @@ func (awsimpl *awsimpl) listbucket(ct context.Context, bucket string, msg *cmn.G
 		// TODO: other cmn.GetMsg props TBD
 		reslist.Entries = append(reslist.Entries, entry)
 	}
+	for _, prefix := range resp.CommonPrefixes {
+		reslist.Entries = append(reslist.Entries, &cmn.BucketEntry{Name: *(prefix.Prefix), Type: cmn.BucketEntryDir})
+	}
+	if len(resp.CommonPrefixes) > 0 {
+		sort.Slice(reslist.Entries, func(i, j int) bool { return reslist.Entries[i].Name < reslist.Entries[j].Name })
+	}
 	if glog.V(4) {
 		glog.Infof("listbucket count %d", len(reslist.Entries))
 	}
@@ -247,7 +258,11 @@ func (awsimpl *awsimpl) listbucket(ct context.Context, bucket string, msg *cmn.G
 	if *resp.IsTruncated {
 		// For AWS, resp.NextMarker is only set when a query has a delimiter.
 		// Without a delimiter, NextMarker should be the last returned key.
-		reslist.PageMarker = reslist.Entries[len(reslist.Entries)-1].Name
+		if resp.NextMarker != nil && *resp.NextMarker != "" {
+			reslist.PageMarker = *resp.NextMarker
+		} else {
+			reslist.PageMarker = reslist.Entries[len(reslist.Entries)-1].Name
+		}
 	}
 
 	jsbytes, err = jsoniter.Marshal(reslist)
@@ -329,6 +344,7 @@ func (awsimpl *awsimpl) headobject(ct context.Context, bucket string, objname st
 		return
 	}
 	objmeta[cmn.HeaderCloudProvider] = cmn.ProviderAmazon
+	objmeta["size"] = strconv.FormatInt(aws.Int64Value(headOutput.ContentLength), 10)
 	if awsIsVersionSet(headOutput.VersionId) {
 		objmeta["version"] = *headOutput.VersionId
 	}
@@ -341,9 +357,19 @@ func (awsimpl *awsimpl) headobject(ct context.Context, bucket string, objname st
 //
 //=======================
 func (awsimpl *awsimpl) getobj(ct context.Context, fqn, bucket, objname string) (props *objectProps, errstr string, errcode int) {
-	var v cksumvalue
 	sess := createSession(ct)
 	svc := s3.New(sess)
+	if ctx.config.ColdGet.Concurrency > 1 {
+		head, err := svc.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(objname)})
+		if err != nil {
+			errcode = awsErrorToHTTP(err)
+			errstr = fmt.Sprintf("Failed to retrieve %s/%s metadata, err: %v", bucket, objname, err)
+			return
+		}
+		if size := aws.Int64Value(head.ContentLength); parallelColdGet(size) {
+			return awsimpl.getobjParallel(svc, fqn, bucket, objname, head)
+		}
+	}
 	obj, err := svc.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(objname),
@@ -353,20 +379,7 @@ func (awsimpl *awsimpl) getobj(ct context.Context, fqn, bucket, objname string)
 		errstr = fmt.Sprintf("Failed to GET %s/%s, err: %v", bucket, objname, err)
 		return
 	}
-	// may not have dfc metadata
-	if htype, ok := obj.Metadata[awsGetDfcHashType]; ok {
-		if hval, ok := obj.Metadata[awsGetDfcHashVal]; ok {
-			v = newcksumvalue(*htype, *hval)
-		}
-	}
-	md5, _ := strconv.Unquote(*obj.ETag)
-	// FIXME: multipart
-	if strings.Contains(md5, awsMultipartDelim) {
-		if glog.V(3) {
-			glog.Infof("Warning: multipart object %s/%s - not validating checksum %s", bucket, objname, md5)
-		}
-		md5 = ""
-	}
+	v, md5 := awsObjCksum(bucket, objname, obj.Metadata, obj.ETag)
 	props = &objectProps{}
 	if obj.VersionId != nil {
 		props.version = *obj.VersionId
@@ -382,6 +395,83 @@ func (awsimpl *awsimpl) getobj(ct context.Context, fqn, bucket, objname string)
 	return
 }
 
+func (awsimpl *awsimpl) getobjReader(ct context.Context, bucket, objname string, objmeta cmn.SimpleKVs, offset, length int64) (r io.ReadCloser, props *objectProps, errstr string, errcode int) {
+	svc := s3.New(createSession(ct))
+	input := &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(objname)}
+	if version, ok := objmeta["version"]; ok {
+		input.VersionId = aws.String(version)
+	}
+	if length > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	}
+	obj, err := svc.GetObject(input)
+	if err != nil {
+		errcode = awsErrorToHTTP(err)
+		errstr = fmt.Sprintf("Failed to GET %s/%s, err: %v", bucket, objname, err)
+		return
+	}
+	props = &objectProps{size: aws.Int64Value(obj.ContentLength)}
+	if awsIsVersionSet(obj.VersionId) {
+		props.version = *obj.VersionId
+	}
+	if length == 0 {
+		props.nhobj, _ = awsObjCksum(bucket, objname, obj.Metadata, obj.ETag)
+	}
+	return obj.Body, props, "", 0
+}
+
+// getobjParallel downloads the object with ranged GETs (see coldparallel.go); all the
+// ranges are pinned to the object's version (if versioned) and ETag
+func (awsimpl *awsimpl) getobjParallel(svc *s3.S3, fqn, bucket, objname string, head *s3.HeadObjectOutput) (props *objectProps, errstr string, errcode int) {
+	v, md5 := awsObjCksum(bucket, objname, head.Metadata, head.ETag)
+	props = &objectProps{size: aws.Int64Value(head.ContentLength)}
+	if head.VersionId != nil {
+		props.version = *head.VersionId
+	}
+	get := func(off, length int64) (io.ReadCloser, error) {
+		input := &s3.GetObjectInput{
+			Bucket:  aws.String(bucket),
+			Key:     aws.String(objname),
+			Range:   aws.String(fmt.Sprintf("bytes=%d-%d", off, off+length-1)),
+			IfMatch: head.ETag,
+		}
+		if awsIsVersionSet(head.VersionId) {
+			input.VersionId = head.VersionId
+		}
+		obj, err := svc.GetObject(input)
+		if err != nil {
+			return nil, err
+		}
+		return obj.Body, nil
+	}
+	if props.nhobj, errstr = awsimpl.t.receiveParallel(fqn, bucket, objname, md5, v, props.size, get); errstr != "" {
+		return
+	}
+	if glog.V(4) {
+		glog.Infof("GET %s/%s", bucket, objname)
+	}
+	return
+}
+
+// awsObjCksum returns the DFC checksum stored with the object (if any) and its md5
+func awsObjCksum(bucket, objname string, metadata map[string]*string, etag *string) (v cksumvalue, md5 string) {
+	// may not have dfc metadata
+	if htype, ok := metadata[awsGetDfcHashType]; ok {
+		if hval, ok := metadata[awsGetDfcHashVal]; ok {
+			v = newcksumvalue(*htype, *hval)
+		}
+	}
+	md5, _ = strconv.Unquote(aws.StringValue(etag))
+	// FIXME: multipart
+	if strings.Contains(md5, awsMultipartDelim) {
+		if glog.V(3) {
+			glog.Infof("Warning: multipart object %s/%s - not validating checksum %s", bucket, objname, md5)
+		}
+		md5 = ""
+	}
+	return
+}
+
 func (awsimpl *awsimpl) putobj(ct context.Context, file *os.File, bucket, objname string, ohash cksumvalue) (version string, errstr string, errcode int) {
 	var (
 		err          error
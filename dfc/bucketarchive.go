@@ -0,0 +1,752 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Bucket export/import (ActExport, ActImport): archives a local bucket in a Cloud bucket -
+// for backup and for moving datasets between clusters - and restores it from there.
+//
+// Both run in the background: the primary proxy broadcasts the request, each target starts
+// its xaction (xactArchive) and responds right away, and the proxy then polls the targets
+// (GET /v1/buckets/bucket-name?what=archive) until all of them are done. The client polls
+// the proxy the same way - see cmn.ArchiveStatus. The status of the bucket's last export or
+// import is kept in memory, by the primary and by each target.
+//
+// Export: each target walks its share of the bucket and writes the objects, one after
+// another, into tar shards of about shard_size bytes that it uploads to the Cloud bucket as
+// <prefix>/<target ID>-<N>.tar. Each tar entry carries the object's DFC checksum, version,
+// and expiration time (tar xattrs, i.e. SCHILY.xattr.* PAX records); compressed objects are
+// archived decompressed. Once all targets are done, the proxy has the manifest
+// (<prefix>/manifest.json) - the list of shards - uploaded as well.
+//
+// Import: each target downloads the manifest and the shards that map to it (HRW of the
+// shard name), and PUTs each object into the local bucket - locally or, if the object
+// belongs to another target, via the latter's rebalance receive path, which validates the
+// checksum and preserves the object's version and expiration time.
+//
+// ================================ Summary ===============================================
+
+const (
+	archiveManifest  = "manifest.json"
+	archiveShardSize = cmn.GiB
+
+	// xattrs of the archived objects (tar.Header.Xattrs: PAXRecords and tar.FormatPAX need Go 1.10)
+	paxChecksum = "DFC.checksum" // as stored (cmn.MakeStoredCksum)
+	paxVersion  = "DFC.version"
+	paxExpires  = "DFC.expires" // RFC3339Nano
+)
+
+// archivePoll is how often the proxy polls the targets running export or import
+var archivePoll = time.Second
+
+type archiveShardWriter struct {
+	shard cmn.ArchiveShard
+	fqn   string // workfile
+	file  *os.File
+	tw    *tar.Writer
+}
+
+func parseArchiveMsg(msg *cmn.ActionMsg) (*cmn.ArchiveMsg, error) {
+	amsg := &cmn.ArchiveMsg{}
+	b, err := jsoniter.Marshal(msg.Value)
+	if err == nil {
+		err = jsoniter.Unmarshal(b, amsg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s message value %+v, err: %v", msg.Action, msg.Value, err)
+	}
+	if amsg.CloudBucket == "" {
+		return nil, fmt.Errorf("Invalid %s message: Cloud bucket is not specified", msg.Action)
+	}
+	if amsg.ShardSize < 0 {
+		return nil, fmt.Errorf("Invalid %s message: negative shard size %d", msg.Action, amsg.ShardSize)
+	}
+	if amsg.ShardSize == 0 {
+		amsg.ShardSize = archiveShardSize
+	}
+	amsg.Prefix = strings.Trim(amsg.Prefix, "/")
+	return amsg, nil
+}
+
+func archiveManifestName(prefix string) string { return path.Join(prefix, archiveManifest) }
+
+func archiveShardName(prefix, tid string, idx int) string {
+	return path.Join(prefix, fmt.Sprintf("%s-%05d.tar", tid, idx))
+}
+
+// archiveHeader returns the tar header of the object
+func archiveHeader(objname string, size int64, mtime time.Time, props *objectProps) *tar.Header {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     objname,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  mtime,
+		Xattrs:   make(map[string]string, 3),
+	}
+	if props.nhobj != nil {
+		hdr.Xattrs[paxChecksum] = string(cmn.MakeStoredCksum(props.nhobj.get()))
+	}
+	if props.version != "" {
+		hdr.Xattrs[paxVersion] = props.version
+	}
+	if !props.expires.IsZero() {
+		hdr.Xattrs[paxExpires] = props.expires.Format(time.RFC3339Nano)
+	}
+	return hdr
+}
+
+// archiveProps returns the properties of the object archived with the tar header
+func archiveProps(hdr *tar.Header) *objectProps {
+	props := &objectProps{version: hdr.Xattrs[paxVersion], size: hdr.Size}
+	if s := hdr.Xattrs[paxChecksum]; s != "" {
+		props.nhobj = newcksumvalue(cmn.ParseStoredCksum([]byte(s)))
+	}
+	if s := hdr.Xattrs[paxExpires]; s != "" {
+		if tm, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			props.expires = tm
+		}
+	}
+	return props
+}
+
+// archiveStatuses keeps the status of the last export or import per bucket
+type archiveStatuses struct {
+	sync.Mutex
+	m map[string]*cmn.ArchiveStatus // bucket => status
+}
+
+// start registers the bucket's new export or import unless one is already running
+func (as *archiveStatuses) start(bucket string, status *cmn.ArchiveStatus) bool {
+	as.Lock()
+	defer as.Unlock()
+	if as.m == nil {
+		as.m = make(map[string]*cmn.ArchiveStatus, 4)
+	}
+	if prev, ok := as.m[bucket]; ok && prev.State == cmn.ArchiveRunning {
+		return false
+	}
+	as.m[bucket] = status
+	return true
+}
+
+func (as *archiveStatuses) finish(bucket string, status *cmn.ArchiveStatus, errstr string) {
+	as.Lock()
+	status.Finished, status.Err = time.Now(), errstr
+	if errstr == "" {
+		status.State = cmn.ArchiveDone
+	} else {
+		status.State = cmn.ArchiveFailed
+	}
+	as.Unlock()
+}
+
+// get returns the JSON of the bucket's status; nil if none
+func (as *archiveStatuses) get(bucket string) []byte {
+	as.Lock()
+	defer as.Unlock()
+	status, ok := as.m[bucket]
+	if !ok {
+		return nil
+	}
+	jsbytes, err := jsoniter.Marshal(status)
+	cmn.Assert(err == nil, err)
+	return jsbytes
+}
+
+//
+// proxy
+//
+
+// POST { export | import } /v1/buckets/bucket-name
+func (p *proxyrunner) bucketArchive(w http.ResponseWriter, r *http.Request, bucket string, msg *cmn.ActionMsg) {
+	// the primary polls the targets and keeps the status
+	if p.forwardCP(w, r, msg, bucket, nil) {
+		return
+	}
+	amsg, err := parseArchiveMsg(msg)
+	if err != nil {
+		p.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	bucketmd := p.bmdowner.get()
+	if !bucketmd.IsLocal(bucket) {
+		p.invalmsghdlr(w, r, fmt.Sprintf("%s: bucket %s is not local", msg.Action, bucket))
+		return
+	}
+	if bucketmd.IsLocal(amsg.CloudBucket) {
+		p.invalmsghdlr(w, r, fmt.Sprintf("%s: bucket %s is not a Cloud bucket", msg.Action, amsg.CloudBucket))
+		return
+	}
+	status := &cmn.ArchiveStatus{Action: msg.Action, State: cmn.ArchiveRunning, Started: time.Now()}
+	if !p.archives.start(bucket, status) {
+		p.invalmsghdlr(w, r, fmt.Sprintf("%s %s: export or import of the bucket is already running", msg.Action, bucket),
+			http.StatusConflict)
+		return
+	}
+	amsg.Manifest = nil
+	jsbytes, err := jsoniter.Marshal(&cmn.ActionMsg{Action: msg.Action, Value: amsg})
+	cmn.Assert(err == nil, err)
+
+	var (
+		smap    = p.smapowner.get()
+		urlpath = cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+		results = p.broadcastTargets(urlpath, nil, http.MethodPost, jsbytes, smap, ctx.config.Timeout.Default)
+		errs    = make([]string, 0)
+	)
+	for res := range results {
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", res.si.DaemonID, res.errstr))
+		}
+	}
+	if len(errs) != 0 {
+		// the targets that have started finish on their own
+		errstr := fmt.Sprintf("%s %s failed to start: %s", msg.Action, bucket, strings.Join(errs, "; "))
+		p.archives.finish(bucket, status, errstr)
+		p.invalmsghdlr(w, r, errstr)
+		return
+	}
+	glog.Infof("%s %s <=> %s/%s: started on %d target(s)", msg.Action, bucket, amsg.CloudBucket, amsg.Prefix, len(smap.Tmap))
+	go p.runArchive(bucket, amsg, status, smap)
+	p.writeJSON(w, r, p.archives.get(bucket), msg.Action)
+}
+
+// GET /v1/buckets/bucket-name?what=archive
+func (p *proxyrunner) getArchiveStatus(w http.ResponseWriter, r *http.Request, bucket string) {
+	if p.forwardCP(w, r, &cmn.ActionMsg{Action: cmn.GetWhatArchive}, bucket, nil) {
+		return
+	}
+	jsbytes := p.archives.get(bucket)
+	if jsbytes == nil {
+		p.invalmsghdlr(w, r, fmt.Sprintf("No export or import of bucket %s", bucket), http.StatusNotFound)
+		return
+	}
+	p.writeJSON(w, r, jsbytes, "archivestatus")
+}
+
+// runArchive polls the targets until all of them are done and then, for export, has the
+// manifest uploaded
+func (p *proxyrunner) runArchive(bucket string, amsg *cmn.ArchiveMsg, status *cmn.ArchiveStatus, smap *smapX) {
+	var (
+		urlpath  = cmn.URLPath(cmn.Version, cmn.Buckets, bucket)
+		query    = url.Values{cmn.URLParamWhat: []string{cmn.GetWhatArchive}}
+		statuses = make(map[string]*cmn.ArchiveStatus, len(smap.Tmap))
+		errs     = make([]string, 0)
+	)
+	for len(statuses) < len(smap.Tmap) {
+		time.Sleep(archivePoll)
+		for sid, si := range smap.Tmap {
+			if _, ok := statuses[sid]; ok {
+				continue
+			}
+			res := p.call(callArgs{
+				si:      si,
+				req:     reqArgs{method: http.MethodGet, base: si.IntraControlNet.DirectURL, path: urlpath, query: query},
+				timeout: ctx.config.Timeout.Default,
+			})
+			tstatus := &cmn.ArchiveStatus{}
+			if res.err == nil {
+				if err := jsoniter.Unmarshal(res.outjson, tstatus); err != nil {
+					res.errstr = fmt.Sprintf("failed to unmarshal, err: %v", err)
+				} else if tstatus.State == cmn.ArchiveRunning {
+					continue
+				}
+			} else if res.errstr == "" {
+				res.errstr = res.err.Error()
+			}
+			if res.errstr == "" && tstatus.Err != "" {
+				res.errstr = tstatus.Err
+			}
+			if res.errstr != "" {
+				errs = append(errs, fmt.Sprintf("%s: %s", sid, res.errstr))
+			}
+			statuses[sid] = tstatus
+		}
+	}
+	if len(errs) != 0 {
+		sort.Strings(errs)
+		errstr := fmt.Sprintf("%s %s failed: %s", status.Action, bucket, strings.Join(errs, "; "))
+		glog.Errorln(errstr)
+		p.archives.finish(bucket, status, errstr)
+		return
+	}
+	if status.Action == cmn.ActImport {
+		result := &cmn.ArchiveResult{}
+		for _, tstatus := range statuses {
+			if tstatus.Result != nil {
+				result.Objects += tstatus.Result.Objects
+				result.Size += tstatus.Result.Size
+			}
+		}
+		p.archives.Lock()
+		status.Result = result
+		p.archives.Unlock()
+		p.archives.finish(bucket, status, "")
+		glog.Infof("import %s: %d objects (%s)", bucket, result.Objects, cmn.B2S(result.Size, 1))
+		return
+	}
+	manifest := mergeArchiveShards(bucket, statuses)
+	if errstr := p.uploadArchiveManifest(bucket, amsg, manifest, smap); errstr != "" {
+		p.archives.finish(bucket, status, errstr)
+		return
+	}
+	p.archives.Lock()
+	status.Manifest = manifest
+	p.archives.Unlock()
+	p.archives.finish(bucket, status, "")
+	glog.Infof("export %s: %d objects (%s) in %d shards", bucket, manifest.Objects, cmn.B2S(manifest.Size, 1), len(manifest.Shards))
+}
+
+// mergeArchiveShards returns the manifest of the export: the shards of all targets
+func mergeArchiveShards(bucket string, statuses map[string]*cmn.ArchiveStatus) *cmn.ArchiveManifest {
+	manifest := &cmn.ArchiveManifest{Bucket: bucket, Created: time.Now(), Shards: make([]cmn.ArchiveShard, 0)}
+	for _, tstatus := range statuses {
+		if tstatus.Manifest == nil {
+			continue
+		}
+		for _, shard := range tstatus.Manifest.Shards {
+			manifest.Objects += shard.Objects
+			manifest.Size += shard.Size
+			manifest.Shards = append(manifest.Shards, shard)
+		}
+	}
+	sort.Slice(manifest.Shards, func(i, j int) bool { return manifest.Shards[i].Name < manifest.Shards[j].Name })
+	return manifest
+}
+
+// uploadArchiveManifest has the manifest uploaded by the target that "owns" it
+func (p *proxyrunner) uploadArchiveManifest(bucket string, amsg *cmn.ArchiveMsg, manifest *cmn.ArchiveManifest, smap *smapX) string {
+	si, errstr := hrwTarget(amsg.CloudBucket, archiveManifestName(amsg.Prefix), smap)
+	if errstr != "" {
+		return errstr
+	}
+	amsg.Manifest = manifest
+	jsbytes, err := jsoniter.Marshal(&cmn.ActionMsg{Action: cmn.ActExport, Value: amsg})
+	cmn.Assert(err == nil, err)
+	res := p.call(callArgs{
+		si: si,
+		req: reqArgs{
+			method: http.MethodPost,
+			base:   si.IntraControlNet.DirectURL,
+			path:   cmn.URLPath(cmn.Version, cmn.Buckets, bucket),
+			body:   jsbytes,
+		},
+		timeout: ctx.config.Timeout.DefaultLong,
+	})
+	if res.err != nil {
+		return fmt.Sprintf("export %s: failed to upload the manifest via %s: %s", bucket, si.DaemonID, res.errstr)
+	}
+	return ""
+}
+
+//
+// target
+//
+
+// POST { export | import } /v1/buckets/bucket-name: starts the xaction
+func (t *targetrunner) bucketArchive(w http.ResponseWriter, r *http.Request, bucket string, msg *cmn.ActionMsg) {
+	amsg, err := parseArchiveMsg(msg)
+	if err != nil {
+		t.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	if !t.bmdowner.get().IsLocal(bucket) {
+		t.invalmsghdlr(w, r, fmt.Sprintf("%s: bucket %s is not local", msg.Action, bucket))
+		return
+	}
+	ct := t.contextWithAuth(r)
+	if amsg.Manifest != nil {
+		if err = t.uploadArchiveManifest(ct, amsg); err != nil {
+			t.invalmsghdlr(w, r, err.Error())
+		}
+		return
+	}
+	if t.IsRebalancing() {
+		t.invalmsghdlr(w, r, fmt.Sprintf("%s %s: rebalance is running, try again later", msg.Action, bucket))
+		return
+	}
+	xarch := t.xactinp.renewArchive(t, msg.Action, bucket)
+	if xarch == nil {
+		t.invalmsghdlr(w, r, fmt.Sprintf("%s %s: export or import of the bucket is already running", msg.Action, bucket))
+		return
+	}
+	status := &cmn.ArchiveStatus{Action: msg.Action, State: cmn.ArchiveRunning, Started: time.Now()}
+	t.archives.start(bucket, status) // cannot be running - see renewArchive
+	glog.Infof("%s started: %s <=> %s/%s", xarch, bucket, amsg.CloudBucket, amsg.Prefix)
+	go t.runArchive(ct, bucket, amsg, xarch, status)
+	t.writeJSON(w, r, t.archives.get(bucket), msg.Action)
+}
+
+func (t *targetrunner) runArchive(ct context.Context, bucket string, amsg *cmn.ArchiveMsg, xarch *xactArchive,
+	status *cmn.ArchiveStatus) {
+	var (
+		shards []cmn.ArchiveShard
+		result *cmn.ArchiveResult
+		err    error
+		errstr string
+	)
+	if xarch.Kind() == cmn.ActExport {
+		shards, err = t.exportBucket(ct, bucket, amsg, xarch)
+	} else {
+		result, err = t.importBucket(ct, bucket, amsg, xarch)
+	}
+	xarch.EndTime(time.Now())
+	t.xactinp.del(xarch.ID())
+	if err != nil {
+		glog.Errorf("%s failed, err: %v", xarch, err)
+		errstr = err.Error()
+	} else {
+		glog.Infof("%s done", xarch)
+		t.archives.Lock()
+		if shards != nil {
+			status.Manifest = &cmn.ArchiveManifest{Bucket: bucket, Shards: shards}
+		}
+		status.Result = result
+		t.archives.Unlock()
+	}
+	t.archives.finish(bucket, status, errstr)
+}
+
+// GET /v1/buckets/bucket-name?what=archive
+func (t *targetrunner) getArchiveStatus(w http.ResponseWriter, r *http.Request, bucket string) {
+	jsbytes := t.archives.get(bucket)
+	if jsbytes == nil {
+		t.invalmsghdlr(w, r, fmt.Sprintf("No export or import of bucket %s", bucket), http.StatusNotFound)
+		return
+	}
+	t.writeJSON(w, r, jsbytes, "archivestatus")
+}
+
+//
+// export
+//
+
+func (t *targetrunner) exportBucket(ct context.Context, bucket string, amsg *cmn.ArchiveMsg,
+	xarch *xactArchive) (shards []cmn.ArchiveShard, err error) {
+	var (
+		sw  *archiveShardWriter
+		idx int
+	)
+	shards = make([]cmn.ArchiveShard, 0)
+	// with Parallel == 1 the mountpaths are walked one at a time - and an error stops
+	// the walk of the current mountpath only
+	opts := &fs.WalkOpts{Scope: fs.WalkLocal, Bucket: bucket, Parallel: 1, Skip: skipNonProcessable, Abort: xarch.ChanAbort()}
+	werr := fs.Mountpaths.WalkObjects(opts, func(entry *fs.WalkEntry) error {
+		if err != nil {
+			return err
+		}
+		if sw == nil {
+			shardName := archiveShardName(amsg.Prefix, t.si.DaemonID, idx)
+			if sw, err = newArchiveShardWriter(amsg.CloudBucket, shardName); err != nil {
+				return err
+			}
+			idx++
+		}
+		if err = t.archiveObj(sw, bucket, entry.Objname, entry.FQN); err != nil {
+			return err
+		}
+		if sw.shard.Size >= amsg.ShardSize {
+			err = t.uploadArchiveShard(ct, amsg.CloudBucket, sw)
+			if err == nil {
+				shards = append(shards, sw.shard)
+			}
+			sw = nil
+		}
+		return err
+	})
+	if err == nil {
+		err = werr
+	}
+	if sw != nil {
+		if err == nil {
+			if err = t.uploadArchiveShard(ct, amsg.CloudBucket, sw); err == nil {
+				shards = append(shards, sw.shard)
+			}
+		} else {
+			sw.discard()
+		}
+	}
+	return
+}
+
+func newArchiveShardWriter(cloudBucket, shardName string) (*archiveShardWriter, error) {
+	fqn, errstr := cluster.FQN(cloudBucket, shardName, false)
+	if errstr != "" {
+		return nil, fmt.Errorf("%s/%s: %s", cloudBucket, shardName, errstr)
+	}
+	sw := &archiveShardWriter{shard: cmn.ArchiveShard{Name: shardName}, fqn: cluster.GenContentFQN(fqn, cluster.DefaultWorkfileType)}
+	file, err := cmn.CreateFile(sw.fqn)
+	if err != nil {
+		return nil, err
+	}
+	sw.file, sw.tw = file, tar.NewWriter(file)
+	return sw, nil
+}
+
+func (sw *archiveShardWriter) discard() {
+	sw.file.Close()
+	if err := os.Remove(sw.fqn); err != nil {
+		glog.Errorf("Failed to remove %s, err: %v", sw.fqn, err)
+	}
+}
+
+// archiveObj appends the object to the shard; the object that has been removed in the
+// meantime is skipped
+func (t *targetrunner) archiveObj(sw *archiveShardWriter, bucket, objname, fqn string) error {
+	uname := cluster.Uname(bucket, objname)
+	t.rtnamemap.Lock(uname, false)
+	defer t.rtnamemap.Unlock(uname, false)
+
+	finfo, err := os.Stat(fqn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		t.fshc(err, fqn)
+		return err
+	}
+	size := finfo.Size()
+	if csize, ok := objCompressed(fqn); ok {
+		size = csize
+	}
+	props := &objectProps{}
+	if b, errstr := Getxattr(fqn, cmn.XattrXXHashVal); errstr == "" && len(b) > 0 {
+		props.nhobj = newcksumvalue(cmn.ParseStoredCksum(b))
+	}
+	if b, errstr := Getxattr(fqn, cmn.XattrObjVersion); errstr == "" {
+		props.version = string(b)
+	}
+	props.expires, _ = objExpires(fqn)
+
+	file, err := openObject(fqn)
+	if err != nil {
+		t.fshc(err, fqn)
+		return err
+	}
+	defer file.Close()
+	if err = sw.tw.WriteHeader(archiveHeader(objname, size, finfo.ModTime(), props)); err != nil {
+		return err
+	}
+	buf, slab := gmem2.AllocFromSlab2(size)
+	_, err = io.CopyBuffer(sw.tw, file, buf)
+	slab.Free(buf)
+	if err != nil {
+		return fmt.Errorf("failed to archive %s/%s, err: %v", bucket, objname, err)
+	}
+	sw.shard.Objects++
+	sw.shard.Size += size
+	return nil
+}
+
+func (t *targetrunner) uploadArchiveShard(ct context.Context, cloudBucket string, sw *archiveShardWriter) error {
+	defer sw.discard()
+	if err := sw.tw.Close(); err != nil {
+		return err
+	}
+	if _, err := sw.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, errstr, _ := getcloudif().putobj(ct, sw.file, cloudBucket, sw.shard.Name, nil); errstr != "" {
+		return fmt.Errorf("failed to upload %s/%s: %s", cloudBucket, sw.shard.Name, errstr)
+	}
+	glog.Infof("Export: uploaded %s/%s: %d objects (%s)", cloudBucket, sw.shard.Name, sw.shard.Objects, cmn.B2S(sw.shard.Size, 1))
+	return nil
+}
+
+func (t *targetrunner) uploadArchiveManifest(ct context.Context, amsg *cmn.ArchiveMsg) error {
+	name := archiveManifestName(amsg.Prefix)
+	fqn, errstr := cluster.FQN(amsg.CloudBucket, name, false)
+	if errstr != "" {
+		return fmt.Errorf("%s/%s: %s", amsg.CloudBucket, name, errstr)
+	}
+	jsbytes, err := jsoniter.MarshalIndent(amsg.Manifest, "", " ")
+	cmn.Assert(err == nil, err)
+	workfqn := cluster.GenContentFQN(fqn, cluster.DefaultWorkfileType)
+	file, err := cmn.CreateFile(workfqn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(workfqn)
+	}()
+	if _, err = file.Write(jsbytes); err != nil {
+		return err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, errstr, _ = getcloudif().putobj(ct, file, amsg.CloudBucket, name, nil); errstr != "" {
+		return fmt.Errorf("failed to upload %s/%s: %s", amsg.CloudBucket, name, errstr)
+	}
+	return nil
+}
+
+//
+// import
+//
+
+func (t *targetrunner) importBucket(ct context.Context, bucket string, amsg *cmn.ArchiveMsg,
+	xarch *xactArchive) (result *cmn.ArchiveResult, err error) {
+	manifest := &cmn.ArchiveManifest{}
+	name := archiveManifestName(amsg.Prefix)
+	err = t.downloadArchive(ct, amsg.CloudBucket, name, func(file *os.File) error {
+		b, err := ioutil.ReadAll(file)
+		if err == nil {
+			err = jsoniter.Unmarshal(b, manifest)
+		}
+		return err
+	})
+	if err != nil {
+		return
+	}
+	result = &cmn.ArchiveResult{}
+	smap := t.smapowner.get()
+	for _, shard := range manifest.Shards {
+		si, errstr := hrwTarget(amsg.CloudBucket, shard.Name, smap)
+		if errstr != "" {
+			return nil, fmt.Errorf("%s", errstr)
+		}
+		if si.DaemonID != t.si.DaemonID {
+			continue
+		}
+		err = t.downloadArchive(ct, amsg.CloudBucket, shard.Name, func(file *os.File) error {
+			return t.importShard(ct, bucket, file, smap, xarch, result)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %v", amsg.CloudBucket, shard.Name, err)
+		}
+		glog.Infof("Import: %s/%s: %d objects (%s)", amsg.CloudBucket, shard.Name, shard.Objects, cmn.B2S(shard.Size, 1))
+	}
+	return
+}
+
+// downloadArchive downloads the Cloud object into a workfile and calls cb to read it
+func (t *targetrunner) downloadArchive(ct context.Context, cloudBucket, objname string, cb func(file *os.File) error) error {
+	fqn, errstr := cluster.FQN(cloudBucket, objname, false)
+	if errstr != "" {
+		return fmt.Errorf("%s/%s: %s", cloudBucket, objname, errstr)
+	}
+	workfqn := cluster.GenContentFQN(fqn, cluster.DefaultWorkfileType)
+	defer os.Remove(workfqn)
+	if _, errstr, _ = getcloudif().getobj(ct, workfqn, cloudBucket, objname); errstr != "" {
+		return fmt.Errorf("failed to download %s/%s: %s", cloudBucket, objname, errstr)
+	}
+	file, err := os.Open(workfqn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return cb(file)
+}
+
+func (t *targetrunner) importShard(ct context.Context, bucket string, file *os.File, smap *smapX,
+	xarch *xactArchive, result *cmn.ArchiveResult) error {
+	tr := tar.NewReader(file)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		select {
+		case <-xarch.ChanAbort():
+			return fmt.Errorf("%s aborted", xarch)
+		default:
+		}
+		si, errstr := hrwTarget(bucket, hdr.Name, smap)
+		if errstr != "" {
+			return fmt.Errorf("%s", errstr)
+		}
+		props := archiveProps(hdr)
+		if si.DaemonID == t.si.DaemonID {
+			errstr = t.importObj(ct, bucket, hdr.Name, props, tr)
+		} else {
+			errstr = t.sendImported(si, bucket, hdr.Name, props, tr)
+		}
+		if errstr != "" {
+			return fmt.Errorf("%s/%s: %s", bucket, hdr.Name, errstr)
+		}
+		result.Objects++
+		result.Size += hdr.Size
+	}
+}
+
+func (t *targetrunner) importObj(ct context.Context, bucket, objname string, props *objectProps, reader io.Reader) (errstr string) {
+	fqn, errstr := cluster.FQN(bucket, objname, true)
+	if errstr != "" {
+		return
+	}
+	// the archived checksum (if any) is validated by receive()
+	putfqn := cluster.GenContentFQN(fqn, cluster.DefaultWorkfileType)
+	if _, props.nhobj, _, errstr = t.receive(putfqn, objname, "", props.nhobj, reader); errstr != "" {
+		return
+	}
+	errstr, _ = t.putCommit(ct, bucket, objname, putfqn, fqn, props, false /*rebalance*/)
+	return
+}
+
+// sendImported PUTs the object to the target it belongs to - the same way rebalance does
+func (t *targetrunner) sendImported(si *cluster.Snode, bucket, objname string, props *objectProps, reader io.Reader) string {
+	url := si.PublicNet.DirectURL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, objname)
+	url += fmt.Sprintf("?%s=%s&%s=%s", cmn.URLParamFromID, t.si.DaemonID, cmn.URLParamToID, si.DaemonID)
+	request, err := http.NewRequest(http.MethodPut, url, ioutil.NopCloser(reader))
+	if err != nil {
+		return fmt.Sprintf("Unexpected failure to create request %s, err: %v", url, err)
+	}
+	request.ContentLength = props.size
+	if props.nhobj != nil {
+		htype, hval := props.nhobj.get()
+		request.Header.Set(cmn.HeaderDFCChecksumType, htype)
+		request.Header.Set(cmn.HeaderDFCChecksumVal, hval)
+	}
+	if props.version != "" {
+		request.Header.Set(cmn.HeaderDFCObjVersion, props.version)
+	}
+	if !props.expires.IsZero() {
+		request.Header.Set(cmn.HeaderDFCObjExpires, props.expires.Format(time.RFC3339Nano))
+	}
+	contextwith, cancel := context.WithTimeout(context.Background(), ctx.config.Timeout.SendFile)
+	defer cancel()
+	response, err := t.intra.Do(request.WithContext(contextwith), true)
+	if err != nil {
+		return fmt.Sprintf("Failed to send to %s, err: %v", si.DaemonID, err)
+	}
+	b, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Sprintf("Failed to send to %s, status %s: %s", si.DaemonID, response.Status, string(b))
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestBucketArchive(t *testing.T) {
+	amsg, err := parseArchiveMsg(&cmn.ActionMsg{Action: cmn.ActExport, Value: cmn.ArchiveMsg{CloudBucket: "cb", Prefix: "/x/y/"}})
+	if err != nil || amsg.ShardSize != archiveShardSize || amsg.Prefix != "x/y" {
+		t.Fatalf("unexpected %+v (err %v)", amsg, err)
+	}
+	if name := archiveShardName(amsg.Prefix, "t1", 7); name != "x/y/t1-00007.tar" {
+		t.Errorf("unexpected shard name %q", name)
+	}
+	for _, v := range []cmn.ArchiveMsg{{}, {CloudBucket: "cb", ShardSize: -1}} {
+		if _, err = parseArchiveMsg(&cmn.ActionMsg{Action: cmn.ActImport, Value: v}); err == nil {
+			t.Errorf("expected %+v to fail", v)
+		}
+	}
+
+	var (
+		buf     bytes.Buffer
+		tw      = tar.NewWriter(&buf)
+		mtime   = time.Now().Truncate(time.Second)
+		expires = mtime.Add(time.Hour)
+		objs    = map[string]*objectProps{
+			"a/b/full": {version: "3", expires: expires, nhobj: newcksumvalue(cmn.ChecksumXXHash, "0123456789abcdef")},
+			"bare":     {},
+		}
+	)
+	for _, objname := range []string{"a/b/full", "bare"} {
+		data := []byte(objname)
+		if err = tw.WriteHeader(archiveHeader(objname, int64(len(data)), mtime, objs[objname])); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(&buf)
+	for i := 0; i < len(objs); i++ {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(tr)
+		if string(data) != hdr.Name || !hdr.ModTime.Equal(mtime) {
+			t.Errorf("%s: unexpected content %q, mtime %v", hdr.Name, data, hdr.ModTime)
+		}
+		exp, props := objs[hdr.Name], archiveProps(hdr)
+		if props.version != exp.version || !props.expires.Equal(exp.expires) || props.size != int64(len(data)) {
+			t.Errorf("%s: expected %+v, got %+v", hdr.Name, exp, props)
+		}
+		if (exp.nhobj == nil) != (props.nhobj == nil) {
+			t.Fatalf("%s: expected checksum %v, got %v", hdr.Name, exp.nhobj, props.nhobj)
+		}
+		if exp.nhobj != nil {
+			_, v1 := exp.nhobj.get()
+			_, v2 := props.nhobj.get()
+			if v1 != v2 {
+				t.Errorf("%s: expected checksum %s, got %s", hdr.Name, v1, v2)
+			}
+		}
+	}
+}
+
+func TestArchiveStatuses(t *testing.T) {
+	var as archiveStatuses
+	if as.get("lb") != nil {
+		t.Error("expected no status")
+	}
+	status := &cmn.ArchiveStatus{Action: cmn.ActExport, State: cmn.ArchiveRunning, Started: time.Now()}
+	if !as.start("lb", status) {
+		t.Fatal("expected the export started")
+	}
+	if as.start("lb", &cmn.ArchiveStatus{Action: cmn.ActImport, State: cmn.ArchiveRunning}) {
+		t.Error("expected the import rejected while the export is running")
+	}
+	as.finish("lb", status, "t1: failed")
+	if status.State != cmn.ArchiveFailed || status.Finished.IsZero() {
+		t.Errorf("unexpected %+v", status)
+	}
+	if !as.start("lb", &cmn.ArchiveStatus{Action: cmn.ActImport, State: cmn.ArchiveRunning}) {
+		t.Error("expected the import started once the export is done")
+	}
+
+	manifest := mergeArchiveShards("lb", map[string]*cmn.ArchiveStatus{
+		"t2": {Manifest: &cmn.ArchiveManifest{Shards: []cmn.ArchiveShard{{Name: "p/t2-00000.tar", Objects: 2, Size: 20}}}},
+		"t1": {Manifest: &cmn.ArchiveManifest{Shards: []cmn.ArchiveShard{
+			{Name: "p/t1-00001.tar", Objects: 1, Size: 10}, {Name: "p/t1-00000.tar", Objects: 3, Size: 30}}}},
+		"t3": {}, // nothing to export
+	})
+	if manifest.Objects != 6 || manifest.Size != 60 || len(manifest.Shards) != 3 ||
+		manifest.Shards[0].Name != "p/t1-00000.tar" || manifest.Shards[2].Name != "p/t2-00000.tar" {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Per-bucket IO for chargeback: each target counts the GETs and the bytes sent, the PUTs
+// and the bytes received, and the DELETEs of each bucket, and reports the counts via
+// GET /v1/daemon?what=bucketstats. GET /v1/cluster?what=bucketstats sums the targets'
+// counts per bucket. With reset=true each target atomically starts counting anew, so that
+// the consecutive billing periods neither overlap nor leave gaps; each target reports its
+// own period (since, until) - since the target's start or its last reset. Targets that do
+// not respond are skipped (and logged) - their counts remain for the next request.
+//
+// ================================ Summary ===============================================
+
+func (p *proxyrunner) invokeHttpGetClusterBucketIO(w http.ResponseWriter, r *http.Request) bool {
+	var (
+		smap  = p.smapowner.get()
+		query = url.Values{}
+		out   = &cmn.ClusterBucketStats{
+			Buckets: make(cmn.BucketStats),
+			Targets: make(map[string]*cmn.TargetBucketStats, len(smap.Tmap)),
+		}
+	)
+	query.Add(cmn.URLParamWhat, cmn.GetWhatBucketIO)
+	reset, err := parsebool(r.URL.Query().Get(cmn.URLParamReset))
+	if err != nil {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Invalid %s, err: %v", cmn.URLParamReset, err))
+		return false
+	}
+	if reset {
+		query.Add(cmn.URLParamReset, "true")
+	}
+	results := p.broadcastDash(cmn.URLPath(cmn.Version, cmn.Daemon), query, smap.Tmap)
+	for res := range results {
+		if res.err != nil {
+			glog.Errorf("Failed to get %s bucket stats: %s", res.si, res.errstr)
+			continue
+		}
+		stats := &cmn.TargetBucketStats{}
+		if err := jsoniter.Unmarshal(res.outjson, stats); err != nil {
+			glog.Errorf("Failed to unmarshal %s bucket stats, err: %v", res.si, err)
+			continue
+		}
+		out.Targets[res.si.DaemonID] = stats
+		for bucket, d := range stats.Buckets {
+			out.Buckets.Add(bucket, d)
+		}
+		out.Other.Add(&stats.Other)
+	}
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterBucketIO")
+}
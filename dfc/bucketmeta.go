@@ -99,6 +99,15 @@ func (m *bucketMD) propsAndChecksum(bucket string) (p cmn.BucketProps, checksum
 	return p, p.Checksum, true
 }
 
+// cksumType returns the type of the checksums computed for the bucket's objects: the
+// bucket's own or the global one
+func (m *bucketMD) cksumType(bucket string) string {
+	if _, checksum, defined := m.propsAndChecksum(bucket); defined {
+		return checksum
+	}
+	return ctx.config.Cksum.Checksum
+}
+
 // lruEnabled returns whether or not LRU is enabled
 // for the bucket. Returns the global setting if bucket not found
 func (m *bucketMD) lruEnabled(bucket string) bool {
@@ -109,6 +118,21 @@ func (m *bucketMD) lruEnabled(bucket string) bool {
 	return p.LRUEnabled
 }
 
+// atimePolicy returns the bucket's access time tracking policy - cmn.AtimeIgnore if LRU
+// is disabled for the bucket
+func (m *bucketMD) atimePolicy(bucket string) string {
+	ok, p := m.get(bucket, m.IsLocal(bucket))
+	switch {
+	case !ok && ctx.config.LRU.LRUEnabled:
+		return cmn.AtimeTrack
+	case !ok, !p.LRUEnabled:
+		return cmn.AtimeIgnore
+	case p.AtimePolicy == "":
+		return cmn.AtimeTrack
+	}
+	return p.AtimePolicy
+}
+
 func (m *bucketMD) clone() *bucketMD {
 	dst := &bucketMD{}
 	m.deepcopy(dst)
@@ -0,0 +1,287 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Cluster summary: how many objects, and how many bytes, does the cluster store? Each target
+// maintains the counts per bucket (stats/bucket_capacity.go) as the objects get committed
+// (PUT, cold GET, rebalance, replication) and removed (DELETE, evict, LRU, expiration), and
+// reports them via GET /v1/daemon?what=summary. The counts are walked once upon startup and
+// upon mountpath changes - in the background, throttled on disk utilization. While walking,
+// each mountpath's position (the last object walked) is tracked: an object added or removed
+// at a position already walked is accounted for as usual, while the one yet to be walked is
+// left to the walk. Since a directory is listed once, as the walk enters it, an object added
+// to it thereafter but ahead of the position is missed - the counts are approximate while
+// being walked, and only that much. GET /v1/cluster?what=summary sums the targets' counts:
+// in total, per provider (local buckets are "dfc"), and local vs cached (Cloud) buckets.
+// Objects stored by more than one target - e.g., the copies that rebalance leaves behind
+// until evicted - are counted as many times.
+//
+// ================================ Summary ===============================================
+
+//
+// target
+//
+
+type (
+	// recountCursor tracks the positions of the recount's walk, per mountpath
+	recountCursor struct {
+		sync.Mutex
+		mpaths map[string]*recountPos // nil - not recounting
+	}
+	recountPos struct {
+		done    bool
+		islocal bool     // local buckets are walked first, Cloud ones next
+		rel     []string // bucket, object name's components: the last object walked
+	}
+)
+
+var capRecount recountCursor
+
+func (rc *recountCursor) start() {
+	rc.Lock()
+	rc.mpaths = make(map[string]*recountPos)
+	rc.Unlock()
+}
+
+func (rc *recountCursor) walk(entry *fs.WalkEntry) {
+	rc.Lock()
+	pos, ok := rc.mpaths[entry.MpathInfo.Path]
+	if !ok {
+		pos = &recountPos{}
+		rc.mpaths[entry.MpathInfo.Path] = pos
+	}
+	pos.islocal, pos.rel = entry.IsLocal, append([]string{entry.Bucket}, strings.Split(entry.Objname, "/")...)
+	rc.Unlock()
+}
+
+func (rc *recountCursor) done(mpath string) {
+	rc.Lock()
+	rc.mpaths[mpath] = &recountPos{done: true}
+	rc.Unlock()
+}
+
+func (rc *recountCursor) stop() {
+	rc.Lock()
+	rc.mpaths = nil
+	rc.Unlock()
+}
+
+// walked returns true if the recount is not running or has walked past the object, so
+// that the object's change is to be accounted for
+func (rc *recountCursor) walked(fqn string, islocal bool) bool {
+	rc.Lock()
+	defer rc.Unlock()
+	if rc.mpaths == nil {
+		return true
+	}
+	mpathInfo, rel := fs.Mountpaths.Path2MpathInfo(fqn)
+	if mpathInfo == nil {
+		return true
+	}
+	pos, ok := rc.mpaths[mpathInfo.Path]
+	switch {
+	case !ok:
+		return false
+	case pos.done:
+		return true
+	case pos.islocal != islocal:
+		return islocal // local buckets are walked before the Cloud ones
+	}
+	comps := strings.Split(filepath.ToSlash(rel), "/")
+	if len(comps) < 2 {
+		return true
+	}
+	return walkOrder(comps[1:], pos.rel) <= 0
+}
+
+// walkOrder compares the paths component-wise - the order of filepath.Walk
+func walkOrder(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return strings.Compare(a[i], b[i])
+		}
+	}
+	return len(a) - len(b)
+}
+
+// addBucketCapacity accounts for the object added to (or removed from) the bucket, unless
+// the running recount is yet to walk it; called once the object is renamed or removed
+func addBucketCapacity(fqn, bucket string, islocal bool, objects, bytes int64) {
+	if capRecount.walked(fqn, islocal) {
+		getstorstatsrunner().AddBucketCapacity(bucket, islocal, objects, bytes)
+	}
+}
+
+// requestRecount requests to recount the objects per bucket; does not block
+func (t *targetrunner) requestRecount() {
+	select {
+	case t.recountCh <- struct{}{}:
+	default: // already requested
+	}
+}
+
+func (t *targetrunner) runRecount() {
+	for range t.recountCh {
+		t.recountCapacity()
+	}
+}
+
+// recountCapacity walks all buckets on all available mountpaths
+func (t *targetrunner) recountCapacity() {
+	var (
+		mu     sync.Mutex
+		walked = &cmn.TargetCapacity{Local: make(map[string]*cmn.ObjCount), Cloud: make(map[string]*cmn.ObjCount)}
+		opts   = &fs.WalkOpts{
+			Skip: skipNonProcessable,
+			Throttler: func(mpathInfo *fs.MountpathInfo) fs.Throttler {
+				return &cluster.Throttle{
+					Riostat:      getiostatrunner(),
+					CapUsedHigh:  &ctx.config.LRU.HighWM,
+					DiskUtilLow:  &ctx.config.Xaction.DiskUtilLowWM,
+					DiskUtilHigh: &ctx.config.Xaction.DiskUtilHighWM,
+					Period:       &ctx.config.Periodic.StatsTime,
+					Path:         mpathInfo.Path,
+					FS:           mpathInfo.FileSystem,
+					Flag:         cluster.OnDiskUtil}
+			},
+		}
+	)
+	capRecount.start()
+	getstorstatsrunner().RecountBucketCapacity()
+	avail, _ := fs.Mountpaths.Get()
+	wg := &sync.WaitGroup{}
+	for _, mpathInfo := range avail {
+		wg.Add(1)
+		go func(mpathInfo *fs.MountpathInfo) {
+			defer wg.Done()
+			opts := *opts
+			opts.Mpaths = []*fs.MountpathInfo{mpathInfo}
+			err := fs.Mountpaths.WalkObjects(&opts, func(entry *fs.WalkEntry) error {
+				mm := walked.Cloud
+				if entry.IsLocal {
+					mm = walked.Local
+				}
+				mu.Lock()
+				capRecount.walk(entry)
+				c, ok := mm[entry.Bucket]
+				if !ok {
+					c = &cmn.ObjCount{}
+					mm[entry.Bucket] = c
+				}
+				c.Objects++
+				c.Bytes += entry.FileInfo.Size()
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				glog.Errorf("Failed to count objects per bucket on %s (the counts are incomplete), err: %v", mpathInfo.Path, err)
+			}
+			capRecount.done(mpathInfo.Path)
+		}(mpathInfo)
+	}
+	wg.Wait()
+	getstorstatsrunner().RecountedBucketCapacity(walked)
+	capRecount.stop()
+	glog.Infof("Counted objects of %d local and %d Cloud bucket(s)", len(walked.Local), len(walked.Cloud))
+}
+
+// replaceDelta returns the change in the bucket's objects once the workfile replaces (or
+// creates) the object; called prior to the rename, under the object's lock
+func replaceDelta(workfqn, fqn string) (objects, bytes int64) {
+	if finfo, err := os.Stat(workfqn); err == nil {
+		bytes = finfo.Size()
+	}
+	if finfo, err := os.Stat(fqn); err == nil {
+		bytes -= finfo.Size()
+	} else {
+		objects = 1
+	}
+	return
+}
+
+// commitObj renames the workfile as the object, accounting for the bucket's objects
+func commitObj(workfqn, fqn, bucket string, islocal bool) error {
+	objects, bytes := replaceDelta(workfqn, fqn)
+	if err := os.Rename(workfqn, fqn); err != nil {
+		return err
+	}
+	addBucketCapacity(fqn, bucket, islocal, objects, bytes)
+	return nil
+}
+
+//
+// proxy
+//
+
+// GET /v1/cluster?what=summary
+func (p *proxyrunner) invokeHttpGetClusterSummary(w http.ResponseWriter, r *http.Request) bool {
+	var (
+		smap     = p.smapowner.get()
+		bucketmd = p.bmdowner.get()
+		query    = url.Values{}
+		out      = &cmn.ClusterSummary{Providers: make(map[string]*cmn.ObjCount, 2), Counting: make([]string, 0)}
+	)
+	query.Add(cmn.URLParamWhat, cmn.GetWhatSummary)
+	addProvider := func(provider string, c *cmn.ObjCount) {
+		acc, ok := out.Providers[provider]
+		if !ok {
+			acc = &cmn.ObjCount{}
+			out.Providers[provider] = acc
+		}
+		acc.Add(c)
+	}
+	results := p.broadcastDash(cmn.URLPath(cmn.Version, cmn.Daemon), query, smap.Tmap)
+	for res := range results {
+		if res.err != nil {
+			glog.Errorf("Failed to get %s summary: %s", res.si, res.errstr)
+			continue
+		}
+		capacity := &cmn.TargetCapacity{}
+		if err := jsoniter.Unmarshal(res.outjson, capacity); err != nil {
+			glog.Errorf("Failed to unmarshal %s summary, err: %v", res.si, err)
+			continue
+		}
+		out.Targets++
+		if capacity.Counting {
+			out.Counting = append(out.Counting, res.si.DaemonID)
+		}
+		for bucket, c := range capacity.Local {
+			if ok, _ := bucketmd.get(bucket, true); !ok {
+				continue // destroyed
+			}
+			out.Local.Add(c)
+			addProvider(cmn.ProviderDFC, c)
+		}
+		for _, c := range capacity.Cloud {
+			out.Cached.Add(c)
+			addProvider(ctx.config.CloudProvider, c)
+		}
+	}
+	out.ObjCount.Add(&out.Local)
+	out.ObjCount.Add(&out.Cached)
+	sort.Strings(out.Counting)
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterSummary")
+}
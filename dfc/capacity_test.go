@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func TestRecountCursor(t *testing.T) {
+	q := fs.Mountpaths
+	defer func() { fs.Mountpaths = q }()
+	fs.Mountpaths = fs.NewMountedFS("local", "cloud")
+	fs.Mountpaths.DisableFsIDCheck()
+	mpath, err := ioutil.TempDir("", "recount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mpath)
+	if err := fs.Mountpaths.Add(mpath); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		rc        recountCursor
+		mpathInfo = &fs.MountpathInfo{Path: mpath}
+		local     = func(name string) string { return filepath.Join(fs.Mountpaths.MakePathLocal(mpath), name) }
+		cloud     = func(name string) string { return filepath.Join(fs.Mountpaths.MakePathCloud(mpath), name) }
+	)
+	if !rc.walked(local("b/o"), true) {
+		t.Error("expected all objects accounted for while not recounting")
+	}
+	rc.start()
+	if rc.walked(local("b/o"), true) {
+		t.Error("expected the mountpath yet to be walked")
+	}
+	rc.walk(&fs.WalkEntry{MpathInfo: mpathInfo, Bucket: "b", Objname: "d/o5", IsLocal: true})
+	tests := []struct {
+		fqn     string
+		islocal bool
+		walked  bool
+	}{
+		{local("a/z"), true, true},
+		{local("b/d/o1"), true, true},
+		{local("b/d/o5"), true, true},
+		{local("b/d/o6"), true, false},
+		{local("b/d-x/o"), true, false}, // "d-x" < "d/..." as strings but is walked after "d"
+		{local("b/c/d/e"), true, true},
+		{local("b/o"), true, false},
+		{local("c"), true, false},
+		{cloud("a/o"), false, false},
+	}
+	for _, test := range tests {
+		if walked := rc.walked(test.fqn, test.islocal); walked != test.walked {
+			t.Errorf("%s: expected walked %t, got %t", test.fqn, test.walked, walked)
+		}
+	}
+	rc.walk(&fs.WalkEntry{MpathInfo: mpathInfo, Bucket: "a", Objname: "o", IsLocal: false})
+	if !rc.walked(local("z/o"), true) || rc.walked(cloud("b/o"), false) {
+		t.Error("expected the local buckets walked before the Cloud ones")
+	}
+	rc.done(mpath)
+	if !rc.walked(cloud("z/o"), false) {
+		t.Error("expected the mountpath walked")
+	}
+	rc.stop()
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Async checksum (cksum_config.async_checksum_put): PUT is acknowledged as soon as the
+// object is written and committed, without computing its checksum. The object is then
+// queued, and the background worker computes the checksum and stores it the same way
+// a regular PUT does (xattr).
+//
+// Until then the object is "pending": warm GET does not validate its checksum and the
+// response carries the HeaderDFCChecksumPending header instead of the checksum. The
+// number of pending objects is reported via the cksum.pending.n stats counter.
+//
+// The pending objects are also marked persistently, with the XattrCksumPending xattr
+// that the PUT stores along with the object's other xattrs and that gets removed once
+// the checksum is in place. Upon startup the target walks its objects in the background
+// and queues the marked ones again, so that the checksums interrupted by the restart
+// are computed nonetheless (while the walk is in progress, a marked object that has not
+// been queued yet is served as if it had no checksum at all).
+//
+// ================================ Summary ===============================================
+
+type (
+	cksumPending struct {
+		bucket  string
+		objname string
+		gen     int64 // incremented when the object is PUT again while still pending
+	}
+	asyncCksum struct {
+		sync.Mutex
+		t      *targetrunner
+		objs   map[string]*cksumPending // fqn => pending
+		workCh chan struct{}
+	}
+)
+
+func newAsyncCksum(t *targetrunner) *asyncCksum {
+	return &asyncCksum{t: t, objs: make(map[string]*cksumPending, 64), workCh: make(chan struct{}, 1)}
+}
+
+// add queues the object for checksumming
+func (a *asyncCksum) add(fqn, bucket, objname string) { a.queue(fqn, bucket, objname, true) }
+
+// requeue queues the object found marked pending unless it is already queued
+func (a *asyncCksum) requeue(fqn, bucket, objname string) { a.queue(fqn, bucket, objname, false) }
+
+func (a *asyncCksum) queue(fqn, bucket, objname string, put bool) {
+	a.Lock()
+	if p, ok := a.objs[fqn]; ok {
+		if put {
+			p.gen++
+		}
+	} else {
+		a.objs[fqn] = &cksumPending{bucket: bucket, objname: objname}
+		a.t.statsif.Add(stats.CksumPendingCount, 1)
+	}
+	a.Unlock()
+	select {
+	case a.workCh <- struct{}{}:
+	default:
+	}
+}
+
+// del is called when the object gets overwritten along with its checksum
+func (a *asyncCksum) del(fqn string) {
+	a.Lock()
+	if _, ok := a.objs[fqn]; ok {
+		delete(a.objs, fqn)
+		a.t.statsif.Add(stats.CksumPendingCount, -1)
+	}
+	a.Unlock()
+}
+
+func (a *asyncCksum) pending(fqn string) (ok bool) {
+	a.Lock()
+	_, ok = a.objs[fqn]
+	a.Unlock()
+	return
+}
+
+// next returns any pending object and its generation
+func (a *asyncCksum) next() (fqn string, p cksumPending, ok bool) {
+	a.Lock()
+	for f, pp := range a.objs {
+		fqn, p, ok = f, *pp, true
+		break
+	}
+	a.Unlock()
+	return
+}
+
+// done removes the object from the pending set unless it has been PUT again in the meantime
+func (a *asyncCksum) done(fqn string, gen int64) {
+	a.Lock()
+	if p, ok := a.objs[fqn]; ok && p.gen == gen {
+		delete(a.objs, fqn)
+		a.t.statsif.Add(stats.CksumPendingCount, -1)
+	}
+	a.Unlock()
+}
+
+func (a *asyncCksum) run() {
+	for range a.workCh {
+		buf, slab := gmem2.AllocFromSlab2(0)
+		for {
+			fqn, p, ok := a.next()
+			if !ok {
+				break
+			}
+			if errstr := a.compute(fqn, &p, buf); errstr != "" {
+				glog.Errorf("Failed to compute checksum of %s/%s: %s", p.bucket, p.objname, errstr)
+			}
+			a.done(fqn, p.gen)
+		}
+		slab.Free(buf)
+	}
+}
+
+func (a *asyncCksum) compute(fqn string, p *cksumPending, buf []byte) (errstr string) {
+	uname := cluster.Uname(p.bucket, p.objname)
+	a.t.rtnamemap.Lock(uname, false)
+	defer a.t.rtnamemap.Unlock(uname, false)
+
+	file, err := os.Open(fqn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return // deleted or moved
+		}
+		a.t.fshc(err, fqn)
+		return err.Error()
+	}
+	kind := a.t.bmdowner.get().cksumType(p.bucket)
+	xxHashVal, errstr := cmn.ComputeCksum(kind, file, buf)
+	file.Close()
+	if errstr != "" {
+		return
+	}
+	if errstr = Setxattr(fqn, cmn.XattrXXHashVal, cmn.MakeStoredCksum(kind, xxHashVal)); errstr != "" {
+		return
+	}
+	a.t.statsif.Add(stats.CksumAsyncCount, 1)
+	if marker, _ := Getxattr(fqn, cmn.XattrCksumPending); marker != nil {
+		errstr = Deletexattr(fqn, cmn.XattrCksumPending)
+	}
+	return
+}
+
+// rescan queues the objects that remained pending when the target was last stopped
+func (a *asyncCksum) rescan() {
+	opts := &fs.WalkOpts{
+		Skip: skipNonProcessable,
+		Throttler: func(mpathInfo *fs.MountpathInfo) fs.Throttler {
+			return &cluster.Throttle{
+				Riostat:      getiostatrunner(),
+				CapUsedHigh:  &ctx.config.LRU.HighWM,
+				DiskUtilLow:  &ctx.config.Xaction.DiskUtilLowWM,
+				DiskUtilHigh: &ctx.config.Xaction.DiskUtilHighWM,
+				Period:       &ctx.config.Periodic.StatsTime,
+				Path:         mpathInfo.Path,
+				FS:           mpathInfo.FileSystem,
+				Flag:         cluster.OnDiskUtil}
+		},
+	}
+	var n int64
+	err := fs.Mountpaths.WalkObjects(opts, func(entry *fs.WalkEntry) error {
+		if marker, errstr := Getxattr(entry.FQN, cmn.XattrCksumPending); errstr != "" || marker == nil {
+			return nil
+		}
+		a.requeue(entry.FQN, entry.Bucket, entry.Objname)
+		atomic.AddInt64(&n, 1)
+		return nil
+	})
+	if err != nil {
+		glog.Errorf("Failed to look up the objects pending checksum, err: %v", err)
+	}
+	if n > 0 {
+		glog.Infof("Queued %d object(s) pending checksum", n)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// fakeStatsTracker collects the stats of a targetrunner or proxyrunner created by a test
+type fakeStatsTracker struct {
+	stats map[string]int64
+}
+
+func (s *fakeStatsTracker) Add(name string, val int64)            { s.stats[name] += val }
+func (s *fakeStatsTracker) AddErrorHTTP(method string, val int64) {}
+func (s *fakeStatsTracker) AddMany(nv ...stats.NamedVal64) {
+	for _, v := range nv {
+		s.Add(v.Name, v.Val)
+	}
+}
+func (s *fakeStatsTracker) AddBucket(bucket string, nv ...stats.NamedVal64) { s.AddMany(nv...) }
+
+func TestAsyncCksumPending(t *testing.T) {
+	tracker := &fakeStatsTracker{stats: make(map[string]int64)}
+	target := newFakeTargetRunner()
+	target.statsif = tracker
+	a := newAsyncCksum(target)
+
+	a.add("/tmp/fqn1", "bucket", "obj1")
+	a.add("/tmp/fqn2", "bucket", "obj2")
+	if !a.pending("/tmp/fqn1") || !a.pending("/tmp/fqn2") || a.pending("/tmp/fqn3") {
+		t.Fatalf("Unexpected pending state: %+v", a.objs)
+	}
+	if n := tracker.stats[stats.CksumPendingCount]; n != 2 {
+		t.Errorf("Expected %d pending checksums, got %d", 2, n)
+	}
+
+	// PUT again while pending: the checksum computed for the previous generation is stale
+	fqn, p, ok := a.next()
+	if !ok {
+		t.Fatalf("Expected a pending object")
+	}
+	a.add(fqn, p.bucket, p.objname)
+	a.done(fqn, p.gen)
+	if !a.pending(fqn) {
+		t.Errorf("Expected %s to remain pending after being PUT again", fqn)
+	}
+	if n := tracker.stats[stats.CksumPendingCount]; n != 2 {
+		t.Errorf("Expected %d pending checksums, got %d", 2, n)
+	}
+	a.done(fqn, p.gen+1)
+	if a.pending(fqn) {
+		t.Errorf("Expected %s to be checksummed", fqn)
+	}
+
+	// regular PUT overwrites the object along with its checksum
+	a.del("/tmp/fqn1")
+	a.del("/tmp/fqn2")
+	a.del("/tmp/fqn2")
+	if _, _, ok := a.next(); ok {
+		t.Errorf("Expected no pending objects, got %+v", a.objs)
+	}
+	if n := tracker.stats[stats.CksumPendingCount]; n != 0 {
+		t.Errorf("Expected no pending checksums, got %d", n)
+	}
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/atime"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/ios"
+	"github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Cold data summary: per bucket, the number and the total size of the objects that were
+// not accessed within the last N days, and of the objects that LRU is permitted to evict
+// (i.e., not accessed within the configured dont_evict_time).
+//
+// Each target computes its part by walking the bucket's directories on all available
+// mountpaths; access times are taken from the atime runner (with fallback to the
+// filesystem atime/mtime) - exactly the way LRU does it. The results are cached for
+// the duration of lru_config.capacity_upd_time, so that repeated queries do not
+// trigger repeated walks. The primary (or any) proxy broadcasts the request and
+// sums up the results.
+//
+// ================================ Summary ===============================================
+
+const (
+	coldDataDefaultDays = 30
+	coldDataMaxDays     = 365 * 10
+)
+
+type (
+	coldDataKey struct {
+		bucket string
+		days   int
+	}
+	coldDataCache struct {
+		sync.Mutex
+		m map[coldDataKey]*cmn.BucketColdSummary
+	}
+	// coldDataWalk accumulates a single mountpath's worth of stats
+	coldDataWalk struct {
+		cmn.ColdDataStats
+		coldTime    time.Time
+		evictTime   time.Time
+		atimeRespCh chan *atime.Response
+	}
+)
+
+func newColdDataCache() *coldDataCache {
+	return &coldDataCache{m: make(map[coldDataKey]*cmn.BucketColdSummary, 8)}
+}
+
+func (c *coldDataCache) get(key coldDataKey, ttl time.Duration) *cmn.BucketColdSummary {
+	c.Lock()
+	summary, ok := c.m[key]
+	c.Unlock()
+	if !ok || time.Since(summary.Updated) > ttl {
+		return nil
+	}
+	return summary
+}
+
+func (c *coldDataCache) put(key coldDataKey, summary *cmn.BucketColdSummary) {
+	c.Lock()
+	c.m[key] = summary
+	c.Unlock()
+}
+
+// coldDataDays parses and validates the optional ?days= query parameter
+func coldDataDays(query url.Values) (days int, errstr string) {
+	s := query.Get(cmn.URLParamDays)
+	if s == "" {
+		return coldDataDefaultDays, ""
+	}
+	days, err := strconv.Atoi(s)
+	if err != nil || days < 0 || days > coldDataMaxDays {
+		return 0, fmt.Sprintf("Invalid %s=%s: expecting integer in the range [0, %d]", cmn.URLParamDays, s, coldDataMaxDays)
+	}
+	return days, ""
+}
+
+func addColdDataStats(to, from *cmn.ColdDataStats) {
+	to.ObjCount += from.ObjCount
+	to.ObjSize += from.ObjSize
+	to.ColdCount += from.ColdCount
+	to.ColdSize += from.ColdSize
+	to.EvictCount += from.EvictCount
+	to.EvictSize += from.EvictSize
+}
+
+//
+// target
+//
+
+// GET /v1/buckets/bucket-name?what=colddata[&days=N]
+func (t *targetrunner) getColdData(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	days, errstr := coldDataDays(query)
+	if errstr != "" {
+		t.invalmsghdlr(w, r, errstr)
+		return
+	}
+	bucketmd := t.bmdowner.get()
+	islocal := bucketmd.IsLocal(bucket)
+	if errstr, errcode := t.checkIsLocal(bucket, bucketmd, query, islocal); errstr != "" {
+		t.invalmsghdlr(w, r, errstr, errcode)
+		return
+	}
+	key := coldDataKey{bucket: bucket, days: days}
+	summary := t.coldData.get(key, ctx.config.LRU.CapacityUpdTime)
+	if summary == nil {
+		var err error
+		if summary, err = t.walkColdData(bucket, islocal, days); err != nil {
+			t.invalmsghdlr(w, r, err.Error())
+			return
+		}
+		t.coldData.put(key, summary)
+	}
+	jsbytes, err := jsoniter.Marshal(summary)
+	cmn.Assert(err == nil, err)
+	t.writeJSON(w, r, jsbytes, "getcolddata")
+}
+
+// walkColdData traverses all available mountpaths in parallel
+func (t *targetrunner) walkColdData(bucket string, islocal bool, days int) (*cmn.BucketColdSummary, error) {
+	type mresp struct {
+		walk       *coldDataWalk
+		failedPath string
+		err        error
+	}
+	var (
+		now                 = time.Now()
+		availablePaths, _   = fs.Mountpaths.Get()
+		ch                  = make(chan *mresp, len(availablePaths))
+		wg                  = &sync.WaitGroup{}
+		coldTime, evictTime = now.Add(-time.Duration(days) * 24 * time.Hour), now.Add(-ctx.config.LRU.DontEvictTime)
+		summary             = &cmn.BucketColdSummary{Bucket: bucket, Days: days, Updated: now}
+	)
+	for _, mpathInfo := range availablePaths {
+		dir := filepath.Join(fs.Mountpaths.MakePathCloud(mpathInfo.Path), bucket)
+		if islocal {
+			dir = filepath.Join(fs.Mountpaths.MakePathLocal(mpathInfo.Path), bucket)
+		}
+		wg.Add(1)
+		go func(dir string) {
+			defer wg.Done()
+			resp := &mresp{walk: &coldDataWalk{
+				coldTime:    coldTime,
+				evictTime:   evictTime,
+				atimeRespCh: make(chan *atime.Response, 1),
+			}}
+			if err := filepath.Walk(dir, resp.walk.walkf); err != nil {
+				glog.Errorf("Failed to traverse path %q, err: %v", dir, err)
+				resp.failedPath, resp.err = dir, err
+			}
+			ch <- resp
+		}(dir)
+	}
+	wg.Wait()
+	close(ch)
+
+	for resp := range ch {
+		if resp.err != nil {
+			t.fshc(resp.err, resp.failedPath)
+			return nil, fmt.Errorf("Failed to read %s", resp.failedPath)
+		}
+		addColdDataStats(&summary.ColdDataStats, &resp.walk.ColdDataStats)
+	}
+	return summary, nil
+}
+
+func (cw *coldDataWalk) walkf(fqn string, osfi os.FileInfo, err error) error {
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		glog.Errorf("walkf invoked with err: %v", err)
+		return err
+	}
+	if osfi.IsDir() {
+		return nil
+	}
+	if spec, _ := cluster.FileSpec(fqn); spec != nil && !spec.PermToProcess() {
+		return nil
+	}
+	// same as LRU: atime runner first, then the greater of atime and mtime
+	usetime, mtime, _ := ios.GetAmTimes(osfi)
+	if atimeResponse := <-getatimerunner().Atime(fqn, cw.atimeRespCh); atimeResponse.Ok {
+		usetime = atimeResponse.AccessTime
+	} else if mtime.After(usetime) {
+		usetime = mtime
+	}
+	size := osfi.Size()
+	cw.ObjCount++
+	cw.ObjSize += size
+	if usetime.Before(cw.coldTime) {
+		cw.ColdCount++
+		cw.ColdSize += size
+	}
+	if usetime.Before(cw.evictTime) {
+		cw.EvictCount++
+		cw.EvictSize += size
+	}
+	return nil
+}
+
+//
+// proxy
+//
+
+// GET /v1/buckets/bucket-name?what=colddata[&days=N]
+func (p *proxyrunner) getColdData(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	days, errstr := coldDataDays(query)
+	if errstr != "" {
+		p.invalmsghdlr(w, r, errstr)
+		return
+	}
+	bucketmd := p.bmdowner.get()
+	query.Set(cmn.URLParamLocal, strconv.FormatBool(bucketmd.IsLocal(bucket)))
+	query.Set(cmn.URLParamDays, strconv.Itoa(days))
+
+	smap := p.smapowner.get()
+	results := p.broadcastTargets(
+		cmn.URLPath(cmn.Version, cmn.Buckets, bucket),
+		query,
+		http.MethodGet,
+		nil, // message
+		smap,
+		ctx.config.Timeout.DefaultLong,
+	)
+	summary := &cmn.BucketColdSummary{
+		Bucket:  bucket,
+		Days:    days,
+		Updated: time.Now(),
+		Targets: make(map[string]*cmn.ColdDataStats, smap.CountTargets()),
+	}
+	for res := range results {
+		if res.err != nil {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Failed to get cold data summary from %s: %s", res.si.DaemonID, res.errstr))
+			return
+		}
+		tsummary := &cmn.BucketColdSummary{}
+		if err := jsoniter.Unmarshal(res.outjson, tsummary); err != nil {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Failed to unmarshal cold data summary from %s, err: %v", res.si.DaemonID, err))
+			return
+		}
+		addColdDataStats(&summary.ColdDataStats, &tsummary.ColdDataStats)
+		summary.Targets[res.si.DaemonID] = &tsummary.ColdDataStats
+		if tsummary.Updated.Before(summary.Updated) {
+			summary.Updated = tsummary.Updated
+		}
+	}
+	jsbytes, err := jsoniter.Marshal(summary)
+	cmn.Assert(err == nil, err)
+	p.writeJSON(w, r, jsbytes, "getcolddata")
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/memsys"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Parallel cold GET: an object larger than cold_get.part_size is downloaded from the Cloud
+// with up to cold_get.concurrency ranged GETs in parallel. Each part is received into
+// memory (SGL), and the parts are then streamed, in order, through the regular receive()
+// path - checksum validation and the resulting workfile are the same as with a single
+// stream. Memory is bounded by (concurrency + 1) * part_size per object.
+//
+// ================================ Summary ===============================================
+
+type (
+	// rangeGetter returns the object's [off, off+length) range; implemented by the Cloud providers
+	rangeGetter func(off, length int64) (io.ReadCloser, error)
+
+	coldPart struct {
+		sgl *memsys.SGL
+		err error
+	}
+	// partsReader reads the parts, in order, as they get downloaded
+	partsReader struct {
+		parts  chan chan *coldPart
+		stopCh chan struct{}
+		cur    *memsys.SGL
+		err    error
+	}
+)
+
+// parallelColdGet returns true if the object of a given size is to be downloaded in parts
+func parallelColdGet(size int64) bool {
+	cfg := &ctx.config.ColdGet
+	return cfg.Concurrency > 1 && cfg.PartSize > 0 && size > cfg.PartSize
+}
+
+func newPartsReader(size, partSize int64, concurrency int, get rangeGetter) *partsReader {
+	r := &partsReader{
+		parts:  make(chan chan *coldPart, concurrency-1), // plus the one being read
+		stopCh: make(chan struct{}),
+	}
+	go func() {
+		defer close(r.parts)
+		for off := int64(0); off < size; off += partSize {
+			length := cmn.MinI64(partSize, size-off)
+			partCh := make(chan *coldPart, 1)
+			select {
+			case r.parts <- partCh:
+			case <-r.stopCh:
+				return
+			}
+			go func(off, length int64) {
+				partCh <- getPart(off, length, get)
+			}(off, length)
+		}
+	}()
+	return r
+}
+
+func getPart(off, length int64, get rangeGetter) *coldPart {
+	rc, err := get(off, length)
+	if err != nil {
+		return &coldPart{err: err}
+	}
+	sgl := gmem2.NewSGL(length)
+	n, err := io.Copy(sgl, rc)
+	rc.Close()
+	if err == nil && n != length {
+		err = fmt.Errorf("range [%d, %d): received %d bytes", off, off+length, n)
+	}
+	if err != nil {
+		sgl.Free()
+		return &coldPart{err: err}
+	}
+	return &coldPart{sgl: sgl}
+}
+
+func (r *partsReader) Read(b []byte) (n int, err error) {
+	for {
+		if r.cur != nil {
+			n, err = r.cur.Read(b)
+			if err != io.EOF {
+				return
+			}
+			r.cur.Free()
+			r.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+		partCh, ok := <-r.parts
+		if !ok {
+			return 0, io.EOF
+		}
+		part := <-partCh
+		if part.err != nil {
+			r.err = part.err
+			return 0, r.err
+		}
+		r.cur = part.sgl
+	}
+}
+
+// Close stops downloading and frees the parts, including those still in flight
+func (r *partsReader) Close() error {
+	close(r.stopCh)
+	if r.cur != nil {
+		r.cur.Free()
+		r.cur = nil
+	}
+	go func() {
+		for partCh := range r.parts {
+			if part := <-partCh; part.sgl != nil {
+				part.sgl.Free()
+			}
+		}
+	}()
+	return nil
+}
+
+// receiveParallel is called by the Cloud providers' getobj() in place of receive()
+func (t *targetrunner) receiveParallel(fqn, bucket, objname, omd5 string, ohobj cksumvalue, size int64,
+	get rangeGetter) (nhobj cksumvalue, errstr string) {
+	var (
+		cfg     = &ctx.config.ColdGet
+		started = time.Now()
+		reader  = newPartsReader(size, cfg.PartSize, cfg.Concurrency, get)
+	)
+	_, nhobj, _, errstr = t.receive(fqn, objname, omd5, ohobj, reader)
+	reader.Close()
+	if errstr != "" {
+		return
+	}
+	elapsed := time.Since(started)
+	t.statsif.AddMany(stats.NamedVal64{Name: stats.GetColdParallelCount, Val: 1},
+		stats.NamedVal64{Name: stats.GetColdParallelSize, Val: size},
+		stats.NamedVal64{Name: stats.GetColdParallelLatency, Val: int64(elapsed)})
+	if glog.V(3) {
+		glog.Infof("Parallel cold GET %s/%s: %s in %v (%s/s, %d streams)", bucket, objname, cmn.B2S(size, 1),
+			elapsed, cmn.B2S(int64(float64(size)/elapsed.Seconds()), 1), cfg.Concurrency)
+	}
+	return
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/memsys"
+)
+
+func TestPartsReader(t *testing.T) {
+	if gmem2 == nil {
+		gmem2 = &memsys.Mem2{Name: "coldparallel"}
+		_ = gmem2.Init(false /* ignore init-time errors */)
+	}
+	data := make([]byte, 1000)
+	rand.Read(data)
+
+	var inflight, maxInflight int32
+	get := func(off, length int64) (io.ReadCloser, error) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInflight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+				break
+			}
+		}
+		// later parts complete first
+		time.Sleep(time.Duration(len(data)-int(off)) * time.Microsecond)
+		return ioutil.NopCloser(bytes.NewReader(data[off : off+length])), nil
+	}
+	for _, partSize := range []int64{1, 7, 100, 999, 1000, 4096} {
+		r := newPartsReader(int64(len(data)), partSize, 4, get)
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("part size %d: unexpected error: %v", partSize, err)
+		}
+		if !bytes.Equal(b, data) {
+			t.Errorf("part size %d: assembled object differs from the original", partSize)
+		}
+	}
+	if maxInflight > 4 {
+		t.Errorf("Expected at most %d parallel range GETs, got %d", 4, maxInflight)
+	}
+
+	// failed or short ranges fail the entire object
+	errRange := errors.New("range failed")
+	for _, get := range []rangeGetter{
+		func(off, length int64) (io.ReadCloser, error) {
+			if off >= 500 {
+				return nil, errRange
+			}
+			return ioutil.NopCloser(bytes.NewReader(data[off : off+length])), nil
+		},
+		func(off, length int64) (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data[off : off+length/2])), nil
+		},
+	} {
+		r := newPartsReader(int64(len(data)), 100, 3, get)
+		if _, err := ioutil.ReadAll(r); err == nil {
+			t.Errorf("Expected an error reading the parts")
+		}
+		r.Close()
+	}
+}
@@ -0,0 +1,338 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/ios"
+	"github.com/NVIDIA/dfcpub/stats"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ================================ Summary ===============================================
+//
+// Compression at rest: every compression.run_time the target runs the ActCompress xaction
+// that walks all buckets and compresses (zstd) the objects that have not been accessed
+// within compression.cold_time - access times are determined the same way LRU does. An
+// object is compressed into a workfile that then atomically replaces it; the object's
+// checksum and version stay the same (they are of the original content), its access and
+// modification times are preserved, and the original size is stored in the xattr
+// (cmn.XattrCompressed) that marks the object compressed. Objects that do not compress
+// are marked as such, and are not tried again until overwritten.
+//
+// Compressed objects are decompressed on the fly by GET (and by all other readers of the
+// objects' content: checksum validation, replication, rebalance, select), and HEAD reports
+// their original size. With compression.decompress_hot, a GET of a compressed object also
+// has it decompressed back in the background - the object is no longer cold.
+//
+// The xaction is skipped (and stops) when rebalance is running, and it is throttled in
+// accordance with the disk utilization. Stats: compress.n, decompress.n, and the disk
+// space saved (net of the decompressions) - compress.saved.size.
+//
+// ================================ Summary ===============================================
+
+const compressMinSize = 4 * cmn.KiB // smaller objects are not worth it
+
+var errCompressRebalance = errors.New("rebalance is running")
+
+// zstdReader decompresses the object's file
+type zstdReader struct {
+	*zstd.Decoder
+	file *os.File
+}
+
+func (r *zstdReader) Close() error {
+	r.Decoder.Close()
+	return r.file.Close()
+}
+
+// objCompressed returns the original size of the compressed object, or false if the
+// object is not compressed
+func objCompressed(fqn string) (size int64, ok bool) {
+	b, errstr := Getxattr(fqn, cmn.XattrCompressed)
+	if errstr != "" || !strings.HasPrefix(string(b), cmn.CompressedZstd+":") {
+		return
+	}
+	size, err := strconv.ParseInt(string(b[len(cmn.CompressedZstd)+1:]), 10, 64)
+	return size, err == nil
+}
+
+// openObject opens the object for reading its (original) content
+func openObject(fqn string) (io.ReadCloser, error) {
+	file, err := os.Open(fqn)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := objCompressed(fqn); !ok {
+		return file, nil
+	}
+	return newZstdReader(file)
+}
+
+func newZstdReader(file *os.File) (*zstdReader, error) {
+	dec, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &zstdReader{Decoder: dec, file: file}, nil
+}
+
+// RunCompress compresses the cold objects of all buckets on all available mountpaths;
+// it is called by housekeeping and does nothing if the previous run is in progress
+func (t *targetrunner) RunCompress() {
+	if t.IsRebalancing() {
+		glog.Infoln("Compression: rebalance is running - skipping")
+		return
+	}
+	xcomp := t.xactinp.renewCompress(t)
+	if xcomp == nil {
+		return
+	}
+	var (
+		n, saved int64
+		coldTime = time.Now().Add(-ctx.config.Compression.ColdTime)
+	)
+	glog.Infof("Compression: %s started: cold-time %v", xcomp, ctx.config.Compression.ColdTime)
+	opts := &fs.WalkOpts{
+		Skip:  skipNonProcessable,
+		Abort: xcomp.ChanAbort(),
+		Throttler: func(mpathInfo *fs.MountpathInfo) fs.Throttler {
+			return &cluster.Throttle{
+				Riostat:      getiostatrunner(),
+				CapUsedHigh:  &ctx.config.LRU.HighWM,
+				DiskUtilLow:  &ctx.config.Xaction.DiskUtilLowWM,
+				DiskUtilHigh: &ctx.config.Xaction.DiskUtilHighWM,
+				Period:       &ctx.config.Periodic.StatsTime,
+				Path:         mpathInfo.Path,
+				FS:           mpathInfo.FileSystem,
+				Flag:         cluster.OnDiskUtil}
+		},
+	}
+	err := fs.Mountpaths.WalkObjects(opts, func(entry *fs.WalkEntry) error {
+		if t.IsRebalancing() {
+			return errCompressRebalance
+		}
+		delta, err := t.compressObj(entry, coldTime)
+		if err != nil {
+			glog.Errorf("Failed to compress %s, err: %v", entry.FQN, err)
+			t.fshc(err, entry.FQN)
+			return nil
+		}
+		if delta > 0 {
+			atomic.AddInt64(&n, 1)
+			atomic.AddInt64(&saved, delta)
+		}
+		return nil
+	})
+	if err != nil {
+		glog.Warningf("Compression: %s stopped, err: %v", xcomp, err)
+	}
+	xcomp.EndTime(time.Now())
+	glog.Infof("Compression: %s: compressed %d objects, saved %s", xcomp, n, cmn.B2S(saved, 1))
+	t.xactinp.del(xcomp.ID())
+}
+
+// compressObj compresses the object if it is cold and compressible, and returns the disk
+// space saved
+func (t *targetrunner) compressObj(entry *fs.WalkEntry, coldTime time.Time) (saved int64, err error) {
+	fqn, size := entry.FQN, entry.FileInfo.Size()
+	if size < compressMinSize || t.asyncCksum.pending(fqn) {
+		return
+	}
+	if b, errstr := Getxattr(fqn, cmn.XattrCompressed); errstr != "" || len(b) != 0 {
+		return
+	}
+	// same as LRU: atime runner first, then the greater of atime and mtime
+	atime, mtime, _ := ios.GetAmTimes(entry.FileInfo)
+	usetime := atime
+	if atimeResponse := <-getatimerunner().Atime(fqn); atimeResponse.Ok {
+		usetime = atimeResponse.AccessTime
+	} else if mtime.After(usetime) {
+		usetime = mtime
+	}
+	if usetime.After(coldTime) {
+		return
+	}
+
+	uname := cluster.Uname(entry.Bucket, entry.Objname)
+	workfqn := cluster.GenContentFQN(fqn, cluster.DefaultWorkfileType)
+	t.rtnamemap.Lock(uname, false)
+	csize, err := compressFile(fqn, workfqn)
+	if err == nil && csize >= size {
+		errstr := Setxattr(fqn, cmn.XattrCompressed, []byte(cmn.CompressedNone))
+		if errstr != "" {
+			err = errors.New(errstr)
+		}
+		os.Remove(workfqn)
+		t.rtnamemap.Unlock(uname, false)
+		return
+	}
+	if err == nil {
+		err = copyObjXattrs(fqn, workfqn)
+	}
+	if err == nil {
+		value := cmn.CompressedZstd + ":" + strconv.FormatInt(size, 10)
+		if errstr := Setxattr(workfqn, cmn.XattrCompressed, []byte(value)); errstr != "" {
+			err = errors.New(errstr)
+		}
+	}
+	t.rtnamemap.Unlock(uname, false)
+	if err != nil {
+		os.Remove(workfqn)
+		return
+	}
+
+	// commit unless the object has changed in the meantime
+	t.rtnamemap.Lock(uname, true)
+	defer t.rtnamemap.Unlock(uname, true)
+	if fi, errstat := os.Stat(fqn); errstat != nil || inode(fi) != inode(entry.FileInfo) ||
+		!fi.ModTime().Equal(entry.FileInfo.ModTime()) || fi.Size() != size {
+		os.Remove(workfqn)
+		return
+	}
+	t.fdCache.invalidate(fqn)
+	if _, err = replaceObj(workfqn, fqn, nil); err != nil {
+		os.Remove(workfqn)
+		return
+	}
+	if errt := os.Chtimes(fqn, atime, mtime); errt != nil { // remains cold
+		glog.Errorf("Failed to restore %s times, err: %v", fqn, errt)
+	}
+	saved = size - csize
+	t.statsif.AddMany(stats.NamedVal64{Name: stats.CompressCount, Val: 1}, stats.NamedVal64{Name: stats.CompressSavedSize, Val: saved})
+	if glog.V(4) {
+		glog.Infof("Compressed %s: %s => %s", fqn, cmn.B2S(size, 1), cmn.B2S(csize, 1))
+	}
+	return saved, nil
+}
+
+// decompressObj decompresses the object back - upon its GET, with compression.decompress_hot
+func (t *targetrunner) decompressObj(bucket, objname, fqn string) {
+	uname := cluster.Uname(bucket, objname)
+	t.rtnamemap.Lock(uname, true)
+	defer t.rtnamemap.Unlock(uname, true)
+
+	fi, err := os.Stat(fqn)
+	if err != nil {
+		return
+	}
+	size, ok := objCompressed(fqn)
+	if !ok {
+		return // decompressed by another GET
+	}
+	workfqn := cluster.GenContentFQN(fqn, cluster.DefaultWorkfileType)
+	if err = decompressFile(fqn, workfqn, size); err == nil {
+		err = copyObjXattrs(fqn, workfqn)
+	}
+	if err == nil {
+		t.fdCache.invalidate(fqn)
+		_, err = replaceObj(workfqn, fqn, nil)
+	}
+	if err != nil {
+		glog.Errorf("Failed to decompress %s, err: %v", fqn, err)
+		os.Remove(workfqn)
+		t.fshc(err, fqn)
+		return
+	}
+	_, mtime, _ := ios.GetAmTimes(fi)
+	if err = os.Chtimes(fqn, time.Now(), mtime); err != nil {
+		glog.Errorf("Failed to restore %s mtime, err: %v", fqn, err)
+	}
+	t.statsif.AddMany(stats.NamedVal64{Name: stats.DecompressCount, Val: 1},
+		stats.NamedVal64{Name: stats.CompressSavedSize, Val: fi.Size() - size})
+	if glog.V(4) {
+		glog.Infof("Decompressed %s (hot)", fqn)
+	}
+}
+
+// compressFile writes the compressed content of the file into the workfile
+func compressFile(fqn, workfqn string) (csize int64, err error) {
+	file, err := os.Open(fqn)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	work, err := cmn.CreateFile(workfqn)
+	if err != nil {
+		return
+	}
+	enc, err := zstd.NewWriter(work, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		work.Close()
+		return
+	}
+	buf, slab := gmem2.AllocFromSlab2(cmn.MiB)
+	_, err = io.CopyBuffer(enc, file, buf)
+	slab.Free(buf)
+	if errc := enc.Close(); err == nil {
+		err = errc
+	}
+	if errc := work.Close(); err == nil {
+		err = errc
+	}
+	if err != nil {
+		return
+	}
+	fi, err := os.Stat(workfqn)
+	if err != nil {
+		return
+	}
+	return fi.Size(), nil
+}
+
+// decompressFile writes the original content of the compressed file into the workfile
+func decompressFile(fqn, workfqn string, size int64) (err error) {
+	file, err := os.Open(fqn)
+	if err != nil {
+		return
+	}
+	zr, err := newZstdReader(file)
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+	work, err := cmn.CreateFile(workfqn)
+	if err != nil {
+		return
+	}
+	buf, slab := gmem2.AllocFromSlab2(size)
+	written, err := io.CopyBuffer(work, zr, buf)
+	slab.Free(buf)
+	if errc := work.Close(); err == nil {
+		err = errc
+	}
+	if err == nil && written != size {
+		err = fmt.Errorf("size mismatch: expected %d, got %d", size, written)
+	}
+	return
+}
+
+// copyObjXattrs copies the object's checksum, version, and expiration time
+func copyObjXattrs(fqn, workfqn string) error {
+	for _, name := range []string{cmn.XattrXXHashVal, cmn.XattrObjVersion, cmn.XattrExpires} {
+		b, errstr := Getxattr(fqn, name)
+		if errstr == "" && len(b) > 0 {
+			errstr = Setxattr(workfqn, name, b)
+		}
+		if errstr != "" {
+			return errors.New(errstr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/memsys"
+)
+
+func TestCompressFile(t *testing.T) {
+	if gmem2 == nil {
+		gmem2 = &memsys.Mem2{Name: "compress"}
+		_ = gmem2.Init(false /* ignore init-time errors */)
+	}
+	dir, err := ioutil.TempDir("", "compress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		fqn     = filepath.Join(dir, "obj")
+		workfqn = filepath.Join(dir, "obj.work")
+		content = bytes.Repeat([]byte("cold data at rest "), 4096)
+		size    = int64(len(content))
+	)
+	if err := ioutil.WriteFile(fqn, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if errstr := Setxattr(fqn, cmn.XattrObjVersion, []byte("1")); errstr != "" {
+		t.Skipf("xattrs not supported: %s", errstr)
+	}
+
+	csize, err := compressFile(fqn, workfqn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csize <= 0 || csize >= size {
+		t.Fatalf("expected %d bytes to compress, got %d", size, csize)
+	}
+	if err := copyObjXattrs(fqn, workfqn); err != nil {
+		t.Fatal(err)
+	}
+	Setxattr(workfqn, cmn.XattrCompressed, []byte(cmn.CompressedZstd+":"+strconv.FormatInt(size, 10)))
+	if err := os.Rename(workfqn, fqn); err != nil {
+		t.Fatal(err)
+	}
+	if osize, ok := objCompressed(fqn); !ok || osize != size {
+		t.Fatalf("expected compressed, original size %d, got %t, %d", size, ok, osize)
+	}
+	if b, _ := Getxattr(fqn, cmn.XattrObjVersion); string(b) != "1" {
+		t.Errorf("expected version 1, got %q", b)
+	}
+
+	// transparently decompressed
+	r, err := openObject(fqn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(b, content) {
+		t.Fatalf("expected the original content, got %d bytes, err %v", len(b), err)
+	}
+
+	// and back
+	if err := decompressFile(fqn, workfqn, size); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = ioutil.ReadFile(workfqn); err != nil || !bytes.Equal(b, content) {
+		t.Fatalf("expected the original content, got %d bytes, err %v", len(b), err)
+	}
+	if _, ok := objCompressed(workfqn); ok {
+		t.Error("expected the decompressed file not to be marked compressed")
+	}
+	if err := decompressFile(fqn, workfqn, size+1); err == nil {
+		t.Error("expected size mismatch")
+	}
+}
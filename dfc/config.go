@@ -8,12 +8,18 @@ package dfc
 import (
 	"flag"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
 	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+	"github.com/NVIDIA/dfcpub/stats/statsd"
 )
 
 // $CONFDIR/*
@@ -132,9 +138,121 @@ func validateconf() (err error) {
 	if int(ctx.config.Periodic.StatsTime/time.Second) <= 0 {
 		return fmt.Errorf("stats-time refresh period is too low (should be higher than 1 second")
 	}
+	if ctx.config.Periodic.StatsLatencyUnit, err = stats.ParseLatencyUnit(ctx.config.Periodic.StatsLatencyUnitStr); err != nil {
+		return fmt.Errorf("Bad stats_latency_unit, err: %v", err)
+	}
+	if ctx.config.Disk.ReservedSpace, ctx.config.Disk.ReservedSpacePct, err = parseReservedSpace(ctx.config.Disk.ReservedSpaceStr); err != nil {
+		return err
+	}
+	if ctx.config.Disk.ReservedSpaceMpath, err = parseReservedSpaceMpath(ctx.config.Disk.ReservedSpaceMpathStr); err != nil {
+		return err
+	}
+	if ctx.config.Disk.SyncGroupTime, err = parseSyncGroupTime(ctx.config.Disk.SyncGroupTimeStr); err != nil {
+		return err
+	}
+	if ctx.config.Disk.PruneDirsTime, err = parsePruneDirsTime(ctx.config.Disk.PruneDirsTimeStr); err != nil {
+		return err
+	}
 	if ctx.config.Periodic.RetrySyncTime, err = time.ParseDuration(ctx.config.Periodic.RetrySyncTimeStr); err != nil {
 		return fmt.Errorf("Bad retry_sync_time format %s, err: %v", ctx.config.Periodic.RetrySyncTimeStr, err)
 	}
+	if ctx.config.Periodic.IostatHistory, err = parseIostatHistory(ctx.config.Periodic.IostatHistoryStr); err != nil {
+		return err
+	}
+	if ctx.config.Periodic.StatsHistory, err = parseStatsHistory(ctx.config.Periodic.StatsHistoryStr); err != nil {
+		return err
+	}
+	if ctx.config.Periodic.StatsLogFull < 0 {
+		return fmt.Errorf("Bad stats_log_full %d: expecting the number of stats periods (0 - no compaction)", ctx.config.Periodic.StatsLogFull)
+	}
+	if ctx.config.Periodic.StatsLogLevel, err = stats.ParseStatsLogLevel(ctx.config.Periodic.StatsLogLevelStr); err != nil {
+		return fmt.Errorf("Bad stats_log_level, err: %v", err)
+	}
+	if err = stats.ValidateStatsLogFormat(ctx.config.Periodic.StatsLogFormat); err != nil {
+		return fmt.Errorf("Bad stats_log_format, err: %v", err)
+	}
+	if ctx.config.Periodic.StatsLogMaxSize < 0 {
+		return fmt.Errorf("Bad stats_log_max_size %d: expecting the size in bytes (0 - %d)", ctx.config.Periodic.StatsLogMaxSize, stats.DefaultStatsLogMaxSize)
+	}
+	if ctx.config.ColdGet.PartSize, err = parseColdGetPartSize(ctx.config.ColdGet.PartSizeStr); err != nil {
+		return err
+	}
+	if err = parseSLO(&ctx.config.SLO); err != nil {
+		return err
+	}
+	if ctx.config.ListCache.TTL, err = parseListCacheTTL(ctx.config.ListCache.TTLStr); err != nil {
+		return err
+	}
+	if err = parseMmapRead(&ctx.config.MmapRead); err != nil {
+		return err
+	}
+	if ctx.config.FDCache.Size < 0 {
+		return fmt.Errorf("Invalid fd_cache size %d: must be non-negative", ctx.config.FDCache.Size)
+	}
+	if ctx.config.FDCache.Size == 0 {
+		ctx.config.FDCache.Size = 1024
+	}
+	if err = parseMDCache(&ctx.config.MDCache); err != nil {
+		return err
+	}
+	if err = parseDirectIO(&ctx.config.DirectIO); err != nil {
+		return err
+	}
+	if ctx.config.AtimeJournal.SyncTime, err = parseAtimeJournalSyncTime(ctx.config.AtimeJournal.SyncTimeStr); err != nil {
+		return err
+	}
+	if ctx.config.Readahead.HotObjects < 0 {
+		return fmt.Errorf("Invalid rahhotobjects %d: must be non-negative", ctx.config.Readahead.HotObjects)
+	}
+	if ctx.config.AtimeFlush.Rate < 0 {
+		return fmt.Errorf("Invalid atime_flush rate %d: must be non-negative", ctx.config.AtimeFlush.Rate)
+	}
+	if ctx.config.QoS.IOPS < 0 || ctx.config.QoS.MBps < 0 {
+		return fmt.Errorf("Invalid qos budget (iops %d, mbps %d): must be non-negative",
+			ctx.config.QoS.IOPS, ctx.config.QoS.MBps)
+	}
+	if err = parseSMART(&ctx.config.SMART); err != nil {
+		return err
+	}
+	if err = parseCORS(&ctx.config.CORS); err != nil {
+		return err
+	}
+	if ctx.config.Presign.MaxExpiry, err = parsePresignMaxExpiry(ctx.config.Presign.MaxExpiryStr); err != nil {
+		return err
+	}
+	if err = parseStatsD(&ctx.config.StatsD); err != nil {
+		return err
+	}
+	if err = parseMetrics(&ctx.config.Metrics); err != nil {
+		return err
+	}
+	if err = parseIntraClient(&ctx.config.Net.HTTP.IntraClient); err != nil {
+		return err
+	}
+	if ctx.config.AtimeDrain.Timeout, err = parseAtimeDrainTimeout(ctx.config.AtimeDrain.TimeoutStr); err != nil {
+		return err
+	}
+	if err = parseEndpointLimits(&ctx.config.EndpointLimits); err != nil {
+		return err
+	}
+	if err = parseCompression(&ctx.config.Compression); err != nil {
+		return err
+	}
+	if err = parsePutDedup(&ctx.config.PutDedup); err != nil {
+		return err
+	}
+	if ctx.config.ObjTTL.RunTime, err = parseObjTTLRunTime(ctx.config.ObjTTL.RunTimeStr); err != nil {
+		return err
+	}
+	if err = validateDiscovery(&ctx.config.Proxy.Discovery); err != nil {
+		return err
+	}
+	if ctx.config.ListCache.MaxPages < 0 {
+		return fmt.Errorf("Invalid list_cache max_pages %d: must be non-negative", ctx.config.ListCache.MaxPages)
+	}
+	if ctx.config.ColdGet.Concurrency < 0 {
+		return fmt.Errorf("Invalid cold_get concurrency %d: must be non-negative", ctx.config.ColdGet.Concurrency)
+	}
 	if ctx.config.Timeout.Default, err = time.ParseDuration(ctx.config.Timeout.DefaultStr); err != nil {
 		return fmt.Errorf("Bad Timeout default format %s, err: %v", ctx.config.Timeout.DefaultStr, err)
 	}
@@ -150,19 +268,29 @@ func validateconf() (err error) {
 	if ctx.config.Rebalance.DestRetryTime, err = time.ParseDuration(ctx.config.Rebalance.DestRetryTimeStr); err != nil {
 		return fmt.Errorf("Bad dest_retry_time format %s, err: %v", ctx.config.Rebalance.DestRetryTimeStr, err)
 	}
+	if ctx.config.Rebalance.Order, err = parseRebOrder(ctx.config.Rebalance.Order); err != nil {
+		return err
+	}
 
 	hwm, lwm := ctx.config.LRU.HighWM, ctx.config.LRU.LowWM
 	if hwm <= 0 || lwm <= 0 || hwm < lwm || lwm > 100 || hwm > 100 {
 		return fmt.Errorf("Invalid LRU configuration %+v", ctx.config.LRU)
 	}
+	if ctx.config.LRU.HotAccessCount < 0 {
+		return fmt.Errorf("Invalid hot_access_count %d: must be non-negative", ctx.config.LRU.HotAccessCount)
+	}
+	if cwm := ctx.config.Disk.CriticalWM; cwm != 0 && (cwm <= hwm || cwm > 100) {
+		return fmt.Errorf("Invalid critical_wm %d: expecting 0 (disabled) or a value in the range (highwm %d, 100]", cwm, hwm)
+	}
 
 	diskUtilHWM, diskUtilLWM := ctx.config.Xaction.DiskUtilHighWM, ctx.config.Xaction.DiskUtilLowWM
 	if diskUtilHWM <= 0 || diskUtilLWM <= 0 || diskUtilHWM <= diskUtilLWM || diskUtilLWM > 100 || diskUtilHWM > 100 {
 		return fmt.Errorf("Invalid Xaction configuration %+v", ctx.config.Xaction)
 	}
 
-	if ctx.config.Cksum.Checksum != cmn.ChecksumXXHash && ctx.config.Cksum.Checksum != cmn.ChecksumNone {
-		return fmt.Errorf("Invalid checksum: %s - expecting %s or %s", ctx.config.Cksum.Checksum, cmn.ChecksumXXHash, cmn.ChecksumNone)
+	if !cmn.ValidCksumType(ctx.config.Cksum.Checksum) && ctx.config.Cksum.Checksum != cmn.ChecksumNone {
+		return fmt.Errorf("Invalid checksum: %s - expecting %s, %s, or %s", ctx.config.Cksum.Checksum,
+			cmn.ChecksumXXHash, cmn.ChecksumCRC32C, cmn.ChecksumNone)
 	}
 	if err := validateVersion(ctx.config.Ver.Versioning); err != nil {
 		return err
@@ -193,6 +321,13 @@ func validateconf() (err error) {
 		return fmt.Errorf("bad target keep alive interval %s", ctx.config.KeepaliveTracker.Target.IntervalStr)
 	}
 
+	if ctx.config.KeepaliveTracker.SuspectAfter, err = parseSuspectAfter(ctx.config.KeepaliveTracker.SuspectAfter); err != nil {
+		return err
+	}
+	if ctx.config.KeepaliveTracker.RebalanceGrace, err = parseRebalanceGrace(ctx.config.KeepaliveTracker.RebalanceGraceStr); err != nil {
+		return err
+	}
+
 	if !ValidKeepaliveType(ctx.config.KeepaliveTracker.Proxy.Name) {
 		return fmt.Errorf("bad proxy keepalive tracker type %s", ctx.config.KeepaliveTracker.Proxy.Name)
 	}
@@ -306,3 +441,474 @@ func ipv4ListsOverlap(alist, blist string) (overlap bool, addr string) {
 	}
 	return
 }
+
+// parseReservedSpace parses disk_config.reserved_space: either "N%" or size in bytes (e.g. "10GB")
+func parseReservedSpace(s string) (bytes, pct int64, err error) {
+	if strings.HasSuffix(s, "%") {
+		if pct, err = strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(s, "%")), 10, 64); err != nil || pct < 0 || pct >= 100 {
+			return 0, 0, fmt.Errorf("Invalid reserved_space %q: expecting percentage in the range [0, 100)", s)
+		}
+		return
+	}
+	if bytes, err = cmn.S2B(s); err != nil || bytes < 0 {
+		return 0, 0, fmt.Errorf("Invalid reserved_space %q: expecting size (e.g. 10GB) or percentage (e.g. 5%%)", s)
+	}
+	return
+}
+
+// parseReservedSpaceMpath parses disk_config.reserved_space_mpath: mountpath => reserved space
+func parseReservedSpaceMpath(kvs cmn.SimpleKVs) (map[string]cmn.ReservedSpace, error) {
+	if len(kvs) == 0 {
+		return nil, nil
+	}
+	reserved := make(map[string]cmn.ReservedSpace, len(kvs))
+	for mpath, s := range kvs {
+		if !filepath.IsAbs(mpath) {
+			return nil, fmt.Errorf("Invalid reserved_space_mpath: mountpath %q is not an absolute path", mpath)
+		}
+		bytes, pct, err := parseReservedSpace(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", mpath, err)
+		}
+		reserved[filepath.Clean(mpath)] = cmn.ReservedSpace{Bytes: bytes, Pct: pct}
+	}
+	return reserved, nil
+}
+
+// parseSuspectAfter validates keepalivetracker.suspect_after; 0 (not configured) means 1
+func parseSuspectAfter(n int) (int, error) {
+	if n < 0 {
+		return 0, fmt.Errorf("Invalid keepalive suspect_after %d: must be non-negative", n)
+	}
+	if n == 0 {
+		n = 1
+	}
+	return n, nil
+}
+
+// parseRebalanceGrace parses keepalivetracker.rebalance_grace; empty string means no grace period
+func parseRebalanceGrace(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Bad keepalive rebalance_grace format %s, err %v", s, err)
+	}
+	return d, nil
+}
+
+// parseIostatHistory validates periodic.iostat_history; "" or 0 disables the history
+func parseIostatHistory(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Bad iostat_history format %s, err %v", s, err)
+	}
+	return d, nil
+}
+
+// parseColdGetPartSize validates cold_get.part_size; "" means 0 (parallel cold GET disabled)
+func parseColdGetPartSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	size, err := cmn.S2B(s)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("Invalid cold_get part_size %q: expecting size, e.g. 64MB", s)
+	}
+	return size, nil
+}
+
+// parseSyncGroupTime validates disk_config.sync_group_time; "" means 0 (no batching delay)
+func parseSyncGroupTime(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 || d > time.Second {
+		return 0, fmt.Errorf("Bad sync_group_time %s: expecting duration in the range [0, 1s], err %v", s, err)
+	}
+	return d, nil
+}
+
+// parseSLO validates the slo section: the compliance window ("" means 24h) and the objectives
+func parseSLO(conf *cmn.SLOConf) error {
+	conf.Window = 24 * time.Hour
+	if conf.WindowStr != "" {
+		d, err := time.ParseDuration(conf.WindowStr)
+		if err != nil || d < time.Hour || d > 30*24*time.Hour {
+			return fmt.Errorf("Bad slo window %s: expecting duration in the range [1h, 720h], err %v", conf.WindowStr, err)
+		}
+		conf.Window = d
+	}
+	names := make(map[string]bool, len(conf.Objectives))
+	for i := range conf.Objectives {
+		obj := &conf.Objectives[i]
+		if obj.Name == "" || names[obj.Name] {
+			return fmt.Errorf("Invalid slo objective #%d: name must be unique and non-empty", i)
+		}
+		names[obj.Name] = true
+		if obj.Stat == "" {
+			return fmt.Errorf("Invalid slo objective %q: stat is required", obj.Name)
+		}
+		if obj.Target <= 0 || obj.Target >= 1 {
+			return fmt.Errorf("Invalid slo objective %q: target %v must be in the range (0, 1)", obj.Name, obj.Target)
+		}
+		switch obj.Kind {
+		case cmn.SLOKindLatency:
+			d, err := time.ParseDuration(obj.ThresholdStr)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("Invalid slo objective %q: bad threshold %q, err %v", obj.Name, obj.ThresholdStr, err)
+			}
+			obj.Threshold = d
+		case cmn.SLOKindErrorRate:
+			if obj.ErrStat == "" {
+				return fmt.Errorf("Invalid slo objective %q: err_stat is required", obj.Name)
+			}
+		default:
+			return fmt.Errorf("Invalid slo objective %q: kind %q (expecting %q or %q)",
+				obj.Name, obj.Kind, cmn.SLOKindLatency, cmn.SLOKindErrorRate)
+		}
+	}
+	return nil
+}
+
+// parsePruneDirsTime validates disk_config.prune_dirs_time; "" means 0 (no pruning)
+func parsePruneDirsTime(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Bad prune_dirs_time format %s, err %v", s, err)
+	}
+	return d, nil
+}
+
+// parsePutDedup validates put_dedup; ttl "" means 0 (idempotency keys are ignored)
+func parsePutDedup(conf *cmn.PutDedupConf) (err error) {
+	if conf.TTL, err = parsePutDedupTTL(conf.TTLStr); err != nil {
+		return
+	}
+	if conf.MaxKeys < 0 {
+		return fmt.Errorf("Invalid put_dedup max_keys %d: must be non-negative", conf.MaxKeys)
+	}
+	return nil
+}
+
+func parsePutDedupTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Bad put_dedup ttl format %s, err %v", s, err)
+	}
+	return d, nil
+}
+
+// parseListCacheTTL validates list_cache.ttl; "" means 0 (Cloud bucket lists are not cached)
+func parseListCacheTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Bad list_cache ttl format %s, err %v", s, err)
+	}
+	return d, nil
+}
+
+// parseMmapRead validates the mmap_read section; max_object_size defaults to 1MB and
+// cache_size - to 256MB
+func parseMmapRead(conf *cmn.MmapReadConf) (err error) {
+	conf.MaxObjSize, conf.CacheSize = cmn.MiB, 256*cmn.MiB
+	if conf.MaxObjSizeStr != "" {
+		if conf.MaxObjSize, err = cmn.S2B(conf.MaxObjSizeStr); err != nil || conf.MaxObjSize <= 0 {
+			return fmt.Errorf("Invalid mmap_read max_object_size %q: expecting size, e.g. 1MB", conf.MaxObjSizeStr)
+		}
+	}
+	if conf.CacheSizeStr != "" {
+		if conf.CacheSize, err = cmn.S2B(conf.CacheSizeStr); err != nil || conf.CacheSize < conf.MaxObjSize {
+			return fmt.Errorf("Invalid mmap_read cache_size %q: expecting size no less than max_object_size", conf.CacheSizeStr)
+		}
+	}
+	return nil
+}
+
+// parseDirectIO validates direct_io; min_object_size defaults to 64MB
+func parseDirectIO(conf *cmn.DirectIOConf) (err error) {
+	conf.MinObjSize = 64 * cmn.MiB
+	if conf.MinObjSizeStr != "" {
+		if conf.MinObjSize, err = cmn.S2B(conf.MinObjSizeStr); err != nil || conf.MinObjSize <= 0 {
+			return fmt.Errorf("Invalid direct_io min_object_size %q: expecting size, e.g. 64MB", conf.MinObjSizeStr)
+		}
+	}
+	return nil
+}
+
+// parseAtimeJournalSyncTime validates atime_journal.sync_time; "" means 1s
+func parseAtimeJournalSyncTime(s string) (time.Duration, error) {
+	if s == "" {
+		return time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("Invalid atime_journal sync_time %q: expecting positive duration, e.g. 1s", s)
+	}
+	return d, nil
+}
+
+// parseAtimeDrainTimeout validates atime_drain.timeout; "" means 1m
+func parseAtimeDrainTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return time.Minute, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("Invalid atime_drain timeout %q: expecting positive duration, e.g. 1m", s)
+	}
+	return d, nil
+}
+
+// parseSMARTInterval validates smart.interval; "" means 10m
+func parseSMARTInterval(s string) (time.Duration, error) {
+	if s == "" {
+		return 10 * time.Minute, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf("Invalid smart interval %q: expecting duration of at least 1s, e.g. 10m", s)
+	}
+	return d, nil
+}
+
+func parseSMART(conf *cmn.SMARTConf) (err error) {
+	if conf.Interval, err = parseSMARTInterval(conf.IntervalStr); err != nil {
+		return
+	}
+	if conf.ReallocatedLimit < 0 || conf.MediaErrorsLimit < 0 || conf.TemperatureLimit < 0 {
+		return fmt.Errorf("Invalid smart limits (reallocated_sectors %d, media_errors %d, temperature %d): must be non-negative",
+			conf.ReallocatedLimit, conf.MediaErrorsLimit, conf.TemperatureLimit)
+	}
+	return
+}
+
+// parseIntraClient validates netconfig.http.intra_client; dial_timeout defaults to 30s,
+// retry_delay - to 100ms
+func parseIntraClient(conf *cmn.IntraClientConf) (err error) {
+	if conf.IdleConnsPerPeer < 0 || conf.Retries < 0 {
+		return fmt.Errorf("Invalid intra_client (idle_conns_per_peer %d, retries %d): must be non-negative",
+			conf.IdleConnsPerPeer, conf.Retries)
+	}
+	for _, p := range []struct {
+		name string
+		s    string
+		d    *time.Duration
+		def  time.Duration
+	}{{"dial_timeout", conf.DialTimeoutStr, &conf.DialTimeout, 30 * time.Second},
+		{"retry_delay", conf.RetryDelayStr, &conf.RetryDelay, 100 * time.Millisecond}} {
+		if p.s == "" {
+			*p.d = p.def
+			continue
+		}
+		if *p.d, err = time.ParseDuration(p.s); err != nil || *p.d < 0 {
+			return fmt.Errorf("Invalid intra_client %s %q: expecting non-negative duration, e.g. %v", p.name, p.s, p.def)
+		}
+	}
+	return nil
+}
+
+// parseRebOrder validates rebalance_conf.order; empty string means RebOrderNone
+func parseRebOrder(s string) (string, error) {
+	switch s {
+	case "":
+		return cmn.RebOrderNone, nil
+	case cmn.RebOrderNone, cmn.RebOrderLargeFirst, cmn.RebOrderSmallFirst, cmn.RebOrderMixed:
+		return s, nil
+	default:
+		return "", fmt.Errorf("Invalid rebalance order %q: expecting %s, %s, %s, or %s",
+			s, cmn.RebOrderNone, cmn.RebOrderLargeFirst, cmn.RebOrderSmallFirst, cmn.RebOrderMixed)
+	}
+}
+
+// parseEndpointLimits validates endpoint_limits; queue_timeout defaults to 1m
+func parseEndpointLimits(conf *cmn.EndpointLimitsConf) (err error) {
+	if conf.List < 0 || conf.Stats < 0 || conf.Xaction < 0 {
+		return fmt.Errorf("Invalid endpoint_limits %+v: must be non-negative", *conf)
+	}
+	conf.QueueTimeout, err = parseEndpointQueueTimeout(conf.QueueTimeoutStr)
+	return
+}
+
+// parseEndpointQueueTimeout validates endpoint_limits.queue_timeout; "" means 1m
+func parseEndpointQueueTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return time.Minute, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("Invalid endpoint_limits queue_timeout %q: expecting positive duration, e.g. 1m", s)
+	}
+	return d, nil
+}
+
+// parseCompression validates the compression section; cold_time defaults to 30 days and
+// run_time - to 24 hours
+func parseCompression(conf *cmn.CompressionConf) (err error) {
+	conf.ColdTime, conf.RunTime = 30*24*time.Hour, 24*time.Hour
+	if conf.ColdTimeStr != "" {
+		if conf.ColdTime, err = time.ParseDuration(conf.ColdTimeStr); err != nil || conf.ColdTime <= 0 {
+			return fmt.Errorf("Invalid compression cold_time %q: expecting positive duration, e.g. 720h", conf.ColdTimeStr)
+		}
+	}
+	if conf.RunTimeStr != "" {
+		if conf.RunTime, err = time.ParseDuration(conf.RunTimeStr); err != nil || conf.RunTime <= 0 {
+			return fmt.Errorf("Invalid compression run_time %q: expecting positive duration, e.g. 24h", conf.RunTimeStr)
+		}
+	}
+	return nil
+}
+
+// parseMDCache validates md_cache; size defaults to 64K entries and snapshot_time to 10m
+func parseMDCache(conf *cmn.MDCacheConf) (err error) {
+	if conf.Size < 0 {
+		return fmt.Errorf("Invalid md_cache size %d: must be non-negative", conf.Size)
+	}
+	if conf.Size == 0 {
+		conf.Size = 64 * 1024
+	}
+	if conf.SnapshotTime, err = parseMDCacheSnapshotTime(conf.SnapshotTimeStr); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseMDCacheSnapshotTime validates md_cache.snapshot_time; "" means 10m, zero - save upon shutdown only
+func parseMDCacheSnapshotTime(s string) (time.Duration, error) {
+	if s == "" {
+		return 10 * time.Minute, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Invalid md_cache snapshot_time %q: expecting non-negative duration, e.g. 10m", s)
+	}
+	return d, nil
+}
+
+// parseObjTTLRunTime validates obj_ttl.run_time; "" means 1h
+func parseObjTTLRunTime(s string) (time.Duration, error) {
+	if s == "" {
+		return time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Invalid obj_ttl run_time %q: expecting non-negative duration, e.g. 1h", s)
+	}
+	return d, nil
+}
+
+// parseCORS validates the cors section; max_age "" means 10m
+func parseCORS(conf *cmn.CORSConf) (err error) {
+	for _, origin := range conf.AllowedOrigins {
+		if origin == "" || (origin != "*" && !strings.Contains(origin, "://")) {
+			return fmt.Errorf("Invalid cors origin %q: expecting \"*\" or scheme://host[:port], e.g. https://example.com", origin)
+		}
+	}
+	conf.MaxAge = 10 * time.Minute
+	if conf.MaxAgeStr != "" {
+		if conf.MaxAge, err = time.ParseDuration(conf.MaxAgeStr); err != nil || conf.MaxAge < 0 {
+			return fmt.Errorf("Invalid cors max_age %q: expecting non-negative duration, e.g. 10m", conf.MaxAgeStr)
+		}
+	}
+	return nil
+}
+
+// parsePresignMaxExpiry validates presign.max_expiry; "" means 24h
+func parsePresignMaxExpiry(s string) (time.Duration, error) {
+	if s == "" {
+		return 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf("Invalid presign max_expiry %q: expecting duration of at least 1s, e.g. 24h", s)
+	}
+	return d, nil
+}
+
+// parseStatsD validates the statsd section; address "" means localhost:8125, flush_interval
+// "" - 100ms, resolve_interval "" - 1m
+func parseStatsD(conf *cmn.StatsDConf) (err error) {
+	if conf.Address == "" {
+		conf.Address = "localhost:8125"
+	}
+	if _, port, err := net.SplitHostPort(conf.Address); err != nil || port == "" {
+		return fmt.Errorf("Invalid statsd address %q: expecting host:port, e.g. localhost:8125", conf.Address)
+	}
+	conf.FlushInterval = 100 * time.Millisecond
+	if conf.FlushIntervalStr != "" {
+		if conf.FlushInterval, err = time.ParseDuration(conf.FlushIntervalStr); err != nil || conf.FlushInterval < 0 {
+			return fmt.Errorf("Invalid statsd flush_interval %q: expecting non-negative duration, e.g. 100ms", conf.FlushIntervalStr)
+		}
+	}
+	if conf.MaxPacketSize < 0 || conf.MaxPacketSize > 65000 {
+		return fmt.Errorf("Invalid statsd max_packet_size %d: expecting 0 (default) to 65000 bytes", conf.MaxPacketSize)
+	}
+	switch conf.Tags {
+	case statsd.TagsNone, statsd.TagsDogStatsD, statsd.TagsInfluxDB:
+	default:
+		return fmt.Errorf("Invalid statsd tags %q: expecting %q, %q, or empty", conf.Tags, statsd.TagsDogStatsD, statsd.TagsInfluxDB)
+	}
+	conf.ResolveInterval = statsd.DefaultResolveInterval
+	if conf.ResolveIntervalStr != "" {
+		if conf.ResolveInterval, err = time.ParseDuration(conf.ResolveIntervalStr); err != nil || conf.ResolveInterval < time.Second {
+			return fmt.Errorf("Invalid statsd resolve_interval %q: expecting duration of at least 1s, e.g. 1m", conf.ResolveIntervalStr)
+		}
+	}
+	return nil
+}
+
+// parseMetrics validates the metrics section; sink "" means statsd; graphite address "" -
+// localhost:2003, flush_interval "" - 10s
+func parseMetrics(conf *cmn.MetricsConf) (err error) {
+	if conf.Sink == "" {
+		conf.Sink = stats.SinkStatsD
+	}
+	names := stats.SinkNames()
+	if i := sort.SearchStrings(names, conf.Sink); i == len(names) || names[i] != conf.Sink {
+		return fmt.Errorf("Invalid metrics sink %q: expecting one of: %s", conf.Sink, strings.Join(names, ", "))
+	}
+	graphite := &conf.Graphite
+	if graphite.Address == "" {
+		graphite.Address = "localhost:2003"
+	}
+	if _, port, err := net.SplitHostPort(graphite.Address); err != nil || port == "" {
+		return fmt.Errorf("Invalid metrics graphite address %q: expecting host:port, e.g. localhost:2003", graphite.Address)
+	}
+	graphite.FlushInterval = 10 * time.Second
+	if graphite.FlushIntervalStr != "" {
+		if graphite.FlushInterval, err = time.ParseDuration(graphite.FlushIntervalStr); err != nil || graphite.FlushInterval < time.Second {
+			return fmt.Errorf("Invalid metrics graphite flush_interval %q: expecting duration of at least 1s, e.g. 10s", graphite.FlushIntervalStr)
+		}
+	}
+	return nil
+}
+
+// validateDiscovery validates proxyconfig.discovery
+func validateDiscovery(conf *cmn.DiscoveryConf) error {
+	switch conf.Provider {
+	case "":
+		return nil
+	case cmn.DiscoveryDNSSRV, cmn.DiscoveryAWS, cmn.DiscoveryGCP:
+	default:
+		return fmt.Errorf("Invalid discovery provider %q: expecting one of: %q, %q, %q",
+			conf.Provider, cmn.DiscoveryDNSSRV, cmn.DiscoveryAWS, cmn.DiscoveryGCP)
+	}
+	if conf.Name == "" {
+		return fmt.Errorf("Invalid %s discovery: name is not defined", conf.Provider)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestParseReservedSpace(t *testing.T) {
+	tests := []struct {
+		s          string
+		bytes, pct int64
+		wantErr    bool
+	}{
+		{"", 0, 0, false}, // not configured
+		{"0", 0, 0, false},
+		{"10GB", 10 * cmn.GiB, 0, false},
+		{"512MB", 512 * cmn.MiB, 0, false},
+		{"4096", 4096, 0, false},
+		{"5%", 0, 5, false},
+		{" 5 %", 0, 5, false},
+		{"0%", 0, 0, false},
+		{"99%", 0, 99, false},
+
+		{"100%", 0, 0, true},
+		{"-1%", 0, 0, true},
+		{"%", 0, 0, true},
+		{"five%", 0, 0, true},
+		{"-10GB", 0, 0, true},
+		{"10XB", 0, 0, true},
+	}
+	for _, test := range tests {
+		bytes, pct, err := parseReservedSpace(test.s)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got %d bytes, %d%%", test.s, bytes, pct)
+			}
+			continue
+		}
+		if err != nil || bytes != test.bytes || pct != test.pct {
+			t.Errorf("%q: expected %d bytes, %d%%, got %d, %d%%, err: %v", test.s, test.bytes, test.pct, bytes, pct, err)
+		}
+	}
+}
+
+func TestReservedSpaceMpath(t *testing.T) {
+	reserved, err := parseReservedSpaceMpath(cmn.SimpleKVs{"/dfc/ssd/": "20GB", "/dfc/hdd": "2%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &cmn.DiskConf{ReservedSpacePct: 10, ReservedSpaceMpath: reserved}
+	const total = 1000 * cmn.GiB
+	for mpath, want := range map[string]uint64{"/dfc/ssd": 20 * cmn.GiB, "/dfc/hdd": 20 * cmn.GiB, "/dfc/other": 100 * cmn.GiB} {
+		if got := conf.ReservedBytes(mpath, total); got != want {
+			t.Errorf("%s: expected %d reserved, got %d", mpath, want, got)
+		}
+	}
+	for _, kvs := range []cmn.SimpleKVs{{"/dfc/ssd": "20XB"}, {"/dfc/ssd": "100%"}, {"dfc/ssd": "1GB"}} {
+		if _, err := parseReservedSpaceMpath(kvs); err == nil {
+			t.Errorf("%v: expected error", kvs)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ================================ Summary ===============================================
+//
+// CORS (Cross-Origin Resource Sharing) on the object endpoints, for the browser-based
+// clients - e.g., labeling tools that read and write objects via the presigned URLs (see
+// presign.go). With cors.allowed_origins configured, the proxies and the targets answer the
+// preflight requests (OPTIONS with Access-Control-Request-Method) and add the CORS headers
+// to the responses to the allowed origins. No credentials (cookies) are allowed.
+//
+// A browser follows the proxy's redirect to the target - another origin - with "Origin: null".
+// The proxy that redirects a request of an allowed origin adds the origin to the redirect URL,
+// signed with the presign secret (see presignSecret) together with the object's path and the
+// redirect time; the targets accept the null origin only with a valid signature of an origin
+// that is (still) allowed, and only for corsRedirectTTL. With no secret configured the null
+// origin is never accepted, and so the browsers cannot follow the redirects cross-origin.
+//
+// ================================ Summary ===============================================
+
+var (
+	corsMethods = strings.Join([]string{http.MethodGet, http.MethodHead, http.MethodPut}, ", ")
+	corsExposed = strings.Join([]string{
+		cmn.HeaderDFCChecksumType, cmn.HeaderDFCChecksumVal, cmn.HeaderDFCObjVersion, cmn.HeaderDFCObjExpires,
+		cmn.HeaderDFCRequestID, cmn.HeaderDFCNodeID, "Content-Length", "Location",
+	}, ", ")
+)
+
+// corsRedirectTTL is how long the signed origin of a redirect is valid
+const corsRedirectTTL = time.Minute
+
+// corsOrigin returns Access-Control-Allow-Origin of the response; empty if the request
+// is not a cross-origin one, or its origin is not allowed
+func corsOrigin(conf *cmn.CORSConf, r *http.Request) string {
+	origin := r.Header.Get("Origin")
+	if origin == "" || len(conf.AllowedOrigins) == 0 {
+		return ""
+	}
+	if origin == "null" {
+		if !corsVerifyRedirect(conf, r, time.Now()) {
+			return ""
+		}
+		return origin
+	}
+	if !corsAllowed(conf, origin) {
+		return ""
+	}
+	for _, allowed := range conf.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+	}
+	return origin
+}
+
+func corsAllowed(conf *cmn.CORSConf, origin string) bool {
+	for _, allowed := range conf.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func corsSignature(secret []byte, origin, path, utm string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(origin + "\n" + path + "\n" + utm))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// corsSignRedirect adds the request's origin, if allowed, and its signature to the query
+// of the proxy's redirect; utm is the redirect's URLParamUnixTime
+func corsSignRedirect(conf *cmn.CORSConf, r *http.Request, utm string, query url.Values) {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" || !corsAllowed(conf, origin) {
+		return
+	}
+	secret := presignSecret()
+	if secret == nil {
+		return
+	}
+	query.Add(cmn.URLParamOrigin, origin)
+	query.Add(cmn.URLParamOriginSig, corsSignature(secret, origin, r.URL.Path, utm))
+}
+
+// corsVerifyRedirect returns true if the request with "Origin: null" is a proxy's redirect
+// of a request of an allowed origin (see corsSignRedirect)
+func corsVerifyRedirect(conf *cmn.CORSConf, r *http.Request, now time.Time) bool {
+	var (
+		query  = r.URL.Query()
+		origin = query.Get(cmn.URLParamOrigin)
+		utm    = query.Get(cmn.URLParamUnixTime)
+		secret = presignSecret()
+	)
+	if origin == "" || secret == nil || !corsAllowed(conf, origin) {
+		return false
+	}
+	ts, err := strconv.ParseInt(utm, 10, 64)
+	if err != nil || now.Sub(time.Unix(0, ts)) > corsRedirectTTL {
+		return false
+	}
+	expected := corsSignature(secret, origin, r.URL.Path, utm)
+	return hmac.Equal([]byte(expected), []byte(query.Get(cmn.URLParamOriginSig)))
+}
+
+// withCORS adds the CORS headers to the responses to the allowed origins and answers
+// the preflight requests
+func withCORS(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf := &ctx.config.CORS
+		origin := corsOrigin(conf, r)
+		if origin == "" {
+			h(w, r)
+			return
+		}
+		hdr := w.Header()
+		hdr.Set("Access-Control-Allow-Origin", origin)
+		if origin != "*" {
+			hdr.Add("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			hdr.Set("Access-Control-Allow-Methods", corsMethods)
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				hdr.Set("Access-Control-Allow-Headers", headers)
+			}
+			hdr.Set("Access-Control-Max-Age", strconv.FormatInt(int64(conf.MaxAge/time.Second), 10))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		hdr.Set("Access-Control-Expose-Headers", corsExposed)
+		h(w, r)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
@@ -37,6 +38,7 @@ const (
 	xatime           = "atime"
 	xmetasyncer      = "metasyncer"
 	xfshc            = "fshc"
+	xsmart           = "smart"
 	xreadahead       = "readahead"
 	xreplication     = "replication"
 )
@@ -57,12 +59,69 @@ type (
 		rg     *rungroup
 	}
 
+	// rungroup runs all the daemon's runners and keeps track of their lifecycle states;
+	// the typed accessors (below) are set by dfcinit() and used via the global helpers,
+	// e.g. getiostatrunner()
 	rungroup struct {
 		runarr []cmn.Runner
-		runmap map[string]cmn.Runner // redundant, named
+		states []int32 // runState of the runarr[i]
 		errCh  chan error
 		stopCh chan error
+		// typed accessors
+		proxy           *proxyrunner
+		target          *targetrunner
+		mem2            *memsys.Mem2
+		proxystats      *stats.Prunner
+		storstats       *stats.Trunner
+		proxykeepalive  *proxyKeepaliveRunner
+		targetkeepalive *targetKeepaliveRunner
+		metasyncer      *metasyncer
+		replication     *replicationRunner
+		fshc            *health.FSHC
+		smart           *ios.SMARTRunner
+		iostat          iostatif
+		atime           atimeif
 	}
+	runState int32
+
+	// iostatif is implemented by ios.IostatRunner
+	iostatif interface {
+		ios.DiskUtilProvider
+		History(disk string, within time.Duration) cmn.DiskHistory
+		AddForeground(fqn string)
+		QoSThrottled(fs string) bool
+	}
+	// initReporter is implemented by the runners that initialize within Run() (proxy and
+	// target): such runner is starting until it calls back the given function
+	initReporter interface {
+		setOnInit(f func())
+	}
+	// atimeif is implemented by atime.Runner
+	atimeif interface {
+		Touch(fqn string, setTime ...time.Time)
+		TouchRelaxed(fqn string)
+		Atime(fqn string, customRespCh ...chan *atime.Response) (responseCh chan *atime.Response)
+		AtimeFallback(fqn string, customRespCh ...chan *atime.Response) (responseCh chan *atime.Response)
+		AtimeBatch(fqns []string) map[string]*atime.Response
+		HotObjects(mpath string, topN int) []atime.HotObject
+		AccessCount(fqn string) uint32
+	}
+)
+
+// runner lifecycle states
+const (
+	runStarting runState = iota // added to the rungroup, not running (initialized) yet
+	runRunning
+	runStopped // Run() returned
+)
+
+var runStateNames = []string{cmn.RunnerStarting, cmn.RunnerRunning, cmn.RunnerStopped}
+
+func (s runState) String() string { return runStateNames[s] }
+
+var (
+	_ iostatif = &ios.IostatRunner{}
+	_ atimeif  = &atime.Runner{}
 )
 
 // - selective disabling of a disk and/or network IO.
@@ -98,7 +157,7 @@ var (
 func (g *rungroup) add(r cmn.Runner, name string, config *cmn.Config) {
 	r.Setname(name)
 	g.runarr = append(g.runarr, r)
-	g.runmap[name] = r
+	g.states = append(g.states, int32(runStarting))
 	if config != nil {
 		rc := r.(cmn.Configured)
 		rc.Setconf(config)
@@ -112,8 +171,19 @@ func (g *rungroup) run() error {
 	g.errCh = make(chan error, len(g.runarr))
 	g.stopCh = make(chan error, 1)
 	for i, r := range g.runarr {
+		running := func(i int) func() {
+			return func() { atomic.CompareAndSwapInt32(&g.states[i], int32(runStarting), int32(runRunning)) }
+		}(i)
+		ir, reports := r.(initReporter)
+		if reports {
+			ir.setOnInit(running)
+		}
 		go func(i int, r cmn.Runner) {
+			if !reports {
+				running()
+			}
 			err := r.Run()
+			atomic.StoreInt32(&g.states[i], int32(runStopped))
 			glog.Warningf("Runner [%s] threw error [%v].", r.Getname(), err)
 			g.errCh <- err
 		}(i, r)
@@ -132,6 +202,20 @@ func (g *rungroup) run() error {
 	return err
 }
 
+// status returns the lifecycle states of all runners; the daemon is ready
+// when all of them are running
+func (g *rungroup) status() *cmn.DaemonRunners {
+	status := &cmn.DaemonRunners{Ready: true, Runners: make([]cmn.RunnerStatus, len(g.runarr))}
+	for i, r := range g.runarr {
+		state := runState(atomic.LoadInt32(&g.states[i]))
+		status.Runners[i] = cmn.RunnerStatus{Name: r.Getname(), State: state.String()}
+		if state != runRunning {
+			status.Ready = false
+		}
+	}
+	return status
+}
+
 func init() {
 	// CLI to override dfc JSON config
 	flag.StringVar(&clivars.role, "role", "", "role: proxy OR target")
@@ -203,31 +287,43 @@ func dfcinit() {
 	fs.Mountpaths = fs.NewMountedFS(ctx.config.LocalBuckets, ctx.config.CloudBuckets)
 	// NOTE: proxy and, respectively, target terminations are executed in the same
 	//       exact order as the initializations below
-	ctx.rg = &rungroup{
+	rg := &rungroup{
 		runarr: make([]cmn.Runner, 0, 8),
-		runmap: make(map[string]cmn.Runner, 8),
+		states: make([]int32, 0, 8),
 	}
+	ctx.rg = rg
 	if clivars.role == xproxy {
 		p := &proxyrunner{}
 		p.initSI()
-		ctx.rg.add(p, xproxy, nil)
+		p.initMetrics("dfcproxy")
+		rg.add(p, xproxy, nil)
+		rg.proxy = p
 		ps := &stats.Prunner{}
 		ps.Init()
-		ctx.rg.add(ps, xproxystats, &ctx.config)
-		ctx.rg.add(newProxyKeepaliveRunner(p), xproxykeepalive, nil)
-		ctx.rg.add(newmetasyncer(p), xmetasyncer, &ctx.config)
+		rg.add(ps, xproxystats, &ctx.config)
+		rg.proxystats = ps
+		rg.proxykeepalive = newProxyKeepaliveRunner(p)
+		rg.add(rg.proxykeepalive, xproxykeepalive, nil)
+		rg.metasyncer = newmetasyncer(p)
+		rg.add(rg.metasyncer, xmetasyncer, &ctx.config)
 	} else {
 		t := &targetrunner{}
 		t.initSI()
-		ctx.rg.add(t, xtarget, nil)
+		t.initMetrics("dfctarget")
+		rg.add(t, xtarget, nil)
+		rg.target = t
 		ts := &stats.Trunner{TargetRunner: t} // iostat below
 		ts.Init()
-		ctx.rg.add(ts, xstorstats, &ctx.config)
-		ctx.rg.add(newTargetKeepaliveRunner(t), xtargetkeepalive, nil)
-
-		// iostat is required: ensure that it is installed and its version is right
-		if err := ios.CheckIostatVersion(); err != nil {
-			glog.Exit(err)
+		rg.add(ts, xstorstats, &ctx.config)
+		rg.storstats = ts
+		rg.targetkeepalive = newTargetKeepaliveRunner(t)
+		rg.add(rg.targetkeepalive, xtargetkeepalive, nil)
+
+		// external iostat, if configured: ensure that it is installed and its version is right
+		if ctx.config.Periodic.IostatExternal {
+			if err := ios.CheckIostatVersion(); err != nil {
+				glog.Exit(err)
+			}
 		}
 
 		t.fsprg.init(t) // subgroup of the ctx.rg rungroup
@@ -235,8 +331,9 @@ func dfcinit() {
 		// system-wide gen-purpose memory manager and slab/SGL allocator
 		mem := &memsys.Mem2{MinPctTotal: 4, MinFree: cmn.GiB * 2} // free mem: try to maintain at least the min of these two
 		_ = mem.Init(false)                                       // don't ignore init-time errors
-		ctx.rg.add(mem, xmem, nil)                                // to periodically house-keep
-		gmem2 = getmem2()                                         // making it global; getmem2() can still be used
+		rg.add(mem, xmem, nil)                                    // to periodically house-keep
+		rg.mem2 = mem
+		gmem2 = getmem2() // making it global; getmem2() can still be used
 
 		// fs.Mountpaths must be inited prior to all runners that utilize all
 		// or run per filesystem(s); for mountpath definition, see fs/mountfs.go
@@ -256,17 +353,23 @@ func dfcinit() {
 		}
 
 		iostat := ios.NewIostatRunner(fs.Mountpaths)
-		ctx.rg.add(iostat, xiostat, &ctx.config)
+		rg.add(iostat, xiostat, &ctx.config)
+		rg.iostat = iostat
 		t.fsprg.add(iostat)
 		ts.Riostat = iostat
 
 		fshc := health.NewFSHC(fs.Mountpaths, gmem2)
-		ctx.rg.add(fshc, xfshc, &ctx.config)
+		rg.add(fshc, xfshc, &ctx.config)
+		rg.fshc = fshc
 		t.fsprg.add(fshc)
 
+		smart := ios.NewSMARTRunner(fs.Mountpaths, fshc)
+		rg.add(smart, xsmart, &ctx.config)
+		rg.smart = smart
+
 		if ctx.config.Readahead.Enabled {
 			readaheader := newReadaheader()
-			ctx.rg.add(readaheader, xreadahead, nil)
+			rg.add(readaheader, xreadahead, nil)
 			t.fsprg.add(readaheader)
 			t.readahead = readaheader
 		} else {
@@ -274,14 +377,20 @@ func dfcinit() {
 		}
 
 		replRunner := newReplicationRunner(t, fs.Mountpaths)
-		ctx.rg.add(replRunner, xreplication, nil)
+		rg.add(replRunner, xreplication, nil)
+		rg.replication = replRunner
 		t.fsprg.add(replRunner)
 
 		atime := atime.NewRunner(fs.Mountpaths, &ctx.config.LRU.AtimeCacheMax, iostat)
-		ctx.rg.add(atime, xatime, nil)
+		atime.Tracker = ts
+		atime.Journal = &ctx.config.AtimeJournal
+		atime.Drain = &ctx.config.AtimeDrain
+		atime.Flush = &ctx.config.AtimeFlush
+		rg.add(atime, xatime, nil)
+		rg.atime = atime
 		t.fsprg.add(atime)
 	}
-	ctx.rg.add(&sigrunner{}, xsignal, nil)
+	rg.add(&sigrunner{}, xsignal, nil)
 }
 
 // Run is the 'main' where everything gets started
@@ -311,85 +420,65 @@ m:
 //
 //==================
 func getproxystatsrunner() *stats.Prunner {
-	r := ctx.rg.runmap[xproxystats]
-	rr, ok := r.(*stats.Prunner)
-	cmn.Assert(ok)
-	return rr
+	cmn.Assert(ctx.rg.proxystats != nil)
+	return ctx.rg.proxystats
 }
 
 func getproxykeepalive() *proxyKeepaliveRunner {
-	r := ctx.rg.runmap[xproxykeepalive]
-	rr, ok := r.(*proxyKeepaliveRunner)
-	cmn.Assert(ok)
-	return rr
+	cmn.Assert(ctx.rg.proxykeepalive != nil)
+	return ctx.rg.proxykeepalive
 }
 
 func gettarget() *targetrunner {
-	r := ctx.rg.runmap[xtarget]
-	rr, ok := r.(*targetrunner)
-	cmn.Assert(ok)
-	return rr
+	cmn.Assert(ctx.rg.target != nil)
+	return ctx.rg.target
 }
 
 func getmem2() *memsys.Mem2 {
-	r := ctx.rg.runmap[xmem]
-	rr, ok := r.(*memsys.Mem2)
-	cmn.Assert(ok)
-	return rr
+	cmn.Assert(ctx.rg.mem2 != nil)
+	return ctx.rg.mem2
 }
 
 func gettargetkeepalive() *targetKeepaliveRunner {
-	r := ctx.rg.runmap[xtargetkeepalive]
-	rr, ok := r.(*targetKeepaliveRunner)
-	cmn.Assert(ok)
-	return rr
+	cmn.Assert(ctx.rg.targetkeepalive != nil)
+	return ctx.rg.targetkeepalive
 }
 
 func getreplicationrunner() *replicationRunner {
-	r := ctx.rg.runmap[xreplication]
-	rr, ok := r.(*replicationRunner)
-	cmn.Assert(ok)
-	return rr
+	cmn.Assert(ctx.rg.replication != nil)
+	return ctx.rg.replication
 }
 
 func getstorstatsrunner() *stats.Trunner {
-	r := ctx.rg.runmap[xstorstats]
-	rr, ok := r.(*stats.Trunner)
-	cmn.Assert(ok)
-	return rr
+	cmn.Assert(ctx.rg.storstats != nil)
+	return ctx.rg.storstats
 }
 
-func getiostatrunner() *ios.IostatRunner {
-	r := ctx.rg.runmap[xiostat]
-	rr, ok := r.(*ios.IostatRunner)
-	cmn.Assert(ok)
-	return rr
+func getiostatrunner() iostatif {
+	cmn.Assert(ctx.rg.iostat != nil)
+	return ctx.rg.iostat
 }
 
-func getatimerunner() *atime.Runner {
-	r := ctx.rg.runmap[xatime]
-	rr, ok := r.(*atime.Runner)
-	cmn.Assert(ok)
-	return rr
+func getatimerunner() atimeif {
+	cmn.Assert(ctx.rg.atime != nil)
+	return ctx.rg.atime
 }
 
 func getcloudif() cloudif {
-	r := ctx.rg.runmap[xtarget]
-	rr, ok := r.(*targetrunner)
-	cmn.Assert(ok)
-	return rr.cloudif
+	return gettarget().cloudif
 }
 
 func getmetasyncer() *metasyncer {
-	r := ctx.rg.runmap[xmetasyncer]
-	rr, ok := r.(*metasyncer)
-	cmn.Assert(ok)
-	return rr
+	cmn.Assert(ctx.rg.metasyncer != nil)
+	return ctx.rg.metasyncer
+}
+
+func getsmartrunner() *ios.SMARTRunner {
+	cmn.Assert(ctx.rg.smart != nil)
+	return ctx.rg.smart
 }
 
 func getfshealthchecker() *health.FSHC {
-	r := ctx.rg.runmap[xfshc]
-	rr, ok := r.(*health.FSHC)
-	cmn.Assert(ok)
-	return rr
+	cmn.Assert(ctx.rg.fshc != nil)
+	return ctx.rg.fshc
 }
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+type fakeRunner struct {
+	cmn.Named
+	stopCh chan error
+}
+
+func (r *fakeRunner) Run() error     { return <-r.stopCh }
+func (r *fakeRunner) Stop(err error) { r.stopCh <- err }
+
+// fakeInitRunner reports running once initCh is closed
+type fakeInitRunner struct {
+	fakeRunner
+	initCh chan struct{}
+	onInit func()
+}
+
+func (r *fakeInitRunner) setOnInit(f func()) { r.onInit = f }
+func (r *fakeInitRunner) Run() error {
+	select {
+	case <-r.initCh:
+		r.onInit()
+	case err := <-r.stopCh:
+		return err
+	}
+	return <-r.stopCh
+}
+
+func TestRungroupStatus(t *testing.T) {
+	var (
+		rg      = &rungroup{}
+		r1      = &fakeRunner{stopCh: make(chan error, 1)}
+		r2      = &fakeRunner{stopCh: make(chan error, 1)}
+		r3      = &fakeInitRunner{fakeRunner: fakeRunner{stopCh: make(chan error, 1)}, initCh: make(chan struct{})}
+		checkRg = func(ready bool, states ...string) {
+			status := rg.status()
+			if status.Ready != ready || len(status.Runners) != len(states) {
+				t.Fatalf("Expected ready=%t and %d runners, got %+v", ready, len(states), status)
+			}
+			for i, st := range status.Runners {
+				if st.State != states[i] {
+					t.Errorf("Expected runner %s to be %s, got %s", st.Name, states[i], st.State)
+				}
+			}
+		}
+	)
+	rg.add(r1, "r1", nil)
+	rg.add(r2, "r2", nil)
+	rg.add(r3, "r3", nil)
+	checkRg(false, cmn.RunnerStarting, cmn.RunnerStarting, cmn.RunnerStarting)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- rg.run() }()
+	for i := 0; i < 100 && rg.status().Runners[1].State != cmn.RunnerRunning; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// r3 is not initialized yet
+	time.Sleep(10 * time.Millisecond)
+	checkRg(false, cmn.RunnerRunning, cmn.RunnerRunning, cmn.RunnerStarting)
+
+	close(r3.initCh)
+	for i := 0; i < 100 && !rg.status().Ready; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	checkRg(true, cmn.RunnerRunning, cmn.RunnerRunning, cmn.RunnerRunning)
+
+	stopErr := errors.New("stop")
+	r1.stopCh <- stopErr
+	if err := <-errCh; err != stopErr {
+		t.Errorf("Expected rungroup to terminate with %v, got %v", stopErr, err)
+	}
+	checkRg(false, cmn.RunnerStopped, cmn.RunnerStopped, cmn.RunnerStopped)
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Stable target IDs. The objects are placed by the targets' IDs (HRW), and so a target that
+// restarts with a different IP address or port (which is common with k8s) must not come back
+// under a different ID - or else the cluster rebalances for no reason. Therefore:
+//
+// 1) the target persists its ID in $CONFDIR/daemonid upon the first start and reuses it
+//    thereafter (the DFCDAEMONID environment variable, when set, takes precedence);
+// 2) the primary proxy renews the cluster map when a known target registers from a new
+//    address - without rebalancing - unless the target at the old address is still alive
+//    and responds with a different identity, in which case the registration is rejected
+//    as a duplicate; a new target registering at the address of another (stale) target
+//    replaces the latter in the same cluster map version;
+// 3) a target that has lost its $CONFDIR and joined under a new ID is administratively
+//    made to take over the ID of the target it replaces: '{"action": "replacetarget",
+//    "name": "<old ID>", "value": "<new ID>"}' /v1/cluster (see api.ReplaceTarget).
+//    The replacing target persists the old ID and restarts (to be restarted by the
+//    deployment, e.g. k8s), and the old ID is mapped to its address.
+//
+// ================================ Summary ===============================================
+
+const daemonidname = "daemonid" // $CONFDIR/daemonid
+
+// restart delay that allows the replacing target to respond to the primary
+const adoptRestartDelay = time.Second
+
+func loadDaemonID() (daemonID string) {
+	pathname := filepath.Join(ctx.config.Confdir, daemonidname)
+	if err := cmn.LocalLoad(pathname, &daemonID); err != nil && !os.IsNotExist(err) && err != io.EOF {
+		glog.Errorf("Failed to load daemon ID from %q, err: %v", pathname, err)
+	}
+	return
+}
+
+func persistDaemonID(daemonID string) error {
+	pathname := filepath.Join(ctx.config.Confdir, daemonidname)
+	if err := cmn.LocalSave(pathname, daemonID); err != nil {
+		return fmt.Errorf("failed to persist daemon ID %s as %q, err: %v", daemonID, pathname, err)
+	}
+	return nil
+}
+
+//
+// target
+//
+
+// PUT '{"action": "replacetarget", "name": "<old ID>"}' /v1/daemon: adopt the old ID and restart
+func (t *targetrunner) adoptDaemonID(w http.ResponseWriter, r *http.Request, msg *cmn.ActionMsg) {
+	if msg.Name == "" {
+		t.invalmsghdlr(w, r, "Missing the ID of the replaced target")
+		return
+	}
+	if os.Getenv("DFCDAEMONID") != "" {
+		t.invalmsghdlr(w, r, fmt.Sprintf("%s: the ID is set via DFCDAEMONID and cannot be replaced", t.si.DaemonID),
+			http.StatusConflict)
+		return
+	}
+	if err := persistDaemonID(msg.Name); err != nil {
+		t.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	glog.Warningf("%s: replacing target %s - restarting under its ID", t.si.DaemonID, msg.Name)
+	go func() {
+		time.Sleep(adoptRestartDelay)
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	}()
+}
+
+//
+// proxy
+//
+
+// aliveNode is the daemon registered under the ID of the registering one, as probed for
+// liveness prior to taking the Smap lock - see probeRegistered and addOrUpdateNode
+type aliveNode struct {
+	si    *cluster.Snode
+	alive bool
+}
+
+// probeRegistered probes the daemon registered under the ID of, and at another address than,
+// the registering one (if any) outside the Smap lock - a network call that would otherwise
+// stall the cluster map updates and keepalive
+func (p *proxyrunner) probeRegistered(nsi *cluster.Snode, isproxy bool) (probe aliveNode) {
+	smap := p.smapowner.get()
+	osi := smap.GetTarget(nsi.DaemonID)
+	if isproxy {
+		osi = smap.GetProxy(nsi.DaemonID)
+	}
+	if osi != nil && !osi.Equals(nsi) {
+		probe.si, probe.alive = osi, p.isAlive(osi)
+	}
+	return
+}
+
+// isAlive returns true if the daemon responds at its address with its ID
+func (p *proxyrunner) isAlive(si *cluster.Snode) bool {
+	query := url.Values{}
+	query.Add(cmn.URLParamWhat, cmn.GetWhatDaemonInfo)
+	args := callArgs{
+		si: si,
+		req: reqArgs{
+			method: http.MethodGet,
+			path:   cmn.URLPath(cmn.Version, cmn.Daemon),
+			query:  query,
+		},
+		timeout: ctx.config.Timeout.CplaneOperation,
+	}
+	res := p.call(args)
+	if res.err != nil {
+		return false
+	}
+	rsi := &cluster.Snode{}
+	return jsoniter.Unmarshal(res.outjson, rsi) == nil && rsi.DaemonID == si.DaemonID
+}
+
+// delStaleTargets removes from the cluster map the targets that share the (public) address of
+// the registering target: a daemon that listens on the same address is no longer there
+func delStaleTargets(clone *smapX, nsi *cluster.Snode) {
+	for id, si := range clone.Tmap {
+		if id != nsi.DaemonID && si.PublicNet.DirectURL == nsi.PublicNet.DirectURL {
+			glog.Warningf("target %s replaces stale target %s at %s", nsi.DaemonID, id, si.PublicNet.DirectURL)
+			clone.delTarget(id)
+		}
+	}
+}
+
+// PUT '{"action": "replacetarget", "name": "<old ID>", "value": "<new ID>"}' /v1/cluster
+func (p *proxyrunner) replaceTarget(w http.ResponseWriter, r *http.Request, msg *cmn.ActionMsg) {
+	newid, ok := msg.Value.(string)
+	oldid := msg.Name
+	if !ok || oldid == "" || newid == "" || oldid == newid {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Invalid %s: expecting the IDs of the replaced (name) and "+
+			"the replacing (value) targets, got %+v", msg.Action, msg))
+		return
+	}
+	smap := p.smapowner.get()
+	nsi := smap.GetTarget(newid)
+	if nsi == nil {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Unknown target %s", newid), http.StatusNotFound)
+		return
+	}
+	if osi := smap.GetTarget(oldid); osi != nil && p.isAlive(osi) {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Cannot replace target %s: alive at %s", oldid, osi.PublicNet.DirectURL),
+			http.StatusConflict)
+		return
+	}
+	msgbytes, err := jsoniter.Marshal(&cmn.ActionMsg{Action: cmn.ActReplaceTarget, Name: oldid})
+	cmn.Assert(err == nil, err)
+	args := callArgs{
+		si: nsi,
+		req: reqArgs{
+			method: http.MethodPut,
+			path:   cmn.URLPath(cmn.Version, cmn.Daemon),
+			body:   msgbytes,
+		},
+		timeout: ctx.config.Timeout.CplaneOperation,
+	}
+	if res := p.call(args); res.err != nil {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Target %s failed to replace %s: %v, %s", newid, oldid, res.err, res.errstr),
+			res.status)
+		return
+	}
+
+	p.smapowner.Lock()
+	clone := p.smapowner.get().clone()
+	if clone.GetTarget(newid) != nil {
+		clone.delTarget(newid)
+	}
+	if clone.GetTarget(oldid) != nil {
+		clone.delTarget(oldid)
+	}
+	rsi := &cluster.Snode{DaemonID: oldid, PublicNet: nsi.PublicNet, IntraControlNet: nsi.IntraControlNet,
+		IntraDataNet: nsi.IntraDataNet}
+	clone.addTarget(rsi)
+	if errstr := p.smapowner.persist(clone, true); errstr != "" {
+		glog.Errorln(errstr)
+	}
+	p.smapowner.put(clone)
+	p.smapowner.Unlock()
+	glog.Infof("target %s replaced by %s at %s", oldid, newid, rsi.PublicNet.DirectURL)
+
+	p.keepalive.heardFrom(oldid, true /* reset */)
+	// no new target ID in the action: the placement does not change, nothing to rebalance
+	p.metasyncer.sync(true, clone, msg)
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestDaemonIDPersist(t *testing.T) {
+	confdir, err := ioutil.TempDir("", "daemonid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(confdir)
+	saved := ctx.config.Confdir
+	defer func() { ctx.config.Confdir = saved }()
+	ctx.config.Confdir = confdir
+
+	if id := loadDaemonID(); id != "" {
+		t.Fatalf("expected no daemon ID, got %q", id)
+	}
+	if err := persistDaemonID("12345"); err != nil {
+		t.Fatal(err)
+	}
+	if id := loadDaemonID(); id != "12345" {
+		t.Errorf("expected daemon ID 12345, got %q", id)
+	}
+}
+
+func TestRegisterNewAddress(t *testing.T) {
+	respondAs := "t1"
+	old := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := jsoniter.Marshal(&cluster.Snode{DaemonID: respondAs})
+		w.Write(b)
+	}))
+	p := newDiscoverServerPrimary()
+	p.intra = cmn.NewIntraClient(cmn.IntraClientArgs{})
+	p.startedup(1)
+
+	osi := &cluster.Snode{DaemonID: "t1", PublicNet: cluster.NetInfo{DirectURL: old.URL}}
+	nsi := &cluster.Snode{DaemonID: "t1", PublicNet: cluster.NetInfo{DirectURL: "http://127.0.0.1:1"}}
+	smap := newSmap()
+	smap.addTarget(osi)
+	p.smapowner.put(smap)
+
+	// t1 is still alive at its old address: duplicate
+	if update, errstr := p.addOrUpdateNode(nsi, osi, p.probeRegistered(nsi, false), false, "target"); update || errstr == "" {
+		t.Errorf("expected duplicate t1 to be rejected, got %t, %q", update, errstr)
+	}
+	// the old address is taken by another daemon
+	respondAs = "t2"
+	if update, errstr := p.addOrUpdateNode(nsi, osi, p.probeRegistered(nsi, false), false, "target"); !update || errstr != "" {
+		t.Errorf("expected t1 renewed, got %t, %q", update, errstr)
+	}
+	// gone
+	old.Close()
+	probe := p.probeRegistered(nsi, false)
+	if update, errstr := p.addOrUpdateNode(nsi, osi, probe, false, "target"); !update || errstr != "" {
+		t.Errorf("expected t1 renewed, got %t, %q", update, errstr)
+	}
+	// re-registered at yet another address after the probe
+	osi = &cluster.Snode{DaemonID: "t1", PublicNet: cluster.NetInfo{DirectURL: "http://127.0.0.1:2"}}
+	if update, errstr := p.addOrUpdateNode(nsi, osi, probe, false, "target"); update || errstr == "" {
+		t.Errorf("expected t1 to be rejected, got %t, %q", update, errstr)
+	}
+}
+
+func TestDelStaleTargets(t *testing.T) {
+	smap := newSmap()
+	smap.addTarget(&cluster.Snode{DaemonID: "t1", PublicNet: cluster.NetInfo{DirectURL: "http://10.0.0.1:8081"}})
+	smap.addTarget(&cluster.Snode{DaemonID: "t2", PublicNet: cluster.NetInfo{DirectURL: "http://10.0.0.2:8081"}})
+
+	nsi := &cluster.Snode{DaemonID: "t3", PublicNet: cluster.NetInfo{DirectURL: "http://10.0.0.1:8081"}}
+	delStaleTargets(smap, nsi)
+	smap.addTarget(nsi)
+	if smap.GetTarget("t1") != nil || smap.GetTarget("t2") == nil || smap.CountTargets() != 2 {
+		t.Errorf("expected t3 to replace t1 at the same address, got %s", smap.pp())
+	}
+}
@@ -0,0 +1,292 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Dashboard: GET /v1/cluster?what=dashboard returns a cluster overview - node health,
+// capacity, ops/sec, rebalance status, and alerts. The overview is generated on demand
+// from the existing endpoints: targets' /v1/daemon?what=stats and /v1/health, and proxies'
+// /v1/health. The result is JSON unless the request Accepts text/html, in which case
+// the same data is rendered as a self-contained HTML page.
+//
+// Ops/sec are computed from the GET and PUT counters' deltas between two consecutive
+// dashboard requests served by the same proxy.
+//
+// ================================ Summary ===============================================
+
+type (
+	// dashStats is the subset of a daemon's stats the dashboard is built from
+	dashStats struct {
+		Core     map[string]int64         `json:"core"`
+		Capacity map[string]*dashCapacity `json:"capacity"`
+		CPUidle  string                   `json:"cpuidle"`
+	}
+	dashCapacity struct {
+		Used     uint64 `json:"used"`
+		Avail    uint64 `json:"avail"`
+		Headroom int64  `json:"headroom"`
+	}
+	dashSample struct {
+		time       time.Time
+		gets, puts int64
+	}
+	// dashState keeps the previous counters, by DaemonID, to compute ops/sec
+	dashState struct {
+		sync.Mutex
+		prev map[string]dashSample
+	}
+)
+
+var dashTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"bytes": func(b uint64) string { return cmn.B2S(int64(b), 1) },
+	"rate":  func(f float64) string { return fmt.Sprintf("%.1f", f) },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta http-equiv="refresh" content="10"><title>DFC cluster</title>
+<style>
+body{font-family:sans-serif;margin:20px}table{border-collapse:collapse;margin-bottom:20px}
+td,th{border:1px solid #ccc;padding:4px 8px;text-align:right}th{background:#eee}
+.bar{width:200px;background:#eee}.used{background:#4a4;height:12px}.high{background:#c44}
+.off{color:#c44}.alert{color:#c44}
+</style></head><body>
+<h2>DFC cluster</h2>
+<p>{{.Time.Format "2006-01-02 15:04:05"}} &middot; primary {{.Primary}} &middot; Smap v{{.SmapVersion}} &middot;
+{{if .Rebalancing}}<b>rebalancing</b>{{else}}not rebalancing{{end}}</p>
+{{if .Alerts}}<h3>Alerts</h3><ul>{{range .Alerts}}<li class="alert">{{.}}</li>{{end}}</ul>{{end}}
+<h3>Proxies</h3>
+<table><tr><th>ID</th><th>URL</th><th>Status</th><th>GET/s</th><th>PUT/s</th><th>Errors</th></tr>
+{{range .Proxies}}<tr><td>{{.DaemonID}}</td><td>{{.URL}}</td>
+{{if .Online}}<td>online</td>{{else}}<td class="off">offline</td>{{end}}
+<td>{{rate .GetPerSec}}</td><td>{{rate .PutPerSec}}</td><td>{{.Errors}}</td></tr>
+{{end}}</table>
+<h3>Targets</h3>
+<table><tr><th>ID</th><th>URL</th><th>Status</th><th>GET/s</th><th>PUT/s</th><th>Errors</th><th>CPU idle</th>
+<th>Capacity</th><th>Used</th><th>Avail</th></tr>
+{{range .Targets}}<tr><td>{{.DaemonID}}</td><td>{{.URL}}</td>
+{{if .Online}}<td>{{if .Emergency}}<span class="alert">emergency</span>{{else if .Rebalancing}}rebalancing{{else}}online{{end}}</td>{{else}}<td class="off">offline</td>{{end}}
+<td>{{rate .GetPerSec}}</td><td>{{rate .PutPerSec}}</td><td>{{.Errors}}</td><td>{{.CPUIdle}}</td>
+<td><div class="bar"><div class="used{{if ge .UsedPct $.HighWM}} high{{end}}" style="width:{{.UsedPct}}%"></div></div>{{.UsedPct}}%</td>
+<td>{{bytes .Used}}</td><td>{{bytes .Avail}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
+
+func (p *proxyrunner) invokeHttpGetClusterDashboard(w http.ResponseWriter, r *http.Request) bool {
+	dash := p.dashboard()
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		jsbytes, err := jsoniter.Marshal(dash)
+		cmn.Assert(err == nil, err)
+		return p.writeJSON(w, r, jsbytes, "HttpGetClusterDashboard")
+	}
+	html, err := renderDashboard(dash, ctx.config.LRU.HighWM)
+	if err != nil {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Failed to render dashboard, err: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err = w.Write(html); err != nil {
+		glog.Errorf("Failed to write dashboard, err: %v", err)
+		return false
+	}
+	return true
+}
+
+func renderDashboard(dash *cmn.ClusterDashboard, highwm int64) ([]byte, error) {
+	var (
+		buf  bytes.Buffer
+		data = struct {
+			*cmn.ClusterDashboard
+			HighWM int64
+		}{dash, highwm}
+	)
+	if err := dashTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// dashboard collects the daemons' stats and summarizes them
+func (p *proxyrunner) dashboard() *cmn.ClusterDashboard {
+	var (
+		smap      = p.smapowner.get()
+		now       = time.Now()
+		healthURL = cmn.URLPath(cmn.Version, cmn.Health)
+		query     = url.Values{}
+		pstats    = make(map[string]*dashStats, len(smap.Pmap))
+		tstats    = make(map[string]*dashStats, len(smap.Tmap))
+		thealth   = make(map[string]*thealthstatus, len(smap.Tmap))
+	)
+	query.Add(cmn.URLParamFromID, p.si.DaemonID)
+
+	// proxies: /v1/health returns the core stats
+	for res := range p.broadcastDash(healthURL, query, smap.Pmap) {
+		if res.err != nil {
+			continue
+		}
+		ds := &dashStats{}
+		if err := jsoniter.Unmarshal(res.outjson, &ds.Core); err != nil {
+			glog.Errorf("Failed to unmarshal %s stats, err: %v", res.si, err)
+			continue
+		}
+		pstats[res.si.DaemonID] = ds
+	}
+	self := &dashStats{Core: make(map[string]int64, 3)}
+	rr := getproxystatsrunner()
+	rr.RLock()
+	for _, name := range []string{stats.GetCount, stats.PutCount, stats.ErrCount} {
+		self.Core[name] = rr.Core.Tracker[name].Value
+	}
+	rr.RUnlock()
+	pstats[p.si.DaemonID] = self
+
+	// targets
+	statsQuery := url.Values{}
+	statsQuery.Add(cmn.URLParamWhat, cmn.GetWhatStats)
+	for res := range p.broadcastDash(cmn.URLPath(cmn.Version, cmn.Daemon), statsQuery, smap.Tmap) {
+		if res.err != nil {
+			continue
+		}
+		ds := &dashStats{}
+		if err := jsoniter.Unmarshal(res.outjson, ds); err != nil {
+			glog.Errorf("Failed to unmarshal %s stats, err: %v", res.si, err)
+			continue
+		}
+		tstats[res.si.DaemonID] = ds
+	}
+	for res := range p.broadcastDash(healthURL, query, smap.Tmap) {
+		if res.err != nil {
+			continue
+		}
+		status := &thealthstatus{}
+		if err := jsoniter.Unmarshal(res.outjson, status); err == nil {
+			thealth[res.si.DaemonID] = status
+		}
+	}
+	return p.dash.summarize(smap, now, pstats, tstats, thealth, ctx.config.LRU.HighWM)
+}
+
+func (p *proxyrunner) broadcastDash(path string, query url.Values, servers map[string]*cluster.Snode) chan callResult {
+	return p.broadcast(bcastCallArgs{
+		req:      reqArgs{method: http.MethodGet, path: path, query: query},
+		internal: true,
+		timeout:  ctx.config.Timeout.Default,
+		servers:  []map[string]*cluster.Snode{servers},
+	})
+}
+
+// summarize builds the dashboard out of the collected stats; a daemon that is in the
+// cluster map but has no stats is reported offline
+func (d *dashState) summarize(smap *smapX, now time.Time, pstats, tstats map[string]*dashStats,
+	thealth map[string]*thealthstatus, highwm int64) *cmn.ClusterDashboard {
+	d.Lock()
+	defer d.Unlock()
+	if d.prev == nil {
+		d.prev = make(map[string]dashSample, len(pstats)+len(tstats))
+	}
+	dash := &cmn.ClusterDashboard{
+		Time:        now,
+		SmapVersion: smap.version(),
+		Proxies:     make([]cmn.DashboardNode, 0, len(smap.Pmap)),
+		Targets:     make([]cmn.DashboardNode, 0, len(smap.Tmap)),
+		Alerts:      make([]string, 0),
+	}
+	if smap.ProxySI != nil {
+		dash.Primary = smap.ProxySI.DaemonID
+	}
+	for id, si := range smap.Pmap {
+		node := d.node(id, si, pstats[id], now)
+		if !node.Online {
+			dash.Alerts = append(dash.Alerts, fmt.Sprintf("proxy %s is offline", id))
+		}
+		dash.Proxies = append(dash.Proxies, node)
+	}
+	for id, si := range smap.Tmap {
+		ds := tstats[id]
+		node := d.node(id, si, ds, now)
+		if !node.Online {
+			dash.Alerts = append(dash.Alerts, fmt.Sprintf("target %s is offline", id))
+			dash.Targets = append(dash.Targets, node)
+			continue
+		}
+		node.CPUIdle = ds.CPUidle
+		if status, ok := thealth[id]; ok {
+			node.Rebalancing, node.Emergency = status.IsRebalancing, status.Emergency
+		}
+		if node.Emergency {
+			dash.Alerts = append(dash.Alerts,
+				fmt.Sprintf("target %s is in capacity emergency mode, writes are refused", id))
+		}
+		dash.Rebalancing = dash.Rebalancing || node.Rebalancing
+		mpaths := make([]string, 0, len(ds.Capacity))
+		for mpath := range ds.Capacity {
+			mpaths = append(mpaths, mpath)
+		}
+		sort.Strings(mpaths)
+		for _, mpath := range mpaths {
+			fscap := ds.Capacity[mpath]
+			node.Used += fscap.Used
+			node.Avail += fscap.Avail
+			if fscap.Headroom < 0 {
+				dash.Alerts = append(dash.Alerts,
+					fmt.Sprintf("target %s: %s is out of reserved space, writes are refused", id, mpath))
+			}
+		}
+		if total := node.Used + node.Avail; total > 0 {
+			node.UsedPct = int64(node.Used * 100 / total)
+		}
+		if highwm > 0 && node.UsedPct >= highwm {
+			dash.Alerts = append(dash.Alerts,
+				fmt.Sprintf("target %s: used capacity %d%% is above the high watermark %d%%", id, node.UsedPct, highwm))
+		}
+		dash.Targets = append(dash.Targets, node)
+	}
+	for id := range d.prev {
+		if smap.GetProxy(id) == nil && smap.GetTarget(id) == nil {
+			delete(d.prev, id)
+		}
+	}
+	sort.Slice(dash.Proxies, func(i, j int) bool { return dash.Proxies[i].DaemonID < dash.Proxies[j].DaemonID })
+	sort.Slice(dash.Targets, func(i, j int) bool { return dash.Targets[i].DaemonID < dash.Targets[j].DaemonID })
+	sort.Strings(dash.Alerts)
+	return dash
+}
+
+func (d *dashState) node(id string, si *cluster.Snode, ds *dashStats, now time.Time) cmn.DashboardNode {
+	node := cmn.DashboardNode{DaemonID: id, URL: si.PublicNet.DirectURL, Online: ds != nil}
+	if ds == nil {
+		delete(d.prev, id)
+		return node
+	}
+	cur := dashSample{time: now, gets: ds.Core[stats.GetCount], puts: ds.Core[stats.PutCount]}
+	node.Errors = ds.Core[stats.ErrCount]
+	if prev, ok := d.prev[id]; ok {
+		// counters go back to zero when the daemon restarts
+		if elapsed := cur.time.Sub(prev.time).Seconds(); elapsed > 0 && cur.gets >= prev.gets && cur.puts >= prev.puts {
+			node.GetPerSec = float64(cur.gets-prev.gets) / elapsed
+			node.PutPerSec = float64(cur.puts-prev.puts) / elapsed
+		}
+	}
+	d.prev[id] = cur
+	return node
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+func TestDashboardSummarize(t *testing.T) {
+	var (
+		d    dashState
+		smap = newSmap()
+		now  = time.Now()
+	)
+	smap.addProxy(&cluster.Snode{DaemonID: "p1"})
+	smap.addProxy(&cluster.Snode{DaemonID: "p2"})
+	smap.addTarget(&cluster.Snode{DaemonID: "t1"})
+	smap.addTarget(&cluster.Snode{DaemonID: "t2"})
+	smap.ProxySI = smap.GetProxy("p1")
+
+	tstats := func(gets, puts int64, used, avail uint64, headroom int64) *dashStats {
+		return &dashStats{
+			Core:     map[string]int64{stats.GetCount: gets, stats.PutCount: puts},
+			Capacity: map[string]*dashCapacity{"/mp": {Used: used, Avail: avail, Headroom: headroom}},
+		}
+	}
+	pstats := map[string]*dashStats{"p1": {Core: map[string]int64{}}}
+
+	// p2 is offline; t2 is above the high watermark and out of reserved space
+	dash := d.summarize(smap, now, pstats, map[string]*dashStats{
+		"t1": tstats(100, 10, 10, 90, 50),
+		"t2": tstats(0, 0, 95, 5, -1),
+	}, map[string]*thealthstatus{"t1": {IsRebalancing: true}, "t2": {Emergency: true}}, 90)
+
+	if dash.Primary != "p1" || !dash.Rebalancing {
+		t.Fatalf("primary %q, rebalancing %t", dash.Primary, dash.Rebalancing)
+	}
+	if len(dash.Proxies) != 2 || dash.Proxies[0].DaemonID != "p1" || !dash.Proxies[0].Online || dash.Proxies[1].Online {
+		t.Fatalf("unexpected proxies: %+v", dash.Proxies)
+	}
+	if len(dash.Targets) != 2 || dash.Targets[0].UsedPct != 10 || dash.Targets[1].UsedPct != 95 {
+		t.Fatalf("unexpected targets: %+v", dash.Targets)
+	}
+	if !dash.Targets[1].Emergency {
+		t.Fatalf("expected t2 in emergency mode: %+v", dash.Targets[1])
+	}
+	if len(dash.Alerts) != 4 {
+		t.Fatalf("expected 4 alerts, got %v", dash.Alerts)
+	}
+
+	// ops/sec: from the deltas since the previous call
+	dash = d.summarize(smap, now.Add(10*time.Second), pstats, map[string]*dashStats{
+		"t1": tstats(300, 60, 10, 90, 50),
+	}, nil, 90)
+	if t1 := dash.Targets[0]; t1.GetPerSec != 20 || t1.PutPerSec != 5 {
+		t.Fatalf("expected 20 GET/s and 5 PUT/s, got %+v", t1)
+	}
+	if dash.Targets[1].Online || dash.Rebalancing {
+		t.Fatalf("unexpected targets: %+v", dash.Targets)
+	}
+
+	html, err := renderDashboard(dash, 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"t1", "t2", "offline", "20.0"} {
+		if !strings.Contains(string(html), s) {
+			t.Errorf("%q is missing in the rendered dashboard", s)
+		}
+	}
+}
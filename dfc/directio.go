@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/memsys"
+)
+
+// ================================ Summary ===============================================
+//
+// Direct I/O (direct_io): streaming a dataset through the target reads and writes each
+// large object once - via the page cache that then evicts the hot small objects. With
+// direct_io enabled, the GETs of entire objects and the PUTs of the objects that are at
+// least direct_io.min_object_size bypass the page cache: O_DIRECT on Linux, F_NOCACHE on
+// macOS.
+//
+// O_DIRECT requires the buffers, offsets, and lengths to be aligned (directIOAlign): the
+// reads and writes go through memsys buffers of the largest slab (directIOBufSize) that
+// are checked for alignment - the object is read/written the regular way if the buffer is
+// not aligned, or if the filesystem does not support direct I/O (e.g., tmpfs). The last,
+// partial block of a PUT is written padded, and the file is then truncated to its size.
+// Range reads, compressed objects, and objects served by the memory-mapped reads, fd_cache,
+// or readahead are not affected.
+//
+// ================================ Summary ===============================================
+
+const (
+	directIOAlign   = 4096
+	directIOBufSize = memsys.Numslabs * 4096
+)
+
+type (
+	// directReader reads the O_DIRECT file via its aligned buffer, whatever the caller's buffer
+	directReader struct {
+		file *os.File
+		buf  []byte
+		slab *memsys.Slab2
+		data []byte // read and not yet returned
+		err  error
+	}
+	// directWriter writes the O_DIRECT file in aligned blocks; flush writes the rest
+	directWriter struct {
+		file *os.File
+		buf  []byte
+		slab *memsys.Slab2
+		n    int   // buffered
+		off  int64 // written
+	}
+)
+
+// useDirectIO returns true if the object of the given size is to bypass the page cache
+func useDirectIO(size int64) bool {
+	return ctx.config.DirectIO.Enabled && size >= ctx.config.DirectIO.MinObjSize && !dryRun.disk
+}
+
+// allocAligned returns the aligned buffer of directIOBufSize, or nil if the slab's buffer
+// is not aligned
+func allocAligned() ([]byte, *memsys.Slab2) {
+	slab, err := gmem2.GetSlab2(directIOBufSize)
+	if err != nil {
+		return nil, nil
+	}
+	buf := slab.Alloc()
+	if uintptr(unsafe.Pointer(&buf[0]))%directIOAlign != 0 {
+		slab.Free(buf)
+		glog.Warningf("direct_io: unaligned %d-byte buffer, reading/writing via page cache", directIOBufSize)
+		return nil, nil
+	}
+	return buf, slab
+}
+
+// newDirectReader opens the object for reading that bypasses the page cache, or returns
+// nil - to open it the regular way
+func newDirectReader(fqn string) *directReader {
+	buf, slab := allocAligned()
+	if buf == nil {
+		return nil
+	}
+	file, err := openDirect(fqn, os.O_RDONLY, 0)
+	if err != nil {
+		slab.Free(buf)
+		if glog.V(4) {
+			glog.Infof("direct_io: %s, err: %v", fqn, err)
+		}
+		return nil
+	}
+	return &directReader{file: file, buf: buf, slab: slab}
+}
+
+func (r *directReader) Read(p []byte) (n int, err error) {
+	if len(r.data) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		var read int
+		read, r.err = io.ReadFull(r.file, r.buf) // full blocks: the offset stays aligned
+		if r.err == io.ErrUnexpectedEOF {
+			r.err = io.EOF
+		}
+		r.data = r.buf[:read]
+		if read == 0 {
+			return 0, r.err
+		}
+	}
+	n = copy(p, r.data)
+	r.data = r.data[n:]
+	return
+}
+
+func (r *directReader) Close() error {
+	r.slab.Free(r.buf)
+	return r.file.Close()
+}
+
+// newDirectWriter creates the file for writing that bypasses the page cache, or returns
+// nil - to create it the regular way
+func newDirectWriter(fqn string) (*directWriter, error) {
+	buf, slab := allocAligned()
+	if buf == nil {
+		return nil, nil
+	}
+	file, err := openDirect(fqn, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		slab.Free(buf)
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		if glog.V(4) {
+			glog.Infof("direct_io: %s, err: %v", fqn, err)
+		}
+		return nil, nil
+	}
+	return &directWriter{file: file, buf: buf, slab: slab}, nil
+}
+
+func (w *directWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		c := copy(w.buf[w.n:], p)
+		w.n += c
+		n += c
+		p = p[c:]
+		if w.n == len(w.buf) {
+			if err = w.writeBuf(len(w.buf)); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// flush writes the last, partial block - padded with zeros - and truncates the file to size;
+// it does not close the file
+func (w *directWriter) flush() (err error) {
+	if w.n == 0 {
+		return nil
+	}
+	size := w.off + int64(w.n)
+	padded := (w.n + directIOAlign - 1) / directIOAlign * directIOAlign
+	for i := w.n; i < padded; i++ {
+		w.buf[i] = 0
+	}
+	if err = w.writeBuf(padded); err != nil {
+		return
+	}
+	return w.file.Truncate(size)
+}
+
+func (w *directWriter) free() {
+	if w.buf != nil {
+		w.slab.Free(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *directWriter) writeBuf(n int) error {
+	if _, err := w.file.Write(w.buf[:n]); err != nil {
+		return err
+	}
+	w.off += int64(n)
+	w.n = 0
+	return nil
+}
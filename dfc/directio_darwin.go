@@ -0,0 +1,24 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"os"
+	"syscall"
+)
+
+// openDirect opens the file and turns off its caching (F_NOCACHE) - macOS has no O_DIRECT
+func openDirect(fqn string, flag int, perm os.FileMode) (*os.File, error) {
+	file, err := os.OpenFile(fqn, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), syscall.F_NOCACHE, 1); errno != 0 {
+		file.Close()
+		return nil, errno
+	}
+	return file, nil
+}
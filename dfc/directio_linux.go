@@ -0,0 +1,16 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"os"
+	"syscall"
+)
+
+// openDirect opens the file with O_DIRECT - fails with EINVAL if the filesystem does not support it
+func openDirect(fqn string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(fqn, flag|syscall.O_DIRECT, perm)
+}
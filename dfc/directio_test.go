@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/memsys"
+)
+
+func TestDirectIO(t *testing.T) {
+	if gmem2 == nil {
+		gmem2 = &memsys.Mem2{Name: "directio"}
+		_ = gmem2.Init(false /* ignore init-time errors */)
+	}
+	dir, err := ioutil.TempDir("", "directio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fqn := filepath.Join(dir, "obj")
+	if file, err := openDirect(fqn, os.O_WRONLY|os.O_CREATE, 0644); err != nil {
+		t.Skipf("direct I/O not supported in %s: %v", dir, err)
+	} else {
+		file.Close()
+	}
+
+	// sizes: empty, partial block, multiple of the buffer, and neither
+	for _, size := range []int{0, 100, 2 * directIOBufSize, 3*directIOBufSize + directIOAlign + 7} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		dw, err := newDirectWriter(fqn)
+		if err != nil || dw == nil {
+			t.Fatalf("size %d: failed to create direct writer, err: %v", size, err)
+		}
+		// odd-sized writes
+		for p := data; len(p) > 0; {
+			n := 1000
+			if n > len(p) {
+				n = len(p)
+			}
+			if _, err := dw.Write(p[:n]); err != nil {
+				t.Fatal(err)
+			}
+			p = p[n:]
+		}
+		if err := dw.flush(); err != nil {
+			t.Fatal(err)
+		}
+		dw.free()
+		dw.file.Close()
+
+		fi, err := os.Stat(fqn)
+		if err != nil || fi.Size() != int64(size) {
+			t.Fatalf("size %d: unexpected stat %v, err: %v", size, fi, err)
+		}
+		dr := newDirectReader(fqn)
+		if dr == nil {
+			t.Fatalf("size %d: failed to open direct reader", size)
+		}
+		read, err := ioutil.ReadAll(dr)
+		dr.Close()
+		if err != nil || !bytes.Equal(read, data) {
+			t.Errorf("size %d: read %d bytes back, err: %v", size, len(read), err)
+		}
+	}
+}
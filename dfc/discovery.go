@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// ================================ Summary ===============================================
+//
+// Cluster discovery (proxyconfig.discovery): a joining node that has no primary URL in its
+// configuration - or cannot reach the configured one - looks up the cluster's proxies:
+//
+// - "dns_srv": in the DNS SRV records (e.g., a headless Kubernetes service, or a Consul
+//   service) - each record is a proxy's host and port;
+// - "aws":     in the EC2 instance tag (via the instance metadata; requires the instance
+//   metadata tags to be enabled) that holds a comma-separated list of proxy URLs;
+// - "gcp":     in the GCE instance - or, if not defined, project - metadata attribute
+//   that holds a comma-separated list of proxy URLs.
+//
+// The node then asks the discovered proxies for the cluster map and joins the primary
+// designated by the most recent (max-versioned) one. Discovery is the last resort when
+// joining: the primary from the Smap, primary_url, discovery_url, and original_url
+// are tried first (see httprunner.join).
+//
+// ================================ Summary ===============================================
+
+type discoverer interface {
+	// proxyURLs returns the (public) URLs of the discovered proxies
+	proxyURLs() ([]string, error)
+}
+
+type (
+	srvDiscoverer struct {
+		name  string
+		proto string // http | https
+	}
+	awsDiscoverer struct {
+		tag string
+	}
+	gcpDiscoverer struct {
+		attr string
+	}
+)
+
+func newDiscoverer(conf *cmn.DiscoveryConf) discoverer {
+	switch conf.Provider {
+	case cmn.DiscoveryDNSSRV:
+		return &srvDiscoverer{name: conf.Name, proto: ctx.config.Net.HTTP.Proto}
+	case cmn.DiscoveryAWS:
+		return &awsDiscoverer{tag: conf.Name}
+	case cmn.DiscoveryGCP:
+		return &gcpDiscoverer{attr: conf.Name}
+	}
+	return nil
+}
+
+func (d *srvDiscoverer) proxyURLs() ([]string, error) {
+	_, addrs, err := net.LookupSRV("", "", d.name)
+	if err != nil {
+		return nil, err
+	}
+	return srvURLs(d.proto, addrs), nil
+}
+
+func (d *awsDiscoverer) proxyURLs() ([]string, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	value, err := ec2metadata.New(sess).GetMetadata("tags/instance/" + d.tag)
+	if err != nil {
+		return nil, err
+	}
+	return parseProxyURLs(value)
+}
+
+func (d *gcpDiscoverer) proxyURLs() ([]string, error) {
+	value, err := metadata.InstanceAttributeValue(d.attr)
+	if _, ok := err.(metadata.NotDefinedError); ok {
+		value, err = metadata.ProjectAttributeValue(d.attr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseProxyURLs(value)
+}
+
+// srvURLs converts SRV records to URLs, in the order of the records' priority
+// (net.LookupSRV sorts them)
+func srvURLs(proto string, addrs []*net.SRV) []string {
+	urls := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		host := strings.TrimSuffix(addr.Target, ".")
+		urls = append(urls, proto+"://"+net.JoinHostPort(host, strconv.Itoa(int(addr.Port))))
+	}
+	return urls
+}
+
+// parseProxyURLs parses a comma-separated list of proxy URLs
+func parseProxyURLs(value string) ([]string, error) {
+	urls := make([]string, 0)
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if u, err := url.Parse(s); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("Invalid proxy URL %q", s)
+		}
+		urls = append(urls, strings.TrimSuffix(s, "/"))
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("No proxy URLs in %q", value)
+	}
+	return urls, nil
+}
+
+// discoverPrimary returns the URL of the primary as per the max-versioned Smap of the
+// discovered proxies, or "" if discovery is disabled or has failed
+func (h *httprunner) discoverPrimary() string {
+	conf := &ctx.config.Proxy.Discovery
+	d := newDiscoverer(conf)
+	if d == nil {
+		return ""
+	}
+	urls, err := d.proxyURLs()
+	if err != nil {
+		glog.Errorf("%s: %s discovery (%s) failed, err: %v", h.si.DaemonID, conf.Provider, conf.Name, err)
+		return ""
+	}
+	var found *smapX
+	for _, u := range urls {
+		if u == h.si.PublicNet.DirectURL {
+			continue
+		}
+		smap, errstr := h.smapFromURL(u)
+		if errstr != "" {
+			glog.Warningf("%s: %s", h.si.DaemonID, errstr)
+			continue
+		}
+		if smap.ProxySI == nil {
+			continue
+		}
+		if found == nil || smap.version() > found.version() {
+			found = smap
+		}
+	}
+	if found == nil {
+		glog.Errorf("%s: none of the discovered proxies %v has returned a valid Smap", h.si.DaemonID, urls)
+		return ""
+	}
+	url := found.ProxySI.PublicNet.DirectURL
+	glog.Infof("%s: discovered primary %s (%s) via %s, Smap v%d",
+		h.si.DaemonID, found.ProxySI.DaemonID, url, conf.Provider, found.version())
+	return url
+}
@@ -0,0 +1,33 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoveryURLs(t *testing.T) {
+	addrs := []*net.SRV{
+		{Target: "proxy-0.dfc.svc.cluster.local.", Port: 8080},
+		{Target: "10.0.0.2", Port: 8081},
+	}
+	expected := []string{"https://proxy-0.dfc.svc.cluster.local:8080", "https://10.0.0.2:8081"}
+	if urls := srvURLs("https", addrs); !reflect.DeepEqual(urls, expected) {
+		t.Errorf("expected %v, got %v", expected, urls)
+	}
+
+	urls, err := parseProxyURLs(" http://10.0.0.1:8080/, http://10.0.0.2:8080,")
+	expected = []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080"}
+	if err != nil || !reflect.DeepEqual(urls, expected) {
+		t.Errorf("expected %v, got %v (err %v)", expected, urls, err)
+	}
+	for _, value := range []string{"", " , ", "10.0.0.1:8080", "ftp://10.0.0.1"} {
+		if _, err := parseProxyURLs(value); err == nil {
+			t.Errorf("expected %q to fail", value)
+		}
+	}
+}
@@ -105,6 +105,9 @@ func (p *proxyrunner) bootstrap() {
 	// step 3: join as a non-primary, or
 	// 	   keep starting up as a primary
 	if !guessAmPrimary {
+		if getSmapURL == "" {
+			getSmapURL = p.discoverPrimary()
+		}
 		cmn.Assert(getSmapURL != p.si.PublicNet.DirectURL, getSmapURL)
 		glog.Infof("%s: starting up as non-primary, joining => %s", p.si.DaemonID, getSmapURL)
 		p.secondaryStartup(getSmapURL)
@@ -399,5 +402,6 @@ func (p *proxyrunner) startedup(val int64) int64 {
 		return atomic.LoadInt64(&p.startedUp)
 	}
 	atomic.StoreInt64(&p.startedUp, val)
+	p.inited() // see rungroup.status
 	return val
 }
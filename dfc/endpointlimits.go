@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Endpoint limits: listing a bucket and querying the cluster's stats or xactions make
+// the proxy call all targets and merge their responses - unlike object requests that are
+// merely redirected. To keep a burst of those from starving the object requests, the
+// proxy handles at most endpoint_limits.{list, stats, xaction} of each at a time (zero
+// means unlimited). The requests over the limit wait for their turn, first come first
+// served, for at most endpoint_limits.queue_timeout and fail with 503 (Service
+// Unavailable) after that.
+//
+// The time spent waiting is tracked by the proxy's lst.wait.lat, stats.wait.lat, and
+// xact.wait.lat latency stats (zero for the requests that have not waited), and the
+// requests that timed out - by err.limit.n.
+//
+// ================================ Summary ===============================================
+
+type (
+	// endpointLimiter admits up to the limit requests at a time; the rest wait in the queue
+	endpointLimiter struct {
+		sync.Mutex
+		running int
+		queue   []chan struct{} // waiting requests, oldest first
+	}
+	endpointLimits struct {
+		list    endpointLimiter
+		stats   endpointLimiter
+		xaction endpointLimiter
+	}
+)
+
+// acquire returns true once the request can proceed - right away if under the limit;
+// false if it has not got its turn within the timeout or the client has gone away
+func (l *endpointLimiter) acquire(r *http.Request, limit int, timeout time.Duration) bool {
+	l.Lock()
+	if limit <= 0 || (l.running < limit && len(l.queue) == 0) {
+		l.running++
+		l.Unlock()
+		return true
+	}
+	ch := make(chan struct{})
+	l.queue = append(l.queue, ch)
+	l.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+	l.Lock()
+	defer l.Unlock()
+	for i, c := range l.queue {
+		if c == ch {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return false
+		}
+	}
+	return true // admitted meanwhile
+}
+
+// release lets the next waiting requests in, as many as the (current) limit allows
+func (l *endpointLimiter) release(limit int) {
+	l.Lock()
+	l.running--
+	for len(l.queue) > 0 && (limit <= 0 || l.running < limit) {
+		close(l.queue[0])
+		l.queue = l.queue[1:]
+		l.running++
+	}
+	l.Unlock()
+}
+
+// waitEndpoint waits for the request's turn, tracks the time spent waiting, and returns true
+// when the request can proceed; otherwise the request fails. The caller must release the
+// limiter when done with the request
+func (p *proxyrunner) waitEndpoint(w http.ResponseWriter, r *http.Request, l *endpointLimiter, limit int,
+	waitStat string) bool {
+	started := time.Now()
+	if !l.acquire(r, limit, ctx.config.EndpointLimits.QueueTimeout) {
+		p.statsif.Add(stats.ErrLimitCount, 1)
+		p.invalmsghdlr(w, r, fmt.Sprintf("Too many concurrent %s requests (limit %d): timed out waiting %v",
+			r.URL.Path, limit, time.Since(started)), http.StatusServiceUnavailable)
+		return false
+	}
+	p.statsif.Add(waitStat, int64(time.Since(started)))
+	return true
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEndpointLimiter(t *testing.T) {
+	var (
+		l = &endpointLimiter{}
+		r = httptest.NewRequest("GET", "/v1/cluster?what=stats", nil)
+	)
+	if !l.acquire(r, 1, time.Second) {
+		t.Fatal("expected the first request admitted right away")
+	}
+
+	// over the limit: times out
+	started := time.Now()
+	if l.acquire(r, 1, 50*time.Millisecond) {
+		t.Fatal("expected the request over the limit to time out")
+	}
+	if elapsed := time.Since(started); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the request to wait for the timeout, waited %v", elapsed)
+	}
+	if len(l.queue) != 0 {
+		t.Errorf("expected the timed out request removed from the queue, got %d queued", len(l.queue))
+	}
+
+	// over the limit: admitted in order once the running request is done
+	admitted := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			if l.acquire(r, 1, time.Minute) {
+				admitted <- i
+			}
+		}(i)
+		time.Sleep(20 * time.Millisecond) // queue in order
+	}
+	l.release(1)
+	if i := <-admitted; i != 0 {
+		t.Errorf("expected the first waiting request admitted, got %d", i)
+	}
+	select {
+	case i := <-admitted:
+		t.Fatalf("expected request %d to wait", i)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// raised at runtime: the rest is admitted upon release
+	l.release(0)
+	if i := <-admitted; i != 1 {
+		t.Errorf("expected the second waiting request admitted, got %d", i)
+	}
+	if l.running != 1 || len(l.queue) != 0 {
+		t.Errorf("expected 1 running, none queued; got %d, %d", l.running, len(l.queue))
+	}
+}
@@ -0,0 +1,162 @@
+// +build faultinject
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Fault injection - the debug builds only (go build -tags faultinject): the integration
+// tests inject faults into the target's datapath to deterministically exercise the error
+// paths - checksum mismatches, disk errors, dropped replication requests, Cloud outages,
+// and interrupted rebalance. The faults are controlled via the target's debug API:
+//
+//   PUT    /v1/faults - add a fault (cmn.FaultSpec)
+//   GET    /v1/faults - list the faults, including the number of times each was triggered
+//   DELETE /v1/faults - clear all faults
+//
+// A request at the fault's injection point triggers the first matching fault. A fault
+// with a non-zero count is removed once it has been triggered that many times.
+// Regular builds do not register the API, and the injection points compile to no-ops
+// (see nofaultinject.go).
+//
+// ================================ Summary ===============================================
+
+type faultRegistry struct {
+	sync.Mutex
+	specs []*cmn.FaultSpec
+}
+
+var (
+	faults = &faultRegistry{}
+
+	// injection point => supported kinds
+	faultKinds = map[string][]string{
+		cmn.FaultPointGet:       {cmn.FaultDelay, cmn.FaultEIO, cmn.FaultShortRead},
+		cmn.FaultPointPut:       {cmn.FaultDelay, cmn.FaultEIO},
+		cmn.FaultPointReplicate: {cmn.FaultDelay, cmn.FaultDrop},
+		cmn.FaultPointCloud:     {cmn.FaultDelay, cmn.Fault503},
+		cmn.FaultPointRebalance: {cmn.FaultDelay, cmn.FaultEIO},
+	}
+)
+
+func (t *targetrunner) registerFaultHandler() {
+	glog.Warningln("Fault injection is enabled")
+	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Faults), t.faultHandler)
+}
+
+// [METHOD] /v1/faults
+func (t *targetrunner) faultHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		jsbytes, err := jsoniter.Marshal(faults.list())
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "faults")
+	case http.MethodPut:
+		spec := &cmn.FaultSpec{}
+		if t.readJSON(w, r, spec) != nil {
+			return
+		}
+		if err := faults.add(spec); err != nil {
+			t.invalmsghdlr(w, r, err.Error())
+			return
+		}
+		glog.Warningf("Injecting fault %+v", *spec)
+	case http.MethodDelete:
+		faults.clear()
+		glog.Warningln("Cleared all injected faults")
+	default:
+		cmn.InvalidHandlerWithMsg(w, r, "invalid method for /faults path")
+	}
+}
+
+// injectFault triggers the first matching fault, if any: sleeps if the fault is a delay
+// and otherwise returns its kind, for the caller to fail accordingly; "" - no fault
+func injectFault(point, bucket, objname string) string {
+	spec := faults.trigger(point, bucket, objname)
+	if spec == nil {
+		return ""
+	}
+	glog.Warningf("Injected fault %s at %s: %s/%s", spec.Kind, point, bucket, objname)
+	if spec.Kind == cmn.FaultDelay {
+		time.Sleep(spec.Delay)
+		return ""
+	}
+	return spec.Kind
+}
+
+func (reg *faultRegistry) add(spec *cmn.FaultSpec) (err error) {
+	kinds, ok := faultKinds[spec.Point]
+	if !ok {
+		return fmt.Errorf("Invalid fault injection point %q", spec.Point)
+	}
+	supported := false
+	for _, kind := range kinds {
+		supported = supported || kind == spec.Kind
+	}
+	if !supported {
+		return fmt.Errorf("Invalid fault %q at %q: expecting one of %v", spec.Kind, spec.Point, kinds)
+	}
+	if spec.Kind == cmn.FaultDelay {
+		if spec.Delay, err = time.ParseDuration(spec.DelayStr); err != nil || spec.Delay <= 0 {
+			return fmt.Errorf("Invalid fault delay %q", spec.DelayStr)
+		}
+	}
+	if spec.Count < 0 {
+		return fmt.Errorf("Invalid fault count %d: must be non-negative", spec.Count)
+	}
+	spec.Hits = 0
+	reg.Lock()
+	reg.specs = append(reg.specs, spec)
+	reg.Unlock()
+	return nil
+}
+
+func (reg *faultRegistry) trigger(point, bucket, objname string) *cmn.FaultSpec {
+	reg.Lock()
+	defer reg.Unlock()
+	for i, spec := range reg.specs {
+		if spec.Point != point || (spec.Bucket != "" && spec.Bucket != bucket) ||
+			!strings.HasPrefix(objname, spec.Prefix) {
+			continue
+		}
+		spec.Hits++
+		if spec.Count > 0 && spec.Hits >= spec.Count {
+			reg.specs = append(reg.specs[:i], reg.specs[i+1:]...)
+		}
+		triggered := *spec
+		return &triggered
+	}
+	return nil
+}
+
+func (reg *faultRegistry) list() []cmn.FaultSpec {
+	reg.Lock()
+	defer reg.Unlock()
+	list := make([]cmn.FaultSpec, 0, len(reg.specs))
+	for _, spec := range reg.specs {
+		list = append(list, *spec)
+	}
+	return list
+}
+
+func (reg *faultRegistry) clear() {
+	reg.Lock()
+	reg.specs = nil
+	reg.Unlock()
+}
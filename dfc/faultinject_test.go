@@ -0,0 +1,57 @@
+// +build faultinject
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestFaultRegistry(t *testing.T) {
+	reg := &faultRegistry{}
+	for _, spec := range []*cmn.FaultSpec{
+		{Point: "none", Kind: cmn.FaultEIO},
+		{Point: cmn.FaultPointGet, Kind: cmn.FaultDrop},
+		{Point: cmn.FaultPointGet, Kind: cmn.FaultDelay, DelayStr: "soon"},
+		{Point: cmn.FaultPointGet, Kind: cmn.FaultEIO, Count: -1},
+	} {
+		if err := reg.add(spec); err == nil {
+			t.Errorf("expected %+v to be rejected", *spec)
+		}
+	}
+
+	if err := reg.add(&cmn.FaultSpec{Point: cmn.FaultPointGet, Kind: cmn.FaultEIO, Bucket: "b", Prefix: "x/", Count: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.add(&cmn.FaultSpec{Point: cmn.FaultPointGet, Kind: cmn.FaultShortRead}); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []struct {
+		bucket, objname, kind string
+	}{
+		{"b", "x/1", cmn.FaultEIO},
+		{"c", "x/1", cmn.FaultShortRead}, // other bucket
+		{"b", "y/1", cmn.FaultShortRead}, // other prefix
+		{"b", "x/2", cmn.FaultEIO},       // count exhausted
+		{"b", "x/3", cmn.FaultShortRead},
+	} {
+		if spec := reg.trigger(cmn.FaultPointGet, step.bucket, step.objname); spec == nil || spec.Kind != step.kind {
+			t.Fatalf("%s/%s: expected %s, got %+v", step.bucket, step.objname, step.kind, spec)
+		}
+	}
+	if spec := reg.trigger(cmn.FaultPointPut, "b", "x/1"); spec != nil {
+		t.Errorf("expected no fault at PUT, got %+v", spec)
+	}
+	if list := reg.list(); len(list) != 1 || list[0].Hits != 3 {
+		t.Errorf("expected one fault triggered 3 times, got %+v", list)
+	}
+	reg.clear()
+	if list := reg.list(); len(list) != 0 {
+		t.Errorf("expected no faults, got %+v", list)
+	}
+}
@@ -0,0 +1,248 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"container/list"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Open file descriptor cache (fd_cache): with fd_cache enabled, the GETs of the hot objects
+// do not open and close the objects' files - the files are kept open, up to fd_cache.size
+// of them, and shared by the GETs (which read them with pread(2) at their own offsets).
+// The least recently used files are closed first.
+//
+// A cached file is used only if the object's file is still the one that was opened (same
+// inode, size, and mtime), and so an overwritten object is never served stale. The files
+// of the deleted, renamed, and evicted objects are closed right away - so as not to hold
+// their disk space - and the entire cache is dropped when rebalance starts. A file is closed
+// only when no longer read by any of the GETs in progress.
+//
+// FD pressure: once the target's open file descriptors exceed 80% of its RLIMIT_NOFILE (or
+// an open fails with EMFILE/ENFILE) the cache is halved, and it is allowed to grow back
+// once the pressure is gone. Hits and misses are counted by get.fd.hit.n and get.fd.miss.n.
+//
+// ================================ Summary ===============================================
+
+const (
+	fdPressurePct      = 80
+	fdPressureInterval = 10 * time.Second
+)
+
+type (
+	fdEntry struct {
+		fqn   string
+		file  *os.File
+		ino   uint64
+		size  int64
+		mtime int64
+		refc  int
+		stale bool // removed from the cache while referenced: close upon release
+		elem  *list.Element
+	}
+	fdCache struct {
+		sync.Mutex
+		entries map[string]*fdEntry
+		lru     *list.List // front - most recently used
+		limit   int        // FD pressure: max number of entries (0 - not limited)
+		checked time.Time  // last FD pressure check
+		openFDs func() (open int, max uint64, err error)
+	}
+)
+
+// fdGet returns the object's cached open file or nil - to open the object the regular way
+func (t *targetrunner) fdGet(fqn string) *fdEntry {
+	e, hit := t.fdCache.acquire(fqn, ctx.config.FDCache.Size)
+	if e == nil {
+		return nil
+	}
+	if hit {
+		t.statsif.Add(stats.GetFDCacheHitCount, 1)
+	} else {
+		t.statsif.Add(stats.GetFDCacheMissCount, 1)
+	}
+	return e
+}
+
+func newFDCache() *fdCache {
+	return &fdCache{entries: make(map[string]*fdEntry), lru: list.New(), openFDs: openFDs}
+}
+
+// acquire returns the open file of the object, opening it if need be - or nil if the
+// object cannot be opened; the caller must release a non-nil entry
+func (c *fdCache) acquire(fqn string, capacity int) (e *fdEntry, hit bool) {
+	fi, err := os.Stat(fqn)
+	if err != nil {
+		c.invalidate(fqn)
+		return
+	}
+	c.Lock()
+	if e = c.entries[fqn]; e != nil {
+		if e.ino == inode(fi) && e.size == fi.Size() && e.mtime == fi.ModTime().UnixNano() {
+			e.refc++
+			c.lru.MoveToFront(e.elem)
+			c.Unlock()
+			return e, true
+		}
+		c.remove(e)
+		e = nil
+	}
+	c.Unlock()
+	c.checkPressure()
+
+	file, err := os.Open(fqn)
+	if err != nil {
+		if isErrFDLimit(err) {
+			glog.Errorf("Failed to open %s, err: %v - shrinking fd cache", fqn, err)
+			c.Lock()
+			c.shrink()
+			c.Unlock()
+		}
+		return
+	}
+	if fi, err = file.Stat(); err != nil { // the file that's been opened
+		file.Close()
+		return
+	}
+	e = &fdEntry{fqn: fqn, file: file, ino: inode(fi), size: fi.Size(), mtime: fi.ModTime().UnixNano(), refc: 1}
+
+	c.Lock()
+	if prev := c.entries[fqn]; prev != nil {
+		c.remove(prev) // opened concurrently - the newer one wins
+	}
+	e.elem = c.lru.PushFront(e)
+	c.entries[fqn] = e
+	if c.limit > 0 && c.limit < capacity {
+		capacity = c.limit
+	}
+	c.evict(capacity)
+	c.Unlock()
+	return
+}
+
+// reader returns the reader of the entire object or - if length > 0 - of its range
+func (e *fdEntry) reader(offset, length int64) io.Reader {
+	if length == 0 {
+		return io.NewSectionReader(e.file, 0, e.size)
+	}
+	return io.NewSectionReader(e.file, offset, length)
+}
+
+func (c *fdCache) release(e *fdEntry) {
+	c.Lock()
+	e.refc--
+	if e.stale && e.refc == 0 {
+		e.file.Close()
+	}
+	c.Unlock()
+}
+
+// invalidate closes the object's file (once unreferenced) - to be called when the object
+// is deleted, renamed, or evicted
+func (c *fdCache) invalidate(fqn string) {
+	c.Lock()
+	if e := c.entries[fqn]; e != nil {
+		c.remove(e)
+	}
+	c.Unlock()
+}
+
+// purge removes all entries; the referenced ones are closed upon release
+func (c *fdCache) purge() {
+	c.Lock()
+	for _, e := range c.entries {
+		c.remove(e)
+	}
+	c.Unlock()
+}
+
+// checkPressure halves the cache if the open file descriptors exceed fdPressurePct of
+// the limit, and doubles the limit back when they do not
+func (c *fdCache) checkPressure() {
+	now := time.Now()
+	c.Lock()
+	if now.Sub(c.checked) < fdPressureInterval {
+		c.Unlock()
+		return
+	}
+	c.checked = now
+	c.Unlock()
+
+	open, max, err := c.openFDs()
+	if err != nil || max == 0 {
+		return
+	}
+	c.Lock()
+	if uint64(open)*100 > max*fdPressurePct {
+		glog.Warningf("FD pressure: %d open files, limit %d - shrinking fd cache (%d)", open, max, len(c.entries))
+		c.shrink()
+	} else if c.limit > 0 {
+		c.limit *= 2
+		if c.limit > int(max) {
+			c.limit = 0
+		}
+	}
+	c.Unlock()
+}
+
+// under lock
+func (c *fdCache) shrink() {
+	c.limit = len(c.entries) / 2
+	if c.limit == 0 {
+		c.limit = 1
+	}
+	c.evict(c.limit)
+}
+
+// under lock
+func (c *fdCache) evict(capacity int) {
+	for el := c.lru.Back(); el != nil && len(c.entries) > capacity; {
+		prev := el.Prev()
+		c.remove(el.Value.(*fdEntry))
+		el = prev
+	}
+}
+
+// under lock
+func (c *fdCache) remove(e *fdEntry) {
+	delete(c.entries, e.fqn)
+	c.lru.Remove(e.elem)
+	e.stale = true
+	if e.refc == 0 {
+		e.file.Close()
+	}
+}
+
+// openFDs returns the number of the process' open file descriptors and the limit
+func openFDs() (open int, max uint64, err error) {
+	var rlimit syscall.Rlimit
+	if err = syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return
+	}
+	dir, err := os.Open(fdDir)
+	if err != nil {
+		return
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	return len(names), uint64(rlimit.Cur), err
+}
+
+func isErrFDLimit(err error) bool {
+	if perr, ok := err.(*os.PathError); ok {
+		return perr.Err == syscall.EMFILE || perr.Err == syscall.ENFILE
+	}
+	return false
+}
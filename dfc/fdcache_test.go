@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFDCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fdcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		fqn := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(fqn+".work", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(fqn+".work", fqn); err != nil {
+			t.Fatal(err)
+		}
+		return fqn
+	}
+	read := func(e *fdEntry, offset, length int64) string {
+		b, err := ioutil.ReadAll(e.reader(offset, length))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	var (
+		c        = newFDCache()
+		capacity = 2
+		open     = 10
+		fqn1     = write("o1", "0123")
+		fqn2     = write("o2", "abcd")
+	)
+	c.openFDs = func() (int, uint64, error) { return open, 100, nil }
+
+	// miss, then hit
+	e, hit := c.acquire(fqn1, capacity)
+	if e == nil || hit {
+		t.Fatalf("expected miss, got %v (hit %t)", e, hit)
+	}
+	if got := read(e, 0, 0); got != "0123" {
+		t.Errorf("expected 0123, got %q", got)
+	}
+	c.release(e)
+	e, hit = c.acquire(fqn1, capacity)
+	if e == nil || !hit {
+		t.Fatalf("expected hit, got %v (hit %t)", e, hit)
+	}
+	if got := read(e, 1, 2); got != "12" {
+		t.Errorf("expected 12, got %q", got)
+	}
+
+	// overwritten while referenced: reopened; the old file stays readable until released
+	time.Sleep(10 * time.Millisecond)
+	write("o1", "4567")
+	e2, hit := c.acquire(fqn1, capacity)
+	if e2 == nil || hit {
+		t.Fatalf("expected miss, got %v (hit %t)", e2, hit)
+	}
+	if got, old := read(e2, 0, 0), read(e, 0, 0); got != "4567" || old != "0123" {
+		t.Errorf("expected 4567 and 0123, got %q and %q", got, old)
+	}
+	c.release(e)
+	c.release(e2)
+
+	// capacity: LRU goes first
+	fqn3 := write("o3", "ABCD")
+	for _, fqn := range []string{fqn2, fqn3} {
+		if e, _ = c.acquire(fqn, capacity); e == nil {
+			t.Fatalf("expected %s open", fqn)
+		}
+		c.release(e)
+	}
+	if _, ok := c.entries[fqn1]; ok || len(c.entries) != 2 {
+		t.Errorf("expected o1 closed, got %d entries", len(c.entries))
+	}
+
+	// deleted: closed
+	c.invalidate(fqn2)
+	if _, ok := c.entries[fqn2]; ok || len(c.entries) != 1 {
+		t.Errorf("expected o2 closed, got %d entries", len(c.entries))
+	}
+
+	// FD pressure: halved, then grows back
+	open, c.checked = 90, time.Time{}
+	if e, _ = c.acquire(fqn1, capacity); e == nil {
+		t.Fatal("expected o1 open")
+	}
+	c.release(e)
+	if c.limit != 1 || len(c.entries) != 1 {
+		t.Errorf("expected the cache shrunk to 1, got limit %d, %d entries", c.limit, len(c.entries))
+	}
+	open, c.checked = 10, time.Time{}
+	c.checkPressure()
+	if c.limit != 2 {
+		t.Errorf("expected the limit to grow back to 2, got %d", c.limit)
+	}
+
+	c.purge()
+	if len(c.entries) != 0 || c.lru.Len() != 0 {
+		t.Errorf("expected empty cache, got %d entries", len(c.entries))
+	}
+}
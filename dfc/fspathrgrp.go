@@ -39,6 +39,7 @@ func (g *fsprungroup) enableMountpath(mpath string) (enabled, exists bool) {
 	}
 	glog.Infof("Re-enabled mountpath %s", mpath)
 	go g.t.runLocalRebalance()
+	g.t.requestRecount()
 
 	availablePaths, _ := fs.Mountpaths.Get()
 	if len(availablePaths) == 1 {
@@ -61,6 +62,7 @@ func (g *fsprungroup) disableMountpath(mpath string) (disabled, exists bool) {
 		r.ReqDisableMountpath(mpath)
 	}
 	glog.Infof("Disabled mountpath %s", mpath)
+	g.t.requestRecount()
 
 	availablePaths, _ := fs.Mountpaths.Get()
 	if len(availablePaths) > 0 {
@@ -94,6 +96,7 @@ func (g *fsprungroup) addMountpath(mpath string) (err error) {
 		r.ReqAddMountpath(mpath)
 	}
 	go g.t.runLocalRebalance()
+	g.t.requestRecount()
 
 	availablePaths, _ := fs.Mountpaths.Get()
 	if len(availablePaths) > 1 {
@@ -117,6 +120,7 @@ func (g *fsprungroup) removeMountpath(mpath string) (err error) {
 	for _, r := range g.runners {
 		r.ReqRemoveMountpath(mpath)
 	}
+	g.t.requestRecount()
 
 	availablePaths, _ := fs.Mountpaths.Get()
 	if len(availablePaths) > 0 {
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -189,8 +190,8 @@ func (gcpimpl *gcpimpl) listbucket(ct context.Context, bucket string, msg *cmn.G
 	var query *storage.Query
 	var pageToken string
 
-	if msg.GetPrefix != "" {
-		query = &storage.Query{Prefix: msg.GetPrefix}
+	if msg.GetPrefix != "" || msg.GetDelimiter != "" {
+		query = &storage.Query{Prefix: msg.GetPrefix, Delimiter: msg.GetDelimiter}
 	}
 	if msg.GetPageMarker != "" {
 		pageToken = msg.GetPageMarker
@@ -212,6 +213,11 @@ func (gcpimpl *gcpimpl) listbucket(ct context.Context, bucket string, msg *cmn.G
 	var reslist = cmn.BucketList{Entries: make([]*cmn.BucketEntry, 0, initialBucketListSize)}
 	reslist.PageMarker = nextPageToken
 	for _, attrs := range objs {
+		// with delimiter, a common prefix is returned as ObjectAttrs with only Prefix set
+		if attrs.Prefix != "" {
+			reslist.Entries = append(reslist.Entries, &cmn.BucketEntry{Name: attrs.Prefix, Type: cmn.BucketEntryDir})
+			continue
+		}
 		entry := &cmn.BucketEntry{}
 		entry.Name = attrs.Name
 		if strings.Contains(msg.GetProps, cmn.GetPropsSize) {
@@ -324,7 +330,11 @@ func (gcpimpl *gcpimpl) headobject(ct context.Context, bucket string, objname st
 		return
 	}
 	objmeta[cmn.HeaderCloudProvider] = cmn.ProviderGoogle
+	objmeta["size"] = strconv.FormatInt(attrs.Size, 10)
 	objmeta["version"] = fmt.Sprintf("%d", attrs.Generation)
+	if v := newcksumvalue(attrs.Metadata[gcpDfcHashType], attrs.Metadata[gcpDfcHashVal]); v != nil {
+		objmeta[cmn.HeaderDFCChecksumType], objmeta[cmn.HeaderDFCChecksumVal] = v.get()
+	}
 	return
 }
 
@@ -348,6 +358,21 @@ func (gcpimpl *gcpimpl) getobj(ct context.Context, fqn string, bucket string, ob
 	}
 	v = newcksumvalue(attrs.Metadata[gcpDfcHashType], attrs.Metadata[gcpDfcHashVal])
 	md5 := hex.EncodeToString(attrs.MD5)
+	if parallelColdGet(attrs.Size) {
+		// ranged reads of the same generation (see coldparallel.go)
+		o = o.Generation(attrs.Generation)
+		get := func(off, length int64) (io.ReadCloser, error) {
+			return o.NewRangeReader(gctx, off, length)
+		}
+		props = &objectProps{version: fmt.Sprintf("%d", attrs.Generation), size: attrs.Size}
+		if props.nhobj, errstr = gcpimpl.t.receiveParallel(fqn, bucket, objname, md5, v, attrs.Size, get); errstr != "" {
+			return
+		}
+		if glog.V(4) {
+			glog.Infof("GET %s/%s", bucket, objname)
+		}
+		return
+	}
 	rc, err := o.NewReader(gctx)
 	if err != nil {
 		errstr = fmt.Sprintf("The object %s/%s either does not exist or is not accessible, err: %v", bucket, objname, err)
@@ -366,6 +391,32 @@ func (gcpimpl *gcpimpl) getobj(ct context.Context, fqn string, bucket string, ob
 	return
 }
 
+func (gcpimpl *gcpimpl) getobjReader(ct context.Context, bucket, objname string, objmeta cmn.SimpleKVs, offset, length int64) (r io.ReadCloser, props *objectProps, errstr string, errcode int) {
+	gcpclient, gctx, _, errstr := createClient(ct)
+	if errstr != "" {
+		return
+	}
+	o := gcpclient.Bucket(bucket).Object(objname)
+	generation, err := strconv.ParseInt(objmeta["version"], 10, 64)
+	if err != nil {
+		errstr = fmt.Sprintf("Failed to GET %s/%s: invalid generation %q", bucket, objname, objmeta["version"])
+		return
+	}
+	props = &objectProps{version: objmeta["version"]}
+	if length == 0 {
+		length = -1 // to the end
+		props.nhobj = newcksumvalue(objmeta[cmn.HeaderDFCChecksumType], objmeta[cmn.HeaderDFCChecksumVal])
+	}
+	rc, err := o.Generation(generation).NewRangeReader(gctx, offset, length)
+	if err != nil {
+		errcode = gcpErrorToHTTP(err)
+		errstr = fmt.Sprintf("Failed to GET %s/%s, err: %v", bucket, objname, err)
+		return
+	}
+	props.size = rc.Remain()
+	return rc, props, "", 0
+}
+
 func (gcpimpl *gcpimpl) putobj(ct context.Context, file *os.File, bucket, objname string, ohash cksumvalue) (version string, errstr string, errcode int) {
 	var (
 		htype, hval string
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Hot objects: GET /v1/daemon?what=hotobjects returns the target's most frequently accessed
+// objects of each mountpath - the hottest of the atime heat map, with the approximate
+// access counts (atime.Runner.AccessCount), the same counts that HEAD reports in the
+// DfcObjAccessCount header. GET /v1/cluster?what=hotobjects merges the targets' results
+// into the cluster's top N - the counts of the same object on different targets (e.g.,
+// while rebalancing) are summed. Targets that do not respond are skipped (and logged).
+//
+// ================================ Summary ===============================================
+
+const (
+	hotObjectsTopN    = 10 // default topn
+	hotObjectsMaxTopN = 1000
+)
+
+func parseTopN(query url.Values) (topN int, errstr string) {
+	s := query.Get(cmn.URLParamTopN)
+	if s == "" {
+		return hotObjectsTopN, ""
+	}
+	topN, err := strconv.Atoi(s)
+	if err != nil || topN <= 0 || topN > hotObjectsMaxTopN {
+		return 0, fmt.Sprintf("Invalid %s %q: expecting a number in the range [1, %d]", cmn.URLParamTopN, s, hotObjectsMaxTopN)
+	}
+	return topN, ""
+}
+
+// hotObjects returns up to topN hottest objects of each mountpath
+func (t *targetrunner) hotObjects(topN int) cmn.TargetHotObjects {
+	var (
+		availablePaths, _ = fs.Mountpaths.Get()
+		atimer            = getatimerunner()
+		out               = make(cmn.TargetHotObjects, len(availablePaths))
+	)
+	for mpath := range availablePaths {
+		hot := atimer.HotObjects(mpath, topN)
+		objects := make([]cmn.HotObject, 0, len(hot))
+		for _, obj := range hot {
+			bucket, objname, err := cluster.ResolveFQN(obj.FQN, t.bmdowner)
+			if err != nil {
+				continue // removed or misplaced
+			}
+			count := int64(atimer.AccessCount(obj.FQN))
+			objects = append(objects, cmn.HotObject{Bucket: bucket, Objname: objname, Count: count})
+		}
+		sortHotObjects(objects)
+		out[mpath] = objects
+	}
+	return out
+}
+
+func (p *proxyrunner) invokeHttpGetClusterHotObjects(w http.ResponseWriter, r *http.Request) bool {
+	topN, errstr := parseTopN(r.URL.Query())
+	if errstr != "" {
+		p.invalmsghdlr(w, r, errstr)
+		return false
+	}
+	var (
+		smap   = p.smapowner.get()
+		query  = url.Values{}
+		counts = make(map[string]*cmn.HotObject)
+		out    = &cmn.ClusterHotObjects{Targets: make(map[string]cmn.TargetHotObjects, len(smap.Tmap))}
+	)
+	query.Add(cmn.URLParamWhat, cmn.GetWhatHotObjects)
+	query.Add(cmn.URLParamTopN, strconv.Itoa(topN))
+	results := p.broadcastDash(cmn.URLPath(cmn.Version, cmn.Daemon), query, smap.Tmap)
+	for res := range results {
+		if res.err != nil {
+			glog.Errorf("Failed to get %s hot objects: %s", res.si, res.errstr)
+			continue
+		}
+		hot := make(cmn.TargetHotObjects)
+		if err := jsoniter.Unmarshal(res.outjson, &hot); err != nil {
+			glog.Errorf("Failed to unmarshal %s hot objects, err: %v", res.si, err)
+			continue
+		}
+		out.Targets[res.si.DaemonID] = hot
+		for _, objects := range hot {
+			for _, obj := range objects {
+				uname := cluster.Uname(obj.Bucket, obj.Objname)
+				if c, ok := counts[uname]; ok {
+					c.Count += obj.Count
+				} else {
+					obj := obj
+					counts[uname] = &obj
+				}
+			}
+		}
+	}
+	out.Objects = make([]cmn.HotObject, 0, len(counts))
+	for _, obj := range counts {
+		out.Objects = append(out.Objects, *obj)
+	}
+	sortHotObjects(out.Objects)
+	if len(out.Objects) > topN {
+		out.Objects = out.Objects[:topN]
+	}
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterHotObjects")
+}
+
+// sortHotObjects sorts the hottest first and, for the same count, by name
+func sortHotObjects(objects []cmn.HotObject) {
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Count != objects[j].Count {
+			return objects[i].Count > objects[j].Count
+		}
+		if objects[i].Bucket != objects[j].Bucket {
+			return objects[i].Bucket < objects[j].Bucket
+		}
+		return objects[i].Objname < objects[j].Objname
+	})
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestParseTopN(t *testing.T) {
+	tests := map[string]int{"": hotObjectsTopN, "1": 1, "1000": 1000, "0": -1, "-5": -1, "1001": -1, "abc": -1}
+	for s, expected := range tests {
+		query := url.Values{}
+		if s != "" {
+			query.Set(cmn.URLParamTopN, s)
+		}
+		topN, errstr := parseTopN(query)
+		if expected < 0 {
+			if errstr == "" {
+				t.Errorf("%q: expected error, got %d", s, topN)
+			}
+		} else if errstr != "" || topN != expected {
+			t.Errorf("%q: expected %d, got %d (%s)", s, expected, topN, errstr)
+		}
+	}
+}
+
+func TestSortHotObjects(t *testing.T) {
+	objects := []cmn.HotObject{
+		{Bucket: "b", Objname: "x", Count: 3},
+		{Bucket: "a", Objname: "y", Count: 7},
+		{Bucket: "a", Objname: "z", Count: 3},
+		{Bucket: "a", Objname: "x", Count: 3},
+	}
+	sortHotObjects(objects)
+	expected := []string{"a/y", "a/x", "a/z", "b/x"}
+	for i, obj := range objects {
+		if name := obj.Bucket + "/" + obj.Objname; name != expected[i] {
+			t.Errorf("%d: expected %s, got %s", i, expected[i], name)
+		}
+	}
+}
@@ -51,8 +51,11 @@ type (
 	objectProps struct {
 		version string
 		atime   time.Time
+		expires time.Time // see objttl.go
 		size    int64
 		nhobj   cksumvalue
+		// async checksum: the checksum is yet to be computed (see cksumasync.go)
+		cksumPending bool
 	}
 
 	// callResult contains http response
@@ -98,11 +101,11 @@ type (
 	}
 )
 
-//===========
+// ===========
 //
 // interfaces
 //
-//===========
+// ===========
 const initialBucketListSize = 512
 
 type cloudif interface {
@@ -113,6 +116,10 @@ type cloudif interface {
 	headobject(ctx context.Context, bucket string, objname string) (objmeta cmn.SimpleKVs, errstr string, errcode int)
 	//
 	getobj(ctx context.Context, fqn, bucket, objname string) (props *objectProps, errstr string, errcode int)
+	// getobjReader opens the object, or its range (zero length - to the end), for reading -
+	// see passthrough.go; objmeta is the object's headobject, and the version read is pinned
+	// to objmeta's; props.size is the size of what is read
+	getobjReader(ctx context.Context, bucket, objname string, objmeta cmn.SimpleKVs, offset, length int64) (r io.ReadCloser, props *objectProps, errstr string, errcode int)
 	putobj(ctx context.Context, file *os.File, bucket, objname string, ohobj cksumvalue) (version string, errstr string, errcode int)
 	deleteobj(ctx context.Context, bucket, objname string) (errstr string, errcode int)
 }
@@ -146,11 +153,11 @@ func copyHeaders(src http.Header, dst *http.Header) {
 	}
 }
 
-//===========================================================================
+// ===========================================================================
 //
 // http runner
 //
-//===========================================================================
+// ===========================================================================
 type glogwriter struct {
 }
 
@@ -178,14 +185,17 @@ type httprunner struct {
 	intraDataServer       *netServer
 	glogger               *log.Logger
 	si                    *cluster.Snode
-	httpclient            *http.Client // http client for intra-cluster comm
-	httpclientLongTimeout *http.Client // http client for long-wait intra-cluster comm
+	intra                 *cmn.IntraClient // http client for intra-cluster comm
+	httpclientLongTimeout *http.Client     // http client for other clusters (next tier, replication)
 	keepalive             keepaliver
 	smapowner             *smapowner
 	bmdowner              *bmdowner
 	xactinp               *xactInProgress
 	statsif               stats.Tracker
-	statsdC               statsd.Client
+	metrics               stats.MetricsSink // see initMetrics
+	maint                 maintState        // maintenance mode (maintenance.go)
+	onInit                func()            // reports the runner initialized - see rungroup.run
+	initOnce              sync.Once
 }
 
 func (server *netServer) listenAndServe(addr string, logger *log.Logger) error {
@@ -220,10 +230,31 @@ func (server *netServer) shutdown() {
 	cancel()
 }
 
+// registerPublicNetHandler registers the v1 path along with its v2 counterpart (see apiV2);
+// all requests are assigned IDs (see withRequestID)
 func (h *httprunner) registerPublicNetHandler(path string, handler func(http.ResponseWriter, *http.Request)) {
-	h.publicServer.mux.HandleFunc(path, handler)
+	h.publicServer.mux.HandleFunc(path, h.withRequestID(handler))
 	if !strings.HasSuffix(path, "/") {
-		h.publicServer.mux.HandleFunc(path+"/", handler)
+		h.publicServer.mux.HandleFunc(path+"/", h.withRequestID(handler))
+	}
+	if v1 := "/" + cmn.Version + "/"; strings.HasPrefix(path, v1) {
+		h.registerPublicNetHandler("/"+cmn.VersionV2+"/"+strings.TrimPrefix(path, v1), apiV2(handler))
+	}
+}
+
+// apiV2 is the compatibility shim that serves the REST API v2 with the v1 handlers: the
+// request is marked as v2 (cmn.IsAPIV2) and has its path rewritten to v1; the handlers
+// (and helpers, e.g. cmn.InvalidHandlerDetailed) that implement the v2 behavior check
+// the mark. Redirects carry the v1 path, while the v2 clients keep sending the mark.
+func apiV2(handler http.HandlerFunc) http.HandlerFunc {
+	v1, v2 := "/"+cmn.Version, "/"+cmn.VersionV2
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set(cmn.HeaderDFCAPIVersion, cmn.VersionV2)
+		r.URL.Path = v1 + strings.TrimPrefix(r.URL.Path, v2)
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = v1 + strings.TrimPrefix(r.URL.RawPath, v2)
+		}
+		handler(w, r)
 	}
 }
 
@@ -247,16 +278,14 @@ func (h *httprunner) init(s stats.Tracker, isproxy bool) {
 		ctx.config.Proxy.PrimaryURL = clivars.proxyurl
 	}
 	h.statsif = s
+	h.maint.started = time.Now()
 	// http client
 	perhost := targetMaxIdleConnsPer
 	if isproxy {
 		perhost = proxyMaxIdleConnsPer
 	}
 
-	h.httpclient = &http.Client{
-		Transport: h.createTransport(perhost, 0),
-		Timeout:   ctx.config.Timeout.Default, // defaultTimeout
-	}
+	h.intra = newIntraClient(perhost)
 	h.httpclientLongTimeout = &http.Client{
 		Transport: h.createTransport(perhost, 0),
 		Timeout:   ctx.config.Timeout.DefaultLong, // longTimeout
@@ -330,17 +359,43 @@ func (h *httprunner) initSI() {
 	}
 
 	daemonID := os.Getenv("DFCDAEMONID")
+	if daemonID == "" && clivars.role == xtarget {
+		daemonID = loadDaemonID() // stable across restarts - see daemonid.go
+	}
 	if daemonID == "" {
 		cs := xxhash.ChecksumString32S(publicAddr.String(), cluster.MLCG32)
 		daemonID = strconv.Itoa(int(cs & 0xfffff))
 		if testingFSPpaths() {
 			daemonID += ":" + ctx.config.Net.L4.PortStr
 		}
+		if clivars.role == xtarget {
+			if err := persistDaemonID(daemonID); err != nil {
+				glog.Errorln(err)
+			}
+		}
 	}
 
 	h.si = newSnode(daemonID, ctx.config.Net.HTTP.Proto, publicAddr, intraControlAddr, intraDataAddr)
 }
 
+// newIntraClient creates the intra-cluster client as per netconfig.http.intra_client and
+// the timeouts; perhost is the default number of idle connections per peer
+func newIntraClient(perhost int) *cmn.IntraClient {
+	conf := &ctx.config.Net.HTTP.IntraClient
+	if conf.IdleConnsPerPeer > 0 {
+		perhost = conf.IdleConnsPerPeer
+	}
+	return cmn.NewIntraClient(cmn.IntraClientArgs{
+		IdleConnsPerPeer: perhost,
+		DialTimeout:      conf.DialTimeout,
+		Timeout:          ctx.config.Timeout.Default,     // defaultTimeout
+		LongTimeout:      ctx.config.Timeout.DefaultLong, // longTimeout
+		Retries:          conf.Retries,
+		RetryDelay:       conf.RetryDelay,
+		UseHTTPS:         ctx.config.Net.HTTP.UseHTTPS,
+	})
+}
+
 func (h *httprunner) createTransport(perhost, numDaemons int) *http.Transport {
 	defaultTransport := http.DefaultTransport.(*http.Transport)
 	transport := &http.Transport{
@@ -405,11 +460,24 @@ func (h *httprunner) run() error {
 	return h.publicServer.listenAndServe(addr, h.glogger)
 }
 
+func (h *httprunner) setOnInit(f func()) { h.onInit = f }
+
+// inited reports (once) that Run() has completed initialization - see rungroup.status
+func (h *httprunner) inited() {
+	h.initOnce.Do(func() {
+		if h.onInit != nil {
+			h.onInit()
+		}
+	})
+}
+
 // stop gracefully
 func (h *httprunner) stop(err error) {
 	glog.Infof("Stopping %s, err: %v", h.Getname(), err)
 
-	h.statsdC.Close()
+	if h.metrics != nil {
+		h.metrics.Close()
+	}
 	if h.publicServer.s == nil {
 		return
 	}
@@ -440,11 +508,11 @@ func (h *httprunner) stop(err error) {
 	wg.Wait()
 }
 
-//=================================
+// =================================
 //
 // intra-cluster IPC, control plane
 //
-//=================================
+// =================================
 // call another target or a proxy
 // optionally, include a json-encoded body
 func (h *httprunner) call(args callArgs) callResult {
@@ -492,22 +560,19 @@ func (h *httprunner) call(args callArgs) callResult {
 		return callResult{args.si, outjson, err, errstr, status}
 	}
 
+	request.Header.Set("Accept", "text/plain") // errors as plain text, to be relayed as is (see cmn.WantsText)
 	copyHeaders(args.req.header, &request.Header)
 	switch args.timeout {
 	case defaultTimeout:
-		response, err = h.httpclient.Do(request)
+		response, err = h.intra.Do(request, false)
 	case longTimeout:
-		response, err = h.httpclientLongTimeout.Do(request)
+		response, err = h.intra.Do(request, true)
 	default:
 		contextwith, cancel := context.WithTimeout(context.Background(), args.timeout)
 		defer cancel() // timeout => context.deadlineExceededError
 		newRequest := request.WithContext(contextwith)
 		copyHeaders(args.req.header, &newRequest.Header)
-		if args.timeout > h.httpclient.Timeout {
-			response, err = h.httpclientLongTimeout.Do(newRequest)
-		} else {
-			response, err = h.httpclient.Do(newRequest)
-		}
+		response, err = h.intra.Do(newRequest, args.timeout > h.intra.Timeout())
 	}
 	if err != nil {
 		if response != nil && response.StatusCode > 0 {
@@ -645,8 +710,9 @@ func (h *httprunner) readJSON(w http.ResponseWriter, r *http.Request, out interf
 }
 
 // NOTE: must be the last error-generating-and-handling call in the http handler
-//       writes http body and header
-//       calls invalmsghdlr() on err
+//
+//	writes http body and header
+//	calls invalmsghdlr() on err
 func (h *httprunner) writeJSON(w http.ResponseWriter, r *http.Request, jsbytes []byte, tag string) (ok bool) {
 	w.Header().Set("Content-Type", "application/json")
 	var err error
@@ -688,11 +754,11 @@ func (h *httprunner) validatebckname(w http.ResponseWriter, r *http.Request, buc
 	return true
 }
 
-//=========================
+// =========================
 //
 // common http req handlers
 //
-//==========================
+// ==========================
 func (h *httprunner) httpdaeget(w http.ResponseWriter, r *http.Request) {
 	var (
 		jsbytes []byte
@@ -718,6 +784,21 @@ func (h *httprunner) httpdaeget(w http.ResponseWriter, r *http.Request) {
 	case cmn.GetWhatDaemonInfo:
 		jsbytes, err = jsoniter.Marshal(h.si)
 		cmn.Assert(err == nil, err)
+	case cmn.GetWhatRunners:
+		jsbytes, err = jsoniter.Marshal(ctx.rg.status())
+		cmn.Assert(err == nil, err)
+	case cmn.GetWhatVersions:
+		jsbytes, err = jsoniter.Marshal(cmn.APIVersions)
+		cmn.Assert(err == nil, err)
+	case cmn.GetWhatKeepalive:
+		jsbytes, err = jsoniter.Marshal(h.keepalive.latency())
+		cmn.Assert(err == nil, err)
+	case cmn.GetWhatIntraStats:
+		jsbytes, err = jsoniter.Marshal(h.intraStats())
+		cmn.Assert(err == nil, err)
+	case cmn.GetWhatMaint:
+		jsbytes, err = jsoniter.Marshal(h.maint.status())
+		cmn.Assert(err == nil, err)
 	default:
 		s := fmt.Sprintf("Invalid GET /daemon request: unrecognized what=%s", getWhat)
 		h.invalmsghdlr(w, r, s)
@@ -726,8 +807,32 @@ func (h *httprunner) httpdaeget(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
 }
 
+// intraStats returns the intra-cluster client's stats by peer host:port, with the daemon
+// IDs of the peers that are in the cluster map
+func (h *httprunner) intraStats() map[string]*cmn.IntraPeerStats {
+	var (
+		out  = h.intra.Stats()
+		smap = h.smapowner.get()
+	)
+	if smap == nil {
+		return out
+	}
+	for _, daemons := range []map[string]*cluster.Snode{smap.Pmap, smap.Tmap} {
+		for sid, si := range daemons {
+			for _, ni := range []cluster.NetInfo{si.PublicNet, si.IntraControlNet, si.IntraDataNet} {
+				if u, err := url.Parse(ni.DirectURL); err == nil {
+					if s, ok := out[u.Host]; ok {
+						s.Peer = sid
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
 func (h *httprunner) setconfig(name, value string) (errstr string) {
-	lm, hm := ctx.config.LRU.LowWM, ctx.config.LRU.HighWM
+	lm, hm, cm := ctx.config.LRU.LowWM, ctx.config.LRU.HighWM, ctx.config.Disk.CriticalWM
 	checkwm := false
 	atoi := func(value string) (int64, error) {
 		v, err := strconv.Atoi(value)
@@ -748,6 +853,257 @@ func (h *httprunner) setconfig(name, value string) (errstr string) {
 		} else {
 			ctx.config.Periodic.StatsTime, ctx.config.Periodic.StatsTimeStr = v, value
 		}
+	case "stats_latency_unit":
+		if v, err := stats.ParseLatencyUnit(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse stats_latency_unit, err: %v", err)
+		} else {
+			ctx.config.Periodic.StatsLatencyUnit, ctx.config.Periodic.StatsLatencyUnitStr = v, value
+		}
+	case "stats_log_full":
+		if v, err := strconv.Atoi(value); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse stats_log_full, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.Periodic.StatsLogFull = v
+		}
+	case "stats_log_level":
+		if v, err := stats.ParseStatsLogLevel(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse stats_log_level, err: %v", err)
+		} else {
+			ctx.config.Periodic.StatsLogLevel, ctx.config.Periodic.StatsLogLevelStr = v, value
+		}
+	case "stats_log_format":
+		if err := stats.ValidateStatsLogFormat(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse stats_log_format, err: %v", err)
+		} else {
+			ctx.config.Periodic.StatsLogFormat = value
+		}
+	case "stats_log_file":
+		ctx.config.Periodic.StatsLogFile = value
+	case "stats_log_max_size":
+		if v, err := strconv.ParseInt(value, 10, 64); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse stats_log_max_size, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.Periodic.StatsLogMaxSize = v
+		}
+	case "cold_get_part_size":
+		if v, err := parseColdGetPartSize(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.ColdGet.PartSize, ctx.config.ColdGet.PartSizeStr = v, value
+		}
+	case "cold_get_concurrency":
+		if v, err := strconv.Atoi(value); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse cold_get_concurrency, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.ColdGet.Concurrency = v
+		}
+	case "iostat_history":
+		if v, err := parseIostatHistory(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.Periodic.IostatHistory, ctx.config.Periodic.IostatHistoryStr = v, value
+		}
+	case "stats_history":
+		if v, err := parseStatsHistory(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.Periodic.StatsHistory, ctx.config.Periodic.StatsHistoryStr = v, value
+		}
+	case "reserved_space":
+		if bytes, pct, err := parseReservedSpace(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.Disk.ReservedSpace, ctx.config.Disk.ReservedSpacePct = bytes, pct
+			ctx.config.Disk.ReservedSpaceStr = value
+		}
+	case "sync_group_time":
+		if v, err := parseSyncGroupTime(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.Disk.SyncGroupTime, ctx.config.Disk.SyncGroupTimeStr = v, value
+		}
+	case "prune_dirs_time":
+		if v, err := parsePruneDirsTime(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.Disk.PruneDirsTime, ctx.config.Disk.PruneDirsTimeStr = v, value
+		}
+	case "mmap_read":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse mmap_read, err: %v", err)
+		} else {
+			ctx.config.MmapRead.Enabled = v
+		}
+	case "fd_cache":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse fd_cache, err: %v", err)
+		} else {
+			ctx.config.FDCache.Enabled = v
+		}
+	case "md_cache":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse md_cache, err: %v", err)
+		} else {
+			ctx.config.MDCache.Enabled = v
+		}
+	case "md_cache_snapshot_time":
+		if v, err := parseMDCacheSnapshotTime(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.MDCache.SnapshotTime, ctx.config.MDCache.SnapshotTimeStr = v, value
+		}
+	case "atime_journal_sync_time":
+		if v, err := parseAtimeJournalSyncTime(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.AtimeJournal.SyncTime, ctx.config.AtimeJournal.SyncTimeStr = v, value
+		}
+	case "atime_drain":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse atime_drain, err: %v", err)
+		} else {
+			ctx.config.AtimeDrain.Enabled = v
+		}
+	case "atime_flush_rate":
+		if v, err := strconv.ParseInt(value, 10, 64); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse atime_flush_rate, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.AtimeFlush.Rate = v
+		}
+	case "qos":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse qos, err: %v", err)
+		} else {
+			ctx.config.QoS.Enabled = v
+		}
+	case "qos_iops":
+		if v, err := strconv.ParseInt(value, 10, 64); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse qos_iops, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.QoS.IOPS = v
+		}
+	case "qos_mbps":
+		if v, err := strconv.ParseInt(value, 10, 64); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse qos_mbps, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.QoS.MBps = v
+		}
+	case "smart":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse smart, err: %v", err)
+		} else {
+			ctx.config.SMART.Enabled = v
+		}
+	case "smart_interval":
+		if v, err := parseSMARTInterval(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.SMART.Interval, ctx.config.SMART.IntervalStr = v, value
+		}
+	case "smart_reallocated_sectors":
+		if v, err := strconv.ParseInt(value, 10, 64); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse smart_reallocated_sectors, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.SMART.ReallocatedLimit = v
+		}
+	case "smart_media_errors":
+		if v, err := strconv.ParseInt(value, 10, 64); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse smart_media_errors, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.SMART.MediaErrorsLimit = v
+		}
+	case "smart_temperature":
+		if v, err := strconv.ParseInt(value, 10, 64); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse smart_temperature, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.SMART.TemperatureLimit = v
+		}
+	case "endpoint_limit_list":
+		if v, err := strconv.Atoi(value); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse endpoint_limit_list, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.EndpointLimits.List = v
+		}
+	case "endpoint_limit_stats":
+		if v, err := strconv.Atoi(value); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse endpoint_limit_stats, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.EndpointLimits.Stats = v
+		}
+	case "endpoint_limit_xaction":
+		if v, err := strconv.Atoi(value); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Failed to parse endpoint_limit_xaction, value %s must be a non-negative number", value)
+		} else {
+			ctx.config.EndpointLimits.Xaction = v
+		}
+	case "endpoint_limit_queue_timeout":
+		if v, err := parseEndpointQueueTimeout(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.EndpointLimits.QueueTimeout, ctx.config.EndpointLimits.QueueTimeoutStr = v, value
+		}
+	case "atime_drain_timeout":
+		if v, err := parseAtimeDrainTimeout(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.AtimeDrain.Timeout, ctx.config.AtimeDrain.TimeoutStr = v, value
+		}
+	case "direct_io":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse direct_io, err: %v", err)
+		} else {
+			ctx.config.DirectIO.Enabled = v
+		}
+	case "compression":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse compression, err: %v", err)
+		} else {
+			ctx.config.Compression.Enabled = v
+		}
+	case "compression_cold_time":
+		if v, err := time.ParseDuration(value); err != nil || v <= 0 {
+			errstr = fmt.Sprintf("Invalid compression_cold_time %q: expecting positive duration, e.g. 720h", value)
+		} else {
+			ctx.config.Compression.ColdTime, ctx.config.Compression.ColdTimeStr = v, value
+		}
+	case "obj_ttl_run_time":
+		if v, err := parseObjTTLRunTime(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.ObjTTL.RunTime, ctx.config.ObjTTL.RunTimeStr = v, value
+		}
+	case "put_dedup_ttl":
+		if v, err := parsePutDedupTTL(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.PutDedup.TTL, ctx.config.PutDedup.TTLStr = v, value
+		}
+	case "list_cache_ttl":
+		if v, err := parseListCacheTTL(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.ListCache.TTL, ctx.config.ListCache.TTLStr = v, value
+		}
+	case "keepalive_suspect_after":
+		if v, err := strconv.Atoi(value); err != nil {
+			errstr = fmt.Sprintf("Failed to convert keepalive_suspect_after, err: %v", err)
+		} else if v, err = parseSuspectAfter(v); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.KeepaliveTracker.SuspectAfter = v
+		}
+	case "keepalive_rebalance_grace":
+		if v, err := parseRebalanceGrace(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.KeepaliveTracker.RebalanceGrace, ctx.config.KeepaliveTracker.RebalanceGraceStr = v, value
+		}
+	case "keepalive_target_alert_only":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse keepalive_target_alert_only, err: %v", err)
+		} else {
+			ctx.config.KeepaliveTracker.TargetAlertOnly = v
+		}
 	case "dont_evict_time":
 		if v, err := time.ParseDuration(value); err != nil {
 			errstr = fmt.Sprintf("Failed to parse dont_evict_time, err: %v", err)
@@ -808,18 +1164,42 @@ func (h *httprunner) setconfig(name, value string) (errstr string) {
 		} else {
 			ctx.config.LRU.HighWM, checkwm = v, true
 		}
+	case "critical_wm":
+		if v, err := atoi(value); err != nil {
+			errstr = fmt.Sprintf("Failed to convert critical_wm, err: %v", err)
+		} else {
+			ctx.config.Disk.CriticalWM, checkwm = v, true
+		}
 	case "lru_enabled":
 		if v, err := strconv.ParseBool(value); err != nil {
 			errstr = fmt.Sprintf("Failed to parse lru_enabled, err: %v", err)
 		} else {
 			ctx.config.LRU.LRUEnabled = v
 		}
+	case "hot_access_count":
+		if v, err := strconv.ParseInt(value, 10, 64); err != nil || v < 0 {
+			errstr = fmt.Sprintf("Invalid hot_access_count %q: expecting a non-negative integer", value)
+		} else {
+			ctx.config.LRU.HotAccessCount = v
+		}
 	case "rebalancing_enabled":
 		if v, err := strconv.ParseBool(value); err != nil {
 			errstr = fmt.Sprintf("Failed to parse rebalancing_enabled, err: %v", err)
 		} else {
 			ctx.config.Rebalance.Enabled = v
 		}
+	case "rebalance_require_confirmation":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse rebalance_require_confirmation, err: %v", err)
+		} else {
+			ctx.config.Rebalance.RequireConfirm = v
+		}
+	case "rebalance_order":
+		if v, err := parseRebOrder(value); err != nil {
+			errstr = err.Error()
+		} else {
+			ctx.config.Rebalance.Order = v
+		}
 	case "replicate_on_cold_get":
 		if v, err := strconv.ParseBool(value); err != nil {
 			errstr = fmt.Sprintf("Failed to parse replicate_on_cold_get, err: %v", err)
@@ -856,6 +1236,12 @@ func (h *httprunner) setconfig(name, value string) (errstr string) {
 		} else {
 			ctx.config.Cksum.EnableReadRangeChecksum = v
 		}
+	case "async_checksum_put":
+		if v, err := strconv.ParseBool(value); err != nil {
+			errstr = fmt.Sprintf("Failed to parse async_checksum_put, err: %v", err)
+		} else {
+			ctx.config.Cksum.AsyncPut = v
+		}
 	case "validate_version_warm_get":
 		if v, err := strconv.ParseBool(value); err != nil {
 			errstr = fmt.Sprintf("Failed to parse validate_version_warm_get, err: %v", err)
@@ -863,10 +1249,11 @@ func (h *httprunner) setconfig(name, value string) (errstr string) {
 			ctx.config.Ver.ValidateWarmGet = v
 		}
 	case "checksum":
-		if value == cmn.ChecksumXXHash || value == cmn.ChecksumNone {
+		if cmn.ValidCksumType(value) || value == cmn.ChecksumNone {
 			ctx.config.Cksum.Checksum = value
 		} else {
-			return fmt.Sprintf("Invalid %s type %s - expecting %s or %s", name, value, cmn.ChecksumXXHash, cmn.ChecksumNone)
+			return fmt.Sprintf("Invalid %s type %s - expecting %s, %s, or %s", name, value,
+				cmn.ChecksumXXHash, cmn.ChecksumCRC32C, cmn.ChecksumNone)
 		}
 	case "versioning":
 		if err := validateVersion(value); err == nil {
@@ -885,9 +1272,10 @@ func (h *httprunner) setconfig(name, value string) (errstr string) {
 	}
 	if checkwm {
 		hwm, lwm := ctx.config.LRU.HighWM, ctx.config.LRU.LowWM
-		if hwm <= 0 || lwm <= 0 || hwm < lwm || lwm > 100 || hwm > 100 {
-			ctx.config.LRU.LowWM, ctx.config.LRU.HighWM = lm, hm
-			errstr = fmt.Sprintf("Invalid LRU watermarks %+v", ctx.config.LRU)
+		cwm := ctx.config.Disk.CriticalWM
+		if hwm <= 0 || lwm <= 0 || hwm < lwm || lwm > 100 || hwm > 100 || (cwm != 0 && (cwm <= hwm || cwm > 100)) {
+			ctx.config.LRU.LowWM, ctx.config.LRU.HighWM, ctx.config.Disk.CriticalWM = lm, hm, cm
+			errstr = fmt.Sprintf("Invalid LRU watermarks %+v, critical_wm %d", ctx.config.LRU, cwm)
 		}
 	}
 	return
@@ -901,14 +1289,25 @@ func (h *httprunner) setconfig(name, value string) (errstr string) {
 
 func (h *httprunner) invalmsghdlr(w http.ResponseWriter, r *http.Request, msg string, errCode ...int) {
 	cmn.InvalidHandlerDetailed(w, r, msg, errCode...)
-	h.statsif.AddErrorHTTP(r.Method, 1)
+	h.statsif.AddBucket(requestBucket(r), stats.ErrorHTTP(r.Method, 1))
 }
 
-//=====================
+// requestBucket returns the bucket of the object or bucket request - /v1/objects/bucket/...
+// or /v1/buckets/bucket; empty otherwise
+func requestBucket(r *http.Request) string {
+	items := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 4)
+	if len(items) < 3 || items[0] != cmn.Version || (items[1] != cmn.Objects && items[1] != cmn.Buckets) ||
+		items[2] == "*" /* bucket names */ {
+		return ""
+	}
+	return items[2]
+}
+
+// =====================
 //
 // metasync Rx handlers
 //
-//=====================
+// =====================
 func (h *httprunner) extractSmap(payload cmn.SimpleKVs) (newsmap *smapX, msg *cmn.ActionMsg, errstr string) {
 	if _, ok := payload[smaptag]; !ok {
 		return
@@ -1041,18 +1440,26 @@ func (h *httprunner) extractRevokedTokenList(payload cmn.SimpleKVs) (*TokenList,
 // The added "discovery_url" is precisely intended to address this scenario.
 //
 // Here's how a node joins a DFC cluster:
-// - first, there's the primary proxy/gateway referenced by the current cluster map (Smap)
-//   or - during the cluster deployment time - by the the configured "primary_url"
-//   (see setup/config.sh)
+//   - first, there's the primary proxy/gateway referenced by the current cluster map (Smap)
+//     or - during the cluster deployment time - by the the configured "primary_url"
+//     (see setup/config.sh)
 //
 // - if that one fails, the new node goes ahead and tries the alternatives:
-// 	- ctx.config.Proxy.DiscoveryURL ("discovery_url")
-// 	- ctx.config.Proxy.OriginalURL ("original_url")
+//   - ctx.config.Proxy.DiscoveryURL ("discovery_url")
+//   - ctx.config.Proxy.OriginalURL ("original_url")
+//
 // - but only if those are defined and different from the previously tried.
 //
+//   - and finally, if configured, the primary discovered via DNS SRV records or cloud
+//     instance metadata (see discovery.go) - which is also the first and the only option
+//     for a node that has no "primary_url" in its configuration.
+//
 // ================================== Background =========================================
 func (h *httprunner) join(isproxy bool, query url.Values) (res callResult) {
 	url, psi := h.getPrimaryURLAndSI()
+	if url == "" && ctx.config.Proxy.Discovery.Provider != "" {
+		return h.joinDiscovered(url, isproxy, query)
+	}
 	res = h.registerToURL(url, psi, defaultTimeout, isproxy, query, false)
 	if res.err == nil {
 		return
@@ -1074,6 +1481,28 @@ func (h *httprunner) join(isproxy bool, query url.Values) (res callResult) {
 			return
 		}
 	}
+	if ctx.config.Proxy.Discovery.Provider != "" {
+		glog.Errorf("%s: (register => %s: %v - retrying => %s discovery...)",
+			h.si.DaemonID, url, res.err, ctx.config.Proxy.Discovery.Provider)
+		if resAlt := h.joinDiscovered(url, isproxy, query); resAlt.err == nil {
+			res = resAlt
+		}
+	}
+	return
+}
+
+// joinDiscovered joins the discovered primary - unless it is the one that has already
+// been tried; upon success, the discovered primary becomes the configured one
+func (h *httprunner) joinDiscovered(tried string, isproxy bool, query url.Values) (res callResult) {
+	url := h.discoverPrimary()
+	if url == "" || url == tried {
+		res.err = fmt.Errorf("%s: failed to discover the primary (%s discovery: %s)",
+			h.si.DaemonID, ctx.config.Proxy.Discovery.Provider, ctx.config.Proxy.Discovery.Name)
+		return
+	}
+	if res = h.registerToURL(url, nil, defaultTimeout, isproxy, query, false); res.err == nil {
+		ctx.config.Proxy.PrimaryURL = url
+	}
 	return
 }
 
@@ -1134,16 +1563,14 @@ func (h *httprunner) getPrimaryURLAndSI() (url string, proxysi *cluster.Snode) {
 	return
 }
 
-//
-// StatsD client using 8125 (default) StatsD port - https://github.com/etsy/statsd
-//
-func (h *httprunner) initStatsD(daemonStr string) (err error) {
-	suffix := strings.Replace(h.si.DaemonID, ":", "_", -1)
-	h.statsdC, err = statsd.New("localhost", 8125, daemonStr+"."+suffix)
+// initMetrics creates the metrics sink as per the metrics config - see stats.MetricsSink;
+// prior to the keepalive tracker that sends its metrics there
+func (h *httprunner) initMetrics(daemonStr string) {
+	var err error
+	h.metrics, err = stats.NewSink(ctx.config.Metrics.Sink, stats.SinkArgs{Daemon: daemonStr, DaemonID: h.si.DaemonID, Config: &ctx.config})
 	if err != nil {
-		glog.Infoln("Failed to connect to StatsD daemon")
+		glog.Warningf("Metrics sink %q: %v", ctx.config.Metrics.Sink, err)
 	}
-	return
 }
 
 func isReplicationPUT(r *http.Request) (isreplica bool, replicasrc string) {
@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestAPIV2(t *testing.T) {
+	var (
+		path string
+		v2   bool
+	)
+	handler := apiV2(func(w http.ResponseWriter, r *http.Request) {
+		path, v2 = r.URL.Path, cmn.IsAPIV2(r)
+		cmn.InvalidHandlerWithMsg(w, r, "not found", http.StatusNotFound)
+	})
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/v2/objects/abc/v2/obj", nil))
+	if path != "/v1/objects/abc/v2/obj" || !v2 {
+		t.Fatalf("expected v2 request with v1 path, got %q (v2 %t)", path, v2)
+	}
+	if ct := w.Header().Get("Content-Type"); w.Code != http.StatusNotFound || ct != "application/json" {
+		t.Errorf("expected JSON error 404, got %d %q", w.Code, ct)
+	}
+}
+
+func TestRequestBucket(t *testing.T) {
+	tests := []struct{ path, bucket string }{
+		{"/v1/objects/abc/dir/obj", "abc"},
+		{"/v1/buckets/abc", "abc"},
+		{"/v1/buckets/*", ""},
+		{"/v1/daemon", ""},
+		{"/v1/cluster/mountpaths", ""},
+	}
+	for _, test := range tests {
+		if bucket := requestBucket(httptest.NewRequest(http.MethodGet, test.path, nil)); bucket != test.bucket {
+			t.Errorf("%s: expected bucket %q, got %q", test.path, test.bucket, bucket)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Keepalive latency: every keepalive round trip updates the kalive.lat stats (the average
+// over the stats period) and kalive.lat.min/kalive.lat.max (the minimum and the maximum
+// within the period - reset every periodic.stats_time, as are all the latencies). In
+// addition, each daemon keeps the same per peer: the current window (one stats period) and
+// the last kaliveHistWindows completed windows, including the number of failed keepalives.
+// The primary proxy's keepalive policy reports the peer's latest window along with its
+// failures.
+//
+// GET /v1/daemon?what=keepalive returns the daemon's windows; GET /v1/cluster?what=keepalive
+// collects them from all daemons into the latency matrix (daemons that do not respond are
+// skipped and logged).
+//
+// ================================ Summary ===============================================
+
+const kaliveHistWindows = 60 // completed windows kept per peer
+
+type (
+	kaliveWindows struct {
+		mu    sync.Mutex
+		peers map[string]*kalivePeer
+	}
+	kalivePeer struct {
+		cur  kaliveWindow
+		hist []cmn.KeepaliveWindow // oldest first
+	}
+	kaliveWindow struct {
+		start         time.Time
+		n, failed     int64
+		min, max, sum time.Duration
+	}
+)
+
+func newKaliveWindows() *kaliveWindows {
+	return &kaliveWindows{peers: make(map[string]*kalivePeer)}
+}
+
+// addKeepaliveLatency updates both the stats and the peer's window with a successful keepalive
+func (k *keepalive) addKeepaliveLatency(statsif stats.Tracker, sid string, delta time.Duration) {
+	statsif.AddMany(
+		stats.NamedVal64{Name: stats.KeepAliveLatency, Val: int64(delta)},
+		stats.NamedVal64{Name: stats.KeepAliveMinLatency, Val: int64(delta)},
+		stats.NamedVal64{Name: stats.KeepAliveMaxLatency, Val: int64(delta)})
+	k.lat.add(sid, delta, false, ctx.config.Periodic.StatsTime, time.Now())
+}
+
+func (k *keepalive) keepaliveFailed(sid string) {
+	k.lat.add(sid, 0, true, ctx.config.Periodic.StatsTime, time.Now())
+}
+
+func (kw *kaliveWindows) add(sid string, delta time.Duration, failed bool, period time.Duration, now time.Time) {
+	kw.mu.Lock()
+	peer, ok := kw.peers[sid]
+	if !ok {
+		peer = &kalivePeer{}
+		kw.peers[sid] = peer
+	}
+	peer.roll(period, now)
+	w := &peer.cur
+	if failed {
+		w.failed++
+	} else {
+		if w.n == 0 || delta < w.min {
+			w.min = delta
+		}
+		if delta > w.max {
+			w.max = delta
+		}
+		w.sum += delta
+		w.n++
+	}
+	kw.mu.Unlock()
+}
+
+// del forgets the peer (e.g., removed from the cluster map)
+func (kw *kaliveWindows) del(sid string) {
+	kw.mu.Lock()
+	delete(kw.peers, sid)
+	kw.mu.Unlock()
+}
+
+// latest returns the peer's most recent window that has any keepalives in it
+func (kw *kaliveWindows) latest(sid string, period time.Duration, now time.Time) (w cmn.KeepaliveWindow, ok bool) {
+	if kw == nil {
+		return
+	}
+	kw.mu.Lock()
+	defer kw.mu.Unlock()
+	peer, ok := kw.peers[sid]
+	if !ok {
+		return
+	}
+	peer.roll(period, now)
+	if peer.cur.n+peer.cur.failed > 0 {
+		return peer.cur.out(), true
+	}
+	if l := len(peer.hist); l > 0 {
+		return peer.hist[l-1], true
+	}
+	return w, false
+}
+
+func (kw *kaliveWindows) get(period time.Duration, now time.Time) *cmn.KeepaliveLatency {
+	kw.mu.Lock()
+	defer kw.mu.Unlock()
+	out := &cmn.KeepaliveLatency{
+		Time:   now,
+		Window: period.String(),
+		Peers:  make(map[string]*cmn.KeepalivePeer, len(kw.peers)),
+	}
+	for sid, peer := range kw.peers {
+		peer.roll(period, now)
+		hist := make([]cmn.KeepaliveWindow, len(peer.hist))
+		copy(hist, peer.hist)
+		out.Peers[sid] = &cmn.KeepalivePeer{Current: peer.cur.out(), History: hist}
+	}
+	return out
+}
+
+// roll completes the current window once the stats period is over; windows are aligned
+// on the period, and those with no keepalives are not kept
+func (peer *kalivePeer) roll(period time.Duration, now time.Time) {
+	if period <= 0 {
+		period = time.Minute
+	}
+	start := now.Truncate(period)
+	if peer.cur.start.Equal(start) {
+		return
+	}
+	if peer.cur.n+peer.cur.failed > 0 {
+		peer.hist = append(peer.hist, peer.cur.out())
+		if l := len(peer.hist); l > kaliveHistWindows {
+			peer.hist = append(peer.hist[:0], peer.hist[l-kaliveHistWindows:]...)
+		}
+	}
+	peer.cur = kaliveWindow{start: start}
+}
+
+func (w *kaliveWindow) out() cmn.KeepaliveWindow {
+	out := cmn.KeepaliveWindow{Start: w.start, N: w.n, Failed: w.failed, Min: w.min, Max: w.max}
+	if w.n > 0 {
+		out.Avg = w.sum / time.Duration(w.n)
+	}
+	return out
+}
+
+func kaliveWindowString(w cmn.KeepaliveWindow) string {
+	return fmt.Sprintf("since %s: %d ok (min %v, avg %v, max %v), %d failed",
+		w.Start.Format("15:04:05"), w.N, w.Min, w.Avg, w.Max, w.Failed)
+}
+
+func (p *proxyrunner) invokeHttpGetClusterKeepalive(w http.ResponseWriter, r *http.Request) bool {
+	var (
+		smap = p.smapowner.get()
+		path = cmn.URLPath(cmn.Version, cmn.Daemon)
+		out  = &cmn.ClusterKeepaliveLatency{
+			Proxies: make(map[string]*cmn.KeepaliveLatency, len(smap.Pmap)),
+			Targets: make(map[string]*cmn.KeepaliveLatency, len(smap.Tmap)),
+		}
+	)
+	collect := func(lats map[string]*cmn.KeepaliveLatency, results chan callResult) {
+		for res := range results {
+			if res.err != nil {
+				glog.Errorf("Failed to get %s keepalive latency: %s", res.si, res.errstr)
+				continue
+			}
+			lat := &cmn.KeepaliveLatency{}
+			if err := jsoniter.Unmarshal(res.outjson, lat); err != nil {
+				glog.Errorf("Failed to unmarshal %s keepalive latency, err: %v", res.si, err)
+				continue
+			}
+			lats[res.si.DaemonID] = lat
+		}
+	}
+	collect(out.Proxies, p.broadcastDash(path, r.URL.Query(), smap.Pmap))
+	collect(out.Targets, p.broadcastDash(path, r.URL.Query(), smap.Tmap))
+	out.Proxies[p.si.DaemonID] = p.keepalive.latency()
+
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterKeepalive")
+}
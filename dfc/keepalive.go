@@ -47,6 +47,7 @@ type keepaliver interface {
 	heardFrom(sid string, reset bool)
 	doKeepalive() (stopped bool)
 	isTimeToPing(sid string) bool
+	latency() *cmn.KeepaliveLatency
 }
 
 type targetKeepaliveRunner struct {
@@ -55,7 +56,8 @@ type targetKeepaliveRunner struct {
 }
 
 type proxyKeepaliveRunner struct {
-	p *proxyrunner
+	p        *proxyrunner
+	suspects map[string]int // daemon ID => number of consecutive failed keepalives (primary only)
 	keepalive
 }
 
@@ -64,6 +66,7 @@ type keepalive struct {
 	k                          keepaliver
 	kt                         KeepaliveTracker
 	tt                         *timeoutTracker
+	lat                        *kaliveWindows // per peer, see kalivestats.go
 	controlCh                  chan controlSignal
 	primaryKeepaliveInProgress int64 // A toggle used only by the primary proxy.
 	interval                   time.Duration
@@ -102,8 +105,9 @@ type KeepaliveTracker interface {
 func newTargetKeepaliveRunner(t *targetrunner) *targetKeepaliveRunner {
 	tkr := &targetKeepaliveRunner{t: t}
 	tkr.keepalive.k = tkr
-	tkr.kt = newKeepaliveTracker(&ctx.config.KeepaliveTracker.Target, &t.statsdC)
+	tkr.kt = newKeepaliveTracker(&ctx.config.KeepaliveTracker.Target, t.metrics)
 	tkr.tt = &timeoutTracker{timeoutStatsMap: make(map[string]*timeoutStats)}
+	tkr.lat = newKaliveWindows()
 	tkr.controlCh = make(chan controlSignal, 1)
 	tkr.interval = ctx.config.KeepaliveTracker.Target.Interval
 	tkr.maxKeepaliveTime = float64(ctx.config.Timeout.MaxKeepalive.Nanoseconds())
@@ -111,10 +115,11 @@ func newTargetKeepaliveRunner(t *targetrunner) *targetKeepaliveRunner {
 }
 
 func newProxyKeepaliveRunner(p *proxyrunner) *proxyKeepaliveRunner {
-	pkr := &proxyKeepaliveRunner{p: p}
+	pkr := &proxyKeepaliveRunner{p: p, suspects: make(map[string]int)}
 	pkr.keepalive.k = pkr
-	pkr.kt = newKeepaliveTracker(&ctx.config.KeepaliveTracker.Proxy, &p.statsdC)
+	pkr.kt = newKeepaliveTracker(&ctx.config.KeepaliveTracker.Proxy, p.metrics)
 	pkr.tt = &timeoutTracker{timeoutStatsMap: make(map[string]*timeoutStats)}
+	pkr.lat = newKaliveWindows()
 	pkr.controlCh = make(chan controlSignal, 1)
 	pkr.interval = ctx.config.KeepaliveTracker.Proxy.Interval
 	pkr.maxKeepaliveTime = float64(ctx.config.Timeout.MaxKeepalive.Nanoseconds())
@@ -168,7 +173,6 @@ func (pkr *proxyKeepaliveRunner) pingAllOthers() (stopped bool) {
 		daemonCnt  = smap.CountProxies() + smap.CountTargets()
 		stoppedCh  = make(chan struct{}, daemonCnt)
 		toRemoveCh = make(chan string, daemonCnt)
-		latencyCh  = make(chan time.Duration, daemonCnt)
 	)
 	for _, daemons := range []map[string]*cluster.Snode{smap.Tmap, smap.Pmap} {
 		for sid, si := range daemons {
@@ -185,16 +189,13 @@ func (pkr *proxyKeepaliveRunner) pingAllOthers() (stopped bool) {
 					wg.Done()
 					return
 				}
-				ok, s, lat := pkr.ping(si)
+				ok, s := pkr.ping(si)
 				if s {
 					stoppedCh <- struct{}{}
 				}
 				if !ok {
 					toRemoveCh <- si.DaemonID
 				}
-				if lat != defaultTimeout {
-					latencyCh <- lat
-				}
 				wg.Done()
 			}(si)
 		}
@@ -202,9 +203,6 @@ func (pkr *proxyKeepaliveRunner) pingAllOthers() (stopped bool) {
 	wg.Wait()
 	close(stoppedCh)
 	close(toRemoveCh)
-	close(latencyCh)
-
-	pkr.statsMinMaxLat(latencyCh)
 
 	pkr.p.smapowner.Lock()
 	newSmap := pkr.p.smapowner.get()
@@ -218,13 +216,14 @@ func (pkr *proxyKeepaliveRunner) pingAllOthers() (stopped bool) {
 		pkr.p.smapowner.Unlock()
 		return true
 	}
-	if len(toRemoveCh) == 0 {
+	toRemove := pkr.applyPolicy(newSmap, toRemoveCh)
+	if len(toRemove) == 0 {
 		pkr.p.smapowner.Unlock()
 		return false
 	}
 	clone := newSmap.clone()
 	metaction := "keepalive: removing ["
-	for sid := range toRemoveCh {
+	for _, sid := range toRemove {
 		if clone.GetProxy(sid) != nil {
 			clone.delProxy(sid)
 			metaction += " proxy " + sid
@@ -232,6 +231,7 @@ func (pkr *proxyKeepaliveRunner) pingAllOthers() (stopped bool) {
 			clone.delTarget(sid)
 			metaction += " target " + sid
 		}
+		pkr.lat.del(sid)
 	}
 	metaction += " ]"
 
@@ -245,26 +245,57 @@ func (pkr *proxyKeepaliveRunner) pingAllOthers() (stopped bool) {
 	return
 }
 
-// min & max keepalive stats
-func (pkr *proxyKeepaliveRunner) statsMinMaxLat(latencyCh chan time.Duration) {
-	min, max := time.Duration(time.Hour), time.Duration(0)
-	for lat := range latencyCh {
-		if min > lat && lat != 0 {
-			min = lat
-		}
-		if max < lat {
-			max = lat
-		}
+// applyPolicy counts consecutive keepalive failures and returns the daemons to remove
+// from the cluster map, as per the configured suspect_after, rebalance_grace, and
+// target_alert_only (see cmn.KeepaliveConf).
+func (pkr *proxyKeepaliveRunner) applyPolicy(smap *smapX, failedCh chan string) (toRemove []string) {
+	var (
+		config     = &ctx.config.KeepaliveTracker
+		failed     = make(map[string]bool, len(failedCh))
+		rebStarted = time.Unix(0, atomic.LoadInt64(&pkr.p.rebStarted))
+		inGrace    = time.Since(rebStarted) < config.RebalanceGrace
+	)
+	for sid := range failedCh {
+		failed[sid] = true
 	}
-	if min != time.Duration(time.Hour) {
-		pkr.p.statsif.Add(stats.KeepAliveMinLatency, int64(min/time.Microsecond))
+	for sid := range pkr.suspects {
+		if !failed[sid] {
+			delete(pkr.suspects, sid) // responded (or is gone)
+		}
 	}
-	if max != 0 {
-		pkr.p.statsif.Add(stats.KeepAliveMaxLatency, int64(max/time.Microsecond))
+	for sid := range failed {
+		pkr.suspects[sid]++
+		missed := pkr.suspects[sid]
+		if w, ok := pkr.lat.latest(sid, ctx.config.Periodic.StatsTime, time.Now()); ok {
+			glog.Warningf("keepalive: %s latency %s", sid, kaliveWindowString(w))
+		}
+		if missed < config.SuspectAfter {
+			glog.Warningf("keepalive: %s failed %d time(s), suspect after %d", sid, missed, config.SuspectAfter)
+			continue
+		}
+		if pkr.p.upgrade.isRestarting(sid) {
+			glog.Warningf("keepalive: %s is not responding (failed %d times) - not removing: restarting (rolling upgrade)",
+				sid, missed)
+			continue
+		}
+		if smap.GetTarget(sid) != nil {
+			if config.TargetAlertOnly {
+				glog.Errorf("keepalive: target %s is not responding (failed %d times) - not removing (alert-only mode)", sid, missed)
+				continue
+			}
+			if inGrace {
+				glog.Errorf("keepalive: target %s is not responding (failed %d times) - not removing: rebalance started %v ago",
+					sid, missed, time.Since(rebStarted))
+				continue
+			}
+		}
+		delete(pkr.suspects, sid)
+		toRemove = append(toRemove, sid)
 	}
+	return
 }
 
-func (pkr *proxyKeepaliveRunner) ping(to *cluster.Snode) (ok, stopped bool, delta time.Duration) {
+func (pkr *proxyKeepaliveRunner) ping(to *cluster.Snode) (ok, stopped bool) {
 	query := url.Values{}
 	query.Add(cmn.URLParamFromID, pkr.p.si.DaemonID)
 
@@ -281,16 +312,16 @@ func (pkr *proxyKeepaliveRunner) ping(to *cluster.Snode) (ok, stopped bool, delt
 	}
 	t := time.Now()
 	res := pkr.p.call(args)
-	delta = time.Since(t)
+	delta := time.Since(t)
 	pkr.updateTimeoutForDaemon(to.DaemonID, delta)
-	pkr.p.statsif.Add(stats.KeepAliveLatency, int64(delta/time.Microsecond))
 
 	if res.err == nil {
-		return true, false, delta
+		pkr.addKeepaliveLatency(pkr.p.statsif, to.DaemonID, delta)
+		return true, false
 	}
+	pkr.keepaliveFailed(to.DaemonID)
 	glog.Warningf("initial keepalive failed, err: %v, status: %d, polling again", res.err, res.status)
-	ok, stopped = pkr.retry(to, args)
-	return ok, stopped, defaultTimeout
+	return pkr.retry(to, args)
 }
 
 func (pkr *proxyKeepaliveRunner) retry(si *cluster.Snode, args callArgs) (ok, stopped bool) {
@@ -371,11 +402,12 @@ func (k *keepalive) register(r registerer, statsif stats.Tracker, primaryProxyID
 	now := time.Now()
 	s, err := r.register(true, timeout)
 	delta := time.Since(now)
-	statsif.Add(stats.KeepAliveLatency, int64(delta/time.Microsecond))
 	timeout = k.updateTimeoutForDaemon(primaryProxyID, delta)
 	if err == nil {
+		k.addKeepaliveLatency(statsif, primaryProxyID, delta)
 		return
 	}
+	k.keepaliveFailed(primaryProxyID)
 	glog.Infof("daemon -> primary proxy keepalive failed, err: %v, status: %d", err, s)
 
 	var i int
@@ -460,6 +492,10 @@ func (k *keepalive) isTimeToPing(sid string) bool {
 	return k.kt.TimedOut(sid)
 }
 
+func (k *keepalive) latency() *cmn.KeepaliveLatency {
+	return k.lat.get(ctx.config.Periodic.StatsTime, time.Now())
+}
+
 func (k *keepalive) Stop(err error) {
 	glog.Infof("Stopping %s, err: %v", k.Getname(), err)
 	k.controlCh <- controlSignal{msg: stop}
@@ -485,7 +521,7 @@ type HeartBeatTracker struct {
 	ch       chan struct{}
 	last     map[string]time.Time
 	interval time.Duration // expected to hear from the server within the interval
-	statsdC  *statsd.Client
+	metrics  stats.MetricsSink
 }
 
 // ValidKeepaliveType returns true if the keepalive type is supported.
@@ -494,22 +530,25 @@ func ValidKeepaliveType(t string) bool {
 }
 
 // NewKeepaliveTracker returns a keepalive tracker based on the parameters given.
-func newKeepaliveTracker(c *cmn.KeepaliveTrackerConf, statsdC *statsd.Client) KeepaliveTracker {
+func newKeepaliveTracker(c *cmn.KeepaliveTrackerConf, metrics stats.MetricsSink) KeepaliveTracker {
+	if metrics == nil {
+		metrics = stats.NopSink{}
+	}
 	switch c.Name {
 	case heartbeatType:
-		return newHeartBeatTracker(c.Interval, statsdC)
+		return newHeartBeatTracker(c.Interval, metrics)
 	case averageType:
-		return newAverageTracker(c.Factor, statsdC)
+		return newAverageTracker(c.Factor, metrics)
 	}
 	return nil
 }
 
 // newHeartBeatTracker returns a HeartBeatTracker.
-func newHeartBeatTracker(interval time.Duration, statsdC *statsd.Client) *HeartBeatTracker {
+func newHeartBeatTracker(interval time.Duration, metrics stats.MetricsSink) *HeartBeatTracker {
 	hb := &HeartBeatTracker{
 		last:     make(map[string]time.Time),
 		ch:       make(chan struct{}, 1),
-		statsdC:  statsdC,
+		metrics:  metrics,
 		interval: interval,
 	}
 
@@ -535,11 +574,11 @@ func (hb *HeartBeatTracker) HeardFrom(id string, reset bool) {
 
 	if ok {
 		delta := t.Sub(last)
-		hb.statsdC.Send("keepalive.heartbeat."+id,
+		hb.metrics.Send("keepalive.heartbeat."+id,
 			metric{statsd.Gauge, "delta", int64(delta / time.Millisecond)},
 			metric{statsd.Counter, "count", 1})
 	} else {
-		hb.statsdC.Send("keepalive.heartbeat."+id, metric{statsd.Counter, "count", 1})
+		hb.metrics.Send("keepalive.heartbeat."+id, metric{statsd.Counter, "count", 1})
 	}
 }
 
@@ -557,7 +596,7 @@ type AverageTracker struct {
 	ch      chan struct{}
 	rec     map[string]averageTrackerRecord
 	factor  int
-	statsdC *statsd.Client
+	metrics stats.MetricsSink
 }
 
 type averageTrackerRecord struct {
@@ -571,11 +610,11 @@ func (rec *averageTrackerRecord) avg() int64 {
 }
 
 // newAverageTracker returns an AverageTracker.
-func newAverageTracker(factor int, statsdC *statsd.Client) *AverageTracker {
+func newAverageTracker(factor int, metrics stats.MetricsSink) *AverageTracker {
 	a := &AverageTracker{
 		rec:     make(map[string]averageTrackerRecord),
 		ch:      make(chan struct{}, 1),
-		statsdC: statsdC,
+		metrics: metrics,
 		factor:  factor,
 	}
 
@@ -599,7 +638,7 @@ func (a *AverageTracker) HeardFrom(id string, reset bool) {
 	if reset || !ok {
 		a.rec[id] = averageTrackerRecord{count: 0, totalMS: 0, last: time.Now()}
 		a.unlock()
-		a.statsdC.Send("keepalive.average."+id, metric{statsd.Counter, "reset", 1})
+		a.metrics.Send("keepalive.average."+id, metric{statsd.Counter, "reset", 1})
 		return
 	}
 
@@ -611,7 +650,7 @@ func (a *AverageTracker) HeardFrom(id string, reset bool) {
 	a.rec[id] = rec
 	a.unlock()
 
-	a.statsdC.Send("keepalive.average."+id,
+	a.metrics.Send("keepalive.average."+id,
 		metric{statsd.Counter, "delta", int64(delta / time.Millisecond)},
 		metric{statsd.Counter, "count", 1})
 }
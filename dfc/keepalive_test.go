@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/NVIDIA/dfcpub/cluster"
 	"github.com/NVIDIA/dfcpub/stats/statsd"
 )
 
@@ -124,3 +125,104 @@ func TestKeepaliveTrackerHeartBeat(t *testing.T) {
 		t.Fatal("Expecting time out")
 	}
 }
+
+func TestKeepalivePolicy(t *testing.T) {
+	const (
+		proxyID  = "proxy1"
+		targetID = "target1"
+	)
+	smap := newSmap()
+	smap.addProxy(&cluster.Snode{DaemonID: proxyID})
+	smap.addTarget(&cluster.Snode{DaemonID: targetID})
+	pkr := &proxyKeepaliveRunner{p: &proxyrunner{}, suspects: make(map[string]int)}
+	failed := func(sids ...string) []string {
+		ch := make(chan string, len(sids))
+		for _, sid := range sids {
+			ch <- sid
+		}
+		close(ch)
+		return pkr.applyPolicy(smap, ch)
+	}
+	oconfig := ctx.config.KeepaliveTracker
+	defer func() { ctx.config.KeepaliveTracker = oconfig }()
+
+	// suspect after 2 consecutive failures; a successful keepalive resets the count
+	ctx.config.KeepaliveTracker.SuspectAfter = 2
+	if removed := failed(targetID); len(removed) != 0 {
+		t.Errorf("expected no removals after the first failure, got %v", removed)
+	}
+	failed()
+	if removed := failed(targetID); len(removed) != 0 {
+		t.Errorf("expected no removals after the count was reset, got %v", removed)
+	}
+	if removed := failed(targetID); len(removed) != 1 || removed[0] != targetID {
+		t.Errorf("expected %s to be removed, got %v", targetID, removed)
+	}
+
+	// alert-only mode applies to targets only
+	ctx.config.KeepaliveTracker.SuspectAfter = 1
+	ctx.config.KeepaliveTracker.TargetAlertOnly = true
+	if removed := failed(targetID, proxyID); len(removed) != 1 || removed[0] != proxyID {
+		t.Errorf("expected only %s to be removed, got %v", proxyID, removed)
+	}
+
+	// grace period after rebalance
+	ctx.config.KeepaliveTracker.TargetAlertOnly = false
+	ctx.config.KeepaliveTracker.RebalanceGrace = time.Minute
+	pkr.p.rebStarted = time.Now().UnixNano()
+	if removed := failed(targetID); len(removed) != 0 {
+		t.Errorf("expected no removals within the rebalance grace period, got %v", removed)
+	}
+	pkr.p.rebStarted = time.Now().Add(-2 * time.Minute).UnixNano()
+	if removed := failed(targetID); len(removed) != 1 {
+		t.Errorf("expected %s to be removed after the grace period, got %v", targetID, removed)
+	}
+}
+
+func TestKeepaliveWindows(t *testing.T) {
+	const (
+		period = time.Minute
+		sid    = "target1"
+	)
+	var (
+		kw  = newKaliveWindows()
+		now = time.Now().Truncate(period)
+	)
+	kw.add(sid, 20*time.Millisecond, false, period, now)
+	kw.add(sid, 10*time.Millisecond, false, period, now.Add(time.Second))
+	kw.add(sid, 0, true, period, now.Add(2*time.Second))
+	w, ok := kw.latest(sid, period, now.Add(3*time.Second))
+	if !ok || w.N != 2 || w.Failed != 1 || w.Min != 10*time.Millisecond || w.Max != 20*time.Millisecond ||
+		w.Avg != 15*time.Millisecond || !w.Start.Equal(now) {
+		t.Errorf("unexpected current window %+v", w)
+	}
+
+	// the next period starts a new window; the previous one goes to the history
+	kw.add(sid, 40*time.Millisecond, false, period, now.Add(period))
+	lat := kw.get(period, now.Add(period+time.Second))
+	peer := lat.Peers[sid]
+	if peer == nil || len(peer.History) != 1 || peer.History[0].N != 2 {
+		t.Fatalf("expected one completed window, got %+v", peer)
+	}
+	if peer.Current.N != 1 || peer.Current.Min != 40*time.Millisecond || peer.Current.Max != 40*time.Millisecond {
+		t.Errorf("unexpected current window %+v", peer.Current)
+	}
+
+	// empty windows are skipped, the history is limited
+	for i := 0; i < kaliveHistWindows+5; i++ {
+		kw.add(sid, time.Millisecond, false, period, now.Add(time.Duration(2+2*i)*period))
+	}
+	peer = kw.get(period, now.Add(time.Hour*24)).Peers[sid]
+	if len(peer.History) != kaliveHistWindows || peer.Current.N != 0 {
+		t.Errorf("expected %d completed windows and an empty current one, got %d, %+v",
+			kaliveHistWindows, len(peer.History), peer.Current)
+	}
+	if w, ok = kw.latest(sid, period, now.Add(time.Hour*24)); !ok || w.N != 1 {
+		t.Errorf("expected the latest completed window, got %+v", w)
+	}
+
+	kw.del(sid)
+	if _, ok = kw.latest(sid, period, now); ok {
+		t.Error("expected no windows after del")
+	}
+}
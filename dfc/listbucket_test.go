@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestConsumeCachedListPartial(t *testing.T) {
+	var (
+		p    = &proxyrunner{}
+		page = func(id string, err error, names ...string) *localFilePage {
+			entries := make([]*cmn.BucketEntry, 0, len(names))
+			for _, name := range names {
+				entries = append(entries, &cmn.BucketEntry{Name: name, Status: cmn.ObjStatusOK})
+			}
+			return &localFilePage{id: id, err: err, entries: entries}
+		}
+		feed = func() chan *localFilePage {
+			ch := make(chan *localFilePage, 3)
+			ch <- page("t1", errors.New("timeout"))
+			ch <- page("t2", nil, "a")
+			ch <- page("t3", nil, "b")
+			close(ch)
+			return ch
+		}
+		bmap = func() map[string]*cmn.BucketEntry {
+			return map[string]*cmn.BucketEntry{"a": {Name: "a"}, "b": {Name: "b"}, "c": {Name: "c"}}
+		}
+	)
+
+	// partial: the failed target is reported, the others' entries are merged
+	m, errs, errCh := bmap(), make(map[string]string), make(chan error, 1)
+	p.consumeCachedList(m, feed(), errCh, errs)
+	if len(errs) != 1 || errs["t1"] != "timeout" {
+		t.Errorf("expected t1 timeout, got %v", errs)
+	}
+	if !m["a"].IsCached || !m["b"].IsCached || m["c"].IsCached {
+		t.Errorf("expected a and b cached, got %v, %v, %v", m["a"].IsCached, m["b"].IsCached, m["c"].IsCached)
+	}
+	if len(errCh) != 0 {
+		t.Error("expected no error in partial mode")
+	}
+
+	// strict: the first failure fails the listing
+	m = bmap()
+	p.consumeCachedList(m, feed(), errCh, nil)
+	select {
+	case err := <-errCh:
+		if err.Error() != "timeout" {
+			t.Errorf("expected timeout, got %v", err)
+		}
+	default:
+		t.Error("expected error in strict mode")
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Cloud list cache: listing a Cloud bucket is relayed by the proxy to one of the targets
+// that, in turn, lists the bucket in the Cloud - page by page. To spare the Cloud provider
+// (and the bill), each target caches the list pages it receives for up to list_cache.ttl.
+// A page is identified by the bucket and the entire list request (prefix, page marker,
+// page size, requested properties, etc.), so that a cached page is returned only to the
+// identical request.
+//
+// The proxy always relays the listing of a given bucket to the same target (the bucket
+// name's HRW target) - the one that caches its pages. The target that PUTs or DELETEs an
+// object in a Cloud bucket invalidates the bucket's cached pages: its own and, if it is
+// not the bucket's list target, those of the list target (ActListInval). Objects changed
+// in the Cloud directly (not via DFC) show up once the cached pages expire, or
+// immediately - when listed with ?refresh=true.
+//
+// ================================ Summary ===============================================
+
+type (
+	listCache struct {
+		sync.Mutex
+		buckets map[string]map[string]*listPage // bucket => list request => page
+	}
+	listPage struct {
+		jsbytes []byte
+		expires time.Time
+	}
+)
+
+func newListCache() *listCache {
+	return &listCache{buckets: make(map[string]map[string]*listPage)}
+}
+
+func (c *listCache) get(bucket, key string, now time.Time) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+	page, ok := c.buckets[bucket][key]
+	if !ok {
+		return nil, false
+	}
+	if now.After(page.expires) {
+		delete(c.buckets[bucket], key)
+		return nil, false
+	}
+	return page.jsbytes, true
+}
+
+// put caches the page; when the bucket has maxPages cached pages (and maxPages is
+// non-zero), the expired pages are dropped or, if none, all of them
+func (c *listCache) put(bucket, key string, jsbytes []byte, now time.Time, ttl time.Duration, maxPages int) {
+	c.Lock()
+	defer c.Unlock()
+	pages, ok := c.buckets[bucket]
+	if !ok {
+		pages = make(map[string]*listPage)
+		c.buckets[bucket] = pages
+	}
+	if _, ok := pages[key]; !ok && maxPages > 0 && len(pages) >= maxPages {
+		for k, page := range pages {
+			if now.After(page.expires) {
+				delete(pages, k)
+			}
+		}
+		if len(pages) >= maxPages {
+			pages = make(map[string]*listPage)
+			c.buckets[bucket] = pages
+		}
+	}
+	pages[key] = &listPage{jsbytes: jsbytes, expires: now.Add(ttl)}
+}
+
+func (c *listCache) inval(bucket string) {
+	c.Lock()
+	delete(c.buckets, bucket)
+	c.Unlock()
+}
+
+// listCloudBucket returns the page of the Cloud bucket list - cached, if available
+func (t *targetrunner) listCloudBucket(r *http.Request, bucket string, msg *cmn.GetMsg) (jsbytes []byte, errstr string, errcode int) {
+	ttl := ctx.config.ListCache.TTL
+	if ttl == 0 {
+		return getcloudif().listbucket(t.contextWithAuth(r), bucket, msg)
+	}
+	keybytes, err := jsoniter.Marshal(msg)
+	cmn.Assert(err == nil, err)
+	var (
+		key        = string(keybytes)
+		refresh, _ = strconv.ParseBool(r.URL.Query().Get(cmn.URLParamRefresh))
+		ok         bool
+	)
+	if !refresh {
+		if jsbytes, ok = t.listCache.get(bucket, key, time.Now()); ok {
+			t.statsif.Add(stats.ListCacheHitCount, 1)
+			return
+		}
+	}
+	if jsbytes, errstr, errcode = getcloudif().listbucket(t.contextWithAuth(r), bucket, msg); errstr != "" {
+		return
+	}
+	t.statsif.Add(stats.ListCacheMissCount, 1)
+	t.listCache.put(bucket, key, jsbytes, time.Now(), ttl, ctx.config.ListCache.MaxPages)
+	return
+}
+
+// invalListCache drops the cached list pages of the Cloud bucket, locally and on the
+// bucket's list target
+func (t *targetrunner) invalListCache(bucket string) {
+	if ctx.config.ListCache.TTL == 0 {
+		return
+	}
+	t.listCache.inval(bucket)
+	si, errstr := hrwTarget(bucket, "", t.smapowner.get())
+	if errstr != "" || si.DaemonID == t.si.DaemonID {
+		return
+	}
+	go func() {
+		body, err := jsoniter.Marshal(cmn.ActionMsg{Action: cmn.ActListInval})
+		cmn.Assert(err == nil, err)
+		args := callArgs{
+			si: si,
+			req: reqArgs{
+				method: http.MethodPost,
+				base:   si.IntraControlNet.DirectURL,
+				path:   cmn.URLPath(cmn.Version, cmn.Buckets, bucket),
+				body:   body,
+			},
+			timeout: defaultTimeout,
+		}
+		if res := t.call(args); res.err != nil {
+			glog.Errorf("Failed to invalidate %s list cache at %s: %s", bucket, si, res.errstr)
+		}
+	}()
+}
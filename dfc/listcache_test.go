@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListCache(t *testing.T) {
+	var (
+		c   = newListCache()
+		now = time.Now()
+		ttl = time.Minute
+	)
+	if _, ok := c.get("b", "p1", now); ok {
+		t.Fatal("expected empty cache")
+	}
+	c.put("b", "p1", []byte("page1"), now, ttl, 2)
+	c.put("other", "p1", []byte("other1"), now, ttl, 2)
+	if jsbytes, ok := c.get("b", "p1", now.Add(time.Second)); !ok || string(jsbytes) != "page1" {
+		t.Errorf("expected cached page1, got %q (%t)", jsbytes, ok)
+	}
+	if _, ok := c.get("b", "p2", now); ok {
+		t.Error("expected p2 not cached")
+	}
+
+	// expiration
+	if _, ok := c.get("b", "p1", now.Add(ttl+time.Second)); ok {
+		t.Error("expected p1 expired")
+	}
+
+	// max pages: the expired pages go first, then all
+	c.put("b", "p1", []byte("page1"), now, ttl, 2)
+	c.put("b", "p2", []byte("page2"), now.Add(time.Minute), ttl, 2)
+	c.put("b", "p3", []byte("page3"), now.Add(90*time.Second), ttl, 2)
+	if _, ok := c.get("b", "p1", now.Add(90*time.Second)); ok {
+		t.Error("expected expired p1 dropped")
+	}
+	for _, key := range []string{"p2", "p3"} {
+		if _, ok := c.get("b", key, now.Add(90*time.Second)); !ok {
+			t.Errorf("expected %s cached", key)
+		}
+	}
+	c.put("b", "p4", []byte("page4"), now.Add(90*time.Second), ttl, 2)
+	if n := len(c.buckets["b"]); n != 1 {
+		t.Errorf("expected 1 page cached, got %d", n)
+	}
+
+	// invalidation is per bucket
+	c.inval("b")
+	if _, ok := c.get("b", "p4", now.Add(90*time.Second)); ok {
+		t.Error("expected p4 invalidated")
+	}
+	if _, ok := c.get("other", "p1", now); !ok {
+		t.Error("expected other bucket's page to stay cached")
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestListDelimiter(t *testing.T) {
+	root, err := ioutil.TempDir("", "listdelim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, name := range []string{"obj1", "a/obj2", "a/b/obj3", "a/b/c/obj4", "a/d/obj5", "e/obj6"} {
+		fqn := filepath.Join(root, name)
+		if err := cmn.CreateDir(filepath.Dir(fqn)); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fqn, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		prefix, marker string
+		expected       []string
+	}{
+		{"", "", []string{"a/", "e/", "obj1"}},
+		{"a/", "", []string{"a/b/", "a/d/", "a/obj2"}},
+		{"a/b", "", []string{"a/b/"}},
+		{"a/b/", "", []string{"a/b/c/", "a/b/obj3"}},
+		{"a/", "a/b/", []string{"a/d/", "a/obj2"}},
+		{"", "a/", []string{"e/", "obj1"}},
+	}
+	for _, tt := range tests {
+		ci := (&targetrunner{}).newFileWalk("bucket", &cmn.GetMsg{
+			GetPrefix: tt.prefix, GetPageMarker: tt.marker, GetDelimiter: cmn.ListDelimiter})
+		ci.rootLength = len(root) + 1
+		if err := filepath.Walk(root, ci.listwalkf); err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(ci.files))
+		for _, e := range uniqueEntries(ci.files) {
+			names = append(names, e.Name)
+		}
+		if !reflect.DeepEqual(names, tt.expected) {
+			t.Errorf("prefix %q, marker %q: expected %v, got %v", tt.prefix, tt.marker, tt.expected, names)
+		}
+	}
+}
+
+func TestUniqueEntries(t *testing.T) {
+	entries := []*cmn.BucketEntry{{Name: "a/"}, {Name: "a/"}, {Name: "b"}, {Name: "c/"}, {Name: "c/"}, {Name: "c/"}}
+	entries = uniqueEntries(entries)
+	if len(entries) != 3 || entries[0].Name != "a/" || entries[1].Name != "b" || entries[2].Name != "c/" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
 	"github.com/NVIDIA/dfcpub/cluster"
 	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
 	"github.com/NVIDIA/dfcpub/stats"
 	"github.com/json-iterator/go"
 )
@@ -33,11 +34,12 @@ const (
 )
 
 type filesWithDeadline struct {
-	ctx      context.Context
-	objnames []string
-	bucket   string
-	deadline time.Time
-	done     chan struct{}
+	ctx       context.Context
+	objnames  []string
+	bucket    string
+	deadline  time.Time
+	done      chan struct{}
+	estimates map[string]prefetchEst // nil if the bucket has no prefetch budget - see prefetchbudget.go
 }
 
 type xactPrefetch struct {
@@ -181,12 +183,14 @@ func (xact *xactEvictDelete) tostring() string {
 //
 //=========
 
-func (t *targetrunner) prefetchMissing(ct context.Context, objname, bucket string) {
+func (t *targetrunner) prefetchMissing(ct context.Context, objname, bucket string, est *prefetchEst, throttlers map[string]fs.Throttler) {
 	var (
 		errstr, version   string
 		vchanged, coldget bool
 		props             *objectProps
+		requests, bytes   int64 // Cloud
 	)
+	defer func() { t.prefetchCharge(bucket, est, requests, bytes) }()
 	versioncfg := &ctx.config.Ver
 	islocal := t.bmdowner.get().IsLocal(bucket)
 	fqn, errstr := cluster.FQN(bucket, objname, islocal)
@@ -203,6 +207,7 @@ func (t *targetrunner) prefetchMissing(ct context.Context, objname, bucket strin
 		return
 	}
 	if !coldget && !islocal && versioncfg.ValidateWarmGet && version != "" && t.versioningConfigured(bucket) {
+		requests++
 		if vchanged, errstr, _ = t.checkCloudVersion(ct, bucket, objname, version); errstr != "" {
 			return
 		}
@@ -211,12 +216,27 @@ func (t *targetrunner) prefetchMissing(ct context.Context, objname, bucket strin
 	if !coldget {
 		return
 	}
+	if t.prefetchTrimmed(bucket, est) {
+		est = nil // (refunded)
+		return
+	}
+	if mpathInfo, _ := fs.Mountpaths.Path2MpathInfo(fqn); mpathInfo != nil {
+		throttler, ok := throttlers[mpathInfo.Path]
+		if !ok {
+			throttler = qosThrottler(mpathInfo)
+			throttlers[mpathInfo.Path] = throttler
+		}
+		throttler.Sleep()
+	}
 	if props, errstr, _ = t.coldget(ct, bucket, objname, true); errstr != "" {
 		if errstr != "skip" {
+			requests++
 			glog.Errorln(errstr)
 		}
 		return
 	}
+	requests++
+	bytes += props.size
 	if glog.V(4) {
 		glog.Infof("PREFETCH: %s/%s", bucket, objname)
 	}
@@ -230,15 +250,32 @@ func (t *targetrunner) prefetchMissing(ct context.Context, objname, bucket strin
 
 func (t *targetrunner) addPrefetchList(ct context.Context, objs []string, bucket string,
 	deadline time.Duration, done chan struct{}) error {
+	return t.addPrefetch(ct, objs, nil, bucket, deadline, done)
+}
+
+// addPrefetch queues the objects admitted by the bucket's prefetch budget; sizes, if known,
+// are the objects' sizes as listed
+func (t *targetrunner) addPrefetch(ct context.Context, objs []string, sizes map[string]int64, bucket string,
+	deadline time.Duration, done chan struct{}) (err error) {
+	var estimates map[string]prefetchEst
 	if t.bmdowner.get().IsLocal(bucket) {
-		return fmt.Errorf("Cannot prefetch from a local bucket: %s", bucket)
+		err = fmt.Errorf("Cannot prefetch from a local bucket: %s", bucket)
+	} else {
+		objs, estimates, err = t.planPrefetch(bucket, objs, sizes)
+	}
+	if err != nil {
+		if done != nil {
+			done <- struct{}{}
+		}
+		return
 	}
 	var absdeadline time.Time
 	if deadline != 0 {
 		// 0 is no deadline - if deadline == 0, the absolute deadline is 0 time.
 		absdeadline = time.Now().Add(deadline)
 	}
-	t.prefetchQueue <- filesWithDeadline{ctx: ct, objnames: objs, bucket: bucket, deadline: absdeadline, done: done}
+	t.prefetchQueue <- filesWithDeadline{ctx: ct, objnames: objs, bucket: bucket, deadline: absdeadline, done: done,
+		estimates: estimates}
 	return nil
 }
 
@@ -412,7 +449,7 @@ func (t *targetrunner) listRangeOperation(r *http.Request, apitems []string, msg
 		if errstr != "" {
 			return fmt.Errorf(errstr + detail)
 		}
-		return t.iterateBucketListPages(r, apitems, rangeMsg, operation)
+		return t.iterateBucketListPages(r, apitems, rangeMsg, msg.Action, operation)
 	}
 	// Parse map into ListMsg
 	listMsg, errstr := parseListMsg(jsmap)
@@ -461,7 +498,7 @@ func (t *targetrunner) listOperation(r *http.Request, apitems []string, listMsg
 	return err
 }
 
-func (t *targetrunner) iterateBucketListPages(r *http.Request, apitems []string, rangeMsg *cmn.RangeMsg, operation listf) error {
+func (t *targetrunner) iterateBucketListPages(r *http.Request, apitems []string, rangeMsg *cmn.RangeMsg, action string, operation listf) error {
 	var (
 		bucketListPage *cmn.BucketList
 		err            error
@@ -470,7 +507,11 @@ func (t *targetrunner) iterateBucketListPages(r *http.Request, apitems []string,
 		ct             = t.contextWithAuth(r)
 		msg            = &cmn.GetMsg{GetPrefix: prefix, GetProps: cmn.GetPropsStatus}
 		islocal        = t.bmdowner.get().IsLocal(bucket)
+		prefetch       = action == cmn.ActPrefetch && !islocal
 	)
+	if prefetch {
+		msg.GetProps += ", " + cmn.GetPropsSize // to estimate the Cloud bytes - see planPrefetch
+	}
 
 	min, max, err := parseRange(rangeMsg.Range)
 	if err != nil {
@@ -486,6 +527,10 @@ func (t *targetrunner) iterateBucketListPages(r *http.Request, apitems []string,
 		if islocal {
 			bucketListPage, err = t.prepareLocalObjectList(bucket, msg)
 		} else {
+			if prefetch && t.prefetchListExhausted(bucket) {
+				t.statsif.Add(stats.PrefetchTrimCount, 1) // (the rest of the range, at least one object)
+				return fmt.Errorf("Prefetch budget of bucket %s is exhausted: stopped listing the range", bucket)
+			}
 			bucketListPage, err = getCloudBucketPage(ct, bucket, msg)
 		}
 		if err != nil {
@@ -495,7 +540,17 @@ func (t *targetrunner) iterateBucketListPages(r *http.Request, apitems []string,
 			break
 		}
 
-		matchingEntries := make([]string, 0, len(bucketListPage.Entries))
+		var (
+			matchingEntries = make([]string, 0, len(bucketListPage.Entries))
+			sizes           map[string]int64
+			op              = operation
+		)
+		if prefetch {
+			sizes = make(map[string]int64, len(bucketListPage.Entries))
+			op = func(ct context.Context, objs []string, bucket string, deadline time.Duration, done chan struct{}) error {
+				return t.addPrefetch(ct, objs, sizes, bucket, deadline, done)
+			}
+		}
 		for _, be := range bucketListPage.Entries {
 			if be.Status != cmn.ObjStatusOK {
 				continue
@@ -504,6 +559,9 @@ func (t *targetrunner) iterateBucketListPages(r *http.Request, apitems []string,
 				continue
 			}
 			matchingEntries = append(matchingEntries, be.Name)
+			if sizes != nil {
+				sizes[be.Name] = be.Size
+			}
 		}
 
 		if len(matchingEntries) != 0 {
@@ -514,7 +572,7 @@ func (t *targetrunner) iterateBucketListPages(r *http.Request, apitems []string,
 			}
 
 			// Call listrange function with paged chunk of entries
-			if err := t.listOperation(r, apitems, listMsg, operation); err != nil {
+			if err := t.listOperation(r, apitems, listMsg, op); err != nil {
 				return err
 			}
 		}
@@ -7,16 +7,13 @@ package dfc
 
 import (
 	"container/heap"
-	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
-	"github.com/NVIDIA/dfcpub/atime"
 	"github.com/NVIDIA/dfcpub/cluster"
 	"github.com/NVIDIA/dfcpub/cmn"
 	"github.com/NVIDIA/dfcpub/fs"
@@ -37,6 +34,11 @@ import (
 // triggered when/if a used local capacity exceeds high watermark (ctx.config.LRU.HighWM). LRU then
 // runs automatically. In order to reduce its impact on the live workload, LRU throttles itself
 // in accordance with the current storage-target's utilization (see xaction_throttle.go).
+// Pinned objects (see pinned.go) are never evicted. With lru_config.hot_access_count, the objects
+// accessed at least that many times recently (atime.Runner.AccessCount) are evicted only after
+// all the colder ones, oldest first - so that a scan of many objects read once does not evict
+// the objects read over and over again. Evicting an object that is also in a bucket snapshot
+// (see snapshot.go) frees no space, and LRU never removes the snapshots themselves.
 //
 // There's only one API that this module provides to the rest of the code:
 //   - runLRU - to initiate a new LRU extended action on the local target
@@ -46,7 +48,8 @@ import (
 
 // LRU defaults/tunables
 const (
-	minevict = cmn.MiB
+	minevict   = cmn.MiB
+	atimeBatch = 256 // objects per atime.Runner.AtimeBatch lookup while traversing
 )
 
 type (
@@ -67,16 +70,20 @@ type (
 		newest  time.Time
 		heap    *fileInfoMinHeap
 		oldwork []*fileInfo
+		hotwork []*fileInfo // evicted last (hot_access_count)
+		pending []*fileInfo // traversed, yet to be looked up in the atime maps
 		// init-time
 		xlru         cmn.XactInterface
 		fs           string
+		mpathInfo    *fs.MountpathInfo
+		scope        int // fs.WalkLocal or fs.WalkCloud
 		bucketdir    string
 		throttler    cluster.Throttler
-		atimeRespCh  chan *atime.Response
 		namelocker   cluster.NameLocker
 		bmdowner     cluster.Bowner
-		statsif      stats.Tracker
 		targetrunner cluster.Target
+		emergency    bool // capacity emergency mode: boosted budget, no dont_evict_time, no throttling
+		fdcache      *fdCache
 	}
 )
 
@@ -96,54 +103,47 @@ func (lctx *lructx) onelru(wg *sync.WaitGroup) {
 	}
 	glog.Infof("%s: evicting %s", lctx.bucketdir, cmn.B2S(lctx.totsize, 2))
 
-	if err := filepath.Walk(lctx.bucketdir, lctx.walk); err != nil {
-		s := err.Error()
-		if strings.Contains(s, "xaction") {
-			glog.Infof("%s: stopping traversal: %s", lctx.bucketdir, s)
+	opts := &fs.WalkOpts{
+		Mpaths: []*fs.MountpathInfo{lctx.mpathInfo},
+		Scope:  lctx.scope,
+		Skip:   skipNonEvictable,
+		Abort:  lctx.xlru.ChanAbort(),
+	}
+	if !lctx.emergency {
+		opts.Throttler = func(*fs.MountpathInfo) fs.Throttler { return lctx.throttler }
+	}
+	if err := fs.Mountpaths.WalkObjects(opts, lctx.walk); err != nil {
+		if err == fs.ErrWalkAborted {
+			glog.Infof("%s: stopping traversal: %s aborted", lctx.bucketdir, lctx.xlru)
 		} else {
 			glog.Errorf("%s: failed to traverse, err: %v", lctx.bucketdir, err)
 		}
 		return
 	}
+	lctx.lookup()
 	if err := lctx.evict(); err != nil {
 		glog.Errorf("%s: failed to evict, err: %v", lctx.bucketdir, err)
 	}
 }
 
-func (lctx *lructx) walk(fqn string, osfi os.FileInfo, err error) error {
+// skipNonEvictable skips workfiles (and other non-evictable content) unless old
+func skipNonEvictable(fqn string) bool {
+	spec, info := cluster.FileSpec(fqn)
+	return spec != nil && !spec.PermToEvict() && !info.Old
+}
+
+func (lctx *lructx) walk(entry *fs.WalkEntry) error {
 	var (
-		spec    cluster.ContentResolver
-		info    *cluster.ContentInfo
-		xlru, h = lctx.xlru, lctx.heap
+		fqn, osfi = entry.FQN, entry.FileInfo
+		xlru      = lctx.xlru
 	)
-	if err != nil {
-		glog.Errorf("invoked with err: %v", err)
-		return err
-	}
-	if osfi.Mode().IsDir() {
-		return nil
-	}
-	if spec, info = cluster.FileSpec(fqn); spec != nil && !spec.PermToEvict() && !info.Old {
-		return nil
-	}
-	lctx.throttler.Sleep()
-
-	_, err = os.Stat(fqn)
+	_, info := cluster.FileSpec(fqn)
+	_, err := os.Stat(fqn)
 	if os.IsNotExist(err) {
 		glog.Infof("Warning (race?): %s "+doesnotexist, fqn)
 		glog.Flush()
 		return nil
 	}
-	// abort?
-	select {
-	case <-xlru.ChanAbort():
-		s := fmt.Sprintf("%s aborted, exiting", xlru)
-		glog.Infoln(s)
-		glog.Flush()
-		return errors.New(s)
-	case <-time.After(time.Millisecond):
-		break
-	}
 	if xlru.Finished() {
 		return fmt.Errorf("%s aborted, exiting", xlru)
 	}
@@ -154,33 +154,66 @@ func (lctx *lructx) walk(fqn string, osfi os.FileInfo, err error) error {
 		lctx.oldwork = append(lctx.oldwork, fi) // TODO: upper-limit to avoid OOM; see Push as well
 		return nil
 	}
+	if isPinned(lctx.bmdowner.Get(), entry.Bucket, entry.Objname, entry.IsLocal) {
+		return nil
+	}
 
-	// object eviction: access time
+	// object eviction: access time, unless the atime map has a more recent one - see lookup
 	usetime := atime
-
-	atimeResponse := <-getatimerunner().Atime(fqn, lctx.atimeRespCh)
-	accessTime, ok := atimeResponse.AccessTime, atimeResponse.Ok
-	if ok {
-		usetime = accessTime
-	} else if mtime.After(atime) {
+	if mtime.After(atime) {
 		usetime = mtime
 	}
+	lctx.pending = append(lctx.pending, &fileInfo{fqn: fqn, usetime: usetime, size: stat.Size})
+	if len(lctx.pending) >= atimeBatch {
+		lctx.lookup()
+	}
+	return nil
+}
+
+// lookup gets the access times of the pending objects from the atime maps, all at once,
+// and considers the objects for eviction
+func (lctx *lructx) lookup() {
+	if len(lctx.pending) == 0 {
+		return
+	}
+	fqns := make([]string, len(lctx.pending))
+	for i, fi := range lctx.pending {
+		fqns[i] = fi.fqn
+	}
+	responses := getatimerunner().AtimeBatch(fqns)
+	for _, fi := range lctx.pending {
+		if response := responses[fi.fqn]; response.Ok {
+			fi.usetime = response.AccessTime
+		}
+		lctx.consider(fi)
+	}
+	lctx.pending = lctx.pending[:0]
+}
+
+func (lctx *lructx) consider(fi *fileInfo) {
+	var (
+		fqn, usetime = fi.fqn, fi.usetime
+		h            = lctx.heap
+	)
 	now := time.Now()
 	dontevictime := now.Add(-ctx.config.LRU.DontEvictTime)
-	if usetime.After(dontevictime) {
+	if usetime.After(dontevictime) && !lctx.emergency {
 		if glog.V(4) {
 			glog.Infof("%s: not evicting (usetime %v, dontevictime %v)", fqn, usetime, dontevictime)
 		}
-		return nil
+		return
 	}
 
 	// cleanup after rebalance
-	_, _, err = cluster.ResolveFQN(fqn, lctx.bmdowner)
-	if err != nil {
+	if _, _, err := cluster.ResolveFQN(fqn, lctx.bmdowner); err != nil {
 		glog.Infof("%s: is misplaced, err: %v", fqn, err)
-		fi := &fileInfo{fqn: fqn, size: stat.Size}
 		lctx.oldwork = append(lctx.oldwork, fi)
-		return nil
+		return
+	}
+
+	if hot := ctx.config.LRU.HotAccessCount; hot > 0 && int64(getatimerunner().AccessCount(fqn)) >= hot {
+		lctx.hotwork = append(lctx.hotwork, fi)
+		return
 	}
 
 	// partial optimization:
@@ -191,16 +224,14 @@ func (lctx *lructx) walk(fqn string, osfi os.FileInfo, err error) error {
 		if glog.V(4) {
 			glog.Infof("%s: use-time-after (usetime=%v, newest=%v)", fqn, usetime, lctx.newest)
 		}
-		return nil
+		return
 	}
 	// push and update the context
-	fi := &fileInfo{fqn: fqn, usetime: usetime, size: stat.Size}
 	heap.Push(h, fi)
 	lctx.cursize += fi.size
 	if usetime.After(lctx.newest) {
 		lctx.newest = usetime
 	}
-	return nil
 }
 
 func (lctx *lructx) evict() error {
@@ -216,6 +247,7 @@ func (lctx *lructx) evict() error {
 				continue
 			}
 		}
+		lctx.fdcache.invalidate(fi.fqn)
 		if err := os.Remove(fi.fqn); err != nil {
 			glog.Warningf("LRU: failed to GC %q", fi.fqn)
 			continue
@@ -225,7 +257,7 @@ func (lctx *lructx) evict() error {
 	}
 	for h.Len() > 0 && lctx.totsize > 0 {
 		fi := heap.Pop(h).(*fileInfo)
-		if err := lctx.evictFQN(fi.fqn); err != nil {
+		if err := lctx.evictFQN(fi.fqn, fi.size); err != nil {
 			glog.Errorf("Failed to evict %q, err: %v", fi.fqn, err)
 			continue
 		}
@@ -233,13 +265,29 @@ func (lctx *lructx) evict() error {
 		bevicted += fi.size
 		fevicted++
 	}
-	lctx.statsif.Add(stats.LruEvictSize, bevicted)
-	lctx.statsif.Add(stats.LruEvictCount, fevicted)
+	if lctx.totsize > 0 && len(lctx.hotwork) > 0 {
+		glog.Infof("%s: evicting hot objects, %s to go", lctx.bucketdir, cmn.B2S(lctx.totsize, 2))
+		sort.Slice(lctx.hotwork, func(i, j int) bool { return lctx.hotwork[i].usetime.Before(lctx.hotwork[j].usetime) })
+		for _, fi := range lctx.hotwork {
+			if lctx.totsize <= 0 {
+				break
+			}
+			if err := lctx.evictFQN(fi.fqn, fi.size); err != nil {
+				glog.Errorf("Failed to evict %q, err: %v", fi.fqn, err)
+				continue
+			}
+			lctx.totsize -= fi.size
+			bevicted += fi.size
+			fevicted++
+		}
+	}
+	getstorstatsrunner().AddMpath(lctx.mpathInfo.Path,
+		stats.NamedVal64{Name: stats.LruEvictCount, Val: fevicted}, stats.NamedVal64{Name: stats.LruEvictSize, Val: bevicted})
 	return nil
 }
 
 // evictFQN evicts a given file
-func (lctx *lructx) evictFQN(fqn string) error {
+func (lctx *lructx) evictFQN(fqn string, size int64) error {
 	bucket, objname, err := cluster.ResolveFQN(fqn, lctx.bmdowner)
 	if err != nil {
 		glog.Errorf("Evicting %q with error: %v", fqn, err)
@@ -253,16 +301,18 @@ func (lctx *lructx) evictFQN(fqn string) error {
 	lctx.namelocker.Lock(uname, true)
 	defer lctx.namelocker.Unlock(uname, true)
 
+	lctx.fdcache.invalidate(fqn)
 	if err := os.Remove(fqn); err != nil {
 		return err
 	}
+	addBucketCapacity(fqn, bucket, lctx.scope == fs.WalkLocal, -1, -size)
 	glog.Infof("LRU: evicted %s/%s", bucket, objname)
 	return nil
 }
 
 func (lctx *lructx) evictSize() (err error) {
 	hwm, lwm := ctx.config.LRU.HighWM, ctx.config.LRU.LowWM
-	blocks, bavail, bsize, err := ios.GetFSStats(lctx.bucketdir)
+	blocks, bavail, bsize, err := getiostatrunner().GetFSStats(lctx.bucketdir)
 	if err != nil {
 		return err
 	}
@@ -276,7 +326,12 @@ func (lctx *lructx) evictSize() (err error) {
 		return
 	}
 	lwmblocks := blocks * uint64(lwm) / 100
-	lctx.totsize = int64(used-lwmblocks) * bsize
+	toevict := used - lwmblocks
+	if lctx.emergency {
+		// boosted budget: twice the distance to the low watermark
+		toevict = cmn.MinU64(2*toevict, used)
+	}
+	lctx.totsize = int64(toevict) * bsize
 	return
 }
 
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ================================ Summary ===============================================
+//
+// Maintenance mode (PUT /v1/daemon {"action": "maintenance", "value": true}) drains the
+// daemon before it is restarted: the new object and bucket requests are rejected with 503
+// (Service Unavailable), Retry-After, and the DfcMaintenance header - so that the clients
+// retry later (api's retry transport) or, in the case of a proxy, with another proxy (see
+// api.FailoverTransport) - while the requests in flight are allowed to complete. The daemon
+// is ready to restart once none are left (cmn.MaintenanceStatus, GET what=maintenance).
+// The same applies to the objects pushed to, rebalanced to, and replicated to a target
+// (push and intra-data object requests). The control-plane requests (daemon, cluster,
+// metasync, keepalive) are served as usual.
+//
+// Maintenance mode is not persistent: the restarted daemon serves requests again. See
+// upgrade.go for the rolling upgrade that drives it node by node.
+//
+// A daemon (e.g., a non-primary proxy) can also be put in maintenance mode directly, with
+// no rolling upgrade running. Nothing clears such maintenance mode automatically: it lasts
+// until the daemon restarts or is explicitly taken out of it ({"value": false}).
+//
+// ================================ Summary ===============================================
+
+// maintRetryAfter is the Retry-After (seconds) of the requests rejected in maintenance mode
+const maintRetryAfter = 5
+
+type maintState struct {
+	since    int64 // atomic: unix nano; zero - not in maintenance
+	inflight int64 // atomic: object and bucket requests being served
+	started  time.Time
+}
+
+func (m *maintState) enabled() bool { return atomic.LoadInt64(&m.since) != 0 }
+
+func (m *maintState) status() *cmn.MaintenanceStatus {
+	status := &cmn.MaintenanceStatus{InFlight: atomic.LoadInt64(&m.inflight), Started: m.started}
+	if since := atomic.LoadInt64(&m.since); since != 0 {
+		status.Enabled, status.Since = true, time.Unix(0, since)
+		status.Ready = status.InFlight == 0
+	}
+	return status
+}
+
+// withMaintenance counts the requests in flight and rejects the new ones in maintenance mode
+func (h *httprunner) withMaintenance(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.maint.enabled() {
+			h.maintReject(w, r)
+			return
+		}
+		// counted only when served; checked again, so that the request that races with
+		// setMaintenance is either rejected or waited for
+		atomic.AddInt64(&h.maint.inflight, 1)
+		if h.maint.enabled() {
+			atomic.AddInt64(&h.maint.inflight, -1)
+			h.maintReject(w, r)
+			return
+		}
+		defer atomic.AddInt64(&h.maint.inflight, -1)
+		handler(w, r)
+	}
+}
+
+func (h *httprunner) maintReject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(cmn.HeaderDFCMaintenance, "true")
+	w.Header().Set("Retry-After", strconv.Itoa(maintRetryAfter))
+	h.invalmsghdlr(w, r, fmt.Sprintf("%s is in maintenance mode", h.si.DaemonID), http.StatusServiceUnavailable)
+}
+
+// setMaintenance executes ActMaintenance: enables (value true) or disables (false) the
+// maintenance mode
+func (h *httprunner) setMaintenance(w http.ResponseWriter, r *http.Request, msg *cmn.ActionMsg) {
+	enable, ok := msg.Value.(bool)
+	if !ok {
+		h.invalmsghdlr(w, r, fmt.Sprintf("Invalid %s value %v: expecting true or false", msg.Action, msg.Value))
+		return
+	}
+	if !enable {
+		if atomic.SwapInt64(&h.maint.since, 0) != 0 {
+			glog.Infof("%s: maintenance mode off", h.si.DaemonID)
+		}
+		return
+	}
+	if atomic.CompareAndSwapInt64(&h.maint.since, 0, time.Now().UnixNano()) {
+		glog.Infof("%s: maintenance mode on, %d request(s) in flight", h.si.DaemonID, atomic.LoadInt64(&h.maint.inflight))
+	}
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Object metadata cache (md_cache): the GETs (and HEADs, list and select) read the object's
+// version, checksum, and compression from its xattrs. With md_cache enabled, the target keeps
+// the metadata of up to md_cache.size recently accessed objects in memory, least recently
+// used dropped first.
+//
+// The cached metadata is used only if the object's file has not changed since (same inode,
+// size, mtime, and ctime - the latter changes with any xattr update in place, e.g. the
+// checksum computed asynchronously after PUT). Hits and misses: md.hit.n and md.miss.n.
+//
+// Warm restart: every md_cache.snapshot_time, and upon shutdown, the target saves the cache
+// - one snapshot per mountpath (mdSnapshotName in the mountpath's root), with the entries of
+// the objects stored on that mountpath - and loads the snapshots back when it starts. The
+// restarted target does not need to re-read the xattrs of its hot objects; entries that have
+// gone stale in the meantime fail the validation above and are simply re-read.
+//
+// ================================ Summary ===============================================
+
+const mdSnapshotName = ".dfc-mdcache.json"
+
+type (
+	// objMD is the object's metadata stored in its xattrs
+	objMD struct {
+		Version    string `json:"v,omitempty"`
+		Cksum      string `json:"c,omitempty"`
+		Compressed bool   `json:"z,omitempty"`
+		OrigSize   int64  `json:"o,omitempty"` // compressed object's original size
+	}
+	mdEntry struct {
+		FQN   string `json:"f"`
+		Ino   uint64 `json:"i"`
+		Size  int64  `json:"s"`
+		Mtime int64  `json:"m"`
+		Ctime int64  `json:"t"`
+		MD    objMD  `json:"md"`
+		elem  *list.Element
+	}
+	mdCache struct {
+		sync.Mutex
+		entries map[string]*mdEntry
+		lru     *list.List // front - most recently used
+		saving  sync.Mutex // serializes snapshots
+	}
+)
+
+// objMD returns the object's metadata - cached, with md_cache enabled; fi, if not nil,
+// is the object's file info that the caller already has
+func (t *targetrunner) objMD(fqn string, fi os.FileInfo) objMD {
+	if !ctx.config.MDCache.Enabled {
+		return loadObjMD(fqn)
+	}
+	if fi == nil {
+		var err error
+		if fi, err = os.Stat(fqn); err != nil {
+			t.mdCache.invalidate(fqn)
+			return loadObjMD(fqn)
+		}
+	}
+	if md, ok := t.mdCache.get(fqn, fi); ok {
+		t.statsif.Add(stats.MDCacheHitCount, 1)
+		return md
+	}
+	t.statsif.Add(stats.MDCacheMissCount, 1)
+	md := loadObjMD(fqn) // stat first: a concurrent update makes the entry stale, not wrong
+	t.mdCache.put(fqn, fi, md, ctx.config.MDCache.Size)
+	return md
+}
+
+// loadObjMD reads the object's metadata from its xattrs
+func loadObjMD(fqn string) (md objMD) {
+	if b, errstr := Getxattr(fqn, cmn.XattrObjVersion); errstr == "" {
+		md.Version = string(b)
+	}
+	if b, errstr := Getxattr(fqn, cmn.XattrXXHashVal); errstr == "" {
+		md.Cksum = string(b)
+	}
+	md.OrigSize, md.Compressed = objCompressed(fqn)
+	return
+}
+
+// SaveMDCache saves the metadata cache snapshots; it is called by housekeeping
+func (t *targetrunner) SaveMDCache() {
+	if ctx.config.MDCache.Enabled {
+		t.mdCache.save()
+	}
+}
+
+func newMDCache() *mdCache {
+	return &mdCache{entries: make(map[string]*mdEntry), lru: list.New()}
+}
+
+func (c *mdCache) get(fqn string, fi os.FileInfo) (md objMD, ok bool) {
+	c.Lock()
+	defer c.Unlock()
+	e := c.entries[fqn]
+	if e == nil {
+		return
+	}
+	if e.Ino != inode(fi) || e.Size != fi.Size() || e.Mtime != fi.ModTime().UnixNano() || e.Ctime != ctime(fi) {
+		c.remove(e)
+		return
+	}
+	c.lru.MoveToFront(e.elem)
+	return e.MD, true
+}
+
+func (c *mdCache) put(fqn string, fi os.FileInfo, md objMD, capacity int) {
+	e := &mdEntry{FQN: fqn, Ino: inode(fi), Size: fi.Size(), Mtime: fi.ModTime().UnixNano(), Ctime: ctime(fi), MD: md}
+	c.Lock()
+	c.add(e, true)
+	c.evict(capacity)
+	c.Unlock()
+}
+
+// invalidate removes the object's entry - to be called when the object is deleted or renamed
+func (c *mdCache) invalidate(fqn string) {
+	c.Lock()
+	if e := c.entries[fqn]; e != nil {
+		c.remove(e)
+	}
+	c.Unlock()
+}
+
+// under lock
+func (c *mdCache) add(e *mdEntry, front bool) {
+	if prev := c.entries[e.FQN]; prev != nil {
+		c.remove(prev)
+	}
+	if front {
+		e.elem = c.lru.PushFront(e)
+	} else {
+		e.elem = c.lru.PushBack(e)
+	}
+	c.entries[e.FQN] = e
+}
+
+// under lock
+func (c *mdCache) evict(capacity int) {
+	for el := c.lru.Back(); el != nil && len(c.entries) > capacity; {
+		prev := el.Prev()
+		c.remove(el.Value.(*mdEntry))
+		el = prev
+	}
+}
+
+// under lock
+func (c *mdCache) remove(e *mdEntry) {
+	delete(c.entries, e.FQN)
+	c.lru.Remove(e.elem)
+}
+
+// save writes the snapshot of each available mountpath, most recently used entries first
+func (c *mdCache) save() {
+	c.saving.Lock()
+	defer c.saving.Unlock()
+	c.Lock()
+	all := make([]*mdEntry, 0, len(c.entries))
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		all = append(all, el.Value.(*mdEntry)) // entries are never modified - only replaced
+	}
+	c.Unlock()
+
+	availablePaths, _ := fs.Mountpaths.Get()
+	snapshots := make(map[string][]*mdEntry, len(availablePaths))
+	for mpath := range availablePaths {
+		snapshots[mpath] = []*mdEntry{}
+	}
+	for _, e := range all {
+		if mpathInfo, _ := fs.Mountpaths.Path2MpathInfo(e.FQN); mpathInfo != nil {
+			snapshots[mpathInfo.Path] = append(snapshots[mpathInfo.Path], e)
+		}
+	}
+	for mpath, entries := range snapshots {
+		if err := saveMDSnapshot(mpath, entries); err != nil {
+			glog.Errorf("Failed to save md_cache snapshot of %s, err: %v", mpath, err)
+		}
+	}
+}
+
+// load reads the snapshots of the available mountpaths, up to capacity entries in total
+func (c *mdCache) load(capacity int) {
+	availablePaths, _ := fs.Mountpaths.Get()
+	for mpath := range availablePaths {
+		entries, err := loadMDSnapshot(mpath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				glog.Errorf("Failed to load md_cache snapshot of %s, err: %v", mpath, err)
+			}
+			continue
+		}
+		c.Lock()
+		for _, e := range entries {
+			if len(c.entries) >= capacity {
+				break
+			}
+			if e.FQN != "" && strings.HasPrefix(e.FQN, mpath+string(filepath.Separator)) {
+				c.add(e, false)
+			}
+		}
+		c.Unlock()
+		glog.Infof("%s: loaded md_cache snapshot (%d entries)", mpath, len(entries))
+	}
+}
+
+func saveMDSnapshot(mpath string, entries []*mdEntry) error {
+	return cmn.LocalSave(filepath.Join(mpath, mdSnapshotName), entries)
+}
+
+func loadMDSnapshot(mpath string) (entries []*mdEntry, err error) {
+	err = cmn.LocalLoad(filepath.Join(mpath, mdSnapshotName), &entries)
+	return
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestMDCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mdcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		c        = newMDCache()
+		capacity = 2
+		fqns     = make([]string, 3)
+	)
+	for i := range fqns {
+		fqns[i] = filepath.Join(dir, "o"+strconv.Itoa(i+1))
+		if err := ioutil.WriteFile(fqns[i], []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if errstr := Setxattr(fqns[i], cmn.XattrObjVersion, []byte("1")); errstr != "" {
+			t.Skipf("xattrs not supported: %s", errstr)
+		}
+	}
+	stat := func(fqn string) os.FileInfo {
+		fi, err := os.Stat(fqn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi
+	}
+
+	// miss, then hit
+	if _, ok := c.get(fqns[0], stat(fqns[0])); ok {
+		t.Fatal("expected miss")
+	}
+	c.put(fqns[0], stat(fqns[0]), loadObjMD(fqns[0]), capacity)
+	if md, ok := c.get(fqns[0], stat(fqns[0])); !ok || md.Version != "1" || md.Compressed {
+		t.Fatalf("expected hit with version 1, got %+v (hit %t)", md, ok)
+	}
+
+	// xattr updated in place: ctime changes, the entry is stale
+	time.Sleep(10 * time.Millisecond)
+	Setxattr(fqns[0], cmn.XattrObjVersion, []byte("2"))
+	if _, ok := c.get(fqns[0], stat(fqns[0])); ok {
+		t.Fatal("expected stale entry to miss")
+	}
+	c.put(fqns[0], stat(fqns[0]), loadObjMD(fqns[0]), capacity)
+
+	// capacity: LRU goes first
+	for _, fqn := range fqns[1:] {
+		c.put(fqn, stat(fqn), loadObjMD(fqn), capacity)
+	}
+	if _, ok := c.get(fqns[0], stat(fqns[0])); ok || len(c.entries) != capacity {
+		t.Fatalf("expected %s evicted, %d entries", fqns[0], len(c.entries))
+	}
+
+	// snapshot: saved MRU first, loaded back in the same order and validated upon use
+	all := make([]*mdEntry, 0, capacity)
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		all = append(all, el.Value.(*mdEntry))
+	}
+	if err := saveMDSnapshot(dir, all); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := loadMDSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].FQN != fqns[2] || entries[1].FQN != fqns[1] {
+		t.Fatalf("unexpected snapshot %+v", entries)
+	}
+	warm := newMDCache()
+	for _, e := range entries {
+		warm.add(e, false)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := ioutil.WriteFile(fqns[1], []byte("new data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if md, ok := warm.get(fqns[2], stat(fqns[2])); !ok || md.Version != "1" {
+		t.Errorf("expected warm hit with version 1, got %+v (hit %t)", md, ok)
+	}
+	if _, ok := warm.get(fqns[1], stat(fqns[1])); ok {
+		t.Error("expected the object overwritten while down to miss")
+	}
+}
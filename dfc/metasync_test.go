@@ -82,7 +82,7 @@ func newPrimary() *proxyrunner {
 	smap.ProxySI = p.si
 	p.smapowner.put(smap)
 
-	p.httpclientLongTimeout = &http.Client{}
+	p.intra = cmn.NewIntraClient(cmn.IntraClientArgs{})
 	ctx.config.Periodic.RetrySyncTime = time.Millisecond * 100
 	ctx.config.KeepaliveTracker.Proxy.Name = "heartbeat"
 	ctx.config.KeepaliveTracker.Proxy.IntervalStr = "as"
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/memsys"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Memory-mapped reads: with mmap_read enabled, a GET of an object no larger than
+// mmap_read.max_object_size is served out of the object's memory mapping - a single
+// write of the mapped bytes instead of the open/read/close sequence. The mappings are
+// cached (keyed by fqn) and reused by the subsequent GETs, up to mmap_read.cache_size
+// bytes mapped in total; the least recently used mappings are unmapped first.
+//
+// A cached mapping is used only if the object's file is still the one that was mapped
+// (same inode, size, and mtime) - objects are overwritten by renaming the workfile
+// (see putatomic.go), and so an overwritten or deleted object is never served stale.
+// A mapping is unmapped only when no longer referenced by any of the GETs in progress.
+//
+// Mapped pages are the page cache - no extra memory is allocated - but they do count
+// against the target's memory: no new mappings are made while memsys reports moderate
+// (or higher) memory pressure, and all unreferenced mappings are dropped when the
+// pressure is high. Hits and misses are counted by get.mmap.hit.n and get.mmap.miss.n.
+//
+// ================================ Summary ===============================================
+
+type (
+	mmapEntry struct {
+		fqn   string
+		data  []byte
+		ino   uint64
+		size  int64
+		mtime int64
+		refc  int
+		stale bool // removed from the cache while referenced: unmap upon release
+		elem  *list.Element
+	}
+	mmapCache struct {
+		sync.Mutex
+		entries map[string]*mmapEntry
+		lru     *list.List // front - most recently used
+		mapped  int64      // total bytes mapped, including the stale entries
+	}
+)
+
+// mmapGet returns the object's mapping or nil - to read the object the regular way
+func (t *targetrunner) mmapGet(fqn string) *mmapEntry {
+	e, hit := t.mmapCache.acquire(fqn, ctx.config.MmapRead.CacheSize, gmem2.Pressure())
+	if e == nil {
+		return nil
+	}
+	if hit {
+		t.statsif.Add(stats.GetMmapHitCount, 1)
+	} else {
+		t.statsif.Add(stats.GetMmapMissCount, 1)
+	}
+	return e
+}
+
+func newMmapCache() *mmapCache {
+	return &mmapCache{entries: make(map[string]*mmapEntry), lru: list.New()}
+}
+
+// acquire returns the mapping of the object, mapping it if need be - or nil if the object
+// cannot or should not be mapped (in which case it is to be read the regular way);
+// the caller must release a non-nil mapping
+func (c *mmapCache) acquire(fqn string, capacity int64, pressure int) (e *mmapEntry, hit bool) {
+	fi, err := os.Stat(fqn)
+	if err != nil || fi.Size() == 0 || fi.Size() > capacity {
+		return
+	}
+	ino, size, mtime := inode(fi), fi.Size(), fi.ModTime().UnixNano()
+
+	c.Lock()
+	if pressure >= memsys.PressureHigh {
+		c.purge()
+	}
+	if e = c.entries[fqn]; e != nil {
+		if e.ino == ino && e.size == size && e.mtime == mtime {
+			e.refc++
+			c.lru.MoveToFront(e.elem)
+			c.Unlock()
+			return e, true
+		}
+		c.remove(e)
+		e = nil
+	}
+	c.Unlock()
+	if pressure >= memsys.PressureModerate {
+		return
+	}
+
+	file, err := os.Open(fqn)
+	if err != nil {
+		return
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	file.Close()
+	if err != nil {
+		glog.Errorf("Failed to mmap %s, err: %v", fqn, err)
+		return
+	}
+	e = &mmapEntry{fqn: fqn, data: data, ino: ino, size: size, mtime: mtime, refc: 1}
+
+	c.Lock()
+	if prev := c.entries[fqn]; prev != nil {
+		c.remove(prev) // mapped concurrently - the newer mapping wins
+	}
+	e.elem = c.lru.PushFront(e)
+	c.entries[fqn] = e
+	c.mapped += size
+	for el := c.lru.Back(); el != nil && c.mapped > capacity; {
+		prev := el.Prev()
+		if victim := el.Value.(*mmapEntry); victim != e {
+			c.remove(victim)
+		}
+		el = prev
+	}
+	c.Unlock()
+	return
+}
+
+// reader returns the reader of the entire object or - if length > 0 - of its range
+func (e *mmapEntry) reader(offset, length int64) io.Reader {
+	if length == 0 {
+		return bytes.NewReader(e.data)
+	}
+	return io.NewSectionReader(bytes.NewReader(e.data), offset, length)
+}
+
+func (c *mmapCache) release(e *mmapEntry) {
+	c.Lock()
+	e.refc--
+	if e.stale && e.refc == 0 {
+		c.unmap(e)
+	}
+	c.Unlock()
+}
+
+// purge removes all entries; the referenced ones are unmapped upon release
+func (c *mmapCache) purge() {
+	for _, e := range c.entries {
+		c.remove(e)
+	}
+}
+
+// under lock
+func (c *mmapCache) remove(e *mmapEntry) {
+	delete(c.entries, e.fqn)
+	c.lru.Remove(e.elem)
+	e.stale = true
+	if e.refc == 0 {
+		c.unmap(e)
+	}
+}
+
+// under lock
+func (c *mmapCache) unmap(e *mmapEntry) {
+	if err := syscall.Munmap(e.data); err != nil {
+		glog.Errorf("Failed to munmap %s, err: %v", e.fqn, err)
+	}
+	e.data = nil
+	c.mapped -= e.size
+}
+
+func inode(fi os.FileInfo) uint64 {
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
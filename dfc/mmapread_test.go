@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/memsys"
+)
+
+func TestMmapCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		fqn := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(fqn+".work", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(fqn+".work", fqn); err != nil {
+			t.Fatal(err)
+		}
+		return fqn
+	}
+	read := func(e *mmapEntry, offset, length int64) string {
+		b, err := ioutil.ReadAll(e.reader(offset, length))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	var (
+		c        = newMmapCache()
+		capacity = int64(8)
+		fqn1     = write("o1", "0123")
+		fqn2     = write("o2", "abcd")
+	)
+
+	// miss, then hit
+	e, hit := c.acquire(fqn1, capacity, memsys.PressureLow)
+	if e == nil || hit {
+		t.Fatalf("expected miss, got %v (hit %t)", e, hit)
+	}
+	if got := read(e, 0, 0); got != "0123" {
+		t.Errorf("expected 0123, got %q", got)
+	}
+	c.release(e)
+	e, hit = c.acquire(fqn1, capacity, memsys.PressureLow)
+	if e == nil || !hit {
+		t.Fatalf("expected hit, got %v (hit %t)", e, hit)
+	}
+	if got := read(e, 1, 2); got != "12" {
+		t.Errorf("expected 12, got %q", got)
+	}
+
+	// overwritten while referenced: remapped; the old mapping stays valid until released
+	time.Sleep(10 * time.Millisecond)
+	write("o1", "4567")
+	e2, hit := c.acquire(fqn1, capacity, memsys.PressureLow)
+	if e2 == nil || hit {
+		t.Fatalf("expected miss, got %v (hit %t)", e2, hit)
+	}
+	if got, old := read(e2, 0, 0), read(e, 0, 0); got != "4567" || old != "0123" {
+		t.Errorf("expected 4567 and 0123, got %q and %q", got, old)
+	}
+	c.release(e)
+	c.release(e2)
+	if c.mapped != 4 {
+		t.Errorf("expected 4 bytes mapped, got %d", c.mapped)
+	}
+
+	// moderate pressure: hits only
+	if e, _ = c.acquire(fqn2, capacity, memsys.PressureModerate); e != nil {
+		t.Error("expected no new mappings under moderate pressure")
+	}
+	if e, hit = c.acquire(fqn1, capacity, memsys.PressureModerate); e == nil || !hit {
+		t.Fatalf("expected hit, got %v (hit %t)", e, hit)
+	}
+	c.release(e)
+
+	// capacity: LRU goes first
+	fqn3 := write("o3", "ABCD")
+	for _, fqn := range []string{fqn2, fqn3} {
+		if e, _ = c.acquire(fqn, capacity, memsys.PressureLow); e == nil {
+			t.Fatalf("expected %s mapped", fqn)
+		}
+		c.release(e)
+	}
+	if _, ok := c.entries[fqn1]; ok || len(c.entries) != 2 || c.mapped != capacity {
+		t.Errorf("expected o1 unmapped, got %d entries, %d bytes mapped", len(c.entries), c.mapped)
+	}
+
+	// high pressure: all dropped
+	if e, _ = c.acquire(fqn2, capacity, memsys.PressureHigh); e != nil || len(c.entries) != 0 || c.mapped != 0 {
+		t.Errorf("expected all unmapped, got %d entries, %d bytes mapped", len(c.entries), c.mapped)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Message bus: the xactions running on different targets coordinate by publishing small
+// JSON messages on named topics - over the intra-control network, to POST /v1/msgbus of
+// the other targets - rather than by polling each other.
+//
+// Subscribers receive the messages via buffered channels; a message that does not fit is
+// dropped (and logged), and so a subscriber must be able to recover on its own - e.g., by
+// checking the state of the sender directly, as the rebalance does. The bus retains the last
+// message of each sender on each topic and hands the retained messages to new subscribers
+// first: a subscriber does not miss the messages published before it has subscribed.
+// There is no ordering across senders.
+//
+// Topics:
+//   - rebalance.done (rebDoneMsg): the sender has finished (or aborted) the global rebalance
+//     of the given Smap version; the target that has joined the cluster waits for all
+//     others to finish
+//
+// ================================ Summary ===============================================
+
+const busTopicRebalanceDone = "rebalance.done"
+
+type (
+	busMsg struct {
+		Topic   string              `json:"topic"`
+		From    string              `json:"from"` // sender's daemon ID
+		Payload jsoniter.RawMessage `json:"payload,omitempty"`
+	}
+	busSub struct {
+		topic string
+		C     chan *busMsg
+	}
+	msgBus struct {
+		sync.Mutex
+		subs     map[string][]*busSub
+		retained map[string]map[string]*busMsg // topic => sender => last message
+	}
+	rebDoneMsg struct {
+		Version int64 `json:"version"` // Smap version
+		Aborted bool  `json:"aborted,omitempty"`
+	}
+)
+
+func newMsgBus() *msgBus {
+	return &msgBus{subs: make(map[string][]*busSub), retained: make(map[string]map[string]*busMsg)}
+}
+
+// subscribe returns the subscription that receives the topic's retained messages, and
+// then the new ones, via the channel of the given size
+func (b *msgBus) subscribe(topic string, size int) *busSub {
+	sub := &busSub{topic: topic, C: make(chan *busMsg, size)}
+	b.Lock()
+	b.subs[topic] = append(b.subs[topic], sub)
+	for _, msg := range b.retained[topic] {
+		b.send(sub, msg)
+	}
+	b.Unlock()
+	return sub
+}
+
+func (b *msgBus) unsubscribe(sub *busSub) {
+	b.Lock()
+	subs := b.subs[sub.topic]
+	for i, s := range subs {
+		if s == sub {
+			b.subs[sub.topic] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(b.subs[sub.topic]) == 0 {
+		delete(b.subs, sub.topic)
+	}
+	b.Unlock()
+}
+
+// deliver retains the message and passes it on to the topic's subscribers
+func (b *msgBus) deliver(msg *busMsg) {
+	b.Lock()
+	senders, ok := b.retained[msg.Topic]
+	if !ok {
+		senders = make(map[string]*busMsg)
+		b.retained[msg.Topic] = senders
+	}
+	senders[msg.From] = msg
+	for _, sub := range b.subs[msg.Topic] {
+		b.send(sub, msg)
+	}
+	b.Unlock()
+}
+
+// under lock
+func (b *msgBus) send(sub *busSub, msg *busMsg) {
+	select {
+	case sub.C <- msg:
+	default:
+		glog.Warningf("msgbus: %s subscriber is full, dropping the message from %s", msg.Topic, msg.From)
+	}
+}
+
+// publish sends the message to the given targets; the targets that fail to receive it
+// are logged
+func (t *targetrunner) publish(topic string, payload interface{}, targets map[string]*cluster.Snode) {
+	b, err := jsoniter.Marshal(payload)
+	cmn.Assert(err == nil, err)
+	body, err := jsoniter.Marshal(&busMsg{Topic: topic, From: t.si.DaemonID, Payload: b})
+	cmn.Assert(err == nil, err)
+	res := t.broadcast(bcastCallArgs{
+		req: reqArgs{
+			method: http.MethodPost,
+			path:   cmn.URLPath(cmn.Version, cmn.MsgBus),
+			body:   body,
+		},
+		internal: true,
+		timeout:  ctx.config.Timeout.CplaneOperation,
+		servers:  []map[string]*cluster.Snode{targets},
+	})
+	for r := range res {
+		if r.err != nil {
+			glog.Warningf("msgbus: failed to publish %s to %s, err: %v", topic, r.si.DaemonID, r.err)
+		}
+	}
+}
+
+// POST /v1/msgbus
+func (t *targetrunner) msgbusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		cmn.InvalidHandlerWithMsg(w, r, "invalid method for /msgbus path")
+		return
+	}
+	msg := &busMsg{}
+	if err := t.readJSON(w, r, msg); err != nil {
+		return
+	}
+	if msg.Topic == "" || msg.From == "" {
+		t.invalmsghdlr(w, r, "msgbus: message without topic or sender")
+		return
+	}
+	t.msgbus.deliver(msg)
+}
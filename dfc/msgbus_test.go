@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+)
+
+func TestMsgBus(t *testing.T) {
+	var (
+		b    = newMsgBus()
+		recv = func(sub *busSub) (froms []string) {
+			for {
+				select {
+				case msg := <-sub.C:
+					froms = append(froms, msg.From)
+				default:
+					return
+				}
+			}
+		}
+	)
+	// published before anyone has subscribed: retained, the last one per sender
+	b.deliver(&busMsg{Topic: busTopicRebalanceDone, From: "t1", Payload: []byte(`{"version":1}`)})
+	b.deliver(&busMsg{Topic: busTopicRebalanceDone, From: "t1", Payload: []byte(`{"version":2}`)})
+	b.deliver(&busMsg{Topic: "other", From: "t2"})
+
+	sub := b.subscribe(busTopicRebalanceDone, 2)
+	select {
+	case msg := <-sub.C:
+		if msg.From != "t1" || string(msg.Payload) != `{"version":2}` {
+			t.Errorf("expected the last retained message of t1, got %+v", msg)
+		}
+	default:
+		t.Fatal("expected the retained message")
+	}
+	if froms := recv(sub); len(froms) != 0 {
+		t.Errorf("expected a single retained message, got more from %v", froms)
+	}
+
+	// full subscriber: dropped rather than blocking the sender
+	for _, from := range []string{"t2", "t3", "t4"} {
+		b.deliver(&busMsg{Topic: busTopicRebalanceDone, From: from})
+	}
+	if froms := recv(sub); len(froms) != 2 || froms[0] != "t2" || froms[1] != "t3" {
+		t.Errorf("expected t2 and t3, got %v", froms)
+	}
+
+	b.unsubscribe(sub)
+	b.deliver(&busMsg{Topic: busTopicRebalanceDone, From: "t5"})
+	if froms := recv(sub); len(froms) != 0 {
+		t.Errorf("expected nothing after unsubscribe, got %v", froms)
+	}
+	if len(b.subs) != 0 || len(b.retained[busTopicRebalanceDone]) != 5 {
+		t.Errorf("unexpected subs %v, retained %v", b.subs, b.retained)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
 	"github.com/json-iterator/go"
 )
 
@@ -33,7 +34,7 @@ func newDiscoverServerPrimary() *proxyrunner {
 	p := proxyrunner{}
 	p.si = newSnode("primary", httpProto, &net.TCPAddr{}, &net.TCPAddr{}, &net.TCPAddr{})
 	p.smapowner = &smapowner{}
-	p.httpclientLongTimeout = &http.Client{}
+	p.intra = cmn.NewIntraClient(cmn.IntraClientArgs{})
 	ctx.config.KeepaliveTracker.Proxy.Name = "heartbeat"
 	p.keepalive = newProxyKeepaliveRunner(&p)
 	return &p
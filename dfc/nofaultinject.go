@@ -0,0 +1,14 @@
+// +build !faultinject
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+// fault injection is disabled in the regular builds - see faultinject.go
+
+func (t *targetrunner) registerFaultHandler() {}
+
+func injectFault(point, bucket, objname string) string { return "" }
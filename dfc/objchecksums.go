@@ -0,0 +1,251 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Batch checksums (ActChecksums): returns the checksums and sizes of many objects at once,
+// so that dataset validation tools can diff the bucket against their local copies without
+// downloading (or HEAD-ing one by one) the objects.
+//
+// The proxy splits the listed object names between the targets (HRW) and sends each target
+// its share; a prefix, on the other hand, is broadcast to all targets. Each target returns
+// the checksums stored with the objects (xattr) - and computes the checksums of the objects
+// stored without one, unless checksumming is disabled for the bucket. The objects that are
+// not stored in the cluster (including the Cloud objects that are not cached) are omitted.
+//
+// ================================ Summary ===============================================
+
+func parseChecksumsMsg(msg *cmn.ActionMsg) (*cmn.ChecksumsMsg, error) {
+	csmsg := &cmn.ChecksumsMsg{}
+	if msg.Value == nil {
+		return csmsg, nil
+	}
+	b, err := jsoniter.Marshal(msg.Value)
+	if err == nil {
+		err = jsoniter.Unmarshal(b, csmsg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s message value %+v, err: %v", msg.Action, msg.Value, err)
+	}
+	return csmsg, nil
+}
+
+//
+// proxy
+//
+
+// POST { checksums } /v1/buckets/bucket-name
+func (p *proxyrunner) objChecksums(w http.ResponseWriter, r *http.Request, bucket string, msg *cmn.ActionMsg) {
+	csmsg, err := parseChecksumsMsg(msg)
+	if err != nil {
+		p.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	var (
+		smap   = p.smapowner.get()
+		shares = make(map[string]*cmn.ChecksumsMsg, len(smap.Tmap)) // daemon ID => target's share
+	)
+	if len(csmsg.Objnames) == 0 {
+		for sid := range smap.Tmap {
+			shares[sid] = csmsg
+		}
+	} else {
+		for _, objname := range csmsg.Objnames {
+			si, errstr := hrwTarget(bucket, objname, smap)
+			if errstr != "" {
+				p.invalmsghdlr(w, r, errstr)
+				return
+			}
+			share, ok := shares[si.DaemonID]
+			if !ok {
+				share = &cmn.ChecksumsMsg{}
+				shares[si.DaemonID] = share
+			}
+			share.Objnames = append(share.Objnames, objname)
+		}
+	}
+
+	var (
+		out  = make(cmn.ObjChecksums, len(csmsg.Objnames))
+		errs = make([]string, 0)
+		mtx  = &sync.Mutex{}
+		wg   = &sync.WaitGroup{}
+	)
+	for sid, share := range shares {
+		jsbytes, err := jsoniter.Marshal(&cmn.ActionMsg{Action: cmn.ActChecksums, Value: share})
+		cmn.Assert(err == nil, err)
+		wg.Add(1)
+		go func(si *cluster.Snode, body []byte) {
+			defer wg.Done()
+			res := p.call(callArgs{
+				si: si,
+				req: reqArgs{
+					method: http.MethodPost,
+					base:   si.IntraControlNet.DirectURL,
+					path:   cmn.URLPath(cmn.Version, cmn.Buckets, bucket),
+					body:   body,
+				},
+				timeout: ctx.config.Timeout.DefaultLong,
+			})
+			cksums := make(cmn.ObjChecksums)
+			if res.err == nil {
+				if err := jsoniter.Unmarshal(res.outjson, &cksums); err != nil {
+					res.errstr = fmt.Sprintf("failed to unmarshal checksums, err: %v", err)
+				}
+			}
+			mtx.Lock()
+			if res.err != nil || res.errstr != "" {
+				errs = append(errs, fmt.Sprintf("%s: %s", si.DaemonID, res.errstr))
+			}
+			for objname, cksum := range cksums {
+				out[objname] = cksum
+			}
+			mtx.Unlock()
+		}(smap.Tmap[sid], jsbytes)
+	}
+	wg.Wait()
+	if len(errs) != 0 {
+		p.invalmsghdlr(w, r, fmt.Sprintf("%s %s failed: %s", msg.Action, bucket, strings.Join(errs, "; ")))
+		return
+	}
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	p.writeJSON(w, r, jsbytes, "checksums")
+}
+
+//
+// target
+//
+
+// POST { checksums } /v1/buckets/bucket-name
+func (t *targetrunner) objChecksums(w http.ResponseWriter, r *http.Request, bucket string, msg *cmn.ActionMsg) {
+	csmsg, err := parseChecksumsMsg(msg)
+	if err != nil {
+		t.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	var (
+		bucketmd = t.bmdowner.get()
+		islocal  = bucketmd.IsLocal(bucket)
+		cksumcfg = &ctx.config.Cksum
+		out      = make(cmn.ObjChecksums, len(csmsg.Objnames))
+		mtx      = &sync.Mutex{}
+	)
+	if bucketProps, _, defined := bucketmd.propsAndChecksum(bucket); defined {
+		cksumcfg = &bucketProps.CksumConf
+	}
+	add := func(objname, fqn string) error {
+		cksum, errstr := t.objChecksum(bucket, objname, fqn, cksumcfg.Checksum != cmn.ChecksumNone)
+		if errstr != "" {
+			return fmt.Errorf("%s/%s: %s", bucket, objname, errstr)
+		}
+		if cksum != nil {
+			mtx.Lock()
+			out[objname] = cksum
+			mtx.Unlock()
+		}
+		return nil
+	}
+
+	if len(csmsg.Objnames) > 0 {
+		for _, objname := range csmsg.Objnames {
+			fqn, errstr := cluster.FQN(bucket, objname, islocal)
+			if errstr == "" {
+				err = add(objname, fqn)
+			} else {
+				err = fmt.Errorf("%s/%s: %s", bucket, objname, errstr)
+			}
+			if err != nil {
+				t.invalmsghdlr(w, r, err.Error())
+				return
+			}
+		}
+	} else {
+		scope := fs.WalkCloud
+		if islocal {
+			scope = fs.WalkLocal
+		}
+		opts := &fs.WalkOpts{Scope: scope, Bucket: bucket, Prefix: csmsg.Prefix, Skip: skipNonProcessable}
+		err = fs.Mountpaths.WalkObjects(opts, func(entry *fs.WalkEntry) error {
+			return add(entry.Objname, entry.FQN)
+		})
+		if err != nil {
+			t.invalmsghdlr(w, r, err.Error())
+			return
+		}
+	}
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	t.writeJSON(w, r, jsbytes, "checksums")
+}
+
+// objChecksum returns the object's stored checksum - or, if there is none, computes it
+// (compute == true); nil if the object does not exist
+func (t *targetrunner) objChecksum(bucket, objname, fqn string, compute bool) (cksum *cmn.ObjChecksum, errstr string) {
+	uname := cluster.Uname(bucket, objname)
+	t.rtnamemap.Lock(uname, false)
+	defer t.rtnamemap.Unlock(uname, false)
+
+	finfo, err := os.Stat(fqn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ""
+		}
+		t.fshc(err, fqn)
+		return nil, err.Error()
+	}
+	cksum = &cmn.ObjChecksum{Type: cmn.ChecksumNone, Size: finfo.Size()}
+	if size, ok := objCompressed(fqn); ok {
+		cksum.Size = size
+	}
+	if xxHashBinary, errstr := Getxattr(fqn, cmn.XattrXXHashVal); errstr == "" && len(xxHashBinary) > 0 {
+		cksum.Type, cksum.Value = cmn.ParseStoredCksum(xxHashBinary)
+		return cksum, ""
+	}
+	if !compute {
+		return cksum, ""
+	}
+	file, err := openObject(fqn)
+	if err != nil {
+		t.fshc(err, fqn)
+		return nil, err.Error()
+	}
+	kind := t.bmdowner.get().cksumType(bucket)
+	if !cmn.ValidCksumType(kind) {
+		kind = cmn.ChecksumXXHash
+	}
+	buf, slab := gmem2.AllocFromSlab2(cksum.Size)
+	cksum.Value, errstr = cmn.ComputeCksum(kind, file, buf)
+	slab.Free(buf)
+	file.Close()
+	if errstr != "" {
+		glog.Errorf("Failed to checksum %s, err: %s", fqn, errstr)
+		return nil, errstr
+	}
+	cksum.Type = kind
+	return cksum, ""
+}
+
+// skipNonProcessable skips workfiles and other content that is not an object
+func skipNonProcessable(fqn string) bool {
+	spec, _ := cluster.FileSpec(fqn)
+	return spec != nil && !spec.PermToProcess()
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Object TTL: a client may PUT an object with a TTL (cmn.HeaderDFCObjTTL, e.g. "24h") -
+// scratch data, intermediate results, and such. The target stores the object's expiration
+// time in the xattr (cmn.XattrExpires) that travels with the object when it is rebalanced,
+// replicated, or compressed; overwriting the object without a TTL makes it permanent.
+//
+// Every obj_ttl.run_time the target runs the ActExpire xaction that walks all buckets and
+// removes the expired objects, regardless of the capacity usage and LRU settings. For a
+// Cloud bucket, it is the cached copy that gets removed - the object in the Cloud stays.
+// The xaction is skipped when rebalance is running, and it is throttled in accordance with
+// the disk utilization. Pinned objects (see pinned.go) are never removed.
+// Stats: expired.n and expired.size.
+//
+// ================================ Summary ===============================================
+
+// parseObjTTL returns the expiration time of the object PUT with the TTL (zero if none)
+func parseObjTTL(s string, now time.Time) (expires time.Time, errstr string) {
+	if s == "" {
+		return
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl <= 0 {
+		errstr = fmt.Sprintf("Invalid %s %q: expecting positive duration, e.g. 24h", cmn.HeaderDFCObjTTL, s)
+		return
+	}
+	return now.Add(ttl), ""
+}
+
+// objExpires returns the object's expiration time, or false if the object never expires
+func objExpires(fqn string) (expires time.Time, ok bool) {
+	b, errstr := Getxattr(fqn, cmn.XattrExpires)
+	if errstr != "" || len(b) == 0 {
+		return
+	}
+	expires, err := time.Parse(time.RFC3339Nano, string(b))
+	return expires, err == nil
+}
+
+// RunExpire removes the expired objects of all buckets on all available mountpaths;
+// it is called by housekeeping and does nothing if the previous run is in progress
+func (t *targetrunner) RunExpire() {
+	if t.IsRebalancing() {
+		glog.Infoln("Expire: rebalance is running - skipping")
+		return
+	}
+	xexp := t.xactinp.renewExpire(t)
+	if xexp == nil {
+		return
+	}
+	var (
+		n, size int64
+		now     = time.Now()
+	)
+	glog.Infof("Expire: %s started", xexp)
+	opts := &fs.WalkOpts{
+		Skip:  skipNonProcessable,
+		Abort: xexp.ChanAbort(),
+		Throttler: func(mpathInfo *fs.MountpathInfo) fs.Throttler {
+			return &cluster.Throttle{
+				Riostat:      getiostatrunner(),
+				CapUsedHigh:  &ctx.config.LRU.HighWM,
+				DiskUtilLow:  &ctx.config.Xaction.DiskUtilLowWM,
+				DiskUtilHigh: &ctx.config.Xaction.DiskUtilHighWM,
+				Period:       &ctx.config.Periodic.StatsTime,
+				Path:         mpathInfo.Path,
+				FS:           mpathInfo.FileSystem,
+				Flag:         cluster.OnDiskUtil}
+		},
+	}
+	bucketmd := t.bmdowner.get()
+	err := fs.Mountpaths.WalkObjects(opts, func(entry *fs.WalkEntry) error {
+		if expires, ok := objExpires(entry.FQN); !ok || expires.After(now) {
+			return nil
+		}
+		if isPinned(&bucketmd.BMD, entry.Bucket, entry.Objname, entry.IsLocal) {
+			return nil
+		}
+		removed, err := t.expireObj(entry.Bucket, entry.Objname, entry.FQN, now)
+		if err != nil {
+			glog.Errorf("Failed to remove expired %s, err: %v", entry.FQN, err)
+			t.fshc(err, entry.FQN)
+			return nil
+		}
+		if removed > 0 {
+			atomic.AddInt64(&n, 1)
+			atomic.AddInt64(&size, removed)
+		}
+		return nil
+	})
+	if err != nil {
+		glog.Warningf("Expire: %s stopped, err: %v", xexp, err)
+	}
+	xexp.EndTime(time.Now())
+	glog.Infof("Expire: %s: removed %d objects (%s)", xexp, n, cmn.B2S(size, 1))
+	t.xactinp.del(xexp.ID())
+}
+
+// expireObj removes the object unless it has been overwritten in the meantime (with a
+// later TTL or without one); returns the size of the removed object
+func (t *targetrunner) expireObj(bucket, objname, fqn string, now time.Time) (size int64, err error) {
+	uname := cluster.Uname(bucket, objname)
+	t.rtnamemap.Lock(uname, true)
+	defer t.rtnamemap.Unlock(uname, true)
+
+	if expires, ok := objExpires(fqn); !ok || expires.After(now) {
+		return
+	}
+	fi, err := os.Stat(fqn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	t.fdCache.invalidate(fqn)
+	t.mdCache.invalidate(fqn)
+	if err = os.Remove(fqn); err != nil {
+		return
+	}
+	size = fi.Size()
+	addBucketCapacity(fqn, bucket, t.bmdowner.get().IsLocal(bucket), -1, -size)
+	t.statsif.AddMany(stats.NamedVal64{Name: stats.ExpiredCount, Val: 1}, stats.NamedVal64{Name: stats.ExpiredSize, Val: size})
+	if glog.V(4) {
+		glog.Infof("Expire: removed %s/%s", bucket, objname)
+	}
+	return
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestObjTTL(t *testing.T) {
+	now := time.Now()
+	if expires, errstr := parseObjTTL("", now); errstr != "" || !expires.IsZero() {
+		t.Errorf("expected no expiration, got %v (%s)", expires, errstr)
+	}
+	for _, s := range []string{"1", "-1h", "0s", "soon"} {
+		if _, errstr := parseObjTTL(s, now); errstr == "" {
+			t.Errorf("expected TTL %q to fail", s)
+		}
+	}
+	expires, errstr := parseObjTTL("90m", now)
+	if errstr != "" || !expires.Equal(now.Add(90*time.Minute)) {
+		t.Fatalf("expected %v, got %v (%s)", now.Add(90*time.Minute), expires, errstr)
+	}
+
+	dir, err := ioutil.TempDir("", "objttl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fqn := filepath.Join(dir, "obj")
+	if err := ioutil.WriteFile(fqn, []byte("scratch"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objExpires(fqn); ok {
+		t.Error("expected the object to never expire")
+	}
+	if errstr := setObjXattrs(fqn, &objectProps{version: "1", expires: expires}); errstr != "" {
+		t.Skipf("xattrs not supported: %s", errstr)
+	}
+	if got, ok := objExpires(fqn); !ok || !got.Equal(expires) {
+		t.Errorf("expected %v, got %v (%t)", expires, got, ok)
+	}
+}
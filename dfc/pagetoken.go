@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/json-iterator/go"
+)
+
+// Cloud bucket listing continuation tokens.
+//
+// The page marker returned by the Cloud provider (the last listed key for AWS, an opaque
+// cursor for GCP) is wrapped into a self-contained token signed with the cluster-wide
+// secret (auth.secret). Since the token carries the entire cursor state, the next page
+// request can be served by any proxy, and a token issued for one bucket/prefix cannot
+// be replayed against another.
+//
+// Format: base64url(JSON payload) + "." + base64url(HMAC-SHA256(payload))
+//
+// Without auth.secret the tokens are signed with an empty key - anyone can forge them - and
+// the proxy warns about it once.
+
+const pageTokenSep = "."
+
+var pageTokenNoSecret sync.Once
+
+type pageToken struct {
+	Bucket   string `json:"b"`
+	Prefix   string `json:"p,omitempty"`
+	Provider string `json:"c"`
+	Marker   string `json:"m"`
+}
+
+func pageTokenSign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(ctx.config.Auth.Secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// encodePageToken wraps the provider's page marker into a signed continuation token
+func encodePageToken(bucket, prefix, marker string) (string, error) {
+	payload, err := jsoniter.Marshal(&pageToken{
+		Bucket:   bucket,
+		Prefix:   prefix,
+		Provider: ctx.config.CloudProvider,
+		Marker:   marker,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode page marker %q of bucket %s, err: %v", marker, bucket, err)
+	}
+	if ctx.config.Auth.Secret == "" {
+		pageTokenNoSecret.Do(func() {
+			glog.Warningln("auth.secret is not configured: bucket list page markers are not securely signed")
+		})
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + pageTokenSep +
+		base64.RawURLEncoding.EncodeToString(pageTokenSign(payload)), nil
+}
+
+// decodePageToken verifies the token and returns the provider's page marker
+func decodePageToken(token, bucket, prefix string) (marker string, err error) {
+	parts := strings.Split(token, pageTokenSep)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("Invalid page marker %q: malformed continuation token", token)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("Invalid page marker %q: %v", token, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("Invalid page marker %q: %v", token, err)
+	}
+	if !hmac.Equal(sig, pageTokenSign(payload)) {
+		return "", fmt.Errorf("Invalid page marker %q: signature mismatch", token)
+	}
+	pt := &pageToken{}
+	if err = jsoniter.Unmarshal(payload, pt); err != nil {
+		return "", fmt.Errorf("Invalid page marker %q: %v", token, err)
+	}
+	if pt.Bucket != bucket || pt.Prefix != prefix || pt.Provider != ctx.config.CloudProvider {
+		return "", fmt.Errorf("Page marker was issued for bucket %s (prefix %q, provider %s), cannot be used with bucket %s (prefix %q)",
+			pt.Bucket, pt.Prefix, pt.Provider, bucket, prefix)
+	}
+	return pt.Marker, nil
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+)
+
+func TestPageToken(t *testing.T) {
+	ctx.config.Auth.Secret = "aBitLongSecretKey"
+	ctx.config.CloudProvider = "aws"
+
+	token, err := encodePageToken("bucket", "prefix/", "prefix/obj1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	marker, err := decodePageToken(token, "bucket", "prefix/")
+	if err != nil {
+		t.Fatalf("Failed to decode page token %q: %v", token, err)
+	}
+	if marker != "prefix/obj1000" {
+		t.Errorf("Expected marker %q, got %q", "prefix/obj1000", marker)
+	}
+
+	tests := []struct {
+		testName string
+		token    string
+		bucket   string
+		prefix   string
+	}{
+		{"raw marker", "prefix/obj1000", "bucket", "prefix/"},
+		{"another bucket", token, "bucket2", "prefix/"},
+		{"another prefix", token, "bucket", "other/"},
+		{"tampered payload", "x" + token, "bucket", "prefix/"},
+		{"tampered signature", token[:len(token)-2] + "AA", "bucket", "prefix/"},
+	}
+	for _, tt := range tests {
+		if _, err := decodePageToken(tt.token, tt.bucket, tt.prefix); err == nil {
+			t.Errorf("%s: expected an error decoding %q", tt.testName, tt.token)
+		}
+	}
+
+	ctx.config.Auth.Secret = "anotherSecret"
+	if _, err := decodePageToken(token, "bucket", "prefix/"); err == nil {
+		t.Errorf("Expected an error decoding the token signed with another secret")
+	}
+
+	// no secret: issued with a warning, and still valid
+	ctx.config.Auth.Secret = ""
+	if token, err = encodePageToken("bucket", "", "obj1"); err != nil {
+		t.Fatal(err)
+	}
+	if marker, err = decodePageToken(token, "bucket", ""); err != nil || marker != "obj1" {
+		t.Errorf("Expected marker %q, got %q, err: %v", "obj1", marker, err)
+	}
+}
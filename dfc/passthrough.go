@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Cloud passthrough: the GET of a Cloud bucket's object that is not cached and is larger than
+// the bucket's passthrough_size (BucketProps.PassthroughSize) is streamed from the Cloud
+// straight to the client - the object (or the requested range of it) is not stored, so that
+// the occasional reads of enormous archives neither churn the LRU nor wait for the entire
+// object to download. The objects that are cached but have changed in the Cloud, or got
+// corrupted, are cold-GET as usual, and so are the prefetched ones.
+//
+// The size is looked up with a HEAD of the Cloud object, which costs a Cloud request per cold
+// GET in the buckets with passthrough_size; the GET that follows reads the version (generation)
+// of the HEAD and takes the checksum, if any, from the HEAD as well. Passthrough GETs count as
+// GETs but not as cold GETs: get.passthrough.n and get.passthrough.size rather than get.cold.n
+// and get.cold.size.
+//
+// The integrity of the streamed content is not verified: the Cloud object's checksum, if any,
+// is passed on to the client in the response headers, to be verified by the client.
+//
+// ================================ Summary ===============================================
+
+// passthrough streams the object from the Cloud if it is larger than the bucket's
+// passthrough size; returns false if the object is to be cold-GET (and cached) instead,
+// true otherwise - the response, including errors, has been sent
+func (t *targetrunner) passthrough(ct context.Context, w http.ResponseWriter, r *http.Request, bucketmd *bucketMD,
+	bucket, objname string, offset, length int64, started time.Time) bool {
+	_, bprops := bucketmd.get(bucket, false)
+	if bprops.PassthroughSize <= 0 {
+		return false
+	}
+	objmeta, errstr, errcode := getcloudif().headobject(ct, bucket, objname)
+	if errstr != "" {
+		t.passthroughErr(w, r, errstr, errcode)
+		return true
+	}
+	size, err := strconv.ParseInt(objmeta["size"], 10, 64)
+	if err != nil || size <= bprops.PassthroughSize {
+		return false
+	}
+	reader, props, errstr, errcode := getcloudif().getobjReader(ct, bucket, objname, objmeta, offset, length)
+	if errstr != "" {
+		t.passthroughErr(w, r, errstr, errcode)
+		return true
+	}
+	defer reader.Close()
+	if props.nhobj != nil {
+		htype, hval := props.nhobj.get()
+		w.Header().Add(cmn.HeaderDFCChecksumType, htype)
+		w.Header().Add(cmn.HeaderDFCChecksumVal, hval)
+	}
+	if props.version != "" {
+		w.Header().Add(cmn.HeaderDFCObjVersion, props.version)
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(props.size, 10))
+	buf, slab := gmem2.AllocFromSlab2(props.size)
+	written, err := io.CopyBuffer(w, reader, buf)
+	slab.Free(buf)
+	if err != nil {
+		glog.Errorf("Failed to GET %s/%s (passthrough), err: %v", bucket, objname, err)
+		t.statsif.AddBucket(bucket, stats.NamedVal64{Name: stats.ErrGetCount, Val: 1})
+		return true
+	}
+	delta := time.Since(started)
+	if glog.V(4) {
+		glog.Infof("GET: %s/%s, %.2f MB, %d µs (passthrough)", bucket, objname, float64(written)/cmn.MiB, delta/1000)
+	}
+	t.statsif.AddBucket(bucket, stats.NamedVal64{Name: stats.GetCount, Val: 1}, stats.NamedVal64{Name: stats.GetLatency, Val: int64(delta)},
+		stats.NamedVal64{Name: stats.GetPassthroughCount, Val: 1}, stats.NamedVal64{Name: stats.GetPassthroughSize, Val: written})
+	getstorstatsrunner().AddBucketIO(bucket, &cmn.BucketIOStats{GetCount: 1, GetSize: written})
+	return true
+}
+
+func (t *targetrunner) passthroughErr(w http.ResponseWriter, r *http.Request, errstr string, errcode int) {
+	if errcode == 0 {
+		t.invalmsghdlr(w, r, errstr)
+	} else {
+		t.invalmsghdlr(w, r, errstr, errcode)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/memsys"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// passthroughCloud is the Cloud of a single object; only HEAD and GET (reader) are implemented
+type passthroughCloud struct {
+	cloudif
+	content string
+	opened  int
+}
+
+func (c *passthroughCloud) headobject(_ context.Context, bucket, objname string) (cmn.SimpleKVs, string, int) {
+	return cmn.SimpleKVs{"size": strconv.Itoa(len(c.content)), "version": "3"}, "", 0
+}
+
+func (c *passthroughCloud) getobjReader(_ context.Context, bucket, objname string, objmeta cmn.SimpleKVs, offset, length int64) (io.ReadCloser, *objectProps, string, int) {
+	c.opened++
+	if objmeta["version"] != "3" {
+		return nil, nil, "expected the version of the HEAD", http.StatusInternalServerError
+	}
+	s := c.content[offset:]
+	if length > 0 {
+		s = s[:length]
+	}
+	return ioutil.NopCloser(strings.NewReader(s)), &objectProps{size: int64(len(s)), version: "3"}, "", 0
+}
+
+func TestPassthrough(t *testing.T) {
+	if gmem2 == nil {
+		gmem2 = &memsys.Mem2{Name: "passthrough"}
+		_ = gmem2.Init(false /* ignore init-time errors */)
+	}
+	var (
+		cloud    = &passthroughCloud{content: "0123456789abcdef"}
+		tracker  = &fakeStatsTracker{stats: make(map[string]int64)}
+		tgt      = newFakeTargetRunner()
+		bucketmd = newBucketMD()
+		rg       = ctx.rg
+	)
+	defer func() { ctx.rg = rg }()
+	tgt.cloudif, tgt.statsif = cloud, tracker
+	ctx.rg = &rungroup{target: tgt, storstats: &stats.Trunner{}}
+	bucketmd.add("archives", false, cmn.BucketProps{PassthroughSize: 10})
+	bucketmd.add("small", false, cmn.BucketProps{PassthroughSize: 100})
+
+	get := func(bucket string, offset, length int64) (*httptest.ResponseRecorder, bool) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/v1/objects/"+bucket+"/obj", nil)
+		return w, tgt.passthrough(context.Background(), w, r, bucketmd, bucket, "obj", offset, length, time.Now())
+	}
+	w, ok := get("archives", 0, 0)
+	if !ok || w.Body.String() != cloud.content || w.Header().Get(cmn.HeaderDFCObjVersion) != "3" {
+		t.Fatalf("expected the object passed through, got %v %q", ok, w.Body.String())
+	}
+	if w.Header().Get("Content-Length") != "16" {
+		t.Errorf("expected Content-Length 16, got %q", w.Header().Get("Content-Length"))
+	}
+	if w, ok = get("archives", 4, 3); !ok || w.Body.String() != "456" || w.Header().Get("Content-Length") != "3" {
+		t.Errorf("expected the range passed through, got %v %q (%v)", ok, w.Body.String(), w.Header())
+	}
+	if tracker.stats[stats.GetPassthroughCount] != 2 || tracker.stats[stats.GetPassthroughSize] != 16+3 ||
+		tracker.stats[stats.GetCount] != 2 || tracker.stats[stats.GetColdCount] != 0 {
+		t.Errorf("unexpected stats %v", tracker.stats)
+	}
+
+	// not larger than the bucket's passthrough size, no passthrough size: to be cold-GET
+	for _, bucket := range []string{"small", "other"} {
+		if _, ok = get(bucket, 0, 0); ok {
+			t.Errorf("%s: expected the object not passed through", bucket)
+		}
+	}
+	if cloud.opened != 2 {
+		t.Errorf("expected the object read twice, got %d", cloud.opened)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Pinned objects: the objects that must stay resident - e.g., critical checkpoints - can be
+// pinned by name or by name prefix (ActPin, ActUnpin; POST /v1/buckets/bucket-name). The
+// pinned names and prefixes are stored in the bucket's properties (BucketProps.Pinned) and
+// distributed with the bucket metadata; setprops and resetprops leave them as they are.
+//
+// LRU and the expiration of the objects' TTL skip the pinned objects; explicit evict and
+// delete requests do not. Every capacity_upd_time each target sums up the sizes of the
+// pinned objects it stores, per mountpath - reported as "pinned" in the capacity stats -
+// so that the capacity that LRU cannot free can be accounted for.
+//
+// ================================ Summary ===============================================
+
+// isPinned returns true if the object is pinned - see cmn.Pinned
+func isPinned(bmd *cluster.BMD, bucket, objname string, islocal bool) bool {
+	mm := bmd.LBmap
+	if !islocal {
+		mm = bmd.CBmap
+	}
+	props, ok := mm[bucket]
+	return ok && props.Pinned != nil && props.Pinned.IsPinned(objname)
+}
+
+func parsePinnedMsg(msg *cmn.ActionMsg) (*cmn.Pinned, error) {
+	pinned := &cmn.Pinned{}
+	b, err := jsoniter.Marshal(msg.Value)
+	if err == nil {
+		err = jsoniter.Unmarshal(b, pinned)
+	}
+	if err == nil && pinned.IsEmpty() {
+		err = fmt.Errorf("no objects or prefixes")
+	}
+	for _, prefix := range pinned.Prefixes {
+		if prefix == "" && err == nil {
+			err = fmt.Errorf("empty prefix")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s message value %+v, err: %v", msg.Action, msg.Value, err)
+	}
+	return pinned, nil
+}
+
+//
+// proxy
+//
+
+// pinObjects executes ActPin and ActUnpin: updates the bucket's pinned objects and prefixes
+func (p *proxyrunner) pinObjects(w http.ResponseWriter, r *http.Request, bucket string, msg *cmn.ActionMsg) {
+	if p.forwardCP(w, r, msg, bucket, nil) {
+		return
+	}
+	pinned, err := parsePinnedMsg(msg)
+	if err != nil {
+		p.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	p.bmdowner.Lock()
+	clone := p.bmdowner.get().clone()
+	isLocal := clone.IsLocal(bucket)
+	exists, props := clone.get(bucket, isLocal)
+	if !exists {
+		props = cmn.BucketProps{
+			CksumConf: cmn.CksumConf{Checksum: cmn.ChecksumInherit},
+			LRUConf:   ctx.config.LRU,
+		}
+		clone.add(bucket, false, props)
+	}
+	current := props.Pinned
+	if current == nil {
+		current = &cmn.Pinned{}
+	}
+	if msg.Action == cmn.ActPin {
+		props.Pinned = current.Pin(pinned)
+	} else {
+		props.Pinned = current.Unpin(pinned)
+	}
+	if props.Pinned.IsEmpty() {
+		props.Pinned = nil
+	}
+	clone.set(bucket, isLocal, props)
+	if errstr := p.savebmdconf(clone); errstr != "" {
+		glog.Errorln(errstr)
+	}
+	p.bmdowner.put(clone)
+	p.bmdowner.Unlock()
+	p.metasyncer.sync(true, clone, msg)
+	glog.Infof("%s %s: %d object(s), %d prefix(es)", msg.Action, bucket, len(pinned.Objects), len(pinned.Prefixes))
+}
+
+//
+// target
+//
+
+// UpdatePinned sums up the sizes of the pinned objects stored by the target, per mountpath,
+// and reports them to the stats; it is called by housekeeping
+func (t *targetrunner) UpdatePinned() {
+	if !atomic.CompareAndSwapInt64(&t.pinning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt64(&t.pinning, 0)
+
+	var (
+		sizes    = make(map[string]int64, 8)
+		bucketmd = t.bmdowner.get()
+	)
+	for _, islocal := range []bool{true, false} {
+		mm := bucketmd.LBmap
+		if !islocal {
+			mm = bucketmd.CBmap
+		}
+		for bucket, props := range mm {
+			if props.Pinned != nil {
+				t.pinnedSizes(bucket, islocal, props.Pinned, sizes)
+			}
+		}
+	}
+	getstorstatsrunner().SetPinned(sizes)
+}
+
+// pinnedSizes adds up the sizes of the bucket's pinned objects: the objects pinned by name
+// are looked up where they belong, the prefixes are walked on all mountpaths
+func (t *targetrunner) pinnedSizes(bucket string, islocal bool, pinned *cmn.Pinned, sizes map[string]int64) {
+	var (
+		counted = make(map[string]bool, len(pinned.Objects))
+		sizesCh = make(chan *fs.WalkEntry, 64)
+		done    = make(chan struct{})
+		scope   = fs.WalkCloud
+	)
+	if islocal {
+		scope = fs.WalkLocal
+	}
+	add := func(fqn string, mpath string, size int64) {
+		if counted[fqn] {
+			return // pinned by name and by prefix, or by overlapping prefixes
+		}
+		counted[fqn] = true
+		sizes[mpath] += size
+	}
+	for _, objname := range pinned.Objects {
+		fqn, errstr := cluster.FQN(bucket, objname, islocal)
+		if errstr != "" {
+			continue
+		}
+		finfo, err := os.Stat(fqn)
+		if err != nil {
+			continue
+		}
+		if mpathInfo, _ := fs.Mountpaths.Path2MpathInfo(fqn); mpathInfo != nil {
+			add(fqn, mpathInfo.Path, finfo.Size())
+		}
+	}
+	// the walk callbacks run concurrently, one goroutine per mountpath
+	go func() {
+		for entry := range sizesCh {
+			add(entry.FQN, entry.MpathInfo.Path, entry.FileInfo.Size())
+		}
+		close(done)
+	}()
+	for _, prefix := range pinned.Prefixes {
+		opts := &fs.WalkOpts{Scope: scope, Bucket: bucket, Prefix: prefix, Skip: skipNonProcessable}
+		err := fs.Mountpaths.WalkObjects(opts, func(entry *fs.WalkEntry) error {
+			sizesCh <- entry
+			return nil
+		})
+		if err != nil {
+			glog.Errorf("Failed to walk pinned %s/%s*, err: %v", bucket, prefix, err)
+		}
+	}
+	close(sizesCh)
+	<-done
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func TestPinned(t *testing.T) {
+	mpath, err := ioutil.TempDir("", "pinned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mpath)
+	q := fs.Mountpaths
+	defer func() { fs.Mountpaths = q }()
+	fs.Mountpaths = fs.NewMountedFS(ctx.config.LocalBuckets, ctx.config.CloudBuckets)
+	fs.Mountpaths.DisableFsIDCheck()
+	if err := fs.Mountpaths.Add(mpath); err != nil {
+		t.Fatal(err)
+	}
+
+	pinned := &cmn.Pinned{Objects: []string{"ckpt/1", "model"}, Prefixes: []string{"ckpt/"}}
+	bucketmd := newBucketMD()
+	bucketmd.add("bck", true, cmn.BucketProps{CloudProvider: cmn.ProviderDFC, Pinned: pinned})
+	if !isPinned(&bucketmd.BMD, "bck", "ckpt/2", true) || isPinned(&bucketmd.BMD, "bck", "logs/1", true) ||
+		isPinned(&bucketmd.BMD, "bck", "model", false) {
+		t.Error("expected the local bucket's ckpt/* and model pinned")
+	}
+
+	// ckpt/1 is pinned by name and by prefix: counted once
+	objects := map[string]int{"ckpt/1": 100, "ckpt/2": 20, "model": 3, "logs/1": 1000}
+	for objname, size := range objects {
+		fqn, errstr := cluster.FQN("bck", objname, true)
+		if errstr != "" {
+			t.Fatal(errstr)
+		}
+		if err := cmn.CreateDir(filepath.Dir(fqn)); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fqn, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sizes := make(map[string]int64)
+	(&targetrunner{}).pinnedSizes("bck", true, pinned, sizes)
+	if len(sizes) != 1 || sizes[mpath] != 123 {
+		t.Errorf("expected 123 bytes pinned on %s, got %v", mpath, sizes)
+	}
+
+	if _, err := parsePinnedMsg(&cmn.ActionMsg{Action: cmn.ActPin, Value: cmn.Pinned{}}); err == nil {
+		t.Error("expected the empty pin message rejected")
+	}
+}
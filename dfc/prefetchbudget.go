@@ -0,0 +1,268 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Cloud-cost-aware prefetch. A Cloud bucket may limit the Cloud requests and bytes that
+// prefetching it consumes per day (UTC) - BucketProps.PrefetchBudget. Each target enforces
+// its own share of the budget - the budget divided by the number of targets - as each
+// prefetches only the objects that map to it (HRW).
+//
+// Before queuing a prefetch, the target plans it: for each object it estimates the Cloud
+// requests (a GET if the object is not cached; a version check if it is and warm GETs are
+// validated) and bytes (the size from the bucket list when prefetching a range; unknown, i.e.
+// zero, when prefetching a list of objects), and admits the objects in order for as long as
+// the estimates fit into what remains of today's budget. The rest of the objects are trimmed;
+// if none fits, the prefetch is refused. The estimates are charged right away - so that the
+// prefetches queued in the meantime are planned against the remainder - and replaced with the
+// actual requests and bytes as each object gets prefetched. An admitted object is skipped
+// (trimmed) nonetheless if, by the time of its turn, the others have used up the budget -
+// e.g., the objects of unknown size. Range prefetches, in addition, charge the bucket list
+// pages and stop listing once the requests are used up.
+//
+// GET /v1/daemon?what=prefetch returns the target's usage per bucket (the buckets with no
+// budget included), and GET /v1/cluster?what=prefetch - summed over all targets. The stats
+// pre.cloud.req.n and pre.trim.n count the Cloud requests of prefetching and the trimmed
+// objects. The usage is kept in memory: a restarted target starts the day anew.
+//
+// ================================ Summary ===============================================
+
+type (
+	prefetchBudgets struct {
+		mu      sync.Mutex
+		buckets cmn.PrefetchUsages
+	}
+	// prefetchEst is the estimate charged to the budget upon admitting the object
+	prefetchEst struct {
+		requests, bytes int64
+	}
+)
+
+func newPrefetchBudgets() *prefetchBudgets {
+	return &prefetchBudgets{buckets: make(cmn.PrefetchUsages)}
+}
+
+// usage returns the bucket's usage of the day, along with the target's share of the budget;
+// must be called under lock
+func (pb *prefetchBudgets) usage(bucket string, budget *cmn.PrefetchBudget, ntargets int, now time.Time) *cmn.PrefetchUsage {
+	day := now.UTC().Format("2006-01-02")
+	u, ok := pb.buckets[bucket]
+	if !ok || u.Day != day {
+		u = &cmn.PrefetchUsage{Day: day}
+		pb.buckets[bucket] = u
+	}
+	u.MaxRequests, u.MaxBytes = 0, 0
+	if budget != nil && ntargets > 0 {
+		// rounded up
+		u.MaxRequests = (budget.Requests + int64(ntargets) - 1) / int64(ntargets)
+		u.MaxBytes = (budget.Bytes + int64(ntargets) - 1) / int64(ntargets)
+	}
+	return u
+}
+
+// admit admits the objects, in order, for as long as their estimates fit into the remaining
+// budget, and charges the estimates; returns the number of objects admitted
+func (pb *prefetchBudgets) admit(bucket string, budget *cmn.PrefetchBudget, ntargets int, ests []prefetchEst, now time.Time) (n int) {
+	pb.mu.Lock()
+	u := pb.usage(bucket, budget, ntargets, now)
+	for _, est := range ests {
+		if (u.MaxRequests > 0 && u.Requests+est.requests > u.MaxRequests) ||
+			(u.MaxBytes > 0 && u.Bytes+est.bytes > u.MaxBytes) {
+			break
+		}
+		u.Requests += est.requests
+		u.Bytes += est.bytes
+		n++
+	}
+	u.Trimmed += int64(len(ests) - n)
+	pb.mu.Unlock()
+	return
+}
+
+// charge adds the requests and bytes (negative: refund) to the bucket's usage
+func (pb *prefetchBudgets) charge(bucket string, budget *cmn.PrefetchBudget, ntargets int, requests, bytes int64, now time.Time) {
+	pb.mu.Lock()
+	u := pb.usage(bucket, budget, ntargets, now)
+	u.Requests = cmn.MaxI64(u.Requests+requests, 0)
+	u.Bytes = cmn.MaxI64(u.Bytes+bytes, 0)
+	pb.mu.Unlock()
+}
+
+// exhausted returns true if the usage, less the object's own estimate, has used up the budget;
+// if so, the object is trimmed
+func (pb *prefetchBudgets) exhausted(bucket string, budget *cmn.PrefetchBudget, ntargets int, own prefetchEst, now time.Time) bool {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	u := pb.usage(bucket, budget, ntargets, now)
+	if (u.MaxRequests > 0 && u.Requests-own.requests >= u.MaxRequests) || (u.MaxBytes > 0 && u.Bytes-own.bytes >= u.MaxBytes) {
+		u.Trimmed++
+		return true
+	}
+	return false
+}
+
+func (pb *prefetchBudgets) get(budgetf func(bucket string) (*cmn.PrefetchBudget, int), now time.Time) cmn.PrefetchUsages {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	out := make(cmn.PrefetchUsages, len(pb.buckets))
+	for bucket := range pb.buckets {
+		budget, ntargets := budgetf(bucket)
+		u := *pb.usage(bucket, budget, ntargets, now)
+		out[bucket] = &u
+	}
+	return out
+}
+
+//
+// target
+//
+
+// prefetchBudget returns the bucket's budget (nil if none) and the number of targets sharing it
+func (t *targetrunner) prefetchBudget(bucket string) (*cmn.PrefetchBudget, int) {
+	ok, props := t.bmdowner.get().get(bucket, false)
+	if !ok || props.PrefetchBudget == nil || (props.PrefetchBudget.Requests == 0 && props.PrefetchBudget.Bytes == 0) {
+		return nil, 0
+	}
+	return props.PrefetchBudget, len(t.smapowner.get().Tmap)
+}
+
+// planPrefetch returns the objects admitted by the bucket's budget and their estimates;
+// nil estimates if the bucket has no budget
+func (t *targetrunner) planPrefetch(bucket string, objs []string, sizes map[string]int64) ([]string, map[string]prefetchEst, error) {
+	budget, ntargets := t.prefetchBudget(bucket)
+	if budget == nil {
+		return objs, nil, nil
+	}
+	ests := make([]prefetchEst, len(objs))
+	for i, objname := range objs {
+		ests[i] = t.prefetchEstimate(bucket, objname, sizes)
+	}
+	n := t.prefbudgets.admit(bucket, budget, ntargets, ests, time.Now())
+	if trimmed := len(objs) - n; trimmed > 0 {
+		t.statsif.Add(stats.PrefetchTrimCount, int64(trimmed))
+		if n == 0 {
+			return nil, nil, fmt.Errorf("Prefetch budget of bucket %s is exhausted: refusing to prefetch %d objects", bucket, len(objs))
+		}
+		glog.Warningf("Prefetch budget of bucket %s: trimmed %d of %d objects", bucket, trimmed, len(objs))
+	}
+	estimates := make(map[string]prefetchEst, n)
+	for i, objname := range objs[:n] {
+		estimates[objname] = ests[i]
+	}
+	return objs[:n], estimates, nil
+}
+
+// prefetchEstimate estimates the Cloud requests and bytes of prefetching the object - see
+// prefetchMissing; the size, if known, is the one listed
+func (t *targetrunner) prefetchEstimate(bucket, objname string, sizes map[string]int64) (est prefetchEst) {
+	fqn, errstr := cluster.FQN(bucket, objname, false /*islocal*/)
+	if errstr != "" {
+		return
+	}
+	coldget, _, version, errstr := t.lookupLocally(bucket, objname, fqn)
+	switch {
+	case coldget:
+		est = prefetchEst{requests: 1, bytes: sizes[objname]}
+	case errstr != "":
+	case ctx.config.Ver.ValidateWarmGet && version != "" && t.versioningConfigured(bucket):
+		est.requests = 1 // (and, if the version has changed, a GET that is not estimated)
+	}
+	return
+}
+
+// prefetchCharge replaces the object's estimate with the actual requests and bytes
+func (t *targetrunner) prefetchCharge(bucket string, est *prefetchEst, requests, bytes int64) {
+	if requests > 0 {
+		t.statsif.Add(stats.PrefetchCloudReqCount, requests)
+	}
+	budget, ntargets := t.prefetchBudget(bucket)
+	if est != nil {
+		requests -= est.requests
+		bytes -= est.bytes
+	}
+	if requests != 0 || bytes != 0 {
+		t.prefbudgets.charge(bucket, budget, ntargets, requests, bytes, time.Now())
+	}
+}
+
+// prefetchTrimmed returns true if the others have used up the budget by the time of the object's turn
+func (t *targetrunner) prefetchTrimmed(bucket string, est *prefetchEst) bool {
+	if est == nil {
+		return false
+	}
+	budget, ntargets := t.prefetchBudget(bucket)
+	if budget == nil || !t.prefbudgets.exhausted(bucket, budget, ntargets, *est, time.Now()) {
+		return false
+	}
+	t.statsif.Add(stats.PrefetchTrimCount, 1)
+	t.prefetchCharge(bucket, est, 0, 0) // refund
+	return true
+}
+
+// prefetchListExhausted returns true if the bucket's requests are used up; otherwise, charges
+// the (range prefetch) bucket list page
+func (t *targetrunner) prefetchListExhausted(bucket string) bool {
+	budget, ntargets := t.prefetchBudget(bucket)
+	if budget != nil && t.prefbudgets.exhausted(bucket, budget, ntargets, prefetchEst{}, time.Now()) {
+		return true
+	}
+	t.prefetchCharge(bucket, nil, 1, 0)
+	return false
+}
+
+func (t *targetrunner) prefetchUsages() cmn.PrefetchUsages {
+	return t.prefbudgets.get(t.prefetchBudget, time.Now())
+}
+
+//
+// proxy
+//
+
+func (p *proxyrunner) invokeHttpGetClusterPrefetch(w http.ResponseWriter, r *http.Request) bool {
+	var (
+		smap  = p.smapowner.get()
+		query = url.Values{}
+		out   = &cmn.ClusterPrefetchUsages{
+			Buckets: make(cmn.PrefetchUsages),
+			Targets: make(map[string]cmn.PrefetchUsages, len(smap.Tmap)),
+		}
+	)
+	query.Add(cmn.URLParamWhat, cmn.GetWhatPrefetch)
+	results := p.broadcastDash(cmn.URLPath(cmn.Version, cmn.Daemon), query, smap.Tmap)
+	for res := range results {
+		if res.err != nil {
+			glog.Errorf("Failed to get %s prefetch usage: %s", res.si, res.errstr)
+			continue
+		}
+		usages := make(cmn.PrefetchUsages)
+		if err := jsoniter.Unmarshal(res.outjson, &usages); err != nil {
+			glog.Errorf("Failed to unmarshal %s prefetch usage, err: %v", res.si, err)
+			continue
+		}
+		out.Targets[res.si.DaemonID] = usages
+		for bucket, u := range usages {
+			out.Buckets.Add(bucket, u)
+		}
+	}
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterPrefetch")
+}
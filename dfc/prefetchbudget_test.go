@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestPrefetchBudgetAdmit(t *testing.T) {
+	var (
+		pb     = newPrefetchBudgets()
+		budget = &cmn.PrefetchBudget{Requests: 10, Bytes: 1000}
+		now    = time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)
+		ests   = []prefetchEst{{1, 300}, {1, 300}, {1, 300}, {1, 300}}
+	)
+	// 3 targets: each gets 4 requests and 334 bytes (rounded up)
+	if n := pb.admit("b", budget, 3, ests, now); n != 1 {
+		t.Fatalf("expected 1 object admitted, got %d", n)
+	}
+	u := pb.get(func(string) (*cmn.PrefetchBudget, int) { return budget, 3 }, now)["b"]
+	if u.MaxRequests != 4 || u.MaxBytes != 334 || u.Requests != 1 || u.Bytes != 300 || u.Trimmed != 3 {
+		t.Fatalf("unexpected usage %+v", *u)
+	}
+	// the object turned out to be smaller: the rest fits
+	pb.charge("b", budget, 3, 0, -280, now)
+	if n := pb.admit("b", budget, 3, ests[:1], now); n != 1 {
+		t.Fatalf("expected the object admitted after the refund, got %d", n)
+	}
+	if n := pb.admit("b", budget, 3, ests[:1], now); n != 0 {
+		t.Fatalf("expected the object trimmed, got %d admitted", n)
+	}
+
+	// unknown sizes are admitted; an object is trimmed once the others have used up the budget
+	if n := pb.admit("c", budget, 1, []prefetchEst{{1, 0}, {1, 0}}, now); n != 2 {
+		t.Fatalf("expected both objects admitted, got %d", n)
+	}
+	pb.charge("c", budget, 1, 0, 1000, now)
+	if !pb.exhausted("c", budget, 1, prefetchEst{1, 0}, now) {
+		t.Fatal("expected the budget exhausted")
+	}
+	pb.charge("c", budget, 1, 0, -5000, now)
+	if pb.exhausted("c", budget, 1, prefetchEst{1, 0}, now) {
+		t.Fatal("expected the budget available (the usage does not go negative)")
+	}
+
+	// next day
+	if n := pb.admit("b", budget, 3, ests, now.Add(24*time.Hour)); n != 1 {
+		t.Fatalf("expected 1 object admitted the next day, got %d", n)
+	}
+	u = pb.get(func(string) (*cmn.PrefetchBudget, int) { return nil, 0 }, now.Add(24*time.Hour))["b"]
+	if u.Day != "2018-11-02" || u.Requests != 1 || u.Trimmed != 3 || u.MaxRequests != 0 {
+		t.Fatalf("unexpected usage of the next day %+v", *u)
+	}
+}
+
+func TestPrefetchUsagesAdd(t *testing.T) {
+	usages := make(cmn.PrefetchUsages)
+	usages.Add("b", &cmn.PrefetchUsage{Day: "2018-11-01", Requests: 5, Bytes: 50, MaxRequests: 10, Trimmed: 1})
+	usages.Add("b", &cmn.PrefetchUsage{Day: "2018-11-02", Requests: 2, Bytes: 20, MaxRequests: 10})
+	usages.Add("b", &cmn.PrefetchUsage{Day: "2018-11-02", Requests: 3, Bytes: 30, MaxRequests: 10, Trimmed: 2})
+	usages.Add("b", &cmn.PrefetchUsage{Day: "2018-11-01", Requests: 100})
+	u := usages["b"]
+	if u.Day != "2018-11-02" || u.Requests != 5 || u.Bytes != 50 || u.MaxRequests != 20 || u.Trimmed != 2 {
+		t.Fatalf("unexpected cluster usage %+v", *u)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Presigned URLs: time-limited GETs and PUTs of a single object that require no credentials,
+// e.g. for the browser-based tools (see also cors.go). A client - authenticated, if auth is
+// enabled - requests the URL from a proxy:
+//   POST {"action": "presign", "value": {"method": "PUT", "expires": "1h"}} /v1/objects/bucket/objname
+// and receives the object's URL with the expiration time (Unix seconds) and the signature -
+// HMAC-SHA256 (presign.secret or, if not configured, auth.secret) of the method, the bucket,
+// the object name, and the expiration time:
+//   http://proxy:8080/v1/objects/bucket/objname?expires=1543000000&signature=<hex>
+// Any proxy (all share the secret) validates the signed requests: the method must match (a
+// GET URL allows HEAD as well), and the URL must not have expired; otherwise, 403. A valid
+// signature stands for the token when auth is enabled. The rest of the query - e.g., offset
+// and length of a ranged GET - is not signed.
+//
+// ================================ Summary ===============================================
+
+// presignSecret returns the secret the URLs are signed with; nil: presigning is disabled
+func presignSecret() []byte {
+	if ctx.config.Presign.Secret != "" {
+		return []byte(ctx.config.Presign.Secret)
+	}
+	if ctx.config.Auth.Secret != "" {
+		return []byte(ctx.config.Auth.Secret)
+	}
+	return nil
+}
+
+func presignSignature(secret []byte, method, bucket, objname string, expires int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(method + "\n" + bucket + "\n" + objname + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// presignPath returns the escaped path of the object's URL; the slashes of the object name
+// are kept
+func presignPath(bucket, objname string) string {
+	segs := strings.Split(objname, "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return cmn.URLPath(cmn.Version, cmn.Objects, url.PathEscape(bucket), strings.Join(segs, "/"))
+}
+
+// isPresigned returns true if the object request carries a signature (see checkPresigned)
+func isPresigned(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, cmn.URLPath(cmn.Version, cmn.Objects)+"/") &&
+		r.URL.Query().Get(cmn.URLParamSignature) != ""
+}
+
+// verifyPresigned validates the presigned request
+func verifyPresigned(r *http.Request, secret []byte, now time.Time) error {
+	apitems, err := cmn.MatchRESTItems(r.URL.Path, 2, false, cmn.Version, cmn.Objects)
+	if err != nil {
+		return err
+	}
+	if len(apitems) < 2 {
+		return errors.New("object name is missing")
+	}
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get(cmn.URLParamExpires), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s=%q", cmn.URLParamExpires, query.Get(cmn.URLParamExpires))
+	}
+	if now.Unix() > expires {
+		return fmt.Errorf("expired at %s", time.Unix(expires, 0).UTC().Format(time.RFC3339))
+	}
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodPut {
+		return fmt.Errorf("%s is not allowed", r.Method)
+	}
+	expected := presignSignature(secret, method, apitems[0], apitems[1], expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get(cmn.URLParamSignature))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func parsePresignMsg(msg *cmn.ActionMsg) (*cmn.PresignMsg, error) {
+	psmsg := &cmn.PresignMsg{}
+	b, err := jsoniter.Marshal(msg.Value)
+	if err == nil {
+		err = jsoniter.Unmarshal(b, psmsg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s message value %+v, err: %v", msg.Action, msg.Value, err)
+	}
+	psmsg.Method = strings.ToUpper(psmsg.Method)
+	if psmsg.Method != http.MethodGet && psmsg.Method != http.MethodPut {
+		return nil, fmt.Errorf("Invalid %s method %q: expecting GET or PUT", msg.Action, psmsg.Method)
+	}
+	return psmsg, nil
+}
+
+//
+// proxy
+//
+
+// checkPresigned validates the presigned requests; 403 if invalid
+func (p *proxyrunner) checkPresigned(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isPresigned(r) {
+			err := errors.New("presigned URLs are disabled")
+			if secret := presignSecret(); secret != nil {
+				err = verifyPresigned(r, secret, time.Now())
+			}
+			if err != nil {
+				p.invalmsghdlr(w, r, fmt.Sprintf("Presigned %s %s: %v", r.Method, r.URL.Path, err), http.StatusForbidden)
+				return
+			}
+		}
+		h(w, r)
+	}
+}
+
+// POST { presign } /v1/objects/bucket-name/object-name
+func (p *proxyrunner) presign(w http.ResponseWriter, r *http.Request, msg *cmn.ActionMsg) {
+	apitems, err := p.checkRESTItems(w, r, 2, false, cmn.Version, cmn.Objects)
+	if err != nil {
+		return
+	}
+	bucket, objname := apitems[0], apitems[1]
+	secret := presignSecret()
+	if secret == nil {
+		p.invalmsghdlr(w, r, "Presigned URLs are disabled: neither presign.secret nor auth.secret is configured")
+		return
+	}
+	psmsg, err := parsePresignMsg(msg)
+	if err != nil {
+		p.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	expiry := ctx.config.Presign.MaxExpiry
+	if psmsg.Expires != "" {
+		d, err := time.ParseDuration(psmsg.Expires)
+		if err != nil || d <= 0 || d > ctx.config.Presign.MaxExpiry {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Invalid %s expires %q: expecting duration up to %v",
+				msg.Action, psmsg.Expires, ctx.config.Presign.MaxExpiry))
+			return
+		}
+		expiry = d
+	}
+	expires := time.Now().Add(expiry).Unix()
+	query := url.Values{}
+	query.Set(cmn.URLParamExpires, strconv.FormatInt(expires, 10))
+	query.Set(cmn.URLParamSignature, presignSignature(secret, psmsg.Method, bucket, objname, expires))
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	out := &cmn.PresignedURL{
+		URL:     scheme + "://" + r.Host + presignPath(bucket, objname) + "?" + query.Encode(),
+		Method:  psmsg.Method,
+		Expires: time.Unix(expires, 0).UTC(),
+	}
+	if glog.V(3) {
+		glog.Infof("Presigned %s %s/%s until %s", psmsg.Method, bucket, objname, out.Expires.Format(time.RFC3339))
+	}
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	p.writeJSON(w, r, jsbytes, "presign")
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func presignedRequest(method, bucket, objname, signedMethod string, expires int64, secret []byte) *http.Request {
+	query := url.Values{}
+	query.Set(cmn.URLParamExpires, strconv.FormatInt(expires, 10))
+	query.Set(cmn.URLParamSignature, presignSignature(secret, signedMethod, bucket, objname, expires))
+	return httptest.NewRequest(method, presignPath(bucket, objname)+"?"+query.Encode(), nil)
+}
+
+func TestVerifyPresigned(t *testing.T) {
+	var (
+		secret  = []byte("secret")
+		now     = time.Now()
+		expires = now.Add(time.Hour).Unix()
+	)
+	tests := []struct {
+		name string
+		r    *http.Request
+		ok   bool
+	}{
+		{"GET", presignedRequest(http.MethodGet, "b", "dir/obj", http.MethodGet, expires, secret), true},
+		{"HEAD of GET", presignedRequest(http.MethodHead, "b", "dir/obj", http.MethodGet, expires, secret), true},
+		{"PUT", presignedRequest(http.MethodPut, "b", "dir/obj", http.MethodPut, expires, secret), true},
+		{"escaped", presignedRequest(http.MethodGet, "b", "dir/a b?#%.jpg", http.MethodGet, expires, secret), true},
+		{"PUT of GET", presignedRequest(http.MethodPut, "b", "dir/obj", http.MethodGet, expires, secret), false},
+		{"DELETE", presignedRequest(http.MethodDelete, "b", "dir/obj", http.MethodDelete, expires, secret), false},
+		{"expired", presignedRequest(http.MethodGet, "b", "dir/obj", http.MethodGet, now.Add(-time.Second).Unix(), secret), false},
+		{"another secret", presignedRequest(http.MethodGet, "b", "dir/obj", http.MethodGet, expires, []byte("other")), false},
+	}
+	for _, test := range tests {
+		if !isPresigned(test.r) {
+			t.Fatalf("%s: expected the request presigned", test.name)
+		}
+		if err := verifyPresigned(test.r, secret, now); (err == nil) != test.ok {
+			t.Errorf("%s: expected ok=%t, got err: %v", test.name, test.ok, err)
+		}
+	}
+
+	// another object, or the expiration extended
+	r := presignedRequest(http.MethodGet, "b", "dir/obj", http.MethodGet, expires, secret)
+	r.URL.Path = cmn.URLPath(cmn.Version, cmn.Objects, "b", "dir/other")
+	if err := verifyPresigned(r, secret, now); err == nil {
+		t.Error("expected the signature of another object rejected")
+	}
+	r = presignedRequest(http.MethodGet, "b", "dir/obj", http.MethodGet, expires, secret)
+	query := r.URL.Query()
+	query.Set(cmn.URLParamExpires, strconv.FormatInt(expires+3600, 10))
+	r.URL.RawQuery = query.Encode()
+	if err := verifyPresigned(r, secret, now); err == nil {
+		t.Error("expected the extended expiration rejected")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, cmn.URLPath(cmn.Version, cmn.Buckets, "b")+"?"+cmn.URLParamSignature+"=x", nil)
+	if isPresigned(r) {
+		t.Error("expected a bucket request not to be presigned")
+	}
+}
+
+func TestCORS(t *testing.T) {
+	saved := ctx.config.CORS
+	defer func() { ctx.config.CORS = saved }()
+	ctx.config.CORS = cmn.CORSConf{AllowedOrigins: []string{"https://labels.example.com"}, MaxAge: 10 * time.Minute}
+
+	var served int
+	h := withCORS(func(w http.ResponseWriter, r *http.Request) { served++ })
+	do := func(method, target, origin string, hdrs ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		for i := 0; i < len(hdrs); i += 2 {
+			r.Header.Set(hdrs[i], hdrs[i+1])
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+	path := cmn.URLPath(cmn.Version, cmn.Objects, "b", "obj")
+
+	// preflight
+	w := do(http.MethodOptions, path, "https://labels.example.com",
+		"Access-Control-Request-Method", "PUT", "Access-Control-Request-Headers", "content-type")
+	if w.Code != http.StatusNoContent || served != 0 {
+		t.Fatalf("expected the preflight answered (204), got %d (served %d)", w.Code, served)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://labels.example.com" ||
+		w.Header().Get("Access-Control-Allow-Headers") != "content-type" || w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("unexpected preflight headers %v", w.Header())
+	}
+
+	// actual request
+	w = do(http.MethodGet, path, "https://labels.example.com")
+	if served != 1 || w.Header().Get("Access-Control-Allow-Origin") != "https://labels.example.com" ||
+		w.Header().Get("Access-Control-Expose-Headers") == "" {
+		t.Errorf("expected the request served with the CORS headers, got %v (served %d)", w.Header(), served)
+	}
+
+	// origin not allowed; no origin
+	for _, origin := range []string{"https://evil.example.com", ""} {
+		w = do(http.MethodGet, path, origin)
+		if w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("origin %q: expected no CORS headers, got %v", origin, w.Header())
+		}
+	}
+
+	// "null": only the proxy's redirect of an allowed origin, signed
+	savedSecret := ctx.config.Presign.Secret
+	defer func() { ctx.config.Presign.Secret = savedSecret }()
+	ctx.config.Presign.Secret = "secret"
+	redirect := func(origin string, ts time.Time) string {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Origin", origin)
+		utm := strconv.FormatInt(ts.UnixNano(), 10)
+		query := url.Values{}
+		query.Set(cmn.URLParamProxyID, "p1")
+		query.Set(cmn.URLParamUnixTime, utm)
+		corsSignRedirect(&ctx.config.CORS, r, utm, query)
+		return path + "?" + query.Encode()
+	}
+	if w = do(http.MethodGet, path, "null"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected the null origin rejected, got %v", w.Header())
+	}
+	if w = do(http.MethodGet, path+"?"+cmn.URLParamProxyID+"=p1", "null"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected the null origin of an unsigned redirect rejected, got %v", w.Header())
+	}
+	if w = do(http.MethodGet, redirect("https://labels.example.com", time.Now()), "null"); w.Header().Get("Access-Control-Allow-Origin") != "null" {
+		t.Errorf("expected the null origin of a signed redirect allowed, got %v", w.Header())
+	}
+	for name, target := range map[string]string{
+		"origin not allowed": redirect("https://evil.example.com", time.Now()),
+		"expired":            redirect("https://labels.example.com", time.Now().Add(-2*corsRedirectTTL)),
+		"another object":     strings.Replace(redirect("https://labels.example.com", time.Now()), "/obj?", "/other?", 1),
+	} {
+		if w = do(http.MethodGet, target, "null"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("%s: expected the null origin rejected, got %v", name, w.Header())
+		}
+	}
+	target := redirect("https://labels.example.com", time.Now())
+	ctx.config.Presign.Secret = ""
+	if w = do(http.MethodGet, target, "null"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected the null origin rejected with no secret, got %v", w.Header())
+	}
+
+	ctx.config.CORS.AllowedOrigins = nil
+	if w = do(http.MethodGet, path, "https://labels.example.com"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected CORS disabled, got %v", w.Header())
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -30,12 +31,20 @@ import (
 	"github.com/json-iterator/go"
 )
 
-const tokenStart = "Bearer"
+const (
+	tokenStart       = "Bearer"
+	clientMaxRetries = 3 // see cmn.ClientHints
+)
 
 type ClusterMountpathsRaw struct {
 	Targets map[string]jsoniter.RawMessage `json:"targets"`
 }
 
+// ClusterDiskHistoryRaw is the wire format of cmn.ClusterDiskHistory and cmn.ClusterDiskHealth
+type ClusterDiskHistoryRaw struct {
+	Targets map[string]jsoniter.RawMessage `json:"targets"`
+}
+
 // Keeps a target response when doing parallel requests to all targets
 type bucketResp struct {
 	outjson []byte
@@ -60,6 +69,47 @@ func wrapHandler(h http.HandlerFunc, wraps ...func(http.HandlerFunc) http.Handle
 	return h
 }
 
+// withProxyURLs adds the public URLs of the cluster's proxies, primary first, to the
+// responses, so that clients could fail over to another proxy (see api.FailoverTransport)
+func (p *proxyrunner) withProxyURLs(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if smap := p.smapowner.get(); smap != nil && smap.ProxySI != nil {
+			w.Header().Set(cmn.HeaderDFCProxyURLs, proxyURLs(smap))
+		}
+		h(w, r)
+	}
+}
+
+func proxyURLs(smap *smapX) string {
+	var (
+		ids  = make([]string, 0, len(smap.Pmap))
+		urls = make([]string, 0, len(smap.Pmap))
+	)
+	for id := range smap.Pmap {
+		if id != smap.ProxySI.DaemonID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	urls = append(urls, smap.ProxySI.PublicNet.DirectURL)
+	for _, id := range ids {
+		urls = append(urls, smap.Pmap[id].PublicNet.DirectURL)
+	}
+	return strings.Join(urls, ",")
+}
+
+// clientHints returns the client configuration: the requests that fail with 503 are retried
+// at the pace of the control plane operations
+func (p *proxyrunner) clientHints() *cmn.ClientHints {
+	return &cmn.ClientHints{
+		ProxyURLs:    strings.Split(proxyURLs(p.smapowner.get()), ","),
+		APIVersions:  cmn.APIVersions,
+		DialTimeout:  ctx.config.Timeout.CplaneOperationStr,
+		MaxRetries:   clientMaxRetries,
+		RetryBackoff: ctx.config.Timeout.CplaneOperationStr,
+	}
+}
+
 //===========================================================================
 //
 // proxy runner
@@ -70,7 +120,16 @@ type proxyrunner struct {
 	starttime  time.Time
 	authn      *authManager
 	startedUp  int64
+	rebStarted int64 // unix nano: the last time this (primary) proxy triggered rebalance
 	metasyncer *metasyncer
+	dash       dashState       // GET /v1/cluster?what=dashboard
+	rebPlan    rebPlanID       // the last rebalance plan: confirms rebalance (rebplan.go)
+	reqsamples reqSampler      // GET /v1/daemon?what=reqsamples (reqsample.go)
+	limits     endpointLimits  // concurrent requests to the expensive endpoints (endpointlimits.go)
+	snapmtx    sync.Mutex      // bucket snapshots being created or deleted, local buckets renamed (snapshot.go)
+	statsAgg   statsAggState   // the previous GET /v1/cluster?what=stats&aggregate=true (statsaggregate.go)
+	upgrade    rollingUpgrade  // the rolling upgrade driven by this (primary) proxy (upgrade.go)
+	archives   archiveStatuses // bucket export and import driven by this (primary) proxy (bucketarchive.go)
 	rproxy     struct {
 		sync.Mutex
 		cloud *httputil.ReverseProxy            // unmodified GET requests => storage.googleapis.com
@@ -121,16 +180,19 @@ func (p *proxyrunner) Run() error {
 
 	// Public network
 	if ctx.config.Auth.Enabled {
-		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Buckets)+"/", wrapHandler(p.bucketHandler, p.checkHTTPAuth))
-		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", wrapHandler(p.objectHandler, p.checkHTTPAuth))
+		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Buckets)+"/", wrapHandler(p.bucketHandler, p.checkHTTPAuth, p.withMaintenance, p.withProxyURLs))
+		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", wrapHandler(p.objectHandler, p.checkHTTPAuth, p.checkPresigned, p.withMaintenance, p.withProxyURLs, withCORS))
+		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Snapshots)+"/", wrapHandler(p.snapshotHandler, p.checkHTTPAuth, p.withProxyURLs))
 	} else {
-		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Buckets)+"/", p.bucketHandler)
-		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", p.objectHandler)
+		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Buckets)+"/", wrapHandler(p.bucketHandler, p.withMaintenance, p.withProxyURLs))
+		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", wrapHandler(p.objectHandler, p.checkPresigned, p.withMaintenance, p.withProxyURLs, withCORS))
+		p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Snapshots)+"/", wrapHandler(p.snapshotHandler, p.withProxyURLs))
 	}
 
-	p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Daemon), p.daemonHandler)
-	p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Cluster), p.clusterHandler)
-	p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Tokens), p.tokenHandler)
+	p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Daemon), wrapHandler(p.daemonHandler, p.withProxyURLs))
+	p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Cluster), wrapHandler(p.clusterHandler, p.withProxyURLs))
+	p.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Tokens), wrapHandler(p.tokenHandler, p.withProxyURLs))
+	p.registerPublicNetHandler(cmn.URLPath(cmn.Metrics), p.metricsHandler)
 
 	if ctx.config.Net.HTTP.RevProxy == RevProxyCloud {
 		p.registerPublicNetHandler("/", p.reverseProxyHandler)
@@ -152,7 +214,7 @@ func (p *proxyrunner) Run() error {
 
 	// Intra data network
 	if ctx.config.Net.UseIntraData {
-		p.registerIntraDataNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", p.objectHandler)
+		p.registerIntraDataNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", p.withMaintenance(p.objectHandler))
 		p.registerIntraDataNetHandler("/", cmn.InvalidHandler)
 	}
 
@@ -168,9 +230,9 @@ func (p *proxyrunner) Run() error {
 	}
 	p.starttime = time.Now()
 
-	_ = p.initStatsD("dfcproxy")
 	sr := getproxystatsrunner()
-	sr.Core.StatsdC = &p.statsdC
+	sr.Core.Sink = p.metrics
+	sr.Core.LatencyUnit = &ctx.config.Periodic.StatsLatencyUnit
 
 	return p.httprunner.run()
 }
@@ -307,6 +369,14 @@ func (p *proxyrunner) httpbckget(w http.ResponseWriter, r *http.Request) {
 		p.getbucketnames(w, r, bucket)
 		return
 	}
+	switch r.URL.Query().Get(cmn.URLParamWhat) {
+	case cmn.GetWhatColdData:
+		p.getColdData(w, r, bucket)
+		return
+	case cmn.GetWhatArchive:
+		p.getArchiveStatus(w, r, bucket)
+		return
+	}
 	s := fmt.Sprintf("Invalid route /buckets/%s", bucket)
 	p.invalmsghdlr(w, r, s)
 }
@@ -322,12 +392,15 @@ func (p *proxyrunner) httpobjget(w http.ResponseWriter, r *http.Request) {
 	if !p.validatebckname(w, r, bucket) {
 		return
 	}
+	rt := p.sampleReq(r, bucket, objname, started)
+	rt.phase(reqPhaseValidate)
 	smap := p.smapowner.get()
 	si, errstr := hrwTarget(bucket, objname, smap)
 	if errstr != "" {
 		p.invalmsghdlr(w, r, errstr)
 		return
 	}
+	rt.phase(reqPhaseHRW)
 
 	if ctx.config.Net.HTTP.RevProxy == RevProxyTarget {
 		if glog.V(4) {
@@ -361,7 +434,9 @@ func (p *proxyrunner) httpobjget(w http.ResponseWriter, r *http.Request) {
 		}
 		http.Redirect(w, r, redirecturl, http.StatusMovedPermanently)
 	}
-	p.statsif.Add(stats.GetCount, 1)
+	rt.phase(reqPhaseRedirect)
+	rt.done(p, si.DaemonID)
+	p.statsif.AddBucket(bucket, stats.NamedVal64{Name: stats.GetCount, Val: 1})
 }
 
 // PUT /v1/objects
@@ -375,12 +450,15 @@ func (p *proxyrunner) httpobjput(w http.ResponseWriter, r *http.Request) {
 	// FIXME: add protection against putting into non-existing local bucket
 	//
 	bucket, objname := apitems[0], apitems[1]
+	rt := p.sampleReq(r, bucket, objname, started)
+	rt.phase(reqPhaseValidate)
 	smap := p.smapowner.get()
 	si, errstr := hrwTarget(bucket, objname, smap)
 	if errstr != "" {
 		p.invalmsghdlr(w, r, errstr)
 		return
 	}
+	rt.phase(reqPhaseHRW)
 	if glog.V(4) {
 		glog.Infof("%s %s/%s => %s", r.Method, bucket, objname, si.DaemonID)
 	}
@@ -393,8 +471,10 @@ func (p *proxyrunner) httpobjput(w http.ResponseWriter, r *http.Request) {
 		redirecturl = p.redirectURL(r, si.IntraDataNet.DirectURL, started, bucket)
 	}
 	http.Redirect(w, r, redirecturl, http.StatusTemporaryRedirect)
+	rt.phase(reqPhaseRedirect)
+	rt.done(p, si.DaemonID)
 
-	p.statsif.Add(stats.PutCount, 1)
+	p.statsif.AddBucket(bucket, stats.NamedVal64{Name: stats.PutCount, Val: 1})
 }
 
 // DELETE { action } /v1/buckets
@@ -450,19 +530,24 @@ func (p *proxyrunner) httpobjdelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	bucket, objname := apitems[0], apitems[1]
+	rt := p.sampleReq(r, bucket, objname, started)
+	rt.phase(reqPhaseValidate)
 	smap := p.smapowner.get()
 	si, errstr := hrwTarget(bucket, objname, smap)
 	if errstr != "" {
 		p.invalmsghdlr(w, r, errstr)
 		return
 	}
+	rt.phase(reqPhaseHRW)
 	if glog.V(4) {
 		glog.Infof("%s %s/%s => %s", r.Method, bucket, objname, si.DaemonID)
 	}
 	redirecturl := p.redirectURL(r, si.PublicNet.DirectURL, started, bucket)
 	http.Redirect(w, r, redirecturl, http.StatusTemporaryRedirect)
+	rt.phase(reqPhaseRedirect)
+	rt.done(p, si.DaemonID)
 
-	p.statsif.Add(stats.DeleteCount, 1)
+	p.statsif.AddBucket(bucket, stats.NamedVal64{Name: stats.DeleteCount, Val: 1})
 }
 
 // [METHOD] /v1/metasync
@@ -533,7 +618,7 @@ func (p *proxyrunner) metasyncHandlerPut(w http.ResponseWriter, r *http.Request)
 func (p *proxyrunner) healthHandler(w http.ResponseWriter, r *http.Request) {
 	rr := getproxystatsrunner()
 	rr.Lock()
-	rr.Core.Tracker[stats.Uptime].Value = int64(time.Since(p.starttime) / time.Microsecond)
+	rr.Core.Tracker[stats.Uptime].Value = int64(time.Since(p.starttime))
 	if p.startedup(0) == 0 {
 		rr.Core.Tracker[stats.Uptime].Value = 0
 	}
@@ -588,6 +673,8 @@ func (p *proxyrunner) httpbckpost(w http.ResponseWriter, r *http.Request) {
 		if p.forwardCP(w, r, &msg, "", nil) {
 			return
 		}
+		p.snapmtx.Lock()
+		defer p.snapmtx.Unlock()
 		bucketFrom, bucketTo := lbucket, msg.Name
 		if bucketFrom == "" || bucketTo == "" {
 			errstr := fmt.Sprintf("Invalid rename local bucket request: empty name %s => %s",
@@ -608,9 +695,14 @@ func (p *proxyrunner) httpbckpost(w http.ResponseWriter, r *http.Request) {
 			p.invalmsghdlr(w, r, s)
 			return
 		}
+		if len(props.Snapshots) > 0 {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Local bucket %s has snapshots: cannot rename", bucketFrom))
+			return
+		}
 		if !p.renameLB(bucketFrom, bucketTo, clone, props, &msg) {
 			errstr := fmt.Sprintf("Failed to rename local bucket %s => %s", bucketFrom, bucketTo)
 			p.invalmsghdlr(w, r, errstr)
+			return
 		}
 		glog.Infof("renamed local bucket %s => %s, bucket-metadata version %d", bucketFrom, bucketTo, clone.version())
 	case cmn.ActSyncLB:
@@ -622,6 +714,18 @@ func (p *proxyrunner) httpbckpost(w http.ResponseWriter, r *http.Request) {
 		p.actionlistrange(w, r, &msg)
 	case cmn.ActListObjects:
 		p.listBucketAndCollectStats(w, r, lbucket, msg, started)
+	case cmn.ActBeginGroup, cmn.ActCommitGroup, cmn.ActAbortGroup:
+		p.putGroupAction(w, r, lbucket, &msg)
+	case cmn.ActRenameBucket:
+		p.renameBucket(w, r, lbucket, &msg)
+	case cmn.ActChecksums:
+		p.objChecksums(w, r, lbucket, &msg)
+	case cmn.ActExport, cmn.ActImport:
+		p.bucketArchive(w, r, lbucket, &msg)
+	case cmn.ActPin, cmn.ActUnpin:
+		p.pinObjects(w, r, lbucket, &msg)
+	case cmn.ActSnapshot:
+		p.createSnapshot(w, r, lbucket, &msg)
 	default:
 		s := fmt.Sprintf("Unexpected cmn.ActionMsg <- JSON [%v]", msg)
 		p.invalmsghdlr(w, r, s)
@@ -630,10 +734,15 @@ func (p *proxyrunner) httpbckpost(w http.ResponseWriter, r *http.Request) {
 
 func (p *proxyrunner) listBucketAndCollectStats(w http.ResponseWriter,
 	r *http.Request, lbucket string, msg cmn.ActionMsg, started time.Time) {
+	limit := ctx.config.EndpointLimits.List
+	if !p.waitEndpoint(w, r, &p.limits.list, limit, stats.ListWaitLatency) {
+		return
+	}
 	pagemarker, ok := p.listbucket(w, r, lbucket, &msg)
+	p.limits.list.release(ctx.config.EndpointLimits.List)
 	if ok {
 		delta := time.Since(started)
-		p.statsif.AddMany(stats.NamedVal64{stats.ListCount, 1}, stats.NamedVal64{stats.ListLatency, int64(delta)})
+		p.statsif.AddBucket(lbucket, stats.NamedVal64{stats.ListCount, 1}, stats.NamedVal64{stats.ListLatency, int64(delta)})
 		if glog.V(3) {
 			lat := int64(delta / time.Microsecond)
 			if pagemarker != "" {
@@ -666,6 +775,12 @@ func (p *proxyrunner) httpobjpost(w http.ResponseWriter, r *http.Request) {
 	case cmn.ActReplicate:
 		p.replicate(w, r, &msg)
 		return
+	case cmn.ActSelect:
+		p.selectObject(w, r)
+		return
+	case cmn.ActPresign:
+		p.presign(w, r, &msg)
+		return
 	default:
 		s := fmt.Sprintf("Unexpected cmn.ActionMsg <- JSON [%v]", msg)
 		p.invalmsghdlr(w, r, s)
@@ -744,6 +859,8 @@ func (p *proxyrunner) httpbckput(w http.ResponseWriter, r *http.Request) {
 		oldProps = cmn.BucketProps{
 			CksumConf: cmn.CksumConf{Checksum: cmn.ChecksumInherit},
 			LRUConf:   ctx.config.LRU,
+			Pinned:    oldProps.Pinned,    // see pinned.go
+			Snapshots: oldProps.Snapshots, // see snapshot.go
 		}
 	}
 
@@ -768,11 +885,14 @@ func (p *proxyrunner) httpobjhead(w http.ResponseWriter, r *http.Request) {
 	if !p.validatebckname(w, r, bucket) {
 		return
 	}
+	rt := p.sampleReq(r, bucket, objname, started)
+	rt.phase(reqPhaseValidate)
 	smap := p.smapowner.get()
 	si, errstr := hrwTarget(bucket, objname, smap)
 	if errstr != "" {
 		return
 	}
+	rt.phase(reqPhaseHRW)
 	if glog.V(4) {
 		glog.Infof("%s %s/%s => %s", r.Method, bucket, objname, si.DaemonID)
 	}
@@ -781,6 +901,8 @@ func (p *proxyrunner) httpobjhead(w http.ResponseWriter, r *http.Request) {
 		redirecturl += fmt.Sprintf("&%s=true", cmn.URLParamCheckCached)
 	}
 	http.Redirect(w, r, redirecturl, http.StatusTemporaryRedirect)
+	rt.phase(reqPhaseRedirect)
+	rt.done(p, si.DaemonID)
 }
 
 //============================
@@ -811,7 +933,7 @@ func (p *proxyrunner) forwardCP(w http.ResponseWriter, r *http.Request, msg *cmn
 		uparsed, err := url.Parse(smap.ProxySI.PublicNet.DirectURL)
 		cmn.Assert(err == nil, err)
 		p.rproxy.p = httputil.NewSingleHostReverseProxy(uparsed)
-		p.rproxy.p.Transport = p.createTransport(proxyMaxIdleConnsPer, 0)
+		p.rproxy.p.Transport = p.intra.Transport()
 	}
 	p.rproxy.Unlock()
 	if len(body) > 0 {
@@ -835,7 +957,7 @@ func (p *proxyrunner) reverseDP(w http.ResponseWriter, r *http.Request, tsi *clu
 		uparsed, err := url.Parse(tsi.PublicNet.DirectURL)
 		cmn.Assert(err == nil, err)
 		rproxy = httputil.NewSingleHostReverseProxy(uparsed)
-		rproxy.Transport = p.createTransport(targetMaxIdleConnsPer, 0)
+		rproxy.Transport = p.intra.Transport()
 		p.rproxy.tmap[tsi.DaemonID] = rproxy
 	}
 	p.rproxy.Unlock()
@@ -924,6 +1046,7 @@ func (p *proxyrunner) redirectURL(r *http.Request, to string, ts time.Time, buck
 		query    = url.Values{}
 		bucketmd = p.bmdowner.get()
 		islocal  = bucketmd.IsLocal(bucket)
+		utm      = strconv.FormatInt(int64(ts.UnixNano()), 10)
 	)
 	redirect = to + r.URL.Path + "?"
 	if r.URL.RawQuery != "" {
@@ -933,7 +1056,8 @@ func (p *proxyrunner) redirectURL(r *http.Request, to string, ts time.Time, buck
 	query.Add(cmn.URLParamLocal, strconv.FormatBool(islocal))
 	query.Add(cmn.URLParamProxyID, p.si.DaemonID)
 	query.Add(cmn.URLParamBMDVersion, bucketmd.vstr)
-	query.Add(cmn.URLParamUnixTime, strconv.FormatInt(int64(ts.UnixNano()), 10))
+	query.Add(cmn.URLParamUnixTime, utm)
+	corsSignRedirect(&ctx.config.CORS, r, utm, query)
 	redirect += query.Encode()
 	return
 }
@@ -959,6 +1083,11 @@ func (p *proxyrunner) targetListBucket(r *http.Request, bucket string, dinfo *cl
 	query.Add(cmn.URLParamLocal, strconv.FormatBool(islocal))
 	query.Add(cmn.URLParamCached, strconv.FormatBool(cached))
 	query.Add(cmn.URLParamBMDVersion, p.bmdowner.get().vstr)
+	if r != nil && !cached {
+		if refresh := r.URL.Query().Get(cmn.URLParamRefresh); refresh != "" {
+			query.Add(cmn.URLParamRefresh, refresh)
+		}
+	}
 
 	args := callArgs{
 		si: dinfo,
@@ -984,14 +1113,20 @@ func (p *proxyrunner) targetListBucket(r *http.Request, bucket string, dinfo *cl
 }
 
 // Receives info about locally cached files from targets in batches
-// and merges with existing list of cloud files
-func (p *proxyrunner) consumeCachedList(bmap map[string]*cmn.BucketEntry, dataCh chan *localFilePage, errCh chan error) {
+// and merges with existing list of cloud files; the targets' failures are collected
+// into errs unless nil (strict listing), in which case the first failure stops it
+func (p *proxyrunner) consumeCachedList(bmap map[string]*cmn.BucketEntry, dataCh chan *localFilePage, errCh chan error,
+	errs map[string]string) {
 	for rb := range dataCh {
 		if rb.err != nil {
+			glog.Errorf("Failed to get information about file in DFC cache: %v", rb.err)
+			if errs != nil {
+				errs[rb.id] = rb.err.Error()
+				continue
+			}
 			if errCh != nil {
 				errCh <- rb.err
 			}
-			glog.Errorf("Failed to get information about file in DFC cache: %v", rb.err)
 			return
 		}
 		if rb.entries == nil || len(rb.entries) == 0 {
@@ -999,6 +1134,9 @@ func (p *proxyrunner) consumeCachedList(bmap map[string]*cmn.BucketEntry, dataCh
 		}
 
 		for _, newEntry := range rb.entries {
+			if newEntry.Type == cmn.BucketEntryDir {
+				continue
+			}
 			nm := newEntry.Name
 			// Do not update the entry if it already contains up-to-date information
 			if entry, ok := bmap[nm]; ok && !entry.IsCached {
@@ -1084,15 +1222,22 @@ func (p *proxyrunner) collectCachedFileList(bucket string, fileList *cmn.BucketL
 	dataCh := make(chan *localFilePage, smap.CountTargets())
 	errCh := make(chan error, 1)
 	wgConsumer := &sync.WaitGroup{}
+	var errs map[string]string
+	if !reqParams.GetStrict {
+		errs = make(map[string]string)
+	}
 	wgConsumer.Add(1)
 	go func() {
-		p.consumeCachedList(bucketMap, dataCh, errCh)
+		p.consumeCachedList(bucketMap, dataCh, errCh, errs)
 		wgConsumer.Done()
 	}()
 
 	// since cached file page marker is not compatible with any cloud
 	// marker, it should be empty for the first call
 	reqParams.GetPageMarker = ""
+	if reqParams.GetDelimiter != cmn.ListDelimiter {
+		reqParams.GetDelimiter = "" // targets list the cached objects by filesystem directories
+	}
 
 	wg := &sync.WaitGroup{}
 	for _, daemon := range smap.Tmap {
@@ -1112,6 +1257,9 @@ func (p *proxyrunner) collectCachedFileList(bucket string, fileList *cmn.BucketL
 		return
 	default:
 	}
+	if len(errs) > 0 {
+		fileList.Errors = errs
+	}
 
 	fileList.Entries = make([]*cmn.BucketEntry, 0, len(bucketMap))
 	for _, entry := range bucketMap {
@@ -1170,10 +1318,16 @@ func (p *proxyrunner) getLocalBucketObjects(bucket string, listmsgjson []byte) (
 
 	// combine results
 	allentries = &cmn.BucketList{Entries: make([]*cmn.BucketEntry, 0, pageSize)}
+	errs := make(map[string]string)
 	for r := range chresult {
 		if r.err != nil {
-			err = r.err
-			return
+			if msg.GetStrict {
+				err = r.err
+				return
+			}
+			glog.Errorf("Failed to list local bucket %s at target %s: %v", bucket, r.resp.id, r.err)
+			errs[r.resp.id] = r.err.Error()
+			continue
 		}
 
 		if r.resp.outjson == nil || len(r.resp.outjson) == 0 {
@@ -1191,12 +1345,22 @@ func (p *proxyrunner) getLocalBucketObjects(bucket string, listmsgjson []byte) (
 
 		allentries.Entries = append(allentries.Entries, bucketList.Entries...)
 	}
+	if len(errs) > 0 {
+		if len(errs) == len(smap.Tmap) {
+			err = fmt.Errorf("Failed to list local bucket %s: none of the %d targets responded", bucket, len(errs))
+			return
+		}
+		allentries.Errors = errs
+	}
 
 	// return the list always sorted in alphabetical order
 	entryLess := func(i, j int) bool {
 		return allentries.Entries[i].Name < allentries.Entries[j].Name
 	}
 	sort.Slice(allentries.Entries, entryLess)
+	if msg.GetDelimiter != "" {
+		allentries.Entries = uniqueEntries(allentries.Entries)
+	}
 
 	// shrink the result to `pageSize` entries. If the page is full than
 	// mark the result incomplete by setting PageMarker
@@ -1227,15 +1391,22 @@ func (p *proxyrunner) getCloudBucketObjects(r *http.Request, bucket string, list
 	if msg.GetPageSize > MaxPageSize {
 		glog.Warningf("Page size(%d) for cloud bucket %s exceeds the limit(%d)", msg.GetPageSize, bucket, MaxPageSize)
 	}
-
-	// first, get the cloud object list from a random target
-	smap := p.smapowner.get()
-	for _, si := range smap.Tmap {
-		resp, err = p.targetListBucket(r, bucket, si, &msg, islocal, cachedObjects)
-		if err != nil {
+	if msg.GetPageMarker != "" {
+		// unwrap the continuation token (see pagetoken.go)
+		if msg.GetPageMarker, err = decodePageToken(msg.GetPageMarker, bucket, msg.GetPrefix); err != nil {
 			return
 		}
-		break
+	}
+
+	// first, get the cloud object list from the bucket's list target (see listcache.go)
+	smap := p.smapowner.get()
+	si, errstr := hrwTarget(bucket, "", smap)
+	if errstr != "" {
+		err = errors.New(errstr)
+		return
+	}
+	if resp, err = p.targetListBucket(r, bucket, si, &msg, islocal, cachedObjects); err != nil {
+		return
 	}
 
 	if resp.outjson == nil || len(resp.outjson) == 0 {
@@ -1244,6 +1415,11 @@ func (p *proxyrunner) getCloudBucketObjects(r *http.Request, bucket string, list
 	if err = jsoniter.Unmarshal(resp.outjson, &allentries); err != nil {
 		return
 	}
+	if allentries.PageMarker != "" {
+		if allentries.PageMarker, err = encodePageToken(bucket, msg.GetPrefix, allentries.PageMarker); err != nil {
+			return
+		}
+	}
 	if len(allentries.Entries) == 0 {
 		return
 	}
@@ -1278,15 +1454,29 @@ func (p *proxyrunner) getCloudBucketObjects(r *http.Request, bucket string, list
 //      * updates the list of objects from the cloud with cached info
 //   - returns the list
 func (p *proxyrunner) listbucket(w http.ResponseWriter, r *http.Request, bucket string, actionMsg *cmn.ActionMsg) (pagemarker string, ok bool) {
-	var allentries *cmn.BucketList
+	var (
+		allentries *cmn.BucketList
+		getmsg     cmn.GetMsg
+		bucketmd   = p.bmdowner.get()
+		islocal    = bucketmd.IsLocal(bucket)
+	)
 	listmsgjson, err := jsoniter.Marshal(actionMsg.Value)
 	if err != nil {
 		s := fmt.Sprintf("Unable to marshal action message: %v. Error: %v", actionMsg, err)
 		p.invalmsghdlr(w, r, s)
 		return
 	}
+	if defined, props := bucketmd.get(bucket, islocal); defined && (props.ListProps != "" || props.ListTimeFormat != "") {
+		if err = jsoniter.Unmarshal(listmsgjson, &getmsg); err != nil {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Unable to unmarshal list message %s, err: %v", listmsgjson, err))
+			return
+		}
+		applyListDefaults(&getmsg, &props)
+		listmsgjson, err = jsoniter.Marshal(&getmsg)
+		cmn.Assert(err == nil, err)
+	}
 
-	if p.bmdowner.get().IsLocal(bucket) {
+	if islocal {
 		allentries, err = p.getLocalBucketObjects(bucket, listmsgjson)
 	} else {
 		allentries, err = p.getCloudBucketObjects(r, bucket, listmsgjson)
@@ -1339,7 +1529,7 @@ func (p *proxyrunner) filrename(w http.ResponseWriter, r *http.Request, msg *cmn
 	redirecturl := p.redirectURL(r, si.PublicNet.DirectURL, started, lbucket)
 	http.Redirect(w, r, redirecturl, http.StatusTemporaryRedirect)
 
-	p.statsif.Add(stats.RenameCount, 1)
+	p.statsif.AddBucket(lbucket, stats.NamedVal64{Name: stats.RenameCount, Val: 1})
 }
 
 func (p *proxyrunner) replicate(w http.ResponseWriter, r *http.Request, msg *cmn.ActionMsg) {
@@ -1497,10 +1687,39 @@ func (p *proxyrunner) validateToken(r *http.Request) (*authRec, error) {
 	return auth, nil
 }
 
+// isPublicRead returns true if the request is a read-only access to a bucket that
+// allows anonymous reads (BucketProps.PublicRead): GET or HEAD of the bucket or its
+// objects, or listing the bucket's objects
+func (p *proxyrunner) isPublicRead(r *http.Request) bool {
+	apitems := cmn.RESTItems(r.URL.Path) // version, buckets|objects, bucket-name, ...
+	if len(apitems) < 3 {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		if apitems[1] != cmn.Buckets || r.Body == nil {
+			return false
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewBuffer(b))
+		if err != nil || jsoniter.Get(b, "action").ToString() != cmn.ActListObjects {
+			return false
+		}
+	default:
+		return false
+	}
+	bucket, bucketmd := apitems[2], p.bmdowner.get()
+	defined, props := bucketmd.get(bucket, bucketmd.IsLocal(bucket))
+	return defined && props.PublicRead
+}
+
 // A wrapper to check any request before delegating the request to real handler
 // If authentication is disabled, it does nothing.
 // If authentication is enabled, it looks for token in request header and
-// makes sure that it is valid
+// makes sure that it is valid. Requests without token are allowed only
+// to read public buckets (see isPublicRead)
 func (p *proxyrunner) checkHTTPAuth(h http.HandlerFunc) http.HandlerFunc {
 	wrappedFunc := func(w http.ResponseWriter, r *http.Request) {
 		var (
@@ -1508,7 +1727,16 @@ func (p *proxyrunner) checkHTTPAuth(h http.HandlerFunc) http.HandlerFunc {
 			err  error
 		)
 
-		if ctx.config.Auth.Enabled {
+		if ctx.config.Auth.Enabled && r.Header.Get("Authorization") == "" && isPresigned(r) {
+			// validated by checkPresigned
+			if glog.V(4) {
+				glog.Infof("Presigned %s %s", r.Method, r.URL.Path)
+			}
+		} else if ctx.config.Auth.Enabled && r.Header.Get("Authorization") == "" && p.isPublicRead(r) {
+			if glog.V(4) {
+				glog.Infof("Anonymous %s %s", r.Method, r.URL.Path)
+			}
+		} else if ctx.config.Auth.Enabled {
 			if auth, err = p.validateToken(r); err != nil {
 				glog.Error(err)
 				p.invalmsghdlr(w, r, "Not authorized", http.StatusUnauthorized)
@@ -1543,16 +1771,64 @@ func (p *proxyrunner) daemonHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GET /metrics
+func (p *proxyrunner) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		p.invalmsghdlr(w, r, "invalid method for /metrics path", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", stats.PrometheusContentType)
+	if err := getproxystatsrunner().WritePrometheus(w); err != nil {
+		glog.Errorf("Failed to write metrics, err: %v", err)
+	}
+}
+
 func (p *proxyrunner) httpdaeget(w http.ResponseWriter, r *http.Request) {
 	getWhat := r.URL.Query().Get(cmn.URLParamWhat)
 	switch getWhat {
 	case cmn.GetWhatConfig, cmn.GetWhatBucketMeta, cmn.GetWhatSmapVote, cmn.GetWhatDaemonInfo:
 		p.httprunner.httpdaeget(w, r)
 	case cmn.GetWhatStats:
-		rst := getproxystatsrunner()
-		rst.RLock()
-		jsbytes, err := jsoniter.Marshal(rst)
-		rst.RUnlock()
+		var (
+			jsbytes []byte
+			err     error
+			rst     = getproxystatsrunner()
+		)
+		snapshot, reset, errstr := parseStatsSnapshot(r.URL.Query())
+		if errstr != "" {
+			p.invalmsghdlr(w, r, errstr)
+			return
+		}
+		if snapshot {
+			jsbytes, err = jsoniter.Marshal(rst.GetSnapshot(reset))
+		} else if buckets, _ := strconv.ParseBool(r.URL.Query().Get(cmn.URLParamBuckets)); buckets {
+			jsbytes, err = rst.MarshalWithBuckets()
+		} else {
+			rst.RLock()
+			jsbytes, err = jsoniter.Marshal(rst)
+			rst.RUnlock()
+		}
+		cmn.Assert(err == nil, err)
+		p.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatSLO:
+		jsbytes, err := jsoniter.Marshal(getproxystatsrunner().SLO())
+		cmn.Assert(err == nil, err)
+		p.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatStatsHist:
+		q, errstr := parseStatsHistQuery(r.URL.Query())
+		if errstr != "" {
+			p.invalmsghdlr(w, r, errstr)
+			return
+		}
+		jsbytes, err := jsoniter.Marshal(getproxystatsrunner().History(q.names, q.res, q.within, q.until))
+		cmn.Assert(err == nil, err)
+		p.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatReqSamples:
+		jsbytes, err := jsoniter.Marshal(p.reqsamples.get(r.URL.Query().Get(cmn.URLParamBucket)))
+		cmn.Assert(err == nil, err)
+		p.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatHints:
+		jsbytes, err := jsoniter.Marshal(p.clientHints())
 		cmn.Assert(err == nil, err)
 		p.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
 	case cmn.GetWhatSmap:
@@ -1645,13 +1921,20 @@ func (p *proxyrunner) httpdaeput(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	case cmn.ActMaintenance:
+		if p.smapowner.get().isPrimary(p.si) {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Cannot put the primary proxy %s in maintenance mode: designate another primary first",
+				p.si.DaemonID))
+			return
+		}
+		p.setMaintenance(w, r, &msg)
 	default:
 		s := fmt.Sprintf("Unexpected ActionMsg <- JSON [%v]", msg)
 		p.invalmsghdlr(w, r, s)
 	}
 }
 
-func (p *proxyrunner) smapFromURL(baseURL string) (smap *smapX, errstr string) {
+func (h *httprunner) smapFromURL(baseURL string) (smap *smapX, errstr string) {
 	query := url.Values{}
 	query.Add(cmn.URLParamWhat, cmn.GetWhatSmap)
 	req := reqArgs{
@@ -1661,7 +1944,7 @@ func (p *proxyrunner) smapFromURL(baseURL string) (smap *smapX, errstr string) {
 		query:  query,
 	}
 	args := callArgs{req: req, timeout: defaultTimeout}
-	res := p.call(args)
+	res := h.call(args)
 	if res.err != nil {
 		return nil, fmt.Sprintf("Failed to get smap from %s: %v", baseURL, res.err)
 	}
@@ -1973,12 +2256,37 @@ func (p *proxyrunner) httpcluget(w http.ResponseWriter, r *http.Request) {
 	getWhat := r.URL.Query().Get(cmn.URLParamWhat)
 	switch getWhat {
 	case cmn.GetWhatStats:
-		ok := p.invokeHttpGetClusterStats(w, r)
+		snapshot, reset, errstr := parseStatsSnapshot(r.URL.Query())
+		if errstr != "" {
+			p.invalmsghdlr(w, r, errstr)
+			return
+		}
+		aggregate, err := parsebool(r.URL.Query().Get(cmn.URLParamAggregate))
+		if err != nil {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Invalid %s, err: %v", cmn.URLParamAggregate, err))
+			return
+		}
+		if !p.waitEndpoint(w, r, &p.limits.stats, ctx.config.EndpointLimits.Stats, stats.StatsWaitLatency) {
+			return
+		}
+		var ok bool
+		if aggregate {
+			ok = p.invokeHttpGetClusterStatsAggregate(w, r, reset)
+		} else if snapshot {
+			ok = p.invokeHttpGetClusterStatsSnapshot(w, r, reset)
+		} else {
+			ok = p.invokeHttpGetClusterStats(w, r)
+		}
+		p.limits.stats.release(ctx.config.EndpointLimits.Stats)
 		if !ok {
 			return
 		}
 	case cmn.GetWhatXaction:
+		if !p.waitEndpoint(w, r, &p.limits.xaction, ctx.config.EndpointLimits.Xaction, stats.XactionWaitLatency) {
+			return
+		}
 		ok := p.invokeHttpGetXaction(w, r)
+		p.limits.xaction.release(ctx.config.EndpointLimits.Xaction)
 		if !ok {
 			return
 		}
@@ -1986,6 +2294,54 @@ func (p *proxyrunner) httpcluget(w http.ResponseWriter, r *http.Request) {
 		if ok := p.invokeHttpGetClusterMountpaths(w, r); !ok {
 			return
 		}
+	case cmn.GetWhatDiskHist, cmn.GetWhatDiskHealth:
+		if ok := p.invokeHttpGetClusterDiskHistory(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatDashboard:
+		if ok := p.invokeHttpGetClusterDashboard(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatRebPlan:
+		if ok := p.invokeHttpGetRebalancePlan(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatSLO:
+		if ok := p.invokeHttpGetClusterSLO(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatReplStats:
+		if ok := p.invokeHttpGetClusterReplStats(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatPrefetch:
+		if ok := p.invokeHttpGetClusterPrefetch(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatSummary:
+		if ok := p.invokeHttpGetClusterSummary(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatBucketIO:
+		if ok := p.invokeHttpGetClusterBucketIO(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatHotObjects:
+		if ok := p.invokeHttpGetClusterHotObjects(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatStatsHist:
+		if ok := p.invokeHttpGetClusterStatsHistory(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatKeepalive:
+		if ok := p.invokeHttpGetClusterKeepalive(w, r); !ok {
+			return
+		}
+	case cmn.GetWhatUpgrade:
+		if ok := p.invokeHttpGetUpgrade(w, r); !ok {
+			return
+		}
 	default:
 		s := fmt.Sprintf("Unexpected GET request, invalid param 'what': [%s]", getWhat)
 		cmn.InvalidHandlerWithMsg(w, r, s)
@@ -2063,6 +2419,19 @@ func (p *proxyrunner) invokeHttpGetClusterStats(w http.ResponseWriter, r *http.R
 
 	out := &stats.ClusterStatsRaw{}
 	out.Target = targetStats
+	if buckets, _ := strconv.ParseBool(r.URL.Query().Get(cmn.URLParamBuckets)); buckets {
+		out.Buckets = make(cmn.StatsPerBucket)
+		for id, raw := range targetStats {
+			var ts struct {
+				Buckets cmn.StatsPerBucket `json:"buckets"`
+			}
+			if err := jsoniter.Unmarshal(raw, &ts); err != nil {
+				glog.Errorf("Failed to unmarshal target %s per-bucket stats, err: %v", id, err)
+				continue
+			}
+			out.Buckets.Add(ts.Buckets)
+		}
+	}
 	rr := getproxystatsrunner()
 	rr.RLock()
 	out.Proxy = rr.Core
@@ -2091,12 +2460,24 @@ func (p *proxyrunner) invokeHttpGetClusterMountpaths(w http.ResponseWriter, r *h
 	return ok
 }
 
+func (p *proxyrunner) invokeHttpGetClusterDiskHistory(w http.ResponseWriter, r *http.Request) bool {
+	targetHistory, ok := p.invokeHttpGetMsgOnTargets(w, r)
+	if !ok {
+		return false
+	}
+	out := &ClusterDiskHistoryRaw{Targets: targetHistory}
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterDiskHistory")
+}
+
 // register|keepalive target|proxy
 func (p *proxyrunner) httpclupost(w http.ResponseWriter, r *http.Request) {
 	var (
 		nsi                   cluster.Snode
 		keepalive, register   bool
 		isproxy, nonelectable bool
+		renewed               bool // known target, new address
 		msg                   *cmn.ActionMsg
 		s                     string
 	)
@@ -2140,20 +2521,31 @@ func (p *proxyrunner) httpclupost(w http.ResponseWriter, r *http.Request) {
 
 	p.statsif.Add(stats.PostCount, 1)
 
+	var probe aliveNode
+	if !keepalive && p.startedup(0) != 0 {
+		probe = p.probeRegistered(&nsi, isproxy)
+	}
 	p.smapowner.Lock()
 	smap := p.smapowner.get()
 	if isproxy {
 		osi := smap.GetProxy(nsi.DaemonID)
-		if !p.addOrUpdateNode(&nsi, osi, keepalive, "proxy") {
+		if update, errstr := p.addOrUpdateNode(&nsi, osi, probe, keepalive, "proxy"); !update {
 			p.smapowner.Unlock()
+			if errstr != "" {
+				p.invalmsghdlr(w, r, errstr, http.StatusConflict)
+			}
 			return
 		}
 	} else {
 		osi := smap.GetTarget(nsi.DaemonID)
-		if !p.addOrUpdateNode(&nsi, osi, keepalive, "target") {
+		if update, errstr := p.addOrUpdateNode(&nsi, osi, probe, keepalive, "target"); !update {
 			p.smapowner.Unlock()
+			if errstr != "" {
+				p.invalmsghdlr(w, r, errstr, http.StatusConflict)
+			}
 			return
 		}
+		renewed = osi != nil
 		if register {
 			if glog.V(3) {
 				glog.Infof("register target %s (num targets before %d)", nsi.DaemonID, smap.CountTargets())
@@ -2201,7 +2593,15 @@ func (p *proxyrunner) httpclupost(w http.ResponseWriter, r *http.Request) {
 		}
 		p.smapowner.Unlock()
 		tokens := p.authn.revokedTokenList()
-		msg.Action = path.Join(msg.Action, nsi.DaemonID)
+		if renewed {
+			// same ID => same placement: the targets do not rebalance
+			glog.Infof("target %s renewed at %s", nsi.DaemonID, nsi.PublicNet.DirectURL)
+		} else {
+			msg.Action = path.Join(msg.Action, nsi.DaemonID)
+			if !isproxy && ctx.config.Rebalance.Enabled {
+				atomic.StoreInt64(&p.rebStarted, time.Now().UnixNano()) // the new target triggers rebalance
+			}
+		}
 		if len(tokens.Tokens) == 0 {
 			p.metasyncer.sync(false, smap, msg)
 		} else {
@@ -2232,6 +2632,7 @@ func (p *proxyrunner) registerToSmap(isproxy bool, nsi *cluster.Snode, nonelecta
 		if clone.GetTarget(id) != nil { // ditto
 			clone.delTarget(id)
 		}
+		delStaleTargets(clone, nsi)
 		clone.addTarget(nsi)
 		if glog.V(3) {
 			glog.Infof("joined target %s (num targets %d)", id, clone.CountTargets())
@@ -2240,36 +2641,52 @@ func (p *proxyrunner) registerToSmap(isproxy bool, nsi *cluster.Snode, nonelecta
 	p.smapowner.put(clone)
 }
 
-func (p *proxyrunner) addOrUpdateNode(nsi *cluster.Snode, osi *cluster.Snode, keepalive bool, kind string) bool {
+// addOrUpdateNode returns true if the cluster map is to be updated with the new node info,
+// and the error string if the node is a duplicate to be rejected. Called under the Smap lock,
+// it relies on the liveness of the registered node probed prior to taking the lock
+func (p *proxyrunner) addOrUpdateNode(nsi *cluster.Snode, osi *cluster.Snode, probe aliveNode, keepalive bool,
+	kind string) (update bool, errstr string) {
 	if keepalive {
 		if osi == nil {
 			glog.Warningf("register/keepalive %s %s: adding back to the cluster map", kind, nsi.DaemonID)
-			return true
+			return true, ""
 		}
 
 		if !osi.Equals(nsi) {
 			if p.detectDaemonDuplicate(osi, nsi) {
 				glog.Errorf("Daemon %s tried to register/keepalive with a duplicate ID %s", nsi.PublicNet.DirectURL, nsi.DaemonID)
-				return false
+				return false, ""
 			}
 			glog.Warningf("register/keepalive %s %s: info changed - renewing", kind, nsi.DaemonID)
-			return true
+			return true, ""
 		}
 
 		p.keepalive.heardFrom(nsi.DaemonID, !keepalive /* reset */)
-		return false
+		return false, ""
 	}
 	if osi != nil {
 		if p.startedup(0) == 0 {
-			return true
+			return true, ""
 		}
 		if osi.Equals(nsi) {
 			glog.Infof("register %s %s: already done", kind, nsi.DaemonID)
-			return false
+			return false, ""
+		}
+		if probe.si == nil || !probe.si.Equals(osi) {
+			errstr = fmt.Sprintf("register %s %s from %s: re-registered concurrently - retry", kind, nsi.DaemonID,
+				nsi.PublicNet.DirectURL)
+			glog.Warningln(errstr)
+			return false, errstr
+		}
+		if probe.alive {
+			errstr = fmt.Sprintf("register %s %s from %s: duplicate ID of the %s at %s", kind, nsi.DaemonID,
+				nsi.PublicNet.DirectURL, kind, osi.PublicNet.DirectURL)
+			glog.Errorln(errstr)
+			return false, errstr
 		}
 		glog.Warningf("register %s %s: renewing the registration %+v => %+v", kind, nsi.DaemonID, osi, nsi)
 	}
-	return true
+	return true, ""
 }
 
 // unregisters a target/proxy
@@ -2362,6 +2779,7 @@ func (p *proxyrunner) httpcludel(w http.ResponseWriter, r *http.Request) {
 // '{"action": "syncsmap"}' /v1/cluster => (proxy) => PUT '{Smap}' /v1/daemon/syncsmap => target(s)
 // '{"action": "rebalance"}' /v1/cluster => (proxy) => PUT '{Smap}' /v1/daemon/rebalance => target(s)
 // '{"action": "setconfig"}' /v1/cluster => (proxy) =>
+// '{"action": "replacetarget"}' /v1/cluster => (proxy) => PUT '{"action": "replacetarget"}' /v1/daemon => target
 func (p *proxyrunner) httpcluput(w http.ResponseWriter, r *http.Request) {
 	var msg cmn.ActionMsg
 	apitems, err := p.checkRESTItems(w, r, 0, true, cmn.Version, cmn.Cluster)
@@ -2431,8 +2849,22 @@ func (p *proxyrunner) httpcluput(w http.ResponseWriter, r *http.Request) {
 		_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
 
 	case cmn.ActGlobalReb:
+		if errstr := p.confirmRebalance(msg.Name); errstr != "" {
+			p.invalmsghdlr(w, r, errstr, http.StatusPreconditionFailed)
+			return
+		}
+		atomic.StoreInt64(&p.rebStarted, time.Now().UnixNano())
 		p.metasyncer.sync(false, p.smapowner.get(), &msg)
 
+	case cmn.ActReplaceTarget:
+		p.replaceTarget(w, r, &msg)
+
+	case cmn.ActRollingUpgrade:
+		p.startUpgrade(w, r, &msg)
+
+	case cmn.ActAbortUpgrade:
+		p.abortUpgrade(w, r)
+
 	default:
 		s := fmt.Sprintf("Unexpected cmn.ActionMsg <- JSON [%v]", msg)
 		p.invalmsghdlr(w, r, s)
@@ -2559,9 +2991,9 @@ func (p *proxyrunner) validateBucketProps(props *cmn.BucketProps, isLocal bool)
 		}
 	}
 	if props.Checksum != cmn.ChecksumInherit &&
-		props.Checksum != cmn.ChecksumNone && props.Checksum != cmn.ChecksumXXHash {
-		return fmt.Errorf("invalid checksum: %s - expecting %s or %s or %s",
-			props.Checksum, cmn.ChecksumXXHash, cmn.ChecksumNone, cmn.ChecksumInherit)
+		props.Checksum != cmn.ChecksumNone && !cmn.ValidCksumType(props.Checksum) {
+		return fmt.Errorf("invalid checksum: %s - expecting %s or %s or %s or %s",
+			props.Checksum, cmn.ChecksumXXHash, cmn.ChecksumCRC32C, cmn.ChecksumNone, cmn.ChecksumInherit)
 	}
 
 	lwm, hwm := props.LowWM, props.HighWM
@@ -2571,6 +3003,14 @@ func (p *proxyrunner) validateBucketProps(props *cmn.BucketProps, isLocal bool)
 	if props.AtimeCacheMax < 0 {
 		return fmt.Errorf("Invalid value: %d, AtimeCacheMax cannot be negative", props.AtimeCacheMax)
 	}
+	if budget := props.PrefetchBudget; budget != nil {
+		if budget.Requests < 0 || budget.Bytes < 0 {
+			return fmt.Errorf("Invalid prefetch budget: requests %d, bytes %d - cannot be negative", budget.Requests, budget.Bytes)
+		}
+		if isLocal {
+			return fmt.Errorf("Prefetch budget applies to Cloud buckets only")
+		}
+	}
 	if props.DontEvictTimeStr != "" {
 		dontEvictTime, err := time.ParseDuration(props.DontEvictTimeStr)
 		if err != nil {
@@ -2586,9 +3026,66 @@ func (p *proxyrunner) validateBucketProps(props *cmn.BucketProps, isLocal bool)
 		}
 		props.CapacityUpdTime = capacityUpdTime
 	}
+	if props.ListProps != "" {
+		for _, prop := range strings.Split(props.ListProps, ",") {
+			if prop = strings.TrimSpace(prop); !validListProp(prop) {
+				return fmt.Errorf("Invalid list property %q in %q", prop, props.ListProps)
+			}
+		}
+	}
+	if props.ListTimeFormat != "" && !validListTimeFormat(props.ListTimeFormat) {
+		return fmt.Errorf("Invalid list time format %q", props.ListTimeFormat)
+	}
+	switch props.SyncPolicy {
+	case "", cmn.SyncPolicyNone, cmn.SyncPolicyData, cmn.SyncPolicyFull, cmn.SyncPolicyGroup:
+	default:
+		return fmt.Errorf("Invalid sync policy %q", props.SyncPolicy)
+	}
+	switch props.AtimePolicy {
+	case "", cmn.AtimeTrack, cmn.AtimeRelaxed, cmn.AtimeIgnore:
+	default:
+		return fmt.Errorf("Invalid atime policy %q", props.AtimePolicy)
+	}
+	if props.DebugSample < 0 || props.DebugSample > 1 {
+		return fmt.Errorf("Invalid debug sample %v: expecting fraction between 0 and 1", props.DebugSample)
+	}
+	if props.PassthroughSize < 0 {
+		return fmt.Errorf("Invalid passthrough size %d: expecting non-negative number of bytes", props.PassthroughSize)
+	}
+	if props.PassthroughSize > 0 && isLocal {
+		return fmt.Errorf("Passthrough size applies only to Cloud buckets")
+	}
 	return nil
 }
 
+func validListProp(prop string) bool {
+	switch prop {
+	case cmn.GetPropsChecksum, cmn.GetPropsSize, cmn.GetPropsAtime, cmn.GetPropsCtime, cmn.GetPropsIsCached,
+		cmn.GetPropsBucket, cmn.GetPropsVersion, cmn.GetTargetURL, cmn.GetPropsStatus:
+		return true
+	}
+	return false
+}
+
+func validListTimeFormat(format string) bool {
+	switch format {
+	case cmn.RFC822, cmn.Stamp, cmn.StampMilli, cmn.RFC822Z, cmn.RFC1123, cmn.RFC1123Z, cmn.RFC3339:
+		return true
+	}
+	return false
+}
+
+// applyListDefaults fills in the bucket's default properties and time format unless
+// the list request specifies its own
+func applyListDefaults(msg *cmn.GetMsg, props *cmn.BucketProps) {
+	if msg.GetProps == "" {
+		msg.GetProps = props.ListProps
+	}
+	if msg.GetTimeFormat == "" {
+		msg.GetTimeFormat = props.ListTimeFormat
+	}
+}
+
 func rechecksumRequired(globalChecksum string, bucketChecksumOld string, bucketChecksumNew string) bool {
 	checksumOld := globalChecksum
 	if bucketChecksumOld != cmn.ChecksumInherit {
@@ -2675,6 +3172,7 @@ func (p *proxyrunner) copyBucketProps(oldProps, newProps *cmn.BucketProps, bucke
 			oldProps.ValidateColdGet = newProps.ValidateColdGet
 			oldProps.ValidateWarmGet = newProps.ValidateWarmGet
 			oldProps.EnableReadRangeChecksum = newProps.EnableReadRangeChecksum
+			oldProps.AsyncPut = newProps.AsyncPut
 		}
 	}
 	oldProps.LowWM = newProps.LowWM // can't conditionally assign if value != 0 since 0 is valid
@@ -2689,4 +3187,12 @@ func (p *proxyrunner) copyBucketProps(oldProps, newProps *cmn.BucketProps, bucke
 		oldProps.CapacityUpdTime = newProps.CapacityUpdTime // parsing done in validateBucketProps()
 	}
 	oldProps.LRUEnabled = newProps.LRUEnabled
+	oldProps.ListProps = newProps.ListProps
+	oldProps.ListTimeFormat = newProps.ListTimeFormat
+	oldProps.PublicRead = newProps.PublicRead
+	oldProps.SyncPolicy = newProps.SyncPolicy
+	oldProps.DebugSample = newProps.DebugSample
+	oldProps.AtimePolicy = newProps.AtimePolicy
+	oldProps.PassthroughSize = newProps.PassthroughSize
+	oldProps.PrefetchBudget = newProps.PrefetchBudget
 }
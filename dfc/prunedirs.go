@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Pruning of empty directories: deleting (or evicting, or moving) objects leaves their
+// directories behind, and empty directory trees slow down listings and the traversals
+// of LRU, rebalance, etc. Every disk_config.prune_dirs_time the target, as part of its
+// periodic housekeeping, walks its buckets and removes the directories that are empty
+// and have not changed within the same period; bucket directories themselves are kept.
+//
+// Coordination with the writers is done via the NameLocker: the directory (its full path)
+// is locked by the pruner exclusively for the duration of the removal, and shared - by
+// those who create the directory to place an object in it (see inObjDir). The latter also
+// recreate the directory once more if its parent gets pruned in-between.
+//
+// ================================ Summary ===============================================
+
+// inObjDir creates the directory of the given fqn and calls create to create (or rename,
+// or copy) the object's file in it, while the directory is locked against pruning
+func (t *targetrunner) inObjDir(fqn string, create func() error) (err error) {
+	dir := filepath.Dir(fqn)
+	t.rtnamemap.Lock(dir, false)
+	defer t.rtnamemap.Unlock(dir, false)
+	for i := 0; i < 2; i++ {
+		if err = cmn.CreateDir(dir); err == nil {
+			err = create()
+		}
+		if !os.IsNotExist(err) {
+			return
+		}
+	}
+	return
+}
+
+// PruneEmptyDirs removes the empty object directories of all buckets on all available
+// mountpaths; it is called by housekeeping and does nothing if the previous run is in progress
+func (t *targetrunner) PruneEmptyDirs() {
+	if !atomic.CompareAndSwapInt64(&t.pruning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt64(&t.pruning, 0)
+
+	var (
+		pruned   int64
+		started  = time.Now()
+		grace    = ctx.config.Disk.PruneDirsTime
+		avail, _ = fs.Mountpaths.Get()
+	)
+	for _, mpathInfo := range avail {
+		for _, root := range []string{fs.Mountpaths.MakePathLocal(mpathInfo.Path), fs.Mountpaths.MakePathCloud(mpathInfo.Path)} {
+			buckets, err := filepath.Glob(filepath.Join(root, "*"))
+			if err != nil {
+				continue
+			}
+			for _, bdir := range buckets {
+				pruned += t.pruneBucketDir(bdir, started.Add(-grace))
+			}
+		}
+	}
+	if pruned > 0 {
+		t.statsif.Add(stats.PrunedDirCount, pruned)
+		glog.Infof("Pruned %d empty directories in %v", pruned, time.Since(started))
+	}
+}
+
+// pruneBucketDir removes the empty subdirectories of the bucket's directory that have not
+// changed since the given time; the subdirectories are removed bottom-up, so that the
+// directories that contain only empty ones get removed as well
+func (t *targetrunner) pruneBucketDir(bdir string, since time.Time) (pruned int64) {
+	type dirinfo struct {
+		path  string
+		mtime time.Time
+	}
+	dirs := make([]dirinfo, 0, 64)
+	walk := func(fqn string, osfi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if osfi.IsDir() && fqn != bdir {
+			dirs = append(dirs, dirinfo{fqn, osfi.ModTime()})
+		}
+		return nil
+	}
+	if err := filepath.Walk(bdir, walk); err != nil {
+		glog.Errorf("Failed to traverse %s, err: %v", bdir, err)
+		return
+	}
+	// children follow their parents in the walk order; removing a child changes the
+	// parent's mtime - hence, the mtimes collected during the walk
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		if dir.mtime.After(since) {
+			continue
+		}
+		if !t.rtnamemap.TryLock(dir.path, true) {
+			continue
+		}
+		err := os.Remove(dir.path) // fails unless empty
+		t.rtnamemap.Unlock(dir.path, true)
+		if err == nil {
+			pruned++
+		}
+	}
+	return
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestPruneBucketDir(t *testing.T) {
+	bdir, err := ioutil.TempDir("", "prunedirs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(bdir)
+	for _, dir := range []string{"a/b/c", "a/d", "e/f", "g"} {
+		if err := cmn.CreateDir(filepath.Join(bdir, dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(bdir, "a/d/obj"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tgt := &targetrunner{rtnamemap: newrtnamemap(16)}
+
+	// too recent
+	if pruned := tgt.pruneBucketDir(bdir, time.Now().Add(-time.Hour)); pruned != 0 {
+		t.Fatalf("expected no directories pruned, got %d", pruned)
+	}
+	// locked by a writer
+	locked := filepath.Join(bdir, "g")
+	tgt.rtnamemap.Lock(locked, false)
+	if pruned := tgt.pruneBucketDir(bdir, time.Now().Add(time.Second)); pruned != 4 {
+		t.Errorf("expected 4 directories pruned (a/b/c, a/b, e/f, e), got %d", pruned)
+	}
+	tgt.rtnamemap.Unlock(locked, false)
+	for _, dir := range []string{"a/d/obj", "g"} {
+		if _, err := os.Stat(filepath.Join(bdir, dir)); err != nil {
+			t.Errorf("expected %s to stay, err: %v", dir, err)
+		}
+	}
+	for _, dir := range []string{"a/b", "e"} {
+		if _, err := os.Stat(filepath.Join(bdir, dir)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be pruned, err: %v", dir, err)
+		}
+	}
+	if _, err := os.Stat(bdir); err != nil {
+		t.Errorf("expected the bucket directory to stay, err: %v", err)
+	}
+
+	// the directory is created again if pruned in-between
+	fqn := filepath.Join(bdir, "h/i/obj")
+	attempts := 0
+	err = tgt.inObjDir(fqn, func() error {
+		if attempts++; attempts == 1 {
+			os.RemoveAll(filepath.Join(bdir, "h"))
+		}
+		return ioutil.WriteFile(fqn, []byte("data"), 0644)
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("expected the object created on the 2nd attempt, got %d attempts, err: %v", attempts, err)
+	}
+}
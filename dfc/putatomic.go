@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ================================ Summary ===============================================
+//
+// Atomic overwrite: PUT receives the object into a workfile that resides next to the
+// object (and on the same filesystem), validates the workfile's size and checksum, sets
+// its metadata (xattrs), and only then renames it over the object. The rename is the
+// commit point: a PUT that fails at any earlier point leaves the previous version intact
+// (and removes the workfile), and so does a target that crashes - the workfiles it leaves
+// behind are never listed nor rebalanced.
+//
+// When there is a previous version, the workfile's data is flushed before the rename -
+// otherwise, after a crash, the object could be replaced with a file that lost (some of)
+// its data. New objects are not flushed unless the bucket's sync_policy says so.
+//
+// A PUT into a Cloud bucket stores the object in the Cloud before committing it locally;
+// if the local commit fails, the cached previous version is evicted, so that it does not
+// shadow the new version that is already in the Cloud.
+//
+// ================================ Summary ===============================================
+
+// replaceObj atomically replaces (or creates) the object with the workfile; setxattrs
+// sets the new object's metadata on the workfile prior to the rename
+func replaceObj(workfqn, fqn string, setxattrs func(workfqn string) string) (errstr string, err error) {
+	if setxattrs != nil {
+		if errstr = setxattrs(workfqn); errstr != "" {
+			return
+		}
+	}
+	if _, errstat := os.Stat(fqn); errstat == nil {
+		if err = syncFile(workfqn, true /*data only*/); err != nil {
+			errstr = fmt.Sprintf("Failed to flush %s prior to overwriting %s, err: %v", workfqn, fqn, err)
+			return
+		}
+	}
+	if err = os.Rename(workfqn, fqn); err != nil {
+		errstr = fmt.Sprintf("Failed to rename %s => %s, err: %v", workfqn, fqn, err)
+	}
+	return
+}
+
+// setObjXattrs stores the object's checksum and version as xattrs of the given file
+func setObjXattrs(fqn string, objprops *objectProps) (errstr string) {
+	if objprops.nhobj != nil {
+		htype, hval := objprops.nhobj.get()
+		cmn.Assert(cmn.ValidCksumType(htype))
+		if errstr = Setxattr(fqn, cmn.XattrXXHashVal, cmn.MakeStoredCksum(htype, hval)); errstr != "" {
+			return
+		}
+	}
+	if objprops.version != "" {
+		if errstr = Setxattr(fqn, cmn.XattrObjVersion, []byte(objprops.version)); errstr != "" {
+			return
+		}
+	}
+	if !objprops.expires.IsZero() {
+		if errstr = Setxattr(fqn, cmn.XattrExpires, []byte(objprops.expires.Format(time.RFC3339Nano))); errstr != "" {
+			return
+		}
+	}
+	if objprops.cksumPending {
+		errstr = Setxattr(fqn, cmn.XattrCksumPending, []byte{'1'})
+	}
+	return
+}
+
+// evictStale removes the cached copy of the object that failed to commit after its new
+// version was stored in the Cloud
+func (t *targetrunner) evictStale(fqn, bucket string) {
+	finfo, _ := os.Stat(fqn)
+	if err := os.Remove(fqn); err != nil {
+		if !os.IsNotExist(err) {
+			glog.Errorf("Failed to evict stale %s, err: %v", fqn, err)
+		}
+		return
+	}
+	if finfo != nil {
+		addBucketCapacity(fqn, bucket, false /* islocal */, -1, -finfo.Size())
+	}
+	glog.Warningf("Evicted stale %s: the new version is in the Cloud", fqn)
+}
+
+// removeWorkfile removes the workfile of the PUT that failed validation
+func (t *targetrunner) removeWorkfile(workfqn, errstr string) {
+	if err := os.Remove(workfqn); err != nil && !os.IsNotExist(err) {
+		glog.Errorf("Nested error: %s => (remove %s => err: %v)", errstr, workfqn, err)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+)
+
+func TestReplaceObj(t *testing.T) {
+	dir, err := ioutil.TempDir("", "putatomic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		fqn     = filepath.Join(dir, "obj")
+		workfqn = cluster.GenContentFQN(fqn, cluster.DefaultWorkfileType)
+	)
+	put := func(content string) {
+		if err := ioutil.WriteFile(workfqn, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(crashpoint, expected string) {
+		b, err := ioutil.ReadFile(fqn)
+		if err != nil {
+			t.Fatalf("%s: %v", crashpoint, err)
+		}
+		if string(b) != expected {
+			t.Errorf("%s: expected %q, got %q", crashpoint, expected, string(b))
+		}
+	}
+	put("v1")
+	if errstr, _ := replaceObj(workfqn, fqn, nil); errstr != "" {
+		t.Fatal(errstr)
+	}
+	check("create", "v1")
+
+	// crash after the workfile is received
+	put("v2-partial")
+	check("received", "v1")
+
+	// failure to set xattrs
+	put("v2")
+	if errstr, _ := replaceObj(workfqn, fqn, func(string) string { return "xattrs" }); errstr != "xattrs" {
+		t.Errorf("expected xattrs failure, got %q", errstr)
+	}
+	check("xattrs", "v1")
+
+	// failure to rename
+	put("v2")
+	if errstr, _ := replaceObj(workfqn, fqn, func(w string) string { os.Remove(w); return "" }); errstr == "" {
+		t.Error("expected rename failure")
+	}
+	check("rename", "v1")
+
+	// the metadata is set on the complete workfile, prior to the rename
+	put("v2")
+	errstr, _ := replaceObj(workfqn, fqn, func(w string) string {
+		if w != workfqn {
+			t.Errorf("expected xattrs set on %s, got %s", workfqn, w)
+		}
+		check("setxattrs", "v1")
+		return ""
+	})
+	if errstr != "" {
+		t.Fatal(errstr)
+	}
+	check("replace", "v2")
+	if _, err := os.Stat(workfqn); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be gone, err: %v", workfqn, err)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Duplicate PUT suppression: a client may PUT an object with an idempotency key
+// (cmn.HeaderDFCIdempotencyKey) - and then retry the PUT with the same key as many times
+// as it takes, e.g. upon timeouts. The proxy redirects all the retries to the same target -
+// the object's HRW target - and the target that has completed the PUT with the key within
+// put_dedup.ttl responds to the retries with success without receiving the object again.
+// A retry that arrives while the original PUT is still in progress waits for the latter
+// to complete; if it fails, the retry is executed.
+//
+// A key identifies a single PUT of a single object: reusing it for another object fails
+// with 409 (Conflict). Each target keeps at most put_dedup.max_keys records (zero means
+// putDedupMaxKeys), and the keys that do not fit are ignored. The completed records are
+// queued in the order of completion, so that the expired ones are removed from the head of
+// the queue - by housekeeping and when the records are at max - without scanning them all.
+// The records are not replicated: a retry that lands on a different target (the cluster
+// map has changed in the meantime) is executed again.
+//
+// ================================ Summary ===============================================
+
+// putDedupMaxKeys is the default put_dedup.max_keys
+const putDedupMaxKeys = 64 * 1024
+
+type (
+	putDedup struct {
+		sync.Mutex
+		keys map[string]*putKey // idempotency key => PUT
+		done []doneKey          // completed PUTs in the order of completion
+	}
+	doneKey struct {
+		key string
+		pk  *putKey
+	}
+	putKey struct {
+		uname   string        // bucket and object
+		done    chan struct{} // closed when the PUT completes
+		ok      bool          // the PUT has succeeded - valid once done
+		expires time.Time     // zero while the PUT is in progress
+	}
+)
+
+func newPutDedup() *putDedup {
+	return &putDedup{keys: make(map[string]*putKey)}
+}
+
+// begin returns the PUT with the key, in progress or completed, or else registers the
+// new one (owner == true); nil PUT means the key cannot be recorded
+func (d *putDedup) begin(key, uname string, now time.Time, maxKeys int) (pk *putKey, owner bool, err error) {
+	if maxKeys == 0 {
+		maxKeys = putDedupMaxKeys
+	}
+	d.Lock()
+	defer d.Unlock()
+	if pk, ok := d.keys[key]; ok {
+		if pk.expires.IsZero() || now.Before(pk.expires) {
+			if pk.uname != uname {
+				return nil, false, fmt.Errorf("idempotency key %q is already used for %s", key, pk.uname)
+			}
+			return pk, false, nil
+		}
+		delete(d.keys, key)
+	}
+	if len(d.keys) >= maxKeys {
+		d.prune(now)
+		if len(d.keys) >= maxKeys {
+			return nil, true, nil
+		}
+	}
+	pk = &putKey{uname: uname, done: make(chan struct{})}
+	d.keys[key] = pk
+	return pk, true, nil
+}
+
+// end records the completed PUT for the next ttl or, if the PUT has failed, forgets it
+func (d *putDedup) end(key string, pk *putKey, ok bool, now time.Time, ttl time.Duration) {
+	if pk == nil {
+		return
+	}
+	d.Lock()
+	if ok {
+		pk.ok, pk.expires = true, now.Add(ttl)
+		d.done = append(d.done, doneKey{key: key, pk: pk})
+	} else if d.keys[key] == pk {
+		delete(d.keys, key)
+	}
+	d.Unlock()
+	close(pk.done)
+}
+
+// prune removes the expired records from the head of the completion queue; with ttl
+// changed at runtime, the records behind a longer-lived one wait for the latter to expire
+func (d *putDedup) prune(now time.Time) {
+	var n int
+	for ; n < len(d.done); n++ {
+		dk := d.done[n]
+		if now.Before(dk.pk.expires) {
+			break
+		}
+		if d.keys[dk.key] == dk.pk {
+			delete(d.keys, dk.key)
+		}
+	}
+	if n > 0 {
+		d.done = append(d.done[:0], d.done[n:]...)
+	}
+}
+
+// PruneIdempotencyKeys removes the expired records of the PUTs with idempotency keys;
+// it is called by housekeeping
+func (t *targetrunner) PruneIdempotencyKeys() {
+	t.putDedup.Lock()
+	t.putDedup.prune(time.Now())
+	t.putDedup.Unlock()
+}
+
+// putOnce executes the PUT unless the PUT with the same idempotency key has already
+// succeeded (or does succeed while this one waits)
+func (t *targetrunner) putOnce(r *http.Request, key, bucket, objname string, put func() (string, int)) (errstr string, errcode int) {
+	uname := cluster.Uname(bucket, objname)
+	for {
+		pk, owner, err := t.putDedup.begin(key, uname, time.Now(), ctx.config.PutDedup.MaxKeys)
+		if err != nil {
+			return err.Error(), http.StatusConflict
+		}
+		if owner {
+			errstr, errcode = put()
+			t.putDedup.end(key, pk, errstr == "", time.Now(), ctx.config.PutDedup.TTL)
+			return
+		}
+		select {
+		case <-pk.done:
+		case <-r.Context().Done():
+			return fmt.Sprintf("PUT %s/%s (idempotency key %q) canceled", bucket, objname, key), 0
+		}
+		if pk.ok {
+			t.statsif.Add(stats.PutDupCount, 1)
+			if glog.V(4) {
+				glog.Infof("PUT %s/%s (idempotency key %q) is a duplicate: no-op", bucket, objname, key)
+			}
+			return "", 0
+		}
+	}
+}
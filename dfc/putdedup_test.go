@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPutDedup(t *testing.T) {
+	var (
+		d   = newPutDedup()
+		now = time.Now()
+		ttl = time.Minute
+	)
+	pk, owner, err := d.begin("k1", "b/o1", now, 2)
+	if err != nil || pk == nil || !owner {
+		t.Fatalf("expected new PUT, got %v (owner %t, err %v)", pk, owner, err)
+	}
+
+	// in progress: the retry gets the same PUT to wait for
+	pk2, owner, err := d.begin("k1", "b/o1", now, 2)
+	if err != nil || pk2 != pk || owner {
+		t.Fatalf("expected the PUT in progress, got %v (owner %t, err %v)", pk2, owner, err)
+	}
+	if _, _, err = d.begin("k1", "b/o2", now, 2); err == nil {
+		t.Error("expected the key reused for another object to fail")
+	}
+
+	// failed: forgotten, and the retry is executed
+	d.end("k1", pk, false, now, ttl)
+	<-pk.done
+	if pk.ok {
+		t.Error("expected failed PUT")
+	}
+	if pk, owner, _ = d.begin("k1", "b/o1", now, 2); pk == nil || !owner {
+		t.Fatalf("expected new PUT, got %v (owner %t)", pk, owner)
+	}
+
+	// succeeded: the retries are duplicates until the record expires
+	d.end("k1", pk, true, now, ttl)
+	if pk2, owner, _ = d.begin("k1", "b/o1", now.Add(time.Second), 2); pk2 != pk || owner || !pk2.ok {
+		t.Fatalf("expected completed PUT, got %v (owner %t)", pk2, owner)
+	}
+	if pk2, owner, _ = d.begin("k1", "b/o1", now.Add(2*ttl), 2); pk2 == pk || !owner {
+		t.Fatalf("expected new PUT once expired, got %v (owner %t)", pk2, owner)
+	}
+	d.end("k1", pk2, true, now.Add(2*ttl), ttl)
+
+	// max keys: expired records make room; otherwise, the key is not recorded
+	if pk, owner, _ = d.begin("k2", "b/o2", now.Add(2*ttl), 2); pk == nil || !owner {
+		t.Fatalf("expected new PUT, got %v (owner %t)", pk, owner)
+	}
+	if pk, owner, _ = d.begin("k3", "b/o3", now.Add(2*ttl), 2); pk != nil || !owner {
+		t.Errorf("expected the key not recorded, got %v (owner %t)", pk, owner)
+	}
+	if pk, owner, _ = d.begin("k3", "b/o3", now.Add(4*ttl), 2); pk == nil || !owner || len(d.keys) != 2 {
+		t.Errorf("expected new PUT, got %v (owner %t), %d keys", pk, owner, len(d.keys))
+	}
+
+	// housekeeping forgets the expired records; zero max keys is the default
+	d.end("k3", pk, true, now.Add(4*ttl), ttl)
+	d.prune(now.Add(6 * ttl))
+	if len(d.keys) != 1 || len(d.done) != 0 {
+		t.Errorf("expected the PUT in progress only, got %d keys, %d completed", len(d.keys), len(d.done))
+	}
+	if pk, owner, _ = d.begin("k4", "b/o4", now.Add(6*ttl), 0); pk == nil || !owner {
+		t.Errorf("expected new PUT, got %v (owner %t)", pk, owner)
+	}
+}
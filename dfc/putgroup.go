@@ -0,0 +1,600 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/stats"
+	"github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Group (transactional) PUT: the client opens a group (ActBeginGroup), PUTs any number
+// of objects with ?group=<ID>, and then either commits (ActCommitGroup) or aborts
+// (ActAbortGroup) the group.
+//
+// Targets stage the group's objects in the group's directory on the objects' mountpaths -
+// <mountpath>/.dfc-putgroups/<group ID>/<object name> - where the staged content is not
+// visible to GET, list-bucket, and the xactions. Commit is a three-phase protocol
+// coordinated by the proxy:
+//   1) prepare: each target validates that it has staged every listed object that maps
+//      onto it (HRW) and stops accepting new PUTs into the group;
+//   2) commit: each target renames the staged objects into place, keeping the prior
+//      versions of the overwritten objects (hard links in the group's directory);
+//   3) finalize: once every target has acknowledged the commit, the targets remove the
+//      prior versions.
+// If any target fails to prepare, the proxy aborts the group on all targets and no object
+// becomes visible. If any target fails to commit, the proxy aborts the group as well: the
+// targets roll back the committed objects, restoring the prior versions or removing the
+// objects that did not exist before. Only the objects that fail to roll back remain
+// committed - the response (cmn.PutGroupCommit) lists them along with the targets' errors.
+// The objects thus become visible together, save for the (brief) window between the first
+// target's commit and the last one's, and save for the target failures during the commit:
+// a target restarted in the middle discards the group's directory and keeps whatever it has
+// committed.
+//
+// Groups are supported for the local buckets that have no next tier - the objects PUT to a
+// Cloud (or next tier) bucket are uploaded upon commit and cannot be rolled back. Groups that
+// are neither committed nor aborted within putGroupTimeout are discarded by the targets (the
+// committed ones - finalized), and so are all staged groups upon target restart. The IDs of
+// the finished groups are remembered for putGroupTimeout, and PUTs into them are rejected.
+//
+// ================================ Summary ===============================================
+
+const (
+	putGroupTimeout  = time.Hour
+	putGroupsDirName = ".dfc-putgroups" // in the mountpath's root
+)
+
+// commit phases other than the commit itself (see bcastPutGroupReq)
+const (
+	phasePrepare  = "prepare"
+	phaseFinalize = "finalize"
+)
+
+type (
+	stagedObj struct {
+		putfqn  string
+		fqn     string
+		props   *objectProps
+		prev    string // hard link to the overwritten object's prior version; "" - none
+		applied bool   // committed
+	}
+	putGroup struct {
+		id        string
+		bucket    string
+		started   time.Time
+		prepared  bool
+		committed bool
+		objs      map[string]*stagedObj // objname => staged
+	}
+	putGroups struct {
+		sync.Mutex
+		m    map[string]*putGroup // group ID => group
+		done map[string]time.Time // group ID => when committed or aborted
+	}
+)
+
+var putGroupSeq int64
+
+func newPutGroups() *putGroups {
+	return &putGroups{m: make(map[string]*putGroup, 8), done: make(map[string]time.Time, 8)}
+}
+
+// cleanup removes the group's directories along with the objects that remain staged and
+// the prior versions of the committed ones
+func (g *putGroup) cleanup() {
+	availablePaths, _ := fs.Mountpaths.Get()
+	for mpath := range availablePaths {
+		dir := filepath.Join(mpath, putGroupsDirName, g.id)
+		if err := os.RemoveAll(dir); err != nil {
+			glog.Errorf("Failed to remove PUT group directory %s, err: %v", dir, err)
+		}
+	}
+}
+
+// putGroupFQN returns the staging workfile of the group's object on the object's mountpath,
+// for the commit to rename it into place
+func putGroupFQN(gid, objname, fqn string) (string, error) {
+	if gid == "" || gid[0] == '.' || strings.ContainsAny(gid, "/\\") {
+		return "", fmt.Errorf("Invalid PUT group ID %q", gid)
+	}
+	parsedFQN, err := fs.Mountpaths.FQN2Info(fqn)
+	if err != nil {
+		return "", err
+	}
+	return cluster.GenContentFQN(filepath.Join(parsedFQN.MpathInfo.Path, putGroupsDirName, gid, objname),
+		cluster.DefaultWorkfileType), nil
+}
+
+// removeStalePutGroups removes the groups staged prior to the target's restart
+func removeStalePutGroups() {
+	availablePaths, _ := fs.Mountpaths.Get()
+	for mpath := range availablePaths {
+		dir := filepath.Join(mpath, putGroupsDirName)
+		if err := os.RemoveAll(dir); err != nil {
+			glog.Errorf("Failed to remove stale PUT groups %s, err: %v", dir, err)
+		}
+	}
+}
+
+// must be called under lock
+func (pg *putGroups) purgeExpired() {
+	for gid, g := range pg.m {
+		if time.Since(g.started) > putGroupTimeout {
+			if g.committed {
+				glog.Warningf("PUT group %s (bucket %s, %d objects) expired - finalizing", gid, g.bucket, len(g.objs))
+			} else {
+				glog.Warningf("PUT group %s (bucket %s, %d objects) expired - discarding", gid, g.bucket, len(g.objs))
+			}
+			g.cleanup()
+			delete(pg.m, gid)
+			pg.done[gid] = time.Now()
+		}
+	}
+	for gid, finished := range pg.done {
+		if time.Since(finished) > putGroupTimeout {
+			delete(pg.done, gid)
+		}
+	}
+}
+
+// finish removes the group once committed or aborted; must be called under lock
+func (pg *putGroups) finish(gid string) (g *putGroup, ok bool) {
+	if g, ok = pg.m[gid]; ok {
+		delete(pg.m, gid)
+	}
+	pg.done[gid] = time.Now()
+	return
+}
+
+func parsePutGroupMsg(msg *cmn.ActionMsg) (*cmn.PutGroupMsg, error) {
+	grpmsg := &cmn.PutGroupMsg{}
+	b, err := jsoniter.Marshal(msg.Value)
+	if err == nil {
+		err = jsoniter.Unmarshal(b, grpmsg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s message value %+v, err: %v", msg.Action, msg.Value, err)
+	}
+	if grpmsg.GroupID == "" {
+		return nil, fmt.Errorf("Invalid %s message: missing group ID", msg.Action)
+	}
+	return grpmsg, nil
+}
+
+//
+// target
+//
+
+// stageGroupPut is called by doput in place of putCommit
+func (t *targetrunner) stageGroupPut(gid, bucket, objname, putfqn, fqn string, props *objectProps) (errstr string) {
+	t.putGroups.Lock()
+	defer t.putGroups.Unlock()
+	t.putGroups.purgeExpired()
+
+	if _, ok := t.putGroups.done[gid]; ok {
+		errstr = fmt.Sprintf("PUT group %s is already committed or aborted, cannot PUT %s/%s", gid, bucket, objname)
+	} else {
+		errstr = t.putGroupBucket(bucket)
+	}
+	if errstr != "" {
+		if err := os.Remove(putfqn); err != nil {
+			glog.Errorf("Nested error: %s => (remove %s => err: %v)", errstr, putfqn, err)
+		}
+		return
+	}
+	g, ok := t.putGroups.m[gid]
+	if !ok {
+		g = &putGroup{id: gid, bucket: bucket, started: time.Now(), objs: make(map[string]*stagedObj, 4)}
+		t.putGroups.m[gid] = g
+	}
+	if g.bucket != bucket {
+		errstr = fmt.Sprintf("PUT group %s belongs to bucket %s, cannot PUT %s/%s", gid, g.bucket, bucket, objname)
+	} else if g.prepared {
+		errstr = fmt.Sprintf("PUT group %s is being committed, cannot PUT %s/%s", gid, bucket, objname)
+	}
+	if errstr != "" {
+		if err := os.Remove(putfqn); err != nil {
+			glog.Errorf("Nested error: %s => (remove %s => err: %v)", errstr, putfqn, err)
+		}
+		return
+	}
+	if prev, ok := g.objs[objname]; ok {
+		if err := os.Remove(prev.putfqn); err != nil && !os.IsNotExist(err) {
+			glog.Errorf("Failed to remove previously staged %s, err: %v", prev.putfqn, err)
+		}
+	}
+	g.objs[objname] = &stagedObj{putfqn: putfqn, fqn: fqn, props: props}
+	if glog.V(4) {
+		glog.Infof("PUT group %s: staged %s/%s", gid, bucket, objname)
+	}
+	return
+}
+
+// putGroupBucket returns an error unless the bucket supports PUT groups - the PUTs to
+// the Cloud and to the next tier cannot be rolled back
+func (t *targetrunner) putGroupBucket(bucket string) (errstr string) {
+	bucketmd := t.bmdowner.get()
+	islocal := bucketmd.IsLocal(bucket)
+	if !islocal {
+		return fmt.Sprintf("Cannot PUT a group of objects into Cloud bucket %s: only local buckets are supported", bucket)
+	}
+	if _, p := bucketmd.get(bucket, islocal); p.NextTierURL != "" {
+		return fmt.Sprintf("Cannot PUT a group of objects into local bucket %s that has a next tier", bucket)
+	}
+	return
+}
+
+// POST { commitgroup | abortgroup } /v1/buckets/bucket-name
+func (t *targetrunner) putGroupAction(w http.ResponseWriter, r *http.Request, bucket string, msg *cmn.ActionMsg) {
+	grpmsg, err := parsePutGroupMsg(msg)
+	if err != nil {
+		t.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	var (
+		errstr string
+		result *cmn.PutGroupCommit
+		query  = r.URL.Query()
+	)
+	switch {
+	case msg.Action == cmn.ActAbortGroup:
+		result = t.abortGroup(grpmsg.GroupID)
+	case query.Get(cmn.URLParamPrepare) == "true":
+		errstr = t.prepareGroup(grpmsg.GroupID, bucket, grpmsg.Objnames)
+	case query.Get(cmn.URLParamFinalize) == "true":
+		t.finalizeGroup(grpmsg.GroupID)
+	default:
+		result, errstr = t.commitGroup(r, grpmsg.GroupID)
+	}
+	if errstr != "" {
+		t.invalmsghdlr(w, r, errstr)
+		return
+	}
+	if result != nil {
+		jsbytes, err := jsoniter.Marshal(result)
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, msg.Action)
+	}
+}
+
+// prepareGroup validates that all the listed objects that belong to this target are staged
+func (t *targetrunner) prepareGroup(gid, bucket string, objnames []string) (errstr string) {
+	smap := t.smapowner.get()
+	t.putGroups.Lock()
+	defer t.putGroups.Unlock()
+
+	g, ok := t.putGroups.m[gid]
+	if ok && g.bucket != bucket {
+		return fmt.Sprintf("PUT group %s belongs to bucket %s, not %s", gid, g.bucket, bucket)
+	}
+	listed := 0
+	for _, objname := range objnames {
+		si, errstr := hrwTarget(bucket, objname, smap)
+		if errstr != "" {
+			return errstr
+		}
+		if si.DaemonID != t.si.DaemonID {
+			continue
+		}
+		listed++
+		if !ok || g.objs[objname] == nil {
+			return fmt.Sprintf("PUT group %s: %s/%s is not staged at %s", gid, bucket, objname, t.si.DaemonID)
+		}
+		if _, err := os.Stat(g.objs[objname].putfqn); err != nil {
+			return fmt.Sprintf("PUT group %s: failed to stat staged %s/%s, err: %v", gid, bucket, objname, err)
+		}
+	}
+	if !ok {
+		return
+	}
+	if listed != len(g.objs) {
+		return fmt.Sprintf("PUT group %s: %d objects staged at %s, %d listed for commit", gid, len(g.objs), t.si.DaemonID, listed)
+	}
+	g.prepared = true
+	return
+}
+
+// commitGroup makes the group's staged objects visible and returns the ones committed by
+// this target. The group stays until finalized (or aborted - rolled back) by the proxy
+func (t *targetrunner) commitGroup(r *http.Request, gid string) (result *cmn.PutGroupCommit, errstr string) {
+	t.putGroups.Lock()
+	g, ok := t.putGroups.m[gid]
+	if ok && g.prepared && !g.committed {
+		g.committed = true
+	} else if ok {
+		errstr = fmt.Sprintf("PUT group %s: cannot commit, the group is not prepared or already committed", gid)
+	}
+	t.putGroups.Unlock()
+	if errstr != "" {
+		return
+	}
+	result = &cmn.PutGroupCommit{GroupID: gid, Committed: []string{}}
+	if !ok {
+		return // nothing was PUT to this target
+	}
+	var (
+		ct   = t.contextWithAuth(r)
+		errs = make([]string, 0)
+	)
+	for objname, staged := range g.objs {
+		var errstr string
+		// the object's directory may not exist yet - see prunedirs.go
+		err := t.inObjDir(staged.fqn, func() error {
+			if errstr = staged.keepPrev(); errstr != "" {
+				return nil
+			}
+			errstr, _ = t.putCommit(ct, g.bucket, objname, staged.putfqn, staged.fqn, staged.props, false /*rebalance*/)
+			return nil
+		})
+		if err != nil {
+			errstr = fmt.Sprintf("Failed to create the directory of %s, err: %v", staged.fqn, err)
+		}
+		if errstr != "" {
+			errs = append(errs, errstr)
+			continue
+		}
+		staged.applied = true
+		result.Committed = append(result.Committed, objname) // visible, even if not synced
+		if errstr = t.syncPut(g.bucket, staged.fqn); errstr != "" {
+			errs = append(errs, errstr)
+		}
+		if finfo, err := os.Stat(staged.fqn); err == nil {
+			getstorstatsrunner().AddBucketIO(g.bucket, &cmn.BucketIOStats{PutCount: 1, PutSize: finfo.Size()})
+		}
+	}
+	sort.Strings(result.Committed)
+	t.statsif.AddBucket(g.bucket, stats.NamedVal64{Name: stats.PutCount, Val: int64(len(result.Committed))})
+	if len(errs) != 0 {
+		s := fmt.Sprintf("failed to commit %d object(s): %s", len(errs), strings.Join(errs, "; "))
+		glog.Errorf("PUT group %s: %s", gid, s)
+		result.Errors = map[string]string{t.si.DaemonID: s}
+		return
+	}
+	glog.Infof("PUT group %s: committed %d objects to bucket %s", gid, len(result.Committed), g.bucket)
+	return
+}
+
+// keepPrev hard-links the object's current version (if any) next to the staged one, for
+// the rollback to restore it; called prior to the commit
+func (staged *stagedObj) keepPrev() (errstr string) {
+	prev := staged.putfqn + ".prev"
+	if err := os.Link(staged.fqn, prev); err != nil {
+		if os.IsNotExist(err) {
+			return // new object
+		}
+		return fmt.Sprintf("Failed to keep the prior version of %s, err: %v", staged.fqn, err)
+	}
+	staged.prev = prev
+	return
+}
+
+// finalizeGroup is called once all the targets have committed the group: the prior
+// versions of the objects are no longer needed
+func (t *targetrunner) finalizeGroup(gid string) {
+	t.putGroups.Lock()
+	g, ok := t.putGroups.finish(gid)
+	t.putGroups.Unlock()
+	if ok {
+		g.cleanup()
+	}
+}
+
+// abortGroup discards the group's staged objects or, if the group is committed, rolls it
+// back; returns the objects that remain committed, if any
+func (t *targetrunner) abortGroup(gid string) (result *cmn.PutGroupCommit) {
+	t.putGroups.Lock()
+	g, ok := t.putGroups.finish(gid)
+	t.putGroups.Unlock()
+	result = &cmn.PutGroupCommit{GroupID: gid, Committed: []string{}}
+	if !ok {
+		return
+	}
+	if g.committed {
+		errs := make([]string, 0)
+		for objname, staged := range g.objs {
+			if !staged.applied {
+				continue
+			}
+			if errstr := t.rollbackObj(g.bucket, objname, staged); errstr != "" {
+				errs = append(errs, errstr)
+				result.Committed = append(result.Committed, objname)
+			}
+		}
+		sort.Strings(result.Committed)
+		if len(errs) != 0 {
+			s := fmt.Sprintf("failed to roll back %d object(s): %s", len(errs), strings.Join(errs, "; "))
+			glog.Errorf("PUT group %s: %s", gid, s)
+			result.Errors = map[string]string{t.si.DaemonID: s}
+		}
+	}
+	g.cleanup()
+	glog.Infof("PUT group %s: aborted, %d objects discarded", gid, len(g.objs)-len(result.Committed))
+	return
+}
+
+// rollbackObj restores the prior version of the committed object or, if there was none,
+// removes the object
+func (t *targetrunner) rollbackObj(bucket, objname string, staged *stagedObj) (errstr string) {
+	uname := cluster.Uname(bucket, objname)
+	t.rtnamemap.Lock(uname, true)
+	defer t.rtnamemap.Unlock(uname, true)
+
+	t.fdCache.invalidate(staged.fqn)
+	t.mdCache.invalidate(staged.fqn)
+	if staged.prev != "" {
+		objects, bytes := replaceDelta(staged.prev, staged.fqn)
+		if err := os.Rename(staged.prev, staged.fqn); err != nil {
+			return fmt.Sprintf("Failed to restore the prior version of %s/%s, err: %v", bucket, objname, err)
+		}
+		addBucketCapacity(staged.fqn, bucket, true /*islocal*/, objects, bytes)
+		return
+	}
+	finfo, err := os.Stat(staged.fqn)
+	if err == nil {
+		err = os.Remove(staged.fqn)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		return fmt.Sprintf("Failed to remove %s/%s, err: %v", bucket, objname, err)
+	}
+	addBucketCapacity(staged.fqn, bucket, true /*islocal*/, -1, -finfo.Size())
+	return
+}
+
+//
+// proxy
+//
+
+// POST { begingroup | commitgroup | abortgroup } /v1/buckets/bucket-name
+func (p *proxyrunner) putGroupAction(w http.ResponseWriter, r *http.Request, bucket string, msg *cmn.ActionMsg) {
+	if msg.Action == cmn.ActBeginGroup {
+		bucketmd := p.bmdowner.get()
+		if !bucketmd.IsLocal(bucket) {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Cannot PUT a group of objects into Cloud bucket %s: only local buckets are supported", bucket))
+			return
+		}
+		if _, props := bucketmd.get(bucket, true); props.NextTierURL != "" {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Cannot PUT a group of objects into local bucket %s that has a next tier", bucket))
+			return
+		}
+		// group ID is all it takes: targets create their groups upon the first PUT
+		grpmsg := &cmn.PutGroupMsg{
+			GroupID: fmt.Sprintf("%s-%x-%d", p.si.DaemonID, time.Now().UnixNano(), atomic.AddInt64(&putGroupSeq, 1)),
+		}
+		jsbytes, err := jsoniter.Marshal(grpmsg)
+		cmn.Assert(err == nil, err)
+		p.writeJSON(w, r, jsbytes, "begingroup")
+		return
+	}
+	grpmsg, err := parsePutGroupMsg(msg)
+	if err != nil {
+		p.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	if msg.Action == cmn.ActAbortGroup {
+		if errstr := p.bcastPutGroup(bucket, msg, ""); errstr != "" {
+			p.invalmsghdlr(w, r, errstr)
+		}
+		return
+	}
+	abortmsg := &cmn.ActionMsg{Action: cmn.ActAbortGroup, Value: &cmn.PutGroupMsg{GroupID: grpmsg.GroupID}}
+	// phase 1: prepare
+	if errstr := p.bcastPutGroup(bucket, msg, phasePrepare); errstr != "" {
+		if errabort := p.bcastPutGroup(bucket, abortmsg, ""); errabort != "" {
+			glog.Errorf("Nested error: %s => (abort PUT group %s => err: %s)", errstr, grpmsg.GroupID, errabort)
+		}
+		p.invalmsghdlr(w, r, errstr)
+		return
+	}
+	// phase 2: commit
+	result := mergePutGroupCommit(grpmsg.GroupID, p.bcastPutGroupReq(bucket, msg, ""), nil)
+	if len(result.Errors) == 0 {
+		// phase 3: finalize - the targets' failures to remove the prior versions are not
+		// fatal (the versions are removed with the expired group)
+		if errstr := p.bcastPutGroup(bucket, msg, phaseFinalize); errstr != "" {
+			glog.Errorf("PUT group %s: %s", grpmsg.GroupID, errstr)
+		}
+	} else {
+		// roll back; the objects that fail to roll back remain committed
+		glog.Errorf("PUT group %s: failed to commit, rolling back; errors: %v", grpmsg.GroupID, result.Errors)
+		result = mergePutGroupCommit(grpmsg.GroupID, p.bcastPutGroupReq(bucket, abortmsg, ""), result.Errors)
+		if len(result.Committed) != 0 {
+			glog.Errorf("PUT group %s: partially committed (%d object(s)), errors: %v", grpmsg.GroupID,
+				len(result.Committed), result.Errors)
+		}
+	}
+	jsbytes, err := jsoniter.Marshal(result)
+	cmn.Assert(err == nil, err)
+	p.writeJSON(w, r, jsbytes, "commitgroup")
+}
+
+// mergePutGroupCommit merges the targets' commit (or rollback) results: the committed
+// objects and the errors, in addition to the given ones
+func mergePutGroupCommit(gid string, results chan callResult, errs map[string]string) *cmn.PutGroupCommit {
+	result := &cmn.PutGroupCommit{GroupID: gid, Committed: []string{}, Errors: errs}
+	for res := range results {
+		tresult := &cmn.PutGroupCommit{}
+		if res.err == nil {
+			if err := jsoniter.Unmarshal(res.outjson, tresult); err != nil {
+				res.errstr = fmt.Sprintf("failed to unmarshal the commit result, err: %v", err)
+			}
+		} else if res.errstr == "" {
+			res.errstr = res.err.Error()
+		}
+		result.Committed = append(result.Committed, tresult.Committed...)
+		for _, errstr := range tresult.Errors { // the target's own
+			res.errstr = errstr
+		}
+		if res.errstr != "" {
+			if result.Errors == nil {
+				result.Errors = make(map[string]string, 2)
+			}
+			if prev, ok := result.Errors[res.si.DaemonID]; ok {
+				res.errstr = prev + "; " + res.errstr
+			}
+			result.Errors[res.si.DaemonID] = res.errstr
+		}
+	}
+	sort.Strings(result.Committed)
+	return result
+}
+
+// bcastPutGroupReq broadcasts the group action; phase is phasePrepare, phaseFinalize, or ""
+func (p *proxyrunner) bcastPutGroupReq(bucket string, msg *cmn.ActionMsg, phase string) chan callResult {
+	jsbytes, err := jsoniter.Marshal(msg)
+	cmn.Assert(err == nil, err)
+	query := url.Values{}
+	switch phase {
+	case phasePrepare:
+		query.Set(cmn.URLParamPrepare, "true")
+	case phaseFinalize:
+		query.Set(cmn.URLParamFinalize, "true")
+	}
+	return p.broadcastTargets(
+		cmn.URLPath(cmn.Version, cmn.Buckets, bucket),
+		query,
+		http.MethodPost,
+		jsbytes,
+		p.smapowner.get(),
+		ctx.config.Timeout.DefaultLong,
+	)
+}
+
+func (p *proxyrunner) bcastPutGroup(bucket string, msg *cmn.ActionMsg, phase string) (errstr string) {
+	errs := make([]string, 0)
+	for res := range p.bcastPutGroupReq(bucket, msg, phase) {
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", res.si.DaemonID, res.errstr))
+		}
+	}
+	if len(errs) != 0 {
+		action := msg.Action
+		if phase != "" {
+			action += " (" + phase + ")"
+		}
+		errstr = fmt.Sprintf("%s failed: %s", action, strings.Join(errs, "; "))
+	}
+	return
+}
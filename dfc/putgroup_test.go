@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func TestPutGroupFQN(t *testing.T) {
+	q := fs.Mountpaths
+	defer func() { fs.Mountpaths = q }()
+	fs.Mountpaths = fs.NewMountedFS("local", "cloud")
+	fs.Mountpaths.DisableFsIDCheck()
+	mpath, err := ioutil.TempDir("", "putgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mpath)
+	if err := fs.Mountpaths.Add(mpath); err != nil {
+		t.Fatal(err)
+	}
+	fqn := filepath.Join(fs.Mountpaths.MakePathLocal(mpath), "bck", "dir/obj")
+
+	for _, gid := range []string{"", ".", "..", "../x", "a/b", `a\b`} {
+		if _, err := putGroupFQN(gid, "dir/obj", fqn); err == nil {
+			t.Errorf("%q: expected invalid group ID", gid)
+		}
+	}
+	putfqn, err := putGroupFQN("g1", "dir/obj", fqn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(mpath, putGroupsDirName, "g1", "dir")
+	if filepath.Dir(putfqn) != dir || !strings.Contains(filepath.Base(putfqn), "obj") {
+		t.Errorf("expected %s staged in %s", putfqn, dir)
+	}
+	if again, _ := putGroupFQN("g1", "dir/obj", fqn); again == putfqn {
+		t.Errorf("expected unique staging workfiles, got %s twice", putfqn)
+	}
+
+	// abort, commit, and expiration remove the group's directory; restart - all groups
+	for _, gid := range []string{"g1", "g2"} {
+		if err := os.MkdirAll(filepath.Join(mpath, putGroupsDirName, gid, "dir"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	(&putGroup{id: "g1"}).cleanup()
+	if _, err := os.Stat(filepath.Join(mpath, putGroupsDirName, "g1")); !os.IsNotExist(err) {
+		t.Errorf("expected the directory of g1 removed, err: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(mpath, putGroupsDirName, "g2")); err != nil {
+		t.Errorf("expected the directory of g2 to stay, err: %v", err)
+	}
+	removeStalePutGroups()
+	if _, err := os.Stat(filepath.Join(mpath, putGroupsDirName)); !os.IsNotExist(err) {
+		t.Errorf("expected stale PUT groups removed, err: %v", err)
+	}
+}
+
+func TestMergePutGroupCommit(t *testing.T) {
+	results := make(chan callResult, 3)
+	results <- callResult{si: &cluster.Snode{DaemonID: "t1"},
+		outjson: []byte(`{"group": "g", "committed": ["c", "a"]}`)}
+	results <- callResult{si: &cluster.Snode{DaemonID: "t2"},
+		outjson: []byte(`{"group": "g", "committed": ["b"], "errors": {"t2": "failed to commit 1 object(s)"}}`)}
+	results <- callResult{si: &cluster.Snode{DaemonID: "t3"}, err: errors.New("connection refused")}
+	close(results)
+
+	result := mergePutGroupCommit("g", results, nil)
+	if !reflect.DeepEqual(result.Committed, []string{"a", "b", "c"}) {
+		t.Errorf("expected a, b, c committed, got %v", result.Committed)
+	}
+	expected := map[string]string{"t2": "failed to commit 1 object(s)", "t3": "connection refused"}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("expected errors %v, got %v", expected, result.Errors)
+	}
+	if !strings.Contains(result.Error(), "3 object(s) remain committed") {
+		t.Errorf("unexpected error: %s", result.Error())
+	}
+
+	results = make(chan callResult, 1)
+	results <- callResult{si: &cluster.Snode{DaemonID: "t1"}, outjson: []byte(`{"group": "g", "committed": []}`)}
+	close(results)
+	if result = mergePutGroupCommit("g", results, nil); len(result.Errors) != 0 || len(result.Committed) != 0 {
+		t.Errorf("expected nothing committed and no errors, got %+v", result)
+	}
+
+	// rollback: the commit errors are kept, the objects that failed to roll back remain committed
+	results = make(chan callResult, 2)
+	results <- callResult{si: &cluster.Snode{DaemonID: "t1"}, outjson: []byte(`{"group": "g", "committed": []}`)}
+	results <- callResult{si: &cluster.Snode{DaemonID: "t2"},
+		outjson: []byte(`{"group": "g", "committed": ["b"], "errors": {"t2": "failed to roll back 1 object(s)"}}`)}
+	close(results)
+	result = mergePutGroupCommit("g", results, map[string]string{"t2": "failed to commit 1 object(s)"})
+	expected = map[string]string{"t2": "failed to commit 1 object(s); failed to roll back 1 object(s)"}
+	if !reflect.DeepEqual(result.Committed, []string{"b"}) || !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("expected b committed with errors %v, got %+v", expected, result)
+	}
+}
+
+func TestPutGroupKeepPrev(t *testing.T) {
+	dir, err := ioutil.TempDir("", "putgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	staged := &stagedObj{putfqn: filepath.Join(dir, "staged"), fqn: filepath.Join(dir, "obj")}
+	if errstr := staged.keepPrev(); errstr != "" || staged.prev != "" {
+		t.Fatalf("expected no prior version of a new object, got %q (%s)", staged.prev, errstr)
+	}
+	if err := ioutil.WriteFile(staged.fqn, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if errstr := staged.keepPrev(); errstr != "" {
+		t.Fatal(errstr)
+	}
+	if err := ioutil.WriteFile(staged.putfqn, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(staged.putfqn, staged.fqn); err != nil { // commit
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(staged.prev); err != nil || string(b) != "v1" {
+		t.Errorf("expected the prior version kept, got %q, err: %v", b, err)
+	}
+}
+
+func TestPutGroupsFinish(t *testing.T) {
+	pg := newPutGroups()
+	pg.m["g1"] = &putGroup{id: "g1", started: time.Now()}
+	if _, ok := pg.finish("g1"); !ok {
+		t.Fatal("expected g1 finished")
+	}
+	if _, ok := pg.m["g1"]; ok {
+		t.Error("expected g1 removed")
+	}
+	if _, ok := pg.done["g1"]; !ok {
+		t.Error("expected g1 remembered as finished")
+	}
+	pg.done["g1"] = time.Now().Add(-putGroupTimeout - time.Minute)
+	pg.purgeExpired()
+	if _, ok := pg.done["g1"]; ok {
+		t.Error("expected g1 forgotten once expired")
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// PUT durability: by default a PUT is acknowledged once the object is written to the page
+// cache. Bucket property sync_policy makes the target flush the newly committed object
+// before acknowledging:
+//   - "fdatasync" - the object's data;
+//   - "fsync"     - the object's data and metadata, and the directory that contains it;
+//   - "group"     - the mountpath's entire filesystem (syncfs), once per batch of PUTs:
+//                   the first PUT waits disk_config.sync_group_time for others to join,
+//                   and a single syncfs then acknowledges all of them.
+// The time spent syncing is reported as put.sync.lat.
+//
+// ================================ Summary ===============================================
+
+type (
+	// groupSyncer batches syncfs calls by mountpath
+	groupSyncer struct {
+		sync.Mutex
+		batches map[string]*syncBatch // batch that is being collected, by mountpath
+		syncfs  func(mpath string) error
+	}
+	syncBatch struct {
+		done chan struct{}
+		err  error
+	}
+)
+
+func newGroupSyncer() *groupSyncer {
+	return &groupSyncer{batches: make(map[string]*syncBatch), syncfs: fs.Syncfs}
+}
+
+// sync returns after the mountpath's filesystem is synced by a syncfs that started after
+// the call; the first caller for a given mountpath waits for others and then syncs for all
+func (g *groupSyncer) sync(mpath string, wait time.Duration) error {
+	g.Lock()
+	if batch, ok := g.batches[mpath]; ok {
+		g.Unlock()
+		<-batch.done
+		return batch.err
+	}
+	batch := &syncBatch{done: make(chan struct{})}
+	g.batches[mpath] = batch
+	g.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	// whoever comes next joins the next batch
+	g.Lock()
+	delete(g.batches, mpath)
+	g.Unlock()
+
+	batch.err = g.syncfs(mpath)
+	close(batch.done)
+	return batch.err
+}
+
+// syncPut flushes the committed object according to its bucket's sync policy
+func (t *targetrunner) syncPut(bucket, fqn string) (errstr string) {
+	var (
+		err     error
+		policy  = cmn.SyncPolicyNone
+		started = time.Now()
+	)
+	if props, _, defined := t.bmdowner.get().propsAndChecksum(bucket); defined && props.SyncPolicy != "" {
+		policy = props.SyncPolicy
+	}
+	switch policy {
+	case cmn.SyncPolicyNone:
+		return
+	case cmn.SyncPolicyData, cmn.SyncPolicyFull:
+		err = syncFile(fqn, policy == cmn.SyncPolicyData)
+		if err == nil && policy == cmn.SyncPolicyFull {
+			err = syncFile(filepath.Dir(fqn), false)
+		}
+	case cmn.SyncPolicyGroup:
+		parsedFQN, errFQN := fs.Mountpaths.FQN2Info(fqn)
+		if errFQN != nil {
+			return errFQN.Error()
+		}
+		err = t.groupSyncer.sync(parsedFQN.MpathInfo.Path, ctx.config.Disk.SyncGroupTime)
+	default:
+		return fmt.Sprintf("Invalid sync policy %q of bucket %s", policy, bucket)
+	}
+	if err != nil {
+		t.fshc(err, fqn)
+		return fmt.Sprintf("Failed to %s %s, err: %v", policy, fqn, err)
+	}
+	t.statsif.Add(stats.PutSyncLatency, int64(time.Since(started)))
+	return
+}
+
+func syncFile(path string, dataOnly bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if dataOnly {
+		err = fs.Fdatasync(file)
+	} else {
+		err = file.Sync()
+	}
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
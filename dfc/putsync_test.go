@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupSyncer(t *testing.T) {
+	var (
+		g     = newGroupSyncer()
+		calls = map[string]*int64{"/mp1": new(int64), "/mp2": new(int64)}
+		wg    = &sync.WaitGroup{}
+	)
+	g.syncfs = func(mpath string) error {
+		atomic.AddInt64(calls[mpath], 1)
+		return nil
+	}
+	// the first caller waits long enough for the rest of its mountpath's batch to join
+	for i := 0; i < 20; i++ {
+		mpath := "/mp1"
+		if i%2 == 1 {
+			mpath = "/mp2"
+		}
+		wg.Add(1)
+		go func(mpath string) {
+			defer wg.Done()
+			if err := g.sync(mpath, 200*time.Millisecond); err != nil {
+				t.Error(err)
+			}
+		}(mpath)
+	}
+	wg.Wait()
+	for mpath, n := range calls {
+		if *n != 1 {
+			t.Errorf("%s: expected a single syncfs for the batch, got %d", mpath, *n)
+		}
+	}
+
+	// no batch in progress: each call syncs
+	for i := 0; i < 3; i++ {
+		if err := g.sync("/mp1", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *calls["/mp1"] != 4 {
+		t.Errorf("expected 4 syncfs, got %d", *calls["/mp1"])
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ================================ Summary ===============================================
+//
+// PUT content verification: a client can supply the expected checksum of the object's
+// content with the PUT - DfcChecksumType (xxhash, crc32c, md5, or sha256) and DfcChecksumVal
+// (hex). The target computes the checksum while receiving the object - the bucket's own
+// checksum is reused when the types match - and, on mismatch, fails the PUT with 422
+// (Unprocessable Entity) before the object gets committed, so that the existing object,
+// if any, is left intact. The rejected PUTs are counted by the err.put.cksum.n statistics.
+//
+// ================================ Summary ===============================================
+
+// putVerifier computes the client-supplied checksum of the PUT content as it is received;
+// h is nil when the checksum is the one the target computes anyway (the bucket's)
+type putVerifier struct {
+	kind, val string
+	h         hash.Hash
+}
+
+// newPutVerifier returns nil if the client has not supplied the checksum
+func newPutVerifier(kind, val, bucketCksum string) (*putVerifier, string) {
+	if kind == "" || kind == cmn.ChecksumNone {
+		return nil, ""
+	}
+	var (
+		h    hash.Hash
+		size int // bytes
+	)
+	switch kind {
+	case cmn.ChecksumXXHash, cmn.ChecksumCRC32C:
+		h = cmn.NewCksumHash(kind)
+		size = h.Size()
+	case cmn.ChecksumMD5:
+		h, size = md5.New(), md5.Size
+	case cmn.ChecksumSHA256:
+		h, size = sha256.New(), sha256.Size
+	default:
+		return nil, fmt.Sprintf("Unsupported checksum type %q (expecting %s, %s, %s, or %s)",
+			kind, cmn.ChecksumXXHash, cmn.ChecksumCRC32C, cmn.ChecksumMD5, cmn.ChecksumSHA256)
+	}
+	if b, err := hex.DecodeString(val); err != nil || len(b) != size {
+		return nil, fmt.Sprintf("Invalid %s checksum value %q", kind, val)
+	}
+	v := &putVerifier{kind: kind, val: strings.ToLower(val)}
+	if kind != bucketCksum {
+		v.h = h
+	}
+	return v, ""
+}
+
+// verify compares the client-supplied checksum with the one computed while receiving -
+// nhobj when it is the bucket's; returns the latter
+func (v *putVerifier) verify(nhobj cksumvalue) (computed string, ok bool) {
+	if v.h != nil {
+		computed = hex.EncodeToString(v.h.Sum(nil))
+	} else if nhobj != nil {
+		_, computed = nhobj.get()
+	}
+	return computed, computed == v.val
+}
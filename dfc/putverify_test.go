@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestPutVerifier(t *testing.T) {
+	const content = "the quick brown fox"
+	sum := sha256.Sum256([]byte(content))
+	sha := hex.EncodeToString(sum[:])
+
+	if v, errstr := newPutVerifier("", "", cmn.ChecksumXXHash); v != nil || errstr != "" {
+		t.Fatalf("expected no verifier without the checksum, got %+v, %q", v, errstr)
+	}
+	for _, tc := range []struct{ kind, val string }{
+		{"sha1", sha}, {cmn.ChecksumSHA256, "xyz"}, {cmn.ChecksumSHA256, sha[:32]}, {cmn.ChecksumMD5, sha},
+	} {
+		if _, errstr := newPutVerifier(tc.kind, tc.val, cmn.ChecksumXXHash); errstr == "" {
+			t.Errorf("expected %s %q rejected", tc.kind, tc.val)
+		}
+	}
+
+	// computed while receiving, uppercase hex accepted
+	v, errstr := newPutVerifier(cmn.ChecksumSHA256, strings.ToUpper(sha), cmn.ChecksumXXHash)
+	if errstr != "" || v.h == nil {
+		t.Fatalf("expected the sha256 computed while receiving, got %+v, %q", v, errstr)
+	}
+	io.Copy(ioutil.Discard, io.TeeReader(strings.NewReader(content), v.h))
+	if computed, ok := v.verify(nil); !ok || computed != sha {
+		t.Errorf("expected %s verified, computed %s", sha, computed)
+	}
+	v, _ = newPutVerifier(cmn.ChecksumSHA256, sha, cmn.ChecksumXXHash)
+	io.Copy(ioutil.Discard, io.TeeReader(strings.NewReader(content+"!"), v.h))
+	if _, ok := v.verify(nil); ok {
+		t.Error("expected the modified content to fail verification")
+	}
+
+	// the bucket's checksum is reused
+	xx := "0123456789abcdef"
+	v, _ = newPutVerifier(cmn.ChecksumXXHash, xx, cmn.ChecksumXXHash)
+	if v.h != nil {
+		t.Fatal("expected the bucket's xxhash reused")
+	}
+	if _, ok := v.verify(newcksumvalue(cmn.ChecksumXXHash, xx)); !ok {
+		t.Error("expected the bucket's xxhash verified")
+	}
+	if _, ok := v.verify(newcksumvalue(cmn.ChecksumXXHash, "fedcba9876543210")); ok {
+		t.Error("expected the xxhash mismatch detected")
+	}
+	// crc32c: 4 bytes, computed unless the bucket's
+	if _, errstr := newPutVerifier(cmn.ChecksumCRC32C, xx, cmn.ChecksumXXHash); errstr == "" {
+		t.Error("expected 8-byte crc32c rejected")
+	}
+	crc, _ := cmn.ComputeCksum(cmn.ChecksumCRC32C, strings.NewReader(content), nil)
+	v, _ = newPutVerifier(cmn.ChecksumCRC32C, crc, cmn.ChecksumXXHash)
+	io.Copy(ioutil.Discard, io.TeeReader(strings.NewReader(content), v.h))
+	if computed, ok := v.verify(nil); !ok {
+		t.Errorf("expected crc32c %s verified, computed %s", crc, computed)
+	}
+	if v, _ = newPutVerifier(cmn.ChecksumCRC32C, crc, cmn.ChecksumCRC32C); v.h != nil {
+		t.Error("expected the bucket's crc32c reused")
+	}
+}
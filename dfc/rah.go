@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
@@ -27,7 +28,7 @@ import (
 const (
 	rahChanSize    = 256
 	rahMapInitSize = 256
-	rahGCTime      = time.Minute // cleanup and free periodically
+	rahGCTime      = time.Minute // cleanup and free periodically; pre-warm the hottest objects (rahhotobjects)
 )
 
 type (
@@ -54,6 +55,7 @@ type (
 		stopCh  chan struct{}         // to stop
 		slab    *memsys.Slab2         // to read files
 		buf     []byte                // ditto
+		warming int32                 // prewarm in progress (atomic)
 	}
 	rahfcache struct {
 		sync.Mutex
@@ -242,6 +244,9 @@ func (rj *rahjogger) jog() error {
 				}
 			}
 		case <-ticker.C: // cleanup and free
+			if n := ctx.config.Readahead.HotObjects; n > 0 && atomic.CompareAndSwapInt32(&rj.warming, 0, 1) {
+				go rj.prewarm(n)
+			}
 			rj.Lock()
 			for fqn, rahfcache := range rj.rahmap {
 				rj.Unlock()
@@ -263,6 +268,31 @@ func (rj *rahjogger) jog() error {
 	}
 }
 
+// prewarm advises the kernel to read the mountpath's n hottest objects - as per the atime
+// heat map - into the page cache, unless the mountpath's disks are busy. Runs asynchronously
+// so that the atime runner does not hold up the readahead requests
+func (rj *rahjogger) prewarm(n int) {
+	defer atomic.StoreInt32(&rj.warming, 0)
+	if mpathInfo, _ := fs.Mountpaths.Path2MpathInfo(rj.mpath); mpathInfo != nil {
+		if util, ok := getiostatrunner().MaxUtilFS(mpathInfo.FileSystem); ok && int64(util) > ctx.config.Xaction.DiskUtilHighWM {
+			return
+		}
+	}
+	var warmed int
+	for _, obj := range getatimerunner().HotObjects(rj.mpath, n) {
+		if err := fs.WillNeed(obj.FQN); err != nil {
+			if !os.IsNotExist(err) {
+				glog.Warningf("Failed to pre-warm %s, err: %v", obj.FQN, err)
+			}
+			continue
+		}
+		warmed++
+	}
+	if glog.V(4) {
+		glog.Infof("readahead-mpath(%s): pre-warmed %d hottest object(s)", rj.mpath, warmed)
+	}
+}
+
 // actual readahead
 func (rahfcache *rahfcache) readahead(buf []byte) {
 	var (
@@ -11,8 +11,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -24,7 +22,10 @@ import (
 	"github.com/json-iterator/go"
 )
 
-const NeighborRebalanceStartDelay = 10 * time.Second
+const (
+	NeighborRebalanceStartDelay = 10 * time.Second
+	rebDoneCheckInterval        = 3 * NeighborRebalanceStartDelay // see waitRebalancingDone
+)
 
 var (
 	runRebalanceOnce      = &sync.Once{}
@@ -33,69 +34,79 @@ var (
 
 type xrebpathrunner struct {
 	t         *targetrunner
-	mpathplus string
+	mpathInfo *fs.MountpathInfo
+	scope     int    // fs.WalkLocal or fs.WalkCloud
+	mpathplus string // mountpath/{local,cloud}
 	xreb      *xactRebalance
 	wg        *sync.WaitGroup
 	newsmap   *smapX
 	aborted   bool
 	fileMoved int64
 	byteMoved int64
+	order     string       // rebalance_conf.order
+	pending   []*rebObject // objects to send in order, unless RebOrderNone (at most rebPendingMax)
 }
 
 type localRebPathRunner struct {
 	t         *targetrunner
-	mpath     string
+	mpathInfo *fs.MountpathInfo
+	scope     int    // fs.WalkLocal or fs.WalkCloud
+	mpath     string // mountpath/{local,cloud}
 	xreb      *xactLocalRebalance
 	aborted   bool
 	fileMoved int64
 	byteMoved int64
 }
 
+// skipNonMovable skips workfiles and other content that is not to be moved between
+// targets or mountpaths
+func skipNonMovable(fqn string) bool {
+	spec, _ := cluster.FileSpec(fqn)
+	return spec != nil && !spec.PermToMove()
+}
+
+// rebWalkOpts returns the options to walk the objects of a given mountpath and scope
+func rebWalkOpts(mpathInfo *fs.MountpathInfo, scope int, abort <-chan struct{}) *fs.WalkOpts {
+	return &fs.WalkOpts{
+		Mpaths:    []*fs.MountpathInfo{mpathInfo},
+		Scope:     scope,
+		Skip:      skipNonMovable,
+		Throttler: qosThrottler,
+		Abort:     abort,
+	}
+}
+
+// qosThrottler throttles the background work on the mountpath while its foreground
+// GETs and PUTs are over budget (see ios/qos); rebalance and prefetch
+func qosThrottler(mpathInfo *fs.MountpathInfo) fs.Throttler {
+	return &cluster.Throttle{
+		QoS:  getiostatrunner(),
+		Path: mpathInfo.Path,
+		FS:   mpathInfo.FileSystem,
+		Flag: cluster.OnQoS,
+	}
+}
+
 func (rcl *xrebpathrunner) oneRebalance() {
-	if err := filepath.Walk(rcl.mpathplus, rcl.rebwalkf); err != nil {
-		s := err.Error()
-		if strings.Contains(s, "xaction") {
-			glog.Infof("Stopping %s traversal due to: %s", rcl.mpathplus, s)
+	opts := rebWalkOpts(rcl.mpathInfo, rcl.scope, rcl.xreb.ChanAbort())
+	if err := fs.Mountpaths.WalkObjects(opts, rcl.rebwalkf); err != nil {
+		if err == fs.ErrWalkAborted {
+			glog.Infof("Stopping %s traversal: %s aborted", rcl.mpathplus, rcl.xreb)
+			rcl.aborted = true
 		} else {
 			glog.Errorf("Failed to traverse %s, err: %v", rcl.mpathplus, err)
 		}
 	}
+	if !rcl.aborted && len(rcl.pending) > 0 {
+		rcl.sendPending()
+	}
 	rcl.xreb.confirmCh <- struct{}{}
 	rcl.wg.Done()
 }
 
 // the walking callback is executed by the LRU xaction
-func (rcl *xrebpathrunner) rebwalkf(fqn string, osfi os.FileInfo, err error) error {
-	// Check if we should abort
-	select {
-	case <-rcl.xreb.ChanAbort():
-		err = fmt.Errorf("%s: aborted for path %s", rcl.xreb, rcl.mpathplus)
-		glog.Infoln(err)
-		glog.Flush()
-		rcl.aborted = true
-		return err
-	default:
-		break
-	}
-
-	// Skip files which are not movable (for example workfiles)
-	if spec, _ := cluster.FileSpec(fqn); spec != nil && !spec.PermToMove() {
-		return nil
-	}
-	if err != nil {
-		// If we are traversing non-existing file we should not care
-		if os.IsNotExist(err) {
-			glog.Warningf("%s does not exist", fqn)
-			return nil
-		}
-		// Otherwise we care
-		glog.Errorf("invoked with err: %v", err)
-		return err
-	}
-	// Skip dirs
-	if osfi.Mode().IsDir() {
-		return nil
-	}
+func (rcl *xrebpathrunner) rebwalkf(entry *fs.WalkEntry) error {
+	fqn, osfi := entry.FQN, entry.FileInfo
 	// rebalance maybe
 	bucket, objname, err := cluster.ResolveFQN(fqn, rcl.t.bmdowner)
 	if err != nil {
@@ -115,28 +126,70 @@ func (rcl *xrebpathrunner) rebwalkf(fqn string, osfi os.FileInfo, err error) err
 	if si.DaemonID == rcl.t.si.DaemonID {
 		return nil
 	}
+	obj := &rebObject{bucket: bucket, objname: objname, fqn: fqn, si: si, size: osfi.Size()}
+	rcl.xreb.remaining.add(obj.size, 1)
+	if rcl.order == cmn.RebOrderNone {
+		rcl.send(obj)
+		rcl.xreb.remaining.add(obj.size, -1)
+		return nil
+	}
+	rcl.pending = append(rcl.pending, obj)
+	if len(rcl.pending) >= rebPendingMax {
+		rcl.sendPending()
+		if rcl.aborted {
+			return fs.ErrWalkAborted
+		}
+	}
+	return nil
+}
+
+// sendPending sends the objects collected by the traversal in the configured order
+func (rcl *xrebpathrunner) sendPending() {
+	objs := orderRebObjects(rcl.pending, rcl.order)
+	rcl.pending = rcl.pending[:0]
+	for i, obj := range objs {
+		select {
+		case <-rcl.xreb.ChanAbort():
+			glog.Infof("Stopping %s rebalance: %s aborted", rcl.mpathplus, rcl.xreb)
+			rcl.aborted = true
+			for _, obj := range objs[i:] {
+				rcl.xreb.remaining.add(obj.size, -1)
+			}
+			return
+		default:
+		}
+		rcl.send(obj)
+		rcl.xreb.remaining.add(obj.size, -1)
+	}
+}
 
-	// do rebalance
+func (rcl *xrebpathrunner) send(obj *rebObject) {
+	var errstr string
 	if glog.V(4) {
-		glog.Infof("%s/%s %s => %s", bucket, objname, rcl.t.si.DaemonID, si.DaemonID)
+		glog.Infof("%s/%s %s => %s", obj.bucket, obj.objname, rcl.t.si.DaemonID, obj.si.DaemonID)
 	}
-	if errstr = rcl.t.sendfile(http.MethodPut, bucket, objname, si, osfi.Size(), "", ""); errstr != "" {
-		glog.Infof("Failed to rebalance %s/%s: %s", bucket, objname, errstr)
+	if injectFault(cmn.FaultPointRebalance, obj.bucket, obj.objname) == cmn.FaultEIO {
+		errstr = fmt.Sprintf("Failed to read %s, err: injected EIO", obj.fqn)
+	} else {
+		errstr = rcl.t.sendfile(http.MethodPut, obj.bucket, obj.objname, obj.si, obj.size, "", "")
+	}
+	if errstr != "" {
+		glog.Infof("Failed to rebalance %s/%s: %s", obj.bucket, obj.objname, errstr)
 	} else {
 		// LRU cleans up the file later
 		rcl.fileMoved++
-		rcl.byteMoved += osfi.Size()
+		rcl.byteMoved += obj.size
 	}
-	return nil
 }
 
 // LOCAL REBALANCE
 
 func (rb *localRebPathRunner) run() {
-	if err := filepath.Walk(rb.mpath, rb.walk); err != nil {
-		s := err.Error()
-		if strings.Contains(s, "xaction") {
-			glog.Infof("Stopping %s traversal due to: %s", rb.mpath, s)
+	opts := rebWalkOpts(rb.mpathInfo, rb.scope, rb.xreb.ChanAbort())
+	if err := fs.Mountpaths.WalkObjects(opts, rb.walk); err != nil {
+		if err == fs.ErrWalkAborted {
+			glog.Infof("Stopping %s traversal: %s aborted", rb.mpath, rb.xreb)
+			rb.aborted = true
 		} else {
 			glog.Errorf("Failed to traverse %s, err: %v", rb.mpath, err)
 		}
@@ -145,38 +198,8 @@ func (rb *localRebPathRunner) run() {
 	rb.xreb.confirmCh <- struct{}{}
 }
 
-func (rb *localRebPathRunner) walk(fqn string, fileInfo os.FileInfo, err error) error {
-	// Check if we should abort
-	select {
-	case <-rb.xreb.ChanAbort():
-		err = fmt.Errorf("%s aborted, exiting rebwalkf path %s", rb.xreb, rb.mpath)
-		glog.Infoln(err)
-		glog.Flush()
-		rb.aborted = true
-		return err
-	default:
-		break
-	}
-
-	// Skip files which are not movable (for example workfiles)
-	if spec, _ := cluster.FileSpec(fqn); spec != nil && !spec.PermToMove() {
-		return nil
-	}
-	if err != nil {
-		// If we are traversing non-existing file we should not care
-		if os.IsNotExist(err) {
-			glog.Warningf("%s does not exist", fqn)
-			return nil
-		}
-		// Otherwise we care
-		glog.Errorf("invoked with err: %v", err)
-		return err
-	}
-	// Skip dirs
-	if fileInfo.IsDir() {
-		return nil
-	}
-
+func (rb *localRebPathRunner) walk(entry *fs.WalkEntry) error {
+	fqn, fileInfo := entry.FQN, entry.FileInfo
 	// Check if we need to move files around
 	changed, newFQN, err := rb.t.changedMountpath(fqn)
 	if err != nil {
@@ -190,24 +213,28 @@ func (rb *localRebPathRunner) walk(fqn string, fileInfo os.FileInfo, err error)
 	if glog.V(4) {
 		glog.Infof("%s => %s", fqn, newFQN)
 	}
-	dir := filepath.Dir(newFQN)
-	if err := cmn.CreateDir(dir); err != nil {
-		glog.Errorf("Failed to create dir: %s", dir)
-		rb.xreb.abort()
-		rb.t.fshc(err, newFQN)
-		return nil
-	}
 
 	// Copy the file instead of moving, LRU takes care of obsolete copies
 	if glog.V(4) {
 		glog.Infof("Copying %s -> %s", fqn, newFQN)
 	}
-	if errFQN, err := copyFile(fqn, newFQN); err != nil {
+	errFQN := newFQN
+	if err := rb.t.inObjDir(newFQN, func() (err error) { errFQN, err = copyFile(fqn, newFQN); return }); err != nil {
 		glog.Error(err.Error())
 		rb.t.fshc(err, errFQN)
 		rb.xreb.abort()
 		return nil
 	}
+	// the copy of a compressed object is compressed as well, and expires at the same time
+	for _, name := range []string{cmn.XattrCompressed, cmn.XattrExpires} {
+		if b, errstr := Getxattr(fqn, name); errstr == "" && len(b) > 0 {
+			if errstr = Setxattr(newFQN, name, b); errstr != "" {
+				glog.Error(errstr)
+				rb.xreb.abort()
+				return nil
+			}
+		}
+	}
 	rb.fileMoved++
 	rb.byteMoved += fileInfo.Size()
 
@@ -258,10 +285,10 @@ func (t *targetrunner) pingTarget(si *cluster.Snode, timeout time.Duration, dead
 	return ok
 }
 
-// waitForRebalanceFinish waits for the other target to complete the current
-// rebalancing operation.
-func (t *targetrunner) waitForRebalanceFinish(si *cluster.Snode, rebalanceVersion int64) {
-	// Phase 1: Call and check if smap is at least our version.
+// rebalanceDone checks whether the other target is done with the rebalance of the given
+// Smap version, i.e., has the version (or a newer one) and is not rebalancing. A target
+// that cannot be reached is assumed to be down/unavailable - and done
+func (t *targetrunner) rebalanceDone(si *cluster.Snode, rebalanceVersion int64) bool {
 	query := url.Values{}
 	query.Add(cmn.URLParamWhat, cmn.GetWhatSmap)
 	args := callArgs{
@@ -273,38 +300,25 @@ func (t *targetrunner) waitForRebalanceFinish(si *cluster.Snode, rebalanceVersio
 		},
 		timeout: defaultTimeout,
 	}
-
-	for {
-		res := t.call(args)
-		// retry once
-		if res.err == context.DeadlineExceeded {
-			args.timeout = ctx.config.Timeout.CplaneOperation * keepaliveTimeoutFactor * 2
-			res = t.call(args)
-		}
-
-		if res.err != nil {
-			glog.Errorf("Failed to call %s, err: %v - assuming down/unavailable", si.PublicNet.DirectURL, res.err)
-			return
-		}
-
-		tsmap := &smapX{}
-		err := jsoniter.Unmarshal(res.outjson, tsmap)
-		if err != nil {
-			glog.Errorf("Unexpected: failed to unmarshal response, err: %v [%v]", err, string(res.outjson))
-			return
-		}
-
-		if tsmap.version() >= rebalanceVersion {
-			break
-		}
-
-		time.Sleep(ctx.config.Timeout.CplaneOperation * keepaliveRetryFactor * 2)
+	res := t.call(args)
+	// retry once
+	if res.err == context.DeadlineExceeded {
+		args.timeout = ctx.config.Timeout.CplaneOperation * keepaliveTimeoutFactor * 2
+		res = t.call(args)
+	}
+	if res.err != nil {
+		glog.Errorf("Failed to call %s, err: %v - assuming down/unavailable", si.PublicNet.DirectURL, res.err)
+		return true
+	}
+	tsmap := &smapX{}
+	if err := jsoniter.Unmarshal(res.outjson, tsmap); err != nil {
+		glog.Errorf("Unexpected: failed to unmarshal response, err: %v [%v]", err, string(res.outjson))
+		return true
+	}
+	if tsmap.version() < rebalanceVersion {
+		return false
 	}
 
-	// Phase 2: Wait to ensure any rebalancing on neighbor has kicked in.
-	time.Sleep(NeighborRebalanceStartDelay)
-
-	// Phase 3: Call thy neighbor to check whether it is rebalancing and wait until it is not.
 	args = callArgs{
 		si: si,
 		req: reqArgs{
@@ -314,27 +328,56 @@ func (t *targetrunner) waitForRebalanceFinish(si *cluster.Snode, rebalanceVersio
 		},
 		timeout: defaultTimeout,
 	}
+	res = t.call(args)
+	if res.err != nil {
+		glog.Errorf("Failed to call %s, err: %v - assuming down/unavailable", si.PublicNet.DirectURL, res.err)
+		return true
+	}
+	status := &thealthstatus{}
+	if err := jsoniter.Unmarshal(res.outjson, status); err != nil {
+		glog.Errorf("Unexpected: failed to unmarshal %s response, err: %v [%v]", si.PublicNet.DirectURL, err, string(res.outjson))
+		return true
+	}
+	if status.IsRebalancing {
+		glog.Infof("waiting for rebalance: %v", si.PublicNet.DirectURL)
+	}
+	return !status.IsRebalancing
+}
 
-	for {
-		res := t.call(args)
-		if res.err != nil {
-			glog.Errorf("Failed to call %s, err: %v - assuming down/unavailable", si.PublicNet.DirectURL, res.err)
-			break
-		}
-
-		status := &thealthstatus{}
-		err := jsoniter.Unmarshal(res.outjson, status)
-		if err != nil {
-			glog.Errorf("Unexpected: failed to unmarshal %s response, err: %v [%v]", si.PublicNet.DirectURL, err, string(res.outjson))
-			break
+// waitRebalancingDone waits for all other targets to finish the rebalance of newSmap:
+// each announces it on the message bus (busTopicRebalanceDone). The targets that have
+// not - e.g., restarted or running an older version - are checked directly every
+// rebDoneCheckInterval
+func (t *targetrunner) waitRebalancingDone(newSmap *smapX) {
+	sub := t.msgbus.subscribe(busTopicRebalanceDone, len(newSmap.Tmap))
+	defer t.msgbus.unsubscribe(sub)
+
+	pending := make(map[string]*cluster.Snode, len(newSmap.Tmap))
+	for id, si := range newSmap.Tmap {
+		if id != t.si.DaemonID {
+			pending[id] = si
 		}
-
-		if !status.IsRebalancing {
-			break
+	}
+	ticker := time.NewTicker(rebDoneCheckInterval)
+	defer ticker.Stop()
+	for len(pending) > 0 {
+		select {
+		case msg := <-sub.C:
+			done := &rebDoneMsg{}
+			if err := jsoniter.Unmarshal(msg.Payload, done); err != nil {
+				glog.Errorf("Unexpected: failed to unmarshal %s from %s, err: %v", msg.Topic, msg.From, err)
+				continue
+			}
+			if done.Version >= newSmap.version() {
+				delete(pending, msg.From)
+			}
+		case <-ticker.C:
+			for id, si := range pending {
+				if t.rebalanceDone(si, newSmap.version()) {
+					delete(pending, id)
+				}
+			}
 		}
-
-		glog.Infof("waiting for rebalance: %v", si.PublicNet.DirectURL)
-		time.Sleep(ctx.config.Timeout.CplaneOperation * keepaliveRetryFactor * 2)
 	}
 }
 
@@ -345,6 +388,7 @@ func (t *targetrunner) runRebalance(newsmap *smapX, newtargetid string) {
 	// first, check whether all the Smap-ed targets are up and running
 	//
 	glog.Infof("rebalance: Smap ver %d, newtargetid=%s", newsmap.version(), newtargetid)
+	t.fdCache.purge() // the objects are about to move
 
 	wg := &sync.WaitGroup{}
 	wg.Add(neighborCnt)
@@ -395,29 +439,36 @@ func (t *targetrunner) runRebalance(newsmap *smapX, newtargetid string) {
 	}
 
 	glog.Infoln(xreb.String())
+	started := time.Now()
 	wg = &sync.WaitGroup{}
 
 	allr := make([]*xrebpathrunner, 0, runnerCnt)
+	order := ctx.config.Rebalance.Order
 	for _, mpathInfo := range availablePaths {
-		rc := &xrebpathrunner{t: t, mpathplus: fs.Mountpaths.MakePathCloud(mpathInfo.Path), xreb: xreb, wg: wg, newsmap: newsmap}
+		rc := &xrebpathrunner{t: t, mpathInfo: mpathInfo, scope: fs.WalkCloud, mpathplus: fs.Mountpaths.MakePathCloud(mpathInfo.Path),
+			xreb: xreb, wg: wg, newsmap: newsmap, order: order}
 		wg.Add(1)
 		go rc.oneRebalance()
 		allr = append(allr, rc)
 
-		rl := &xrebpathrunner{t: t, mpathplus: fs.Mountpaths.MakePathLocal(mpathInfo.Path), xreb: xreb, wg: wg, newsmap: newsmap}
+		rl := &xrebpathrunner{t: t, mpathInfo: mpathInfo, scope: fs.WalkLocal, mpathplus: fs.Mountpaths.MakePathLocal(mpathInfo.Path),
+			xreb: xreb, wg: wg, newsmap: newsmap, order: order}
 		wg.Add(1)
 		go rl.oneRebalance()
 		allr = append(allr, rl)
 	}
 	wg.Wait()
+	t.fdCache.purge() // the files of the moved objects
 
+	var aborted bool
+	for _, r := range allr {
+		if r.aborted {
+			aborted = true
+		}
+	}
 	if pmarker != "" {
-		var aborted bool
 		totalMovedN, totalMovedBytes := int64(0), int64(0)
 		for _, r := range allr {
-			if r.aborted {
-				aborted = true
-			}
 			totalMovedN += r.fileMoved
 			totalMovedBytes += r.byteMoved
 		}
@@ -429,39 +480,28 @@ func (t *targetrunner) runRebalance(newsmap *smapX, newtargetid string) {
 		if totalMovedN > 0 {
 			t.statsif.Add(stats.RebalGlobalCount, totalMovedN)
 			t.statsif.Add(stats.RebalGlobalSize, totalMovedBytes)
+			if !aborted {
+				t.setRebThroughput(totalMovedBytes, time.Since(started))
+			}
 		}
 	}
 	if newtargetid == t.si.DaemonID {
 		glog.Infof("rebalance: %s <= self", newtargetid)
-		t.pollRebalancingDone(newsmap) // until the cluster is fully rebalanced - see t.httpobjget
+		t.waitRebalancingDone(newsmap) // until the cluster is fully rebalanced - see t.httpobjget
+	} else if si := newsmap.Tmap[newtargetid]; si != nil {
+		done := &rebDoneMsg{Version: newsmap.version(), Aborted: aborted}
+		t.publish(busTopicRebalanceDone, done, map[string]*cluster.Snode{newtargetid: si})
 	}
 	xreb.EndTime(time.Now())
 	glog.Infoln(xreb.String())
 	t.xactinp.del(xreb.ID())
 }
 
-func (t *targetrunner) pollRebalancingDone(newSmap *smapX) {
-	wg := &sync.WaitGroup{}
-	wg.Add(len(newSmap.Tmap) - 1)
-
-	for _, si := range newSmap.Tmap {
-		if si.DaemonID == t.si.DaemonID {
-			continue
-		}
-
-		go func(si *cluster.Snode) {
-			t.waitForRebalanceFinish(si, newSmap.version())
-			wg.Done()
-		}(si)
-	}
-
-	wg.Wait()
-}
-
 func (t *targetrunner) runLocalRebalance() {
 	availablePaths, _ := fs.Mountpaths.Get()
 	runnerCnt := len(availablePaths) * 2
 	xreb := t.xactinp.renewLocalRebalance(t, runnerCnt)
+	t.fdCache.purge()
 
 	pmarker := t.xactinp.localRebalanceInProgress()
 	file, err := cmn.CreateFile(pmarker)
@@ -476,7 +516,7 @@ func (t *targetrunner) runLocalRebalance() {
 	wg := &sync.WaitGroup{}
 	glog.Infof("starting local rebalance with %d runners\n", runnerCnt)
 	for _, mpathInfo := range availablePaths {
-		runner := &localRebPathRunner{t: t, mpath: fs.Mountpaths.MakePathCloud(mpathInfo.Path), xreb: xreb}
+		runner := &localRebPathRunner{t: t, mpathInfo: mpathInfo, scope: fs.WalkCloud, mpath: fs.Mountpaths.MakePathCloud(mpathInfo.Path), xreb: xreb}
 		wg.Add(1)
 		go func(runner *localRebPathRunner) {
 			runner.run()
@@ -484,7 +524,7 @@ func (t *targetrunner) runLocalRebalance() {
 		}(runner)
 		allr = append(allr, runner)
 
-		runner = &localRebPathRunner{t: t, mpath: fs.Mountpaths.MakePathLocal(mpathInfo.Path), xreb: xreb}
+		runner = &localRebPathRunner{t: t, mpathInfo: mpathInfo, scope: fs.WalkLocal, mpath: fs.Mountpaths.MakePathLocal(mpathInfo.Path), xreb: xreb}
 		wg.Add(1)
 		go func(runner *localRebPathRunner) {
 			runner.run()
@@ -493,6 +533,7 @@ func (t *targetrunner) runLocalRebalance() {
 		allr = append(allr, runner)
 	}
 	wg.Wait()
+	t.fdCache.purge() // the files of the moved objects
 
 	if pmarker != "" {
 		var aborted bool
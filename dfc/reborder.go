@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+// ================================ Summary ===============================================
+//
+// Rebalance order: by default, global rebalance sends misplaced objects as it traverses
+// the mountpaths. With rebalance_conf.order set to large_first, small_first, or mixed,
+// each mountpath runner first collects the objects to send and then sends them in that
+// order: the largest first (to amortize the per-object overhead), the smallest first (to
+// reduce the number of misplaced objects quickly), or one object of each size class in
+// turn, starting from the large ones. A runner collects at most rebPendingMax objects at
+// a time: once it has that many, it sends them and then resumes the traversal - so that
+// the order holds within each batch rather than across the mountpath.
+//
+// With any order, a runner adds each object it finds to the remaining objects and bytes
+// of the rebalance xaction, per size class, until the object is sent; those are reported
+// by GET /v1/cluster?what=xaction&props=rebalance while rebalance is running.
+//
+// ================================ Summary ===============================================
+
+// size classes of misplaced objects
+const (
+	rebSizeSmall = iota // less than rebSizeMedium bytes
+	rebSizeMedium
+	rebSizeLarge // rebSizeLargeMin bytes and more
+	rebSizeClasses
+)
+
+const (
+	rebSizeMediumMin = cmn.MiB
+	rebSizeLargeMin  = 64 * cmn.MiB
+	rebPendingMax    = 64 * 1024 // objects collected by a mountpath runner before sending
+)
+
+var rebSizeNames = [rebSizeClasses]string{"small", "medium", "large"}
+
+// rebObject is a misplaced object to be sent to the target si
+type rebObject struct {
+	bucket  string
+	objname string
+	fqn     string
+	si      *cluster.Snode
+	size    int64
+}
+
+// rebRemaining counts the objects and bytes yet to be sent, per size class
+type rebRemaining struct {
+	objects [rebSizeClasses]int64
+	bytes   [rebSizeClasses]int64
+}
+
+func rebSizeClass(size int64) int {
+	switch {
+	case size >= rebSizeLargeMin:
+		return rebSizeLarge
+	case size >= rebSizeMediumMin:
+		return rebSizeMedium
+	default:
+		return rebSizeSmall
+	}
+}
+
+func (rr *rebRemaining) add(size, n int64) {
+	class := rebSizeClass(size)
+	atomic.AddInt64(&rr.objects[class], n)
+	atomic.AddInt64(&rr.bytes[class], n*size)
+}
+
+func (rr *rebRemaining) get() []stats.RebalanceSizeClass {
+	out := make([]stats.RebalanceSizeClass, rebSizeClasses)
+	for class := range out {
+		out[class] = stats.RebalanceSizeClass{
+			Class:   rebSizeNames[class],
+			Objects: atomic.LoadInt64(&rr.objects[class]),
+			Bytes:   atomic.LoadInt64(&rr.bytes[class]),
+		}
+	}
+	return out
+}
+
+// orderRebObjects returns the objects in the given order (cmn.RebOrderNone excluded);
+// objects of the same size keep the order of traversal
+func orderRebObjects(objs []*rebObject, order string) []*rebObject {
+	switch order {
+	case cmn.RebOrderLargeFirst:
+		sort.SliceStable(objs, func(i, j int) bool { return objs[i].size > objs[j].size })
+	case cmn.RebOrderSmallFirst:
+		sort.SliceStable(objs, func(i, j int) bool { return objs[i].size < objs[j].size })
+	case cmn.RebOrderMixed:
+		var bins [rebSizeClasses][]*rebObject
+		for _, obj := range objs {
+			class := rebSizeClass(obj.size)
+			bins[class] = append(bins[class], obj)
+		}
+		out := make([]*rebObject, 0, len(objs))
+		for i := 0; len(out) < len(objs); i++ {
+			for class := rebSizeClasses - 1; class >= 0; class-- {
+				if i < len(bins[class]) {
+					out = append(out, bins[class][i])
+				}
+			}
+		}
+		return out
+	}
+	return objs
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestOrderRebObjects(t *testing.T) {
+	sizes := []int64{10, 100 * cmn.MiB, 2 * cmn.MiB, 20, 200 * cmn.MiB, 3 * cmn.MiB}
+	newObjs := func() []*rebObject {
+		objs := make([]*rebObject, len(sizes))
+		for i, size := range sizes {
+			objs[i] = &rebObject{size: size}
+		}
+		return objs
+	}
+	tests := []struct {
+		order    string
+		expected []int64
+	}{
+		{cmn.RebOrderNone, sizes},
+		{cmn.RebOrderLargeFirst, []int64{200 * cmn.MiB, 100 * cmn.MiB, 3 * cmn.MiB, 2 * cmn.MiB, 20, 10}},
+		{cmn.RebOrderSmallFirst, []int64{10, 20, 2 * cmn.MiB, 3 * cmn.MiB, 100 * cmn.MiB, 200 * cmn.MiB}},
+		// large, medium, and small in turn, each in the order of traversal
+		{cmn.RebOrderMixed, []int64{100 * cmn.MiB, 2 * cmn.MiB, 10, 200 * cmn.MiB, 3 * cmn.MiB, 20}},
+	}
+	for _, test := range tests {
+		objs := orderRebObjects(newObjs(), test.order)
+		if len(objs) != len(test.expected) {
+			t.Fatalf("%s: expected %d objects, got %d", test.order, len(test.expected), len(objs))
+		}
+		for i, obj := range objs {
+			if obj.size != test.expected[i] {
+				t.Errorf("%s: expected size %d at %d, got %d", test.order, test.expected[i], i, obj.size)
+			}
+		}
+	}
+}
+
+func TestRebRemaining(t *testing.T) {
+	rr := &rebRemaining{}
+	rr.add(10, 1)
+	rr.add(20, 1)
+	rr.add(100*cmn.MiB, 1)
+	rr.add(20, -1)
+
+	remaining := rr.get()
+	if len(remaining) != rebSizeClasses {
+		t.Fatalf("expected %d size classes, got %d", rebSizeClasses, len(remaining))
+	}
+	small, medium, large := remaining[rebSizeSmall], remaining[rebSizeMedium], remaining[rebSizeLarge]
+	if small.Class != "small" || small.Objects != 1 || small.Bytes != 10 {
+		t.Errorf("unexpected small objects remaining: %+v", small)
+	}
+	if medium.Objects != 0 || medium.Bytes != 0 {
+		t.Errorf("unexpected medium objects remaining: %+v", medium)
+	}
+	if large.Objects != 1 || large.Bytes != 100*cmn.MiB {
+		t.Errorf("unexpected large objects remaining: %+v", large)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Rebalance plan: GET /v1/cluster?what=rebplan makes each target traverse its mountpaths,
+// the same way global rebalance does, and count the objects and bytes that it would
+// send to each other target given the current cluster map - without moving anything.
+// The primary proxy aggregates the result into a plan with per target-pair totals and
+// the estimated duration: each target moves its data in parallel with others, at the
+// throughput it measured during its last rebalance.
+//
+// With rebalance_conf.require_confirmation the primary refuses to start user-requested
+// global rebalance unless the request carries the ID of the plan computed for the
+// current version of the cluster map.
+//
+// ================================ Summary ===============================================
+
+// rebPlanTTL is how long a rebalance plan can be used to confirm rebalance
+const rebPlanTTL = time.Hour
+
+type rebPlanID struct {
+	sync.Mutex
+	id      string
+	smapver int64
+	created time.Time
+}
+
+//
+// target
+//
+
+// rebalancePlan traverses the available mountpaths and sums up what global rebalance would move
+func (t *targetrunner) rebalancePlan() *cmn.TargetRebalancePlan {
+	var (
+		smap  = t.smapowner.get()
+		moves = make(map[string]*cmn.RebalanceMove, len(smap.Tmap))
+	)
+	walk := func(entry *fs.WalkEntry) error {
+		bucket, objname, err := cluster.ResolveFQN(entry.FQN, t.bmdowner)
+		if err != nil {
+			return nil
+		}
+		si, errstr := hrwTarget(bucket, objname, smap)
+		if errstr != "" {
+			return errors.New(errstr)
+		}
+		if si.DaemonID == t.si.DaemonID {
+			return nil
+		}
+		move, ok := moves[si.DaemonID]
+		if !ok {
+			move = &cmn.RebalanceMove{From: t.si.DaemonID, To: si.DaemonID}
+			moves[si.DaemonID] = move
+		}
+		move.Objects++
+		move.Bytes += entry.FileInfo.Size()
+		return nil
+	}
+	// one mountpath at a time: the moves are not synchronized
+	if err := fs.Mountpaths.WalkObjects(&fs.WalkOpts{Parallel: 1, Skip: skipNonMovable}, walk); err != nil {
+		glog.Errorf("Failed to traverse mountpaths, err: %v", err)
+	}
+	plan := &cmn.TargetRebalancePlan{
+		SmapVersion: smap.version(),
+		Moves:       make([]cmn.RebalanceMove, 0, len(moves)),
+		Throughput:  atomic.LoadInt64(&t.rebThroughput),
+	}
+	for _, move := range moves {
+		plan.Moves = append(plan.Moves, *move)
+	}
+	return plan
+}
+
+// setRebThroughput is called upon completion of global rebalance
+func (t *targetrunner) setRebThroughput(bytes int64, elapsed time.Duration) {
+	if bytes == 0 || elapsed <= 0 {
+		return
+	}
+	atomic.StoreInt64(&t.rebThroughput, int64(float64(bytes)/elapsed.Seconds()))
+}
+
+//
+// proxy
+//
+
+func (p *proxyrunner) invokeHttpGetRebalancePlan(w http.ResponseWriter, r *http.Request) bool {
+	// the primary keeps the plan's ID to confirm rebalance
+	if p.forwardCP(w, r, &cmn.ActionMsg{Action: cmn.GetWhatRebPlan}, "", nil) {
+		return false
+	}
+	var (
+		smap  = p.smapowner.get()
+		plans = make(map[string]*cmn.TargetRebalancePlan, smap.CountTargets())
+	)
+	// traversing all objects takes time
+	results := p.broadcastTargets(cmn.URLPath(cmn.Version, cmn.Daemon), r.URL.Query(), http.MethodGet,
+		nil, smap, ctx.config.Timeout.DefaultLong)
+	for res := range results {
+		id := res.si.DaemonID
+		if res.err != nil {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Failed to get target %s rebalance plan: %s", id, res.errstr))
+			return false
+		}
+		plan := &cmn.TargetRebalancePlan{}
+		if err := jsoniter.Unmarshal(res.outjson, plan); err != nil {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Failed to unmarshal target %s rebalance plan, err: %v", id, err))
+			return false
+		}
+		if plan.SmapVersion != smap.version() {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Target %s has Smap v%d, expecting v%d - cluster map is changing, try again",
+				id, plan.SmapVersion, smap.version()), http.StatusConflict)
+			return false
+		}
+		plans[id] = plan
+	}
+	out := summarizeRebPlan(smap.version(), plans)
+	p.rebPlan.Lock()
+	p.rebPlan.id, p.rebPlan.smapver, p.rebPlan.created = out.ID, out.SmapVersion, time.Now()
+	p.rebPlan.Unlock()
+
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetRebalancePlan")
+}
+
+// summarizeRebPlan aggregates the targets' plans. Each target sends its data in parallel
+// with others - the slowest one determines the duration. Targets that have not rebalanced
+// yet are assumed to move data at the average throughput of those that have
+func summarizeRebPlan(smapver int64, plans map[string]*cmn.TargetRebalancePlan) *cmn.ClusterRebalancePlan {
+	var (
+		out = &cmn.ClusterRebalancePlan{
+			ID:          fmt.Sprintf("%d-%x", smapver, time.Now().UnixNano()),
+			SmapVersion: smapver,
+			Moves:       make([]cmn.RebalanceMove, 0),
+		}
+		measured, sum int64
+	)
+	for _, plan := range plans {
+		if plan.Throughput > 0 {
+			measured++
+			sum += plan.Throughput
+		}
+	}
+	for _, plan := range plans {
+		var bytes int64
+		for _, move := range plan.Moves {
+			out.Moves = append(out.Moves, move)
+			out.Objects += move.Objects
+			bytes += move.Bytes
+		}
+		out.Bytes += bytes
+		throughput := plan.Throughput
+		if throughput == 0 && measured > 0 {
+			throughput = sum / measured
+		}
+		if throughput > 0 {
+			estimated := time.Duration(float64(bytes) / float64(throughput) * float64(time.Second))
+			if estimated > out.EstimatedTime {
+				out.EstimatedTime = estimated
+			}
+		}
+	}
+	sort.Slice(out.Moves, func(i, j int) bool {
+		if out.Moves[i].From != out.Moves[j].From {
+			return out.Moves[i].From < out.Moves[j].From
+		}
+		return out.Moves[i].To < out.Moves[j].To
+	})
+	return out
+}
+
+// confirmRebalance returns an error string unless rebalance is confirmed with the ID of
+// the plan computed for the current cluster map
+func (p *proxyrunner) confirmRebalance(id string) (errstr string) {
+	if !ctx.config.Rebalance.RequireConfirm {
+		return
+	}
+	if id == "" {
+		return "Rebalance requires confirmation: get the rebalance plan (GET /v1/cluster?what=rebplan) " +
+			"and pass its ID as the action's name"
+	}
+	p.rebPlan.Lock()
+	defer p.rebPlan.Unlock()
+	switch {
+	case id != p.rebPlan.id:
+		errstr = fmt.Sprintf("Unknown rebalance plan %q: get the plan again", id)
+	case p.rebPlan.smapver != p.smapowner.get().version():
+		errstr = fmt.Sprintf("Rebalance plan %q was computed for Smap v%d that has changed: get the plan again",
+			id, p.rebPlan.smapver)
+	case time.Since(p.rebPlan.created) > rebPlanTTL:
+		errstr = fmt.Sprintf("Rebalance plan %q has expired: get the plan again", id)
+	default:
+		p.rebPlan.id = "" // one-time
+	}
+	return
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestRebalancePlan(t *testing.T) {
+	plans := map[string]*cmn.TargetRebalancePlan{
+		// 100MB at 10MB/s
+		"t1": {Moves: []cmn.RebalanceMove{{From: "t1", To: "t3", Objects: 10, Bytes: 100 * cmn.MiB}}, Throughput: 10 * cmn.MiB},
+		// 300MB at the average throughput of others (20MB/s)
+		"t2": {Moves: []cmn.RebalanceMove{
+			{From: "t2", To: "t3", Objects: 20, Bytes: 200 * cmn.MiB},
+			{From: "t2", To: "t1", Objects: 5, Bytes: 100 * cmn.MiB},
+		}},
+		"t3": {Throughput: 30 * cmn.MiB},
+	}
+	plan := summarizeRebPlan(5, plans)
+	if plan.Objects != 35 || plan.Bytes != 400*cmn.MiB || len(plan.Moves) != 3 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if plan.Moves[0].From != "t1" || plan.Moves[1].To != "t1" {
+		t.Errorf("moves are not sorted: %+v", plan.Moves)
+	}
+	if plan.EstimatedTime != 15*time.Second {
+		t.Errorf("expected estimated time 15s, got %v", plan.EstimatedTime)
+	}
+
+	// no throughput measured: the estimate is unknown
+	plan = summarizeRebPlan(5, map[string]*cmn.TargetRebalancePlan{"t2": plans["t2"]})
+	if plan.EstimatedTime != 0 {
+		t.Errorf("expected unknown estimated time, got %v", plan.EstimatedTime)
+	}
+}
+
+func TestConfirmRebalance(t *testing.T) {
+	ctx.config.Rebalance.RequireConfirm = true
+	defer func() { ctx.config.Rebalance.RequireConfirm = false }()
+
+	p := &proxyrunner{}
+	p.smapowner = &smapowner{}
+	smap := newSmap()
+	smap.Version = 5
+	p.smapowner.put(smap)
+	p.rebPlan.id, p.rebPlan.smapver, p.rebPlan.created = "5-abc", 5, time.Now()
+
+	if errstr := p.confirmRebalance(""); errstr == "" {
+		t.Error("expected rebalance without plan ID to fail")
+	}
+	if errstr := p.confirmRebalance("4-abc"); errstr == "" {
+		t.Error("expected rebalance with unknown plan ID to fail")
+	}
+	if errstr := p.confirmRebalance("5-abc"); errstr != "" {
+		t.Error(errstr)
+	}
+	// one-time
+	if errstr := p.confirmRebalance("5-abc"); errstr == "" {
+		t.Error("expected plan ID to be usable only once")
+	}
+
+	// the cluster map has changed since
+	p.rebPlan.id, p.rebPlan.smapver = "4-abc", 4
+	if errstr := p.confirmRebalance("4-abc"); errstr == "" {
+		t.Error("expected rebalance with outdated plan to fail")
+	}
+}
@@ -114,7 +114,7 @@ func (rcksctx *recksumctx) walkFunc(fqn string, osfi os.FileInfo, err error) err
 		break
 	}
 
-	file, err := os.Open(fqn)
+	file, err := openObject(fqn)
 	if err != nil {
 		if os.IsNotExist(err) {
 			glog.Infof("Warning (file evicted?): %s", fqn)
@@ -137,14 +137,18 @@ func (rcksctx *recksumctx) walkFunc(fqn string, osfi os.FileInfo, err error) err
 		return ioerr
 	}
 
+	kind := rcksctx.t.bmdowner.get().cksumType(rcksctx.xrcksum.bucket)
+	if !cmn.ValidCksumType(kind) {
+		return nil
+	}
 	buf, slab := gmem2.AllocFromSlab2(osfi.Size())
-	xxHashVal, errstr := cmn.ComputeXXHash(file, buf)
+	xxHashVal, errstr := cmn.ComputeCksum(kind, file, buf)
 	slab.Free(buf)
 	if errstr != "" {
 		glog.Warningf("failed to compute hash on %s, error: %s", fqn, errstr)
 		return errors.New(errstr)
 	}
-	if errstr = Setxattr(fqn, cmn.XattrXXHashVal, []byte(xxHashVal)); errstr != "" {
+	if errstr = Setxattr(fqn, cmn.XattrXXHashVal, cmn.MakeStoredCksum(kind, xxHashVal)); errstr != "" {
 		ioerr := errors.New(errstr)
 		glog.Warningf("failed to set attribute %s for file %s, error: %v", cmn.XattrXXHashVal, fqn, ioerr)
 		rcksctx.t.fshc(ioerr, fqn)
@@ -0,0 +1,358 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Atomic rename of a local bucket (ActRenameBucket). Unlike ActRenameLB, which migrates
+// the bucket object by object and therefore temporarily needs twice the capacity, the
+// targets rename the bucket's directories in place. The primary proxy coordinates:
+//   1) prepare: each target starts a renamebck xaction that renames local/<from> as
+//      local/<to> on every mountpath;
+//   2) if any target fails to prepare, the proxy rolls back (ActAbortRenameBucket):
+//      the targets rename back whatever they have renamed, and the bucket keeps its name;
+//   3) commit: the proxy replaces the bucket in the bucket-metadata and metasyncs it;
+//      the targets then finish their xactions.
+// Objects are placed (HRW) by their bucket and object names, so after the commit the
+// renamed objects are generally misplaced: the targets run local rebalance and the proxy
+// starts global rebalance. GET locates the objects that are not yet moved (see
+// getFromNeighbor).
+//
+// From prepare until commit (or rollback) the targets reject object requests into both
+// buckets with 503 and Retry-After, and prepare waits for the requests in progress to
+// finish before renaming the directories - a PUT into the bucket being renamed would
+// otherwise re-create local/<from> behind the rename and break its rollback.
+//
+// ================================ Summary ===============================================
+
+//
+// proxy
+//
+
+// POST { renamebck } /v1/buckets/bucket-name
+func (p *proxyrunner) renameBucket(w http.ResponseWriter, r *http.Request, bucketFrom string, msg *cmn.ActionMsg) {
+	if p.forwardCP(w, r, msg, "", nil) {
+		return
+	}
+	bucketTo := msg.Name
+	if bucketFrom == "" || bucketTo == "" {
+		errstr := fmt.Sprintf("Invalid rename local bucket request: empty name %s => %s", bucketFrom, bucketTo)
+		p.invalmsghdlr(w, r, errstr)
+		return
+	}
+	p.snapmtx.Lock() // no snapshots get created while renaming
+	defer p.snapmtx.Unlock()
+	bucketmd := p.bmdowner.get()
+	ok, props := bucketmd.get(bucketFrom, true)
+	if !ok {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Local bucket %s "+doesnotexist, bucketFrom))
+		return
+	}
+	if ok, _ := bucketmd.get(bucketTo, true); ok {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Local bucket %s already exists", bucketTo))
+		return
+	}
+	if len(props.Snapshots) > 0 {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Local bucket %s has snapshots: cannot rename", bucketFrom))
+		return
+	}
+
+	// phase 1: prepare
+	if errstr := p.bcastRenameBucket(bucketFrom, msg, true /*prepare*/); errstr != "" {
+		p.rollbackRenameBucket(bucketFrom, bucketTo, errstr)
+		p.invalmsghdlr(w, r, errstr)
+		return
+	}
+
+	// commit point: the bucket-metadata
+	p.bmdowner.Lock()
+	clone := p.bmdowner.get().clone()
+	ok, props = clone.get(bucketFrom, true)
+	if exists, _ := clone.get(bucketTo, true); !ok || exists {
+		p.bmdowner.Unlock()
+		errstr := fmt.Sprintf("Local bucket %s => %s: bucket-metadata changed while renaming", bucketFrom, bucketTo)
+		p.rollbackRenameBucket(bucketFrom, bucketTo, errstr)
+		p.invalmsghdlr(w, r, errstr)
+		return
+	}
+	clone.del(bucketFrom, true)
+	clone.add(bucketTo, true, props)
+	if errstr := p.savebmdconf(clone); errstr != "" {
+		glog.Errorln(errstr)
+	}
+	p.bmdowner.put(clone)
+	p.bmdowner.Unlock()
+	p.metasyncer.sync(true, clone, msg)
+
+	// phase 2: finish up
+	if errstr := p.bcastRenameBucket(bucketFrom, msg, false /*prepare*/); errstr != "" {
+		// the rename is committed, the misplaced objects get moved by rebalance
+		glog.Errorf("Renamed local bucket %s => %s, err: %s", bucketFrom, bucketTo, errstr)
+	}
+	smap := p.smapowner.get()
+	if smap.CountTargets() > 1 {
+		atomic.StoreInt64(&p.rebStarted, time.Now().UnixNano())
+		p.metasyncer.sync(false, smap, &cmn.ActionMsg{Action: cmn.ActGlobalReb})
+	}
+	glog.Infof("renamed local bucket %s => %s, bucket-metadata version %d", bucketFrom, bucketTo, clone.version())
+}
+
+func (p *proxyrunner) rollbackRenameBucket(bucketFrom, bucketTo, errstr string) {
+	abortmsg := &cmn.ActionMsg{Action: cmn.ActAbortRenameBucket, Name: bucketTo}
+	if errabort := p.bcastRenameBucket(bucketFrom, abortmsg, false); errabort != "" {
+		glog.Errorf("Nested error: %s => (roll back rename %s => %s, err: %s)", errstr, bucketFrom, bucketTo, errabort)
+	}
+}
+
+func (p *proxyrunner) bcastRenameBucket(bucketFrom string, msg *cmn.ActionMsg, prepare bool) (errstr string) {
+	jsbytes, err := jsoniter.Marshal(msg)
+	cmn.Assert(err == nil, err)
+	query := url.Values{}
+	if prepare {
+		query.Set(cmn.URLParamPrepare, "true")
+	}
+	results := p.broadcastTargets(
+		cmn.URLPath(cmn.Version, cmn.Buckets, bucketFrom),
+		query,
+		http.MethodPost,
+		jsbytes,
+		p.smapowner.get(),
+		ctx.config.Timeout.DefaultLong,
+	)
+	errs := make([]string, 0)
+	for res := range results {
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", res.si.DaemonID, res.errstr))
+		}
+	}
+	if len(errs) != 0 {
+		phase := msg.Action
+		if prepare {
+			phase += " (prepare)"
+		}
+		errstr = fmt.Sprintf("%s failed: %s", phase, strings.Join(errs, "; "))
+	}
+	return
+}
+
+//
+// target
+//
+
+const renamingRetryAfter = 1 // seconds
+
+// renamingBuckets tracks the object requests in progress per bucket, to hold them off
+// while the bucket is being renamed
+type renamingBuckets struct {
+	sync.Mutex
+	held     map[string]bool  // bucket => being renamed
+	inflight map[string]int64 // bucket => object requests in progress
+}
+
+func newRenamingBuckets() *renamingBuckets {
+	return &renamingBuckets{held: make(map[string]bool, 2), inflight: make(map[string]int64, 16)}
+}
+
+// enter returns false if the bucket is being renamed; otherwise, the request must leave
+func (rb *renamingBuckets) enter(bucket string) bool {
+	rb.Lock()
+	defer rb.Unlock()
+	if rb.held[bucket] {
+		return false
+	}
+	rb.inflight[bucket]++
+	return true
+}
+
+func (rb *renamingBuckets) leave(bucket string) {
+	rb.Lock()
+	if rb.inflight[bucket]--; rb.inflight[bucket] <= 0 {
+		delete(rb.inflight, bucket)
+	}
+	rb.Unlock()
+}
+
+// hold rejects new requests into the buckets and waits for the ones in progress to finish
+func (rb *renamingBuckets) hold(timeout time.Duration, buckets ...string) bool {
+	rb.Lock()
+	for _, bucket := range buckets {
+		rb.held[bucket] = true
+	}
+	rb.Unlock()
+	for deadline := time.Now().Add(timeout); ; time.Sleep(10 * time.Millisecond) {
+		rb.Lock()
+		inflight := int64(0)
+		for _, bucket := range buckets {
+			inflight += rb.inflight[bucket]
+		}
+		rb.Unlock()
+		if inflight == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+	}
+}
+
+func (rb *renamingBuckets) release(buckets ...string) {
+	rb.Lock()
+	for _, bucket := range buckets {
+		delete(rb.held, bucket)
+	}
+	rb.Unlock()
+}
+
+// objectBucket returns the bucket of the /v1/objects/bucket-name/... request
+func objectBucket(r *http.Request) string {
+	prefix := cmn.URLPath(cmn.Version, cmn.Objects) + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		return ""
+	}
+	bucket := r.URL.Path[len(prefix):]
+	if i := strings.IndexByte(bucket, '/'); i >= 0 {
+		bucket = bucket[:i]
+	}
+	return bucket
+}
+
+func (t *targetrunner) renamingReject(w http.ResponseWriter, r *http.Request, bucket string) {
+	w.Header().Set("Retry-After", strconv.Itoa(renamingRetryAfter))
+	t.invalmsghdlr(w, r, fmt.Sprintf("Local bucket %s is being renamed", bucket), http.StatusServiceUnavailable)
+}
+
+// POST { renamebck | abortrenamebck } /v1/buckets/bucket-name
+func (t *targetrunner) renameBucketAction(w http.ResponseWriter, r *http.Request, bucketFrom string, msg *cmn.ActionMsg) {
+	var (
+		errstr   string
+		bucketTo = msg.Name
+	)
+	switch {
+	case msg.Action == cmn.ActAbortRenameBucket:
+		errstr = t.rollbackRenameBucket(bucketFrom, bucketTo)
+	case r.URL.Query().Get(cmn.URLParamPrepare) == "true":
+		errstr = t.prepareRenameBucket(bucketFrom, bucketTo)
+	default:
+		errstr = t.commitRenameBucket(bucketFrom, bucketTo)
+	}
+	if errstr != "" {
+		t.invalmsghdlr(w, r, errstr)
+	}
+}
+
+// prepareRenameBucket renames the bucket's directories on all mountpaths; on failure,
+// it renames back the directories renamed so far
+func (t *targetrunner) prepareRenameBucket(bucketFrom, bucketTo string) (errstr string) {
+	bucketmd := t.bmdowner.get()
+	if ok, _ := bucketmd.get(bucketFrom, true); !ok {
+		return fmt.Sprintf("Local bucket %s "+doesnotexist, bucketFrom)
+	}
+	if ok, _ := bucketmd.get(bucketTo, true); ok {
+		return fmt.Sprintf("Local bucket %s already exists", bucketTo)
+	}
+	if hasSnapshots(bucketFrom) {
+		return fmt.Sprintf("Local bucket %s has snapshots: cannot rename", bucketFrom)
+	}
+	xren := t.xactinp.renewRenameBucket(t, bucketFrom, bucketTo)
+	if xren == nil {
+		return fmt.Sprintf("Local bucket %s or %s is being renamed", bucketFrom, bucketTo)
+	}
+	if !t.renaming.hold(ctx.config.Timeout.Default, bucketFrom, bucketTo) {
+		errstr = fmt.Sprintf("Local bucket %s => %s: timed out waiting for the object requests in progress", bucketFrom, bucketTo)
+		xren.abort()
+		t.undoRenameBucket(xren)
+		return
+	}
+	t.fdCache.purge() // the objects are about to move
+	availablePaths, _ := fs.Mountpaths.Get()
+	for _, mpathInfo := range availablePaths {
+		localdir := fs.Mountpaths.MakePathLocal(mpathInfo.Path)
+		fromdir, todir := filepath.Join(localdir, bucketFrom), filepath.Join(localdir, bucketTo)
+		if _, err := os.Stat(fromdir); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			errstr = fmt.Sprintf("Failed to rename %s => %s, err: %v", fromdir, todir, err)
+			break
+		}
+		if err := os.Rename(fromdir, todir); err != nil {
+			errstr = fmt.Sprintf("Failed to rename %s => %s, err: %v", fromdir, todir, err)
+			break
+		}
+		xren.renamed = append(xren.renamed, localdir)
+	}
+	if errstr != "" {
+		xren.abort()
+		t.undoRenameBucket(xren)
+		return
+	}
+	getstorstatsrunner().RenameBucketCapacity(bucketFrom, bucketTo, true)
+	return
+}
+
+// rollbackRenameBucket undoes the prepared rename - nothing to do if this target has
+// either failed to prepare or has not received the prepare request
+func (t *targetrunner) rollbackRenameBucket(bucketFrom, bucketTo string) (errstr string) {
+	xren := t.xactinp.findRenameBucket(bucketFrom, bucketTo)
+	if xren == nil {
+		return
+	}
+	xren.abort()
+	getstorstatsrunner().RenameBucketCapacity(bucketTo, bucketFrom, true)
+	return t.undoRenameBucket(xren)
+}
+
+func (t *targetrunner) undoRenameBucket(xren *xactRenameBucket) (errstr string) {
+	for i := len(xren.renamed) - 1; i >= 0; i-- {
+		localdir := xren.renamed[i]
+		fromdir, todir := filepath.Join(localdir, xren.bucketFrom), filepath.Join(localdir, xren.bucketTo)
+		if err := os.Rename(todir, fromdir); err != nil {
+			errstr = fmt.Sprintf("Failed to roll back rename %s => %s, err: %v", fromdir, todir, err)
+			glog.Errorln(errstr)
+		}
+	}
+	xren.renamed = nil
+	t.renaming.release(xren.bucketFrom, xren.bucketTo)
+	xren.EndTime(time.Now())
+	glog.Infoln(xren.String())
+	t.xactinp.del(xren.ID())
+	return
+}
+
+// commitRenameBucket finishes the rename committed by the proxy and moves the objects
+// that now map onto other mountpaths
+func (t *targetrunner) commitRenameBucket(bucketFrom, bucketTo string) (errstr string) {
+	xren := t.xactinp.findRenameBucket(bucketFrom, bucketTo)
+	if xren == nil {
+		return fmt.Sprintf("Local bucket %s => %s: rename is not prepared", bucketFrom, bucketTo)
+	}
+	t.renaming.release(bucketFrom, bucketTo)
+	xren.EndTime(time.Now())
+	glog.Infoln(xren.String())
+	t.xactinp.del(xren.ID())
+	if len(xren.renamed) > 0 {
+		go t.runLocalRebalance()
+	}
+	return
+}
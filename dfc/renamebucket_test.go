@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/stats"
+)
+
+func TestRenameBucketRollback(t *testing.T) {
+	q := fs.Mountpaths
+	defer func() { fs.Mountpaths = q }()
+	fs.Mountpaths = fs.NewMountedFS(ctx.config.LocalBuckets, ctx.config.CloudBuckets)
+	fs.Mountpaths.DisableFsIDCheck()
+	localdirs := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		mpath, err := ioutil.TempDir("", "renamebucket")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(mpath)
+		if err := fs.Mountpaths.Add(mpath); err != nil {
+			t.Fatal(err)
+		}
+		localdir := fs.Mountpaths.MakePathLocal(mpath)
+		if err := cmn.CreateDir(filepath.Join(localdir, "src", "dir")); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(localdir, "src", "dir", "obj"), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		localdirs = append(localdirs, localdir)
+	}
+
+	rg := ctx.rg
+	defer func() { ctx.rg = rg }()
+	ctx.rg = &rungroup{storstats: &stats.Trunner{}}
+	getstorstatsrunner().AddBucketCapacity("src", true, 2, 8)
+
+	tgt := &targetrunner{fdCache: newFDCache(), renaming: newRenamingBuckets()}
+	tgt.xactinp = newxactinp()
+	tgt.bmdowner = &bmdowner{}
+	bucketmd := newBucketMD()
+	bucketmd.add("src", true, cmn.BucketProps{CloudProvider: cmn.ProviderDFC})
+	tgt.bmdowner.put(bucketmd)
+
+	check := func(tag, bucket string) {
+		for _, localdir := range localdirs {
+			if _, err := os.Stat(filepath.Join(localdir, bucket, "dir", "obj")); err != nil {
+				t.Errorf("%s: %v", tag, err)
+			}
+		}
+	}
+
+	// one of the mountpaths cannot be renamed: the other one is renamed back
+	obstacle := filepath.Join(localdirs[1], "dst", "obstacle")
+	if err := cmn.CreateDir(obstacle); err != nil {
+		t.Fatal(err)
+	}
+	if errstr := tgt.prepareRenameBucket("src", "dst"); errstr == "" {
+		t.Fatal("expected prepare to fail")
+	}
+	check("failed prepare", "src")
+	if xren := tgt.xactinp.findRenameBucket("src", "dst"); xren != nil {
+		t.Errorf("unexpected %s after failed prepare", xren)
+	}
+	// ... and the proxy's rollback finds nothing to do
+	if errstr := tgt.rollbackRenameBucket("src", "dst"); errstr != "" {
+		t.Error(errstr)
+	}
+
+	// prepared, then rolled back because some other target has failed
+	if err := os.RemoveAll(filepath.Join(localdirs[1], "dst")); err != nil {
+		t.Fatal(err)
+	}
+	if errstr := tgt.prepareRenameBucket("src", "dst"); errstr != "" {
+		t.Fatal(errstr)
+	}
+	check("prepare", "dst")
+	if tgt.renaming.enter("src") || tgt.renaming.enter("dst") {
+		t.Error("expected object requests into the buckets being renamed rejected")
+	}
+	if c := getstorstatsrunner().BucketCapacity(); c.Local["dst"] == nil || c.Local["src"] != nil {
+		t.Errorf("expected the counts of the renamed bucket, got %+v", c.Local)
+	}
+	if errstr := tgt.prepareRenameBucket("src", "other"); errstr == "" {
+		t.Error("expected concurrent rename to fail")
+	}
+	if errstr := tgt.rollbackRenameBucket("src", "dst"); errstr != "" {
+		t.Fatal(errstr)
+	}
+	check("rollback", "src")
+	if !tgt.renaming.enter("src") {
+		t.Error("expected object requests accepted once the rename is rolled back")
+	}
+	tgt.renaming.leave("src")
+	if c := getstorstatsrunner().BucketCapacity(); c.Local["src"] == nil || c.Local["src"].Objects != 2 {
+		t.Errorf("expected the counts rolled back, got %+v", c.Local)
+	}
+	if errstr := tgt.commitRenameBucket("src", "dst"); errstr == "" {
+		t.Error("expected commit of the rolled back rename to fail")
+	}
+}
+
+func TestRenamingBucketsHold(t *testing.T) {
+	rb := newRenamingBuckets()
+	if !rb.enter("src") {
+		t.Fatal("expected the request accepted")
+	}
+	if rb.hold(50*time.Millisecond, "src", "dst") {
+		t.Error("expected hold to time out while the request is in progress")
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		rb.leave("src")
+	}()
+	if !rb.hold(time.Second, "src", "dst") {
+		t.Error("expected hold to succeed once the request is done")
+	}
+	rb.release("src", "dst")
+	if !rb.enter("dst") {
+		t.Error("expected the request accepted once released")
+	}
+}
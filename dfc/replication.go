@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
@@ -197,13 +198,16 @@ func (r *mpathReplicator) send(req *replRequest) error {
 		return errors.New(errstr)
 	}
 
+	if injectFault(cmn.FaultPointReplicate, bucket, object) == cmn.FaultDrop {
+		return fmt.Errorf("Replication of %s/%s to %s dropped (injected fault)", bucket, object, req.remoteDirectURL)
+	}
 	url := req.remoteDirectURL + cmn.URLPath(cmn.Version, cmn.Objects, bucket, object)
 
 	uname := cluster.Uname(bucket, object)
 	r.t.rtnamemap.Lock(uname, req.deleteObject)
 	defer r.t.rtnamemap.Unlock(uname, req.deleteObject)
 
-	file, err := os.Open(req.fqn)
+	file, err := openObject(req.fqn)
 	if err != nil {
 		if os.IsPermission(err) {
 			errstr = fmt.Sprintf("Permission denied: access forbidden to %s", req.fqn)
@@ -216,29 +220,40 @@ func (r *mpathReplicator) send(req *replRequest) error {
 	defer file.Close()
 
 	xxHashBinary, errstr := Getxattr(req.fqn, cmn.XattrXXHashVal)
-	xxHashVal := ""
+	xxHashVal, htype := "", r.t.bmdowner.get().cksumType(bucket)
 	if errstr != "" {
+		if !cmn.ValidCksumType(htype) {
+			htype = cmn.ChecksumXXHash
+		}
 		buf, slab := gmem2.AllocFromSlab2(0)
-		xxHashVal, errstr = cmn.ComputeXXHash(file, buf)
+		xxHashVal, errstr = cmn.ComputeCksum(htype, file, buf)
 		slab.Free(buf)
 		if errstr != "" {
 			errstr = fmt.Sprintf("Failed to compute checksum on %s, error: %s", req.fqn, errstr)
 			return errors.New(errstr)
 		}
-		if _, err = file.Seek(0, os.SEEK_SET); err != nil {
-			return fmt.Errorf("Unexpected fseek failure when replicating (sending) %q, err: %v", req.fqn, err)
+		file.Close()
+		if file, err = openObject(req.fqn); err != nil {
+			return fmt.Errorf("Failed to reopen %q when replicating (sending), err: %v", req.fqn, err)
 		}
 	} else {
-		xxHashVal = string(xxHashBinary)
+		htype, xxHashVal = cmn.ParseStoredCksum(xxHashBinary)
 	}
 
-	httpReq, err := http.NewRequest(http.MethodPut, url, file)
+	acct := &cmn.ReplDestStats{Requests: 1}
+	httpReq, err := http.NewRequest(http.MethodPut, url, &replReader{file, acct})
 	if err != nil {
 		errstr = fmt.Sprintf("Failed to create HTTP request, err: %v", err)
 		return errors.New(errstr)
 	}
 	httpReq.GetBody = func() (io.ReadCloser, error) {
-		return os.Open(req.fqn)
+		file, err := openObject(req.fqn)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&acct.Requests, 1)
+		atomic.AddInt64(&acct.Retries, 1)
+		return &replReader{file, acct}, nil
 	}
 	var accessTime time.Time
 	okAccessTime := r.t.bucketLRUEnabled(bucket)
@@ -264,17 +279,21 @@ func (r *mpathReplicator) send(req *replRequest) error {
 	} else if len(version) != 0 {
 		httpReq.Header.Add(cmn.HeaderDFCObjVersion, string(version))
 	}
+	if expires, errstr := Getxattr(req.fqn, cmn.XattrExpires); errstr == "" && len(expires) != 0 {
+		httpReq.Header.Add(cmn.HeaderDFCObjExpires, string(expires))
+	}
 
 	// specify source direct URL in request header
 	httpReq.Header.Add(cmn.HeaderDFCReplicationSrc, r.directURL)
 
-	httpReq.Header.Add(cmn.HeaderDFCChecksumType, cmn.ChecksumXXHash)
+	httpReq.Header.Add(cmn.HeaderDFCChecksumType, htype)
 	httpReq.Header.Add(cmn.HeaderDFCChecksumVal, xxHashVal)
 	if okAccessTime {
 		httpReq.Header.Add(cmn.HeaderDFCObjAtime, string(accessTime.Format(cmn.RFC822)))
 	}
 
 	resp, err := r.t.httpclientLongTimeout.Do(httpReq)
+	r.account(req.remoteDirectURL, acct, err != nil || resp.StatusCode >= http.StatusBadRequest)
 	if err != nil {
 		return err
 	}
@@ -298,15 +317,45 @@ func (r *mpathReplicator) send(req *replRequest) error {
 	}
 
 	if req.deleteObject {
+		r.t.fdCache.invalidate(req.fqn)
+		finfo, _ := os.Stat(req.fqn)
 		if err := os.Remove(req.fqn); err != nil {
 			errstr = fmt.Sprintf("failed to remove local file %s, error: %v", req.fqn, err)
 			return errors.New(errstr)
 		}
+		if finfo != nil {
+			addBucketCapacity(req.fqn, bucket, r.t.bmdowner.get().IsLocal(bucket), -1, -finfo.Size())
+		}
 	}
 
 	return nil
 }
 
+// account adds the request's traffic - including retries - to the destination's stats
+func (r *mpathReplicator) account(dst string, acct *cmn.ReplDestStats, failed bool) {
+	d := &cmn.ReplDestStats{
+		Bytes:    atomic.LoadInt64(&acct.Bytes),
+		Requests: atomic.LoadInt64(&acct.Requests),
+		Retries:  atomic.LoadInt64(&acct.Retries),
+	}
+	if failed {
+		d.Errors = 1
+	}
+	getstorstatsrunner().AddReplication(dst, d)
+}
+
+// replReader counts the bytes of the replica actually sent
+type replReader struct {
+	io.ReadCloser
+	acct *cmn.ReplDestStats
+}
+
+func (rr *replReader) Read(p []byte) (n int, err error) {
+	n, err = rr.ReadCloser.Read(p)
+	atomic.AddInt64(&rr.acct.Bytes, int64(n))
+	return
+}
+
 func (r *mpathReplicator) receive(req *replRequest) error {
 	var (
 		nhobj         cksumvalue
@@ -336,21 +385,22 @@ func (r *mpathReplicator) receive(req *replRequest) error {
 	}
 
 	hdhtype, hdhval := hdhobj.get()
-	if hdhtype != cmn.ChecksumXXHash {
+	if !cmn.ValidCksumType(hdhtype) {
 		errstr = fmt.Sprintf("Unsupported checksum type: %q", hdhtype)
 		return errors.New(errstr)
 	}
 
 	// Avoid replication by checking if cheksums from header and existing file match
 	// Attempt to access the checksum Xattr if it already exists
-	if xxHashBinary, errstr := Getxattr(req.fqn, cmn.XattrXXHashVal); errstr != "" && xxHashBinary != nil && string(xxHashBinary) == hdhval {
+	if xxHashBinary, errstr := Getxattr(req.fqn, cmn.XattrXXHashVal); errstr != "" && xxHashBinary != nil &&
+		string(xxHashBinary) == string(cmn.MakeStoredCksum(hdhtype, hdhval)) {
 		glog.Infof("Existing %s/%s is valid: replication PUT is a no-op", bucket, object)
 		return nil
 	}
 	// Compute the checksum when the Xattr does not exit
-	if file, err := os.Open(req.fqn); err == nil {
+	if file, err := openObject(req.fqn); err == nil {
 		buf, slab := gmem2.AllocFromSlab2(0)
-		xxHashVal, errstr := cmn.ComputeXXHash(file, buf)
+		xxHashVal, errstr := cmn.ComputeCksum(hdhtype, file, buf)
 		slab.Free(buf)
 		if err = file.Close(); err != nil {
 			glog.Warningf("Unexpected failure to close %s once xxhash has been computed, error: %v", req.fqn, err)
@@ -389,6 +439,11 @@ func (r *mpathReplicator) receive(req *replRequest) error {
 	if !accessTime.IsZero() {
 		props.atime = accessTime
 	}
+	if expiresStr := httpr.Header.Get(cmn.HeaderDFCObjExpires); expiresStr != "" {
+		if expires, err := time.Parse(time.RFC3339Nano, expiresStr); err == nil {
+			props.expires = expires
+		}
+	}
 	errstr, _ = r.t.putCommit(r.t.contextWithAuth(httpr), bucket, object, putfqn, req.fqn, props, false /* rebalance */)
 	if errstr != "" {
 		return errors.New(errstr)
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Replication traffic accounting: each target counts the bytes and the requests it sends
+// to each replication destination (remote cluster's direct URL) - including the requests
+// retried and the bytes re-sent by the retries - and reports the counts via
+// GET /v1/daemon?what=replstats. The totals are also tracked by the replication.tx.n and
+// replication.tx.size stats. GET /v1/cluster?what=replstats sums the targets' counts per
+// destination - e.g., to attribute the cost of the cross-region bandwidth. Targets that do
+// not respond are skipped (and logged).
+//
+// ================================ Summary ===============================================
+
+func (p *proxyrunner) invokeHttpGetClusterReplStats(w http.ResponseWriter, r *http.Request) bool {
+	var (
+		smap  = p.smapowner.get()
+		query = url.Values{}
+		out   = &cmn.ClusterReplStats{
+			Destinations: make(cmn.ReplStats),
+			Targets:      make(map[string]cmn.ReplStats, len(smap.Tmap)),
+		}
+	)
+	query.Add(cmn.URLParamWhat, cmn.GetWhatReplStats)
+	results := p.broadcastDash(cmn.URLPath(cmn.Version, cmn.Daemon), query, smap.Tmap)
+	for res := range results {
+		if res.err != nil {
+			glog.Errorf("Failed to get %s replication stats: %s", res.si, res.errstr)
+			continue
+		}
+		stats := make(cmn.ReplStats)
+		if err := jsoniter.Unmarshal(res.outjson, &stats); err != nil {
+			glog.Errorf("Failed to unmarshal %s replication stats, err: %v", res.si, err)
+			continue
+		}
+		out.Targets[res.si.DaemonID] = stats
+		for dst, d := range stats {
+			out.Destinations.Add(dst, d)
+		}
+	}
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterReplStats")
+}
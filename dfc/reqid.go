@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ================================ Summary ===============================================
+//
+// Request IDs: each request served via the public network carries an ID in the
+// DfcRequestID header - the client's own or, if none (or if it is not a short printable
+// string), the one assigned by the daemon: "<daemon ID>-<startup nonce>-<sequence number>".
+// The ID is echoed in the response along with the daemon's ID (DfcNodeID), is logged with
+// the errors, and is included in the JSON errors (cmn.HTTPError) - so that automated
+// clients can correlate their failures with the daemons' logs. A client that follows the
+// proxy's redirect to a target resends its own ID, so that both daemons log the same one.
+//
+// ================================ Summary ===============================================
+
+const maxRequestIDLen = 128
+
+var (
+	reqIDNonce = strconv.FormatInt(time.Now().UnixNano(), 36)
+	reqIDSeq   uint64 // atomic
+)
+
+// withRequestID assigns (or validates) the request's ID and echoes it in the response
+func (h *httprunner) withRequestID(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(cmn.HeaderDFCRequestID)
+		if !validRequestID(id) {
+			id = h.si.DaemonID + "-" + reqIDNonce + "-" + strconv.FormatUint(atomic.AddUint64(&reqIDSeq, 1), 36)
+			r.Header.Set(cmn.HeaderDFCRequestID, id)
+		}
+		w.Header().Set(cmn.HeaderDFCRequestID, id)
+		w.Header().Set(cmn.HeaderDFCNodeID, h.si.DaemonID)
+		handler(w, r)
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
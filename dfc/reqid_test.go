@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestWithRequestID(t *testing.T) {
+	var (
+		h       = &httprunner{si: &cluster.Snode{DaemonID: "t1"}}
+		seen    string
+		handler = h.withRequestID(func(w http.ResponseWriter, r *http.Request) {
+			seen = r.Header.Get(cmn.HeaderDFCRequestID)
+		})
+	)
+	do := func(id string) http.Header {
+		r := httptest.NewRequest(http.MethodGet, "/v1/daemon", nil)
+		if id != "" {
+			r.Header.Set(cmn.HeaderDFCRequestID, id)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Header()
+	}
+
+	// the client's own: echoed
+	hdr := do("client-42")
+	if seen != "client-42" || hdr.Get(cmn.HeaderDFCRequestID) != "client-42" || hdr.Get(cmn.HeaderDFCNodeID) != "t1" {
+		t.Errorf("expected the client's ID echoed, got %q, %v", seen, hdr)
+	}
+
+	// none or invalid: assigned, unique
+	ids := make(map[string]bool)
+	for _, id := range []string{"", "bad id", strings.Repeat("x", maxRequestIDLen+1), ""} {
+		hdr = do(id)
+		assigned := hdr.Get(cmn.HeaderDFCRequestID)
+		if !strings.HasPrefix(assigned, "t1-") || assigned != seen || ids[assigned] {
+			t.Errorf("%q: expected a new ID assigned, got %q (handler saw %q)", id, assigned, seen)
+		}
+		ids[assigned] = true
+	}
+}
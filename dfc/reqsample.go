@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ================================ Summary ===============================================
+//
+// Request sampling: to diagnose intermittent slowness without tracing every request, a
+// bucket can have a fraction of its object requests (GET, PUT, DELETE, and HEAD) sampled by
+// the proxies - BucketProps.DebugSample, from 0 (default, disabled) to 1 (all requests).
+// For a sampled request the proxy records the time spent in each of its phases:
+//   - validate: parsing the request and validating the bucket
+//   - hrw:      selecting the target out of the cluster map
+//   - redirect: redirecting the client to the target (or reverse-proxying the request)
+// along with the target it has chosen. Each proxy keeps its last reqSampleBufSize samples,
+// of all buckets, in memory and returns them, oldest first, via
+// GET /v1/daemon?what=reqsamples[&bucket=name].
+//
+// ================================ Summary ===============================================
+
+const reqSampleBufSize = 1024
+
+const (
+	reqPhaseValidate = "validate"
+	reqPhaseHRW      = "hrw"
+	reqPhaseRedirect = "redirect"
+)
+
+type (
+	// reqSampler is the rolling buffer of the proxy's request samples
+	reqSampler struct {
+		sync.Mutex
+		samples []cmn.ReqSample
+		next    int // index of the next (and, when full, the oldest) sample
+		count   int
+	}
+	// reqTimer times the phases of a sampled request; nil for the requests not sampled
+	reqTimer struct {
+		sample cmn.ReqSample
+		last   time.Time
+	}
+)
+
+// sampleReq returns the timer of the request if the bucket's debug_sample selects it, nil otherwise
+func (p *proxyrunner) sampleReq(r *http.Request, bucket, objname string, started time.Time) *reqTimer {
+	bucketmd := p.bmdowner.get()
+	defined, props := bucketmd.get(bucket, bucketmd.IsLocal(bucket))
+	if !defined || props.DebugSample <= 0 || rand.Float64() >= props.DebugSample {
+		return nil
+	}
+	return &reqTimer{
+		sample: cmn.ReqSample{Time: started, Method: r.Method, Bucket: bucket, Object: objname,
+			Phases: make([]cmn.ReqPhase, 0, 3)},
+		last: started,
+	}
+}
+
+// phase records the time since the previous phase
+func (rt *reqTimer) phase(name string) {
+	if rt == nil {
+		return
+	}
+	now := time.Now()
+	rt.sample.Phases = append(rt.sample.Phases, cmn.ReqPhase{Name: name, Latency: now.Sub(rt.last)})
+	rt.last = now
+}
+
+// done completes the sample and adds it to the proxy's buffer
+func (rt *reqTimer) done(p *proxyrunner, target string) {
+	if rt == nil {
+		return
+	}
+	rt.sample.Target = target
+	rt.sample.Total = rt.last.Sub(rt.sample.Time)
+	p.reqsamples.add(rt.sample)
+}
+
+func (s *reqSampler) add(sample cmn.ReqSample) {
+	s.Lock()
+	if s.samples == nil {
+		s.samples = make([]cmn.ReqSample, reqSampleBufSize)
+	}
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % len(s.samples)
+	if s.count < len(s.samples) {
+		s.count++
+	}
+	s.Unlock()
+}
+
+// get returns the samples of the given bucket (all buckets if empty), oldest first
+func (s *reqSampler) get(bucket string) []cmn.ReqSample {
+	s.Lock()
+	defer s.Unlock()
+	samples := make([]cmn.ReqSample, 0, s.count)
+	start := s.next - s.count
+	if start < 0 {
+		start += len(s.samples)
+	}
+	for i := 0; i < s.count; i++ {
+		sample := s.samples[(start+i)%len(s.samples)]
+		if bucket == "" || sample.Bucket == bucket {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestReqSampling(t *testing.T) {
+	p := &proxyrunner{}
+	bucketmd := newBucketMD()
+	bucketmd.add("sampled", true, cmn.BucketProps{CloudProvider: cmn.ProviderDFC, DebugSample: 1})
+	bucketmd.add("other", true, cmn.BucketProps{CloudProvider: cmn.ProviderDFC})
+	p.bmdowner = &bmdowner{}
+	p.bmdowner.put(bucketmd)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/objects/other/obj", nil)
+	if rt := p.sampleReq(r, "other", "obj", time.Now()); rt != nil {
+		t.Fatal("expected the bucket without debug_sample not sampled")
+	}
+	for i := 0; i < reqSampleBufSize+2; i++ {
+		objname := strconv.Itoa(i)
+		rt := p.sampleReq(r, "sampled", objname, time.Now())
+		if rt == nil {
+			t.Fatal("expected the bucket with debug_sample=1 sampled")
+		}
+		rt.phase(reqPhaseValidate)
+		rt.phase(reqPhaseHRW)
+		rt.phase(reqPhaseRedirect)
+		rt.done(p, "t1")
+	}
+
+	samples := p.reqsamples.get("sampled")
+	if len(samples) != reqSampleBufSize || samples[0].Object != "2" || samples[len(samples)-1].Object != strconv.Itoa(reqSampleBufSize+1) {
+		t.Fatalf("expected the last %d samples, oldest first, got %d: %s..%s", reqSampleBufSize, len(samples),
+			samples[0].Object, samples[len(samples)-1].Object)
+	}
+	s := samples[0]
+	if s.Method != http.MethodGet || s.Target != "t1" || len(s.Phases) != 3 || s.Phases[1].Name != reqPhaseHRW {
+		t.Errorf("unexpected sample %+v", s)
+	}
+	var sum time.Duration
+	for _, phase := range s.Phases {
+		sum += phase.Latency
+	}
+	if sum != s.Total {
+		t.Errorf("expected the phases to add up to the total %v, got %v", s.Total, sum)
+	}
+	if samples = p.reqsamples.get("other"); len(samples) != 0 {
+		t.Errorf("expected no samples of the other bucket, got %d", len(samples))
+	}
+}
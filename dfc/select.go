@@ -0,0 +1,537 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Server-side select (a subset of S3 Select): the target reads a CSV or JSON-lines object
+// in a streaming fashion and sends back only the rows that match the "where" expression
+// and, optionally, only the requested fields. The object is never loaded into memory:
+// rows are parsed one at a time, and the output is accumulated in a memsys buffer.
+//
+// Expression: cond [AND cond]...; cond: field op value; op: = != < <= > >= contains
+// Values may be quoted with single or double quotes.
+//
+// ================================ Summary ===============================================
+
+const selectMaxLine = 16 * cmn.MiB // max size of a single JSON line
+
+var selectOps = []string{"<=", ">=", "!=", "=", "<", ">"}
+
+type (
+	selectCond struct {
+		field string
+		op    string
+		value string
+		num   float64
+		isnum bool
+	}
+	selectQuery struct {
+		msg   *cmn.SelectMsg
+		conds []*selectCond
+		delim rune
+	}
+	// selectWriter buffers the output in a (memsys) buffer
+	selectWriter struct {
+		w   io.Writer
+		buf []byte
+		n   int
+	}
+)
+
+//
+// expression
+//
+
+func newSelectQuery(msg *cmn.SelectMsg) (*selectQuery, error) {
+	q := &selectQuery{msg: msg, delim: ','}
+	switch msg.Format {
+	case cmn.SelectFormatCSV:
+		if msg.Delimiter != "" {
+			r, size := utf8.DecodeRuneInString(msg.Delimiter)
+			if size != len(msg.Delimiter) || r == '"' || r == '\n' || r == '\r' {
+				return nil, fmt.Errorf("Invalid CSV delimiter %q", msg.Delimiter)
+			}
+			q.delim = r
+		}
+	case cmn.SelectFormatJSON:
+	default:
+		return nil, fmt.Errorf("Invalid select format %q (expecting %s or %s)", msg.Format, cmn.SelectFormatCSV, cmn.SelectFormatJSON)
+	}
+	for _, s := range splitSelectExpr(msg.Where) {
+		cond, err := parseSelectCond(s)
+		if err != nil {
+			return nil, err
+		}
+		q.conds = append(q.conds, cond)
+	}
+	return q, nil
+}
+
+// splitSelectExpr splits the expression by (case-insensitive) AND outside of quotes
+func splitSelectExpr(expr string) (conds []string) {
+	var (
+		quote rune
+		start int
+	)
+	for i, c := range expr {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ' ' && i+4 <= len(expr) && strings.EqualFold(expr[i:i+4], " and") && (i+4 == len(expr) || expr[i+4] == ' '):
+			conds = append(conds, expr[start:i])
+			start = i + 4
+		}
+	}
+	if s := strings.TrimSpace(expr[start:]); s != "" || len(conds) > 0 {
+		conds = append(conds, expr[start:])
+	}
+	return
+}
+
+func parseSelectCond(s string) (*selectCond, error) {
+	cond := &selectCond{}
+	for i := 0; i < len(s) && cond.op == ""; i++ {
+		if s[i] == '\'' || s[i] == '"' {
+			break // operator must precede the value
+		}
+		if i > 0 && i+len(" contains ") <= len(s) && strings.EqualFold(s[i-1:i+len("contains ")], " contains ") {
+			cond.field, cond.op, cond.value = s[:i-1], "contains", s[i+len("contains "):]
+			break
+		}
+		for _, op := range selectOps {
+			if strings.HasPrefix(s[i:], op) {
+				cond.field, cond.op, cond.value = s[:i], op, s[i+len(op):]
+				break
+			}
+		}
+	}
+	cond.field, cond.value = strings.TrimSpace(cond.field), strings.TrimSpace(cond.value)
+	if cond.op == "" || cond.field == "" || cond.value == "" {
+		return nil, fmt.Errorf("Invalid select condition %q: expecting <field> <op> <value>", strings.TrimSpace(s))
+	}
+	if l := len(cond.value); l >= 2 && (cond.value[0] == '\'' || cond.value[0] == '"') && cond.value[l-1] == cond.value[0] {
+		cond.value = cond.value[1 : l-1]
+	} else if f, err := strconv.ParseFloat(cond.value, 64); err == nil {
+		cond.num, cond.isnum = f, true
+	}
+	return cond, nil
+}
+
+func (c *selectCond) match(v string, exists bool) bool {
+	if !exists {
+		return c.op == "!="
+	}
+	if c.op == "contains" {
+		return strings.Contains(v, c.value)
+	}
+	cmp := strings.Compare(v, c.value)
+	if c.isnum {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			switch {
+			case f < c.num:
+				cmp = -1
+			case f > c.num:
+				cmp = 1
+			default:
+				cmp = 0
+			}
+		}
+	}
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // ">="
+		return cmp >= 0
+	}
+}
+
+//
+// streaming
+//
+
+func (sw *selectWriter) Write(p []byte) (int, error) {
+	if sw.n+len(p) > len(sw.buf) {
+		if err := sw.flush(); err != nil {
+			return 0, err
+		}
+		if len(p) > len(sw.buf) {
+			return sw.w.Write(p)
+		}
+	}
+	sw.n += copy(sw.buf[sw.n:], p)
+	return len(p), nil
+}
+
+func (sw *selectWriter) WriteString(s string) (int, error) {
+	if sw.n+len(s) > len(sw.buf) {
+		return sw.Write([]byte(s))
+	}
+	sw.n += copy(sw.buf[sw.n:], s)
+	return len(s), nil
+}
+
+func (sw *selectWriter) flush() (err error) {
+	if sw.n > 0 {
+		_, err = sw.w.Write(sw.buf[:sw.n])
+		sw.n = 0
+	}
+	return
+}
+
+// run reads from r and writes the selected rows to sw; returns the number of rows written
+func (q *selectQuery) run(r io.Reader, sw *selectWriter, buf []byte) (rows int64, err error) {
+	if q.msg.Format == cmn.SelectFormatCSV {
+		rows, err = q.selectCSV(r, sw)
+	} else {
+		rows, err = q.selectJSON(r, sw, buf)
+	}
+	if err == nil {
+		err = sw.flush()
+	}
+	return
+}
+
+func (q *selectQuery) selectCSV(r io.Reader, sw *selectWriter) (rows int64, err error) {
+	var (
+		cr      = csv.NewReader(r)
+		outIdx  []int // columns to return
+		condIdx []int // columns the conditions refer to
+	)
+	cr.Comma, cr.FieldsPerRecord, cr.ReuseRecord = q.delim, -1, true
+	resolve := func(header []string) error {
+		column := func(name string) (int, error) {
+			for i, h := range header {
+				if h == name {
+					return i, nil
+				}
+			}
+			if strings.HasPrefix(name, "_") {
+				if n, err := strconv.Atoi(name[1:]); err == nil && n > 0 {
+					return n - 1, nil
+				}
+			}
+			return 0, fmt.Errorf("Unknown CSV column %q", name)
+		}
+		for _, name := range q.msg.Fields {
+			idx, err := column(name)
+			if err != nil {
+				return err
+			}
+			outIdx = append(outIdx, idx)
+		}
+		for _, cond := range q.conds {
+			idx, err := column(cond.field)
+			if err != nil {
+				return err
+			}
+			condIdx = append(condIdx, idx)
+		}
+		return nil
+	}
+	if !q.msg.Header {
+		if err = resolve(nil); err != nil {
+			return
+		}
+	}
+	for first := true; q.msg.Limit == 0 || rows < q.msg.Limit; first = false {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, err
+		}
+		if first && q.msg.Header {
+			if err = resolve(record); err != nil {
+				return rows, err
+			}
+		} else if !q.matchCSV(record, condIdx) {
+			continue
+		} else {
+			rows++
+		}
+		if err = q.writeCSV(record, outIdx, sw); err != nil {
+			return rows, err
+		}
+	}
+	return
+}
+
+func (q *selectQuery) matchCSV(record []string, condIdx []int) bool {
+	for i, cond := range q.conds {
+		idx := condIdx[i]
+		if idx < len(record) {
+			if !cond.match(record[idx], true) {
+				return false
+			}
+		} else if !cond.match("", false) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *selectQuery) writeCSV(record []string, outIdx []int, sw *selectWriter) (err error) {
+	writeField := func(i int, field string) {
+		if i > 0 {
+			sw.WriteString(string(q.delim))
+		}
+		if field == "" || !strings.ContainsAny(field, string(q.delim)+"\"\r\n") && field[0] != ' ' {
+			sw.WriteString(field)
+			return
+		}
+		sw.WriteString("\"" + strings.Replace(field, "\"", "\"\"", -1) + "\"")
+	}
+	if len(outIdx) == 0 {
+		for i, field := range record {
+			writeField(i, field)
+		}
+	} else {
+		for i, idx := range outIdx {
+			if idx < len(record) {
+				writeField(i, record[idx])
+			} else {
+				writeField(i, "")
+			}
+		}
+	}
+	_, err = sw.WriteString("\n")
+	return
+}
+
+func (q *selectQuery) selectJSON(r io.Reader, sw *selectWriter, buf []byte) (rows int64, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(buf, selectMaxLine)
+	for lineno := 1; scanner.Scan() && (q.msg.Limit == 0 || rows < q.msg.Limit); lineno++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if !jsoniter.Valid(line) || jsoniter.Get(line).ValueType() != jsoniter.ObjectValue {
+			return rows, fmt.Errorf("Invalid JSON object at line %d", lineno)
+		}
+		matched := true
+		for _, cond := range q.conds {
+			v := jsonSelectField(line, cond.field)
+			if !cond.match(jsonSelectString(v), v.ValueType() != jsoniter.InvalidValue) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		rows++
+		if len(q.msg.Fields) == 0 {
+			sw.Write(line)
+		} else {
+			sw.WriteString("{")
+			for i, name := range q.msg.Fields {
+				if i > 0 {
+					sw.WriteString(",")
+				}
+				key, _ := jsoniter.Marshal(name)
+				sw.Write(key)
+				sw.WriteString(":")
+				sw.WriteString(jsonSelectRaw(jsonSelectField(line, name)))
+			}
+			sw.WriteString("}")
+		}
+		if _, err = sw.WriteString("\n"); err != nil {
+			return
+		}
+	}
+	return rows, scanner.Err()
+}
+
+// jsonSelectField looks up the field - by its full name first and, if not found,
+// as a dot-separated path into the nested objects
+func jsonSelectField(line []byte, name string) jsoniter.Any {
+	v := jsoniter.Get(line, name)
+	if v.ValueType() != jsoniter.InvalidValue || !strings.Contains(name, ".") {
+		return v
+	}
+	keys := strings.Split(name, ".")
+	path := make([]interface{}, len(keys))
+	for i, key := range keys {
+		path[i] = key
+	}
+	return jsoniter.Get(line, path...)
+}
+
+// jsonSelectString returns the value to compare: strings unquoted, everything else as is
+func jsonSelectString(v jsoniter.Any) string {
+	switch v.ValueType() {
+	case jsoniter.InvalidValue:
+		return ""
+	case jsoniter.NilValue:
+		return "null"
+	default:
+		return v.ToString()
+	}
+}
+
+// jsonSelectRaw returns the value's JSON representation; missing fields are null
+func jsonSelectRaw(v jsoniter.Any) string {
+	switch v.ValueType() {
+	case jsoniter.InvalidValue, jsoniter.NilValue:
+		return "null"
+	case jsoniter.StringValue:
+		b, _ := jsoniter.Marshal(v.ToString())
+		return string(b)
+	default:
+		return v.ToString()
+	}
+}
+
+//
+// target
+//
+
+// POST {"action": "select", "value": {...}} /v1/objects/bucket-name/object-name
+func (t *targetrunner) selectObject(w http.ResponseWriter, r *http.Request, msg cmn.ActionMsg) {
+	started := time.Now()
+	apitems, err := t.checkRESTItems(w, r, 2, false, cmn.Version, cmn.Objects)
+	if err != nil {
+		return
+	}
+	bucket, objname := apitems[0], apitems[1]
+	if !t.validatebckname(w, r, bucket) {
+		return
+	}
+	selmsg := &cmn.SelectMsg{}
+	b, err := jsoniter.Marshal(msg.Value)
+	if err == nil {
+		err = jsoniter.Unmarshal(b, selmsg)
+	}
+	if err != nil {
+		t.invalmsghdlr(w, r, fmt.Sprintf("Invalid select message value %+v, err: %v", msg.Value, err))
+		return
+	}
+	q, err := newSelectQuery(selmsg)
+	if err != nil {
+		t.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	islocal := t.bmdowner.get().IsLocal(bucket)
+	fqn, errstr := cluster.FQN(bucket, objname, islocal)
+	if errstr != "" {
+		t.invalmsghdlr(w, r, errstr)
+		return
+	}
+	uname := cluster.Uname(bucket, objname)
+	t.rtnamemap.Lock(uname, false)
+	coldget, _, _, errstr := t.lookupLocally(bucket, objname, fqn)
+	if errstr != "" && (islocal || !coldget) {
+		t.rtnamemap.Unlock(uname, false)
+		errcode := http.StatusInternalServerError
+		if strings.Contains(errstr, doesnotexist) {
+			errcode = http.StatusNotFound
+		}
+		t.invalmsghdlr(w, r, errstr, errcode)
+		return
+	}
+	if coldget {
+		var errcode int
+		t.rtnamemap.Unlock(uname, false)
+		if _, errstr, errcode = t.coldget(t.contextWithAuth(r), bucket, objname, false); errstr != "" {
+			if errcode == 0 {
+				t.invalmsghdlr(w, r, errstr)
+			} else {
+				t.invalmsghdlr(w, r, errstr, errcode)
+			}
+			return
+		}
+		// NOTE: coldget() keeps the read lock if successful
+	}
+	defer t.rtnamemap.Unlock(uname, false)
+
+	file, err := openObject(fqn)
+	if err != nil {
+		t.fshc(err, fqn)
+		t.invalmsghdlr(w, r, fmt.Sprintf("Failed to open %s, err: %v", fqn, err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	slab := gmem2.SelectSlab2(cmn.MiB)
+	scanbuf, outbuf := slab.Alloc(), slab.Alloc()
+	defer func() {
+		slab.Free(scanbuf)
+		slab.Free(outbuf)
+	}()
+	if selmsg.Format == cmn.SelectFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	rows, err := q.run(file, &selectWriter{w: w, buf: outbuf}, scanbuf)
+	if err != nil {
+		// the response may have been partially sent already
+		errstr = fmt.Sprintf("Failed to select from %s/%s, err: %v", bucket, objname, err)
+		t.invalmsghdlr(w, r, errstr)
+		return
+	}
+	if glog.V(4) {
+		glog.Infof("SELECT %s/%s: %d rows, %d µs", bucket, objname, rows, int64(time.Since(started)/time.Microsecond))
+	}
+}
+
+//
+// proxy
+//
+
+// POST {"action": "select", "value": {...}} /v1/objects/bucket-name/object-name
+func (p *proxyrunner) selectObject(w http.ResponseWriter, r *http.Request) {
+	started := time.Now()
+	apitems, err := p.checkRESTItems(w, r, 2, false, cmn.Version, cmn.Objects)
+	if err != nil {
+		return
+	}
+	bucket, objname := apitems[0], apitems[1]
+	si, errstr := hrwTarget(bucket, objname, p.smapowner.get())
+	if errstr != "" {
+		p.invalmsghdlr(w, r, errstr)
+		return
+	}
+	if glog.V(4) {
+		glog.Infof("SELECT %s/%s => %s", bucket, objname, si.DaemonID)
+	}
+	// 307 to preserve the JSON payload
+	redirecturl := p.redirectURL(r, si.PublicNet.DirectURL, started, bucket)
+	http.Redirect(w, r, redirecturl, http.StatusTemporaryRedirect)
+}
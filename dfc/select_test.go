@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+const (
+	selectCSV = `name,age,city
+alice,31,"San Jose"
+bob,25,Austin
+"carol, jr",42,"New ""York"""
+dave,7,Austin
+`
+	selectJSON = `{"name":"alice","age":31,"addr":{"city":"San Jose"}}
+{"name":"bob","age":25,"addr":{"city":"Austin"}}
+
+{"name":"carol","age":42,"addr":{"city":"New York"},"vip":true}
+{"name":"dave","age":7}
+`
+)
+
+func runSelect(t *testing.T, msg *cmn.SelectMsg, in string) (string, int64) {
+	q, err := newSelectQuery(msg)
+	if err != nil {
+		t.Fatalf("Failed to parse %+v: %v", msg, err)
+	}
+	out := &bytes.Buffer{}
+	// small buffer to exercise flushing
+	rows, err := q.run(strings.NewReader(in), &selectWriter{w: out, buf: make([]byte, 16)}, make([]byte, 64))
+	if err != nil {
+		t.Fatalf("Failed to select %+v: %v", msg, err)
+	}
+	return out.String(), rows
+}
+
+func TestSelectParse(t *testing.T) {
+	tests := []struct {
+		where string
+		conds []selectCond
+	}{
+		{"", nil},
+		{"age>=30", []selectCond{{field: "age", op: ">=", value: "30", num: 30, isnum: true}}},
+		{"city = 'Sand and Stone' AND age < 5", []selectCond{
+			{field: "city", op: "=", value: "Sand and Stone"},
+			{field: "age", op: "<", value: "5", num: 5, isnum: true},
+		}},
+		{`name contains "a=b" and x != "1"`, []selectCond{
+			{field: "name", op: "contains", value: "a=b"},
+			{field: "x", op: "!=", value: "1"},
+		}},
+	}
+	for _, tt := range tests {
+		q, err := newSelectQuery(&cmn.SelectMsg{Format: cmn.SelectFormatCSV, Where: tt.where})
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.where, err)
+			continue
+		}
+		if len(q.conds) != len(tt.conds) {
+			t.Errorf("%q: expected %d conditions, got %d", tt.where, len(tt.conds), len(q.conds))
+			continue
+		}
+		for i, cond := range q.conds {
+			if *cond != tt.conds[i] {
+				t.Errorf("%q: expected %+v, got %+v", tt.where, tt.conds[i], *cond)
+			}
+		}
+	}
+
+	for _, msg := range []cmn.SelectMsg{
+		{Format: "xml"},
+		{Format: cmn.SelectFormatCSV, Delimiter: "||"},
+		{Format: cmn.SelectFormatCSV, Where: "age"},
+		{Format: cmn.SelectFormatJSON, Where: "age > 1 AND"},
+		{Format: cmn.SelectFormatJSON, Where: "= 1"},
+		{Format: cmn.SelectFormatJSON, Where: "age >"},
+	} {
+		if _, err := newSelectQuery(&msg); err == nil {
+			t.Errorf("Expected an error parsing %+v", msg)
+		}
+	}
+}
+
+func TestSelectCSV(t *testing.T) {
+	tests := []struct {
+		msg  cmn.SelectMsg
+		out  string
+		rows int64
+	}{
+		{cmn.SelectMsg{Header: true, Where: "age > 30"},
+			"name,age,city\nalice,31,San Jose\n\"carol, jr\",42,\"New \"\"York\"\"\"\n", 2},
+		{cmn.SelectMsg{Header: true, Fields: []string{"city", "name"}, Where: "city = Austin"},
+			"city,name\nAustin,bob\nAustin,dave\n", 2},
+		{cmn.SelectMsg{Header: true, Fields: []string{"name"}, Where: "age < 30", Limit: 1},
+			"name\nbob\n", 1},
+		// no header: the first line is data, and "age" compares as a string (greater than "31")
+		{cmn.SelectMsg{Fields: []string{"_1"}, Where: "_2 >= 31"},
+			"name\nalice\n\"carol, jr\"\n", 3},
+	}
+	for _, tt := range tests {
+		tt.msg.Format = cmn.SelectFormatCSV
+		out, rows := runSelect(t, &tt.msg, selectCSV)
+		if out != tt.out || rows != tt.rows {
+			t.Errorf("%+v: expected (%q, %d), got (%q, %d)", tt.msg, tt.out, tt.rows, out, rows)
+		}
+	}
+
+	msg := &cmn.SelectMsg{Format: cmn.SelectFormatCSV, Header: true, Fields: []string{"zip"}}
+	q, _ := newSelectQuery(msg)
+	if _, err := q.run(strings.NewReader(selectCSV), &selectWriter{w: &bytes.Buffer{}, buf: make([]byte, 16)}, nil); err == nil {
+		t.Errorf("Expected an error selecting unknown column")
+	}
+}
+
+func TestSelectJSON(t *testing.T) {
+	tests := []struct {
+		msg  cmn.SelectMsg
+		out  string
+		rows int64
+	}{
+		{cmn.SelectMsg{Where: "addr.city = Austin"},
+			`{"name":"bob","age":25,"addr":{"city":"Austin"}}` + "\n", 1},
+		{cmn.SelectMsg{Fields: []string{"name", "addr.city"}, Where: "age >= 25"},
+			`{"name":"alice","addr.city":"San Jose"}` + "\n" +
+				`{"name":"bob","addr.city":"Austin"}` + "\n" +
+				`{"name":"carol","addr.city":"New York"}` + "\n", 3},
+		{cmn.SelectMsg{Fields: []string{"name"}, Where: "vip != true"},
+			`{"name":"alice"}` + "\n" + `{"name":"bob"}` + "\n" + `{"name":"dave"}` + "\n", 3},
+		{cmn.SelectMsg{Fields: []string{"addr"}, Where: "name contains o", Limit: 1},
+			`{"addr":{"city":"Austin"}}` + "\n", 1},
+	}
+	for _, tt := range tests {
+		tt.msg.Format = cmn.SelectFormatJSON
+		out, rows := runSelect(t, &tt.msg, selectJSON)
+		if out != tt.out || rows != tt.rows {
+			t.Errorf("%+v: expected (%q, %d), got (%q, %d)", tt.msg, tt.out, tt.rows, out, rows)
+		}
+	}
+}
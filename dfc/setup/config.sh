@@ -9,7 +9,8 @@ cat > $CONFFILE <<EOL
 		"rahtotalmem":		1073741824,
 		"rahbyproxy":		true,
 		"rahdiscard":		false,
-		"rahenabled":		false
+		"rahenabled":		false,
+		"rahhotobjects":	0
 	},
 	"log": {
 		"logdir":		"$LOGDIR",
@@ -19,7 +20,16 @@ cat > $CONFFILE <<EOL
 	},
 	"periodic": {
 		"stats_time":		"10s",
-		"retry_sync_time":	"2s"
+		"stats_latency_unit":	"us",
+		"retry_sync_time":	"2s",
+		"iostat_history":	"10m",
+		"stats_history":	"24h",
+		"iostat_external":	false,
+		"stats_log_full":	6,
+		"stats_log_level":	"",
+		"stats_log_format":	"text",
+		"stats_log_file":	"",
+		"stats_log_max_size":	67108864
 	},
 	"timeout": {
 		"default_timeout":	"30s",
@@ -34,7 +44,11 @@ cat > $CONFFILE <<EOL
 		"non_electable":	${NON_ELECTABLE},
 		"primary_url":		"${PROXYURL}",
 		"original_url": 	"${PROXYURL}",
-		"discovery_url": 	"${DISCOVERYURL}"
+		"discovery_url": 	"${DISCOVERYURL}",
+		"discovery": {
+			"provider":	"",
+			"name":		""
+		}
 	},
 	"lru_config": {
 		"lowwm":		75,
@@ -42,7 +56,8 @@ cat > $CONFFILE <<EOL
 		"atime_cache_max":	65536,
 		"dont_evict_time":	"120m",
 		"capacity_upd_time":	"10m",
-		"lru_enabled":  	true
+		"lru_enabled":  	true,
+		"hot_access_count":	0
 	},
 	"xaction_config":{
 	    "disk_util_low_wm":      60,
@@ -50,7 +65,9 @@ cat > $CONFFILE <<EOL
 	},
 	"rebalance_conf": {
 		"dest_retry_time":	"2m",
-		"rebalancing_enabled": 	true
+		"rebalancing_enabled": 	true,
+		"require_confirmation":	false,
+		"order":		"none"
 	},
 	"replication": {
 		"replicate_on_cold_get": 		false,
@@ -61,7 +78,8 @@ cat > $CONFFILE <<EOL
 		"checksum":                    "xxhash",
 		"validate_checksum_cold_get":  true,
 		"validate_checksum_warm_get":  false,
-		"enable_read_range_checksum":  false
+		"enable_read_range_checksum":  false,
+		"async_checksum_put":          false
 	},
 	"version_config": {
 		"validate_version_warm_get":    false,
@@ -91,7 +109,13 @@ cat > $CONFFILE <<EOL
 			"server_certificate":	"server.crt",
 			"server_key":		"server.key",
 			"max_num_targets":	16,
-			"use_https":		${USE_HTTPS}
+			"use_https":		${USE_HTTPS},
+			"intra_client": {
+				"idle_conns_per_peer":	32,
+				"dial_timeout":		"30s",
+				"retries":		1,
+				"retry_delay":		"100ms"
+			}
 		}
 	},
 	"fshc": {
@@ -114,7 +138,115 @@ cat > $CONFFILE <<EOL
 			"interval": "10s",
 			"name": "heartbeat",
 			"factor": 3
+		},
+		"suspect_after":	1,
+		"rebalance_grace":	"0s",
+		"target_alert_only":	false
+	},
+	"disk_config": {
+		"reserved_space":	"0",
+		"reserved_space_mpath":	{},
+		"sync_group_time":	"10ms",
+		"prune_dirs_time":	"1h",
+		"critical_wm":		0
+	},
+	"cold_get": {
+		"part_size":	"64MB",
+		"concurrency":	4
+	},
+	"list_cache": {
+		"ttl":		"1m",
+		"max_pages":	1024
+	},
+	"mmap_read": {
+		"enabled":		false,
+		"max_object_size":	"1MB",
+		"cache_size":		"256MB"
+	},
+	"fd_cache": {
+		"enabled":	false,
+		"size":		1024
+	},
+	"md_cache": {
+		"enabled":		false,
+		"size":			65536,
+		"snapshot_time":	"10m"
+	},
+	"direct_io": {
+		"enabled":		false,
+		"min_object_size":	"64MB"
+	},
+	"atime_journal": {
+		"enabled":	false,
+		"sync_time":	"1s"
+	},
+	"atime_drain": {
+		"enabled":	false,
+		"timeout":	"1m"
+	},
+	"atime_flush": {
+		"rate":		0
+	},
+	"endpoint_limits": {
+		"list":			16,
+		"stats":		8,
+		"xaction":		8,
+		"queue_timeout":	"1m"
+	},
+	"compression": {
+		"enabled":		false,
+		"cold_time":		"720h",
+		"run_time":		"24h",
+		"decompress_hot":	true
+	},
+	"put_dedup": {
+		"ttl":		"10m",
+		"max_keys":	65536
+	},
+	"obj_ttl": {
+		"run_time":	"1h"
+	},
+	"qos": {
+		"enabled":	false,
+		"iops":		0,
+		"mbps":		0
+	},
+	"smart": {
+		"enabled":		false,
+		"interval":		"10m",
+		"reallocated_sectors":	0,
+		"media_errors":		0,
+		"temperature":		0
+	},
+	"cors": {
+		"allowed_origins":	[],
+		"max_age":		"10m"
+	},
+	"presign": {
+		"secret":	"",
+		"max_expiry":	"24h"
+	},
+	"statsd": {
+		"address":		"localhost:8125",
+		"flush_interval":	"100ms",
+		"max_packet_size":	1432,
+		"tags":			"",
+		"resolve_interval":	"1m"
+	},
+	"metrics": {
+		"sink":			"statsd",
+		"graphite": {
+			"address":		"localhost:2003",
+			"flush_interval":	"10s",
+			"tags":			false
 		}
+	},
+	"slo": {
+		"window":	"24h",
+		"objectives": [
+			{"name": "get-latency",	"kind": "latency",	"stat": "get.lat",	"threshold": "50ms",	"target": 0.99},
+			{"name": "get-errors",	"kind": "error_rate",	"stat": "get.n",	"err_stat": "err.get.n",	"target": 0.999}
+		]
 	}
 }
 EOL
@@ -179,7 +179,8 @@ BUILD=`git rev-parse --short HEAD`
 if [ "$ENABLE_CODE_COVERAGE" == "" ]
 then
 	EXE=$GOPATH/bin/dfc
-	go build && go install && GOBIN=$GOPATH/bin go install -ldflags "-X github.com/NVIDIA/dfcpub/dfc.build=$BUILD" setup/dfc.go
+	# TAGS="faultinject" builds the fault injection API (see dfc/faultinject.go) - for testing only
+	go build -tags "$TAGS" && go install -tags "$TAGS" && GOBIN=$GOPATH/bin go install -tags "$TAGS" -ldflags "-X github.com/NVIDIA/dfcpub/dfc.build=$BUILD" setup/dfc.go
 else
 	echo "Note: code test-coverage enabled!"
 	EXE=$GOPATH/bin/dfc_coverage.test
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Service level objectives: each daemon evaluates the configured objectives (slo.objectives)
+// continuously out of its own stats, and reports their state via GET /v1/daemon?what=slo.
+// GET /v1/cluster?what=slo collects the daemons' reports and evaluates each objective over
+// the sum of the daemons' events - e.g., the cluster-wide GET error rate is the total number
+// of failed GETs divided by the total number of GETs. Daemons that do not respond are
+// skipped (and logged).
+//
+// ================================ Summary ===============================================
+
+func (p *proxyrunner) invokeHttpGetClusterSLO(w http.ResponseWriter, r *http.Request) bool {
+	var (
+		smap  = p.smapowner.get()
+		path  = cmn.URLPath(cmn.Version, cmn.Daemon)
+		query = url.Values{}
+		out   = &cmn.ClusterSLOReport{
+			Proxies: make(map[string]*cmn.SLOReport, len(smap.Pmap)),
+			Targets: make(map[string]*cmn.SLOReport, len(smap.Tmap)),
+		}
+	)
+	query.Add(cmn.URLParamWhat, cmn.GetWhatSLO)
+	collect := func(reports map[string]*cmn.SLOReport, results chan callResult) {
+		for res := range results {
+			if res.err != nil {
+				glog.Errorf("Failed to get %s SLO report: %s", res.si, res.errstr)
+				continue
+			}
+			report := &cmn.SLOReport{}
+			if err := jsoniter.Unmarshal(res.outjson, report); err != nil {
+				glog.Errorf("Failed to unmarshal %s SLO report, err: %v", res.si, err)
+				continue
+			}
+			reports[res.si.DaemonID] = report
+		}
+	}
+	collect(out.Proxies, p.broadcastDash(path, query, smap.Pmap))
+	collect(out.Targets, p.broadcastDash(path, query, smap.Tmap))
+	out.Proxies[p.si.DaemonID] = getproxystatsrunner().SLO()
+
+	out.SLOReport = *sumSLO(time.Now(), out.Proxies, out.Targets)
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterSLO")
+}
+
+// sumSLO evaluates the objectives over the sum of the daemons' events; the objectives
+// are ordered as configured
+func sumSLO(now time.Time, reports ...map[string]*cmn.SLOReport) *cmn.SLOReport {
+	var (
+		out   = &cmn.SLOReport{Time: now, Objectives: make([]cmn.SLOStatus, 0, len(ctx.config.SLO.Objectives))}
+		index = make(map[string]int, len(ctx.config.SLO.Objectives))
+	)
+	for i, obj := range ctx.config.SLO.Objectives {
+		index[obj.Name] = i
+		out.Objectives = append(out.Objectives, cmn.SLOStatus{Name: obj.Name, Target: obj.Target, Window: ctx.config.SLO.Window})
+	}
+	for _, daemons := range reports {
+		for _, report := range daemons {
+			for _, status := range report.Objectives {
+				i, ok := index[status.Name]
+				if !ok {
+					continue
+				}
+				sum := &out.Objectives[i]
+				sum.Events.Good += status.Events.Good
+				sum.Events.Bad += status.Events.Bad
+				sum.Events1h.Good += status.Events1h.Good
+				sum.Events1h.Bad += status.Events1h.Bad
+				sum.Events5m.Good += status.Events5m.Good
+				sum.Events5m.Bad += status.Events5m.Bad
+			}
+		}
+	}
+	for i := range out.Objectives {
+		out.Objectives[i].Eval()
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestSumSLO(t *testing.T) {
+	conf := cmn.SLOConf{
+		WindowStr: "1h",
+		Objectives: []cmn.SLOObjective{
+			{Name: "get-errors", Kind: cmn.SLOKindErrorRate, Stat: "get.n", ErrStat: "err.get.n", Target: 0.9},
+		},
+	}
+	if err := parseSLO(&conf); err != nil {
+		t.Fatal(err)
+	}
+	saved := ctx.config.SLO
+	ctx.config.SLO = conf
+	defer func() { ctx.config.SLO = saved }()
+
+	report := func(good, bad int64) *cmn.SLOReport {
+		events := cmn.SLOEvents{Good: good, Bad: bad}
+		return &cmn.SLOReport{Objectives: []cmn.SLOStatus{
+			{Name: "get-errors", Events: events, Events1h: events, Events5m: events},
+			{Name: "removed-since", Events: events},
+		}}
+	}
+	// each target meets the objective on its own, the cluster does not
+	proxies := map[string]*cmn.SLOReport{"p1": report(0, 0)}
+	targets := map[string]*cmn.SLOReport{"t1": report(10, 1), "t2": report(0, 1), "t3": report(9, 1)}
+	out := sumSLO(time.Now(), proxies, targets)
+	if len(out.Objectives) != 1 {
+		t.Fatalf("expected a single objective, got %+v", out.Objectives)
+	}
+	status := out.Objectives[0]
+	if status.Events != (cmn.SLOEvents{Good: 19, Bad: 3}) || status.Window != time.Hour || status.Met {
+		t.Errorf("unexpected cluster-wide status: %+v", status)
+	}
+	if status.BudgetLeft >= 0 {
+		t.Errorf("expected overspent error budget, got %v", status.BudgetLeft)
+	}
+}
+
+func TestParseSLO(t *testing.T) {
+	tests := []cmn.SLOConf{
+		{WindowStr: "10m"},
+		{Objectives: []cmn.SLOObjective{{Name: "a", Kind: cmn.SLOKindLatency, Stat: "get.lat", Target: 0.99}}},
+		{Objectives: []cmn.SLOObjective{{Name: "a", Kind: cmn.SLOKindErrorRate, Stat: "get.n", Target: 0.99}}},
+		{Objectives: []cmn.SLOObjective{{Name: "a", Kind: cmn.SLOKindLatency, Stat: "get.lat", ThresholdStr: "1ms", Target: 1}}},
+		{Objectives: []cmn.SLOObjective{{Name: "a", Kind: "p99", Stat: "get.lat", Target: 0.99}}},
+		{Objectives: []cmn.SLOObjective{
+			{Name: "a", Kind: cmn.SLOKindErrorRate, Stat: "get.n", ErrStat: "err.get.n", Target: 0.99},
+			{Name: "a", Kind: cmn.SLOKindErrorRate, Stat: "put.n", ErrStat: "err.put.n", Target: 0.99},
+		}},
+	}
+	for i, conf := range tests {
+		if err := parseSLO(&conf); err == nil {
+			t.Errorf("#%d: expected invalid SLO configuration %+v", i, conf)
+		}
+	}
+}
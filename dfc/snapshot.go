@@ -0,0 +1,660 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	"github.com/NVIDIA/dfcpub/stats"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Bucket snapshots: a named, read-only, point-in-time copy of a local bucket - e.g., the
+// exact dataset of a training run - that costs no data copying. ActSnapshot (POST
+// /v1/buckets/bucket-name) has each target hardlink all of its objects of the bucket into
+// <mountpath>/.dfc-snapshots/<bucket>/<snapshot>/ on the respective mountpaths - first into
+// a temporary directory, renamed once all objects are linked - and stores the number and
+// size of the linked objects next to the directory (.<snapshot>.meta), for listing. The
+// proxy records the snapshot in the bucket's properties (BucketProps.Snapshots). The
+// objects are never modified in place: PUT (and compression, and everything else that
+// rewrites an object) writes a workfile and renames it over the object, and DELETE unlinks
+// the object - so that the snapshot keeps referencing the original content. Objects
+// written while the snapshot is being created may or may not be in it.
+//
+// GET /v1/snapshots/bucket-name lists the bucket's snapshots; DELETE /v1/snapshots/bucket-
+// name/snapshot-name deletes one. GET and HEAD /v1/snapshots/bucket-name/snapshot-name/
+// object-name read the object as of the snapshot: the proxy redirects to the object's
+// target, which looks the object up on all its mountpaths and, if not found (the cluster
+// map has changed since), redirects to the target that has it. Snapshots are not moved by
+// rebalance; they are destroyed along with the bucket, and a bucket with snapshots cannot
+// be renamed (the proxy checks BucketProps.Snapshots, the targets - their mountpaths).
+//
+// Capacity: the content of the objects overwritten or deleted since the snapshot stays on
+// disk until the snapshot is deleted. The mountpaths' used capacity includes it, but neither
+// the bucket's counts nor LRU see it - LRU cannot free that space, nor the space of the
+// evicted objects that are also in a snapshot.
+//
+// ================================ Summary ===============================================
+
+const snapshotsDirName = ".dfc-snapshots" // in the mountpath's root
+
+// snapshotDir returns the directory of the bucket's snapshot on the mountpath; empty
+// snapshot name - the directory of all the bucket's snapshots
+func snapshotDir(mpath, bucket, name string) string {
+	return filepath.Join(mpath, snapshotsDirName, bucket, name)
+}
+
+func validateSnapshotName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return nil
+}
+
+// snapshotItems parses /v1/snapshots/bucket-name[/snapshot-name[/object-name]]
+func snapshotItems(apitems []string) (bucket, name, objname string) {
+	items := strings.SplitN(apitems[0], "/", 3)
+	bucket = items[0]
+	if len(items) > 1 {
+		name = items[1]
+	}
+	if len(items) > 2 {
+		objname = items[2]
+	}
+	return
+}
+
+// snapshotMetaPath returns the file that stores the snapshot's creation time and the
+// number and size of its objects on the mountpath, so that listing does not walk them
+func snapshotMetaPath(mpath, bucket, name string) string {
+	return snapshotDir(mpath, bucket, "."+name+".meta")
+}
+
+// hasSnapshots returns true if the target stores any snapshots of the bucket - not
+// counting the leftovers of the snapshots being created and deleted
+func hasSnapshots(bucket string) bool {
+	availablePaths, _ := fs.Mountpaths.Get()
+	for _, mpathInfo := range availablePaths {
+		finfos, _ := ioutil.ReadDir(snapshotDir(mpathInfo.Path, bucket, ""))
+		for _, finfo := range finfos {
+			if finfo.IsDir() && !strings.HasPrefix(finfo.Name(), ".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//
+// proxy
+//
+
+// POST { snapshot } /v1/buckets/bucket-name
+func (p *proxyrunner) createSnapshot(w http.ResponseWriter, r *http.Request, bucket string, msg *cmn.ActionMsg) {
+	if p.forwardCP(w, r, msg, bucket, nil) {
+		return
+	}
+	if err := validateSnapshotName(msg.Name); err != nil {
+		p.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	if !p.bmdowner.get().IsLocal(bucket) {
+		p.invalmsghdlr(w, r, fmt.Sprintf("Local bucket %s "+doesnotexist, bucket))
+		return
+	}
+	p.snapmtx.Lock()
+	defer p.snapmtx.Unlock()
+	// record the snapshot first, so that the bucket is not renamed while being snapshotted
+	if errstr := p.updateSnapshots(bucket, msg.Name, true /*add*/); errstr != "" {
+		p.invalmsghdlr(w, r, errstr)
+		return
+	}
+	jsbytes, err := jsoniter.Marshal(msg)
+	cmn.Assert(err == nil, err)
+	results := p.broadcastTargets(
+		cmn.URLPath(cmn.Version, cmn.Buckets, bucket),
+		nil, // query
+		http.MethodPost,
+		jsbytes,
+		p.smapowner.get(),
+		ctx.config.Timeout.DefaultLong,
+	)
+	errs := make([]string, 0)
+	for res := range results {
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", res.si.DaemonID, res.errstr))
+		}
+	}
+	if len(errs) == 0 {
+		glog.Infof("created snapshot %s of local bucket %s", msg.Name, bucket)
+		return
+	}
+	errstr := fmt.Sprintf("Failed to create snapshot %s of local bucket %s: %s", msg.Name, bucket, strings.Join(errs, "; "))
+	if rberr := p.bcastDeleteSnapshot(bucket, msg.Name); rberr != "" && rberr != doesnotexist {
+		errstr += fmt.Sprintf(" (roll back: %s)", rberr)
+	} else {
+		p.updateSnapshots(bucket, msg.Name, false /*add*/)
+	}
+	p.invalmsghdlr(w, r, errstr)
+}
+
+// verb /v1/snapshots/bucket-name[/snapshot-name[/object-name]]
+func (p *proxyrunner) snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	apitems, err := p.checkRESTItems(w, r, 1, false, cmn.Version, cmn.Snapshots)
+	if err != nil {
+		return
+	}
+	bucket, name, objname := snapshotItems(apitems)
+	if !p.validatebckname(w, r, bucket) {
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		p.listSnapshots(w, r, bucket)
+	case r.Method == http.MethodDelete && name != "" && objname == "":
+		p.deleteSnapshot(w, r, bucket, name)
+	case (r.Method == http.MethodGet || r.Method == http.MethodHead) && objname != "":
+		smap := p.smapowner.get()
+		si, errstr := hrwTarget(bucket, objname, smap)
+		if errstr != "" {
+			p.invalmsghdlr(w, r, errstr)
+			return
+		}
+		redirecturl := p.redirectURL(r, si.PublicNet.DirectURL, time.Now(), bucket)
+		http.Redirect(w, r, redirecturl, http.StatusTemporaryRedirect)
+	default:
+		p.invalmsghdlr(w, r, fmt.Sprintf("Invalid %s /%s/%s", r.Method, cmn.Snapshots, apitems[0]))
+	}
+}
+
+// GET /v1/snapshots/bucket-name: the targets' snapshots, summed up
+func (p *proxyrunner) listSnapshots(w http.ResponseWriter, r *http.Request, bucket string) {
+	results := p.broadcastTargets(
+		cmn.URLPath(cmn.Version, cmn.Snapshots, bucket),
+		nil, // query
+		http.MethodGet,
+		nil, // message
+		p.smapowner.get(),
+		ctx.config.Timeout.DefaultLong,
+	)
+	snaps := make(map[string]*cmn.BucketSnapshot, 4)
+	for res := range results {
+		if res.err != nil {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Failed to list snapshots of %s at %s: %s", bucket, res.si.DaemonID, res.errstr))
+			return
+		}
+		tsnaps := make([]cmn.BucketSnapshot, 0)
+		if err := jsoniter.Unmarshal(res.outjson, &tsnaps); err != nil {
+			p.invalmsghdlr(w, r, fmt.Sprintf("Failed to unmarshal snapshots of %s from %s, err: %v", bucket, res.si.DaemonID, err))
+			return
+		}
+		addSnapshots(snaps, tsnaps)
+	}
+	jsbytes, err := jsoniter.Marshal(sortSnapshots(snaps))
+	cmn.Assert(err == nil, err)
+	p.writeJSON(w, r, jsbytes, "listsnapshots")
+}
+
+// DELETE /v1/snapshots/bucket-name/snapshot-name
+func (p *proxyrunner) deleteSnapshot(w http.ResponseWriter, r *http.Request, bucket, name string) {
+	msg := &cmn.ActionMsg{Action: cmn.ActDelete, Name: name}
+	if p.forwardCP(w, r, msg, bucket, []byte{}) {
+		return
+	}
+	p.snapmtx.Lock()
+	defer p.snapmtx.Unlock()
+	errstr := p.bcastDeleteSnapshot(bucket, name)
+	if errstr == "" || errstr == doesnotexist {
+		if p.updateSnapshots(bucket, name, false /*add*/) == "" {
+			errstr = "" // recorded - even if none of the targets has it
+		}
+	}
+	if errstr != "" {
+		status := http.StatusBadRequest
+		if errstr == doesnotexist {
+			errstr, status = fmt.Sprintf("Snapshot %s of %s "+doesnotexist, name, bucket), http.StatusNotFound
+		}
+		p.invalmsghdlr(w, r, errstr, status)
+		return
+	}
+	glog.Infof("deleted snapshot %s of local bucket %s", name, bucket)
+}
+
+// bcastDeleteSnapshot deletes the snapshot on all targets; returns doesnotexist if none
+// of them has it
+func (p *proxyrunner) bcastDeleteSnapshot(bucket, name string) (errstr string) {
+	results := p.broadcastTargets(
+		cmn.URLPath(cmn.Version, cmn.Snapshots, bucket, name),
+		nil, // query
+		http.MethodDelete,
+		nil, // message
+		p.smapowner.get(),
+		ctx.config.Timeout.DefaultLong,
+	)
+	var (
+		errs    = make([]string, 0)
+		deleted bool
+	)
+	for res := range results {
+		switch {
+		case res.err == nil:
+			deleted = true
+		case res.status != http.StatusNotFound:
+			errs = append(errs, fmt.Sprintf("%s: %s", res.si.DaemonID, res.errstr))
+		}
+	}
+	if len(errs) > 0 {
+		return strings.Join(errs, "; ")
+	}
+	if !deleted {
+		return doesnotexist
+	}
+	return
+}
+
+// updateSnapshots adds the snapshot to (or removes it from) the bucket's properties and
+// metasyncs the bucket-metadata; removing returns doesnotexist if the snapshot is not there
+func (p *proxyrunner) updateSnapshots(bucket, name string, add bool) (errstr string) {
+	p.bmdowner.Lock()
+	clone := p.bmdowner.get().clone()
+	ok, props := clone.get(bucket, true)
+	if !ok {
+		p.bmdowner.Unlock()
+		return fmt.Sprintf("Local bucket %s "+doesnotexist, bucket)
+	}
+	snapshots := make([]string, 0, len(props.Snapshots)+1)
+	for _, s := range props.Snapshots {
+		if s != name {
+			snapshots = append(snapshots, s)
+		}
+	}
+	switch {
+	case add && len(snapshots) < len(props.Snapshots):
+		p.bmdowner.Unlock()
+		return fmt.Sprintf("Snapshot %s of local bucket %s already exists", name, bucket)
+	case add:
+		snapshots = append(snapshots, name)
+		sort.Strings(snapshots)
+	case len(snapshots) == len(props.Snapshots):
+		p.bmdowner.Unlock()
+		return doesnotexist
+	}
+	props.Snapshots = nil
+	if len(snapshots) > 0 {
+		props.Snapshots = snapshots
+	}
+	clone.set(bucket, true, props)
+	if errstr := p.savebmdconf(clone); errstr != "" {
+		glog.Errorln(errstr)
+	}
+	p.bmdowner.put(clone)
+	p.bmdowner.Unlock()
+	action := cmn.ActSnapshot
+	if !add {
+		action = cmn.ActDelete
+	}
+	p.metasyncer.sync(true, clone, &cmn.ActionMsg{Action: action, Name: name})
+	return
+}
+
+func addSnapshots(snaps map[string]*cmn.BucketSnapshot, add []cmn.BucketSnapshot) {
+	for _, s := range add {
+		snap, ok := snaps[s.Name]
+		if !ok {
+			snap = &cmn.BucketSnapshot{Name: s.Name, Created: s.Created}
+			snaps[s.Name] = snap
+		}
+		snap.Objects += s.Objects
+		snap.Size += s.Size
+		if s.Created.Before(snap.Created) {
+			snap.Created = s.Created
+		}
+	}
+}
+
+func sortSnapshots(snaps map[string]*cmn.BucketSnapshot) []cmn.BucketSnapshot {
+	out := make([]cmn.BucketSnapshot, 0, len(snaps))
+	for _, snap := range snaps {
+		out = append(out, *snap)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+//
+// target
+//
+
+// POST { snapshot } /v1/buckets/bucket-name
+func (t *targetrunner) createSnapshot(w http.ResponseWriter, r *http.Request, bucket string, msg *cmn.ActionMsg) {
+	if err := validateSnapshotName(msg.Name); err != nil {
+		t.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	if errstr := t.doCreateSnapshot(bucket, msg.Name); errstr != "" {
+		t.invalmsghdlr(w, r, errstr)
+	}
+}
+
+// doCreateSnapshot links the bucket's objects into the temporary directories, one per
+// mountpath, and then renames the latter; on failure, removes whatever it has created
+func (t *targetrunner) doCreateSnapshot(bucket, name string) (errstr string) {
+	t.snapmtx.Lock()
+	defer t.snapmtx.Unlock()
+	if ok, _ := t.bmdowner.get().get(bucket, true); !ok {
+		return fmt.Sprintf("Local bucket %s "+doesnotexist, bucket)
+	}
+	var (
+		availablePaths, _ = fs.Mountpaths.Get()
+		tmpname           = "." + name + ".tmp" // snapshot names never start with a dot
+		renamed           = make([]string, 0, len(availablePaths))
+		metas             = make(map[string]*cmn.BucketSnapshot, len(availablePaths))
+		created           = time.Now()
+		err               error
+	)
+	for _, mpathInfo := range availablePaths {
+		if _, err := os.Stat(snapshotDir(mpathInfo.Path, bucket, name)); err == nil {
+			return fmt.Sprintf("Snapshot %s of local bucket %s already exists", name, bucket)
+		}
+	}
+	defer func() {
+		if errstr == "" {
+			return
+		}
+		for _, mpathInfo := range availablePaths {
+			os.RemoveAll(snapshotDir(mpathInfo.Path, bucket, tmpname))
+			os.Remove(snapshotMetaPath(mpathInfo.Path, bucket, name))
+		}
+		for _, dir := range renamed {
+			os.RemoveAll(dir)
+		}
+	}()
+	for _, mpathInfo := range availablePaths {
+		tmpdir := snapshotDir(mpathInfo.Path, bucket, tmpname)
+		if err = os.RemoveAll(tmpdir); err == nil { // leftovers
+			err = cmn.CreateDir(tmpdir)
+		}
+		if err != nil {
+			return fmt.Sprintf("Failed to create snapshot %s of local bucket %s, err: %v", name, bucket, err)
+		}
+		metas[mpathInfo.Path] = &cmn.BucketSnapshot{Name: name, Created: created}
+	}
+	opts := &fs.WalkOpts{Scope: fs.WalkLocal, Bucket: bucket, Skip: skipNonProcessable}
+	err = fs.Mountpaths.WalkObjects(opts, func(entry *fs.WalkEntry) error {
+		linkfqn := filepath.Join(snapshotDir(entry.MpathInfo.Path, bucket, tmpname), entry.Objname)
+		if err := cmn.CreateDir(filepath.Dir(linkfqn)); err != nil {
+			return err
+		}
+		if err := os.Link(entry.FQN, linkfqn); err != nil {
+			if os.IsNotExist(err) {
+				return nil // the object has been deleted in the meantime
+			}
+			return err
+		}
+		meta := metas[entry.MpathInfo.Path] // each mountpath is walked by a single goroutine
+		meta.Objects++
+		meta.Size += entry.FileInfo.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Sprintf("Failed to create snapshot %s of local bucket %s, err: %v", name, bucket, err)
+	}
+	for _, mpathInfo := range availablePaths {
+		if err = saveSnapshotMeta(snapshotMetaPath(mpathInfo.Path, bucket, name), metas[mpathInfo.Path]); err != nil {
+			return fmt.Sprintf("Failed to create snapshot %s of local bucket %s, err: %v", name, bucket, err)
+		}
+	}
+	for _, mpathInfo := range availablePaths {
+		dir := snapshotDir(mpathInfo.Path, bucket, name)
+		if err = os.Rename(snapshotDir(mpathInfo.Path, bucket, tmpname), dir); err != nil {
+			return fmt.Sprintf("Failed to create snapshot %s of local bucket %s, err: %v", name, bucket, err)
+		}
+		renamed = append(renamed, dir)
+	}
+	return
+}
+
+// verb /v1/snapshots/bucket-name[/snapshot-name[/object-name]]
+func (t *targetrunner) snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	apitems, err := t.checkRESTItems(w, r, 1, false, cmn.Version, cmn.Snapshots)
+	if err != nil {
+		return
+	}
+	bucket, name, objname := snapshotItems(apitems)
+	if !t.validatebckname(w, r, bucket) {
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		jsbytes, err := jsoniter.Marshal(t.listSnapshots(bucket))
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "listsnapshots")
+	case r.Method == http.MethodDelete && name != "" && objname == "":
+		if errstr, status := t.deleteSnapshot(bucket, name); errstr != "" {
+			t.invalmsghdlr(w, r, errstr, status)
+		}
+	case (r.Method == http.MethodGet || r.Method == http.MethodHead) && objname != "":
+		t.getSnapshotObj(w, r, bucket, name, objname)
+	default:
+		t.invalmsghdlr(w, r, fmt.Sprintf("Invalid %s /%s/%s", r.Method, cmn.Snapshots, apitems[0]))
+	}
+}
+
+// listSnapshots returns the target's snapshots of the bucket, as stored by doCreateSnapshot
+// next to the snapshots' directories
+func (t *targetrunner) listSnapshots(bucket string) []cmn.BucketSnapshot {
+	availablePaths, _ := fs.Mountpaths.Get()
+	snaps := make(map[string]*cmn.BucketSnapshot, 4)
+	for _, mpathInfo := range availablePaths {
+		dir := snapshotDir(mpathInfo.Path, bucket, "")
+		finfos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, finfo := range finfos {
+			if !finfo.IsDir() || strings.HasPrefix(finfo.Name(), ".") { // in progress
+				continue
+			}
+			snap := cmn.BucketSnapshot{}
+			metapath := snapshotMetaPath(mpathInfo.Path, bucket, finfo.Name())
+			if err := cmn.LocalLoad(metapath, &snap); err != nil || snap.Name != finfo.Name() {
+				snap = countSnapshot(filepath.Join(dir, finfo.Name()), finfo)
+				if err := saveSnapshotMeta(metapath, &snap); err != nil {
+					glog.Errorf("Failed to save %s, err: %v", metapath, err)
+				}
+			}
+			addSnapshots(snaps, []cmn.BucketSnapshot{snap})
+		}
+	}
+	return sortSnapshots(snaps)
+}
+
+// countSnapshot walks the snapshot's directory on the mountpath - the snapshots created
+// before their counts were stored; the creation time is the directory's modification time
+// (the directory is never modified once created)
+func countSnapshot(dir string, finfo os.FileInfo) cmn.BucketSnapshot {
+	snap := cmn.BucketSnapshot{Name: finfo.Name(), Created: finfo.ModTime()}
+	filepath.Walk(dir, func(fqn string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			snap.Objects++
+			snap.Size += fi.Size()
+		}
+		return nil
+	})
+	return snap
+}
+
+// saveSnapshotMeta writes the file in place: a file cut short fails to load and gets
+// counted anew (see listSnapshots)
+func saveSnapshotMeta(metapath string, snap *cmn.BucketSnapshot) error {
+	jsbytes, err := jsoniter.Marshal(snap)
+	cmn.Assert(err == nil, err)
+	return ioutil.WriteFile(metapath, jsbytes, 0644)
+}
+
+// deleteSnapshot removes the snapshot's directories; 404 if the target has none
+func (t *targetrunner) deleteSnapshot(bucket, name string) (errstr string, status int) {
+	if err := validateSnapshotName(name); err != nil {
+		return err.Error(), http.StatusBadRequest
+	}
+	t.snapmtx.Lock()
+	defer t.snapmtx.Unlock()
+	var (
+		availablePaths, _ = fs.Mountpaths.Get()
+		found             bool
+	)
+	for _, mpathInfo := range availablePaths {
+		dir := snapshotDir(mpathInfo.Path, bucket, name)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		found = true
+		// rename first, so that a partially removed snapshot is never listed
+		tmpdir := snapshotDir(mpathInfo.Path, bucket, "."+name+".del")
+		if err := os.Rename(dir, tmpdir); err != nil {
+			return fmt.Sprintf("Failed to delete snapshot %s of %s, err: %v", name, bucket, err), http.StatusInternalServerError
+		}
+		if err := os.RemoveAll(tmpdir); err != nil {
+			glog.Errorf("Failed to remove %s, err: %v", tmpdir, err)
+		}
+		if err := os.Remove(snapshotMetaPath(mpathInfo.Path, bucket, name)); err != nil && !os.IsNotExist(err) {
+			glog.Errorf("Failed to remove snapshot %s metadata, err: %v", name, err)
+		}
+	}
+	if !found {
+		return fmt.Sprintf("Snapshot %s of %s "+doesnotexist, name, bucket), http.StatusNotFound
+	}
+	return
+}
+
+// removeSnapshots removes all the bucket's snapshots - the bucket is destroyed
+func removeSnapshots(bucket string) {
+	availablePaths, _ := fs.Mountpaths.Get()
+	for _, mpathInfo := range availablePaths {
+		dir := snapshotDir(mpathInfo.Path, bucket, "")
+		if err := os.RemoveAll(dir); err != nil {
+			glog.Errorf("Failed to remove snapshots %q, err: %v", dir, err)
+		}
+	}
+}
+
+// GET | HEAD /v1/snapshots/bucket-name/snapshot-name/object-name[?offset=&length=]
+func (t *targetrunner) getSnapshotObj(w http.ResponseWriter, r *http.Request, bucket, name, objname string) {
+	var (
+		fqn   string
+		finfo os.FileInfo
+		query = r.URL.Query()
+	)
+	if err := validateSnapshotName(name); err != nil {
+		t.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	availablePaths, _ := fs.Mountpaths.Get()
+	for _, mpathInfo := range availablePaths {
+		fqn = filepath.Join(snapshotDir(mpathInfo.Path, bucket, name), objname)
+		if fi, err := os.Stat(fqn); err == nil && !fi.IsDir() {
+			finfo = fi
+			break
+		}
+	}
+	if finfo == nil {
+		// the cluster map has changed since the snapshot was created; the neighbors
+		// look up locally only (URLParamFromID)
+		if query.Get(cmn.URLParamFromID) == "" {
+			if si := t.lookupSnapshotObj(r.URL.Path); si != nil {
+				redirecturl := si.PublicNet.DirectURL + r.URL.Path
+				if r.URL.RawQuery != "" {
+					redirecturl += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, redirecturl, http.StatusTemporaryRedirect)
+				return
+			}
+		}
+		t.invalmsghdlr(w, r, fmt.Sprintf("%s/%s "+doesnotexist+" in snapshot %s", bucket, objname, name), http.StatusNotFound)
+		return
+	}
+	offset, length, errstr := t.offsetAndLength(query)
+	if errstr != "" {
+		t.invalmsghdlr(w, r, errstr)
+		return
+	}
+	md := loadObjMD(fqn)
+	size := finfo.Size()
+	if md.Compressed {
+		size = md.OrigSize
+	}
+	if offset >= size && length > 0 {
+		t.invalmsghdlr(w, r, fmt.Sprintf("Invalid offset %d: %s/%s is %d bytes", offset, bucket, objname, size))
+		return
+	}
+	if length > 0 && offset+length > size {
+		length = size - offset
+	}
+	if md.Cksum != "" && length == 0 {
+		htype, hval := cmn.ParseStoredCksum([]byte(md.Cksum))
+		w.Header().Set(cmn.HeaderDFCChecksumType, htype)
+		w.Header().Set(cmn.HeaderDFCChecksumVal, hval)
+	}
+	if md.Version != "" {
+		w.Header().Set(cmn.HeaderDFCObjVersion, md.Version)
+	}
+	if length == 0 {
+		length = size
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	if r.Method == http.MethodHead {
+		return
+	}
+	reader, err := openObject(fqn) // decompresses, if need be
+	if err != nil {
+		t.invalmsghdlr(w, r, fmt.Sprintf("Failed to open %s, err: %v", fqn, err), http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+	if seeker, ok := reader.(io.Seeker); ok && offset > 0 {
+		_, err = seeker.Seek(offset, io.SeekStart)
+	} else if offset > 0 {
+		_, err = io.CopyN(ioutil.Discard, reader, offset)
+	}
+	if err != nil {
+		t.invalmsghdlr(w, r, fmt.Sprintf("Failed to read %s, err: %v", fqn, err), http.StatusInternalServerError)
+		return
+	}
+	if _, err = io.CopyN(w, reader, length); err != nil {
+		glog.Errorf("Failed to send %s/%s (snapshot %s), err: %v", bucket, objname, name, err)
+		return
+	}
+	t.statsif.AddBucket(bucket, stats.NamedVal64{Name: stats.GetCount, Val: 1})
+}
+
+// lookupSnapshotObj returns the neighbor that has the object of the snapshot
+func (t *targetrunner) lookupSnapshotObj(path string) *cluster.Snode {
+	query := url.Values{}
+	query.Set(cmn.URLParamFromID, t.si.DaemonID)
+	results := t.broadcastNeighbors(path, query, http.MethodHead, nil, t.smapowner.get(), ctx.config.Timeout.MaxKeepalive)
+	for res := range results {
+		if res.err == nil {
+			return res.si
+		}
+	}
+	return nil
+}
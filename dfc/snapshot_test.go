@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func TestBucketSnapshot(t *testing.T) {
+	q := fs.Mountpaths
+	defer func() { fs.Mountpaths = q }()
+	fs.Mountpaths = fs.NewMountedFS(ctx.config.LocalBuckets, ctx.config.CloudBuckets)
+	fs.Mountpaths.DisableFsIDCheck()
+	var (
+		mpaths    = make([]string, 0, 2)
+		localdirs = make([]string, 0, 2)
+	)
+	for i := 0; i < 2; i++ {
+		mpath, err := ioutil.TempDir("", "snapshot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(mpath)
+		if err := fs.Mountpaths.Add(mpath); err != nil {
+			t.Fatal(err)
+		}
+		mpaths = append(mpaths, mpath)
+		localdirs = append(localdirs, fs.Mountpaths.MakePathLocal(mpath))
+	}
+	put := func(localdir, objname, data string) {
+		fqn := filepath.Join(localdir, "bck", objname)
+		if err := cmn.CreateDir(filepath.Dir(fqn)); err != nil {
+			t.Fatal(err)
+		}
+		// the way PUT does it: workfile, then rename
+		if err := ioutil.WriteFile(fqn+".work", []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(fqn+".work", fqn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put(localdirs[0], "dir/obj1", "original")
+	put(localdirs[0], "obj2", "12345")
+	put(localdirs[1], "dir/obj3", "abc")
+
+	tgt := &targetrunner{}
+	tgt.statsif = &fakeStatsTracker{stats: make(map[string]int64)}
+	tgt.bmdowner = &bmdowner{}
+	bucketmd := newBucketMD()
+	bucketmd.add("bck", true, cmn.BucketProps{CloudProvider: cmn.ProviderDFC})
+	tgt.bmdowner.put(bucketmd)
+
+	if errstr := tgt.doCreateSnapshot("bck", "v1"); errstr != "" {
+		t.Fatal(errstr)
+	}
+	if errstr := tgt.doCreateSnapshot("bck", "v1"); errstr == "" {
+		t.Error("expected the existing snapshot not to be created again")
+	}
+	if errstr := tgt.doCreateSnapshot("nonexisting", "v1"); errstr == "" {
+		t.Error("expected the snapshot of a nonexisting bucket to fail")
+	}
+	if !hasSnapshots("bck") {
+		t.Error("expected the bucket to have snapshots")
+	}
+
+	// the bucket changes; the snapshot does not
+	put(localdirs[0], "dir/obj1", "overwritten")
+	if err := os.Remove(filepath.Join(localdirs[0], "bck", "obj2")); err != nil {
+		t.Fatal(err)
+	}
+	get := func(method, objname, query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, cmn.URLPath(cmn.Version, cmn.Snapshots, "bck", "v1", objname)+query, nil)
+		w := httptest.NewRecorder()
+		tgt.getSnapshotObj(w, r, "bck", "v1", objname)
+		return w
+	}
+	if w := get(http.MethodGet, "dir/obj1", ""); w.Code != http.StatusOK || w.Body.String() != "original" {
+		t.Errorf("expected the original content, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get(http.MethodGet, "obj2", "?offset=1&length=3"); w.Body.String() != "234" {
+		t.Errorf("expected the range of the deleted object, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get(http.MethodHead, "dir/obj3", ""); w.Code != http.StatusOK || w.Header().Get("Content-Length") != "3" {
+		t.Errorf("expected HEAD 3 bytes, got %d %v", w.Code, w.Header())
+	}
+	// (a single target: no neighbors to look up)
+	if w := get(http.MethodGet, "dir/obj4", "?"+cmn.URLParamFromID+"=t1"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+
+	snaps := tgt.listSnapshots("bck")
+	if len(snaps) != 1 || snaps[0].Name != "v1" || snaps[0].Objects != 3 || snaps[0].Size != 16 || snaps[0].Created.IsZero() {
+		t.Fatalf("unexpected snapshots %+v", snaps)
+	}
+	// listed from the stored counts; without those (older snapshots) - counted once and stored
+	if err := os.Remove(snapshotMetaPath(mpaths[0], "bck", "v1")); err != nil {
+		t.Fatal(err)
+	}
+	if snaps = tgt.listSnapshots("bck"); len(snaps) != 1 || snaps[0].Objects != 3 || snaps[0].Size != 16 {
+		t.Errorf("unexpected recounted snapshots %+v", snaps)
+	}
+	if _, err := os.Stat(snapshotMetaPath(mpaths[0], "bck", "v1")); err != nil {
+		t.Errorf("expected the counts stored again, err: %v", err)
+	}
+
+	if errstr, _ := tgt.deleteSnapshot("bck", "v1"); errstr != "" {
+		t.Fatal(errstr)
+	}
+	if errstr, status := tgt.deleteSnapshot("bck", "v1"); status != http.StatusNotFound {
+		t.Errorf("expected 404, got %d %s", status, errstr)
+	}
+	if snaps = tgt.listSnapshots("bck"); len(snaps) != 0 {
+		t.Errorf("expected no snapshots, got %+v", snaps)
+	}
+	// the leftovers of interrupted creation and deletion are not snapshots
+	for _, name := range []string{".v2.tmp", ".v3.del"} {
+		if err := cmn.CreateDir(snapshotDir(mpaths[0], "bck", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hasSnapshots("bck") {
+		t.Error("expected the bucket to have no snapshots")
+	}
+	if _, err := os.Stat(snapshotMetaPath(mpaths[0], "bck", "v1")); !os.IsNotExist(err) {
+		t.Errorf("expected the counts removed along with the snapshot, err: %v", err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(localdirs[0], "bck", "dir", "obj1")); err != nil || string(b) != "overwritten" {
+		t.Errorf("expected the bucket unaffected, got %q, err: %v", b, err)
+	}
+
+	for _, name := range []string{"", ".tmp", "a/b"} {
+		if validateSnapshotName(name) == nil {
+			t.Errorf("expected snapshot name %q invalid", name)
+		}
+	}
+}
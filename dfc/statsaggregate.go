@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Cluster-wide stats: GET /v1/cluster?what=stats&aggregate=true aggregates the targets'
+// stats snapshots (statssnapshot.go) rather than returning them as is. The proxy keeps the
+// snapshots of the previous aggregate and derives each target's rates - and the average
+// latencies - over the interval since; the first aggregate (or the first after the target's
+// snapshot was reset, or after the target joined) is over the interval since the target's
+// start (or reset). The previous aggregate is that of any poller, so that the concurrent
+// pollers split the intervals between themselves.
+//
+// Each stat is then summed up across the targets (cmn.StatsAggregate), along with the
+// minimum, the maximum, and the average across the targets; a target whose rate, gauge, or
+// latency is off the targets' median by more than cmn.StatsOutlierFactor is an outlier.
+//
+// ================================ Summary ===============================================
+
+const (
+	statsAggCounter = "counter"
+	statsAggGauge   = "gauge"
+	statsAggLatency = "latency"
+)
+
+type (
+	// statsAggState is the previous aggregate: the snapshots, by target ID
+	statsAggState struct {
+		sync.Mutex
+		prev map[string]*cmn.StatsSnapshot
+	}
+	// statsAggValue is a target's value of a single stat
+	statsAggValue struct {
+		tid string
+		v   float64
+	}
+	// statsAggStat collects a single stat across the targets
+	statsAggStat struct {
+		agg    cmn.StatsAggregate
+		values []statsAggValue
+	}
+)
+
+func (p *proxyrunner) invokeHttpGetClusterStatsAggregate(w http.ResponseWriter, r *http.Request, reset bool) bool {
+	snaps, missing := p.targetStatsSnapshots(reset)
+	out := p.statsAgg.aggregate(snaps)
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		out.Missing = missing
+	}
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterStatsAggregate")
+}
+
+// aggregate aggregates the snapshots and keeps them for the next aggregate, along with the
+// previous snapshots of the targets that did not respond this time
+func (a *statsAggState) aggregate(snaps map[string]*cmn.StatsSnapshot) *cmn.ClusterStatsAggregate {
+	a.Lock()
+	defer a.Unlock()
+	out := aggregateStats(a.prev, snaps)
+	for tid, snap := range a.prev {
+		if _, ok := snaps[tid]; !ok {
+			snaps[tid] = snap
+		}
+	}
+	a.prev = snaps
+	return out
+}
+
+// aggregateStats aggregates the targets' snapshots over the intervals since their previous
+// snapshots, if any
+func aggregateStats(prev, snaps map[string]*cmn.StatsSnapshot) *cmn.ClusterStatsAggregate {
+	var (
+		out = &cmn.ClusterStatsAggregate{
+			Targets:   len(snaps),
+			Counters:  make(map[string]*cmn.StatsAggregate, 32),
+			Gauges:    make(map[string]*cmn.StatsAggregate, 8),
+			Latencies: make(map[string]*cmn.StatsAggregate, 16),
+		}
+		counters  = make(map[string]*statsAggStat, 32)
+		gauges    = make(map[string]*statsAggStat, 8)
+		latencies = make(map[string]*statsAggStat, 16)
+		tids      = make([]string, 0, len(snaps))
+	)
+	for tid := range snaps {
+		tids = append(tids, tid)
+	}
+	sort.Strings(tids) // for the ties to resolve the same way every time
+	for _, tid := range tids {
+		var (
+			snap  = snaps[tid]
+			base  = prev[tid]
+			start = snap.Since
+		)
+		if base != nil && base.Since.Equal(snap.Since) && base.Until.Before(snap.Until) {
+			start = base.Until
+		} else {
+			base = &cmn.StatsSnapshot{} // not polled before, or reset since
+		}
+		if out.Since.IsZero() || start.Before(out.Since) {
+			out.Since = start
+		}
+		if snap.Until.After(out.Until) {
+			out.Until = snap.Until
+		}
+		secs := snap.Until.Sub(start).Seconds()
+		for name, val := range snap.Counters {
+			stat := statsAggGet(counters, name)
+			stat.agg.Total += val
+			rate := perSecond(val-base.Counters[name], secs)
+			stat.agg.Rate += rate
+			stat.values = append(stat.values, statsAggValue{tid: tid, v: rate})
+		}
+		for name, val := range snap.Gauges {
+			stat := statsAggGet(gauges, name)
+			stat.agg.Total += val
+			stat.values = append(stat.values, statsAggValue{tid: tid, v: float64(val)})
+		}
+		for name, lat := range snap.Latencies {
+			var (
+				stat  = statsAggGet(latencies, name)
+				b     = base.Latencies[name]
+				count = lat.Count - b.Count
+			)
+			stat.agg.Total += lat.Count
+			stat.agg.Rate += perSecond(count, secs)
+			if count > 0 { // no samples - no latency to compare
+				stat.values = append(stat.values, statsAggValue{tid: tid, v: float64(lat.Sum-b.Sum) / float64(count)})
+			}
+		}
+	}
+	for _, kind := range []struct {
+		name  string
+		stats map[string]*statsAggStat
+		out   map[string]*cmn.StatsAggregate
+	}{
+		{statsAggCounter, counters, out.Counters},
+		{statsAggGauge, gauges, out.Gauges},
+		{statsAggLatency, latencies, out.Latencies},
+	} {
+		for name, stat := range kind.stats {
+			stat.finalize()
+			kind.out[name] = &stat.agg
+			out.Outliers = append(out.Outliers, stat.outliers(name, kind.name)...)
+		}
+	}
+	sort.Slice(out.Outliers, func(i, j int) bool {
+		if out.Outliers[i].Target != out.Outliers[j].Target {
+			return out.Outliers[i].Target < out.Outliers[j].Target
+		}
+		return out.Outliers[i].Stat < out.Outliers[j].Stat
+	})
+	return out
+}
+
+func statsAggGet(stats map[string]*statsAggStat, name string) *statsAggStat {
+	stat, ok := stats[name]
+	if !ok {
+		stat = &statsAggStat{}
+		stats[name] = stat
+	}
+	return stat
+}
+
+// perSecond returns the rate of the (non-negative) delta over the interval
+func perSecond(delta int64, secs float64) float64 {
+	if delta <= 0 || secs <= 0 {
+		return 0
+	}
+	return float64(delta) / secs
+}
+
+// finalize computes the minimum, the maximum, and the average across the targets
+func (stat *statsAggStat) finalize() {
+	if len(stat.values) == 0 {
+		return
+	}
+	var sum float64
+	stat.agg.Min, stat.agg.Max = math.MaxFloat64, -math.MaxFloat64
+	for _, tv := range stat.values {
+		sum += tv.v
+		if tv.v < stat.agg.Min {
+			stat.agg.Min, stat.agg.MinTarget = tv.v, tv.tid
+		}
+		if tv.v > stat.agg.Max {
+			stat.agg.Max, stat.agg.MaxTarget = tv.v, tv.tid
+		}
+	}
+	stat.agg.Avg = sum / float64(len(stat.values))
+}
+
+// outliers returns the targets off the median: above it or - except latencies, where lower
+// is only better - below it
+func (stat *statsAggStat) outliers(name, kind string) (outliers []cmn.StatsOutlier) {
+	if len(stat.values) < cmn.StatsOutlierMinTargets {
+		return
+	}
+	sorted := make([]float64, len(stat.values))
+	for i, tv := range stat.values {
+		sorted[i] = tv.v
+	}
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (median + sorted[len(sorted)/2-1]) / 2
+	}
+	if median <= 0 {
+		return
+	}
+	for _, tv := range stat.values {
+		if tv.v > median*cmn.StatsOutlierFactor || (kind != statsAggLatency && tv.v < median/cmn.StatsOutlierFactor) {
+			outliers = append(outliers, cmn.StatsOutlier{Target: tv.tid, Stat: name, Kind: kind, Value: tv.v, Median: median})
+		}
+	}
+	return
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestStatsAggregate(t *testing.T) {
+	var (
+		start = time.Now().Add(-time.Hour)
+		poll1 = start.Add(100 * time.Second)
+		poll2 = poll1.Add(10 * time.Second)
+		state statsAggState
+	)
+	snap := func(until time.Time, gets int64, latCount, latSum int64, used int64) *cmn.StatsSnapshot {
+		return &cmn.StatsSnapshot{
+			Since:     start,
+			Until:     until,
+			Counters:  map[string]int64{"get.n": gets},
+			Gauges:    map[string]int64{"used": used},
+			Latencies: map[string]cmn.LatencySnapshot{"get.ns": {Count: latCount, Sum: latSum}},
+		}
+	}
+
+	// the first aggregate: since the targets' start
+	out := state.aggregate(map[string]*cmn.StatsSnapshot{
+		"t1": snap(poll1, 1000, 100, 100*int64(time.Millisecond), 10),
+		"t2": snap(poll1, 2000, 100, 100*int64(time.Millisecond), 20),
+		"t3": snap(poll1, 3000, 100, 100*int64(time.Millisecond), 30),
+	})
+	gets := out.Counters["get.n"]
+	if out.Targets != 3 || gets.Total != 6000 || gets.Rate != 60 || gets.Min != 10 || gets.Max != 30 || gets.Avg != 20 ||
+		gets.MinTarget != "t1" || gets.MaxTarget != "t3" {
+		t.Errorf("unexpected get.n aggregate %+v", *gets)
+	}
+	if !out.Since.Equal(start) || !out.Until.Equal(poll1) {
+		t.Errorf("expected [%v, %v], got [%v, %v]", start, poll1, out.Since, out.Until)
+	}
+	if used := out.Gauges["used"]; used.Total != 60 || used.Rate != 0 || used.Avg != 20 {
+		t.Errorf("unexpected used aggregate %+v", *used)
+	}
+	if len(out.Outliers) != 0 {
+		t.Errorf("expected no outliers, got %+v", out.Outliers)
+	}
+
+	// the second aggregate: since the first; t2 is slow and gets (almost) nothing, t3 has
+	// been reset, t4 joins
+	t3 := snap(poll2, 50, 10, 10*int64(time.Millisecond), 30)
+	t3.Since = poll1.Add(5 * time.Second)
+	out = state.aggregate(map[string]*cmn.StatsSnapshot{
+		"t1": snap(poll2, 1100, 110, 110*int64(time.Millisecond), 10),
+		"t2": snap(poll2, 2001, 101, 101*int64(time.Millisecond)+int64(time.Second), 20),
+		"t3": t3,
+		"t4": snap(poll2, 10*110, 110, 110*int64(time.Millisecond), 20),
+	})
+	gets = out.Counters["get.n"]
+	// t1: 100/10s, t2: 1/10s, t3: 50/5s, t4: 1100/110s
+	if math.Abs(gets.Rate-30.1) > 1e-9 || gets.Min != 0.1 || gets.MinTarget != "t2" || gets.Total != 1100+2001+50+1100 {
+		t.Errorf("unexpected get.n aggregate %+v", *gets)
+	}
+	lat := out.Latencies["get.ns"]
+	if lat.Max != float64(time.Second+time.Millisecond) || lat.MaxTarget != "t2" || lat.Min != float64(time.Millisecond) {
+		t.Errorf("unexpected get.ns aggregate %+v", *lat)
+	}
+	if !out.Since.Equal(start) || !out.Until.Equal(poll2) {
+		t.Errorf("expected the interval since t4's start, got [%v, %v]", out.Since, out.Until)
+	}
+	outliers := make(map[string]string, len(out.Outliers))
+	for _, o := range out.Outliers {
+		outliers[o.Target+"/"+o.Stat] = o.Kind
+	}
+	if len(outliers) != 2 || outliers["t2/get.n"] != statsAggCounter || outliers["t2/get.ns"] != statsAggLatency {
+		t.Errorf("expected t2's get.n and get.ns outliers, got %+v", out.Outliers)
+	}
+
+	// a missing target keeps its previous snapshot
+	state.aggregate(map[string]*cmn.StatsSnapshot{"t1": snap(poll2.Add(time.Second), 1100, 110, 0, 10)})
+	if _, ok := state.prev["t2"]; !ok || len(state.prev) != 4 {
+		t.Errorf("expected the previous snapshots of the missing targets kept, got %d", len(state.prev))
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Stats history: each daemon keeps its stats summed up per minute, per 10 minutes, and
+// per hour for the duration of periodic.stats_history (default 24h), and returns them via
+// GET /v1/daemon?what=statshistory. The query selects the stats (metric, comma-separated;
+// all if omitted), the resolution (1m - the default, 10m, or 1h), and the time range:
+// the samples taken within `since` (all if omitted) and before `until` ago (now if omitted).
+// GET /v1/cluster?what=statshistory returns the same query answered by every daemon;
+// daemons that do not respond are skipped (and logged).
+//
+// ================================ Summary ===============================================
+
+// maxStatsHistory limits periodic.stats_history - the 1-minute samples of the entire
+// horizon are kept in memory
+const maxStatsHistory = 7 * 24 * time.Hour
+
+type statsHistQuery struct {
+	names              []string
+	res, within, until time.Duration
+}
+
+// parseStatsHistory validates periodic.stats_history; "" means 24h, zero disables the history
+func parseStatsHistory(s string) (time.Duration, error) {
+	if s == "" {
+		return 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 || d > maxStatsHistory {
+		return 0, fmt.Errorf("Invalid stats_history %q: expecting duration between 0 and %v, e.g. 24h", s, maxStatsHistory)
+	}
+	return d, nil
+}
+
+func parseStatsHistQuery(query url.Values) (q statsHistQuery, errstr string) {
+	if s := query.Get(cmn.URLParamMetric); s != "" {
+		q.names = strings.Split(s, ",")
+	}
+	q.res = stats.HistoryResolutions[0]
+	if s := query.Get(cmn.URLParamResolution); s != "" {
+		res, err := time.ParseDuration(s)
+		valid := false
+		for _, r := range stats.HistoryResolutions {
+			valid = valid || res == r
+		}
+		if err != nil || !valid {
+			errstr = fmt.Sprintf("Invalid %s=%s: expecting one of %v", cmn.URLParamResolution, s, stats.HistoryResolutions)
+			return
+		}
+		q.res = res
+	}
+	for _, p := range []struct {
+		name string
+		d    *time.Duration
+	}{{cmn.URLParamSince, &q.within}, {cmn.URLParamUntil, &q.until}} {
+		if s := query.Get(p.name); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				errstr = fmt.Sprintf("Invalid %s=%s: expecting duration, e.g. 1h", p.name, s)
+				return
+			}
+			*p.d = d
+		}
+	}
+	if q.within > 0 && q.until >= q.within {
+		errstr = fmt.Sprintf("Invalid range: %s=%v must be less than %s=%v", cmn.URLParamUntil, q.until,
+			cmn.URLParamSince, q.within)
+	}
+	return
+}
+
+func (p *proxyrunner) invokeHttpGetClusterStatsHistory(w http.ResponseWriter, r *http.Request) bool {
+	q, errstr := parseStatsHistQuery(r.URL.Query())
+	if errstr != "" {
+		p.invalmsghdlr(w, r, errstr)
+		return false
+	}
+	var (
+		smap = p.smapowner.get()
+		path = cmn.URLPath(cmn.Version, cmn.Daemon)
+		out  = &cmn.ClusterStatsHistory{
+			Proxies: make(map[string]*cmn.StatsHistory, len(smap.Pmap)),
+			Targets: make(map[string]*cmn.StatsHistory, len(smap.Tmap)),
+		}
+	)
+	collect := func(hists map[string]*cmn.StatsHistory, results chan callResult) {
+		for res := range results {
+			if res.err != nil {
+				glog.Errorf("Failed to get %s stats history: %s", res.si, res.errstr)
+				continue
+			}
+			hist := &cmn.StatsHistory{}
+			if err := jsoniter.Unmarshal(res.outjson, hist); err != nil {
+				glog.Errorf("Failed to unmarshal %s stats history, err: %v", res.si, err)
+				continue
+			}
+			hists[res.si.DaemonID] = hist
+		}
+	}
+	collect(out.Proxies, p.broadcastDash(path, r.URL.Query(), smap.Pmap))
+	collect(out.Targets, p.broadcastDash(path, r.URL.Query(), smap.Tmap))
+	out.Proxies[p.si.DaemonID] = getproxystatsrunner().History(q.names, q.res, q.within, q.until)
+
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterStatsHistory")
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Stats snapshots for the external pollers: GET /v1/daemon?what=stats&snapshot=true returns
+// a consistent copy of the daemon's counters, and the number and the sum of the latency
+// samples, since the daemon's start or the last reset (reset=true) - see stats/snapshot.go.
+// GET /v1/cluster?what=stats&snapshot=true returns the snapshots of the proxy and of all
+// targets; with reset=true, each of them starts anew. Targets that do not respond are skipped
+// (and logged) - their counters remain for the next snapshot.
+//
+// ================================ Summary ===============================================
+
+// parseStatsSnapshot parses the snapshot and reset flags of what=stats
+func parseStatsSnapshot(query url.Values) (snapshot, reset bool, errstr string) {
+	var err error
+	if snapshot, err = parsebool(query.Get(cmn.URLParamSnapshot)); err != nil {
+		return false, false, fmt.Sprintf("Invalid %s, err: %v", cmn.URLParamSnapshot, err)
+	}
+	if reset, err = parsebool(query.Get(cmn.URLParamReset)); err != nil {
+		return false, false, fmt.Sprintf("Invalid %s, err: %v", cmn.URLParamReset, err)
+	}
+	return
+}
+
+func (p *proxyrunner) invokeHttpGetClusterStatsSnapshot(w http.ResponseWriter, r *http.Request, reset bool) bool {
+	out := &cmn.ClusterStatsSnapshot{}
+	out.Targets, _ = p.targetStatsSnapshots(reset)
+	out.Proxy = getproxystatsrunner().GetSnapshot(reset)
+	jsbytes, err := jsoniter.Marshal(out)
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetClusterStatsSnapshot")
+}
+
+// targetStatsSnapshots returns the snapshots of the targets, by ID, and the IDs of the targets
+// that failed to respond
+func (p *proxyrunner) targetStatsSnapshots(reset bool) (snaps map[string]*cmn.StatsSnapshot, missing []string) {
+	var (
+		smap  = p.smapowner.get()
+		query = url.Values{}
+	)
+	snaps = make(map[string]*cmn.StatsSnapshot, len(smap.Tmap))
+	query.Add(cmn.URLParamWhat, cmn.GetWhatStats)
+	query.Add(cmn.URLParamSnapshot, "true")
+	if reset {
+		query.Add(cmn.URLParamReset, "true")
+	}
+	results := p.broadcastDash(cmn.URLPath(cmn.Version, cmn.Daemon), query, smap.Tmap)
+	for res := range results {
+		if res.err != nil {
+			glog.Errorf("Failed to get %s stats snapshot: %s", res.si, res.errstr)
+			missing = append(missing, res.si.DaemonID)
+			continue
+		}
+		snap := &cmn.StatsSnapshot{}
+		if err := jsoniter.Unmarshal(res.outjson, snap); err != nil {
+			glog.Errorf("Failed to unmarshal %s stats snapshot, err: %v", res.si, err)
+			missing = append(missing, res.si.DaemonID)
+			continue
+		}
+		snaps[res.si.DaemonID] = snap
+	}
+	return
+}
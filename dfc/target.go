@@ -8,7 +8,6 @@ package dfc
 import (
 	"context"
 	"crypto/md5"
-	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -39,7 +38,6 @@ import (
 	"github.com/NVIDIA/dfcpub/stats"
 	"github.com/NVIDIA/dfcpub/stats/statsd"
 	"github.com/NVIDIA/dfcpub/transport"
-	"github.com/OneOfOne/xxhash"
 	"github.com/json-iterator/go"
 )
 
@@ -56,7 +54,7 @@ type (
 		files        []*cmn.BucketEntry
 		prefix       string
 		marker       string
-		markerDir    string
+		delimiter    string
 		msg          *cmn.GetMsg
 		lastFilePath string
 		bucket       string
@@ -77,6 +75,7 @@ type (
 	}
 	thealthstatus struct {
 		IsRebalancing bool `json:"is_rebalancing"`
+		Emergency     bool `json:"emergency"` // capacity emergency mode (disk_config.critical_wm)
 		// NOTE: include core stats and other info as needed
 	}
 	renamectx struct {
@@ -94,11 +93,29 @@ type (
 		uxprocess      *uxprocess
 		rtnamemap      *rtnamemap
 		prefetchQueue  chan filesWithDeadline
+		prefbudgets    *prefetchBudgets // prefetch cost budgets - prefetchbudget.go
 		authn          *authManager
 		clusterStarted int64
 		regstate       regstate // registration state - the state of being registered (with the proxy) or maybe not
 		fsprg          fsprungroup
 		readahead      readaheader
+		coldData       *coldDataCache
+		putGroups      *putGroups
+		asyncCksum     *asyncCksum
+		groupSyncer    *groupSyncer
+		rebThroughput  int64 // bytes/sec measured during the last global rebalance (atomic)
+		pruning        int64 // empty directories are being pruned (atomic)
+		pinning        int64 // pinned objects are being accounted for (atomic)
+		listCache      *listCache
+		mmapCache      *mmapCache
+		fdCache        *fdCache
+		mdCache        *mdCache
+		msgbus         *msgBus
+		putDedup       *putDedup
+		renaming       *renamingBuckets // object I/O into the buckets being renamed (renamebucket.go)
+		archives       archiveStatuses  // bucket export and import (bucketarchive.go)
+		recountCh      chan struct{}    // requests to recount the objects per bucket (capacity.go)
+		snapmtx        sync.Mutex       // bucket snapshots being created or deleted (snapshot.go)
 	}
 )
 
@@ -114,6 +131,18 @@ func (t *targetrunner) Run() error {
 	dryinit()
 
 	t.rtnamemap = newrtnamemap(128) // lock/unlock name
+	t.coldData = newColdDataCache()
+	t.putGroups = newPutGroups()
+	t.asyncCksum = newAsyncCksum(t)
+	t.groupSyncer = newGroupSyncer()
+	t.listCache = newListCache()
+	t.mmapCache = newMmapCache()
+	t.fdCache = newFDCache()
+	t.mdCache = newMDCache()
+	t.msgbus = newMsgBus()
+	t.putDedup = newPutDedup()
+	t.renaming = newRenamingBuckets()
+	t.recountCh = make(chan struct{}, 1)
 
 	bucketmd := newBucketMD()
 	t.bmdowner.put(bucketmd)
@@ -139,6 +168,8 @@ func (t *targetrunner) Run() error {
 	}
 
 	go t.pollClusterStarted()
+	go t.asyncCksum.run()
+	go t.asyncCksum.rescan()
 
 	err := t.createBucketDirs("local", ctx.config.LocalBuckets, fs.Mountpaths.MakePathLocal)
 	if err != nil {
@@ -150,7 +181,13 @@ func (t *targetrunner) Run() error {
 		glog.Error(err)
 		os.Exit(1)
 	}
+	removeStalePutGroups()
 	t.detectMpathChanges()
+	go t.runRecount()
+	t.requestRecount()
+	if ctx.config.MDCache.Enabled {
+		t.mdCache.load(ctx.config.MDCache.Size)
+	}
 
 	// cloud provider
 	if ctx.config.CloudProvider == cmn.ProviderAmazon {
@@ -164,6 +201,7 @@ func (t *targetrunner) Run() error {
 
 	// prefetch
 	t.prefetchQueue = make(chan filesWithDeadline, prefetchChanSize)
+	t.prefbudgets = newPrefetchBudgets()
 
 	t.authn = &authManager{
 		tokens:        make(map[string]*authRec),
@@ -176,11 +214,14 @@ func (t *targetrunner) Run() error {
 	//
 
 	// Public network
-	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Buckets)+"/", t.bucketHandler)
-	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", t.objectHandler)
+	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Buckets)+"/", t.withMaintenance(t.bucketHandler))
+	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", withCORS(t.withMaintenance(t.objectHandler)))
+	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Snapshots)+"/", t.snapshotHandler)
 	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Daemon), t.daemonHandler)
-	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Push)+"/", t.pushHandler)
+	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Push)+"/", t.withMaintenance(t.pushHandler))
 	t.registerPublicNetHandler(cmn.URLPath(cmn.Version, cmn.Tokens), t.tokenHandler)
+	t.registerPublicNetHandler(cmn.URLPath(cmn.Metrics), t.metricsHandler)
+	t.registerFaultHandler()                                // debug builds only
 	transport.SetMux(cmn.NetworkPublic, t.publicServer.mux) // to register transport handlers at runtime
 	t.registerPublicNetHandler("/", cmn.InvalidHandler)
 
@@ -188,6 +229,7 @@ func (t *targetrunner) Run() error {
 	t.registerIntraControlNetHandler(cmn.URLPath(cmn.Version, cmn.Metasync), t.metasyncHandler)
 	t.registerIntraControlNetHandler(cmn.URLPath(cmn.Version, cmn.Health), t.healthHandler)
 	t.registerIntraControlNetHandler(cmn.URLPath(cmn.Version, cmn.Vote), t.voteHandler)
+	t.registerIntraControlNetHandler(cmn.URLPath(cmn.Version, cmn.MsgBus), t.msgbusHandler)
 	if ctx.config.Net.UseIntraControl {
 		transport.SetMux(cmn.NetworkIntraControl, t.intraControlServer.mux) // to register transport handlers at runtime
 		t.registerIntraControlNetHandler("/", cmn.InvalidHandler)
@@ -196,7 +238,7 @@ func (t *targetrunner) Run() error {
 	// Intra data network
 	if ctx.config.Net.UseIntraData {
 		transport.SetMux(cmn.NetworkIntraData, t.intraDataServer.mux) // to register transport handlers at runtime
-		t.registerIntraDataNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", t.objectHandler)
+		t.registerIntraDataNetHandler(cmn.URLPath(cmn.Version, cmn.Objects)+"/", t.withMaintenance(t.objectHandler))
 		t.registerIntraDataNetHandler("/", cmn.InvalidHandler)
 	}
 
@@ -205,9 +247,9 @@ func (t *targetrunner) Run() error {
 	pid := int64(os.Getpid())
 	t.uxprocess = &uxprocess{time.Now(), strconv.FormatInt(pid, 16), pid}
 
-	_ = t.initStatsD("dfctarget")
 	sr := getstorstatsrunner()
-	sr.Core.StatsdC = &t.statsdC
+	sr.Core.Sink = t.metrics
+	sr.Core.LatencyUnit = &ctx.config.Periodic.StatsLatencyUnit
 
 	getfshealthchecker().SetDispatcher(t)
 
@@ -221,6 +263,7 @@ func (t *targetrunner) Run() error {
 		go runLocalRebalanceOnce.Do(f) // only once at startup
 	}
 
+	t.inited() // registered and about to serve - see rungroup.status
 	return t.httprunner.run()
 }
 
@@ -231,6 +274,7 @@ func (t *targetrunner) Stop(err error) {
 	if t.publicServer.s != nil {
 		t.unregister() // ignore errors
 	}
+	t.SaveMDCache()
 
 	t.httprunner.stop(err)
 	if sleep {
@@ -314,21 +358,25 @@ func (t *targetrunner) RunLRU() {
 	}
 	wg := &sync.WaitGroup{}
 
-	glog.Infof("LRU: %s started: dont-evict-time %v", xlru, ctx.config.LRU.DontEvictTime)
+	if getstorstatsrunner().Emergency() {
+		glog.Warningf("LRU: %s started in capacity emergency mode: evicting regardless of dont-evict-time", xlru)
+	} else {
+		glog.Infof("LRU: %s started: dont-evict-time %v", xlru, ctx.config.LRU.DontEvictTime)
+	}
 
 	//
 	// NOTE the sequence: LRU local buckets first, Cloud buckets - second
 	//
 
 	availablePaths, _ := fs.Mountpaths.Get()
-	for path, mpathInfo := range availablePaths {
-		lctx := t.newlru(xlru, mpathInfo, fs.Mountpaths.MakePathLocal(path))
+	for _, mpathInfo := range availablePaths {
+		lctx := t.newlru(xlru, mpathInfo, fs.WalkLocal)
 		wg.Add(1)
 		go lctx.onelru(wg)
 	}
 	wg.Wait()
-	for path, mpathInfo := range availablePaths {
-		lctx := t.newlru(xlru, mpathInfo, fs.Mountpaths.MakePathCloud(path))
+	for _, mpathInfo := range availablePaths {
+		lctx := t.newlru(xlru, mpathInfo, fs.WalkCloud)
 		wg.Add(1)
 		go lctx.onelru(wg)
 	}
@@ -349,16 +397,31 @@ func (t *targetrunner) Prefetch() {
 	if xpre == nil {
 		return
 	}
+	throttlers := make(map[string]fs.Throttler) // mountpath => QoS throttler
 loop:
 	for {
 		select {
 		case fwd := <-t.prefetchQueue:
+			bucket := fwd.bucket
 			if !fwd.deadline.IsZero() && time.Now().After(fwd.deadline) {
+				for _, est := range fwd.estimates {
+					t.prefetchCharge(bucket, &est, 0, 0) // refund
+				}
 				continue
 			}
-			bucket := fwd.bucket
-			for _, objname := range fwd.objnames {
-				t.prefetchMissing(fwd.ctx, objname, bucket)
+			if getstorstatsrunner().Emergency() {
+				glog.Warningf("Capacity emergency mode: not prefetching %d objects of %s", len(fwd.objnames), bucket)
+				for _, est := range fwd.estimates {
+					t.prefetchCharge(bucket, &est, 0, 0) // refund
+				}
+			} else {
+				for _, objname := range fwd.objnames {
+					var est *prefetchEst
+					if e, ok := fwd.estimates[objname]; ok {
+						est = &e
+					}
+					t.prefetchMissing(fwd.ctx, objname, bucket, est, throttlers)
+				}
 			}
 
 			// Signal completion of prefetch
@@ -399,6 +462,13 @@ func (t *targetrunner) bucketHandler(w http.ResponseWriter, r *http.Request) {
 
 // verb /v1/objects
 func (t *targetrunner) objectHandler(w http.ResponseWriter, r *http.Request) {
+	if bucket := objectBucket(r); bucket != "" {
+		if !t.renaming.enter(bucket) {
+			t.renamingReject(w, r, bucket)
+			return
+		}
+		defer t.renaming.leave(bucket)
+	}
 	switch r.Method {
 	case http.MethodGet:
 		t.httpobjget(w, r)
@@ -430,6 +500,14 @@ func (t *targetrunner) httpbckget(w http.ResponseWriter, r *http.Request) {
 		t.getbucketnames(w, r)
 		return
 	}
+	switch r.URL.Query().Get(cmn.URLParamWhat) {
+	case cmn.GetWhatColdData:
+		t.getColdData(w, r, bucket)
+		return
+	case cmn.GetWhatArchive:
+		t.getArchiveStatus(w, r, bucket)
+		return
+	}
 	s := fmt.Sprintf("Invalid route /buckets/%s", bucket)
 	t.invalmsghdlr(w, r, s)
 }
@@ -448,9 +526,10 @@ func (t *targetrunner) httpobjget(w http.ResponseWriter, r *http.Request) {
 		started                       time.Time
 		errcode                       int
 		coldget, vchanged, inNextTier bool
+		cksumPending, uncached        bool
 		err                           error
 		file                          *os.File
-		written                       int64
+		written, sent                 int64
 	)
 	//
 	// 1. start, validate, readahead
@@ -564,6 +643,7 @@ func (t *targetrunner) httpobjget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	uncached = coldget // as opposed to the cached objects that have changed or got corrupted
 	if !coldget && !islocal {
 		if versioncfg.ValidateWarmGet && (version != "" &&
 			t.versioningConfigured(bucket)) {
@@ -577,7 +657,8 @@ func (t *targetrunner) httpobjget(w http.ResponseWriter, r *http.Request) {
 			coldget = vchanged
 		}
 	}
-	if !coldget && cksumcfg.ValidateWarmGet && cksumcfg.Checksum != cmn.ChecksumNone {
+	cksumPending = !coldget && t.asyncCksum.pending(fqn)
+	if !coldget && !cksumPending && cksumcfg.ValidateWarmGet && cksumcfg.Checksum != cmn.ChecksumNone {
 		validChecksum, errstr := t.validateObjectChecksum(fqn, cksumcfg.Checksum, size)
 		if errstr != "" {
 			t.invalmsghdlr(w, r, errstr, http.StatusInternalServerError)
@@ -588,6 +669,8 @@ func (t *targetrunner) httpobjget(w http.ResponseWriter, r *http.Request) {
 			if islocal {
 				if err := os.Remove(fqn); err != nil {
 					glog.Warningf("Bad checksum, failed to remove %s/%s, err: %v", bucket, objname, err)
+				} else {
+					addBucketCapacity(fqn, bucket, islocal, -1, -size)
 				}
 				t.invalmsghdlr(w, r, fmt.Sprintf("Bad checksum %s/%s", bucket, objname), http.StatusInternalServerError)
 				t.rtnamemap.Unlock(uname, false)
@@ -598,6 +681,9 @@ func (t *targetrunner) httpobjget(w http.ResponseWriter, r *http.Request) {
 	}
 	if coldget && !dryRun.disk {
 		t.rtnamemap.Unlock(uname, false)
+		if uncached && !islocal && t.passthrough(ct, w, r, bucketmd, bucket, objname, rangeOff, rangeLen, started) {
+			return
+		}
 		if props, errstr, errcode = t.coldget(ct, bucket, objname, false); errstr != "" {
 			if errcode == 0 {
 				t.invalmsghdlr(w, r, errstr)
@@ -622,11 +708,26 @@ existslocally:
 		rahSize            int64
 		rahfcacher, rahsgl = t.readahead.get(fqn)
 		sendMore           bool
+		mapped             *mmapEntry
+		cached             *fdEntry
+		direct             *directReader
+		zr                 *zstdReader
+		fault              = injectFault(cmn.FaultPointGet, bucket, objname)
 	)
 	defer func() {
 		rahfcacher.got()
+		if mapped != nil {
+			t.mmapCache.release(mapped)
+		}
+		if cached != nil {
+			t.fdCache.release(cached)
+		}
 		t.rtnamemap.Unlock(uname, false)
-		if file != nil {
+		if zr != nil {
+			zr.Close()
+		} else if direct != nil {
+			direct.Close()
+		} else if file != nil {
 			file.Close()
 		}
 		if buf != nil {
@@ -637,18 +738,23 @@ existslocally:
 		}
 	}()
 
-	cksumRange := cksumcfg.Checksum != cmn.ChecksumNone && rangeLen > 0 && cksumcfg.EnableReadRangeChecksum
-	if !coldget && !cksumRange && cksumcfg.Checksum != cmn.ChecksumNone {
-		xxHashBinary, errstr := Getxattr(fqn, cmn.XattrXXHashVal)
-		if errstr == "" && xxHashBinary != nil {
-			nhobj = newcksumvalue(cksumcfg.Checksum, string(xxHashBinary))
-		}
+	md := t.objMD(fqn, nil)
+	compressed := md.Compressed
+	if compressed {
+		rahsgl = nil // readahead has read the compressed content
+	}
+	cksumRange := !compressed && cksumcfg.Checksum != cmn.ChecksumNone && rangeLen > 0 && cksumcfg.EnableReadRangeChecksum
+	if !coldget && !cksumRange && cksumcfg.Checksum != cmn.ChecksumNone && md.Cksum != "" {
+		nhobj = newcksumvalue(cmn.ParseStoredCksum([]byte(md.Cksum)))
 	}
 	if nhobj != nil && !cksumRange {
 		htype, hval := nhobj.get()
 		w.Header().Add(cmn.HeaderDFCChecksumType, htype)
 		w.Header().Add(cmn.HeaderDFCChecksumVal, hval)
 	}
+	if cksumPending {
+		w.Header().Add(cmn.HeaderDFCChecksumPending, "true")
+	}
 	if props != nil && props.version != "" {
 		w.Header().Add(cmn.HeaderDFCObjVersion, props.version)
 	}
@@ -659,13 +765,13 @@ existslocally:
 			rd := readers.NewRandReader(dryRun.size)
 			if _, err = io.Copy(w, rd); err != nil {
 				errstr = fmt.Sprintf("dry-run: failed to send random response, err: %v", err)
-				t.statsif.Add(stats.ErrGetCount, 1)
+				t.statsif.AddBucket(bucket, stats.NamedVal64{Name: stats.ErrGetCount, Val: 1})
 				return
 			}
 		}
 
 		delta := time.Since(started)
-		t.statsif.AddMany(stats.NamedVal64{stats.GetCount, 1}, stats.NamedVal64{stats.GetLatency, int64(delta)})
+		t.statsif.AddBucket(bucket, stats.NamedVal64{stats.GetCount, 1}, stats.NamedVal64{stats.GetLatency, int64(delta)})
 		return
 	}
 	if size == 0 {
@@ -680,7 +786,21 @@ existslocally:
 			sendMore = rahSize < rangeLen
 		}
 	}
-	if rahSize == 0 || sendMore {
+	if fault == cmn.FaultEIO {
+		errstr = fmt.Sprintf("Failed to open %s, err: injected EIO", fqn)
+		t.invalmsghdlr(w, r, errstr, http.StatusInternalServerError)
+		return
+	}
+	if rahsgl == nil && !cksumRange && !compressed && ctx.config.MmapRead.Enabled && size <= ctx.config.MmapRead.MaxObjSize {
+		mapped = t.mmapGet(fqn)
+	}
+	if mapped == nil && rahsgl == nil && !cksumRange && !compressed && ctx.config.FDCache.Enabled {
+		cached = t.fdGet(fqn)
+	}
+	if mapped == nil && cached == nil && rahsgl == nil && rangeLen == 0 && !compressed && useDirectIO(size) {
+		direct = newDirectReader(fqn)
+	}
+	if mapped == nil && cached == nil && direct == nil && (rahSize == 0 || sendMore) {
 		file, err = os.Open(fqn)
 		if err != nil {
 			if os.IsPermission(err) {
@@ -693,10 +813,42 @@ existslocally:
 			t.fshc(err, fqn)
 			return
 		}
+		if compressed {
+			if zr, err = newZstdReader(file); err != nil {
+				errstr = fmt.Sprintf("Failed to decompress %s, err: %v", fqn, err)
+				t.invalmsghdlr(w, r, errstr, http.StatusInternalServerError)
+				return
+			}
+		}
 	}
 
 send:
-	if rahsgl != nil && rahSize > 0 {
+	if zr != nil {
+		reader = zr
+		if rangeLen > 0 {
+			if _, err = io.CopyN(ioutil.Discard, zr, rangeOff); err != nil {
+				errstr = fmt.Sprintf("Failed to decompress %s, err: %v", fqn, err)
+				t.invalmsghdlr(w, r, errstr, http.StatusInternalServerError)
+				return
+			}
+			reader = io.LimitReader(zr, rangeLen)
+			buf, slab = gmem2.AllocFromSlab2(rangeLen)
+		} else {
+			buf, slab = gmem2.AllocFromSlab2(size)
+		}
+	} else if mapped != nil {
+		reader = mapped.reader(rangeOff, rangeLen)
+	} else if direct != nil {
+		reader = direct
+		buf, slab = gmem2.AllocFromSlab2(size)
+	} else if cached != nil {
+		reader = cached.reader(rangeOff, rangeLen)
+		if rangeLen == 0 {
+			buf, slab = gmem2.AllocFromSlab2(size)
+		} else {
+			buf, slab = gmem2.AllocFromSlab2(rangeLen)
+		}
+	} else if rahsgl != nil && rahSize > 0 {
 		// reader = memsys.NewReader(rahsgl) - NOTE
 		reader = rahsgl
 		slab = rahsgl.Slab()
@@ -717,7 +869,7 @@ send:
 		if cksumRange {
 			cmn.Assert(rahSize == 0, "NOT IMPLEMENTED YET") // TODO
 			var cksum string
-			cksum, sgl, rangeReader, errstr = t.rangeCksum(file, fqn, rangeOff, rangeLen, buf)
+			cksum, sgl, rangeReader, errstr = t.rangeCksum(cksumcfg.Checksum, file, fqn, rangeOff, rangeLen, buf)
 			if errstr != "" {
 				t.invalmsghdlr(w, r, errstr, http.StatusInternalServerError)
 				return
@@ -730,6 +882,9 @@ send:
 			reader = io.NewSectionReader(file, rangeOff, rangeLen)
 		}
 	}
+	if fault == cmn.FaultShortRead {
+		reader = io.LimitReader(reader, size/2)
+	}
 
 	if !dryRun.network {
 		written, err = io.CopyBuffer(w, reader, buf)
@@ -743,17 +898,26 @@ send:
 			errstr = fmt.Sprintf("dry-run: failed to read/discard %s, err: %v", fqn, err)
 		}
 		t.fshc(err, fqn)
-		t.statsif.Add(stats.ErrGetCount, 1)
+		t.statsif.AddBucket(bucket, stats.NamedVal64{Name: stats.ErrGetCount, Val: 1})
 		return
 	}
+	sent += written
 	if sendMore {
 		rahsgl = nil
 		sendMore = false
 		goto send
 	}
 
-	if !coldget && bucketmd.lruEnabled(bucket) {
-		getatimerunner().Touch(fqn)
+	if !coldget {
+		switch bucketmd.atimePolicy(bucket) {
+		case cmn.AtimeTrack:
+			getatimerunner().Touch(fqn)
+		case cmn.AtimeRelaxed:
+			getatimerunner().TouchRelaxed(fqn)
+		}
+	}
+	if compressed && ctx.config.Compression.DecompressHot {
+		go t.decompressObj(bucket, objname, fqn)
 	}
 	if glog.V(4) {
 		s := fmt.Sprintf("GET: %s/%s, %.2f MB, %d µs", bucket, objname, float64(written)/cmn.MiB, time.Since(started)/1000)
@@ -764,13 +928,15 @@ send:
 	}
 
 	delta := time.Since(started)
-	t.statsif.AddMany(stats.NamedVal64{stats.GetCount, 1}, stats.NamedVal64{stats.GetLatency, int64(delta)})
+	t.statsif.AddBucket(bucket, stats.NamedVal64{stats.GetCount, 1}, stats.NamedVal64{stats.GetLatency, int64(delta)})
+	getstorstatsrunner().AddBucketIO(bucket, &cmn.BucketIOStats{GetCount: 1, GetSize: sent})
+	getiostatrunner().AddForeground(fqn)
 }
 
-func (t *targetrunner) rangeCksum(file *os.File, fqn string, offset, length int64, buf []byte) (
+func (t *targetrunner) rangeCksum(kind string, file *os.File, fqn string, offset, length int64, buf []byte) (
 	cksum string, sgl *memsys.SGL, rangeReader io.ReadSeeker, errstr string) {
 	rangeReader = io.NewSectionReader(file, offset, length)
-	xx := xxhash.New64()
+	xx := cmn.NewCksumHash(kind)
 	if length <= maxBytesInMem {
 		sgl = gmem2.NewSGL(length)
 		_, err := cmn.ReceiveAndChecksum(sgl, rangeReader, buf, xx)
@@ -790,10 +956,7 @@ func (t *targetrunner) rangeCksum(file *os.File, fqn string, offset, length int6
 		return
 	}
 
-	hashIn64 := xx.Sum64()
-	hashInBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(hashInBytes, hashIn64)
-	cksum = hex.EncodeToString(hashInBytes)
+	cksum = cmn.CksumHex(xx)
 	return
 }
 
@@ -857,11 +1020,23 @@ func (t *targetrunner) httpobjput(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if getstorstatsrunner().Emergency() {
+			t.invalmsghdlr(w, r, "Capacity emergency mode: writes are refused until used capacity drops below "+
+				strconv.FormatInt(ctx.config.LRU.HighWM, 10)+"%", http.StatusInsufficientStorage)
+			return
+		}
 		errstr := ""
 		errcode := 0
 		if replica, replicaSrc := isReplicationPUT(r); !replica {
 			// regular PUT
-			errstr, errcode = t.doput(w, r, bucket, objname)
+			key := r.Header.Get(cmn.HeaderDFCIdempotencyKey)
+			if key == "" || ctx.config.PutDedup.TTL == 0 {
+				errstr, errcode = t.doput(w, r, bucket, objname)
+			} else {
+				errstr, errcode = t.putOnce(r, key, bucket, objname, func() (string, int) {
+					return t.doput(w, r, bucket, objname)
+				})
+			}
 		} else {
 			// replication PUT
 			errstr = t.doReplicationPut(w, r, bucket, objname, replicaSrc)
@@ -1035,11 +1210,29 @@ func (t *targetrunner) httpbckpost(w http.ResponseWriter, r *http.Request) {
 		tag, ok := t.listbucket(w, r, lbucket, &msg)
 		if ok {
 			delta := time.Since(started)
-			t.statsif.AddMany(stats.NamedVal64{stats.ListCount, 1}, stats.NamedVal64{stats.ListLatency, int64(delta)})
+			t.statsif.AddBucket(lbucket, stats.NamedVal64{stats.ListCount, 1}, stats.NamedVal64{stats.ListLatency, int64(delta)})
 			if glog.V(3) {
 				glog.Infof("LIST %s: %s, %d µs", tag, lbucket, int64(delta/time.Microsecond))
 			}
 		}
+	case cmn.ActListInval:
+		t.listCache.inval(apitems[0])
+	case cmn.ActChecksums:
+		t.objChecksums(w, r, apitems[0], &msg)
+	case cmn.ActExport, cmn.ActImport:
+		t.bucketArchive(w, r, apitems[0], &msg)
+	case cmn.ActCommitGroup, cmn.ActAbortGroup:
+		bucket := apitems[0]
+		if !t.validatebckname(w, r, bucket) {
+			return
+		}
+		t.putGroupAction(w, r, bucket, &msg)
+	case cmn.ActRenameBucket, cmn.ActAbortRenameBucket:
+		bucket := apitems[0]
+		if !t.validatebckname(w, r, bucket) {
+			return
+		}
+		t.renameBucketAction(w, r, bucket, &msg)
 	case cmn.ActRechecksum:
 		bucket := apitems[0]
 		if !t.validatebckname(w, r, bucket) {
@@ -1047,6 +1240,12 @@ func (t *targetrunner) httpbckpost(w http.ResponseWriter, r *http.Request) {
 		}
 		// re-checksum the bucket and return
 		t.runRechecksumBucket(bucket)
+	case cmn.ActSnapshot:
+		bucket := apitems[0]
+		if !t.validatebckname(w, r, bucket) {
+			return
+		}
+		t.createSnapshot(w, r, bucket, &msg)
 	default:
 		t.invalmsghdlr(w, r, "Unexpected action "+msg.Action)
 	}
@@ -1063,6 +1262,8 @@ func (t *targetrunner) httpobjpost(w http.ResponseWriter, r *http.Request) {
 		t.renamefile(w, r, msg)
 	case cmn.ActReplicate:
 		t.replicate(w, r, msg)
+	case cmn.ActSelect:
+		t.selectObject(w, r, msg)
 	default:
 		t.invalmsghdlr(w, r, "Unexpected action "+msg.Action)
 	}
@@ -1144,6 +1345,23 @@ func (t *targetrunner) httpbckhead(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add(cmn.HeaderBucketDontEvictTime, props.DontEvictTimeStr)
 	w.Header().Add(cmn.HeaderBucketCapUpdTime, props.CapacityUpdTimeStr)
 	w.Header().Add(cmn.HeaderBucketLRUEnabled, strconv.FormatBool(props.LRUEnabled))
+	w.Header().Add(cmn.HeaderBucketListProps, props.ListProps)
+	w.Header().Add(cmn.HeaderBucketListTimeFormat, props.ListTimeFormat)
+	w.Header().Add(cmn.HeaderBucketPublicRead, strconv.FormatBool(props.PublicRead))
+	w.Header().Add(cmn.HeaderBucketSyncPolicy, props.SyncPolicy)
+	w.Header().Add(cmn.HeaderBucketDebugSample, strconv.FormatFloat(props.DebugSample, 'f', -1, 64))
+	w.Header().Add(cmn.HeaderBucketAtimePolicy, props.AtimePolicy)
+	w.Header().Add(cmn.HeaderBucketPassthroughSize, strconv.FormatInt(props.PassthroughSize, 10))
+	if props.PrefetchBudget != nil {
+		b, err := jsoniter.Marshal(props.PrefetchBudget)
+		cmn.Assert(err == nil, err)
+		w.Header().Add(cmn.HeaderBucketPrefetchBudget, string(b))
+	}
+	if props.Pinned != nil {
+		b, err := jsoniter.Marshal(props.Pinned)
+		cmn.Assert(err == nil, err)
+		w.Header().Add(cmn.HeaderBucketPinned, string(b))
+	}
 }
 
 // HEAD /v1/objects/bucket-name/object-name
@@ -1195,6 +1413,10 @@ func (t *targetrunner) httpobjhead(w http.ResponseWriter, r *http.Request) {
 		objmeta = make(cmn.SimpleKVs)
 		objmeta["size"] = strconv.FormatInt(size, 10)
 		objmeta["version"] = version
+		if expires, errstr := Getxattr(fqn, cmn.XattrExpires); errstr == "" && len(expires) != 0 {
+			objmeta[cmn.HeaderDFCObjExpires] = string(expires)
+		}
+		objmeta[cmn.HeaderDFCObjAccessCount] = strconv.FormatUint(uint64(getatimerunner().AccessCount(fqn)), 10)
 		glog.Infoln("httpobjhead FOUND:", bucket, objname, size, version)
 	} else {
 		objmeta, errstr, errcode = getcloudif().headobject(t.contextWithAuth(r), bucket, objname)
@@ -1284,7 +1506,7 @@ func (t *targetrunner) healthHandler(w http.ResponseWriter, r *http.Request) {
 	if !aborted && !running {
 		aborted, running = t.xactinp.isAbortedOrRunningLocalRebalance()
 	}
-	status := &thealthstatus{IsRebalancing: aborted || running}
+	status := &thealthstatus{IsRebalancing: aborted || running, Emergency: getstorstatsrunner().Emergency()}
 
 	jsbytes, err := jsoniter.Marshal(status)
 	cmn.Assert(err == nil, err)
@@ -1351,6 +1573,9 @@ func (t *targetrunner) pushHandler(w http.ResponseWriter, r *http.Request) {
 //
 //====================================================================================
 func (t *targetrunner) renameLB(bucketFrom, bucketTo string, p cmn.BucketProps, clone *bucketMD) (errstr string) {
+	if hasSnapshots(bucketFrom) {
+		return fmt.Sprintf("Local bucket %s has snapshots: cannot rename", bucketFrom)
+	}
 	// ready to receive migrated obj-s _after_ that point
 	// insert directly w/o incrementing the version (metasyncer will do at the end of the operation)
 	clone.LBmap[bucketTo] = p
@@ -1466,7 +1691,7 @@ func (t *targetrunner) getFromNeighbor(bucket, objname string, r *http.Request,
 	defer cancel()
 	newrequest := newr.WithContext(contextwith)
 
-	response, err := t.httpclientLongTimeout.Do(newrequest)
+	response, err := t.intra.Do(newrequest, true)
 	if err != nil {
 		glog.Errorf("Failed to GET redirect URL %q, err: %v", geturl, err)
 		return
@@ -1502,7 +1727,7 @@ func (t *targetrunner) getFromNeighbor(bucket, objname string, r *http.Request,
 		}
 	}
 	// commit
-	if err = os.Rename(getfqn, fqn); err != nil {
+	if err = commitObj(getfqn, fqn, bucket, islocal); err != nil {
 		glog.Errorf("Failed to rename %s => %s, err: %v", getfqn, fqn, err)
 		return
 	}
@@ -1576,12 +1801,16 @@ func (t *targetrunner) coldget(ct context.Context, bucket, objname string, prefe
 		props = &objectProps{version: version, size: size}
 		xxHashBinary, _ := Getxattr(fqn, cmn.XattrXXHashVal)
 		if xxHashBinary != nil {
-			props.nhobj = newcksumvalue(cksumcfg.Checksum, string(xxHashBinary))
+			props.nhobj = newcksumvalue(cmn.ParseStoredCksum(xxHashBinary))
 		}
 		glog.Infof("cold GET race: %s/%s, size=%d, version=%s - nothing to do", bucket, objname, size, version)
 		goto ret
 	}
 	// cold
+	if errstr, errcode = t.checkReservedSpace(fqn, 0); errstr != "" {
+		t.rtnamemap.Unlock(uname, true)
+		return
+	}
 	nextTierURL = bucketProps.NextTierURL
 	if nextTierURL != "" && bucketProps.ReadPolicy == cmn.RWPolicyNextTier {
 		if inNextTier, errstr, errcode = t.objectInNextTier(nextTierURL, bucket, objname); errstr != "" {
@@ -1596,6 +1825,11 @@ func (t *targetrunner) coldget(ct context.Context, bucket, objname string, prefe
 		}
 	}
 	if !inNextTier || (inNextTier && errstr != "") {
+		if injectFault(cmn.FaultPointCloud, bucket, objname) == cmn.Fault503 {
+			errstr, errcode = fmt.Sprintf("%s/%s: injected Cloud 503", bucket, objname), http.StatusServiceUnavailable
+			t.rtnamemap.Unlock(uname, true)
+			return
+		}
 		if props, errstr, errcode = getcloudif().getobj(ct, getfqn, bucket, objname); errstr != "" {
 			t.rtnamemap.Unlock(uname, true)
 			return
@@ -1610,7 +1844,7 @@ func (t *targetrunner) coldget(ct context.Context, bucket, objname string, prefe
 			}
 		}
 	}()
-	if err = os.Rename(getfqn, fqn); err != nil {
+	if err = commitObj(getfqn, fqn, bucket, islocal); err != nil {
 		errstr = fmt.Sprintf("Unexpected failure to rename %s => %s, err: %v", getfqn, fqn, err)
 		t.fshc(err, fqn)
 		return
@@ -1626,10 +1860,10 @@ ret:
 		t.rtnamemap.Unlock(uname, true)
 	} else {
 		if vchanged {
-			t.statsif.AddMany(stats.NamedVal64{stats.GetColdCount, 1}, stats.NamedVal64{stats.GetColdSize, props.size},
+			t.statsif.AddBucket(bucket, stats.NamedVal64{stats.GetColdCount, 1}, stats.NamedVal64{stats.GetColdSize, props.size},
 				stats.NamedVal64{stats.VerChangeSize, props.size}, stats.NamedVal64{stats.VerChangeCount, 1})
 		} else {
-			t.statsif.AddMany(stats.NamedVal64{stats.GetColdCount, 1}, stats.NamedVal64{stats.GetColdSize, props.size})
+			t.statsif.AddBucket(bucket, stats.NamedVal64{stats.GetColdCount, 1}, stats.NamedVal64{stats.GetColdSize, props.size})
 		}
 		t.rtnamemap.DowngradeLock(uname)
 	}
@@ -1657,9 +1891,11 @@ func (t *targetrunner) lookupLocally(bucket, objname, fqn string) (coldget bool,
 		return
 	}
 	size = finfo.Size()
-	if bytes, errs := Getxattr(fqn, cmn.XattrObjVersion); errs == "" {
-		version = string(bytes)
+	md := t.objMD(fqn, finfo)
+	if md.Compressed {
+		size = md.OrigSize
 	}
+	version = md.Version
 	return
 }
 
@@ -1703,6 +1939,9 @@ func (t *targetrunner) prepareLocalObjectList(bucket string, msg *cmn.GetMsg) (*
 		err        error
 	}
 
+	if msg.GetDelimiter != "" && msg.GetDelimiter != cmn.ListDelimiter {
+		return nil, fmt.Errorf("Unsupported delimiter %q: only %q is supported", msg.GetDelimiter, cmn.ListDelimiter)
+	}
 	availablePaths, _ := fs.Mountpaths.Get()
 	ch := make(chan *mresp, len(availablePaths))
 	wg := &sync.WaitGroup{}
@@ -1764,6 +2003,12 @@ func (t *targetrunner) prepareLocalObjectList(bucket string, msg *cmn.GetMsg) (*
 		fileCount += r.infos.fileCount
 	}
 
+	if msg.GetDelimiter != "" {
+		sort.Slice(bckEntries, func(i, j int) bool { return bckEntries[i].Name < bckEntries[j].Name })
+		bckEntries = uniqueEntries(bckEntries)
+		fileCount = len(bckEntries)
+	}
+
 	// sort the result and return only first `pageSize` entries
 	marker := ""
 	if fileCount > pageSize {
@@ -1787,13 +2032,34 @@ func (t *targetrunner) prepareLocalObjectList(bucket string, msg *cmn.GetMsg) (*
 
 	if strings.Contains(msg.GetProps, cmn.GetTargetURL) {
 		for _, e := range bucketList.Entries {
-			e.TargetURL = t.si.PublicNet.DirectURL
+			if e.Type != cmn.BucketEntryDir {
+				e.TargetURL = t.si.PublicNet.DirectURL
+			}
 		}
 	}
 
 	return bucketList, nil
 }
 
+// uniqueEntries removes duplicates from the list sorted by name: with delimiter, the
+// same directory can be listed by multiple mountpaths and targets
+func uniqueEntries(entries []*cmn.BucketEntry) []*cmn.BucketEntry {
+	if len(entries) < 2 {
+		return entries
+	}
+	j := 1
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Name != entries[j-1].Name {
+			entries[j] = entries[i]
+			j++
+		}
+	}
+	for i := j; i < len(entries); i++ {
+		entries[i] = nil
+	}
+	return entries[:j]
+}
+
 func (t *targetrunner) getbucketnames(w http.ResponseWriter, r *http.Request) {
 	bucketmd := t.bmdowner.get()
 
@@ -1892,7 +2158,7 @@ func (t *targetrunner) listbucket(w http.ResponseWriter, r *http.Request, bucket
 		jsbytes, errstr, errcode = t.listCachedObjects(bucket, &msg)
 	} else {
 		tag = "cloud"
-		jsbytes, errstr, errcode = getcloudif().listbucket(t.contextWithAuth(r), bucket, &msg)
+		jsbytes, errstr, errcode = t.listCloudBucket(r, bucket, &msg)
 	}
 	if errstr != "" {
 		if errcode == 0 {
@@ -1907,12 +2173,6 @@ func (t *targetrunner) listbucket(w http.ResponseWriter, r *http.Request, bucket
 }
 
 func (t *targetrunner) newFileWalk(bucket string, msg *cmn.GetMsg) *allfinfos {
-	// Marker is always a file name, so we need to strip filename from path
-	markerDir := ""
-	if msg.GetPageMarker != "" {
-		markerDir = filepath.Dir(msg.GetPageMarker)
-	}
-
 	// A small optimization: set boolean variables need* to avoid
 	// doing string search(strings.Contains) for every entry.
 	ci := &allfinfos{
@@ -1920,7 +2180,7 @@ func (t *targetrunner) newFileWalk(bucket string, msg *cmn.GetMsg) *allfinfos {
 		files:        make([]*cmn.BucketEntry, 0, cmn.DefaultPageSize),
 		prefix:       msg.GetPrefix,
 		marker:       msg.GetPageMarker,
-		markerDir:    markerDir,
+		delimiter:    msg.GetDelimiter,
 		msg:          msg,
 		lastFilePath: "",
 		bucket:       bucket,
@@ -1947,6 +2207,7 @@ func (t *targetrunner) newFileWalk(bucket string, msg *cmn.GetMsg) *allfinfos {
 //  - Object name must start with prefix (if it is set)
 //  - Object name is not in early processed directories by the previos call:
 //    paging support
+//  - With delimiter, the directory that starts with prefix is listed as a whole
 func (ci *allfinfos) processDir(fqn string) error {
 	if len(fqn) <= ci.rootLength {
 		return nil
@@ -1960,11 +2221,22 @@ func (ci *allfinfos) processDir(fqn string) error {
 		return filepath.SkipDir
 	}
 
-	if ci.markerDir != "" && relname < ci.markerDir {
+	// skip the directory if all its content precedes the marker
+	dirname := relname + string(filepath.Separator)
+	if ci.marker != "" && dirname < ci.marker && !strings.HasPrefix(ci.marker, dirname) {
 		return filepath.SkipDir
 	}
 
-	return nil
+	if ci.delimiter == "" || dirname == ci.prefix || !strings.HasPrefix(dirname, ci.prefix) {
+		return nil
+	}
+	// the first directory down the tree that has the delimiter after prefix is the common
+	// prefix: return the directory instead of its content
+	if ci.marker == "" || dirname > ci.marker {
+		ci.fileCount++
+		ci.files = append(ci.files, &cmn.BucketEntry{Name: dirname, Type: cmn.BucketEntryDir})
+	}
+	return filepath.SkipDir
 }
 
 // Adds an info about cached object to the list if:
@@ -2015,7 +2287,8 @@ func (ci *allfinfos) processRegularFile(fqn string, osfi os.FileInfo, objStatus
 	if ci.needChkSum {
 		xxHashBinary, errstr := Getxattr(fqn, cmn.XattrXXHashVal)
 		if errstr == "" {
-			fileInfo.Checksum = hex.EncodeToString(xxHashBinary)
+			_, val := cmn.ParseStoredCksum(xxHashBinary)
+			fileInfo.Checksum = hex.EncodeToString([]byte(val))
 		}
 	}
 	if ci.needVersion {
@@ -2073,13 +2346,15 @@ func (ci *allfinfos) listwalkf(fqn string, osfi os.FileInfo, err error) error {
 // In both case a new checksum is saved to xattrs
 func (t *targetrunner) doput(w http.ResponseWriter, r *http.Request, bucket, objname string) (errstr string, errcode int) {
 	var (
-		file                       *os.File
-		err                        error
-		hdhobj, nhobj              cksumvalue
-		xxHashVal                  string
-		htype, hval, nhtype, nhval string
-		sgl                        *memsys.SGL
-		started                    time.Time
+		file          io.ReadCloser
+		err           error
+		hdhobj, nhobj cksumvalue
+		verifier      *putVerifier
+		xxHashVal     string
+		htype, hval   string
+		sgl           *memsys.SGL
+		written       int64
+		started       time.Time
 	)
 	started = time.Now()
 	islocal := t.bmdowner.get().IsLocal(bucket)
@@ -2087,27 +2362,39 @@ func (t *targetrunner) doput(w http.ResponseWriter, r *http.Request, bucket, obj
 	if errstr != "" {
 		return errstr, http.StatusBadRequest
 	}
+	if injectFault(cmn.FaultPointPut, bucket, objname) == cmn.FaultEIO {
+		return fmt.Sprintf("Failed to create %s, err: injected EIO", fqn), http.StatusInternalServerError
+	}
+	expires, errstr := parseObjTTL(r.Header.Get(cmn.HeaderDFCObjTTL), started)
+	if errstr != "" {
+		return errstr, http.StatusBadRequest
+	}
 	putfqn := cluster.GenContentFQN(fqn, cluster.DefaultWorkfileType)
+	gid := r.URL.Query().Get(cmn.URLParamPutGroup)
+	if gid != "" {
+		// staged in the group's directory (see putgroup.go)
+		if putfqn, err = putGroupFQN(gid, objname, fqn); err != nil {
+			return err.Error(), http.StatusBadRequest
+		}
+	}
 	cksumcfg := &ctx.config.Cksum
 	if bucketProps, _, defined := t.bmdowner.get().propsAndChecksum(bucket); defined {
 		cksumcfg = &bucketProps.CksumConf
 	}
-	hdhobj = newcksumvalue(r.Header.Get(cmn.HeaderDFCChecksumType), r.Header.Get(cmn.HeaderDFCChecksumVal))
-
-	if hdhobj != nil {
-		htype, hval = hdhobj.get()
+	htype, hval = r.Header.Get(cmn.HeaderDFCChecksumType), r.Header.Get(cmn.HeaderDFCChecksumVal)
+	if verifier, errstr = newPutVerifier(htype, hval, cksumcfg.Checksum); errstr != "" {
+		return errstr, http.StatusBadRequest
 	}
-	// optimize out if the checksums do match
-	if hdhobj != nil && cksumcfg.Checksum != cmn.ChecksumNone && !dryRun.disk && !dryRun.network {
-		file, err = os.Open(fqn)
-		// exists - compute checksum and compare with the caller's
+	if verifier != nil && cmn.ValidCksumType(htype) {
+		hdhobj = newcksumvalue(htype, verifier.val)
+	}
+	// optimize out if the checksums do match (except for grouped PUTs that must be staged)
+	if hdhobj != nil && gid == "" && cksumcfg.Checksum != cmn.ChecksumNone && !dryRun.disk && !dryRun.network {
+		file, err = openObject(fqn)
+		// exists - compute checksum of the (original, not compressed) content and compare with the caller's
 		if err == nil {
 			buf, slab := gmem2.AllocFromSlab2(0)
-			if htype == cmn.ChecksumXXHash {
-				xxHashVal, errstr = cmn.ComputeXXHash(file, buf)
-			} else {
-				errstr = fmt.Sprintf("Unsupported checksum type %s", htype)
-			}
+			xxHashVal, errstr = cmn.ComputeCksum(htype, file, buf)
 			// not a critical error
 			if errstr != "" {
 				glog.Warningf("Warning: Bad checksum: %s: %v", fqn, errstr)
@@ -2117,33 +2404,67 @@ func (t *targetrunner) doput(w http.ResponseWriter, r *http.Request, bucket, obj
 			if err = file.Close(); err != nil {
 				glog.Warningf("Unexpected failure to close %s once xxhash-ed, err: %v", fqn, err)
 			}
-			if errstr == "" && xxHashVal == hval {
+			if errstr == "" && xxHashVal == verifier.val {
 				glog.Infof("Existing %s/%s is valid: PUT is a no-op", bucket, objname)
 				return
 			}
 		}
 	}
-	if sgl, nhobj, _, errstr = t.receive(putfqn, objname, "", hdhobj, r.Body); errstr != "" {
+	if errstr, errcode = t.checkReservedSpace(fqn, r.ContentLength); errstr != "" {
 		return
 	}
-	if nhobj != nil {
-		nhtype, nhval = nhobj.get()
-		cmn.Assert(hdhobj == nil || htype == nhtype)
+	// async checksum: only if there's nothing to validate against
+	deferCksum := verifier == nil && gid == "" && cksumcfg.AsyncPut && cksumcfg.Checksum != cmn.ChecksumNone
+	reader := io.Reader(r.Body)
+	if verifier != nil && verifier.h != nil {
+		reader = io.TeeReader(r.Body, verifier.h)
+	}
+	if sgl, nhobj, written, errstr = t.doReceive(putfqn, bucket, objname, "", nil, reader, r.ContentLength, deferCksum); errstr != "" {
+		return
 	}
-	// validate checksum when and if provided
-	if hval != "" && nhval != "" && hval != nhval && !dryRun.disk && !dryRun.network {
-		errstr = fmt.Sprintf("Bad checksum: %s/%s %s %.8s... != %.8s...", bucket, objname, htype, hval, nhval)
+	// validate size when and if provided (the object is in the workfile - see putatomic.go)
+	if sgl == nil && r.ContentLength >= 0 && written != r.ContentLength && !dryRun.disk && !dryRun.network {
+		errstr = fmt.Sprintf("Bad size: %s/%s received %d bytes, expected %d", bucket, objname, written, r.ContentLength)
+		t.removeWorkfile(putfqn, errstr)
 		return
 	}
+	// validate checksum when and if provided (see putverify.go)
+	if verifier != nil && !dryRun.disk && !dryRun.network {
+		if computed, ok := verifier.verify(nhobj); !ok {
+			errstr = fmt.Sprintf("Bad checksum: %s/%s %s %.8s... != %.8s... computed", bucket, objname, htype, verifier.val, computed)
+			t.statsif.AddMany(stats.NamedVal64{Name: stats.ErrPutCksumCount, Val: 1},
+				stats.NamedVal64{Name: stats.ErrCksumCount, Val: 1}, stats.NamedVal64{Name: stats.ErrCksumSize, Val: written})
+			if sgl == nil {
+				t.removeWorkfile(putfqn, errstr)
+			} else {
+				sgl.Free()
+			}
+			return errstr, http.StatusUnprocessableEntity
+		}
+	}
 	// commit
-	props := &objectProps{nhobj: nhobj}
+	props := &objectProps{nhobj: nhobj, expires: expires, cksumPending: deferCksum}
+	if gid != "" && !dryRun.disk && !dryRun.network {
+		// grouped PUT: stays invisible until the group is committed (see putgroup.go)
+		cmn.Assert(sgl == nil)
+		errstr = t.stageGroupPut(gid, bucket, objname, putfqn, fqn, props)
+		return
+	}
 	if sgl == nil {
 		if !dryRun.disk && !dryRun.network {
 			errstr, errcode = t.putCommit(t.contextWithAuth(r), bucket, objname, putfqn, fqn, props, false /*rebalance*/)
+			if errstr == "" {
+				errstr = t.syncPut(bucket, fqn)
+			}
 		}
 		if errstr == "" {
+			if deferCksum && !dryRun.disk && !dryRun.network {
+				t.asyncCksum.add(fqn, bucket, objname)
+			}
 			delta := time.Since(started)
-			t.statsif.AddMany(stats.NamedVal64{stats.PutCount, 1}, stats.NamedVal64{stats.PutLatency, int64(delta)})
+			t.statsif.AddBucket(bucket, stats.NamedVal64{stats.PutCount, 1}, stats.NamedVal64{stats.PutLatency, int64(delta)})
+			getstorstatsrunner().AddBucketIO(bucket, &cmn.BucketIOStats{PutCount: 1, PutSize: written})
+			getiostatrunner().AddForeground(fqn)
 			if glog.V(4) {
 				glog.Infof("PUT: %s/%s, %d µs", bucket, objname, int64(delta/time.Microsecond))
 			}
@@ -2174,7 +2495,7 @@ func (t *targetrunner) doReplicationPut(w http.ResponseWriter, r *http.Request,
 	}
 
 	delta := time.Since(started)
-	t.statsif.AddMany(stats.NamedVal64{stats.ReplPutCount, 1}, stats.NamedVal64{stats.ReplPutLatency, int64(delta / time.Microsecond)})
+	t.statsif.AddMany(stats.NamedVal64{stats.ReplPutCount, 1}, stats.NamedVal64{stats.ReplPutLatency, int64(delta)})
 	if glog.V(4) {
 		glog.Infof("Replication PUT: %s/%s, %d µs", bucket, objname, int64(delta/time.Microsecond))
 	}
@@ -2189,7 +2510,8 @@ func (t *targetrunner) sglToCloudAsync(ct context.Context, sgl *memsys.SGL, buck
 		slab.Free(buf)
 	}()
 	// sgl => fqn sequence
-	file, err := cmn.CreateFile(putfqn)
+	var file *os.File
+	err := t.inObjDir(putfqn, func() (err error) { file, err = os.Create(putfqn); return })
 	if err != nil {
 		t.fshc(err, putfqn)
 		glog.Errorln("sglToCloudAsync: create", putfqn, err)
@@ -2292,22 +2614,27 @@ func (t *targetrunner) doPutCommit(ct context.Context, bucket, objname, putfqn,
 	if errstr != "" {
 		return
 	}
+	if !islocal && !rebalance {
+		t.invalListCache(bucket)
+	}
 
 	// when all set and done:
 	uname := cluster.Uname(bucket, objname)
 	t.rtnamemap.Lock(uname, true)
 
-	if err = os.Rename(putfqn, fqn); err != nil {
+	setxattrs := func(workfqn string) string { return setObjXattrs(workfqn, objprops) }
+	objects, bytes := replaceDelta(putfqn, fqn)
+	if errstr, err = replaceObj(putfqn, fqn, setxattrs); errstr != "" {
+		if !islocal && !rebalance {
+			t.evictStale(fqn, bucket)
+		}
 		t.rtnamemap.Unlock(uname, true)
-		errstr = fmt.Sprintf("Failed to rename %s => %s, err: %v", putfqn, fqn, err)
+		glog.Errorf("PUT %s/%s: %s (%+v)", bucket, objname, errstr, objprops)
 		return
 	}
 	renamed = true
-	if errstr = t.finalizeobj(fqn, bucket, objprops); errstr != "" {
-		t.rtnamemap.Unlock(uname, true)
-		glog.Errorf("finalizeobj %s/%s: %s (%+v)", bucket, objname, errstr, objprops)
-		return
-	}
+	addBucketCapacity(fqn, bucket, islocal, objects, bytes)
+	t.objFinalized(fqn, bucket, objprops)
 	t.rtnamemap.Unlock(uname, true)
 	return
 }
@@ -2375,10 +2702,15 @@ func (t *targetrunner) dorebalance(r *http.Request, from, to, bucket, objname st
 				props.atime = tm
 			}
 		}
+		if timeStr := r.Header.Get(cmn.HeaderDFCObjExpires); timeStr != "" {
+			if tm, err := time.Parse(time.RFC3339Nano, timeStr); err == nil {
+				props.expires = tm
+			}
+		}
 		if _, props.nhobj, size, errstr = t.receive(putfqn, objname, "", hdhobj, r.Body); errstr != "" {
 			return
 		}
-		if props.nhobj != nil {
+		if props.nhobj != nil && hdhobj != nil {
 			nhtype, nhval := props.nhobj.get()
 			htype, hval := hdhobj.get()
 			cmn.Assert(htype == nhtype)
@@ -2418,8 +2750,10 @@ func (t *targetrunner) fildelete(ct context.Context, bucket, objname string, evi
 			}
 			return fmt.Errorf("%d: %s", errcode, errstr)
 		}
+		t.invalListCache(bucket)
 
-		t.statsif.Add(stats.DeleteCount, 1)
+		t.statsif.AddBucket(bucket, stats.NamedVal64{Name: stats.DeleteCount, Val: 1})
+		getstorstatsrunner().AddBucketIO(bucket, &cmn.BucketIOStats{DeleteCount: 1})
 	}
 
 	finfo, err := os.Stat(fqn)
@@ -2435,10 +2769,23 @@ func (t *targetrunner) fildelete(ct context.Context, bucket, objname string, evi
 	}
 	if !(evict && islocal) {
 		// Don't evict from a local bucket (this would be deletion)
+		t.fdCache.invalidate(fqn)
+		t.mdCache.invalidate(fqn)
 		if err := os.Remove(fqn); err != nil {
 			return err
-		} else if evict {
-			t.statsif.AddMany(stats.NamedVal64{stats.LruEvictCount, 1}, stats.NamedVal64{stats.LruEvictSize, finfo.Size()})
+		}
+		if finfo != nil {
+			addBucketCapacity(fqn, bucket, islocal, -1, -finfo.Size())
+		}
+		if evict {
+			evicted := []stats.NamedVal64{{Name: stats.LruEvictCount, Val: 1}, {Name: stats.LruEvictSize, Val: finfo.Size()}}
+			if mpathInfo, _ := fs.Mountpaths.Path2MpathInfo(fqn); mpathInfo != nil {
+				getstorstatsrunner().AddMpath(mpathInfo.Path, evicted...)
+			} else {
+				t.statsif.AddMany(evicted...)
+			}
+		} else if islocal {
+			getstorstatsrunner().AddBucketIO(bucket, &cmn.BucketIOStats{DeleteCount: 1})
 		}
 	}
 	return nil
@@ -2529,13 +2876,14 @@ func (t *targetrunner) renameobject(bucketFrom, objnameFrom, bucketTo, objnameTo
 		if errstr != "" {
 			return
 		}
-		dirname := filepath.Dir(newfqn)
-		if err := cmn.CreateDir(dirname); err != nil {
-			errstr = fmt.Sprintf("Unexpected failure to create local dir %s, err: %v", dirname, err)
-		} else if err := os.Rename(fqn, newfqn); err != nil {
+		t.fdCache.invalidate(fqn)
+		t.mdCache.invalidate(fqn)
+		if err := t.inObjDir(newfqn, func() error { return os.Rename(fqn, newfqn) }); err != nil {
 			errstr = fmt.Sprintf("Failed to rename %s => %s, err: %v", fqn, newfqn, err)
 		} else {
-			t.statsif.Add(stats.RenameCount, 1)
+			t.statsif.AddBucket(bucketFrom, stats.NamedVal64{Name: stats.RenameCount, Val: 1})
+			addBucketCapacity(fqn, bucketFrom, islocalFrom, -1, -finfo.Size())
+			addBucketCapacity(newfqn, bucketTo, islocalTo, 1, finfo.Size())
 			if glog.V(3) {
 				glog.Infof("Renamed %s => %s", fqn, newfqn)
 			}
@@ -2603,26 +2951,28 @@ func (t *targetrunner) sendfile(method, bucket, objname string, destsi *cluster.
 		}
 	}
 
-	file, err := os.Open(fqn)
+	file, err := openObject(fqn)
 	if err != nil {
 		return fmt.Sprintf("Failed to open %q, err: %v", fqn, err)
 	}
-	defer file.Close()
+	defer func() { file.Close() }()
 
 	if version, errstr = Getxattr(fqn, cmn.XattrObjVersion); errstr != "" {
 		glog.Errorf("Failed to read %q xattr %s, err %s", fqn, cmn.XattrObjVersion, errstr)
 	}
+	expires, _ := Getxattr(fqn, cmn.XattrExpires)
 
 	if cksumcfg.Checksum != cmn.ChecksumNone {
-		cmn.Assert(cksumcfg.Checksum == cmn.ChecksumXXHash, "invalid checksum type: '"+cksumcfg.Checksum+"'")
+		cmn.Assert(cmn.ValidCksumType(cksumcfg.Checksum), "invalid checksum type: '"+cksumcfg.Checksum+"'")
 		buf, slab := gmem2.AllocFromSlab2(size)
-		if xxHashVal, errstr = cmn.ComputeXXHash(file, buf); errstr != "" {
+		if xxHashVal, errstr = cmn.ComputeCksum(cksumcfg.Checksum, file, buf); errstr != "" {
 			slab.Free(buf)
 			return errstr
 		}
 		slab.Free(buf)
-		if _, err = file.Seek(0, 0); err != nil {
-			return fmt.Sprintf("Unexpected fseek failure when sending %q from %s, err: %v", fqn, t.si.DaemonID, err)
+		file.Close()
+		if file, err = openObject(fqn); err != nil {
+			return fmt.Sprintf("Failed to reopen %q when sending from %s, err: %v", fqn, t.si.DaemonID, err)
 		}
 	}
 	//
@@ -2633,7 +2983,7 @@ func (t *targetrunner) sendfile(method, bucket, objname string, destsi *cluster.
 		return fmt.Sprintf("Unexpected failure to create %s request %s, err: %v", method, url, err)
 	}
 	if xxHashVal != "" {
-		request.Header.Set(cmn.HeaderDFCChecksumType, cmn.ChecksumXXHash)
+		request.Header.Set(cmn.HeaderDFCChecksumType, cksumcfg.Checksum)
 		request.Header.Set(cmn.HeaderDFCChecksumVal, xxHashVal)
 	}
 	if len(version) != 0 {
@@ -2642,13 +2992,16 @@ func (t *targetrunner) sendfile(method, bucket, objname string, destsi *cluster.
 	if accessTimeStr != "" {
 		request.Header.Set(cmn.HeaderDFCObjAtime, accessTimeStr)
 	}
+	if len(expires) != 0 {
+		request.Header.Set(cmn.HeaderDFCObjExpires, string(expires))
+	}
 
 	// Do
 	contextwith, cancel := context.WithTimeout(context.Background(), ctx.config.Timeout.SendFile)
 	defer cancel()
 	newrequest := request.WithContext(contextwith)
 
-	response, err := t.httpclientLongTimeout.Do(newrequest)
+	response, err := t.intra.Do(newrequest, true)
 
 	// err handle
 	if err != nil {
@@ -2779,6 +3132,18 @@ func (t *targetrunner) daemonHandler(w http.ResponseWriter, r *http.Request) {
 	glog.Flush()
 }
 
+// GET /metrics
+func (t *targetrunner) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		cmn.InvalidHandlerWithMsg(w, r, "invalid method for /metrics path")
+		return
+	}
+	w.Header().Set("Content-Type", stats.PrometheusContentType)
+	if err := getstorstatsrunner().WritePrometheus(w); err != nil {
+		glog.Errorf("Failed to write metrics, err: %v", err)
+	}
+}
+
 func (t *targetrunner) httpdaeput(w http.ResponseWriter, r *http.Request) {
 	apitems, err := t.checkRESTItems(w, r, 0, true, cmn.Version, cmn.Daemon)
 	if err != nil {
@@ -2833,6 +3198,10 @@ func (t *targetrunner) httpdaeput(w http.ResponseWriter, r *http.Request) {
 		}
 	case cmn.ActShutdown:
 		_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	case cmn.ActReplaceTarget:
+		t.adoptDaemonID(w, r, &msg)
+	case cmn.ActMaintenance:
+		t.setMaintenance(w, r, &msg)
 	default:
 		s := fmt.Sprintf("Unexpected cmn.ActionMsg <- JSON [%v]", msg)
 		t.invalmsghdlr(w, r, s)
@@ -2893,10 +3262,25 @@ func (t *targetrunner) httpdaeget(w http.ResponseWriter, r *http.Request) {
 	case cmn.GetWhatConfig, cmn.GetWhatSmap, cmn.GetWhatBucketMeta, cmn.GetWhatSmapVote, cmn.GetWhatDaemonInfo:
 		t.httprunner.httpdaeget(w, r)
 	case cmn.GetWhatStats:
-		rst := getstorstatsrunner()
-		rst.RLock()
-		jsbytes, err := jsoniter.Marshal(rst)
-		rst.RUnlock()
+		var (
+			jsbytes []byte
+			err     error
+			rst     = getstorstatsrunner()
+		)
+		snapshot, reset, errstr := parseStatsSnapshot(r.URL.Query())
+		if errstr != "" {
+			t.invalmsghdlr(w, r, errstr)
+			return
+		}
+		if snapshot {
+			jsbytes, err = jsoniter.Marshal(rst.GetSnapshot(reset))
+		} else if buckets, _ := strconv.ParseBool(r.URL.Query().Get(cmn.URLParamBuckets)); buckets {
+			jsbytes, err = rst.MarshalWithBuckets()
+		} else {
+			rst.RLock()
+			jsbytes, err = jsoniter.Marshal(rst)
+			rst.RUnlock()
+		}
 		cmn.Assert(err == nil, err)
 		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
 	case cmn.GetWhatXaction:
@@ -2911,7 +3295,11 @@ func (t *targetrunner) httpdaeget(w http.ResponseWriter, r *http.Request) {
 			allXactionDetails = t.getXactionsByType(kind)
 		)
 		if kind == cmn.XactionRebalance {
-			jsbytes = sts.GetRebalanceStats(allXactionDetails)
+			var remaining []stats.RebalanceSizeClass
+			if _, xx := t.xactinp.findL(cmn.ActGlobalReb); xx != nil && ctx.config.Rebalance.Order != cmn.RebOrderNone {
+				remaining = xx.(*xactRebalance).remaining.get()
+			}
+			jsbytes = sts.GetRebalanceStats(allXactionDetails, remaining)
 		} else {
 			cmn.Assert(kind == cmn.XactionPrefetch)
 			jsbytes = sts.GetPrefetchStats(allXactionDetails)
@@ -2940,6 +3328,73 @@ func (t *targetrunner) httpdaeget(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatRebPlan:
+		jsbytes, err := jsoniter.Marshal(t.rebalancePlan())
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatSLO:
+		jsbytes, err := jsoniter.Marshal(getstorstatsrunner().SLO())
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatReplStats:
+		jsbytes, err := jsoniter.Marshal(getstorstatsrunner().ReplStats())
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatPrefetch:
+		jsbytes, err := jsoniter.Marshal(t.prefetchUsages())
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatSummary:
+		jsbytes, err := jsoniter.Marshal(getstorstatsrunner().BucketCapacity())
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatHotObjects:
+		topN, errstr := parseTopN(r.URL.Query())
+		if errstr != "" {
+			t.invalmsghdlr(w, r, errstr)
+			return
+		}
+		jsbytes, err := jsoniter.Marshal(t.hotObjects(topN))
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatBucketIO:
+		reset, err := parsebool(r.URL.Query().Get(cmn.URLParamReset))
+		if err != nil {
+			t.invalmsghdlr(w, r, fmt.Sprintf("Invalid %s, err: %v", cmn.URLParamReset, err))
+			return
+		}
+		jsbytes, err := jsoniter.Marshal(getstorstatsrunner().BucketIO(reset))
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatStatsHist:
+		q, errstr := parseStatsHistQuery(r.URL.Query())
+		if errstr != "" {
+			t.invalmsghdlr(w, r, errstr)
+			return
+		}
+		jsbytes, err := jsoniter.Marshal(getstorstatsrunner().History(q.names, q.res, q.within, q.until))
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatDiskHist:
+		var (
+			within time.Duration
+			err    error
+			query  = r.URL.Query()
+		)
+		if s := query.Get(cmn.URLParamSince); s != "" {
+			if within, err = time.ParseDuration(s); err != nil || within < 0 {
+				t.invalmsghdlr(w, r, fmt.Sprintf("Invalid %s=%s: expecting duration, e.g. 5m", cmn.URLParamSince, s))
+				return
+			}
+		}
+		hist := getiostatrunner().History(query.Get(cmn.URLParamDisk), within)
+		jsbytes, err := jsoniter.Marshal(hist)
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
+	case cmn.GetWhatDiskHealth:
+		jsbytes, err := jsoniter.Marshal(getsmartrunner().Health())
+		cmn.Assert(err == nil, err)
+		t.writeJSON(w, r, jsbytes, "httpdaeget-"+getWhat)
 	default:
 		t.httprunner.httpdaeget(w, r)
 	}
@@ -3037,9 +3492,18 @@ func (t *targetrunner) httpdaedelete(w http.ResponseWriter, r *http.Request) {
 //==============================================================================================
 func (t *targetrunner) receive(fqn string, objname, omd5 string, ohobj cksumvalue,
 	reader io.Reader) (sgl *memsys.SGL, nhobj cksumvalue, written int64, errstr string) {
+	bucket, _, _ := cluster.ResolveFQN(fqn, t.bmdowner)
+	return t.doReceive(fqn, bucket, objname, omd5, ohobj, reader, -1 /*size unknown*/, false /*deferCksum*/)
+}
+
+// doReceive with deferCksum == true does not compute the checksum - see cksumasync.go;
+// size, if known (-1 otherwise), determines whether to write bypassing the page cache (direct_io)
+func (t *targetrunner) doReceive(fqn, bucket, objname, omd5 string, ohobj cksumvalue,
+	reader io.Reader, size int64, deferCksum bool) (sgl *memsys.SGL, nhobj cksumvalue, written int64, errstr string) {
 	var (
 		err                  error
 		file                 *os.File
+		dw                   *directWriter
 		filewriter           io.Writer
 		ohtype, ohval, nhval string
 		cksumcfg             = &ctx.config.Cksum
@@ -3049,22 +3513,35 @@ func (t *targetrunner) receive(fqn string, objname, omd5 string, ohobj cksumvalu
 		return
 	}
 	// try to override cksum config with bucket-level config
-	if bucket, _, err := cluster.ResolveFQN(fqn, t.bmdowner); err == nil {
-		if bucketProps, _, defined := t.bmdowner.get().propsAndChecksum(bucket); defined {
-			cksumcfg = &bucketProps.CksumConf
-		}
+	if bucketProps, _, defined := t.bmdowner.get().propsAndChecksum(bucket); defined {
+		cksumcfg = &bucketProps.CksumConf
 	}
 
 	if dryRun.network {
 		reader = readers.NewRandReader(dryRun.size)
 	}
 	if !dryRun.disk {
-		if file, err = cmn.CreateFile(fqn); err != nil {
+		create := func() (err error) {
+			if useDirectIO(size) {
+				if dw, err = newDirectWriter(fqn); dw != nil || err != nil {
+					if dw != nil {
+						file = dw.file
+					}
+					return
+				}
+			}
+			file, err = os.Create(fqn)
+			return
+		}
+		if err = t.inObjDir(fqn, create); err != nil {
 			t.fshc(err, fqn)
 			errstr = fmt.Sprintf("Failed to create %s, err: %s", fqn, err)
 			return
 		}
 		filewriter = file
+		if dw != nil {
+			filewriter = dw
+		}
 	} else {
 		filewriter = ioutil.Discard
 	}
@@ -3072,6 +3549,9 @@ func (t *targetrunner) receive(fqn string, objname, omd5 string, ohobj cksumvalu
 	buf, slab := gmem2.AllocFromSlab2(0)
 	defer func() { // free & cleanup on err
 		slab.Free(buf)
+		if dw != nil {
+			dw.free()
+		}
 		if errstr == "" {
 			return
 		}
@@ -3098,25 +3578,27 @@ func (t *targetrunner) receive(fqn string, objname, omd5 string, ohobj cksumvalu
 	}
 
 	// receive and checksum
-	if cksumcfg.Checksum != cmn.ChecksumNone {
-		cmn.Assert(cksumcfg.Checksum == cmn.ChecksumXXHash)
-		xx := xxhash.New64()
-		if written, err = cmn.ReceiveAndChecksum(filewriter, reader, buf, xx); err != nil {
+	if cksumcfg.Checksum != cmn.ChecksumNone && !deferCksum {
+		cmn.Assert(cmn.ValidCksumType(cksumcfg.Checksum))
+		// the object sent along with its checksum keeps the latter's type
+		kind := cksumcfg.Checksum
+		if ohobj != nil {
+			if ohtype, ohval = ohobj.get(); cmn.ValidCksumType(ohtype) {
+				kind = ohtype
+			}
+		}
+		h := cmn.NewCksumHash(kind)
+		if written, err = cmn.ReceiveAndChecksum(filewriter, reader, buf, h); err != nil {
 			errstr = err.Error()
 			t.fshc(err, fqn)
 			return
 		}
-		hashIn64 := xx.Sum64()
-		hashInBytes := make([]byte, 8)
-		binary.BigEndian.PutUint64(hashInBytes, hashIn64)
-		nhval = hex.EncodeToString(hashInBytes)
-		nhobj = newcksumvalue(cmn.ChecksumXXHash, nhval)
-		if ohobj != nil {
-			ohtype, ohval = ohobj.get()
-			cmn.Assert(ohtype == cmn.ChecksumXXHash)
+		nhval = cmn.CksumHex(h)
+		nhobj = newcksumvalue(kind, nhval)
+		if ohobj != nil && ohtype == kind {
 			if ohval != nhval {
 				errstr = fmt.Sprintf("Bad checksum: %s %s %.8s... != %.8s... computed for the %q",
-					objname, cksumcfg.Checksum, ohval, nhval, fqn)
+					objname, kind, ohval, nhval, fqn)
 
 				t.statsif.AddMany(stats.NamedVal64{stats.ErrCksumCount, 1}, stats.NamedVal64{stats.ErrCksumSize, written})
 				return
@@ -3145,6 +3627,13 @@ func (t *targetrunner) receive(fqn string, objname, omd5 string, ohobj cksumvalu
 			return
 		}
 	}
+	if dw != nil {
+		if err = dw.flush(); err != nil {
+			errstr = fmt.Sprintf("Failed to write %s, err: %v", fqn, err)
+			t.fshc(err, fqn)
+			return
+		}
+	}
 	if err = file.Close(); err != nil {
 		errstr = fmt.Sprintf("Failed to close received file %s, err: %v", fqn, err)
 	}
@@ -3174,6 +3663,36 @@ func (t *targetrunner) redirectLatency(started time.Time, query url.Values) (red
 	return
 }
 
+// checkReservedSpace refuses to write size bytes (0 if unknown) to the fqn's mountpath
+// if that would leave less than the mountpath's reserved space (disk_config.reserved_space
+// and reserved_space_mpath)
+func (t *targetrunner) checkReservedSpace(fqn string, size int64) (errstr string, errcode int) {
+	if !ctx.config.Disk.Reserved() || dryRun.disk {
+		return
+	}
+	parsedFQN, err := fs.Mountpaths.FQN2Info(fqn)
+	if err != nil {
+		return err.Error(), http.StatusBadRequest
+	}
+	mpath := parsedFQN.MpathInfo.Path
+	blocks, bavail, bsize, err := ios.GetFSStats(mpath)
+	if err != nil {
+		t.fshc(err, fqn)
+		return fmt.Sprintf("Failed to statfs mountpath %s, err: %v", mpath, err), http.StatusInternalServerError
+	}
+	if size < 0 {
+		size = 0
+	}
+	avail := int64(bavail * uint64(bsize))
+	reserved := int64(ctx.config.Disk.ReservedBytes(mpath, blocks*uint64(bsize)))
+	if avail-size < reserved {
+		errstr = fmt.Sprintf("Insufficient storage at %s: available %s, reserved %s, requested %s",
+			mpath, cmn.B2S(avail, 2), cmn.B2S(reserved, 2), cmn.B2S(size, 2))
+		return errstr, http.StatusInsufficientStorage
+	}
+	return
+}
+
 // changedMountpath checks if the mountpath for provided fqn has changed. This
 // situation can happen when new mountpath is added or mountpath is moved from
 // disabled to enabled.
@@ -3299,22 +3818,21 @@ func (t *targetrunner) versioningConfigured(bucket string) bool {
 
 // xattrs
 func (t *targetrunner) finalizeobj(fqn, bucket string, objprops *objectProps) (errstr string) {
-	if objprops.nhobj != nil {
-		htype, hval := objprops.nhobj.get()
-		cmn.Assert(htype == cmn.ChecksumXXHash)
-		if errstr = Setxattr(fqn, cmn.XattrXXHashVal, []byte(hval)); errstr != "" {
-			return errstr
-		}
-	}
-	if objprops.version != "" {
-		errstr = Setxattr(fqn, cmn.XattrObjVersion, []byte(objprops.version))
+	if errstr = setObjXattrs(fqn, objprops); errstr != "" {
+		return
 	}
+	t.objFinalized(fqn, bucket, objprops)
+	return
+}
 
-	if !objprops.atime.IsZero() && t.bucketLRUEnabled(bucket) {
-		getatimerunner().Touch(fqn, objprops.atime)
+// objFinalized is called once the object with its xattrs is in place
+func (t *targetrunner) objFinalized(fqn, bucket string, objprops *objectProps) {
+	if objprops.nhobj != nil {
+		t.asyncCksum.del(fqn) // overwritten with the checksum in place
+	}
+	if !objprops.atime.IsZero() && t.bmdowner.get().atimePolicy(bucket) != cmn.AtimeIgnore {
+		getatimerunner().Touch(fqn, objprops.atime) // (the received atime as is)
 	}
-
-	return
 }
 
 // increaseObjectVersion increments the current version xattrs and returns the new value.
@@ -3359,7 +3877,7 @@ func (t *targetrunner) fshc(err error, filepath string) {
 	if mpathInfo != nil {
 		keyName := mpathInfo.Path
 		// keyName is the mountpath is the fspath - counting IO errors on a per basis..
-		t.statsdC.Send(keyName+".io.errors", metric{statsd.Counter, "count", 1})
+		t.metrics.Send(keyName+".io.errors", metric{statsd.Counter, "count", 1})
 	}
 
 	if ctx.config.FSHC.Enabled {
@@ -3518,12 +4036,15 @@ func (t *targetrunner) receiveBucketMD(newbucketmd *bucketMD, msg *cmn.ActionMsg
 	for bucket := range bucketmd.LBmap {
 		if _, ok := newbucketmd.LBmap[bucket]; !ok {
 			glog.Infof("Destroy local bucket %s", bucket)
+			t.fdCache.purge()
+			getstorstatsrunner().DelBucketCapacity(bucket, true)
 			for _, mpathInfo := range availablePaths {
 				localbucketfqn := filepath.Join(fs.Mountpaths.MakePathLocal(mpathInfo.Path), bucket)
 				if err := os.RemoveAll(localbucketfqn); err != nil {
 					glog.Errorf("Failed to destroy local bucket dir %q, err: %v", localbucketfqn, err)
 				}
 			}
+			removeSnapshots(bucket)
 		}
 	}
 
@@ -3683,7 +4204,7 @@ func (t *targetrunner) httpTokenDelete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (t *targetrunner) validateObjectChecksum(fqn string, checksumAlgo string, slabSize int64) (validChecksum bool, errstr string) {
-	if checksumAlgo != cmn.ChecksumXXHash {
+	if !cmn.ValidCksumType(checksumAlgo) {
 		errstr := fmt.Sprintf("Unsupported checksum algorithm: [%s]", checksumAlgo)
 		return false, errstr
 	}
@@ -3699,23 +4220,25 @@ func (t *targetrunner) validateObjectChecksum(fqn string, checksumAlgo string, s
 		return true, ""
 	}
 
-	file, err := os.Open(fqn)
+	file, err := openObject(fqn)
 	if err != nil {
 		errstr := fmt.Sprintf("Failed to read object %s, err: %v", fqn, err)
 		return false, errstr
 	}
 
+	// the stored checksum's own type: the bucket's may have changed since
+	kind, stored := cmn.ParseStoredCksum(xxHashBinary)
 	buf, slab := gmem2.AllocFromSlab2(slabSize)
-	xxHashVal, errstr := cmn.ComputeXXHash(file, buf)
+	xxHashVal, errstr := cmn.ComputeCksum(kind, file, buf)
 	file.Close()
 	slab.Free(buf)
 
 	if errstr != "" {
-		errstr := fmt.Sprintf("Unable to compute xxHash, err: %s", errstr)
+		errstr := fmt.Sprintf("Unable to compute %s, err: %s", kind, errstr)
 		return false, errstr
 	}
 
-	return string(xxHashBinary) == xxHashVal, ""
+	return stored == xxHashVal, ""
 }
 
 // unregisters the target and marks it as disabled by an internal event
@@ -3829,27 +4352,34 @@ func (t *targetrunner) getFromNeighborFS(bucket, object string, islocal bool) (f
 //
 //==============================================================================
 
-func (t *targetrunner) newlru(xlru *xactLRU, mpathInfo *fs.MountpathInfo, bucketdir string) *lructx {
+func (t *targetrunner) newlru(xlru *xactLRU, mpathInfo *fs.MountpathInfo, scope int) *lructx {
+	bucketdir := fs.Mountpaths.MakePathCloud(mpathInfo.Path)
+	if scope == fs.WalkLocal {
+		bucketdir = fs.Mountpaths.MakePathLocal(mpathInfo.Path)
+	}
 	throttler := &cluster.Throttle{
 		Riostat:      getiostatrunner(),
+		QoS:          getiostatrunner(),
 		CapUsedHigh:  &ctx.config.LRU.HighWM,
 		DiskUtilLow:  &ctx.config.Xaction.DiskUtilLowWM,
 		DiskUtilHigh: &ctx.config.Xaction.DiskUtilHighWM,
 		Period:       &ctx.config.Periodic.StatsTime,
 		Path:         mpathInfo.Path,
 		FS:           mpathInfo.FileSystem,
-		Flag:         cluster.OnDiskUtil | cluster.OnFSUsed}
+		Flag:         cluster.OnDiskUtil | cluster.OnFSUsed | cluster.OnQoS}
 	lctx := &lructx{
 		oldwork:      make([]*fileInfo, 0, 64),
 		xlru:         xlru,
 		fs:           mpathInfo.FileSystem,
+		mpathInfo:    mpathInfo,
+		scope:        scope,
 		bucketdir:    bucketdir,
 		throttler:    throttler,
-		atimeRespCh:  make(chan *atime.Response, 1),
 		namelocker:   t.rtnamemap,
 		bmdowner:     t.bmdowner,
-		statsif:      t.statsif,
 		targetrunner: t, // as cluster.Target i/f
+		fdcache:      t.fdCache,
+		emergency:    getstorstatsrunner().Emergency(),
 	}
 	return lctx
 }
@@ -118,3 +118,48 @@ func TestSetBucketNextTierURLInvalid(t *testing.T) {
 		}
 	}
 }
+
+func TestBucketListProps(t *testing.T) {
+	const objname = "listprops/obj"
+	var (
+		proxyURL    = getPrimaryURL(t, proxyURLRO)
+		bucketProps = *testBucketProps(t)
+	)
+	createFreshLocalBucket(t, proxyURL, TestLocalBucketName)
+	defer destroyLocalBucket(t, proxyURL, TestLocalBucketName)
+
+	reader, err := tutils.NewRandReader(1024, false)
+	tutils.CheckFatal(err, t)
+	tutils.CheckFatal(tutils.Put(proxyURL, reader, TestLocalBucketName, objname, true), t)
+
+	// invalid defaults
+	for _, props := range []cmn.BucketProps{{ListProps: "size, color"}, {ListTimeFormat: "yesterday"}} {
+		props.CksumConf, props.LRUConf = bucketProps.CksumConf, bucketProps.LRUConf
+		if err = api.SetBucketProps(tutils.HTTPClient, proxyURL, TestLocalBucketName, props); err == nil {
+			t.Errorf("Expected setting list props %q, time format %q to fail", props.ListProps, props.ListTimeFormat)
+		}
+	}
+
+	bucketProps.ListProps = cmn.GetPropsSize + ", " + cmn.GetTargetURL
+	bucketProps.ListTimeFormat = cmn.RFC3339
+	tutils.CheckFatal(api.SetBucketProps(tutils.HTTPClient, proxyURL, TestLocalBucketName, bucketProps), t)
+	p, err := api.HeadBucket(tutils.HTTPClient, proxyURL, TestLocalBucketName)
+	tutils.CheckFatal(err, t)
+	if p.ListProps != bucketProps.ListProps || p.ListTimeFormat != bucketProps.ListTimeFormat {
+		t.Errorf("Expected list defaults (%q, %q), got (%q, %q)",
+			bucketProps.ListProps, bucketProps.ListTimeFormat, p.ListProps, p.ListTimeFormat)
+	}
+
+	// plain list gets the bucket's defaults
+	reslist, err := tutils.ListBucket(proxyURL, TestLocalBucketName, &cmn.GetMsg{GetPrefix: "listprops/"}, 0)
+	tutils.CheckFatal(err, t)
+	if len(reslist.Entries) != 1 || reslist.Entries[0].TargetURL == "" {
+		t.Errorf("Expected a single entry with the target URL, got %+v", reslist.Entries)
+	}
+	// request's props override the defaults
+	reslist, err = tutils.ListBucket(proxyURL, TestLocalBucketName, &cmn.GetMsg{GetPrefix: "listprops/", GetProps: cmn.GetPropsSize}, 0)
+	tutils.CheckFatal(err, t)
+	if len(reslist.Entries) != 1 || reslist.Entries[0].TargetURL != "" {
+		t.Errorf("Expected a single entry without the target URL, got %+v", reslist.Entries)
+	}
+}
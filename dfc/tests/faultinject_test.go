@@ -0,0 +1,106 @@
+// +build faultinject
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+// Package dfc_test provides distributed file-based cache with Amazon and Google Cloud backends.
+package dfc_test
+
+// The tests in this file require the cluster deployed with fault injection enabled:
+// TAGS=faultinject make deploy; go test -tags faultinject -run=Fault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/api"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/tutils"
+)
+
+// injectFault injects the fault into all targets and returns the cleanup
+func injectFault(t *testing.T, proxyURL string, spec cmn.FaultSpec) func() {
+	smap := getClusterMap(t, proxyURL)
+	for _, si := range smap.Tmap {
+		s := spec
+		tutils.CheckFatal(api.InjectFault(tutils.HTTPClient, si.PublicNet.DirectURL, &s), t)
+	}
+	return func() {
+		for _, si := range smap.Tmap {
+			tutils.CheckFatal(api.ClearFaults(tutils.HTTPClient, si.PublicNet.DirectURL), t)
+		}
+	}
+}
+
+func TestFaultInjectionGet(t *testing.T) {
+	var (
+		proxyURL = getPrimaryURL(t, proxyURLRO)
+		objname  = "faults/obj"
+	)
+	createFreshLocalBucket(t, proxyURL, TestLocalBucketName)
+	defer destroyLocalBucket(t, proxyURL, TestLocalBucketName)
+
+	r, err := tutils.NewRandReader(16*1024, true)
+	tutils.CheckFatal(err, t)
+	tutils.CheckFatal(tutils.Put(proxyURL, r, TestLocalBucketName, objname, true), t)
+
+	// EIO: fails once, then the object is readable again
+	cleanup := injectFault(t, proxyURL, cmn.FaultSpec{Point: cmn.FaultPointGet, Kind: cmn.FaultEIO,
+		Bucket: TestLocalBucketName, Count: 1})
+	if _, err = api.GetObject(tutils.HTTPClient, proxyURL, TestLocalBucketName, objname); err == nil {
+		t.Error("Expected GET to fail with the injected EIO")
+	}
+	if _, err = api.GetObject(tutils.HTTPClient, proxyURL, TestLocalBucketName, objname); err != nil {
+		t.Errorf("Expected GET to succeed once the fault is triggered, err: %v", err)
+	}
+	cleanup()
+
+	// short read: detected by the checksum validation
+	cleanup = injectFault(t, proxyURL, cmn.FaultSpec{Point: cmn.FaultPointGet, Kind: cmn.FaultShortRead,
+		Bucket: TestLocalBucketName, Prefix: "faults/", Count: 1})
+	if _, err = api.GetObjectWithValidation(tutils.HTTPClient, proxyURL, TestLocalBucketName, objname); err == nil {
+		t.Error("Expected the short read to fail the checksum validation")
+	}
+	cleanup()
+
+	// delay
+	cleanup = injectFault(t, proxyURL, cmn.FaultSpec{Point: cmn.FaultPointGet, Kind: cmn.FaultDelay,
+		DelayStr: "2s", Bucket: TestLocalBucketName})
+	started := time.Now()
+	_, err = api.GetObject(tutils.HTTPClient, proxyURL, TestLocalBucketName, objname)
+	tutils.CheckFatal(err, t)
+	if elapsed := time.Since(started); elapsed < 2*time.Second {
+		t.Errorf("Expected GET delayed by 2s, took %v", elapsed)
+	}
+	cleanup()
+}
+
+func TestFaultInjectionAPI(t *testing.T) {
+	var (
+		proxyURL  = getPrimaryURL(t, proxyURLRO)
+		smap      = getClusterMap(t, proxyURL)
+		targetURL string
+	)
+	for _, si := range smap.Tmap {
+		targetURL = si.PublicNet.DirectURL
+		break
+	}
+	// unsupported kind at the point
+	err := api.InjectFault(tutils.HTTPClient, targetURL, &cmn.FaultSpec{Point: cmn.FaultPointPut, Kind: cmn.FaultDrop})
+	if err == nil {
+		t.Error("Expected drop at PUT to be rejected")
+	}
+	spec := &cmn.FaultSpec{Point: cmn.FaultPointReplicate, Kind: cmn.FaultDrop, Count: 3}
+	tutils.CheckFatal(api.InjectFault(tutils.HTTPClient, targetURL, spec), t)
+	faults, err := api.GetFaults(tutils.HTTPClient, targetURL)
+	tutils.CheckFatal(err, t)
+	if len(faults) != 1 || faults[0].Point != cmn.FaultPointReplicate || faults[0].Count != 3 {
+		t.Errorf("Unexpected faults: %+v", faults)
+	}
+	tutils.CheckFatal(api.ClearFaults(tutils.HTTPClient, targetURL), t)
+	if faults, err = api.GetFaults(tutils.HTTPClient, targetURL); err != nil || len(faults) != 0 {
+		t.Errorf("Expected no faults, got %+v (err %v)", faults, err)
+	}
+}
@@ -1094,6 +1094,126 @@ func TestRenameNonEmptyLocalBucket(t *testing.T) {
 	destroyLocalBucket(t, m.proxyURL, m.bucket)
 }
 
+func TestAtomicRenameLocalBucket(t *testing.T) {
+	const (
+		newTestLocalBucketName = TestLocalBucketName + "_atomic"
+		num                    = 1000
+		filesize               = 1024
+		seed                   = int64(112)
+		maxErrPct              = 0
+	)
+
+	var (
+		err error
+		m   = metadata{
+			t:               t,
+			delay:           0 * time.Second,
+			num:             num,
+			numGetsEachFile: 2,
+			repFilenameCh:   make(chan repFile, num),
+			semaphore:       make(chan struct{}, 10), // 10 concurrent GET requests at a time
+			wg:              &sync.WaitGroup{},
+			bucket:          TestLocalBucketName,
+		}
+		filenameCh = make(chan string, m.num)
+		errCh      = make(chan error, m.num)
+		sgl        *memsys.SGL
+	)
+
+	// Initialize metadata
+	saveClusterState(&m)
+	if m.originalTargetCount < 1 {
+		t.Fatalf("Must have 1 or more targets in the cluster, have only %d", m.originalTargetCount)
+	}
+
+	// Create local buckets: renaming onto an existing bucket must fail and change nothing
+	createFreshLocalBucket(t, m.proxyURL, m.bucket)
+	createFreshLocalBucket(t, m.proxyURL, newTestLocalBucketName)
+
+	if usingSG {
+		sgl = tutils.Mem2.NewSGL(filesize)
+		defer sgl.Free()
+	}
+
+	tutils.Logf("PUT %d objects into bucket %s...\n", num, m.bucket)
+	putRandObjs(m.proxyURL, seed, filesize, num, m.bucket, errCh, filenameCh, SmokeDir, SmokeStr, true, sgl)
+	selectErr(errCh, "put", t, false)
+	close(filenameCh)
+	close(errCh)
+	for f := range filenameCh {
+		m.repFilenameCh <- repFile{repetitions: m.numGetsEachFile, filename: f}
+	}
+
+	err = api.RenameBucket(tutils.HTTPClient, m.proxyURL, m.bucket, newTestLocalBucketName)
+	if err == nil {
+		t.Fatalf("Renamed local bucket %s onto the existing %s", m.bucket, newTestLocalBucketName)
+	}
+	destroyLocalBucket(t, m.proxyURL, newTestLocalBucketName)
+
+	// Rename it
+	oldLocalBucketName := m.bucket
+	m.bucket = newTestLocalBucketName
+	err = api.RenameBucket(tutils.HTTPClient, m.proxyURL, oldLocalBucketName, m.bucket)
+	tutils.CheckFatal(err, t)
+
+	exists, err := tutils.DoesLocalBucketExist(m.proxyURL, oldLocalBucketName)
+	tutils.CheckFatal(err, t)
+	if exists {
+		t.Errorf("Local bucket %s still exists after the rename", oldLocalBucketName)
+	}
+
+	// Gets on renamed local bucket (while rebalancing, if more than one target)
+	m.wg.Add(num * m.numGetsEachFile)
+	doGetsInParallel(&m)
+	m.wg.Wait()
+	resultsBeforeAfter(&m, num, maxErrPct)
+	waitForRebalanceToComplete(t, m.proxyURL)
+
+	// Destroy renamed local bucket
+	destroyLocalBucket(t, m.proxyURL, m.bucket)
+}
+
+func TestClusterSummary(t *testing.T) {
+	const (
+		num      = 100
+		filesize = 1024
+		seed     = int64(113)
+	)
+	var (
+		proxyURL   = getPrimaryURL(t, proxyURLRO)
+		bucket     = TestLocalBucketName
+		filenameCh = make(chan string, num)
+		errCh      = make(chan error, num)
+	)
+	createFreshLocalBucket(t, proxyURL, bucket)
+	before, err := api.GetClusterSummary(tutils.HTTPClient, proxyURL)
+	tutils.CheckFatal(err, t)
+	if len(before.Counting) != 0 {
+		t.Skipf("Targets %v are counting objects", before.Counting)
+	}
+
+	putRandObjs(proxyURL, seed, filesize, num, bucket, errCh, filenameCh, SmokeDir, SmokeStr, true, nil)
+	selectErr(errCh, "put", t, true)
+	after, err := api.GetClusterSummary(tutils.HTTPClient, proxyURL)
+	tutils.CheckFatal(err, t)
+	if after.Local.Objects-before.Local.Objects != num || after.Local.Bytes-before.Local.Bytes != num*filesize {
+		t.Errorf("Expected %d objects, %d bytes more in local buckets, got %+v => %+v", num, num*filesize, before.Local, after.Local)
+	}
+	if p := after.Providers[cmn.ProviderDFC]; p == nil || *p != after.Local {
+		t.Errorf("Expected the %q provider to account for all local buckets, got %+v", cmn.ProviderDFC, p)
+	}
+	if after.Objects != after.Local.Objects+after.Cached.Objects {
+		t.Errorf("Expected the total to add up, got %+v", after)
+	}
+
+	destroyLocalBucket(t, proxyURL, bucket)
+	after, err = api.GetClusterSummary(tutils.HTTPClient, proxyURL)
+	tutils.CheckFatal(err, t)
+	if after.Local.Objects-before.Local.Objects != 0 {
+		t.Errorf("Expected the objects of the destroyed bucket gone, got %+v => %+v", before.Local, after.Local)
+	}
+}
+
 func TestDirectoryExistenceWhenModifyingBucket(t *testing.T) {
 	const (
 		newTestLocalBucketName = TestLocalBucketName + "_new"
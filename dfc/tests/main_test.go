@@ -1540,6 +1540,18 @@ func validateBucketProps(t *testing.T, expected, actual cmn.BucketProps) {
 		t.Errorf("Expected LRU enabled setting: %t, received: %t",
 			expected.LRUEnabled, actual.LRUEnabled)
 	}
+	if actual.ListProps != expected.ListProps {
+		t.Errorf("Expected list props: %q, received: %q", expected.ListProps, actual.ListProps)
+	}
+	if actual.ListTimeFormat != expected.ListTimeFormat {
+		t.Errorf("Expected list time format: %q, received: %q", expected.ListTimeFormat, actual.ListTimeFormat)
+	}
+	if actual.PublicRead != expected.PublicRead {
+		t.Errorf("Expected public read: %t, received: %t", expected.PublicRead, actual.PublicRead)
+	}
+	if actual.SyncPolicy != expected.SyncPolicy {
+		t.Errorf("Expected sync policy: %q, received: %q", expected.SyncPolicy, actual.SyncPolicy)
+	}
 }
 
 func defaultBucketProps() cmn.BucketProps {
@@ -697,6 +697,191 @@ func TestGetClusterStats(t *testing.T) {
 	}
 }
 
+func TestBucketColdSummary(t *testing.T) {
+	const fileSize = 1024
+	var (
+		numPuts  = 20
+		wg       = &sync.WaitGroup{}
+		errCh    = make(chan error, numPuts)
+		proxyURL = getPrimaryURL(t, proxyURLRO)
+	)
+	createFreshLocalBucket(t, proxyURL, TestLocalBucketName)
+	defer destroyLocalBucket(t, proxyURL, TestLocalBucketName)
+
+	for i := 0; i < numPuts; i++ {
+		r, err := tutils.NewRandReader(fileSize, true /* withHash */)
+		tutils.CheckFatal(err, t)
+		wg.Add(1)
+		go tutils.PutAsync(wg, proxyURL, r, TestLocalBucketName, fmt.Sprintf("colddata/obj%d", i), errCh, !testing.Verbose())
+	}
+	wg.Wait()
+	selectErr(errCh, "put", t, true)
+
+	// days=0: every object is older than "now"
+	summary, err := api.GetBucketColdSummary(tutils.HTTPClient, proxyURL, TestLocalBucketName, 0)
+	tutils.CheckFatal(err, t)
+	if summary.ObjCount != int64(numPuts) || summary.ObjSize != int64(numPuts*fileSize) {
+		t.Fatalf("Expected %d objects (%d bytes), got %d (%d bytes)",
+			numPuts, numPuts*fileSize, summary.ObjCount, summary.ObjSize)
+	}
+	if summary.ColdCount != summary.ObjCount {
+		t.Errorf("Expected all %d objects to be cold, got %d", summary.ObjCount, summary.ColdCount)
+	}
+
+	// the objects were just written - none of them is a year old
+	summary, err = api.GetBucketColdSummary(tutils.HTTPClient, proxyURL, TestLocalBucketName, 365)
+	tutils.CheckFatal(err, t)
+	if summary.ColdCount != 0 || summary.ColdSize != 0 {
+		t.Errorf("Expected no cold objects, got %d (%d bytes)", summary.ColdCount, summary.ColdSize)
+	}
+}
+
+func TestPutGroup(t *testing.T) {
+	var (
+		proxyURL = getPrimaryURL(t, proxyURLRO)
+		objnames = []string{"putgroup/data", "putgroup/label", "putgroup/manifest"}
+		content  = []byte("0123456789abcdef")
+	)
+	createFreshLocalBucket(t, proxyURL, TestLocalBucketName)
+	defer destroyLocalBucket(t, proxyURL, TestLocalBucketName)
+
+	countObjects := func() int {
+		reslist, err := tutils.ListBucket(proxyURL, TestLocalBucketName, &cmn.GetMsg{GetPrefix: "putgroup/"}, 0)
+		tutils.CheckFatal(err, t)
+		return len(reslist.Entries)
+	}
+	putGroup := func(objnames []string) string {
+		groupID, err := api.BeginPutGroup(tutils.HTTPClient, proxyURL, TestLocalBucketName)
+		tutils.CheckFatal(err, t)
+		for _, objname := range objnames {
+			err = api.PutGroupObject(tutils.HTTPClient, proxyURL, TestLocalBucketName, objname, groupID, content)
+			tutils.CheckFatal(err, t)
+		}
+		if n := countObjects(); n != 0 {
+			t.Fatalf("Expected uncommitted objects to be invisible, listed %d", n)
+		}
+		return groupID
+	}
+
+	// abort
+	groupID := putGroup(objnames)
+	tutils.CheckFatal(api.AbortPutGroup(tutils.HTTPClient, proxyURL, TestLocalBucketName, groupID), t)
+	if n := countObjects(); n != 0 {
+		t.Fatalf("Expected no objects after abort, listed %d", n)
+	}
+
+	// commit that lists an object that was never PUT must fail and leave nothing behind
+	groupID = putGroup(objnames[:2])
+	if err := api.CommitPutGroup(tutils.HTTPClient, proxyURL, TestLocalBucketName, groupID, objnames); err == nil {
+		t.Fatalf("Expected commit of an incomplete PUT group to fail")
+	}
+	if n := countObjects(); n != 0 {
+		t.Fatalf("Expected no objects after failed commit, listed %d", n)
+	}
+
+	// commit
+	groupID = putGroup(objnames)
+	tutils.CheckFatal(api.CommitPutGroup(tutils.HTTPClient, proxyURL, TestLocalBucketName, groupID, objnames), t)
+	if n := countObjects(); n != len(objnames) {
+		t.Fatalf("Expected %d objects after commit, listed %d", len(objnames), n)
+	}
+	for _, objname := range objnames {
+		n, err := api.GetObject(tutils.HTTPClient, proxyURL, TestLocalBucketName, objname)
+		tutils.CheckFatal(err, t)
+		if n != int64(len(content)) {
+			t.Errorf("%s: expected %d bytes, got %d", objname, len(content), n)
+		}
+	}
+}
+
+func TestGetChecksums(t *testing.T) {
+	var (
+		proxyURL = getPrimaryURL(t, proxyURLRO)
+		hashes   = make(map[string]string)
+	)
+	createFreshLocalBucket(t, proxyURL, TestLocalBucketName)
+	defer destroyLocalBucket(t, proxyURL, TestLocalBucketName)
+
+	for i := 0; i < 10; i++ {
+		objname := fmt.Sprintf("checksums/obj%d", i)
+		r, err := tutils.NewRandReader(int64(1024*(i+1)), true)
+		tutils.CheckFatal(err, t)
+		tutils.CheckFatal(tutils.Put(proxyURL, r, TestLocalBucketName, objname, true), t)
+		hashes[objname] = r.XXHash()
+	}
+	validate := func(cksums cmn.ObjChecksums, expected []string) {
+		if len(cksums) != len(expected) {
+			t.Fatalf("Expected %d checksums, got %d", len(expected), len(cksums))
+		}
+		for _, objname := range expected {
+			cksum, ok := cksums[objname]
+			if !ok {
+				t.Fatalf("%s: checksum missing", objname)
+			}
+			if cksum.Type != cmn.ChecksumXXHash || cksum.Value != hashes[objname] {
+				t.Errorf("%s: expected %s, got %s %s", objname, hashes[objname], cksum.Type, cksum.Value)
+			}
+		}
+	}
+
+	// by name: objects that do not exist are omitted
+	cksums, err := api.GetChecksums(tutils.HTTPClient, proxyURL, TestLocalBucketName,
+		[]string{"checksums/obj1", "checksums/obj7", "checksums/nonexisting"}, "")
+	tutils.CheckFatal(err, t)
+	validate(cksums, []string{"checksums/obj1", "checksums/obj7"})
+	if size := cksums["checksums/obj7"].Size; size != 8*1024 {
+		t.Errorf("Expected size %d, got %d", 8*1024, size)
+	}
+
+	// by prefix
+	cksums, err = api.GetChecksums(tutils.HTTPClient, proxyURL, TestLocalBucketName, nil, "checksums/")
+	tutils.CheckFatal(err, t)
+	all := make([]string, 0, len(hashes))
+	for objname := range hashes {
+		all = append(all, objname)
+	}
+	validate(cksums, all)
+}
+
+func TestSelectObject(t *testing.T) {
+	var (
+		proxyURL = getPrimaryURL(t, proxyURLRO)
+		csv      = "name,size,label\nimg1,1024,cat\nimg2,4096,dog\nimg3,8192,cat\n"
+		jsonl    = `{"name":"img1","meta":{"size":1024,"label":"cat"}}` + "\n" +
+			`{"name":"img2","meta":{"size":4096,"label":"dog"}}` + "\n"
+	)
+	createFreshLocalBucket(t, proxyURL, TestLocalBucketName)
+	defer destroyLocalBucket(t, proxyURL, TestLocalBucketName)
+
+	tutils.CheckFatal(tutils.Put(proxyURL, tutils.NewBytesReader([]byte(csv)), TestLocalBucketName, "select/data.csv", true), t)
+	tutils.CheckFatal(tutils.Put(proxyURL, tutils.NewBytesReader([]byte(jsonl)), TestLocalBucketName, "select/data.json", true), t)
+
+	tests := []struct {
+		objname  string
+		msg      cmn.SelectMsg
+		expected string
+	}{
+		{"select/data.csv", cmn.SelectMsg{Format: cmn.SelectFormatCSV, Header: true, Fields: []string{"name"}, Where: "label = cat AND size > 2048"},
+			"name\nimg3\n"},
+		{"select/data.json", cmn.SelectMsg{Format: cmn.SelectFormatJSON, Fields: []string{"name"}, Where: "meta.label = dog"},
+			`{"name":"img2"}` + "\n"},
+	}
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		n, err := api.SelectObject(tutils.HTTPClient, proxyURL, TestLocalBucketName, tt.objname, tt.msg, out)
+		tutils.CheckFatal(err, t)
+		if out.String() != tt.expected || n != int64(len(tt.expected)) {
+			t.Errorf("%s: expected %q, got %q (%d bytes)", tt.objname, tt.expected, out.String(), n)
+		}
+	}
+
+	// invalid expression
+	msg := cmn.SelectMsg{Format: cmn.SelectFormatCSV, Where: "size >"}
+	if _, err := api.SelectObject(tutils.HTTPClient, proxyURL, TestLocalBucketName, "select/data.csv", msg, ioutil.Discard); err == nil {
+		t.Errorf("Expected select with an invalid expression to fail")
+	}
+}
+
 func TestConfig(t *testing.T) {
 	proxyURL := getPrimaryURL(t, proxyURLRO)
 	oconfig := getConfig(proxyURL+cmn.URLPath(cmn.Version, cmn.Daemon), t)
@@ -0,0 +1,417 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+// Package dfc is a scalable object-storage based caching system with Amazon and Google Cloud backends.
+package dfc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Rolling upgrade (PUT /v1/cluster {"action": "rollingupgrade"}) restarts the cluster's
+// daemons one at a time, so that upgrading DFC does not take the entire cluster down. The
+// primary proxy drives it: for each daemon - the targets first, then the other proxies - it
+//
+// 1. waits for all daemons in the cluster map to respond;
+// 2. puts the daemon in maintenance mode (maintenance.go) and waits for its requests in
+//    flight to complete (drain_timeout);
+// 3. reports the daemon ready to restart (cmn.UpgradeReady) and, optionally, shuts it down
+//    for its supervisor to restart; meanwhile, keepalive does not remove the daemon from
+//    the cluster map;
+// 4. waits for the daemon to come back - to respond with a different start time
+//    (restart_timeout) - and moves on to the next one.
+//
+// Restarting the daemons is up to the operator (or the supervisor): the progress is
+// GET /v1/cluster?what=upgrade (cmn.RollingUpgradeStatus). The upgrade stops at the first
+// daemon that fails to drain or to restart; abort (PUT {"action": "abortupgrade"}) takes the
+// current daemon out of maintenance mode. The primary itself is not upgraded: designate
+// another primary (PUT /v1/cluster/proxy/<id>) and upgrade the former one from there.
+//
+// Proxies keep redirecting the object requests to the target in maintenance mode (HRW is
+// not aware of it), and so the objects that belong to the target are unavailable - 503 -
+// for the duration of its drain and restart.
+//
+// ================================ Summary ===============================================
+
+const (
+	upgradeDrainTimeout   = 2 * time.Minute
+	upgradeRestartTimeout = 10 * time.Minute
+)
+
+// upgradePoll is how often the daemon being upgraded, and the cluster, are polled
+var upgradePoll = time.Second
+
+type (
+	rollingUpgrade struct {
+		sync.Mutex
+		status     *cmn.RollingUpgradeStatus
+		abort      chan struct{} // closed to abort; nil - not running (or already aborted)
+		restarting string        // ID of the daemon being restarted: not to be removed by keepalive
+	}
+	upgradeArgs struct {
+		drain, restart time.Duration
+		shutdown       bool
+		abort          chan struct{}
+	}
+)
+
+func parseUpgradeMsg(msg *cmn.ActionMsg) (*upgradeArgs, *cmn.RollingUpgradeMsg, error) {
+	var (
+		umsg = &cmn.RollingUpgradeMsg{}
+		args = &upgradeArgs{drain: upgradeDrainTimeout, restart: upgradeRestartTimeout}
+	)
+	b, err := jsoniter.Marshal(msg.Value)
+	if err == nil {
+		err = jsoniter.Unmarshal(b, umsg)
+	}
+	if err == nil && umsg.DrainTimeout != "" {
+		args.drain, err = time.ParseDuration(umsg.DrainTimeout)
+	}
+	if err == nil && umsg.RestartTimeout != "" {
+		args.restart, err = time.ParseDuration(umsg.RestartTimeout)
+	}
+	if err == nil && (args.drain <= 0 || args.restart <= 0) {
+		err = fmt.Errorf("timeouts must be positive")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid %s message value %+v, err: %v", msg.Action, msg.Value, err)
+	}
+	args.shutdown = umsg.Shutdown
+	return args, umsg, nil
+}
+
+// upgradeNodes returns the daemons to upgrade, in order: as requested or, if none, all
+// targets followed by all proxies but the primary
+func (p *proxyrunner) upgradeNodes(smap *smapX, ids []string) ([]*cmn.UpgradeNode, error) {
+	if len(ids) == 0 {
+		tids, pids := make([]string, 0, len(smap.Tmap)), make([]string, 0, len(smap.Pmap))
+		for id := range smap.Tmap {
+			tids = append(tids, id)
+		}
+		for id := range smap.Pmap {
+			if id != p.si.DaemonID {
+				pids = append(pids, id)
+			}
+		}
+		sort.Strings(tids)
+		sort.Strings(pids)
+		ids = append(tids, pids...)
+	}
+	var (
+		nodes = make([]*cmn.UpgradeNode, 0, len(ids))
+		seen  = make(map[string]bool, len(ids))
+		now   = time.Now()
+	)
+	for _, id := range ids {
+		node := &cmn.UpgradeNode{ID: id, Phase: cmn.UpgradePending, Since: now}
+		switch {
+		case seen[id]:
+			return nil, fmt.Errorf("%s is listed more than once", id)
+		case id == p.si.DaemonID:
+			return nil, fmt.Errorf("%s is the primary proxy: designate another primary and upgrade %s from there", id, id)
+		case smap.GetTarget(id) != nil:
+			node.Role = xtarget
+		case smap.GetProxy(id) != nil:
+			node.Role = xproxy
+		default:
+			return nil, fmt.Errorf("Unknown daemon %s", id)
+		}
+		seen[id] = true
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("No daemons to upgrade")
+	}
+	return nodes, nil
+}
+
+// startUpgrade executes ActRollingUpgrade
+func (p *proxyrunner) startUpgrade(w http.ResponseWriter, r *http.Request, msg *cmn.ActionMsg) {
+	args, umsg, err := parseUpgradeMsg(msg)
+	if err != nil {
+		p.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	nodes, err := p.upgradeNodes(p.smapowner.get(), umsg.Nodes)
+	if err != nil {
+		p.invalmsghdlr(w, r, err.Error())
+		return
+	}
+	p.upgrade.Lock()
+	if p.upgrade.status != nil && p.upgrade.status.State == cmn.UpgradeRunning {
+		p.upgrade.Unlock()
+		p.invalmsghdlr(w, r, "Rolling upgrade is already in progress", http.StatusConflict)
+		return
+	}
+	args.abort = make(chan struct{})
+	p.upgrade.status = &cmn.RollingUpgradeStatus{State: cmn.UpgradeRunning, Started: time.Now(), Nodes: nodes}
+	p.upgrade.abort = args.abort
+	jsbytes, err := jsoniter.Marshal(p.upgrade.status)
+	p.upgrade.Unlock()
+	cmn.Assert(err == nil, err)
+
+	glog.Infof("rolling upgrade: %d daemon(s), drain timeout %v, restart timeout %v", len(nodes), args.drain, args.restart)
+	go p.runUpgrade(nodes, args)
+	p.writeJSON(w, r, jsbytes, "RollingUpgrade")
+}
+
+// abortUpgrade executes ActAbortUpgrade
+func (p *proxyrunner) abortUpgrade(w http.ResponseWriter, r *http.Request) {
+	p.upgrade.Lock()
+	defer p.upgrade.Unlock()
+	if p.upgrade.status == nil || p.upgrade.status.State != cmn.UpgradeRunning {
+		p.invalmsghdlr(w, r, "No rolling upgrade in progress", http.StatusNotFound)
+		return
+	}
+	if p.upgrade.abort != nil {
+		close(p.upgrade.abort)
+		p.upgrade.abort = nil
+		glog.Infoln("rolling upgrade: aborting")
+	}
+}
+
+func (p *proxyrunner) invokeHttpGetUpgrade(w http.ResponseWriter, r *http.Request) bool {
+	// the primary drives the upgrade
+	if p.forwardCP(w, r, &cmn.ActionMsg{Action: cmn.GetWhatUpgrade}, "", nil) {
+		return false
+	}
+	p.upgrade.Lock()
+	if p.upgrade.status == nil {
+		p.upgrade.Unlock()
+		p.invalmsghdlr(w, r, "No rolling upgrade", http.StatusNotFound)
+		return false
+	}
+	jsbytes, err := jsoniter.Marshal(p.upgrade.status)
+	p.upgrade.Unlock()
+	cmn.Assert(err == nil, err)
+	return p.writeJSON(w, r, jsbytes, "HttpGetUpgrade")
+}
+
+// isRestarting returns true if the daemon is being restarted by the rolling upgrade
+func (u *rollingUpgrade) isRestarting(sid string) bool {
+	u.Lock()
+	defer u.Unlock()
+	return u.restarting == sid
+}
+
+func (u *rollingUpgrade) setPhase(node *cmn.UpgradeNode, phase, errstr string) {
+	u.Lock()
+	node.Phase, node.Since, node.Err = phase, time.Now(), errstr
+	switch phase {
+	case cmn.UpgradeDraining:
+		u.status.Current = node.ID
+	case cmn.UpgradeReady:
+		u.restarting = node.ID
+	case cmn.UpgradeDone:
+		u.status.Done++
+		fallthrough
+	default:
+		u.restarting = ""
+	}
+	u.Unlock()
+}
+
+func (u *rollingUpgrade) finish(state, errstr string) {
+	u.Lock()
+	u.status.State, u.status.Finished, u.status.Current, u.status.Err = state, time.Now(), "", errstr
+	u.abort, u.restarting = nil, ""
+	u.Unlock()
+}
+
+func (p *proxyrunner) runUpgrade(nodes []*cmn.UpgradeNode, args *upgradeArgs) {
+	for _, node := range nodes {
+		state, errstr := p.upgradeNode(node, args)
+		if state != cmn.UpgradeDone {
+			p.upgrade.setPhase(node, state, errstr)
+			if errstr != "" {
+				errstr = fmt.Sprintf("%s %s: %s", node.Role, node.ID, errstr)
+				glog.Errorf("rolling upgrade: %s", errstr)
+			}
+			p.upgrade.finish(state, errstr)
+			return
+		}
+		p.upgrade.setPhase(node, cmn.UpgradeDone, "")
+		glog.Infof("rolling upgrade: %s %s done", node.Role, node.ID)
+	}
+	p.upgrade.finish(cmn.UpgradeDone, "")
+	glog.Infof("rolling upgrade: done, %d daemon(s)", len(nodes))
+}
+
+// upgradeNode drains the daemon and waits for it to restart; returns cmn.UpgradeDone,
+// cmn.UpgradeAborted, or cmn.UpgradeFailed along with the reason
+func (p *proxyrunner) upgradeNode(node *cmn.UpgradeNode, args *upgradeArgs) (state, errstr string) {
+	var down string
+	ok, aborted := upgradeWait(args.abort, args.restart, func() bool {
+		down = p.downDaemon()
+		return down == ""
+	})
+	if aborted {
+		return cmn.UpgradeAborted, ""
+	}
+	if !ok {
+		return cmn.UpgradeFailed, fmt.Sprintf("not starting: %s is not responding", down)
+	}
+	si := upgradeSnode(p.smapowner.get(), node.ID)
+	if si == nil {
+		return cmn.UpgradeFailed, "not in the cluster map"
+	}
+	before, errstr := p.maintStatus(si)
+	if errstr != "" {
+		return cmn.UpgradeFailed, errstr
+	}
+
+	// drain
+	p.upgrade.setPhase(node, cmn.UpgradeDraining, "")
+	if errstr = p.putMaintenance(si, true); errstr != "" {
+		return cmn.UpgradeFailed, errstr
+	}
+	// unless restarted, the daemon must not be left in maintenance mode
+	defer func() {
+		if state == cmn.UpgradeDone {
+			return
+		}
+		if errstr := p.putMaintenance(si, false); errstr != "" {
+			glog.Warningf("rolling upgrade: %s remains in maintenance mode: %s", node.ID, errstr)
+		}
+	}()
+	var status *cmn.MaintenanceStatus
+	ok, aborted = upgradeWait(args.abort, args.drain, func() bool {
+		status, errstr = p.maintStatus(si)
+		return errstr == "" && status.Ready
+	})
+	if !ok {
+		if aborted {
+			return cmn.UpgradeAborted, ""
+		}
+		if errstr == "" {
+			errstr = fmt.Sprintf("%d request(s) still in flight", status.InFlight)
+		}
+		return cmn.UpgradeFailed, fmt.Sprintf("failed to drain in %v: %s", args.drain, errstr)
+	}
+
+	// restart
+	p.upgrade.setPhase(node, cmn.UpgradeReady, "")
+	glog.Infof("rolling upgrade: %s %s is ready to restart", node.Role, node.ID)
+	if args.shutdown {
+		p.shutdownDaemon(si)
+	}
+	ok, aborted = upgradeWait(args.abort, args.restart, func() bool {
+		si := upgradeSnode(p.smapowner.get(), node.ID) // may have rejoined at another address
+		if si == nil {
+			return false
+		}
+		status, errstr := p.maintStatus(si)
+		return errstr == "" && !status.Started.Equal(before.Started)
+	})
+	if aborted {
+		return cmn.UpgradeAborted, ""
+	}
+	if !ok {
+		return cmn.UpgradeFailed, fmt.Sprintf("did not restart in %v", args.restart)
+	}
+	return cmn.UpgradeDone, ""
+}
+
+func upgradeSnode(smap *smapX, id string) *cluster.Snode {
+	if si := smap.GetTarget(id); si != nil {
+		return si
+	}
+	return smap.GetProxy(id)
+}
+
+// upgradeWait polls until the condition is met (true), the timeout, or the abort (false, true)
+func upgradeWait(abort chan struct{}, timeout time.Duration, cond func() bool) (ok, aborted bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if cond() {
+			return true, false
+		}
+		if time.Now().After(deadline) {
+			return false, false
+		}
+		select {
+		case <-abort:
+			return false, true
+		case <-time.After(upgradePoll):
+		}
+	}
+}
+
+// downDaemon returns the ID of a daemon in the cluster map that does not respond, if any
+func (p *proxyrunner) downDaemon() string {
+	smap := p.smapowner.get()
+	for _, m := range []map[string]*cluster.Snode{smap.Tmap, smap.Pmap} {
+		for id, si := range m {
+			if id != p.si.DaemonID && !p.isAlive(si) {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+func (p *proxyrunner) maintStatus(si *cluster.Snode) (*cmn.MaintenanceStatus, string) {
+	query := url.Values{}
+	query.Add(cmn.URLParamWhat, cmn.GetWhatMaint)
+	args := callArgs{
+		si: si,
+		req: reqArgs{
+			method: http.MethodGet,
+			path:   cmn.URLPath(cmn.Version, cmn.Daemon),
+			query:  query,
+		},
+		timeout: ctx.config.Timeout.CplaneOperation,
+	}
+	res := p.call(args)
+	if res.err != nil {
+		return nil, fmt.Sprintf("failed to get maintenance status: %v, %s", res.err, res.errstr)
+	}
+	status := &cmn.MaintenanceStatus{}
+	if err := jsoniter.Unmarshal(res.outjson, status); err != nil {
+		return nil, fmt.Sprintf("failed to unmarshal maintenance status, err: %v", err)
+	}
+	return status, ""
+}
+
+func (p *proxyrunner) putMaintenance(si *cluster.Snode, enable bool) string {
+	return p.putDaemonAction(si, &cmn.ActionMsg{Action: cmn.ActMaintenance, Value: enable})
+}
+
+// shutdownDaemon asks the daemon to shut down, for its supervisor to restart it; the
+// daemon may well exit before it responds
+func (p *proxyrunner) shutdownDaemon(si *cluster.Snode) {
+	if errstr := p.putDaemonAction(si, &cmn.ActionMsg{Action: cmn.ActShutdown}); errstr != "" {
+		glog.Warningf("rolling upgrade: %s", errstr)
+	}
+}
+
+func (p *proxyrunner) putDaemonAction(si *cluster.Snode, msg *cmn.ActionMsg) string {
+	msgbytes, err := jsoniter.Marshal(msg)
+	cmn.Assert(err == nil, err)
+	args := callArgs{
+		si: si,
+		req: reqArgs{
+			method: http.MethodPut,
+			path:   cmn.URLPath(cmn.Version, cmn.Daemon),
+			body:   msgbytes,
+		},
+		timeout: ctx.config.Timeout.CplaneOperation,
+	}
+	if res := p.call(args); res.err != nil {
+		return fmt.Sprintf("%s %s failed: %v, %s", msg.Action, si.DaemonID, res.err, res.errstr)
+	}
+	return ""
+}
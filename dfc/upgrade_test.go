@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package dfc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cluster"
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestMaintenance(t *testing.T) {
+	h := &httprunner{si: &cluster.Snode{DaemonID: "t1"}, statsif: &fakeStatsTracker{stats: make(map[string]int64)}}
+	var (
+		release = make(chan struct{})
+		served  = make(chan struct{})
+		handler = h.withMaintenance(func(w http.ResponseWriter, r *http.Request) {
+			served <- struct{}{}
+			<-release
+		})
+		maintenance = func(enable bool) {
+			w := httptest.NewRecorder()
+			h.setMaintenance(w, nil, &cmn.ActionMsg{Action: cmn.ActMaintenance, Value: enable})
+			if w.Code != http.StatusOK {
+				t.Fatalf("failed to set maintenance mode %t: %d", enable, w.Code)
+			}
+		}
+		get = func() *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodGet, "/v1/objects/b/o", nil))
+			return w
+		}
+	)
+	go get()
+	<-served
+	maintenance(true)
+	if status := h.maint.status(); !status.Enabled || status.InFlight != 1 || status.Ready {
+		t.Errorf("expected a request in flight, got %+v", status)
+	}
+	w := get()
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get(cmn.HeaderDFCMaintenance) == "" || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected the request rejected, got %d %v", w.Code, w.Header())
+	}
+	close(release)
+	for i := 0; i < 100 && !h.maint.status().Ready; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := h.maint.status(); !status.Ready || status.InFlight != 0 {
+		t.Errorf("expected ready to restart, got %+v", status)
+	}
+	maintenance(false)
+	go func() { <-served }()
+	if w = get(); w.Code != http.StatusOK {
+		t.Errorf("expected the request served, got %d", w.Code)
+	}
+}
+
+// upgradeDaemon is a daemon that restarts upon shutdown
+type upgradeDaemon struct {
+	sync.Mutex
+	srv      *httptest.Server
+	id       string
+	started  time.Time
+	maint    bool
+	inflight int64
+	restarts bool // upon shutdown
+}
+
+func newUpgradeDaemon(id string) *upgradeDaemon {
+	d := &upgradeDaemon{id: id, started: time.Now(), restarts: true}
+	d.srv = httptest.NewServer(http.HandlerFunc(d.handle))
+	return d
+}
+
+func (d *upgradeDaemon) handle(w http.ResponseWriter, r *http.Request) {
+	d.Lock()
+	defer d.Unlock()
+	if r.Method == http.MethodGet {
+		var v interface{} = &cluster.Snode{DaemonID: d.id}
+		if r.URL.Query().Get(cmn.URLParamWhat) == cmn.GetWhatMaint {
+			v = &cmn.MaintenanceStatus{Enabled: d.maint, InFlight: d.inflight, Ready: d.maint && d.inflight == 0, Started: d.started}
+		}
+		b, _ := json.Marshal(v)
+		w.Write(b)
+		return
+	}
+	var msg cmn.ActionMsg
+	json.NewDecoder(r.Body).Decode(&msg)
+	switch msg.Action {
+	case cmn.ActMaintenance:
+		d.maint = msg.Value.(bool)
+	case cmn.ActShutdown:
+		if d.restarts {
+			d.started, d.maint = time.Now(), false
+		}
+	}
+}
+
+func (d *upgradeDaemon) snode() *cluster.Snode {
+	return &cluster.Snode{DaemonID: d.id, PublicNet: cluster.NetInfo{DirectURL: d.srv.URL}}
+}
+
+func (d *upgradeDaemon) inMaintenance() bool {
+	d.Lock()
+	defer d.Unlock()
+	return d.maint
+}
+
+func TestRollingUpgrade(t *testing.T) {
+	poll := upgradePoll
+	defer func() { upgradePoll = poll }()
+	upgradePoll = 10 * time.Millisecond
+
+	var (
+		t1, t2, p2 = newUpgradeDaemon("t1"), newUpgradeDaemon("t2"), newUpgradeDaemon("p2")
+		p          = newDiscoverServerPrimary()
+		smap       = newSmap()
+	)
+	defer func() { t1.srv.Close(); t2.srv.Close(); p2.srv.Close() }()
+	p.statsif = &fakeStatsTracker{stats: make(map[string]int64)}
+	smap.addTarget(t1.snode())
+	smap.addTarget(t2.snode())
+	smap.addProxy(p2.snode())
+	smap.addProxy(p.si)
+	smap.ProxySI = p.si
+	p.smapowner.put(smap)
+
+	start := func(umsg *cmn.RollingUpgradeMsg) int {
+		w := httptest.NewRecorder()
+		p.startUpgrade(w, httptest.NewRequest(http.MethodPut, "/v1/cluster", nil),
+			&cmn.ActionMsg{Action: cmn.ActRollingUpgrade, Value: umsg})
+		return w.Code
+	}
+	wait := func() *cmn.RollingUpgradeStatus {
+		for i := 0; i < 500; i++ {
+			p.upgrade.Lock()
+			status := *p.upgrade.status
+			p.upgrade.Unlock()
+			if status.State != cmn.UpgradeRunning {
+				return &status
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("rolling upgrade did not finish")
+		return nil
+	}
+
+	// all but the primary, the targets first
+	if code := start(&cmn.RollingUpgradeMsg{Shutdown: true}); code != http.StatusOK {
+		t.Fatalf("failed to start rolling upgrade: %d", code)
+	}
+	if code := start(&cmn.RollingUpgradeMsg{}); code != http.StatusConflict {
+		t.Errorf("expected the second upgrade rejected, got %d", code)
+	}
+	status := wait()
+	if status.State != cmn.UpgradeDone || status.Done != 3 || len(status.Nodes) != 3 || status.Nodes[0].ID != "t1" ||
+		status.Nodes[1].ID != "t2" || status.Nodes[2].ID != "p2" || status.Nodes[2].Role != xproxy {
+		t.Errorf("unexpected rolling upgrade status %+v", status)
+	}
+
+	// t1 does not drain in time: taken out of maintenance
+	t1.Lock()
+	t1.inflight = 1
+	t1.Unlock()
+	if code := start(&cmn.RollingUpgradeMsg{Nodes: []string{"t1", "t2"}, DrainTimeout: "50ms"}); code != http.StatusOK {
+		t.Fatalf("failed to start rolling upgrade: %d", code)
+	}
+	if status = wait(); status.State != cmn.UpgradeFailed || status.Nodes[0].Phase != cmn.UpgradeFailed ||
+		status.Nodes[1].Phase != cmn.UpgradePending || t1.inMaintenance() {
+		t.Errorf("expected t1 to fail to drain, got %+v, %+v", status, status.Nodes[0])
+	}
+	t1.Lock()
+	t1.inflight = 0
+	t1.Unlock()
+
+	// t2 is not restarted in time: taken out of maintenance
+	t2.Lock()
+	t2.restarts = false
+	t2.Unlock()
+	if code := start(&cmn.RollingUpgradeMsg{Nodes: []string{"t2"}, Shutdown: true, RestartTimeout: "100ms"}); code != http.StatusOK {
+		t.Fatalf("failed to start rolling upgrade: %d", code)
+	}
+	if status = wait(); status.State != cmn.UpgradeFailed || status.Nodes[0].Phase != cmn.UpgradeFailed || t2.inMaintenance() {
+		t.Errorf("expected t2 to fail to restart and be out of maintenance, got %+v, %+v", status, status.Nodes[0])
+	}
+
+	// t2 is not restarted: aborted while ready
+	if code := start(&cmn.RollingUpgradeMsg{Nodes: []string{"t2"}, Shutdown: true}); code != http.StatusOK {
+		t.Fatalf("failed to start rolling upgrade: %d", code)
+	}
+	for i := 0; i < 500 && !p.upgrade.isRestarting("t2"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	p.abortUpgrade(httptest.NewRecorder(), nil)
+	if status = wait(); status.State != cmn.UpgradeAborted || status.Nodes[0].Phase != cmn.UpgradeAborted || t2.inMaintenance() {
+		t.Errorf("expected t2 aborted and out of maintenance, got %+v", status)
+	}
+
+	// invalid requests
+	for _, ids := range [][]string{{"t1", "t1"}, {"primary"}, {"t3"}} {
+		if code := start(&cmn.RollingUpgradeMsg{Nodes: ids}); code != http.StatusBadRequest {
+			t.Errorf("%v: expected the upgrade rejected, got %d", ids, code)
+		}
+	}
+}
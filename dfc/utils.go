@@ -169,6 +169,11 @@ type cksumvalxxhash struct {
 	val string
 }
 
+type cksumvalcrc32c struct {
+	tag string
+	val string
+}
+
 type cksumvalmd5 struct {
 	tag string
 	val string
@@ -185,12 +190,17 @@ func newcksumvalue(kind string, val string) cksumvalue {
 	if kind == cmn.ChecksumXXHash {
 		return &cksumvalxxhash{kind, val}
 	}
+	if kind == cmn.ChecksumCRC32C {
+		return &cksumvalcrc32c{kind, val}
+	}
 	cmn.Assert(kind == cmn.ChecksumMD5)
 	return &cksumvalmd5{kind, val}
 }
 
 func (v *cksumvalxxhash) get() (string, string) { return v.tag, v.val }
 
+func (v *cksumvalcrc32c) get() (string, string) { return v.tag, v.val }
+
 func (v *cksumvalmd5) get() (string, string) { return v.tag, v.val }
 
 // FIXME: usage
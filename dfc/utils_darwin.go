@@ -8,8 +8,11 @@ package dfc
 import (
 	"encoding/binary"
 	"fmt"
+	"os"
 	"syscall"
 	"unsafe"
+
+	"github.com/NVIDIA/dfcpub/cmn"
 )
 
 // Getxattr returns specific attribute for specified fqn.
@@ -72,5 +75,16 @@ func TotalMemory() (uint64, error) {
 
 	var buf [8]byte
 	copy(buf[:], v)
-	return binary.LittleEndian.Uint64(buf[:]) / MiB, nil
+	return binary.LittleEndian.Uint64(buf[:]) / cmn.MiB, nil
+}
+
+// the process' open file descriptors
+const fdDir = "/dev/fd"
+
+// ctime returns the file's status change time - changes with its content, xattrs, and times
+func ctime(fi os.FileInfo) int64 {
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return stat.Ctimespec.Nano()
+	}
+	return 0
 }
@@ -7,6 +7,7 @@ package dfc
 
 import (
 	"fmt"
+	"os"
 	"syscall"
 
 	"github.com/NVIDIA/dfcpub/cmn"
@@ -55,3 +56,14 @@ func TotalMemory() (mb uint64, err error) {
 	mb = sysinfo.Totalram * uint64(sysinfo.Unit) / cmn.MiB
 	return
 }
+
+// the process' open file descriptors
+const fdDir = "/proc/self/fd"
+
+// ctime returns the file's status change time - changes with its content, xattrs, and times
+func ctime(fi os.FileInfo) int64 {
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return stat.Ctim.Nano()
+	}
+	return 0
+}
@@ -30,6 +30,7 @@ type xactRebalance struct {
 	targetrunner *targetrunner
 	runnerCnt    int
 	confirmCh    chan struct{}
+	remaining    rebRemaining // see reborder.go
 }
 
 type xactLocalRebalance struct {
@@ -44,6 +45,22 @@ type xactLRU struct {
 	targetrunner *targetrunner
 }
 
+type xactCompress struct {
+	cmn.XactBase
+	targetrunner *targetrunner
+}
+
+type xactExpire struct {
+	cmn.XactBase
+	targetrunner *targetrunner
+}
+
+type xactArchive struct {
+	cmn.XactBase
+	targetrunner *targetrunner
+	bucket       string
+}
+
 type xactElection struct {
 	cmn.XactBase
 	proxyrunner *proxyrunner
@@ -56,6 +73,14 @@ type xactRechecksum struct {
 	bucket       string
 }
 
+type xactRenameBucket struct {
+	cmn.XactBase
+	targetrunner *targetrunner
+	bucketFrom   string
+	bucketTo     string
+	renamed      []string // local-bucket dirs of the mountpaths renamed so far (see renamebucket.go)
+}
+
 //===================
 //
 // xactInProgress
@@ -267,6 +292,65 @@ func (q *xactInProgress) renewLRU(t *targetrunner) *xactLRU {
 	return xlru
 }
 
+func (q *xactInProgress) renewCompress(t *targetrunner) *xactCompress {
+	q.lock.Lock()
+	_, xx := q.findU(cmn.ActCompress)
+	if xx != nil {
+		xcomp := xx.(*xactCompress)
+		glog.Infof("%s already running, nothing to do", xcomp)
+		q.lock.Unlock()
+		return nil
+	}
+	id := q.uniqueid()
+	xcomp := &xactCompress{XactBase: *cmn.NewXactBase(id, cmn.ActCompress)}
+	xcomp.targetrunner = t
+	q.add(xcomp)
+	q.lock.Unlock()
+	return xcomp
+}
+
+func (q *xactInProgress) renewExpire(t *targetrunner) *xactExpire {
+	q.lock.Lock()
+	_, xx := q.findU(cmn.ActExpire)
+	if xx != nil {
+		xexp := xx.(*xactExpire)
+		glog.Infof("%s already running, nothing to do", xexp)
+		q.lock.Unlock()
+		return nil
+	}
+	id := q.uniqueid()
+	xexp := &xactExpire{XactBase: *cmn.NewXactBase(id, cmn.ActExpire)}
+	xexp.targetrunner = t
+	q.add(xexp)
+	q.lock.Unlock()
+	return xexp
+}
+
+// renewArchive starts export (ActExport) or import (ActImport) of the local bucket
+// unless either is already running for the bucket
+func (q *xactInProgress) renewArchive(t *targetrunner, kind, bucket string) *xactArchive {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, k := range []string{cmn.ActExport, cmn.ActImport} {
+		for _, xx := range q.findUAll(k) {
+			xarch := xx.(*xactArchive)
+			if xarch.bucket == bucket {
+				glog.Infof("%s already running for bucket %s", xarch, bucket)
+				return nil
+			}
+		}
+	}
+	id := q.uniqueid()
+	xarch := &xactArchive{
+		XactBase:     *cmn.NewXactBase(id, kind),
+		targetrunner: t,
+		bucket:       bucket,
+	}
+	q.add(xarch)
+	return xarch
+}
+
 func (q *xactInProgress) renewElection(p *proxyrunner, vr *VoteRecord) *xactElection {
 	q.lock.Lock()
 	_, xx := q.findU(cmn.ActElection)
@@ -308,6 +392,42 @@ func (q *xactInProgress) renewRechecksum(t *targetrunner, bucket string) *xactRe
 	return xrcksum
 }
 
+func (q *xactInProgress) renewRenameBucket(t *targetrunner, bucketFrom, bucketTo string) *xactRenameBucket {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, xx := range q.findUAll(cmn.ActRenameBucket) {
+		xren := xx.(*xactRenameBucket)
+		if xren.bucketFrom == bucketFrom || xren.bucketTo == bucketTo ||
+			xren.bucketFrom == bucketTo || xren.bucketTo == bucketFrom {
+			glog.Errorf("%s (%s => %s) is in progress", xren, xren.bucketFrom, xren.bucketTo)
+			return nil
+		}
+	}
+	id := q.uniqueid()
+	xren := &xactRenameBucket{
+		XactBase:     *cmn.NewXactBase(id, cmn.ActRenameBucket),
+		targetrunner: t,
+		bucketFrom:   bucketFrom,
+		bucketTo:     bucketTo,
+	}
+	q.add(xren)
+	return xren
+}
+
+func (q *xactInProgress) findRenameBucket(bucketFrom, bucketTo string) *xactRenameBucket {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, xx := range q.findUAll(cmn.ActRenameBucket) {
+		xren := xx.(*xactRenameBucket)
+		if xren.bucketFrom == bucketFrom && xren.bucketTo == bucketTo {
+			return xren
+		}
+	}
+	return nil
+}
+
 func (q *xactInProgress) abortAll() (sleep bool) {
 	q.lock.Lock()
 	for _, xact := range q.xactinp {
@@ -334,6 +454,48 @@ func (xact *xactLRU) String() string {
 		xact.StartTime().Format(timeStampFormat), xact.EndTime().Format(timeStampFormat), d)
 }
 
+//===================
+//
+// xactCompress
+//
+//===================
+func (xact *xactCompress) String() string {
+	if !xact.Finished() {
+		return fmt.Sprintf("xaction %s:%d started %v", xact.Kind(), xact.ID(), xact.StartTime().Format(timeStampFormat))
+	}
+	d := xact.EndTime().Sub(xact.StartTime())
+	return fmt.Sprintf("xaction %s:%d %v finished %v (duration %v)", xact.Kind(), xact.ID(),
+		xact.StartTime().Format(timeStampFormat), xact.EndTime().Format(timeStampFormat), d)
+}
+
+//===================
+//
+// xactExpire
+//
+//===================
+func (xact *xactExpire) String() string {
+	if !xact.Finished() {
+		return fmt.Sprintf("xaction %s:%d started %v", xact.Kind(), xact.ID(), xact.StartTime().Format(timeStampFormat))
+	}
+	d := xact.EndTime().Sub(xact.StartTime())
+	return fmt.Sprintf("xaction %s:%d %v finished %v (duration %v)", xact.Kind(), xact.ID(),
+		xact.StartTime().Format(timeStampFormat), xact.EndTime().Format(timeStampFormat), d)
+}
+
+//===================
+//
+// xactArchive
+//
+//===================
+func (xact *xactArchive) String() string {
+	if !xact.Finished() {
+		return fmt.Sprintf("xaction %s:%d %s started %v", xact.Kind(), xact.ID(), xact.bucket, xact.StartTime().Format(timeStampFormat))
+	}
+	d := xact.EndTime().Sub(xact.StartTime())
+	return fmt.Sprintf("xaction %s:%d %s %v finished %v (duration %v)", xact.Kind(), xact.ID(), xact.bucket,
+		xact.StartTime().Format(timeStampFormat), xact.EndTime().Format(timeStampFormat), d)
+}
+
 //===================
 //
 // xactRebalance
@@ -409,3 +571,22 @@ func (xact *xactRechecksum) abort() {
 	xact.XactBase.Abort()
 	glog.Infof("ABORT: " + xact.String())
 }
+
+//===================
+//
+// xactRenameBucket
+//
+//===================
+func (xact *xactRenameBucket) String() string {
+	if !xact.Finished() {
+		return fmt.Sprintf("xaction %s:%d started %v", xact.Kind(), xact.ID(), xact.StartTime().Format(timeStampFormat))
+	}
+	d := xact.EndTime().Sub(xact.StartTime())
+	return fmt.Sprintf("xaction %s:%d started %v finished %v (duration %v)", xact.Kind(), xact.ID(),
+		xact.StartTime().Format(timeStampFormat), xact.EndTime().Format(timeStampFormat), d)
+}
+
+func (xact *xactRenameBucket) abort() {
+	xact.XactBase.Abort()
+	glog.Infoln("ABORT:", xact.String())
+}
@@ -0,0 +1,33 @@
+// +build darwin freebsd
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package fs
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Fqn2fsAtStartup returns the filesystem - the device or, e.g., the ZFS dataset - mounted
+// at (or above) the given path, as per statfs(2)
+func Fqn2fsAtStartup(fqn string) (string, error) {
+	statfs := syscall.Statfs_t{}
+	if err := syscall.Statfs(fqn, &statfs); err != nil {
+		return "", fmt.Errorf("unable to retrieve FS from fspath %s, err: %v", fqn, err)
+	}
+	b := make([]byte, 0, len(statfs.Mntfromname))
+	for _, c := range statfs.Mntfromname {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	if len(b) == 0 {
+		return "", fmt.Errorf("unable to retrieve FS from fspath %s", fqn)
+	}
+	return string(b), nil
+}
@@ -1,14 +0,0 @@
-/*
- * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
- *
- */
-
-package fs
-
-import (
-	"fmt"
-)
-
-func Fqn2fsAtStartup(fqn string) (string, error) {
-	return "", fmt.Errorf("Not yet implemented")
-}
@@ -0,0 +1,18 @@
+// +build darwin freebsd
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package fs
+
+import (
+	"os"
+)
+
+// WillNeed is not supported: the file is only checked for existence
+func WillNeed(path string) error {
+	_, err := os.Stat(path)
+	return err
+}
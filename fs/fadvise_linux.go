@@ -0,0 +1,23 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// WillNeed advises the kernel to read the entire file into the page cache, asynchronously
+func WillNeed(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = unix.Fadvise(int(file.Fd()), 0, 0, unix.FADV_WILLNEED)
+	file.Close()
+	return err
+}
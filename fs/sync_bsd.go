@@ -0,0 +1,22 @@
+// +build darwin freebsd
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package fs
+
+import (
+	"os"
+	"syscall"
+)
+
+func Fdatasync(file *os.File) error {
+	return file.Sync()
+}
+
+func Syncfs(path string) error {
+	syscall.Sync()
+	return nil
+}
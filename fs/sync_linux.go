@@ -0,0 +1,28 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Fdatasync flushes the file's data, and only the metadata required to read it back
+func Fdatasync(file *os.File) error {
+	return unix.Fdatasync(int(file.Fd()))
+}
+
+// Syncfs flushes the entire filesystem that contains the given path
+func Syncfs(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = unix.Syncfs(int(dir.Fd()))
+	dir.Close()
+	return err
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// WalkOpts.Scope enum: 0 (the default) walks both local and Cloud buckets
+const (
+	WalkLocal = 1 << iota
+	WalkCloud
+)
+
+// ErrWalkAborted is returned by WalkObjects when the walk is aborted via WalkOpts.Abort
+var ErrWalkAborted = errors.New("walk aborted")
+
+type (
+	// WalkEntry is an object (file) found by WalkObjects
+	WalkEntry struct {
+		FQN       string
+		FileInfo  os.FileInfo
+		MpathInfo *MountpathInfo
+		Bucket    string
+		Objname   string
+		IsLocal   bool
+	}
+	// WalkFunc is called for each object; a non-nil error stops the walk of the mountpath
+	WalkFunc func(entry *WalkEntry) error
+
+	// Throttler slows down the walk, e.g. in accordance with the disk utilization
+	// (see cluster.Throttle)
+	Throttler interface {
+		Sleep()
+	}
+
+	// WalkOpts filters the objects and controls the walk; the zero value walks all
+	// objects of all buckets on all available mountpaths, in parallel
+	WalkOpts struct {
+		Mpaths    []*MountpathInfo                         // mountpaths to walk; nil - all available
+		Scope     int                                      // WalkLocal | WalkCloud; 0 - both
+		Bucket    string                                   // "" - all buckets
+		Prefix    string                                   // object name prefix
+		Parallel  int                                      // max number of mountpaths walked concurrently; 0 - all
+		Skip      func(fqn string) bool                    // e.g., workfiles: the files that are not objects
+		Throttler func(mpathInfo *MountpathInfo) Throttler // per-mountpath throttler; nil - no throttling
+		Abort     <-chan struct{}                          // closing it aborts the walk
+	}
+)
+
+// WalkObjects calls cb for each object matching the options. Mountpaths are walked in
+// parallel (each - by a single goroutine), and so cb must be safe for concurrent use
+// unless opts.Parallel is 1. Files and directories that disappear during the walk are
+// skipped. An error (or abort) stops the walk of the respective mountpath only - the
+// first error is returned once all the mountpaths are done.
+func (mfs *MountedFS) WalkObjects(opts *WalkOpts, cb WalkFunc) error {
+	mpaths := opts.Mpaths
+	if mpaths == nil {
+		avail, _ := mfs.Get()
+		mpaths = make([]*MountpathInfo, 0, len(avail))
+		for _, mpathInfo := range avail {
+			mpaths = append(mpaths, mpathInfo)
+		}
+	}
+	parallel := opts.Parallel
+	if parallel <= 0 || parallel > len(mpaths) {
+		parallel = len(mpaths)
+	}
+	var (
+		wg   = &sync.WaitGroup{}
+		sema = make(chan struct{}, parallel)
+		errs = make([]error, len(mpaths))
+	)
+	for i, mpathInfo := range mpaths {
+		wg.Add(1)
+		sema <- struct{}{}
+		go func(i int, mpathInfo *MountpathInfo) {
+			errs[i] = mfs.walkMpath(mpathInfo, opts, cb)
+			<-sema
+			wg.Done()
+		}(i, mpathInfo)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mfs *MountedFS) walkMpath(mpathInfo *MountpathInfo, opts *WalkOpts, cb WalkFunc) error {
+	var throttler Throttler
+	if opts.Throttler != nil {
+		throttler = opts.Throttler(mpathInfo)
+	}
+	for _, islocal := range []bool{true, false} {
+		if islocal && opts.Scope == WalkCloud || !islocal && opts.Scope == WalkLocal {
+			continue
+		}
+		root := mfs.MakePathCloud(mpathInfo.Path)
+		if islocal {
+			root = mfs.MakePathLocal(mpathInfo.Path)
+		}
+		start := root
+		if opts.Bucket != "" {
+			start = filepath.Join(root, opts.Bucket)
+		}
+		walk := func(fqn string, osfi os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			select {
+			case <-opts.Abort:
+				return ErrWalkAborted
+			default:
+			}
+			if fqn == root {
+				return nil
+			}
+			// bucket/objname
+			var (
+				rel             = fqn[len(root)+1:]
+				bucket, objname = rel, ""
+			)
+			if i := strings.IndexByte(rel, filepath.Separator); i >= 0 {
+				bucket, objname = rel[:i], rel[i+1:]
+			}
+			if osfi.IsDir() {
+				if objname != "" && opts.Prefix != "" && !prefixMayMatch(objname+"/", opts.Prefix) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if objname == "" || !strings.HasPrefix(objname, opts.Prefix) {
+				return nil
+			}
+			if opts.Skip != nil && opts.Skip(fqn) {
+				return nil
+			}
+			if throttler != nil {
+				throttler.Sleep()
+			}
+			return cb(&WalkEntry{FQN: fqn, FileInfo: osfi, MpathInfo: mpathInfo, Bucket: bucket, Objname: objname, IsLocal: islocal})
+		}
+		if err := filepath.Walk(start, walk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prefixMayMatch returns true if the directory (with the trailing slash) may contain
+// objects with the given name prefix
+func prefixMayMatch(dir, prefix string) bool {
+	return strings.HasPrefix(dir, prefix) || strings.HasPrefix(prefix, dir)
+}
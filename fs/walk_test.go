@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWalkObjects(t *testing.T) {
+	mfs := NewMountedFS("local", "cloud")
+	mfs.DisableFsIDCheck()
+	var mpaths []string
+	for i := 0; i < 2; i++ {
+		mpath, err := ioutil.TempDir("", "walk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(mpath)
+		if err := mfs.Add(mpath); err != nil {
+			t.Fatal(err)
+		}
+		mpaths = append(mpaths, mpath)
+	}
+	files := []string{
+		"local/lb/a/obj1", "local/lb/a/obj1.work", "local/lb/b/obj2", "local/lb/obj3",
+		"cloud/cb/a/obj4",
+	}
+	for i, name := range files {
+		fqn := filepath.Join(mpaths[i%2], name)
+		if err := os.MkdirAll(filepath.Dir(fqn), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fqn, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	walk := func(opts *WalkOpts) (names []string, err error) {
+		var mtx sync.Mutex
+		err = mfs.WalkObjects(opts, func(e *WalkEntry) error {
+			mtx.Lock()
+			names = append(names, e.Bucket+"/"+e.Objname)
+			mtx.Unlock()
+			return nil
+		})
+		sort.Strings(names)
+		return
+	}
+	skipWork := func(fqn string) bool { return strings.HasSuffix(fqn, ".work") }
+	tests := []struct {
+		opts     WalkOpts
+		expected string
+	}{
+		{WalkOpts{Skip: skipWork}, "cb/a/obj4 lb/a/obj1 lb/b/obj2 lb/obj3"},
+		{WalkOpts{}, "cb/a/obj4 lb/a/obj1 lb/a/obj1.work lb/b/obj2 lb/obj3"},
+		{WalkOpts{Scope: WalkLocal, Skip: skipWork}, "lb/a/obj1 lb/b/obj2 lb/obj3"},
+		{WalkOpts{Scope: WalkCloud, Parallel: 1}, "cb/a/obj4"},
+		{WalkOpts{Bucket: "lb", Prefix: "a/", Skip: skipWork}, "lb/a/obj1"},
+		{WalkOpts{Prefix: "o"}, "lb/obj3"},
+		{WalkOpts{Bucket: "nonexisting"}, ""},
+	}
+	for _, test := range tests {
+		names, err := walk(&test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(names, " "); got != test.expected {
+			t.Errorf("%+v: expected %q, got %q", test.opts, test.expected, got)
+		}
+	}
+
+	// abort
+	abort := make(chan struct{})
+	close(abort)
+	if names, err := walk(&WalkOpts{Abort: abort}); err != ErrWalkAborted || len(names) != 0 {
+		t.Errorf("expected aborted walk, got %v (%v)", err, names)
+	}
+}
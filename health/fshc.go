@@ -119,6 +119,18 @@ func (f *FSHC) OnErr(fqn string) {
 	f.fileListCh <- fqn
 }
 
+// OnDiskFailing disables the mountpath right away, without testing it: its disk is failing
+// as per the SMART attributes (see ios.SMARTRunner)
+func (f *FSHC) OnDiskFailing(mpath, why string) {
+	if !f.Getconf().FSHC.Enabled || f.dispatcher == nil {
+		return
+	}
+	glog.Errorf("Disabling mountpath %s: %s", mpath, why)
+	if disabled, exists := f.dispatcher.Disable(mpath, why); !disabled && exists {
+		glog.Errorf("Failed to disable mountpath: %s", mpath)
+	}
+}
+
 //
 // private methods
 //
@@ -0,0 +1,310 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ================================ Summary ===============================================
+//
+// Inside a container with block I/O limits (the cgroup io or blkio controller), the
+// host-level %util reported by iostat misrepresents the container's own throttling: a disk
+// that is mostly idle may well be saturated as far as the container's bps or iops limit is
+// concerned. When DFC is containerized (Docker, Kubernetes), IostatRunner therefore also
+// reads the usage and the limits of its cgroup - io.stat and io.max (cgroup v2) or
+// blkio.throttle.* (v1) - and, for each limited device, reports the higher of the host's
+// and the container's utilization as the device's %util (and the latter as cgroup%util).
+// The container's utilization is its usage rate relative to the tightest of the limits.
+//
+// ================================ Summary ===============================================
+
+const (
+	cgroupRoot    = "/sys/fs/cgroup"
+	sysClassBlock = "/sys/class/block"
+	cgroupUtil    = "cgroup%util" // the container's utilization of the device (iometrics)
+)
+
+type (
+	// cumulative usage of a device
+	cgroupIO struct {
+		rbytes, wbytes, rios, wios int64
+	}
+	// per-second limits of a device; zero - unlimited
+	cgroupLimits struct {
+		rbps, wbps, riops, wiops int64
+	}
+	cgroupCollector struct {
+		dir     string              // the container's cgroup directory of the io (blkio) controller
+		v2      bool                // cgroup v2 (unified) vs v1
+		devnums map[string]string   // device name => "major:minor"
+		prev    map[string]cgroupIO // "major:minor" => usage as of the last sample
+		sampled time.Time           // time of the last sample
+		util    map[string]float64  // "major:minor" => utilization of the limited devices
+	}
+)
+
+// newCgroupCollector returns nil unless DFC runs in a container that has its own cgroup
+func newCgroupCollector() *cgroupCollector {
+	if !containerized() {
+		return nil
+	}
+	dir, v2 := cgroupDir()
+	if dir == "" {
+		glog.Warningln("Containerized, but failed to find the io (blkio) cgroup - using host iostat only")
+		return nil
+	}
+	glog.Infof("Containerized: collecting the I/O usage and limits of cgroup %s (v2: %t)", dir, v2)
+	return &cgroupCollector{dir: dir, v2: v2, devnums: make(map[string]string)}
+}
+
+func containerized() bool {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return true
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	b, err := ioutil.ReadFile("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	for _, s := range []string{"docker", "kubepods", "containerd", "lxc"} {
+		if strings.Contains(string(b), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// cgroupDir returns the cgroup directory of the process: the blkio controller's (v1)
+// or the unified one (v2)
+func cgroupDir() (dir string, v2 bool) {
+	b, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if cmn.StringInSlice("blkio", strings.Split(parts[1], ",")) {
+			return cgroupPath(filepath.Join(cgroupRoot, "blkio"), parts[2]), false
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+				dir, v2 = cgroupPath(cgroupRoot, parts[2]), true
+			}
+		}
+	}
+	return
+}
+
+// with the private cgroup namespace, the container's cgroup is mounted as the root
+func cgroupPath(mount, path string) string {
+	if path != "/" {
+		dir := filepath.Join(mount, path)
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir
+		}
+	}
+	return mount
+}
+
+// sample computes the utilization of the limited devices since the previous sample
+func (c *cgroupCollector) sample(now time.Time) {
+	usage, limits, err := c.read()
+	if err != nil {
+		glog.Errorf("Failed to read cgroup %s I/O stats, err: %v", c.dir, err)
+		return
+	}
+	elapsed := now.Sub(c.sampled).Seconds()
+	util := make(map[string]float64, len(limits))
+	for devnum, limit := range limits {
+		cur, ok := usage[devnum]
+		prev, okprev := c.prev[devnum]
+		if !ok || !okprev || c.sampled.IsZero() || elapsed <= 0 {
+			continue
+		}
+		var u float64
+		for _, x := range [][2]int64{
+			{cur.rbytes - prev.rbytes, limit.rbps}, {cur.wbytes - prev.wbytes, limit.wbps},
+			{cur.rios - prev.rios, limit.riops}, {cur.wios - prev.wios, limit.wiops},
+		} {
+			if x[1] > 0 {
+				u = math.Max(u, float64(x[0])/elapsed/float64(x[1])*100)
+			}
+		}
+		util[devnum] = math.Min(u, 100)
+	}
+	c.prev, c.sampled, c.util = usage, now, util
+}
+
+// adjust reports the container's utilization of the device, if limited, as the device's
+// %util unless the host's is higher
+func (c *cgroupCollector) adjust(device string, iometrics cmn.SimpleKVs) {
+	u, ok := c.util[c.devnum(device)]
+	if !ok {
+		return
+	}
+	utilstr := strconv.FormatFloat(u, 'f', 2, 64)
+	iometrics[cgroupUtil] = utilstr
+	if host, err := strconv.ParseFloat(iometrics["%util"], 64); err != nil || u > host {
+		iometrics["%util"] = utilstr
+	}
+}
+
+func (c *cgroupCollector) devnum(device string) string {
+	devnum, ok := c.devnums[device]
+	if !ok {
+		if b, err := ioutil.ReadFile(filepath.Join(sysClassBlock, device, "dev")); err == nil {
+			devnum = strings.TrimSpace(string(b))
+		}
+		c.devnums[device] = devnum
+	}
+	return devnum
+}
+
+func (c *cgroupCollector) read() (usage map[string]cgroupIO, limits map[string]cgroupLimits, err error) {
+	if c.v2 {
+		return c.readV2()
+	}
+	return c.readV1()
+}
+
+// io.stat: "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0"
+// io.max:  "8:0 rbps=max wbps=1048576 riops=max wiops=max"
+func (c *cgroupCollector) readV2() (usage map[string]cgroupIO, limits map[string]cgroupLimits, err error) {
+	var lines []string
+	if lines, err = readLines(filepath.Join(c.dir, "io.stat")); err != nil {
+		return
+	}
+	usage = make(map[string]cgroupIO, len(lines))
+	for _, line := range lines {
+		devnum, kvs := parseCgroupKVs(line)
+		usage[devnum] = cgroupIO{rbytes: kvs["rbytes"], wbytes: kvs["wbytes"], rios: kvs["rios"], wios: kvs["wios"]}
+	}
+	limits = make(map[string]cgroupLimits)
+	if lines, err = readLines(filepath.Join(c.dir, "io.max")); err != nil {
+		if os.IsNotExist(err) { // the root cgroup
+			err = nil
+		}
+		return
+	}
+	for _, line := range lines {
+		devnum, kvs := parseCgroupKVs(line)
+		limits[devnum] = cgroupLimits{rbps: kvs["rbps"], wbps: kvs["wbps"], riops: kvs["riops"], wiops: kvs["wiops"]}
+	}
+	return
+}
+
+// "major:minor key=value ..." where the value "max" means unlimited (zero)
+func parseCgroupKVs(line string) (devnum string, kvs map[string]int64) {
+	fields := strings.Fields(line)
+	kvs = make(map[string]int64, len(fields))
+	for i, field := range fields {
+		if i == 0 {
+			devnum = field
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseInt(kv[1], 10, 64); err == nil {
+			kvs[kv[0]] = v
+		}
+	}
+	return
+}
+
+// blkio.throttle.io_service_bytes, blkio.throttle.io_serviced: "8:0 Read 1459200" (also
+// Write, Sync, Async, Total); blkio.throttle.{read,write}_{bps,iops}_device: "8:0 1048576"
+func (c *cgroupCollector) readV1() (usage map[string]cgroupIO, limits map[string]cgroupLimits, err error) {
+	usage = make(map[string]cgroupIO)
+	for _, file := range []string{"blkio.throttle.io_service_bytes", "blkio.throttle.io_serviced"} {
+		var lines []string
+		if lines, err = readLines(filepath.Join(c.dir, file)); err != nil {
+			return
+		}
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			if len(fields) != 3 {
+				continue // "Total N"
+			}
+			v, errp := strconv.ParseInt(fields[2], 10, 64)
+			if errp != nil {
+				continue
+			}
+			io := usage[fields[0]]
+			switch {
+			case fields[1] == "Read" && file == "blkio.throttle.io_service_bytes":
+				io.rbytes = v
+			case fields[1] == "Write" && file == "blkio.throttle.io_service_bytes":
+				io.wbytes = v
+			case fields[1] == "Read":
+				io.rios = v
+			case fields[1] == "Write":
+				io.wios = v
+			}
+			usage[fields[0]] = io
+		}
+	}
+	limits = make(map[string]cgroupLimits)
+	for _, file := range []string{"read_bps_device", "write_bps_device", "read_iops_device", "write_iops_device"} {
+		lines, errl := readLines(filepath.Join(c.dir, "blkio.throttle."+file))
+		if errl != nil {
+			continue // e.g., the root cgroup
+		}
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			v, errp := strconv.ParseInt(fields[1], 10, 64)
+			if errp != nil {
+				continue
+			}
+			limit := limits[fields[0]]
+			switch file {
+			case "read_bps_device":
+				limit.rbps = v
+			case "write_bps_device":
+				limit.wbps = v
+			case "read_iops_device":
+				limit.riops = v
+			default:
+				limit.wiops = v
+			}
+			limits[fields[0]] = limit
+		}
+	}
+	return
+}
+
+func readLines(path string) (lines []string, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return
+}
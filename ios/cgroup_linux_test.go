@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestCgroupCollector(t *testing.T) {
+	tests := []struct {
+		v2              bool
+		before, after   map[string]string
+		limits          map[string]string
+		expected        float64 // %util of 8:0, the only limited device
+		unlimitedDevnum string
+	}{
+		{
+			v2:     true,
+			before: map[string]string{"io.stat": "8:0 rbytes=0 wbytes=0 rios=0 wios=0\n8:16 rbytes=0 wbytes=0 rios=0 wios=0\n"},
+			after:  map[string]string{"io.stat": "8:0 rbytes=1048576 wbytes=0 rios=10 wios=50\n8:16 rbytes=1000 wbytes=0 rios=1 wios=0\n"},
+			limits: map[string]string{"io.max": "8:0 rbps=max wbps=1048576 riops=max wiops=100\n"},
+			// 50 writes in 1s against 100 iops
+			expected:        50,
+			unlimitedDevnum: "8:16",
+		},
+		{
+			before: map[string]string{
+				"blkio.throttle.io_service_bytes": "8:0 Read 0\n8:0 Write 0\n8:0 Total 0\nTotal 0\n",
+				"blkio.throttle.io_serviced":      "8:0 Read 0\n8:0 Write 0\n8:0 Total 0\nTotal 0\n",
+			},
+			after: map[string]string{
+				"blkio.throttle.io_service_bytes": "8:0 Read 786432\n8:0 Write 0\n8:0 Total 786432\nTotal 786432\n",
+				"blkio.throttle.io_serviced":      "8:0 Read 3\n8:0 Write 0\n8:0 Total 3\nTotal 3\n",
+			},
+			limits: map[string]string{"blkio.throttle.read_bps_device": "8:0 1048576\n"},
+			// 768KiB in 1s against 1MiB/s
+			expected: 75,
+		},
+	}
+	for _, test := range tests {
+		dir, err := ioutil.TempDir("", "cgroup")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		write := func(files map[string]string) {
+			for name, content := range files {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		c := &cgroupCollector{dir: dir, v2: test.v2, devnums: map[string]string{"sda": "8:0", "sdb": "8:16"}}
+		now := time.Now()
+		write(test.before)
+		write(test.limits)
+		c.sample(now)
+		if len(c.util) != 0 {
+			t.Errorf("v2=%t: expected no utilization upon the first sample, got %v", test.v2, c.util)
+		}
+		write(test.after)
+		c.sample(now.Add(time.Second))
+		if u, ok := c.util["8:0"]; !ok || u != test.expected {
+			t.Errorf("v2=%t: expected %%util %.2f, got %v", test.v2, test.expected, c.util)
+		}
+		if _, ok := c.util[test.unlimitedDevnum]; test.unlimitedDevnum != "" && ok {
+			t.Errorf("v2=%t: unexpected %%util of the unlimited device %s", test.v2, test.unlimitedDevnum)
+		}
+
+		// the container's utilization is reported unless the host's is higher
+		iometrics := cmn.SimpleKVs{"%util": "10.00"}
+		c.adjust("sda", iometrics)
+		if iometrics[cgroupUtil] != iometrics["%util"] || iometrics["%util"] == "10.00" {
+			t.Errorf("v2=%t: expected the container's %%util, got %v", test.v2, iometrics)
+		}
+		iometrics = cmn.SimpleKVs{"%util": "99.00"}
+		c.adjust("sda", iometrics)
+		if iometrics["%util"] != "99.00" {
+			t.Errorf("v2=%t: expected the host's %%util, got %v", test.v2, iometrics)
+		}
+		iometrics = cmn.SimpleKVs{"%util": "10.00"}
+		c.adjust("sdb", iometrics)
+		if _, ok := iometrics[cgroupUtil]; ok || iometrics["%util"] != "10.00" {
+			t.Errorf("v2=%t: expected the unlimited device's %%util unchanged, got %v", test.v2, iometrics)
+		}
+	}
+}
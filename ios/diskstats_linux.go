@@ -0,0 +1,186 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ================================ Summary ===============================================
+//
+// Native disk statistics: rather than running the iostat (sysstat) binary, IostatRunner reads
+// the cumulative per-device counters from /proc/diskstats and the CPU times from /proc/stat
+// once every stats_time, and computes the same extended statistics that `iostat -cdxm`
+// reports (sysstat 11 names): r/s, w/s, rMB/s, wMB/s, await, r_await, w_await, %util, etc.,
+// as well as the CPU %idle. The devices that have never done any I/O are not reported,
+// as with iostat. The external iostat is used only if periodic.iostat_external is set.
+//
+// ================================ Summary ===============================================
+
+const (
+	procDiskstats = "/proc/diskstats"
+	procStat      = "/proc/stat"
+	sectorSize    = 512
+)
+
+type (
+	// cumulative counters of a device: /proc/diskstats fields 4 through 14
+	diskCounters struct {
+		reads, rmerged, rsectors, rticks   int64
+		writes, wmerged, wsectors, wticks  int64
+		inflight, ioticks, weightedioticks int64
+	}
+	diskstatsCollector struct {
+		diskstats, stat string                  // /proc files (tests)
+		prev            map[string]diskCounters // device => counters as of the last sample
+		prevCPU         []int64                 // /proc/stat cpu times as of the last sample
+		sampled         time.Time
+	}
+)
+
+func newDiskstatsCollector() *diskstatsCollector {
+	return &diskstatsCollector{diskstats: procDiskstats, stat: procStat}
+}
+
+// sample returns the statistics of the devices and the CPU %idle since the previous sample;
+// the first sample returns no devices
+func (c *diskstatsCollector) sample(now time.Time) (disks map[string]cmn.SimpleKVs, cpuidle string, err error) {
+	counters, err := c.readDiskstats()
+	if err != nil {
+		return
+	}
+	cpu, err := c.readCPU()
+	if err != nil {
+		return
+	}
+	elapsed := now.Sub(c.sampled).Seconds()
+	if !c.sampled.IsZero() && elapsed > 0 {
+		disks = make(map[string]cmn.SimpleKVs, len(counters))
+		for device, cur := range counters {
+			if prev, ok := c.prev[device]; ok {
+				disks[device] = diskMetrics(&cur, &prev, elapsed)
+			}
+		}
+		cpuidle = cpuIdle(cpu, c.prevCPU)
+	}
+	c.prev, c.prevCPU, c.sampled = counters, cpu, now
+	return
+}
+
+// e.g.: "8 0 sda 1540 300 98114 2048 4400 2111 293008 61736 0 5196 63784 ..."
+func (c *diskstatsCollector) readDiskstats() (counters map[string]diskCounters, err error) {
+	lines, err := readLines(c.diskstats)
+	if err != nil {
+		return
+	}
+	counters = make(map[string]diskCounters, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 14 {
+			continue
+		}
+		var v [11]int64
+		for i := range v {
+			if v[i], err = strconv.ParseInt(fields[i+3], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s line %q, err: %v", c.diskstats, line, err)
+			}
+		}
+		if v[0] == 0 && v[4] == 0 {
+			continue // no I/O since boot
+		}
+		counters[fields[2]] = diskCounters{
+			reads: v[0], rmerged: v[1], rsectors: v[2], rticks: v[3],
+			writes: v[4], wmerged: v[5], wsectors: v[6], wticks: v[7],
+			inflight: v[8], ioticks: v[9], weightedioticks: v[10],
+		}
+	}
+	return
+}
+
+// e.g.: "cpu  4705 356 584 3699176 23060 0 277 0 0 0" (user nice system idle iowait irq
+// softirq steal guest guest_nice, in USER_HZ); guest time is included in user
+func (c *diskstatsCollector) readCPU() (cpu []int64, err error) {
+	lines, err := readLines(c.stat)
+	if err != nil {
+		return
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		if len(fields) > 9 {
+			fields = fields[:9] // up to and including steal
+		}
+		cpu = make([]int64, len(fields)-1)
+		for i := range cpu {
+			if cpu[i], err = strconv.ParseInt(fields[i+1], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s line %q, err: %v", c.stat, line, err)
+			}
+		}
+		return
+	}
+	return nil, fmt.Errorf("no cpu line in %s", c.stat)
+}
+
+func diskMetrics(cur, prev *diskCounters, elapsed float64) cmn.SimpleKVs {
+	var (
+		reads    = float64(cur.reads - prev.reads)
+		writes   = float64(cur.writes - prev.writes)
+		rsectors = float64(cur.rsectors - prev.rsectors)
+		wsectors = float64(cur.wsectors - prev.wsectors)
+		rticks   = float64(cur.rticks - prev.rticks)
+		wticks   = float64(cur.wticks - prev.wticks)
+		ioticks  = float64(cur.ioticks - prev.ioticks)
+		ios      = reads + writes
+		perio    = func(x, n float64) float64 {
+			if n <= 0 {
+				return 0
+			}
+			return x / n
+		}
+		util = ioticks / (elapsed * 10) // ms per second => percent
+	)
+	if util > 100 {
+		util = 100
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	return cmn.SimpleKVs{
+		"rrqm/s":   f(float64(cur.rmerged-prev.rmerged) / elapsed),
+		"wrqm/s":   f(float64(cur.wmerged-prev.wmerged) / elapsed),
+		"r/s":      f(reads / elapsed),
+		"w/s":      f(writes / elapsed),
+		"rMB/s":    f(rsectors * sectorSize / cmn.MiB / elapsed),
+		"wMB/s":    f(wsectors * sectorSize / cmn.MiB / elapsed),
+		"avgrq-sz": f(perio(rsectors+wsectors, ios)),
+		"avgqu-sz": f(float64(cur.weightedioticks-prev.weightedioticks) / (elapsed * 1000)),
+		"await":    f(perio(rticks+wticks, ios)),
+		"r_await":  f(perio(rticks, reads)),
+		"w_await":  f(perio(wticks, writes)),
+		"svctm":    f(perio(ioticks, ios)),
+		"%util":    f(util),
+	}
+}
+
+func cpuIdle(cur, prev []int64) string {
+	if len(cur) != len(prev) || len(cur) < 4 {
+		return ""
+	}
+	var total int64
+	for i := range cur {
+		total += cur[i] - prev[i]
+	}
+	if total <= 0 {
+		return ""
+	}
+	idle := cur[3] - prev[3]
+	return strconv.FormatFloat(float64(idle)/float64(total)*100, 'f', 2, 64)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskstatsCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &diskstatsCollector{diskstats: filepath.Join(dir, "diskstats"), stat: filepath.Join(dir, "stat")}
+	write := func(diskstats, stat string) {
+		if err := ioutil.WriteFile(c.diskstats, []byte(diskstats), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(c.stat, []byte(stat), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("   8       0 sda 100 0 800 100 100 0 800 100 0 100 200 0 0 0 0\n"+
+		"   7       0 loop0 0 0 0 0 0 0 0 0 0 0 0\n",
+		"cpu  100 0 100 700 100 0 0 0 0 0\ncpu0 100 0 100 700 100 0 0 0 0 0\n")
+	now := time.Now()
+	if disks, _, err := c.sample(now); err != nil || len(disks) != 0 {
+		t.Fatalf("expected no stats upon the first sample, got %v, %v", disks, err)
+	}
+
+	// in 2s: 100 reads of 512KiB each (avg. 4ms), 300 writes of 4KiB each (avg. 8ms), busy 1.5s
+	write("   8       0 sda 200 10 103200 500 400 30 3200 2500 2 1600 3200 0 0 0 0\n"+
+		"   7       0 loop0 0 0 0 0 0 0 0 0 0 0 0\n",
+		"cpu  150 0 150 1500 200 0 0 0 0 0\ncpu0 150 0 150 1500 200 0 0 0 0 0\n")
+	disks, cpuidle, err := c.sample(now.Add(2 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := disks["loop0"]; ok || len(disks) != 1 {
+		t.Fatalf("expected sda only, got %v", disks)
+	}
+	expected := map[string]string{
+		"r/s": "50.00", "w/s": "150.00", "rrqm/s": "5.00", "wrqm/s": "15.00",
+		"rMB/s": "25.00", "wMB/s": "0.59",
+		"r_await": "4.00", "w_await": "8.00", "await": "7.00",
+		"%util": "75.00", "avgqu-sz": "1.50",
+	}
+	for name, value := range expected {
+		if disks["sda"][name] != value {
+			t.Errorf("%s: expected %s, got %s", name, value, disks["sda"][name])
+		}
+	}
+	// idle 800 out of 1000
+	if cpuidle != "80.00" {
+		t.Errorf("expected 80.00 CPU idle, got %s", cpuidle)
+	}
+}
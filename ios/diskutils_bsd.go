@@ -0,0 +1,37 @@
+// +build darwin freebsd
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"regexp"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// the disk of a partition or a slice: macOS /dev/disk1s1 => disk1,
+// FreeBSD /dev/ada0p2 => ada0, /dev/da0s1a => da0, /dev/nvd0p1 => nvd0
+var diskRegex = regexp.MustCompile(`^/dev/([a-z]+[0-9]+)`)
+
+//
+// private
+//
+
+// fs2disks returns the disk of the filesystem's device; none for the filesystems that
+// are not mounted off a device, e.g. ZFS datasets
+func fs2disks(fs string) (disks cmn.StringSet) {
+	m := diskRegex.FindStringSubmatch(fs)
+	if m == nil {
+		return
+	}
+	disks = cmn.StringSet{m[1]: struct{}{}}
+	if glog.V(3) {
+		glog.Infof("Device: %s, disk list: %v\n", fs, disks)
+	}
+	return
+}
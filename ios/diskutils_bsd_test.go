@@ -0,0 +1,32 @@
+// +build darwin freebsd
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import "testing"
+
+func TestFs2disksBSD(t *testing.T) {
+	for fs, expected := range map[string]string{
+		"/dev/disk1s1":       "disk1",
+		"/dev/ada0p2":        "ada0",
+		"/dev/da0s1a":        "da0",
+		"/dev/nvd0p1":        "nvd0",
+		"zroot/ROOT/default": "",
+		"/dev/gpt/rootfs":    "",
+	} {
+		disks := fs2disks(fs)
+		if expected == "" {
+			if len(disks) != 0 {
+				t.Errorf("%s: expected no disks, got %v", fs, disks)
+			}
+			continue
+		}
+		if _, ok := disks[expected]; !ok || len(disks) != 1 {
+			t.Errorf("%s: expected %s, got %v", fs, expected, disks)
+		}
+	}
+}
@@ -6,8 +6,10 @@
 package ios
 
 import (
+	"io/ioutil"
+	"os"
 	"os/exec"
-	"strconv"
+	"path/filepath"
 	"strings"
 
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
@@ -24,6 +26,12 @@ type BlockDevice struct {
 	BlockDevices []BlockDevice `json:"children"`
 }
 
+const (
+	sysBlockDir = "/sys/class/block"
+	// max depth of a device stack, e.g.: LV => dm-crypt => md RAID => partition => disk
+	maxStackDepth = 16
+)
+
 //
 // private
 //
@@ -31,6 +39,9 @@ type BlockDevice struct {
 // This method is used when starting iostat runner to
 // retrieve the disks associated with a filesystem.
 func fs2disks(fs string) (disks cmn.StringSet) {
+	if disks = sysfs2disks(sysBlockDir, fs); len(disks) != 0 {
+		return
+	}
 	getDiskCommand := exec.Command("lsblk", "-no", "name", "-J")
 	outputBytes, err := getDiskCommand.Output()
 	if err != nil || len(outputBytes) == 0 {
@@ -42,6 +53,53 @@ func fs2disks(fs string) (disks cmn.StringSet) {
 	return
 }
 
+// sysfs2disks resolves the filesystem's block device to the disks underneath by walking
+// the device's slaves in sysfs all the way down: device-mapper (LVM, dm-crypt, multipath)
+// and md devices to their components, and partitions to their disks. NVMe namespaces
+// (e.g., nvme0n1) are the disks - the names under which their I/O is reported.
+func sysfs2disks(sysblock, fs string) (disks cmn.StringSet) {
+	if !strings.HasPrefix(fs, "/dev/") {
+		return
+	}
+	// e.g. /dev/mapper/vg0-lv0 => /dev/dm-2
+	dev := fs
+	if resolved, err := filepath.EvalSymlinks(fs); err == nil {
+		dev = resolved
+	}
+	disks = make(cmn.StringSet)
+	device2disks(sysblock, filepath.Base(dev), disks, 0)
+	if glog.V(3) {
+		glog.Infof("Device: %s, disk list: %v\n", fs, disks)
+	}
+	return
+}
+
+func device2disks(sysblock, device string, disks cmn.StringSet, depth int) {
+	if depth > maxStackDepth {
+		glog.Errorf("%s: block device stack is too deep", device)
+		return
+	}
+	dir := filepath.Join(sysblock, device)
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	// partition: /sys/class/block/sda1 => ../../devices/.../block/sda/sda1
+	if _, err := os.Stat(filepath.Join(dir, "partition")); err == nil {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			device2disks(sysblock, filepath.Base(filepath.Dir(resolved)), disks, depth+1)
+			return
+		}
+	}
+	slaves, _ := ioutil.ReadDir(filepath.Join(dir, "slaves"))
+	if len(slaves) == 0 {
+		disks[device] = struct{}{}
+		return
+	}
+	for _, slave := range slaves {
+		device2disks(sysblock, slave.Name(), disks, depth+1)
+	}
+}
+
 func childMatches(devList []BlockDevice, device string) bool {
 	for _, dev := range devList {
 		if dev.Name == device {
@@ -87,32 +145,3 @@ func lsblkOutput2disks(lsblkOutputBytes []byte, fs string) (disks cmn.StringSet)
 
 	return disks
 }
-
-func maxUtilDisks(disksMetricsMap map[string]cmn.SimpleKVs, disks cmn.StringSet) (maxutil float64) {
-	maxutil = -1
-	util := func(disk string) (u float64) {
-		if ioMetrics, ok := disksMetricsMap[disk]; ok {
-			if utilStr, ok := ioMetrics["%util"]; ok {
-				var err error
-				if u, err = strconv.ParseFloat(utilStr, 32); err == nil {
-					return
-				}
-			}
-		}
-		return
-	}
-	if len(disks) > 0 {
-		for disk := range disks {
-			if u := util(disk); u > maxutil {
-				maxutil = u
-			}
-		}
-		return
-	}
-	for disk := range disksMetricsMap {
-		if u := util(disk); u > maxutil {
-			maxutil = u
-		}
-	}
-	return
-}
@@ -7,8 +7,10 @@ package ios
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -18,10 +20,6 @@ import (
 	"github.com/json-iterator/go"
 )
 
-func init() {
-	fs.Mountpaths = fs.NewMountedFS("local", "cloud")
-}
-
 func TestGetFSDiskUtil(t *testing.T) {
 	if err := CheckIostatVersion(); err != nil {
 		t.Skip("iostat version is not okay.")
@@ -169,6 +167,27 @@ func TestGetMaxUtil(t *testing.T) {
 	}
 }
 
+func TestMaxUtilFSAvg(t *testing.T) {
+	riostat := NewIostatRunner(fs.Mountpaths)
+	riostat.fsdisks = map[string]cmn.StringSet{"fs1": {"disk1": {}, "disk2": {}}}
+	if util, ok := riostat.MaxUtilFSAvg("fs1"); ok {
+		t.Errorf("Expected no utilization before the first report, got %f", util)
+	}
+
+	// busy disk1 idles for a moment, disk2 stays at 10%
+	for _, util := range []string{"90", "90", "90", "0"} {
+		riostat.updateUtilAvg("disk1", cmn.SimpleKVs{"%util": util})
+		riostat.updateUtilAvg("disk2", cmn.SimpleKVs{"%util": "10"})
+	}
+	util, ok := riostat.MaxUtilFSAvg("fs1")
+	if !ok || math.Abs(72-float64(util)) > 0.0001 {
+		t.Errorf("Expected: 72. Actual: %f", util)
+	}
+	if _, ok := riostat.MaxUtilFSAvg("fs2"); ok {
+		t.Error("Expected no utilization of unknown filesystem")
+	}
+}
+
 func TestGetFSDiskUtilizationInvalid(t *testing.T) {
 	tempRoot := "/tmp"
 	fs.Mountpaths.Add(tempRoot)
@@ -376,8 +395,56 @@ func TestLsblk(t *testing.T) {
 	}
 }
 
-func testConfig(d time.Duration) *cmn.Config {
-	config := cmn.Config{}
-	config.Periodic.StatsTime = d
-	return &config
+func TestSysfsStacks(t *testing.T) {
+	root, err := ioutil.TempDir("", "sysfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	sysblock := filepath.Join(root, "class", "block")
+	// devices/<disk>/<partition> as in /sys/devices/.../block; /sys/class/block links to all
+	for dev, entries := range map[string][]string{
+		"sda": nil, "sda/sda1": {"partition"}, "sdb": nil, "sdb/sdb1": {"partition"},
+		"nvme0n1": nil, "nvme0n1/nvme0n1p1": {"partition"},
+		"md0":  {"slaves/sda1", "slaves/sdb1"}, // RAID-1 of two partitions
+		"dm-0": {"slaves/md0"},                 // LV on the RAID
+		"dm-1": {"slaves/dm-2"},                // dm-crypt on an LV on the NVMe partition
+		"dm-2": {"slaves/nvme0n1p1"},
+	} {
+		dir := filepath.Join(root, "devices", dev)
+		if err := os.MkdirAll(filepath.Join(dir, "slaves"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if err := ioutil.WriteFile(filepath.Join(dir, entry), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		os.MkdirAll(sysblock, 0755)
+		if err := os.Symlink(dir, filepath.Join(sysblock, filepath.Base(dev))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for fs, expected := range map[string][]string{
+		"/dev/sda":       {"sda"},
+		"/dev/sdb1":      {"sdb"},
+		"/dev/nvme0n1p1": {"nvme0n1"},
+		"/dev/md0":       {"sda", "sdb"},
+		"/dev/dm-0":      {"sda", "sdb"},
+		"/dev/dm-1":      {"nvme0n1"},
+		"/dev/sdc":       {},
+		"tmpfs":          {},
+	} {
+		disks := sysfs2disks(sysblock, fs)
+		if len(disks) != len(expected) {
+			t.Errorf("%s: expected %v, got %v", fs, expected, disks)
+			continue
+		}
+		for _, disk := range expected {
+			if _, ok := disks[disk]; !ok {
+				t.Errorf("%s: expected %v, got %v", fs, expected, disks)
+			}
+		}
+	}
 }
@@ -0,0 +1,46 @@
+// +build darwin freebsd
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+)
+
+func GetFSStats(path string) (blocks uint64, bavail uint64, bsize int64, err error) {
+	fsStats := syscall.Statfs_t{}
+	if err = syscall.Statfs(path, &fsStats); err != nil {
+		glog.Errorf("Failed to statfs %q, err: %v", path, err)
+		return
+	}
+	// darwin: Bsize uint32; freebsd: Bavail int64 (negative when over the reserve)
+	if fsStats.Bavail > 0 {
+		bavail = uint64(fsStats.Bavail)
+	}
+	return fsStats.Blocks, bavail, int64(fsStats.Bsize), nil
+}
+
+func GetFSUsedPercentage(path string) (usedPercentage uint64, ok bool) {
+	totalBlocks, blocksAvailable, _, err := GetFSStats(path)
+	if err != nil {
+		return
+	}
+	usedBlocks := totalBlocks - blocksAvailable
+	return usedBlocks * 100 / totalBlocks, true
+}
+
+func GetAmTimes(osfi os.FileInfo) (time.Time, time.Time, *syscall.Stat_t) {
+	stat := osfi.Sys().(*syscall.Stat_t)
+	atime := time.Unix(stat.Atimespec.Sec, stat.Atimespec.Nsec)
+	// NOTE: see https://en.wikipedia.org/wiki/Stat_(system_call)#Criticism_of_atime
+	mtime := osfi.ModTime()
+	return atime, mtime, stat
+}
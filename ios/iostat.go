@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+// utilAvgAlpha is the weight of the latest %util report in the disk's average utilization:
+// the reports older than ~10 iostat periods barely count
+const utilAvgAlpha = 0.2
+
+type IostatRunner struct {
+	sync.RWMutex
+	cmn.NamedConfigured
+	// public
+	Disk       map[string]cmn.SimpleKVs
+	Filesystem map[string]FSMetrics // aggregated per mounted filesystem (see iostat_fs)
+	CPUidle    string
+	// private
+	mountpaths  *fs.MountedFS
+	stopCh      chan struct{}
+	metricnames []string
+	process     *os.Process // running iostat process. Required so it can be killed later
+	fsdisks     map[string]cmn.StringSet
+	period      time.Duration        // iostat refresh period
+	history     map[string]*diskRing // disk => recent reports (see iostat_history)
+	utilAvg     map[string]float64   // disk => exponentially weighted average %util
+	cgroup      *cgroupCollector     // containerized only (see cgroup_linux)
+	qos         map[string]*qosFS    // filesystem => foreground load and throttling (see qos)
+}
+
+func NewIostatRunner(mountpaths *fs.MountedFS) *IostatRunner {
+	return &IostatRunner{
+		mountpaths:  mountpaths,
+		stopCh:      make(chan struct{}, 1),
+		Disk:        make(map[string]cmn.SimpleKVs),
+		metricnames: make([]string, 0),
+		utilAvg:     make(map[string]float64),
+		qos:         make(map[string]*qosFS),
+	}
+}
+
+// as an fsprunner
+var _ fs.PathRunner = &IostatRunner{}
+
+func (r *IostatRunner) ReqEnableMountpath(mpath string)  { r.updateFSDisks() }
+func (r *IostatRunner) ReqDisableMountpath(mpath string) { r.updateFSDisks() }
+func (r *IostatRunner) ReqAddMountpath(mpath string)     { r.updateFSDisks() }
+func (r *IostatRunner) ReqRemoveMountpath(mpath string)  { r.updateFSDisks() }
+
+//
+// API
+//
+
+func (r *IostatRunner) Stop(err error) {
+	glog.Infof("Stopping %s, err: %v", r.Getname(), err)
+	r.stopCh <- struct{}{}
+	close(r.stopCh)
+
+	// Kill process if started
+	if r.process != nil {
+		if err := r.process.Kill(); err != nil {
+			glog.Errorf("Failed to kill iostat, err: %v", err)
+		}
+	}
+}
+
+func (r *IostatRunner) MaxUtilFS(fs string) (util float32, ok bool) {
+	r.RLock()
+	disks, isOk := r.fsdisks[fs]
+	if !isOk {
+		r.RUnlock()
+		return
+	}
+	util = float32(maxUtilDisks(r.Disk, disks))
+	r.RUnlock()
+	if util < 0 {
+		return
+	}
+	return util, true
+}
+
+// MaxUtilFSAvg is MaxUtilFS based on the disks' average utilization over the recent
+// reports rather than on the latest one - to tell an idle disk from a momentary lull;
+// not ok until at least one of the filesystem's disks has been reported
+func (r *IostatRunner) MaxUtilFSAvg(fs string) (util float32, ok bool) {
+	r.RLock()
+	defer r.RUnlock()
+	for disk := range r.fsdisks[fs] {
+		avg, isOk := r.utilAvg[disk]
+		if !isOk {
+			continue
+		}
+		ok = true
+		if u := float32(avg); u > util {
+			util = u
+		}
+	}
+	return
+}
+
+func (r *IostatRunner) IsZeroUtil(dev string) bool {
+	r.RLock()
+	defer r.RUnlock()
+	iometrics := r.Disk[dev]
+	if utilstr, ok := iometrics["%util"]; ok {
+		if util, err := strconv.ParseFloat(utilstr, 32); err == nil {
+			if util == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Snapshot returns the latest report: the CPU idle time and the metrics of each disk
+func (r *IostatRunner) Snapshot() (cpuidle string, disks map[string]cmn.SimpleKVs) {
+	r.RLock()
+	disks = make(map[string]cmn.SimpleKVs, len(r.Disk))
+	for dev, iometrics := range r.Disk {
+		metrics := make(cmn.SimpleKVs, len(iometrics))
+		for name, value := range iometrics {
+			metrics[name] = value
+		}
+		disks[dev] = metrics
+	}
+	cpuidle = r.CPUidle
+	r.RUnlock()
+	return
+}
+
+//
+// private
+//
+
+// update records the device's latest report; must be called under lock
+func (r *IostatRunner) update(device string, iometrics cmn.SimpleKVs, now time.Time) {
+	if r.cgroup != nil {
+		r.cgroup.adjust(device, iometrics)
+	}
+	r.Disk[device] = iometrics
+	r.addSample(device, iometrics, now)
+	r.updateUtilAvg(device, iometrics)
+}
+
+// must be called under lock
+func (r *IostatRunner) updateUtilAvg(device string, iometrics cmn.SimpleKVs) {
+	util, err := strconv.ParseFloat(iometrics["%util"], 64)
+	if err != nil {
+		return
+	}
+	if avg, ok := r.utilAvg[device]; ok {
+		util = utilAvgAlpha*util + (1-utilAvgAlpha)*avg
+	}
+	r.utilAvg[device] = util
+}
+
+func (r *IostatRunner) updateFSDisks() {
+	availablePaths, _ := fs.Mountpaths.Get()
+	r.Lock()
+	r.fsdisks = make(map[string]cmn.StringSet, len(availablePaths))
+	qos := make(map[string]*qosFS, len(availablePaths))
+	for _, mpathInfo := range availablePaths {
+		disks := fs2disks(mpathInfo.FileSystem)
+		if len(disks) == 0 {
+			glog.Errorf("filesystem (%+v) - no disks?", mpathInfo)
+			continue
+		}
+		r.fsdisks[mpathInfo.FileSystem] = disks
+		if qos[mpathInfo.FileSystem] = r.qos[mpathInfo.FileSystem]; qos[mpathInfo.FileSystem] == nil {
+			qos[mpathInfo.FileSystem] = &qosFS{}
+		}
+	}
+	r.qos = qos
+	r.Unlock()
+}
+
+func (r *IostatRunner) diskUtilFromFQN(fqn string) (util float32, ok bool) {
+	mpathInfo, _ := r.mountpaths.Path2MpathInfo(fqn)
+	if mpathInfo == nil {
+		return
+	}
+	return r.MaxUtilFS(mpathInfo.FileSystem)
+}
+
+func maxUtilDisks(disksMetricsMap map[string]cmn.SimpleKVs, disks cmn.StringSet) (maxutil float64) {
+	maxutil = -1
+	util := func(disk string) (u float64) {
+		if ioMetrics, ok := disksMetricsMap[disk]; ok {
+			if utilStr, ok := ioMetrics["%util"]; ok {
+				var err error
+				if u, err = strconv.ParseFloat(utilStr, 32); err == nil {
+					return
+				}
+			}
+		}
+		return
+	}
+	if len(disks) > 0 {
+		for disk := range disks {
+			if u := util(disk); u > maxutil {
+				maxutil = u
+			}
+		}
+		return
+	}
+	for disk := range disksMetricsMap {
+		if u := util(disk); u > maxutil {
+			maxutil = u
+		}
+	}
+	return
+}
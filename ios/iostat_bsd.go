@@ -0,0 +1,114 @@
+// +build darwin freebsd
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// ================================ Summary ===============================================
+//
+// macOS and FreeBSD. There is no /proc/diskstats: the runner always runs the system's
+// iostat (periodic.iostat_external is implied) and translates its output into the metric
+// names of the Linux extended statistics wherever the two have the same meaning - see
+// iostat_darwin and iostat_freebsd. The first report (the averages since boot) is skipped.
+//
+// Degradation: macOS iostat reports neither %util nor reads and writes separately, and
+// therefore the filesystems' utilization is always 0 and their QoS load is unknown - the
+// background xactions are never throttled. FreeBSD reports both (%b is %util). Filesystems
+// that are not backed by a disk (e.g., ZFS datasets) have no disks either way.
+//
+// ================================ Summary ===============================================
+
+// iostatParser translates the platform's iostat output, one line at a time
+type iostatParser interface {
+	// parse returns the disks' metrics reported on the line, if any, and true once
+	// a report is complete
+	parse(fields []string) (disks map[string]cmn.SimpleKVs, done bool)
+}
+
+// cgroupCollector is Linux only (see cgroup_linux)
+type cgroupCollector struct{}
+
+func (c *cgroupCollector) adjust(device string, iometrics cmn.SimpleKVs) {}
+
+//
+// API
+//
+
+func (r *IostatRunner) Run() error {
+	r.updateFSDisks()
+	r.period = r.Getconf().Periodic.StatsTime.Truncate(time.Second)
+	refreshPeriod := int(r.period / time.Second)
+	cmd := iostatCommand(refreshPeriod)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	r.process = cmd.Process
+
+	glog.Infof("Starting %s (iostat)", r.Getname())
+
+	var (
+		reader = bufio.NewReader(stdout)
+		parser = newIostatParser()
+	)
+	for {
+		b, err := reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		disks, done := parser.parse(strings.Fields(string(b)))
+		if len(disks) > 0 || done {
+			now := time.Now()
+			r.Lock()
+			for device, iometrics := range disks {
+				r.update(device, iometrics, now)
+			}
+			if done {
+				r.updateFilesystems()
+				r.updateQoS()
+			}
+			r.Unlock()
+		}
+		select {
+		case <-r.stopCh:
+			return nil
+		default:
+		}
+	}
+}
+
+// CheckIostatVersion determines whether iostat is present; the BSD iostat has no version
+func CheckIostatVersion() error {
+	if _, err := exec.LookPath("iostat"); err != nil {
+		return fmt.Errorf("[iostat] Error: %v", err)
+	}
+	return nil
+}
+
+//
+// private
+//
+
+// isNumber returns true if the iostat field is a value rather than a name
+func isNumber(field string) bool {
+	_, err := strconv.ParseFloat(field, 64)
+	return err == nil
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"os/exec"
+	"strconv"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// iostatMaxDisks is the max number of disks that macOS iostat reports (-n)
+const iostatMaxDisks = 64
+
+// iostat -d -n 64 -w 10:
+//
+//	           disk0               disk2
+//	 KB/t  tps  MB/s     KB/t  tps  MB/s
+//	19.43   10  0.19     0.00    0  0.00
+//
+// The header is repeated every screenful of reports.
+func iostatCommand(period int) *exec.Cmd {
+	return exec.Command("iostat", "-d", "-n", strconv.Itoa(iostatMaxDisks), "-w", strconv.Itoa(period))
+}
+
+type darwinParser struct {
+	disks       []string // as per the latest header
+	metricnames []string // per disk
+	reports     int
+}
+
+func newIostatParser() iostatParser { return &darwinParser{} }
+
+// each line of values is a report
+func (p *darwinParser) parse(fields []string) (disks map[string]cmn.SimpleKVs, done bool) {
+	if len(fields) == 0 {
+		return
+	}
+	if !isNumber(fields[0]) {
+		if len(p.disks) > 0 && len(fields)%len(p.disks) == 0 {
+			p.metricnames = append(p.metricnames[:0], fields[:len(fields)/len(p.disks)]...)
+		} else {
+			p.disks = append(p.disks[:0], fields...)
+			p.metricnames = p.metricnames[:0]
+		}
+		return
+	}
+	p.reports++
+	if p.reports == 1 || len(p.metricnames) == 0 || len(fields) != len(p.disks)*len(p.metricnames) {
+		return // since boot or unexpected
+	}
+	disks = make(map[string]cmn.SimpleKVs, len(p.disks))
+	for i, disk := range p.disks {
+		iometrics := make(cmn.SimpleKVs, len(p.metricnames))
+		for j, name := range p.metricnames {
+			iometrics[name] = fields[i*len(p.metricnames)+j]
+		}
+		disks[disk] = iometrics
+	}
+	return disks, true
+}
@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDarwinParser(t *testing.T) {
+	var (
+		p      = newIostatParser()
+		output = []string{
+			"              disk0               disk2",
+			"    KB/t  tps  MB/s     KB/t  tps  MB/s",
+			"   19.43   10  0.19     0.00    0  0.00", // since boot
+			"   12.00    8  0.09     4.00    2  0.01",
+			"              disk0               disk2",
+			"    KB/t  tps  MB/s     KB/t  tps  MB/s",
+			"    0.00    0  0.00    16.00   64  1.00",
+		}
+		reports []string
+	)
+	for _, line := range output {
+		disks, done := p.parse(strings.Fields(line))
+		if done {
+			reports = append(reports, disks["disk0"]["tps"]+" "+disks["disk2"]["MB/s"])
+		} else if len(disks) > 0 {
+			t.Errorf("%q: disks reported prior to the report is done", line)
+		}
+	}
+	if len(reports) != 2 || reports[0] != "8 0.01" || reports[1] != "0 1.00" {
+		t.Errorf("unexpected reports %v", reports)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"os/exec"
+	"strconv"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// iostat -x -d -w 10:
+//
+//	                        extended device statistics
+//	device       r/s     w/s     kr/s     kw/s  ms/r  ms/w  ms/o  ms/t qlen  %b
+//	ada0           0       1      0.0      8.0     0     1     0     1    0   0
+//
+// (prior to FreeBSD 12: device r/s w/s kr/s kw/s qlen svc_t %b)
+func iostatCommand(period int) *exec.Cmd {
+	return exec.Command("iostat", "-x", "-d", "-w", strconv.Itoa(period))
+}
+
+// freebsdMetrics maps the devstat names onto the Linux extended statistics; the kr/s and
+// kw/s are converted to MB/s
+var freebsdMetrics = map[string]string{
+	"kr/s":  "rMB/s",
+	"kw/s":  "wMB/s",
+	"ms/r":  "r_await",
+	"ms/w":  "w_await",
+	"svc_t": "svctm",
+	"qlen":  "avgqu-sz",
+	"%b":    "%util",
+}
+
+type freebsdParser struct {
+	metricnames []string
+	reports     int
+}
+
+func newIostatParser() iostatParser { return &freebsdParser{} }
+
+// a report is a header followed by a line per disk; the next header completes it
+func (p *freebsdParser) parse(fields []string) (disks map[string]cmn.SimpleKVs, done bool) {
+	switch {
+	case len(fields) == 0:
+	case fields[0] == "extended":
+		p.reports++
+		done = p.reports > 2
+	case fields[0] == "device":
+		p.metricnames = append(p.metricnames[:0], fields[1:]...)
+	case p.reports > 1 && len(fields) == len(p.metricnames)+1:
+		iometrics := make(cmn.SimpleKVs, len(p.metricnames))
+		for i, name := range p.metricnames {
+			value := fields[i+1]
+			if linuxname, ok := freebsdMetrics[name]; ok {
+				if name == "kr/s" || name == "kw/s" {
+					if kbps, err := strconv.ParseFloat(value, 64); err == nil {
+						value = strconv.FormatFloat(kbps/1024, 'f', 2, 64)
+					}
+				}
+				name = linuxname
+			}
+			iometrics[name] = value
+		}
+		disks = map[string]cmn.SimpleKVs{fields[0]: iometrics}
+	}
+	return
+}
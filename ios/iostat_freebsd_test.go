@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestFreeBSDParser(t *testing.T) {
+	var (
+		p      = newIostatParser()
+		output = []string{
+			"                        extended device statistics",
+			"device       r/s     w/s     kr/s     kw/s  ms/r  ms/w  ms/o  ms/t qlen  %b",
+			"ada0           5       1    512.0      8.0     0     1     0     1    0   3", // since boot
+			"                        extended device statistics",
+			"device       r/s     w/s     kr/s     kw/s  ms/r  ms/w  ms/o  ms/t qlen  %b",
+			"ada0         100      20   2048.0   1024.0     2     4     0     3    1  57",
+			"da0            0       0      0.0      0.0     0     0     0     0    0   0",
+			"                        extended device statistics",
+		}
+		latest = make(map[string]cmn.SimpleKVs)
+		done   int
+	)
+	for _, line := range output {
+		disks, d := p.parse(strings.Fields(line))
+		for disk, iometrics := range disks {
+			latest[disk] = iometrics
+		}
+		if d {
+			done++
+		}
+	}
+	if done != 1 || len(latest) != 2 {
+		t.Fatalf("expected 1 report of 2 disks, got %d report(s): %v", done, latest)
+	}
+	ada0 := latest["ada0"]
+	for name, expected := range map[string]string{"r/s": "100", "rMB/s": "2.00", "wMB/s": "1.00",
+		"r_await": "2", "w_await": "4", "avgqu-sz": "1", "%util": "57"} {
+		if ada0[name] != expected {
+			t.Errorf("ada0 %s: expected %s, got %q", name, expected, ada0[name])
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"sort"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+// ================================ Summary ===============================================
+//
+// Per-filesystem metrics. The iostat reports are per device, while the mountpaths (and
+// hence LRU, atime flushing, and the rest) are per filesystem - possibly spanning several
+// disks. Upon each report the runner aggregates the metrics of each mounted filesystem's
+// disks: the maximum and the average %util and await (ms; for the reports that have only
+// r_await and w_await - their average weighted by r/s and w/s), and the total throughput
+// and IOPS. The target's stats runner logs them and sends them to StatsD along with the
+// disks' (see stats.Trunner).
+//
+// ================================ Summary ===============================================
+
+// FSMetrics are a filesystem's metrics as of the latest report
+type FSMetrics struct {
+	Mountpaths []string `json:"mountpaths"`
+	Disks      int      `json:"disks"`
+	UtilMax    float64  `json:"util.max"`
+	UtilAvg    float64  `json:"util.avg"`
+	AwaitMax   float64  `json:"await.max"`
+	AwaitAvg   float64  `json:"await.avg"`
+	ReadMBps   float64  `json:"rMB/s"`
+	WriteMBps  float64  `json:"wMB/s"`
+	IOPS       float64  `json:"iops"`
+}
+
+// Filesystems returns the metrics of each mounted filesystem as of the latest report
+func (r *IostatRunner) Filesystems() map[string]FSMetrics {
+	r.RLock()
+	out := make(map[string]FSMetrics, len(r.Filesystem))
+	for fs, m := range r.Filesystem {
+		out[fs] = m
+	}
+	r.RUnlock()
+	return out
+}
+
+// MountpathMetrics returns the metrics of the mountpath's filesystem
+func (r *IostatRunner) MountpathMetrics(mpath string) (m FSMetrics, ok bool) {
+	availablePaths, _ := fs.Mountpaths.Get()
+	mpathInfo, found := availablePaths[mpath]
+	if !found {
+		return
+	}
+	r.RLock()
+	m, ok = r.Filesystem[mpathInfo.FileSystem]
+	r.RUnlock()
+	return
+}
+
+// updateFilesystems aggregates the latest report per filesystem; must be called under lock
+func (r *IostatRunner) updateFilesystems() {
+	availablePaths, _ := fs.Mountpaths.Get()
+	filesystems := make(map[string]FSMetrics, len(r.fsdisks))
+	for fs, disks := range r.fsdisks {
+		filesystems[fs] = aggregateDisks(r.Disk, disks)
+	}
+	for mpath, mpathInfo := range availablePaths {
+		if m, ok := filesystems[mpathInfo.FileSystem]; ok {
+			m.Mountpaths = append(m.Mountpaths, mpath)
+			filesystems[mpathInfo.FileSystem] = m
+		}
+	}
+	for fs, m := range filesystems {
+		sort.Strings(m.Mountpaths)
+		filesystems[fs] = m
+	}
+	r.Filesystem = filesystems
+}
+
+func aggregateDisks(diskMetrics map[string]cmn.SimpleKVs, disks cmn.StringSet) (m FSMetrics) {
+	var utilSum, awaitSum float64
+	for disk := range disks {
+		iometrics, ok := diskMetrics[disk]
+		if !ok {
+			continue
+		}
+		m.Disks++
+		util, await := metricValue(iometrics, "%util"), diskAwait(iometrics)
+		utilSum += util
+		awaitSum += await
+		if util > m.UtilMax {
+			m.UtilMax = util
+		}
+		if await > m.AwaitMax {
+			m.AwaitMax = await
+		}
+		m.ReadMBps += metricValue(iometrics, "rMB/s")
+		m.WriteMBps += metricValue(iometrics, "wMB/s")
+		m.IOPS += metricValue(iometrics, "r/s") + metricValue(iometrics, "w/s")
+	}
+	if m.Disks > 0 {
+		m.UtilAvg = utilSum / float64(m.Disks)
+		m.AwaitAvg = awaitSum / float64(m.Disks)
+	}
+	return
+}
+
+func diskAwait(iometrics cmn.SimpleKVs) float64 {
+	if _, ok := iometrics["await"]; ok {
+		return metricValue(iometrics, "await")
+	}
+	reads, writes := metricValue(iometrics, "r/s"), metricValue(iometrics, "w/s")
+	if reads+writes == 0 {
+		return 0
+	}
+	return (metricValue(iometrics, "r_await")*reads + metricValue(iometrics, "w_await")*writes) / (reads + writes)
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func TestFilesystemMetrics(t *testing.T) {
+	riostat := NewIostatRunner(fs.Mountpaths)
+	riostat.Setconf(testConfig(time.Second))
+	riostat.fsdisks = map[string]cmn.StringSet{
+		"/dev/md0": {"sda": struct{}{}, "sdb": struct{}{}},
+		"/dev/sdc": {"sdc": struct{}{}},
+	}
+	riostat.Disk = map[string]cmn.SimpleKVs{
+		"sda": {"%util": "80.00", "await": "4.00", "r/s": "100.00", "w/s": "50.00", "rMB/s": "10.00", "wMB/s": "5.00"},
+		"sdb": {"%util": "20.00", "await": "2.00", "r/s": "10.00", "w/s": "0.00", "rMB/s": "1.00", "wMB/s": "0.00"},
+		// no await: r_await and w_await weighted by r/s and w/s
+		"sdc": {"%util": "10.00", "r_await": "1.00", "w_await": "4.00", "r/s": "20.00", "w/s": "10.00"},
+	}
+	riostat.updateFilesystems()
+
+	md0, ok := riostat.Filesystems()["/dev/md0"]
+	if !ok || md0.Disks != 2 || md0.UtilMax != 80 || md0.UtilAvg != 50 || md0.AwaitMax != 4 || md0.AwaitAvg != 3 {
+		t.Errorf("unexpected md0 util/await %+v", md0)
+	}
+	if md0.ReadMBps != 11 || md0.WriteMBps != 5 || md0.IOPS != 160 {
+		t.Errorf("unexpected md0 throughput %+v", md0)
+	}
+	if sdc := riostat.Filesystems()["/dev/sdc"]; sdc.AwaitMax != 2 || sdc.IOPS != 30 {
+		t.Errorf("expected sdc await 2ms at 30 IOPS, got %+v", sdc)
+	}
+	if _, ok := riostat.MountpathMetrics("/no/such/mountpath"); ok {
+		t.Error("expected no metrics for an unknown mountpath")
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// diskRing keeps the last len(samples) iostat reports of a given disk
+type diskRing struct {
+	samples []cmn.DiskSample
+	next    int // index of the next (and, when full, the oldest) sample
+	count   int
+}
+
+func newDiskRing(size int) *diskRing {
+	return &diskRing{samples: make([]cmn.DiskSample, size)}
+}
+
+func (dr *diskRing) add(sample cmn.DiskSample) {
+	dr.samples[dr.next] = sample
+	dr.next = (dr.next + 1) % len(dr.samples)
+	if dr.count < len(dr.samples) {
+		dr.count++
+	}
+}
+
+// resize keeps the most recent samples that fit
+func (dr *diskRing) resize(size int) {
+	samples := dr.get(time.Time{})
+	if len(samples) > size {
+		samples = samples[len(samples)-size:]
+	}
+	dr.samples = make([]cmn.DiskSample, size)
+	dr.count = copy(dr.samples, samples)
+	dr.next = dr.count % size
+}
+
+// get returns the samples taken after the specified time, oldest first
+func (dr *diskRing) get(since time.Time) []cmn.DiskSample {
+	samples := make([]cmn.DiskSample, 0, dr.count)
+	start := dr.next - dr.count
+	if start < 0 {
+		start += len(dr.samples)
+	}
+	for i := 0; i < dr.count; i++ {
+		sample := dr.samples[(start+i)%len(dr.samples)]
+		if sample.Time.After(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// historySize returns the number of reports that cover the configured iostat_history
+func historySize(history, period time.Duration) int {
+	if history <= 0 || period <= 0 {
+		return 0
+	}
+	if size := int(history / period); size > 0 {
+		return size
+	}
+	return 1
+}
+
+// must be called under lock
+func (r *IostatRunner) addSample(device string, iometrics cmn.SimpleKVs, now time.Time) {
+	size := historySize(r.Getconf().Periodic.IostatHistory, r.period)
+	if size == 0 {
+		r.history = nil
+		return
+	}
+	if r.history == nil {
+		r.history = make(map[string]*diskRing, len(r.Disk))
+	}
+	ring, ok := r.history[device]
+	if !ok {
+		ring = newDiskRing(size)
+		r.history[device] = ring
+	} else if len(ring.samples) != size {
+		ring.resize(size)
+	}
+	metrics := make(cmn.SimpleKVs, len(iometrics))
+	for name, value := range iometrics {
+		metrics[name] = value
+	}
+	ring.add(cmn.DiskSample{Time: now, Metrics: metrics})
+}
+
+// History returns the recent iostat reports of the specified disk (all disks if empty)
+// taken within the specified duration (all reports if zero)
+func (r *IostatRunner) History(disk string, within time.Duration) cmn.DiskHistory {
+	var since time.Time
+	if within > 0 {
+		since = time.Now().Add(-within)
+	}
+	hist := make(cmn.DiskHistory)
+	r.RLock()
+	for device, ring := range r.history {
+		if disk == "" || disk == device {
+			hist[device] = ring.get(since)
+		}
+	}
+	r.RUnlock()
+	return hist
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func TestDiskRing(t *testing.T) {
+	var (
+		ring  = newDiskRing(3)
+		start = time.Now()
+	)
+	check := func(since time.Time, expected ...int) {
+		samples := ring.get(since)
+		if len(samples) != len(expected) {
+			t.Fatalf("Expected %d samples, got %d: %+v", len(expected), len(samples), samples)
+		}
+		for i, sample := range samples {
+			if sample.Metrics["n"] != strconv.Itoa(expected[i]) {
+				t.Errorf("Expected sample #%d to be %d, got %+v", i, expected[i], sample)
+			}
+		}
+	}
+	add := func(n int) {
+		ring.add(cmn.DiskSample{Time: start.Add(time.Duration(n) * time.Second), Metrics: cmn.SimpleKVs{"n": strconv.Itoa(n)}})
+	}
+
+	check(time.Time{})
+	add(1)
+	add(2)
+	check(time.Time{}, 1, 2)
+	add(3)
+	add(4)
+	add(5)
+	check(time.Time{}, 3, 4, 5)
+	check(start.Add(3*time.Second), 4, 5)
+
+	ring.resize(5)
+	check(time.Time{}, 3, 4, 5)
+	add(6)
+	add(7)
+	add(8)
+	check(time.Time{}, 4, 5, 6, 7, 8)
+
+	ring.resize(2)
+	check(time.Time{}, 7, 8)
+	add(9)
+	check(time.Time{}, 8, 9)
+}
+
+func TestIostatHistory(t *testing.T) {
+	config := testConfig(10 * time.Second)
+	config.Periodic.IostatHistory = time.Minute
+	riostat := NewIostatRunner(fs.Mountpaths)
+	riostat.Setconf(config)
+	riostat.period = config.Periodic.StatsTime
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		iometrics := cmn.SimpleKVs{"%util": strconv.Itoa(i)}
+		riostat.addSample("sda", iometrics, now.Add(time.Duration(i-10)*time.Second))
+		riostat.addSample("sdb", iometrics, now.Add(time.Duration(i-10)*time.Second))
+		iometrics["%util"] = "modified"
+	}
+	hist := riostat.History("", 0)
+	if len(hist) != 2 || len(hist["sda"]) != 6 {
+		t.Fatalf("Expected 2 disks with 6 samples each, got %+v", hist)
+	}
+	if hist["sda"][0].Metrics["%util"] != "4" || hist["sda"][5].Metrics["%util"] != "9" {
+		t.Errorf("Unexpected samples %+v", hist["sda"])
+	}
+	if hist = riostat.History("sdb", 3*time.Second); len(hist) != 1 || len(hist["sdb"]) != 2 {
+		t.Errorf("Expected 2 samples of sdb, got %+v", hist)
+	}
+
+	// disabled at runtime
+	config.Periodic.IostatHistory = 0
+	riostat.addSample("sda", cmn.SimpleKVs{}, now)
+	if hist = riostat.History("", 0); len(hist) != 0 {
+		t.Errorf("Expected no history, got %+v", hist)
+	}
+}
@@ -8,16 +8,13 @@ package ios
 import (
 	"bufio"
 	"fmt"
-	"os"
 	"os/exec"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/NVIDIA/dfcpub/3rdparty/glog"
 	"github.com/NVIDIA/dfcpub/cmn"
-	"github.com/NVIDIA/dfcpub/fs"
 )
 
 const (
@@ -26,46 +23,57 @@ const (
 	iostatMinVersion = 11
 )
 
-type IostatRunner struct {
-	sync.RWMutex
-	cmn.NamedConfigured
-	// public
-	Disk    map[string]cmn.SimpleKVs
-	CPUidle string
-	// private
-	mountpaths  *fs.MountedFS
-	stopCh      chan struct{}
-	metricnames []string
-	process     *os.Process // running iostat process. Required so it can be killed later
-	fsdisks     map[string]cmn.StringSet
-}
-
-func NewIostatRunner(mountpaths *fs.MountedFS) *IostatRunner {
-	return &IostatRunner{
-		mountpaths:  mountpaths,
-		stopCh:      make(chan struct{}, 1),
-		Disk:        make(map[string]cmn.SimpleKVs),
-		metricnames: make([]string, 0),
-	}
-}
-
-// as an fsprunner
-var _ fs.PathRunner = &IostatRunner{}
-
-func (r *IostatRunner) ReqEnableMountpath(mpath string)  { r.updateFSDisks() }
-func (r *IostatRunner) ReqDisableMountpath(mpath string) { r.updateFSDisks() }
-func (r *IostatRunner) ReqAddMountpath(mpath string)     { r.updateFSDisks() }
-func (r *IostatRunner) ReqRemoveMountpath(mpath string)  { r.updateFSDisks() }
-
 //
 // API
 //
 
-// iostat -cdxtm 10
 func (r *IostatRunner) Run() error {
 	r.updateFSDisks()
-	period := r.Getconf().Periodic.StatsTime
-	refreshPeriod := int(period / time.Second)
+	r.period = r.Getconf().Periodic.StatsTime.Truncate(time.Second)
+	r.cgroup = newCgroupCollector()
+	if r.Getconf().Periodic.IostatExternal {
+		return r.runIostat()
+	}
+	glog.Infof("Starting %s (%s)", r.Getname(), procDiskstats)
+	return r.runDiskstats()
+}
+
+// runDiskstats computes the extended statistics from /proc - see diskstats_linux
+func (r *IostatRunner) runDiskstats() error {
+	c := newDiskstatsCollector()
+	if _, _, err := c.sample(time.Now()); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			disks, cpuidle, err := c.sample(now)
+			if err != nil {
+				glog.Errorf("Failed to read disk stats, err: %v", err)
+				continue
+			}
+			r.Lock()
+			r.CPUidle = cpuidle
+			if r.cgroup != nil {
+				r.cgroup.sample(now)
+			}
+			for device, iometrics := range disks {
+				r.update(device, iometrics, now)
+			}
+			r.updateFilesystems()
+			r.updateQoS()
+			r.Unlock()
+		case <-r.stopCh:
+			return nil
+		}
+	}
+}
+
+// iostat -cdxtm 10
+func (r *IostatRunner) runIostat() error {
+	refreshPeriod := int(r.period / time.Second)
 	cmd := exec.Command("iostat", "-cdxtm", strconv.Itoa(refreshPeriod))
 	stdout, err := cmd.StdoutPipe()
 	reader := bufio.NewReader(stdout)
@@ -79,7 +87,7 @@ func (r *IostatRunner) Run() error {
 	// Assigning started process
 	r.process = cmd.Process
 
-	glog.Infof("Starting %s", r.Getname())
+	glog.Infof("Starting %s (iostat)", r.Getname())
 
 	for {
 		b, err := reader.ReadBytes('\n')
@@ -92,6 +100,8 @@ func (r *IostatRunner) Run() error {
 		if len(fields) == iostatnumsys {
 			r.Lock()
 			r.CPUidle = fields[iostatnumsys-1]
+			r.updateFilesystems() // the previous report is complete
+			r.updateQoS()
 			r.Unlock()
 		} else if len(fields) >= iostatnumdsk {
 			if strings.HasPrefix(fields[0], "Device") {
@@ -112,7 +122,11 @@ func (r *IostatRunner) Run() error {
 					name := r.metricnames[i-1]
 					iometrics[name] = fields[i]
 				}
-				r.Disk[device] = iometrics
+				now := time.Now()
+				if r.cgroup != nil && now.Sub(r.cgroup.sampled) >= r.period/2 { // once per report
+					r.cgroup.sample(now)
+				}
+				r.update(device, iometrics, now)
 				r.Unlock()
 			}
 		}
@@ -124,35 +138,7 @@ func (r *IostatRunner) Run() error {
 	}
 }
 
-func (r *IostatRunner) Stop(err error) {
-	glog.Infof("Stopping %s, err: %v", r.Getname(), err)
-	r.stopCh <- struct{}{}
-	close(r.stopCh)
-
-	// Kill process if started
-	if r.process != nil {
-		if err := r.process.Kill(); err != nil {
-			glog.Errorf("Failed to kill iostat, err: %v", err)
-		}
-	}
-}
-
-func (r *IostatRunner) MaxUtilFS(fs string) (util float32, ok bool) {
-	r.RLock()
-	disks, isOk := r.fsdisks[fs]
-	if !isOk {
-		r.RUnlock()
-		return
-	}
-	util = float32(maxUtilDisks(r.Disk, disks))
-	r.RUnlock()
-	if util < 0 {
-		return
-	}
-	return util, true
-}
-
-// CheckIostatVersion determines whether iostat is present and current
+// CheckIostatVersion determines whether iostat is present and current (periodic.iostat_external)
 func CheckIostatVersion() error {
 	cmd := exec.Command("iostat", "-V")
 
@@ -182,42 +168,3 @@ func CheckIostatVersion() error {
 
 	return nil
 }
-
-//
-// private
-//
-
-func (r *IostatRunner) IsZeroUtil(dev string) bool {
-	iometrics := r.Disk[dev]
-	if utilstr, ok := iometrics["%util"]; ok {
-		if util, err := strconv.ParseFloat(utilstr, 32); err == nil {
-			if util == 0 {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-func (r *IostatRunner) updateFSDisks() {
-	availablePaths, _ := fs.Mountpaths.Get()
-	r.Lock()
-	r.fsdisks = make(map[string]cmn.StringSet, len(availablePaths))
-	for _, mpathInfo := range availablePaths {
-		disks := fs2disks(mpathInfo.FileSystem)
-		if len(disks) == 0 {
-			glog.Errorf("filesystem (%+v) - no disks?", mpathInfo)
-			continue
-		}
-		r.fsdisks[mpathInfo.FileSystem] = disks
-	}
-	r.Unlock()
-}
-
-func (r *IostatRunner) diskUtilFromFQN(fqn string) (util float32, ok bool) {
-	mpathInfo, _ := r.mountpaths.Path2MpathInfo(fqn)
-	if mpathInfo == nil {
-		return
-	}
-	return r.MaxUtilFS(mpathInfo.FileSystem)
-}
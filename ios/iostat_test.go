@@ -0,0 +1,23 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func init() {
+	fs.Mountpaths = fs.NewMountedFS("local", "cloud")
+}
+
+func testConfig(d time.Duration) *cmn.Config {
+	config := cmn.Config{}
+	config.Periodic.StatsTime = d
+	return &config
+}
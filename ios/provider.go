@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import "sync"
+
+// DiskUtilProvider reports the utilization of the filesystems' disks and the filesystems'
+// capacity. IostatRunner is the provider of record; StaticDiskUtil reports the values it
+// is given - for unit tests and for the platforms without disk statistics.
+type DiskUtilProvider interface {
+	// MaxUtilFS returns the max %util of the filesystem's disks as of the latest report
+	MaxUtilFS(fs string) (util float32, ok bool)
+	// MaxUtilFSAvg is MaxUtilFS averaged over the recent reports
+	MaxUtilFSAvg(fs string) (util float32, ok bool)
+	// IsZeroUtil returns true if the disk's %util is known to be zero
+	IsZeroUtil(dev string) bool
+	// GetFSStats returns the capacity of the filesystem of the given path, as per statfs(2)
+	GetFSStats(path string) (blocks uint64, bavail uint64, bsize int64, err error)
+}
+
+// StaticDiskUtil is the DiskUtilProvider with the utilization (and, optionally, the
+// capacity) set by the caller
+type StaticDiskUtil struct {
+	mu      sync.RWMutex
+	fsUtil  map[string]float32 // filesystem => util; "" - any filesystem
+	devUtil map[string]float32
+	fsStats *fsStats // nil - statfs(2)
+}
+
+type fsStats struct {
+	blocks, bavail uint64
+	bsize          int64
+}
+
+var (
+	_ DiskUtilProvider = &IostatRunner{}
+	_ DiskUtilProvider = &StaticDiskUtil{}
+)
+
+// FSUsedPercentage is GetFSUsedPercentage as per the provider's GetFSStats
+func FSUsedPercentage(p DiskUtilProvider, path string) (usedPercentage uint64, ok bool) {
+	blocks, bavail, _, err := p.GetFSStats(path)
+	if err != nil || blocks == 0 {
+		return
+	}
+	return (blocks - bavail) * 100 / blocks, true
+}
+
+//
+// IostatRunner
+//
+
+func (r *IostatRunner) GetFSStats(path string) (blocks uint64, bavail uint64, bsize int64, err error) {
+	return GetFSStats(path)
+}
+
+//
+// StaticDiskUtil
+//
+
+func NewStaticDiskUtil() *StaticDiskUtil {
+	return &StaticDiskUtil{fsUtil: make(map[string]float32), devUtil: make(map[string]float32)}
+}
+
+// SetUtil sets the utilization of the filesystem; "" sets it for all filesystems
+func (s *StaticDiskUtil) SetUtil(fs string, util float32) {
+	s.mu.Lock()
+	s.fsUtil[fs] = util
+	s.mu.Unlock()
+}
+
+func (s *StaticDiskUtil) SetDiskUtil(dev string, util float32) {
+	s.mu.Lock()
+	s.devUtil[dev] = util
+	s.mu.Unlock()
+}
+
+// SetFSStats sets the capacity of all filesystems; zero bsize reverts to statfs(2)
+func (s *StaticDiskUtil) SetFSStats(blocks, bavail uint64, bsize int64) {
+	s.mu.Lock()
+	if bsize == 0 {
+		s.fsStats = nil
+	} else {
+		s.fsStats = &fsStats{blocks: blocks, bavail: bavail, bsize: bsize}
+	}
+	s.mu.Unlock()
+}
+
+func (s *StaticDiskUtil) MaxUtilFS(fs string) (util float32, ok bool) {
+	s.mu.RLock()
+	if util, ok = s.fsUtil[fs]; !ok {
+		util, ok = s.fsUtil[""]
+	}
+	s.mu.RUnlock()
+	return
+}
+
+func (s *StaticDiskUtil) MaxUtilFSAvg(fs string) (util float32, ok bool) { return s.MaxUtilFS(fs) }
+
+func (s *StaticDiskUtil) IsZeroUtil(dev string) bool {
+	s.mu.RLock()
+	util, ok := s.devUtil[dev]
+	s.mu.RUnlock()
+	return ok && util == 0
+}
+
+func (s *StaticDiskUtil) GetFSStats(path string) (blocks uint64, bavail uint64, bsize int64, err error) {
+	s.mu.RLock()
+	stats := s.fsStats
+	s.mu.RUnlock()
+	if stats == nil {
+		return GetFSStats(path)
+	}
+	return stats.blocks, stats.bavail, stats.bsize, nil
+}
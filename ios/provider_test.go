@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import "testing"
+
+func TestStaticDiskUtil(t *testing.T) {
+	s := NewStaticDiskUtil()
+	if _, ok := s.MaxUtilFS("/dev/sda1"); ok {
+		t.Error("expected unknown utilization")
+	}
+	s.SetUtil("", 30)
+	s.SetUtil("/dev/sda1", 90)
+	if util, ok := s.MaxUtilFS("/dev/sda1"); !ok || util != 90 {
+		t.Errorf("/dev/sda1: expected 90, got %.0f (%t)", util, ok)
+	}
+	if util, ok := s.MaxUtilFSAvg("/dev/sdb1"); !ok || util != 30 {
+		t.Errorf("/dev/sdb1: expected 30 (any filesystem), got %.0f (%t)", util, ok)
+	}
+
+	s.SetDiskUtil("sda", 0)
+	s.SetDiskUtil("sdb", 1)
+	if !s.IsZeroUtil("sda") || s.IsZeroUtil("sdb") || s.IsZeroUtil("sdc") {
+		t.Error("expected zero utilization of sda only")
+	}
+
+	s.SetFSStats(1000, 250, 4096)
+	if pct, ok := FSUsedPercentage(s, "/"); !ok || pct != 75 {
+		t.Errorf("expected 75%% used, got %d%% (%t)", pct, ok)
+	}
+	s.SetFSStats(0, 0, 0) // statfs(2)
+	if _, ok := FSUsedPercentage(s, "/"); !ok {
+		t.Error("failed to statfs /")
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+// ================================ Summary ===============================================
+//
+// Per-mountpath QoS. With qos.enabled, upon each report the runner adds up the IOPS (r/s
+// plus w/s) and the throughput (rMB/s plus wMB/s) of the disks of each mountpath's
+// filesystem, and takes the number of foreground GETs and PUTs the target has served off
+// the filesystem since the previous report (see AddForeground). A filesystem that has served
+// foreground requests while its disks did more than qos.iops or qos.mbps is over its budget,
+// and until the next report the background xactions that read and write it - LRU, rebalance,
+// and prefetch - are throttled (cluster.Throttle with OnQoS), so that the foreground does not
+// compete with them for the disks.
+//
+// ================================ Summary ===============================================
+
+type (
+	// QoSState is the mountpath's load as of the latest report
+	QoSState struct {
+		IOPS       float64 `json:"iops"`
+		MBps       float64 `json:"mbps"`
+		Foreground int64   `json:"fg.n"` // foreground GETs and PUTs
+		Throttled  bool    `json:"throttled"`
+	}
+	qosFS struct {
+		fg    int64 // foreground GETs and PUTs since the latest report; atomic
+		state QoSState
+	}
+)
+
+// AddForeground accounts for a foreground GET or PUT of the object
+func (r *IostatRunner) AddForeground(fqn string) {
+	if !r.Getconf().QoS.Enabled {
+		return
+	}
+	mpathInfo, _ := r.mountpaths.Path2MpathInfo(fqn)
+	if mpathInfo == nil {
+		return
+	}
+	r.RLock()
+	if q, ok := r.qos[mpathInfo.FileSystem]; ok {
+		atomic.AddInt64(&q.fg, 1)
+	}
+	r.RUnlock()
+}
+
+// QoSThrottled returns true if the background work on the filesystem is to be throttled
+func (r *IostatRunner) QoSThrottled(fs string) bool {
+	r.RLock()
+	q, ok := r.qos[fs]
+	throttled := ok && q.state.Throttled
+	r.RUnlock()
+	return throttled
+}
+
+// QoS returns the state of each mountpath as of the latest report
+func (r *IostatRunner) QoS() map[string]QoSState {
+	availablePaths, _ := fs.Mountpaths.Get()
+	states := make(map[string]QoSState, len(availablePaths))
+	r.RLock()
+	for mpath, mpathInfo := range availablePaths {
+		if q, ok := r.qos[mpathInfo.FileSystem]; ok {
+			states[mpath] = q.state
+		}
+	}
+	r.RUnlock()
+	return states
+}
+
+// updateQoS evaluates the budgets upon each report; must be called under lock
+func (r *IostatRunner) updateQoS() {
+	conf := &r.Getconf().QoS
+	for fs, q := range r.qos {
+		var iops, mbps float64
+		for disk := range r.fsdisks[fs] {
+			iometrics := r.Disk[disk]
+			iops += metricValue(iometrics, "r/s") + metricValue(iometrics, "w/s")
+			mbps += metricValue(iometrics, "rMB/s") + metricValue(iometrics, "wMB/s")
+		}
+		fg := atomic.SwapInt64(&q.fg, 0)
+		throttled := conf.Enabled && fg > 0 &&
+			(conf.IOPS > 0 && iops > float64(conf.IOPS) || conf.MBps > 0 && mbps > float64(conf.MBps))
+		if throttled && !q.state.Throttled {
+			glog.Warningf("%s: %.0f IOPS, %.1f MB/s while serving %d GETs and PUTs - throttling background xactions",
+				fs, iops, mbps, fg)
+		} else if !throttled && q.state.Throttled {
+			glog.Infof("%s: within the budget", fs)
+		}
+		q.state = QoSState{IOPS: iops, MBps: mbps, Foreground: fg, Throttled: throttled}
+	}
+}
+
+func metricValue(iometrics cmn.SimpleKVs, name string) float64 {
+	v, err := strconv.ParseFloat(iometrics[name], 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func TestQoS(t *testing.T) {
+	config := testConfig(time.Second)
+	config.QoS = cmn.QoSConf{Enabled: true, IOPS: 1000, MBps: 100}
+	riostat := NewIostatRunner(fs.Mountpaths)
+	riostat.Setconf(config)
+	riostat.fsdisks = map[string]cmn.StringSet{
+		"/dev/md0": {"sda": struct{}{}, "sdb": struct{}{}},
+		"/dev/sdc": {"sdc": struct{}{}},
+	}
+	riostat.qos = map[string]*qosFS{"/dev/md0": {}, "/dev/sdc": {}}
+	riostat.Disk = map[string]cmn.SimpleKVs{
+		"sda": {"r/s": "300.00", "w/s": "300.00", "rMB/s": "10.00", "wMB/s": "10.00"},
+		"sdb": {"r/s": "300.00", "w/s": "300.00", "rMB/s": "10.00", "wMB/s": "10.00"},
+		"sdc": {"r/s": "10.00", "w/s": "0.00", "rMB/s": "150.00", "wMB/s": "0.00"},
+	}
+
+	// over the IOPS (md0) and MB/s (sdc) budgets, but no foreground
+	riostat.updateQoS()
+	if riostat.QoSThrottled("/dev/md0") || riostat.QoSThrottled("/dev/sdc") {
+		t.Fatal("expected no throttling without foreground requests")
+	}
+	riostat.qos["/dev/md0"].fg = 3
+	riostat.qos["/dev/sdc"].fg = 1
+	riostat.updateQoS()
+	if state := riostat.qos["/dev/md0"].state; !state.Throttled || state.IOPS != 1200 || state.Foreground != 3 {
+		t.Errorf("expected md0 throttled at 1200 IOPS, got %+v", state)
+	}
+	if !riostat.QoSThrottled("/dev/sdc") {
+		t.Error("expected sdc throttled at 150 MB/s")
+	}
+	// foreground counted anew each report
+	riostat.updateQoS()
+	if riostat.QoSThrottled("/dev/md0") || riostat.qos["/dev/md0"].fg != 0 {
+		t.Error("expected md0 unthrottled without new foreground requests")
+	}
+
+	// within the budget
+	config.QoS.IOPS, config.QoS.MBps = 2000, 0
+	riostat.qos["/dev/md0"].fg = 1
+	riostat.qos["/dev/sdc"].fg = 1
+	riostat.updateQoS()
+	if riostat.QoSThrottled("/dev/md0") || riostat.QoSThrottled("/dev/sdc") {
+		t.Error("expected no throttling within the budget")
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ================================ Summary ===============================================
+//
+// Disk health. With smart.enabled, every smart.interval the runner reads the SMART
+// attributes of the disks of all available mountpaths - `smartctl -j -H -A /dev/<disk>`
+// (smartmontools 7 or later) or, where smartctl is not installed, whatever the OS exposes
+// without it (Linux: the temperature in sysfs). A disk that fails its overall self-assessment
+// or reaches any of the configured limits - reallocated sectors, media errors, temperature -
+// is reported to the DiskFailureHandler (FSHC), which disables the disk's mountpaths right
+// away rather than upon the I/O errors that would follow. The latest reports are returned by
+// GET /v1/daemon?what=diskhealth.
+//
+// ================================ Summary ===============================================
+
+// ATA attributes
+const (
+	ataReallocated   = 5
+	ataPending       = 197
+	ataUncorrectable = 198
+)
+
+type (
+	// DiskFailureHandler disables the mountpaths of the failing disks (see health.FSHC)
+	DiskFailureHandler interface {
+		OnDiskFailing(mpath, why string)
+	}
+	SMARTRunner struct {
+		sync.RWMutex
+		cmn.NamedConfigured
+		mountpaths *fs.MountedFS
+		handler    DiskFailureHandler
+		health     cmn.TargetDiskHealth
+		stopCh     chan struct{}
+	}
+	// smartctl -j output (the parts that matter)
+	smartctlOutput struct {
+		Status *struct {
+			Passed bool `json:"passed"`
+		} `json:"smart_status"`
+		Temperature *struct {
+			Current int64 `json:"current"`
+		} `json:"temperature"`
+		ATA *struct {
+			Table []struct {
+				ID  int `json:"id"`
+				Raw struct {
+					Value int64 `json:"value"`
+				} `json:"raw"`
+			} `json:"table"`
+		} `json:"ata_smart_attributes"`
+		NVMe *struct {
+			MediaErrors int64 `json:"media_errors"`
+		} `json:"nvme_smart_health_information_log"`
+		SCSIGrownDefects *int64 `json:"scsi_grown_defect_list"`
+	}
+)
+
+var errNoSMART = errors.New("smartctl is not installed and the OS does not report the disk's health")
+
+func NewSMARTRunner(mountpaths *fs.MountedFS, handler DiskFailureHandler) *SMARTRunner {
+	return &SMARTRunner{
+		mountpaths: mountpaths,
+		handler:    handler,
+		health:     make(cmn.TargetDiskHealth),
+		stopCh:     make(chan struct{}, 1),
+	}
+}
+
+//
+// API
+//
+
+func (r *SMARTRunner) Run() error {
+	glog.Infof("Starting %s", r.Getname())
+	for {
+		if r.Getconf().SMART.Enabled {
+			r.poll()
+		}
+		timer := time.NewTimer(r.Getconf().SMART.Interval)
+		select {
+		case <-timer.C:
+		case <-r.stopCh:
+			timer.Stop()
+			return nil
+		}
+	}
+}
+
+func (r *SMARTRunner) Stop(err error) {
+	glog.Infof("Stopping %s, err: %v", r.Getname(), err)
+	r.stopCh <- struct{}{}
+	close(r.stopCh)
+}
+
+// Health returns the latest SMART reports by disk name
+func (r *SMARTRunner) Health() cmn.TargetDiskHealth {
+	r.RLock()
+	health := make(cmn.TargetDiskHealth, len(r.health))
+	for disk, h := range r.health {
+		health[disk] = h
+	}
+	r.RUnlock()
+	return health
+}
+
+//
+// private
+//
+
+func (r *SMARTRunner) poll() {
+	var (
+		availablePaths, _ = r.mountpaths.Get()
+		disk2mpaths       = make(map[string][]string, len(availablePaths))
+		conf              = &r.Getconf().SMART
+	)
+	for mpath, mpathInfo := range availablePaths {
+		for disk := range fs2disks(mpathInfo.FileSystem) {
+			disk2mpaths[disk] = append(disk2mpaths[disk], mpath)
+		}
+	}
+	health := make(cmn.TargetDiskHealth, len(disk2mpaths))
+	for disk, mpaths := range disk2mpaths {
+		h := readDiskHealth(disk)
+		h.Failing = diskFailing(&h, conf)
+		health[disk] = h
+		if h.Failing == "" {
+			continue
+		}
+		glog.Errorf("Disk %s is failing: %s", disk, h.Failing)
+		for _, mpath := range mpaths {
+			r.handler.OnDiskFailing(mpath, fmt.Sprintf("disk %s is failing: %s", disk, h.Failing))
+		}
+	}
+	r.Lock()
+	r.health = health
+	r.Unlock()
+}
+
+func readDiskHealth(disk string) (h cmn.DiskHealth) {
+	h = cmn.DiskHealth{Time: time.Now(), Reallocated: -1, Pending: -1, MediaErrors: -1, Temperature: -1}
+	// the exit status is a bitmask that is non-zero for a failing disk as well - the output decides
+	out, err := exec.Command("smartctl", "-j", "-H", "-A", "/dev/"+disk).Output()
+	if len(out) > 0 {
+		h.Source = "smartctl"
+		err = parseSmartctl(out, &h)
+	} else if e, ok := err.(*exec.Error); ok && e.Err == exec.ErrNotFound {
+		h.Source = "sysfs"
+		err = sysfsDiskHealth(disk, &h)
+	}
+	if err != nil {
+		h.Err = err.Error()
+		if glog.V(3) {
+			glog.Infof("Disk %s: %v", disk, err)
+		}
+	}
+	return
+}
+
+func parseSmartctl(out []byte, h *cmn.DiskHealth) error {
+	var smart smartctlOutput
+	if err := jsoniter.Unmarshal(out, &smart); err != nil {
+		return fmt.Errorf("failed to parse smartctl output, err: %v", err)
+	}
+	if smart.Status == nil {
+		return errors.New("SMART is not available")
+	}
+	h.SMARTFailed = !smart.Status.Passed
+	if smart.Temperature != nil {
+		h.Temperature = smart.Temperature.Current
+	}
+	if smart.ATA != nil {
+		for _, attr := range smart.ATA.Table {
+			switch attr.ID {
+			case ataReallocated:
+				h.Reallocated = attr.Raw.Value
+			case ataPending:
+				h.Pending = attr.Raw.Value
+			case ataUncorrectable:
+				h.MediaErrors = attr.Raw.Value
+			}
+		}
+	}
+	if smart.NVMe != nil {
+		h.MediaErrors = smart.NVMe.MediaErrors
+	}
+	if smart.SCSIGrownDefects != nil {
+		h.Reallocated = *smart.SCSIGrownDefects
+	}
+	return nil
+}
+
+// diskFailing returns the reason the disk is considered failing, if any
+func diskFailing(h *cmn.DiskHealth, conf *cmn.SMARTConf) string {
+	switch {
+	case h.SMARTFailed:
+		return "SMART overall self-assessment failed"
+	case conf.ReallocatedLimit > 0 && h.Reallocated >= conf.ReallocatedLimit:
+		return fmt.Sprintf("%d reallocated sectors (limit %d)", h.Reallocated, conf.ReallocatedLimit)
+	case conf.MediaErrorsLimit > 0 && h.MediaErrors >= conf.MediaErrorsLimit:
+		return fmt.Sprintf("%d media errors (limit %d)", h.MediaErrors, conf.MediaErrorsLimit)
+	case conf.TemperatureLimit > 0 && h.Temperature >= conf.TemperatureLimit:
+		return fmt.Sprintf("temperature %dC (limit %dC)", h.Temperature, conf.TemperatureLimit)
+	}
+	return ""
+}
@@ -0,0 +1,13 @@
+// +build darwin freebsd
+
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import "github.com/NVIDIA/dfcpub/cmn"
+
+// sysfsDiskHealth: SMART requires smartctl
+func sysfsDiskHealth(disk string, h *cmn.DiskHealth) error { return errNoSMART }
@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// sysfsDiskHealth reads the disk's temperature off its hwmon sensor - NVMe and the drivetemp
+// (SATA) disks; the rest of SMART requires smartctl
+func sysfsDiskHealth(disk string, h *cmn.DiskHealth) error {
+	for _, pattern := range []string{
+		filepath.Join(sysBlockDir, disk, "device", "hwmon", "hwmon*", "temp1_input"),
+		filepath.Join(sysBlockDir, disk, "device", "hwmon*", "temp1_input"),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		for _, path := range matches {
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			if millis, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64); err == nil {
+				h.Temperature = millis / 1000
+				return nil
+			}
+		}
+	}
+	return errNoSMART
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+// Package ios is a collection of interfaces to the local storage subsystem;
+// the package includes OS-dependent implementations for those interfaces.
+package ios
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+const (
+	smartctlATA = `{
+  "smart_status": {"passed": true},
+  "ata_smart_attributes": {"table": [
+    {"id": 5, "name": "Reallocated_Sector_Ct", "raw": {"value": 24, "string": "24"}},
+    {"id": 194, "name": "Temperature_Celsius", "raw": {"value": 38, "string": "38 (Min/Max 18/45)"}},
+    {"id": 197, "name": "Current_Pending_Sector", "raw": {"value": 2, "string": "2"}},
+    {"id": 198, "name": "Offline_Uncorrectable", "raw": {"value": 1, "string": "1"}}
+  ]},
+  "temperature": {"current": 38}
+}`
+	smartctlNVMe = `{
+  "smart_status": {"passed": false},
+  "nvme_smart_health_information_log": {"critical_warning": 4, "temperature": 71, "media_errors": 3},
+  "temperature": {"current": 71}
+}`
+	smartctlNoSMART = `{"smartctl": {"exit_status": 4}, "device": {"name": "/dev/loop0"}}`
+)
+
+func TestParseSmartctl(t *testing.T) {
+	newHealth := func() cmn.DiskHealth {
+		return cmn.DiskHealth{Reallocated: -1, Pending: -1, MediaErrors: -1, Temperature: -1}
+	}
+	h := newHealth()
+	if err := parseSmartctl([]byte(smartctlATA), &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.SMARTFailed || h.Reallocated != 24 || h.Pending != 2 || h.MediaErrors != 1 || h.Temperature != 38 {
+		t.Errorf("ATA: unexpected %+v", h)
+	}
+	h = newHealth()
+	if err := parseSmartctl([]byte(smartctlNVMe), &h); err != nil {
+		t.Fatal(err)
+	}
+	if !h.SMARTFailed || h.Reallocated != -1 || h.MediaErrors != 3 || h.Temperature != 71 {
+		t.Errorf("NVMe: unexpected %+v", h)
+	}
+	h = newHealth()
+	if err := parseSmartctl([]byte(smartctlNoSMART), &h); err == nil {
+		t.Error("expected an error when SMART is not available")
+	}
+}
+
+func TestDiskFailing(t *testing.T) {
+	conf := &cmn.SMARTConf{ReallocatedLimit: 10, MediaErrorsLimit: 1, TemperatureLimit: 70}
+	tests := []struct {
+		h       cmn.DiskHealth
+		failing bool
+	}{
+		{cmn.DiskHealth{Reallocated: -1, Pending: -1, MediaErrors: -1, Temperature: -1}, false},
+		{cmn.DiskHealth{Reallocated: 9, MediaErrors: 0, Temperature: 69}, false},
+		{cmn.DiskHealth{SMARTFailed: true}, true},
+		{cmn.DiskHealth{Reallocated: 10}, true},
+		{cmn.DiskHealth{MediaErrors: 1}, true},
+		{cmn.DiskHealth{Temperature: 70}, true},
+	}
+	for _, test := range tests {
+		if why := diskFailing(&test.h, conf); (why != "") != test.failing {
+			t.Errorf("%+v: expected failing=%t, got %q", test.h, test.failing, why)
+		}
+	}
+	// zero limits: only the self-assessment counts
+	if why := diskFailing(&cmn.DiskHealth{Reallocated: 1000, MediaErrors: 1000, Temperature: 100}, &cmn.SMARTConf{}); why != "" {
+		t.Errorf("expected no limits, got %q", why)
+	}
+}
@@ -145,6 +145,7 @@ type (
 		sorted   []sortpair
 		toGC     int64 // accumulates over time and triggers GC upon reaching the spec-ed limit
 		mindepth int64 // minimum ring depth aka length
+		pressure int64 // PressureLow etc. - as of the last check (atomic)
 		// for user to specify at construction time
 		Name        string
 		MinFree     uint64        // memory that must be available at all times
@@ -161,6 +162,13 @@ type (
 	}
 )
 
+// memory pressure (see Pressure())
+const (
+	PressureLow      = iota // free memory above low watermark
+	PressureModerate        // in-between
+	PressureHigh            // free memory below MinFree, or swapping
+)
+
 // private types
 type sortpair struct {
 	s *Slab2
@@ -407,6 +415,12 @@ func (r *Mem2) SelectSlab2(estimatedSize int64) *Slab2 {
 	return r.rings[len(r.rings)-1]
 }
 
+// Pressure returns the memory pressure as of the last periodic check; the users that
+// hold memory outside of the slabs (caches, etc.) are expected to shrink accordingly
+func (r *Mem2) Pressure() int {
+	return int(atomic.LoadInt64(&r.pressure))
+}
+
 func (r *Mem2) AllocFromSlab2(estimSize int64) ([]byte, *Slab2) {
 	slab := r.SelectSlab2(estimSize)
 	return slab.Alloc(), slab
@@ -489,6 +503,7 @@ func (r *Mem2) work() {
 
 	// 1. enough => free idle
 	if mem.ActualFree > r.lowwm && !swapping {
+		atomic.StoreInt64(&r.pressure, PressureLow)
 		atomic.StoreInt64(&r.mindepth, int64(mindepth))
 		if delta := r.freeIdle(freeIdleMin); delta > 0 {
 			atomic.AddInt64(&r.toGC, delta)
@@ -504,9 +519,11 @@ func (r *Mem2) work() {
 		if swapping {
 			depth = 1
 		}
+		atomic.StoreInt64(&r.pressure, PressureHigh)
 		atomic.StoreInt64(&r.mindepth, int64(depth))
 		limit = sizetoGC / 2
 	} else { // 3. in-between hysteresis
+		atomic.StoreInt64(&r.pressure, PressureModerate)
 		x := uint64(maxdepth-mindepth) * (mem.ActualFree - r.MinFree)
 		depth = mindepth + int(x/(r.lowwm-r.MinFree)) // Heu #2
 		if r.Debug {
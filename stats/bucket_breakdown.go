@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"github.com/NVIDIA/dfcpub/cmn"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Per-bucket breakdown of the request and error counters (bucketCounters). In addition to
+// the totals, the daemon counts the GETs, PUTs, etc., and the errors of each bucket - those
+// added via AddBucket - in a second-level statsTracker per bucket, allocated when the bucket
+// is first counted. The counters are cumulative, since the daemon's start; the latencies are
+// not broken down. The breakdown is returned by GET /v1/daemon?what=stats&buckets=true and,
+// summed over all targets, by GET /v1/cluster?what=stats&buckets=true.
+//
+// The number of buckets is bounded: once maxBucketTrackers buckets are tracked, counting a
+// new one rolls the least recently active bucket over into cmn.StatsOtherBuckets, so that
+// the buckets that currently drive the traffic stay visible, and the sums remain exact.
+
+const maxBucketTrackers = 1024
+
+var bucketCounters = map[string]bool{
+	GetCount:            true,
+	PutCount:            true,
+	PostCount:           true,
+	DeleteCount:         true,
+	RenameCount:         true,
+	ListCount:           true,
+	GetColdCount:        true,
+	GetColdSize:         true,
+	GetPassthroughCount: true,
+	GetPassthroughSize:  true,
+	ErrCount:            true,
+	ErrGetCount:         true,
+	ErrDeleteCount:      true,
+	ErrPostCount:        true,
+	ErrPutCount:         true,
+	ErrHeadCount:        true,
+	ErrListCount:        true,
+	ErrRangeCount:       true,
+}
+
+type bucketTrackers struct {
+	trackers map[string]statsTracker // nil until the first bucket is counted
+	touched  map[string]int64        // bucket => sequence number of its latest update
+	other    statsTracker            // the buckets rolled over
+	seq      int64
+}
+
+func newBucketTracker() statsTracker {
+	tracker := make(statsTracker, len(bucketCounters))
+	for name := range bucketCounters {
+		tracker.register(name, statsKindCounter)
+	}
+	return tracker
+}
+
+// add counts the value if the named stat is broken down; under the runner's lock
+func (b *bucketTrackers) add(bucket, name string, val int64) {
+	if !bucketCounters[name] {
+		return
+	}
+	tracker, ok := b.trackers[bucket]
+	if !ok {
+		if b.trackers == nil {
+			b.trackers = make(map[string]statsTracker, 16)
+			b.touched = make(map[string]int64, 16)
+		}
+		if len(b.trackers) >= maxBucketTrackers {
+			b.rollover()
+		}
+		tracker = newBucketTracker()
+		b.trackers[bucket] = tracker
+	}
+	tracker[name].Value += val
+	b.seq++
+	b.touched[bucket] = b.seq
+}
+
+// rollover moves the counters of the least recently active bucket into the "other"
+func (b *bucketTrackers) rollover() {
+	var (
+		lru string
+		min int64 = -1
+	)
+	for bucket, seq := range b.touched {
+		if min < 0 || seq < min {
+			lru, min = bucket, seq
+		}
+	}
+	if b.other == nil {
+		b.other = newBucketTracker()
+	}
+	for name, stat := range b.trackers[lru] {
+		b.other[name].Value += stat.Value
+	}
+	delete(b.trackers, lru)
+	delete(b.touched, lru)
+}
+
+// outputs returns the breakdown, omitting the zero counters; under the runner's (read) lock
+func (b *bucketTrackers) outputs() cmn.StatsPerBucket {
+	out := make(cmn.StatsPerBucket, len(b.trackers)+1)
+	add := func(bucket string, tracker statsTracker) {
+		counters := make(map[string]int64, 4)
+		for name, stat := range tracker {
+			if stat.Value != 0 {
+				counters[name] = stat.Value
+			}
+		}
+		out[bucket] = counters
+	}
+	for bucket, tracker := range b.trackers {
+		add(bucket, tracker)
+	}
+	if b.other != nil {
+		add(cmn.StatsOtherBuckets, b.other)
+	}
+	return out
+}
+
+//
+// Prunner and Trunner
+//
+
+// MarshalWithBuckets marshals the proxy's stats along with the per-bucket breakdown ("buckets")
+func (r *Prunner) MarshalWithBuckets() ([]byte, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return jsoniter.Marshal(&struct {
+		*Prunner
+		Buckets cmn.StatsPerBucket `json:"buckets"`
+	}{r, r.Core.buckets.outputs()})
+}
+
+// MarshalWithBuckets marshals the target's stats along with the per-bucket breakdown ("buckets")
+func (r *Trunner) MarshalWithBuckets() ([]byte, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return jsoniter.Marshal(&struct {
+		*Trunner
+		Buckets cmn.StatsPerBucket `json:"buckets"`
+	}{r, r.Core.buckets.outputs()})
+}
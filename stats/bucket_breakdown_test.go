@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestBucketBreakdown(t *testing.T) {
+	b := &bucketTrackers{}
+	if out := b.outputs(); len(out) != 0 {
+		t.Fatalf("expected no buckets, got %v", out)
+	}
+	b.add("b1", GetCount, 2)
+	b.add("b1", ErrGetCount, 1)
+	b.add("b1", GetLatency, 1000) // not broken down
+	b.add("b2", PutCount, 1)
+	out := b.outputs()
+	if len(out) != 2 || len(out["b1"]) != 2 || out["b1"][GetCount] != 2 || out["b1"][ErrGetCount] != 1 ||
+		out["b2"][PutCount] != 1 {
+		t.Fatalf("unexpected breakdown %v", out)
+	}
+
+	// the least recently active buckets roll over
+	for i := 0; i < maxBucketTrackers; i++ {
+		if i == 10 {
+			b.add("b2", PutCount, 1)
+		}
+		b.add(fmt.Sprintf("x%d", i), GetCount, 1)
+	}
+	out = b.outputs()
+	if len(out) != maxBucketTrackers+1 {
+		t.Fatalf("expected %d buckets and the other, got %d", maxBucketTrackers, len(out))
+	}
+	if _, ok := out["b1"]; ok {
+		t.Error("expected b1 rolled over")
+	}
+	if out["b2"][PutCount] != 2 {
+		t.Errorf("expected the recently active b2 tracked, got %v", out["b2"])
+	}
+	other := out[cmn.StatsOtherBuckets]
+	if other[GetCount] != 2+1 || other[ErrGetCount] != 1 {
+		t.Errorf("expected b1 and x0 in the other, got %v", other)
+	}
+	var total int64
+	for _, counters := range out {
+		total += counters[GetCount]
+	}
+	if total != 2+maxBucketTrackers {
+		t.Errorf("expected the sum of the GETs %d, got %d", 2+maxBucketTrackers, total)
+	}
+
+	cluster := make(cmn.StatsPerBucket)
+	cluster.Add(cmn.StatsPerBucket{"b2": {PutCount: 2}})
+	cluster.Add(cmn.StatsPerBucket{"b2": {PutCount: 3, ErrPutCount: 1}, "b3": {GetCount: 1}})
+	if cluster["b2"][PutCount] != 5 || cluster["b2"][ErrPutCount] != 1 || cluster["b3"][GetCount] != 1 {
+		t.Errorf("unexpected cluster breakdown %v", cluster)
+	}
+}
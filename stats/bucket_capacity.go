@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"sync"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// Per-bucket capacity: each target maintains the number of objects and their total size in
+// each of the buckets it stores - as the objects are created, overwritten, and removed - so
+// that the totals are known without walking the buckets. The counts are recounted (walked)
+// upon the target's startup and upon mountpath changes; during the recount, the counts are
+// approximate.
+
+type bucketCapacity struct {
+	sync.Mutex
+	local, cloud map[string]*cmn.ObjCount
+	counting     bool
+}
+
+func (b *bucketCapacity) buckets(islocal bool) map[string]*cmn.ObjCount {
+	mm := &b.cloud
+	if islocal {
+		mm = &b.local
+	}
+	if *mm == nil {
+		*mm = make(map[string]*cmn.ObjCount, 16)
+	}
+	return *mm
+}
+
+// AddBucketCapacity accounts for the objects added to (or, with negative counts, removed
+// from) the bucket
+func (r *Trunner) AddBucketCapacity(bucket string, islocal bool, objects, bytes int64) {
+	b := &r.bucketCapacity
+	b.Lock()
+	b.add(bucket, islocal, &cmn.ObjCount{Objects: objects, Bytes: bytes})
+	b.Unlock()
+}
+
+func (b *bucketCapacity) add(bucket string, islocal bool, d *cmn.ObjCount) {
+	mm := b.buckets(islocal)
+	c, ok := mm[bucket]
+	if !ok {
+		c = &cmn.ObjCount{}
+		mm[bucket] = c
+	}
+	c.Add(d)
+	if c.Objects == 0 && c.Bytes == 0 {
+		delete(mm, bucket)
+	}
+}
+
+// RenameBucketCapacity moves the counts of the bucket that has been renamed
+func (r *Trunner) RenameBucketCapacity(bucketFrom, bucketTo string, islocal bool) {
+	b := &r.bucketCapacity
+	b.Lock()
+	mm := b.buckets(islocal)
+	if c, ok := mm[bucketFrom]; ok {
+		delete(mm, bucketFrom)
+		b.add(bucketTo, islocal, c)
+	}
+	b.Unlock()
+}
+
+// DelBucketCapacity forgets the bucket that has been destroyed
+func (r *Trunner) DelBucketCapacity(bucket string, islocal bool) {
+	b := &r.bucketCapacity
+	b.Lock()
+	delete(b.buckets(islocal), bucket)
+	b.Unlock()
+}
+
+// RecountBucketCapacity starts counting anew: the objects added and removed during the
+// recount are accounted for as usual - the caller reports only those that have already been
+// walked - and the walked ones via RecountedBucketCapacity
+func (r *Trunner) RecountBucketCapacity() {
+	b := &r.bucketCapacity
+	b.Lock()
+	b.local, b.cloud, b.counting = nil, nil, true
+	b.Unlock()
+}
+
+// RecountedBucketCapacity adds the walked counts and ends the recount
+func (r *Trunner) RecountedBucketCapacity(walked *cmn.TargetCapacity) {
+	b := &r.bucketCapacity
+	b.Lock()
+	for bucket, c := range walked.Local {
+		b.add(bucket, true, c)
+	}
+	for bucket, c := range walked.Cloud {
+		b.add(bucket, false, c)
+	}
+	b.counting = false
+	b.Unlock()
+}
+
+// BucketCapacity returns the target's objects per bucket
+func (r *Trunner) BucketCapacity() *cmn.TargetCapacity {
+	b := &r.bucketCapacity
+	b.Lock()
+	defer b.Unlock()
+	out := &cmn.TargetCapacity{
+		Local:    make(map[string]*cmn.ObjCount, len(b.local)),
+		Cloud:    make(map[string]*cmn.ObjCount, len(b.cloud)),
+		Counting: b.counting,
+	}
+	for bucket, c := range b.local {
+		out.Local[bucket] = c.NonNegative()
+	}
+	for bucket, c := range b.cloud {
+		out.Cloud[bucket] = c.NonNegative()
+	}
+	return out
+}
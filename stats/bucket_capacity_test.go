@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestBucketCapacity(t *testing.T) {
+	r := &Trunner{}
+	r.AddBucketCapacity("b1", true, 1, 100)
+	r.AddBucketCapacity("b1", true, 1, 50)
+	r.AddBucketCapacity("b1", false, 1, 10) // same name, Cloud bucket
+	r.AddBucketCapacity("b1", true, 0, 25)  // overwritten with a larger object
+
+	c := r.BucketCapacity()
+	if l := c.Local["b1"]; l == nil || *l != (cmn.ObjCount{Objects: 2, Bytes: 175}) {
+		t.Errorf("unexpected local b1 counts: %+v", l)
+	}
+	if cl := c.Cloud["b1"]; cl == nil || *cl != (cmn.ObjCount{Objects: 1, Bytes: 10}) {
+		t.Errorf("unexpected Cloud b1 counts: %+v", cl)
+	}
+
+	// all removed: the bucket is forgotten
+	r.AddBucketCapacity("b1", false, -1, -10)
+	if _, ok := r.BucketCapacity().Cloud["b1"]; ok {
+		t.Error("expected the emptied Cloud bucket to be forgotten")
+	}
+
+	r.RenameBucketCapacity("b1", "b2", true)
+	if c = r.BucketCapacity(); c.Local["b1"] != nil || c.Local["b2"] == nil || c.Local["b2"].Objects != 2 {
+		t.Errorf("expected b1 renamed as b2, got %+v", c.Local)
+	}
+	r.DelBucketCapacity("b2", true)
+	if len(r.BucketCapacity().Local) != 0 {
+		t.Error("expected no local buckets after b2 is destroyed")
+	}
+
+	// recount: the objects added and removed during the walk are accounted for
+	r.AddBucketCapacity("b3", true, 5, 500)
+	r.RecountBucketCapacity()
+	r.AddBucketCapacity("b3", true, 1, 10)
+	r.AddBucketCapacity("b4", true, -1, -20) // removed before walked
+	if c = r.BucketCapacity(); !c.Counting || c.Local["b4"].Objects != 0 {
+		t.Errorf("expected counting and non-negative counts, got %t, %+v", c.Counting, c.Local["b4"])
+	}
+	r.RecountedBucketCapacity(&cmn.TargetCapacity{Local: map[string]*cmn.ObjCount{
+		"b3": {Objects: 3, Bytes: 300},
+		"b4": {Objects: 2, Bytes: 40},
+	}})
+	c = r.BucketCapacity()
+	if c.Counting || *c.Local["b3"] != (cmn.ObjCount{Objects: 4, Bytes: 310}) || *c.Local["b4"] != (cmn.ObjCount{Objects: 1, Bytes: 20}) {
+		t.Errorf("unexpected counts after recount: %t, %+v, %+v", c.Counting, c.Local["b3"], c.Local["b4"])
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// Per-bucket IO (chargeback): each target counts the GETs and the bytes read, the PUTs and
+// the bytes written, and the DELETEs of each bucket - since its start or the last reset.
+// The number of buckets is bounded: the IO of the buckets beyond maxBucketStats is counted
+// as "other", so that the totals remain exact.
+
+const maxBucketStats = 4096
+
+type bucketIO struct {
+	sync.Mutex // the GET/PUT path: not to contend with the stats runner's lock
+	since      time.Time
+	buckets    cmn.BucketStats
+	other      cmn.BucketIOStats
+}
+
+// AddBucketIO accounts for the bucket's IO
+func (r *Trunner) AddBucketIO(bucket string, d *cmn.BucketIOStats) {
+	b := &r.bucketIO
+	b.Lock()
+	if b.buckets == nil {
+		b.buckets = make(cmn.BucketStats, 64)
+	}
+	if _, ok := b.buckets[bucket]; ok || len(b.buckets) < maxBucketStats {
+		b.buckets.Add(bucket, d)
+	} else {
+		b.other.Add(d)
+	}
+	b.Unlock()
+}
+
+// BucketIO returns the IO per bucket since the target's start or the last reset; with reset,
+// atomically starts counting anew - so that consecutive periods neither overlap nor leave gaps
+func (r *Trunner) BucketIO(reset bool) *cmn.TargetBucketStats {
+	b := &r.bucketIO
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	if b.since.IsZero() {
+		b.since = r.starttime
+	}
+	out := &cmn.TargetBucketStats{Since: b.since, Until: now, Buckets: make(cmn.BucketStats, len(b.buckets)), Other: b.other}
+	for bucket, d := range b.buckets {
+		out.Buckets.Add(bucket, d)
+	}
+	if reset {
+		b.since, b.buckets, b.other = now, nil, cmn.BucketIOStats{}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestBucketIO(t *testing.T) {
+	r := &Trunner{}
+	r.starttime = time.Now()
+	r.AddBucketIO("b1", &cmn.BucketIOStats{GetCount: 1, GetSize: 100})
+	r.AddBucketIO("b1", &cmn.BucketIOStats{PutCount: 1, PutSize: 10})
+	r.AddBucketIO("b2", &cmn.BucketIOStats{DeleteCount: 1})
+
+	first := r.BucketIO(true /*reset*/)
+	if d := first.Buckets["b1"]; d == nil || *d != (cmn.BucketIOStats{GetCount: 1, GetSize: 100, PutCount: 1, PutSize: 10}) {
+		t.Errorf("unexpected b1 stats: %+v", d)
+	}
+	if d := first.Buckets["b2"]; d == nil || d.DeleteCount != 1 {
+		t.Errorf("unexpected b2 stats: %+v", d)
+	}
+	if !first.Since.Equal(r.starttime) || first.Until.Before(first.Since) {
+		t.Errorf("expected the period to start at the target's start, got [%v, %v)", first.Since, first.Until)
+	}
+
+	// the next period starts where the previous one ends
+	r.AddBucketIO("b1", &cmn.BucketIOStats{GetCount: 1, GetSize: 5})
+	second := r.BucketIO(false)
+	if !second.Since.Equal(first.Until) || len(second.Buckets) != 1 || second.Buckets["b1"].GetSize != 5 {
+		t.Errorf("unexpected stats after reset: %+v", second)
+	}
+
+	// bounded: the buckets beyond the max are counted as other
+	for i := 0; i < maxBucketStats+2; i++ {
+		r.AddBucketIO("bck"+strconv.Itoa(i), &cmn.BucketIOStats{GetCount: 1})
+	}
+	r.AddBucketIO("b1", &cmn.BucketIOStats{GetCount: 1})
+	third := r.BucketIO(false)
+	if len(third.Buckets) != maxBucketStats || third.Buckets["b1"].GetCount != 2 {
+		t.Errorf("expected %d buckets and b1 still counted, got %d, %+v", maxBucketStats, len(third.Buckets), third.Buckets["b1"])
+	}
+	if third.Other.GetCount != 3 {
+		t.Errorf("expected 3 GETs counted as other, got %d", third.Other.GetCount)
+	}
+}
@@ -4,6 +4,7 @@
 package stats
 
 import (
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -23,8 +24,11 @@ import (
 const logsTotalSizeCheckTime = time.Hour * 3
 
 const (
-	statsKindCounter = "counter"
-	statsKindLatency = "latency"
+	statsKindCounter    = "counter"
+	statsKindLatency    = "latency"
+	statsKindMinLatency = "latency.min"  // the minimum within the current stats period (stats_time)
+	statsKindMaxLatency = "latency.max"  // ditto, the maximum
+	statsKindHistogram  = "latency.hist" // latency, plus the percentiles of the stats period - see histogram.go
 )
 
 // Stats common to ProxyCoreStats and targetCoreStats
@@ -35,12 +39,12 @@ const (
 	DeleteCount         = "del.n"
 	RenameCount         = "ren.n"
 	ListCount           = "lst.n"
-	GetLatency          = "get.μs"
-	ListLatency         = "lst.μs"
-	KeepAliveMinLatency = "kalive.μs.min"
-	KeepAliveMaxLatency = "kalive.μs.max"
-	KeepAliveLatency    = "kalive.μs"
-	Uptime              = "uptime.μs"
+	GetLatency          = "get.lat"
+	ListLatency         = "lst.lat"
+	KeepAliveMinLatency = "kalive.lat.min"
+	KeepAliveMaxLatency = "kalive.lat.max"
+	KeepAliveLatency    = "kalive.lat"
+	Uptime              = "uptime"
 	ErrCount            = "err.n"
 	ErrGetCount         = "err.get.n"
 	ErrDeleteCount      = "err.delete.n"
@@ -64,40 +68,64 @@ type (
 	statslogger interface {
 		log() (runlru bool)
 		housekeep(bool)
-		doAdd(nv NamedVal64)
+		doAdd(nv NamedVal64, bucket string)
 	}
 	// implemented by the *CoreStats types
 	Tracker interface {
 		Add(name string, val int64)
 		AddErrorHTTP(method string, val int64)
 		AddMany(namedVal64 ...NamedVal64)
+		AddBucket(bucket string, namedVal64 ...NamedVal64) // ditto, and broken down per bucket
 	}
 	NamedVal64 struct {
 		Name string
 		Val  int64
 	}
+	// bucketVal64 is the NamedVal64 attributed to the bucket (empty: none) - see AddBucket
+	bucketVal64 struct {
+		NamedVal64
+		bucket string
+	}
 	statsrunner struct {
 		sync.RWMutex
 		cmn.NamedConfigured
 		stopCh    chan struct{}
-		workCh    chan NamedVal64
+		workCh    chan bucketVal64
 		starttime time.Time
+		slo       *sloTracker
+		hist      statsHistory
+		snap      statsSnapshot // baseline of GetSnapshot - see snapshot.go
+		slog      statsLog      // stats log compaction - see statslog.go
 	}
 	// Stats are tracked via a map of stats names (key) to statInstances (values).
 	// There are two main types of stats: counter and latency declared
 	// using the the kind field. Only latency stats have associatedVals to them
 	// that are used in calculating latency measurements.
+	// Latencies are always added and accumulated as time.Duration (nanoseconds);
+	// the conversion into the configured output unit (stats_latency_unit) is done only
+	// when the stats are reported (JSON and logs). StatsD always receives milliseconds.
+	// The histogram kind is a latency that, in addition, keeps the distribution of its
+	// samples over the stats period, reported as the percentiles <name>.p50 etc.
 	statsInstance struct {
 		Value         int64 `json:"value"`
 		kind          string
 		associatedVal int64
+		hist          *latencyHist  // latency and histogram kinds - see prometheus.go
+		hdr           *hdrHistogram // histogram kind only
 	}
 	statsTracker map[string]*statsInstance
 )
 
 func (stats statsTracker) register(key string, kind string) {
-	cmn.Assert(kind == statsKindCounter || kind == statsKindLatency, "Invalid stats kind "+kind)
-	stats[key] = &statsInstance{0, kind, 0}
+	cmn.Assert(kind == statsKindCounter || isLatencyKind(kind), "Invalid stats kind "+kind)
+	stat := &statsInstance{kind: kind}
+	if kind == statsKindLatency || kind == statsKindHistogram {
+		stat.hist = newLatencyHist()
+	}
+	if kind == statsKindHistogram {
+		stat.hdr = &hdrHistogram{}
+	}
+	stats[key] = stat
 }
 
 // These stats are common to ProxyCoreStats and targetCoreStats
@@ -110,10 +138,10 @@ func (stats statsTracker) registerCommonStats() {
 	stats.register(DeleteCount, statsKindCounter)
 	stats.register(RenameCount, statsKindCounter)
 	stats.register(ListCount, statsKindCounter)
-	stats.register(GetLatency, statsKindCounter)
+	stats.register(GetLatency, statsKindHistogram)
 	stats.register(ListLatency, statsKindLatency)
-	stats.register(KeepAliveMinLatency, statsKindLatency)
-	stats.register(KeepAliveMaxLatency, statsKindLatency)
+	stats.register(KeepAliveMinLatency, statsKindMinLatency)
+	stats.register(KeepAliveMaxLatency, statsKindMaxLatency)
 	stats.register(KeepAliveLatency, statsKindLatency)
 	stats.register(Uptime, statsKindLatency)
 	stats.register(ErrCount, statsKindCounter)
@@ -130,6 +158,81 @@ func (stat *statsInstance) MarshalJSON() ([]byte, error) {
 	return jsoniter.Marshal(stat.Value)
 }
 
+func isLatencyKind(kind string) bool {
+	return kind == statsKindLatency || kind == statsKindHistogram || kind == statsKindMinLatency || kind == statsKindMaxLatency
+}
+
+// minmax keeps the minimum (or the maximum) of the values added since the last reset
+func (stat *statsInstance) minmax(val int64) {
+	if stat.associatedVal == 0 ||
+		(stat.kind == statsKindMinLatency && val < stat.Value) ||
+		(stat.kind == statsKindMaxLatency && val > stat.Value) {
+		stat.Value = val
+	}
+	stat.associatedVal++
+}
+
+// output returns the value in the given unit; for the latency (and histogram) kind it
+// is the average over the accumulated samples (if any), for the min/max kinds - the
+// minimum/maximum sample of the current stats period
+func (stat *statsInstance) output(unit time.Duration) int64 {
+	if !isLatencyKind(stat.kind) {
+		return stat.Value
+	}
+	d := time.Duration(stat.Value)
+	if (stat.kind == statsKindLatency || stat.kind == statsKindHistogram) && stat.associatedVal > 0 {
+		d /= time.Duration(stat.associatedVal)
+	}
+	return int64(d / unit)
+}
+
+func (stats statsTracker) marshal(unit time.Duration) ([]byte, error) {
+	return jsoniter.Marshal(stats.outputs(unit))
+}
+
+// outputs returns the reported values, including the percentiles of the histogram kind
+func (stats statsTracker) outputs(unit time.Duration) map[string]int64 {
+	out := make(map[string]int64, len(stats))
+	for name, stat := range stats {
+		out[name] = stat.output(unit)
+		if stat.kind == statsKindHistogram {
+			for _, pct := range histPercentiles {
+				out[name+pct.suffix] = int64(time.Duration(stat.hdr.percentile(pct.q)) / unit)
+			}
+		}
+	}
+	return out
+}
+
+// reset all the latency stats only (including min/max - which makes the latter
+// the min/max of the stats period)
+func (stats statsTracker) resetLatencies() {
+	for _, v := range stats {
+		if isLatencyKind(v.kind) {
+			v.Value = 0
+			v.associatedVal = 0
+			if v.hdr != nil {
+				v.hdr.reset()
+			}
+		}
+	}
+}
+
+// ParseLatencyUnit parses the stats_latency_unit configuration value
+func ParseLatencyUnit(s string) (time.Duration, error) {
+	switch s {
+	case "ns":
+		return time.Nanosecond, nil
+	case "", "us", "µs", "μs":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	}
+	return 0, fmt.Errorf("invalid latency unit %q (expecting one of: ns, us, ms, s)", s)
+}
+
 func (stat *statsInstance) UnmarshalJSON(b []byte) error {
 	return jsoniter.Unmarshal(b, &stat.Value)
 }
@@ -143,17 +246,20 @@ var _ Tracker = &statsrunner{}
 
 func (r *statsrunner) runcommon(logger statslogger) error {
 	r.stopCh = make(chan struct{}, 4)
-	r.workCh = make(chan NamedVal64, 256)
+	r.workCh = make(chan bucketVal64, 256)
 	r.starttime = time.Now()
 
 	glog.Infof("Starting %s", r.Getname())
 	config := r.Getconf()
+	r.Lock()
+	r.slo = newSLOTracker(&config.SLO)
+	r.Unlock()
 	ticker := time.NewTicker(config.Periodic.StatsTime)
 	for {
 		select {
-		case nv, ok := <-r.workCh:
+		case bv, ok := <-r.workCh:
 			if ok {
-				logger.doAdd(nv)
+				logger.doAdd(bv.NamedVal64, bv.bucket)
 			}
 		case <-ticker.C:
 			runlru := logger.log()
@@ -172,33 +278,53 @@ func (r *statsrunner) Stop(err error) {
 }
 
 // statslogger interface impl
-func (r *statsrunner) log() (runlru bool)  { return false }
-func (r *statsrunner) housekeep(bool)      {}
-func (r *statsrunner) doAdd(nv NamedVal64) {}
+func (r *statsrunner) log() (runlru bool)                 { return false }
+func (r *statsrunner) housekeep(bool)                     {}
+func (r *statsrunner) doAdd(nv NamedVal64, bucket string) {}
+
+// track feeds the named value to the SLO tracker and the stats history; under lock
+func (r *statsrunner) track(name string, val int64) {
+	now := time.Now()
+	r.slo.add(name, val, now)
+	r.hist.add(name, val, r.Getconf().Periodic.StatsHistory, now)
+}
 
 func (r *statsrunner) AddMany(nvs ...NamedVal64) {
 	for _, nv := range nvs {
-		r.workCh <- nv
+		r.workCh <- bucketVal64{NamedVal64: nv}
+	}
+}
+
+// AddBucket adds the named values to the totals and, if the bucket is given, to the
+// bucket's counters - see bucket_breakdown.go
+func (r *statsrunner) AddBucket(bucket string, nvs ...NamedVal64) {
+	for _, nv := range nvs {
+		r.workCh <- bucketVal64{NamedVal64: nv, bucket: bucket}
 	}
 }
 
 func (r *statsrunner) Add(name string, val int64) {
-	r.workCh <- NamedVal64{name, val}
+	r.workCh <- bucketVal64{NamedVal64: NamedVal64{Name: name, Val: val}}
 }
 
 func (r *statsrunner) AddErrorHTTP(method string, val int64) {
+	r.workCh <- bucketVal64{NamedVal64: ErrorHTTP(method, val)}
+}
+
+// ErrorHTTP returns the error counter of the HTTP method
+func ErrorHTTP(method string, val int64) NamedVal64 {
 	switch method {
 	case http.MethodGet:
-		r.workCh <- NamedVal64{ErrGetCount, val}
+		return NamedVal64{ErrGetCount, val}
 	case http.MethodDelete:
-		r.workCh <- NamedVal64{ErrDeleteCount, val}
+		return NamedVal64{ErrDeleteCount, val}
 	case http.MethodPost:
-		r.workCh <- NamedVal64{ErrPostCount, val}
+		return NamedVal64{ErrPostCount, val}
 	case http.MethodPut:
-		r.workCh <- NamedVal64{ErrPutCount, val}
+		return NamedVal64{ErrPutCount, val}
 	case http.MethodHead:
-		r.workCh <- NamedVal64{ErrHeadCount, val}
+		return NamedVal64{ErrHeadCount, val}
 	default:
-		r.workCh <- NamedVal64{ErrCount, val}
+		return NamedVal64{ErrCount, val}
 	}
 }
@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/stats/statsd"
+)
+
+// The Graphite sink sends the metrics to carbon over TCP, in the plaintext protocol
+// ("name value timestamp" lines). Since carbon keeps a single value per metric per
+// timestamp, the sink aggregates the metrics of each flush interval (metrics.graphite.
+// flush_interval) and sends one value per metric: the sum of the counters, the average of
+// the timers, and the latest gauge. With metrics.graphite.tags (Graphite 1.1+), the
+// daemon's ID and the bucket are sent as tags ("name;key=value") rather than as part of
+// the name. The metrics that cannot be sent are dropped.
+
+const graphiteDialTimeout = 5 * time.Second
+
+type (
+	graphiteSink struct {
+		mtx      sync.Mutex
+		addr     string
+		prefix   string
+		tags     []statsd.Tag // sent with every metric (tagged)
+		tagged   bool
+		interval time.Duration
+		aggs     map[string]*graphiteAgg // by series, as sent
+		conn     net.Conn
+		failed   bool // logged once, until sent again
+		stopCh   chan struct{}
+		wg       sync.WaitGroup
+	}
+	graphiteAgg struct {
+		typ statsd.MetricType
+		val float64 // sum; the latest for gauges
+		n   int64
+	}
+)
+
+var graphiteEscaper = strings.NewReplacer(" ", "_", "/", "_", ";", "_", "=", "_", "~", "_", "\n", "_", "\t", "_")
+
+func newGraphiteSink(args SinkArgs) (MetricsSink, error) {
+	conf := &args.Config.Metrics.Graphite
+	g := &graphiteSink{
+		addr:     conf.Address,
+		tagged:   conf.Tags,
+		interval: conf.FlushInterval,
+		aggs:     make(map[string]*graphiteAgg, 64),
+		stopCh:   make(chan struct{}),
+	}
+	g.prefix, g.tags = daemonPrefix(args, conf.Tags)
+	g.wg.Add(1)
+	go g.flusher()
+	return g, nil
+}
+
+func (g *graphiteSink) Send(name string, metrics ...statsd.Metric) {
+	g.SendTags(name, nil, metrics...)
+}
+
+func (g *graphiteSink) SendTags(name string, tags []statsd.Tag, metrics ...statsd.Metric) {
+	g.mtx.Lock()
+	for _, m := range metrics {
+		v, ok := metricValue(m.Value)
+		if !ok {
+			continue
+		}
+		series := g.series(name+"."+m.Name, tags)
+		a, ok := g.aggs[series]
+		if !ok {
+			a = &graphiteAgg{typ: m.Type}
+			g.aggs[series] = a
+		}
+		if m.Type == statsd.Gauge {
+			a.val = v
+		} else {
+			a.val += v
+		}
+		a.n++
+	}
+	g.mtx.Unlock()
+}
+
+// series returns the name as sent: escaped, prefixed, and tagged
+func (g *graphiteSink) series(name string, tags []statsd.Tag) string {
+	s := graphiteEscaper.Replace(g.prefix + "." + name)
+	if !g.tagged {
+		return s
+	}
+	for _, list := range [][]statsd.Tag{g.tags, tags} {
+		for _, tag := range list {
+			s += ";" + graphiteEscaper.Replace(tag.Key) + "=" + graphiteEscaper.Replace(tag.Value)
+		}
+	}
+	return s
+}
+
+func (g *graphiteSink) Close() error {
+	close(g.stopCh)
+	g.wg.Wait()
+	g.flush(time.Now())
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	if g.conn == nil {
+		return nil
+	}
+	return g.conn.Close()
+}
+
+func (g *graphiteSink) flusher() {
+	defer g.wg.Done()
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			g.flush(now)
+		case <-g.stopCh:
+			return
+		}
+	}
+}
+
+// flush sends the metrics aggregated since the previous flush, timestamped now
+func (g *graphiteSink) flush(now time.Time) {
+	g.mtx.Lock()
+	aggs := g.aggs
+	if len(aggs) == 0 {
+		g.mtx.Unlock()
+		return
+	}
+	g.aggs = make(map[string]*graphiteAgg, len(aggs))
+	g.mtx.Unlock()
+
+	var (
+		buf bytes.Buffer
+		ts  = " " + strconv.FormatInt(now.Unix(), 10) + "\n"
+	)
+	for series, a := range aggs {
+		v := a.val
+		if a.typ == statsd.Timer {
+			v /= float64(a.n)
+		}
+		buf.WriteString(series + " " + strconv.FormatFloat(v, 'f', -1, 64) + ts)
+	}
+	err := g.write(buf.Bytes())
+	g.mtx.Lock()
+	if err != nil && !g.failed {
+		glog.Warningf("Failed to send metrics to Graphite at %s (dropping them until it is back), err: %v", g.addr, err)
+	} else if err == nil && g.failed {
+		glog.Infof("Graphite at %s is back", g.addr)
+	}
+	g.failed = err != nil
+	g.mtx.Unlock()
+}
+
+// write (re)connects, if need be, and writes; on error, closes the connection
+func (g *graphiteSink) write(b []byte) (err error) {
+	g.mtx.Lock()
+	conn := g.conn
+	g.mtx.Unlock()
+	if conn == nil {
+		if conn, err = net.DialTimeout("tcp", g.addr, graphiteDialTimeout); err != nil {
+			return
+		}
+		g.mtx.Lock()
+		g.conn = conn
+		g.mtx.Unlock()
+	}
+	conn.SetWriteDeadline(time.Now().Add(graphiteDialTimeout))
+	if _, err = conn.Write(b); err != nil {
+		conn.Close()
+		g.mtx.Lock()
+		g.conn = nil
+		g.mtx.Unlock()
+	}
+	return
+}
+
+func metricValue(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	f, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+	return f, err == nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"math"
+	"math/bits"
+)
+
+// hdrHistogram is a log-linear (HDR-style) histogram of the non-negative int64 samples -
+// here, latencies in nanoseconds. The values below 2*hdrSubBuckets are counted exactly;
+// above, each power of two is divided into hdrSubBuckets equal buckets, which bounds the
+// relative error of the percentiles by 1/hdrSubBuckets (under 1%) across the entire int64
+// range. The buckets are allocated as needed, up to the largest sample.
+const (
+	hdrSubBits    = 7
+	hdrSubBuckets = 1 << hdrSubBits
+)
+
+// the percentiles of the statsKindHistogram stats: logged, returned via the stats API
+// as <name>.p50, <name>.p95, <name>.p99, and sent to StatsD
+var histPercentiles = []struct {
+	suffix string
+	q      float64
+}{
+	{".p50", 0.5},
+	{".p95", 0.95},
+	{".p99", 0.99},
+}
+
+type hdrHistogram struct {
+	counts []int64
+	n      int64
+	max    int64
+}
+
+func hdrIndex(val int64) int {
+	if val < 2*hdrSubBuckets {
+		return int(val)
+	}
+	shift := uint(bits.Len64(uint64(val)) - hdrSubBits - 1)
+	return int(shift)*hdrSubBuckets + int(val>>shift)
+}
+
+// hdrValue returns the midpoint of the bucket
+func hdrValue(idx int) int64 {
+	if idx < 2*hdrSubBuckets {
+		return int64(idx)
+	}
+	shift := uint(idx/hdrSubBuckets - 1)
+	sub := int64(idx - int(shift)*hdrSubBuckets)
+	return sub<<shift + (int64(1)<<shift)/2
+}
+
+func (h *hdrHistogram) record(val int64) {
+	if val < 0 {
+		val = 0
+	}
+	idx := hdrIndex(val)
+	if idx >= len(h.counts) {
+		counts := make([]int64, idx+1, idx+1+hdrSubBuckets)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	h.counts[idx]++
+	h.n++
+	if val > h.max {
+		h.max = val
+	}
+}
+
+// percentile returns the value at or below which q (0 < q <= 1) of the samples are
+func (h *hdrHistogram) percentile(q float64) int64 {
+	if h.n == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.n)))
+	if rank < 1 {
+		rank = 1
+	}
+	var cnt int64
+	for idx, c := range h.counts {
+		cnt += c
+		if cnt >= rank {
+			if v := hdrValue(idx); v < h.max {
+				return v
+			}
+			break
+		}
+	}
+	return h.max
+}
+
+// reset keeps the allocated buckets
+func (h *hdrHistogram) reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.n, h.max = 0, 0
+}
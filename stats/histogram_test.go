@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestHDRBuckets(t *testing.T) {
+	prev := -1
+	for _, v := range []int64{0, 1, 255, 256, 257, 1000, 1 << 20, int64(time.Hour), 1<<62 + 12345} {
+		idx := hdrIndex(v)
+		if idx < prev {
+			t.Fatalf("bucket indices must not decrease with the values: %d => %d (previous %d)", v, idx, prev)
+		}
+		prev = idx
+		if v < 2*hdrSubBuckets && hdrValue(idx) != v {
+			t.Errorf("expected the small value %d counted exactly, got %d", v, hdrValue(idx))
+		}
+	}
+	for i := 0; i < 10000; i++ {
+		v := rand.Int63n(int64(time.Minute))
+		got := hdrValue(hdrIndex(v))
+		if diff := got - v; diff*hdrSubBuckets > v || -diff*hdrSubBuckets > v {
+			t.Fatalf("%d is off by more than 1/%d: %d", v, hdrSubBuckets, got)
+		}
+	}
+}
+
+func TestHDRPercentiles(t *testing.T) {
+	h := &hdrHistogram{}
+	if p := h.percentile(0.99); p != 0 {
+		t.Errorf("expected zero percentile of the empty histogram, got %d", p)
+	}
+	// 1ms..1000ms; and a single 10s outlier that shows up only at the very top
+	for ms := int64(1); ms <= 1000; ms++ {
+		h.record(ms * int64(time.Millisecond))
+	}
+	h.record(int64(10 * time.Second))
+	for _, test := range []struct {
+		q        float64
+		expected time.Duration
+	}{
+		{0.5, 501 * time.Millisecond},
+		{0.95, 951 * time.Millisecond},
+		{0.99, 991 * time.Millisecond},
+		{1, 10 * time.Second},
+	} {
+		got := time.Duration(h.percentile(test.q))
+		if diff := got - test.expected; diff < -test.expected/100 || diff > test.expected/100 {
+			t.Errorf("p%v: expected %v (within 1%%), got %v", test.q*100, test.expected, got)
+		}
+	}
+	h.reset()
+	h.record(5)
+	if p := h.percentile(0.5); p != 5 || h.n != 1 {
+		t.Errorf("expected the reset histogram to have a single sample (5), got %d (n=%d)", p, h.n)
+	}
+}
+
+func TestHistogramStats(t *testing.T) {
+	r := &Trunner{Core: &targetCoreStats{}}
+	r.Setconf(&cmn.Config{})
+	r.Core.initStatsTracker()
+	r.Core.Sink = NopSink{}
+	unit := time.Millisecond
+
+	for ms := int64(1); ms <= 100; ms++ {
+		r.Core.doAdd(PutLatency, ms*int64(time.Millisecond))
+	}
+	out := r.Core.Tracker.outputs(unit)
+	// (within 1%, truncated to the unit)
+	approx := func(got, expected int64) bool { return got == expected || got == expected-1 }
+	if out[PutLatency] != 50 || !approx(out[PutLatency+".p50"], 50) || !approx(out[PutLatency+".p95"], 95) ||
+		!approx(out[PutLatency+".p99"], 99) {
+		t.Errorf("expected put.lat avg 50ms and p50/p95/p99 50/95/99ms, got %v", out)
+	}
+	if _, ok := out[ListLatency+".p50"]; ok {
+		t.Errorf("expected no percentiles of the plain latency %s", ListLatency)
+	}
+
+	// next stats period
+	r.Core.Tracker.resetLatencies()
+	out = r.Core.Tracker.outputs(unit)
+	if out[PutLatency] != 0 || out[PutLatency+".p99"] != 0 {
+		t.Errorf("expected the percentiles reset along with the latencies, got %v", out)
+	}
+}
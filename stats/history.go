@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// Stats history (periodic.stats_history): the named values that update the stats are also
+// summed up per minute, per 10 minutes, and per hour, and each resolution keeps the sums
+// for the configured horizon - so that the recent performance of the daemon can be looked
+// at without an external time series database.
+
+// HistoryResolutions are the resolutions of the stats history, finest first
+var HistoryResolutions = []time.Duration{time.Minute, 10 * time.Minute, time.Hour}
+
+type (
+	statsHistory struct {
+		rings []*histRing // one per resolution
+	}
+	// histRing is the ring buffer of slots indexed by the number of resolution periods
+	// since the epoch
+	histRing struct {
+		res   time.Duration
+		slots []histSlot
+	}
+	histSlot struct {
+		n    int64
+		vals map[string]*histVal
+	}
+	histVal struct {
+		sum      int64
+		count    int64 // number of values (latency samples)
+		min, max int64
+	}
+)
+
+// historySlots returns the number of slots that cover the horizon at the given resolution
+func historySlots(horizon, res time.Duration) int {
+	if n := int(horizon / res); n > 0 {
+		return n + 1 // the current (partial) period included
+	}
+	return 1
+}
+
+func (h *statsHistory) add(name string, val int64, horizon time.Duration, now time.Time) {
+	if horizon <= 0 {
+		h.rings = nil
+		return
+	}
+	if h.rings == nil {
+		h.rings = make([]*histRing, len(HistoryResolutions))
+		for i, res := range HistoryResolutions {
+			h.rings[i] = &histRing{res: res, slots: make([]histSlot, historySlots(horizon, res))}
+		}
+	}
+	for _, ring := range h.rings {
+		if size := historySlots(horizon, ring.res); size != len(ring.slots) {
+			ring.resize(size)
+		}
+		slot := ring.slot(now)
+		v, ok := slot.vals[name]
+		if !ok {
+			v = &histVal{}
+			slot.vals[name] = v
+		}
+		if v.count == 0 || val < v.min {
+			v.min = val
+		}
+		if v.count == 0 || val > v.max {
+			v.max = val
+		}
+		v.sum += val
+		v.count++
+	}
+}
+
+func (ring *histRing) slot(now time.Time) *histSlot {
+	n := now.UnixNano() / int64(ring.res)
+	slot := &ring.slots[n%int64(len(ring.slots))]
+	if slot.n != n || slot.vals == nil {
+		*slot = histSlot{n: n, vals: make(map[string]*histVal, 16)}
+	}
+	return slot
+}
+
+// resize keeps the most recent slots that fit
+func (ring *histRing) resize(size int) {
+	old := ring.slots
+	ring.slots = make([]histSlot, size)
+	for _, slot := range old {
+		if slot.vals == nil {
+			continue
+		}
+		i := slot.n % int64(size)
+		if slot.n > ring.slots[i].n {
+			ring.slots[i] = slot
+		}
+	}
+}
+
+// query returns the samples of the given resolution within [since, until), oldest first;
+// the counters are summed up per sample, the latencies - averaged (the min/max latencies -
+// minimized/maximized) and converted into the given unit. The slots that are older than the horizon (as of now) are skipped
+func (h *statsHistory) query(tracker statsTracker, unit time.Duration, names []string, res time.Duration,
+	since, until, now time.Time) []cmn.StatsSample {
+	var ring *histRing
+	for _, r := range h.rings {
+		if r.res == res {
+			ring = r
+		}
+	}
+	samples := make([]cmn.StatsSample, 0, 8)
+	if ring == nil {
+		return samples
+	}
+	oldest := now.UnixNano()/int64(res) - int64(len(ring.slots)) + 1
+	for _, slot := range ring.slots {
+		if slot.vals == nil || slot.n < oldest {
+			continue
+		}
+		start := time.Unix(0, slot.n*int64(res))
+		if start.Add(res).Before(since) || !start.Before(until) {
+			continue
+		}
+		sample := cmn.StatsSample{Time: start, Values: make(map[string]int64, len(slot.vals))}
+		for name, v := range slot.vals {
+			if len(names) != 0 && !cmn.StringInSlice(name, names) {
+				continue
+			}
+			stat, ok := tracker[name]
+			switch {
+			case !ok || stat.kind == statsKindCounter:
+				sample.Values[name] = v.sum
+			case stat.kind == statsKindMinLatency:
+				sample.Values[name] = int64(time.Duration(v.min) / unit)
+			case stat.kind == statsKindMaxLatency:
+				sample.Values[name] = int64(time.Duration(v.max) / unit)
+			default:
+				sample.Values[name] = int64(time.Duration(v.sum/v.count) / unit)
+			}
+		}
+		if len(sample.Values) != 0 {
+			samples = append(samples, sample)
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples
+}
+
+func (r *statsrunner) history(tracker statsTracker, unit time.Duration, names []string, res, within,
+	until time.Duration) *cmn.StatsHistory {
+	var (
+		now   = time.Now()
+		since time.Time
+	)
+	if within > 0 {
+		since = now.Add(-within)
+	}
+	r.RLock()
+	defer r.RUnlock()
+	return &cmn.StatsHistory{
+		Resolution: res.String(),
+		Samples:    r.hist.query(tracker, unit, names, res, since, now.Add(-until), now),
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsHistory(t *testing.T) {
+	var (
+		h       statsHistory
+		tracker = statsTracker{}
+		horizon = 3 * time.Hour
+		now     = time.Now().Truncate(time.Hour).Add(30*time.Minute + 10*time.Second)
+		past    = now.Add(-2 * time.Hour)
+	)
+	tracker.registerCommonStats()
+	// 2 hours ago: 3 GETs, 10ms and 20ms latency
+	h.add(GetCount, 3, horizon, past)
+	h.add(GetLatency, int64(10*time.Millisecond), horizon, past)
+	h.add(GetLatency, int64(20*time.Millisecond), horizon, past.Add(30*time.Second))
+	// just now: 5 GETs, 1 failed
+	h.add(GetCount, 5, horizon, now)
+	h.add(ErrGetCount, 1, horizon, now)
+
+	samples := h.query(tracker, time.Millisecond, nil, time.Minute, time.Time{}, now, now)
+	if len(samples) != 2 || !samples[0].Time.Equal(past.Truncate(time.Minute)) {
+		t.Fatalf("expected 2 samples, oldest first, got %+v", samples)
+	}
+	if v := samples[0].Values; v[GetCount] != 3 || v[GetLatency] != 15 {
+		t.Errorf("expected the counter summed up and the latency averaged, got %v", v)
+	}
+	if v := samples[1].Values; v[GetCount] != 5 || v[ErrGetCount] != 1 || len(v) != 2 {
+		t.Errorf("unexpected %v", v)
+	}
+
+	// selected stats, time range
+	samples = h.query(tracker, time.Millisecond, []string{GetCount}, time.Hour, now.Add(-time.Hour), now, now)
+	if len(samples) != 1 || len(samples[0].Values) != 1 || samples[0].Values[GetCount] != 5 {
+		t.Errorf("expected the last hour's get.n only, got %+v", samples)
+	}
+	if samples = h.query(tracker, time.Millisecond, nil, 10*time.Minute, time.Time{}, now.Add(-time.Hour), now); len(samples) != 1 {
+		t.Errorf("expected the sample of 2 hours ago only, got %+v", samples)
+	}
+
+	// shorter horizon: the old samples are dropped, the recent ones kept
+	h.add(GetCount, 1, time.Hour, now)
+	samples = h.query(tracker, time.Millisecond, nil, time.Minute, time.Time{}, now, now)
+	if len(samples) != 1 || samples[0].Values[GetCount] != 6 {
+		t.Errorf("expected a single sample after resize, got %+v", samples)
+	}
+	// disabled
+	h.add(GetCount, 1, 0, now)
+	if samples = h.query(tracker, time.Millisecond, nil, time.Minute, time.Time{}, now, now); len(samples) != 0 {
+		t.Errorf("expected no history, got %+v", samples)
+	}
+}
+
+func TestStatsHistoryMinMax(t *testing.T) {
+	var (
+		h       statsHistory
+		tracker = statsTracker{}
+		now     = time.Now().Truncate(time.Minute).Add(10 * time.Second)
+	)
+	tracker.registerCommonStats()
+	for _, ms := range []time.Duration{30, 10, 20} {
+		h.add(KeepAliveMinLatency, int64(ms*time.Millisecond), time.Hour, now)
+		h.add(KeepAliveMaxLatency, int64(ms*time.Millisecond), time.Hour, now)
+		h.add(KeepAliveLatency, int64(ms*time.Millisecond), time.Hour, now)
+	}
+	samples := h.query(tracker, time.Millisecond, nil, time.Minute, time.Time{}, now, now)
+	if len(samples) != 1 {
+		t.Fatalf("expected 1 sample, got %+v", samples)
+	}
+	if v := samples[0].Values; v[KeepAliveMinLatency] != 10 || v[KeepAliveMaxLatency] != 30 || v[KeepAliveLatency] != 20 {
+		t.Errorf("expected min 10, max 30, avg 20, got %v", v)
+	}
+}
@@ -0,0 +1,318 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Prometheus exposition (GET /metrics on both the proxy and the target) of the same
+// stats that are logged, sent to StatsD, and returned via GET /v1/daemon?what=stats,
+// in the Prometheus text format (version 0.0.4). The stats are mapped as follows:
+//   - counters (".n" and ".size") => counters dfc_<name>_total and dfc_<name>_bytes_total,
+//     respectively, e.g. get.n => dfc_get_total, get.cold.size => dfc_get_cold_bytes_total;
+//   - counters that go up and down (promGauges) => gauges;
+//   - latencies => histograms dfc_<name>_latency_seconds with the promLatencyBuckets;
+//     unlike the averages, the histograms are cumulative since the daemon's start;
+//   - min/max latencies of the stats period and the uptime => gauges (seconds);
+//   - target only: capacity (per mountpath), per-mountpath LRU and atime stats, iostat
+//     metrics (per disk and per filesystem), and CPU idle => gauges with the respective
+//     mountpath, disk, or filesystem label.
+// The capacity and the iostat metrics are those of the latest stats period (stats_time).
+
+// PrometheusContentType is the Content-Type of the Prometheus text format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const promPrefix = "dfc_"
+
+// the upper bounds of the latency histograms' buckets, in seconds
+var promLatencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// label values' escaping
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// counters that are, in fact, gauges
+var promGauges = map[string]string{
+	AtimeMapSize:      "atime_map_entries",
+	CksumPendingCount: "cksum_pending",
+	CompressSavedSize: "compress_saved_bytes",
+}
+
+type (
+	// latencyHist is the cumulative histogram of a latency stat
+	latencyHist struct {
+		counts []int64 // per promLatencyBuckets, non-cumulative; the last one is +Inf
+		sum    time.Duration
+		n      int64
+	}
+	promWriter struct {
+		w      *bufio.Writer
+		latest string // the family whose HELP and TYPE have been written
+	}
+)
+
+func newLatencyHist() *latencyHist {
+	return &latencyHist{counts: make([]int64, len(promLatencyBuckets)+1)}
+}
+
+func (h *latencyHist) observe(val int64) {
+	secs := time.Duration(val).Seconds()
+	i := sort.SearchFloat64s(promLatencyBuckets, secs)
+	h.counts[i]++
+	h.sum += time.Duration(val)
+	h.n++
+}
+
+//
+// Prunner and Trunner
+//
+
+// WritePrometheus writes the proxy's stats in the Prometheus text format
+func (r *Prunner) WritePrometheus(w io.Writer) error {
+	pw := newPromWriter(w)
+	r.RLock()
+	r.Core.Tracker.writePrometheus(pw)
+	r.RUnlock()
+	pw.gauge("uptime_seconds", "uptime", time.Since(r.starttime).Seconds())
+	return pw.w.Flush()
+}
+
+// WritePrometheus writes the target's stats in the Prometheus text format
+func (r *Trunner) WritePrometheus(w io.Writer) error {
+	pw := newPromWriter(w)
+	r.RLock()
+	r.Core.Tracker.writePrometheus(pw)
+	pw.gauge("uptime_seconds", "uptime", time.Since(r.starttime).Seconds())
+
+	mpaths := make([]string, 0, len(r.Capacity))
+	for mpath := range r.Capacity {
+		mpaths = append(mpaths, mpath)
+	}
+	sort.Strings(mpaths)
+	capacity := []struct {
+		name, help string
+		val        func(c *fscapacity) float64
+	}{
+		{"capacity_total_bytes", "capacity.total", func(c *fscapacity) float64 { return float64(c.Total) }},
+		{"capacity_used_bytes", "capacity.used", func(c *fscapacity) float64 { return float64(c.Used) }},
+		{"capacity_avail_bytes", "capacity.avail", func(c *fscapacity) float64 { return float64(c.Avail) }},
+		{"capacity_used_percent", "capacity.usedpct", func(c *fscapacity) float64 { return float64(c.Usedpct) }},
+		{"capacity_reserved_bytes", "capacity.reserved", func(c *fscapacity) float64 { return float64(c.Reserved) }},
+		{"capacity_headroom_bytes", "capacity.headroom", func(c *fscapacity) float64 { return float64(c.Headroom) }},
+		{"capacity_pinned_bytes", "capacity.pinned", func(c *fscapacity) float64 { return float64(c.Pinned) }},
+	}
+	for _, m := range capacity {
+		for _, mpath := range mpaths {
+			pw.gauge(m.name, m.help, m.val(r.Capacity[mpath]), "mountpath", mpath)
+		}
+	}
+
+	mpaths = mpaths[:0]
+	for mpath := range r.Mountpaths {
+		mpaths = append(mpaths, mpath)
+	}
+	sort.Strings(mpaths)
+	for _, mpath := range mpaths {
+		pw.counter("mountpath_lru_evict_total", "mountpaths."+LruEvictCount, float64(r.Mountpaths[mpath].LruEvictCount), "mountpath", mpath)
+	}
+	for _, mpath := range mpaths {
+		pw.counter("mountpath_lru_evict_bytes_total", "mountpaths."+LruEvictSize, float64(r.Mountpaths[mpath].LruEvictSize), "mountpath", mpath)
+	}
+	for _, mpath := range mpaths {
+		pw.counter("mountpath_atime_flush_total", "mountpaths."+AtimeFlushCount, float64(r.Mountpaths[mpath].AtimeFlushCount), "mountpath", mpath)
+	}
+
+	if idle, err := strconv.ParseFloat(r.CPUidle, 64); err == nil {
+		pw.gauge("cpu_idle_percent", "cpuidle", idle)
+	}
+	r.writeDisks(pw)
+	r.writeFilesystems(pw)
+	r.RUnlock()
+	return pw.w.Flush()
+}
+
+func (r *Trunner) writeDisks(pw *promWriter) {
+	var (
+		disks   = make([]string, 0, len(r.Disk))
+		metrics = make(map[string]string, 16) // iostat metric => prometheus name
+	)
+	for disk, iometrics := range r.Disk {
+		disks = append(disks, disk)
+		for k := range iometrics {
+			metrics[k] = "disk_" + promName(k)
+		}
+	}
+	sort.Strings(disks)
+	names := make([]string, 0, len(metrics))
+	for k := range metrics {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, disk := range disks {
+			v, ok := r.Disk[disk][k]
+			if !ok {
+				continue
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				pw.gauge(metrics[k], "disk."+k, f, "disk", disk)
+			}
+		}
+	}
+}
+
+func (r *Trunner) writeFilesystems(pw *promWriter) {
+	fsnames := make([]string, 0, len(r.Filesystem))
+	for fsname := range r.Filesystem {
+		fsnames = append(fsnames, fsname)
+	}
+	sort.Strings(fsnames)
+	type fsmetric struct {
+		name, help string
+		val        float64
+	}
+	for _, fsname := range fsnames {
+		m := r.Filesystem[fsname]
+		for _, fm := range []fsmetric{
+			{"fs_util_max_percent", "filesystem.util.max", m.UtilMax},
+			{"fs_util_avg_percent", "filesystem.util.avg", m.UtilAvg},
+			{"fs_await_max_ms", "filesystem.await.max", m.AwaitMax},
+			{"fs_await_avg_ms", "filesystem.await.avg", m.AwaitAvg},
+			{"fs_read_mbps", "filesystem.rMB/s", m.ReadMBps},
+			{"fs_write_mbps", "filesystem.wMB/s", m.WriteMBps},
+			{"fs_iops", "filesystem.iops", m.IOPS},
+		} {
+			pw.gauge(fm.name, fm.help, fm.val, "filesystem", fsname)
+		}
+	}
+}
+
+//
+// statsTracker
+//
+
+func (stats statsTracker) writePrometheus(pw *promWriter) {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stat := stats[name]
+		switch {
+		case name == Uptime:
+			continue // reported by the runner
+		case stat.kind == statsKindLatency || stat.kind == statsKindHistogram:
+			if stat.hist == nil {
+				continue // unmarshaled
+			}
+			pw.histogram(promLatencyName(name), name, stat.hist)
+		case stat.kind == statsKindMinLatency || stat.kind == statsKindMaxLatency:
+			pw.gauge(promLatencyName(name), name, time.Duration(stat.Value).Seconds())
+		default:
+			if gauge, ok := promGauges[name]; ok {
+				pw.gauge(gauge, name, float64(stat.Value))
+			} else {
+				pw.counter(promCounterName(name), name, float64(stat.Value))
+			}
+		}
+	}
+}
+
+// get.n => get_total, get.cold.size => get_cold_bytes_total
+func promCounterName(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".n"):
+		return promName(strings.TrimSuffix(name, ".n")) + "_total"
+	case strings.HasSuffix(name, ".size"):
+		return promName(strings.TrimSuffix(name, ".size")) + "_bytes_total"
+	}
+	return promName(name) + "_total"
+}
+
+// get.lat => get_latency_seconds, kalive.lat.min => kalive_latency_min_seconds
+func promLatencyName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		if p == "lat" {
+			parts[i] = "latency"
+		}
+	}
+	return promName(strings.Join(parts, ".")) + "_seconds"
+}
+
+// promName makes a valid (and readable) metric name out of the stats or iostat name,
+// e.g.: %util => pct_util, rMB/s => rmb_per_sec, aqu-sz => aqu_sz
+func promName(name string) string {
+	name = strings.Replace(name, "%", "pct_", -1)
+	name = strings.Replace(name, "/s", "_per_sec", -1)
+	b := make([]rune, 0, len(name))
+	for _, c := range strings.ToLower(name) {
+		if c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+//
+// promWriter
+//
+
+func newPromWriter(w io.Writer) *promWriter {
+	return &promWriter{w: bufio.NewWriter(w)}
+}
+
+func (pw *promWriter) family(name, help, typ string) {
+	if pw.latest == name {
+		return
+	}
+	pw.latest = name
+	fmt.Fprintf(pw.w, "# HELP %s%s %s\n# TYPE %s%s %s\n", promPrefix, name, help, promPrefix, name, typ)
+}
+
+func (pw *promWriter) sample(name string, val float64, labels ...string) {
+	pw.w.WriteString(promPrefix + name)
+	if len(labels) > 0 {
+		pw.w.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				pw.w.WriteByte(',')
+			}
+			pw.w.WriteString(labels[i] + `="` + promEscaper.Replace(labels[i+1]) + `"`)
+		}
+		pw.w.WriteByte('}')
+	}
+	pw.w.WriteString(" " + strconv.FormatFloat(val, 'g', -1, 64) + "\n")
+}
+
+func (pw *promWriter) counter(name, help string, val float64, labels ...string) {
+	pw.family(name, help, "counter")
+	pw.sample(name, val, labels...)
+}
+
+func (pw *promWriter) gauge(name, help string, val float64, labels ...string) {
+	pw.family(name, help, "gauge")
+	pw.sample(name, val, labels...)
+}
+
+func (pw *promWriter) histogram(name, help string, h *latencyHist) {
+	pw.family(name, help, "histogram")
+	var cumulative int64
+	for i, le := range promLatencyBuckets {
+		cumulative += h.counts[i]
+		pw.sample(name+"_bucket", float64(cumulative), "le", strconv.FormatFloat(le, 'g', -1, 64))
+	}
+	pw.sample(name+"_bucket", float64(h.n), "le", "+Inf")
+	pw.sample(name+"_sum", h.sum.Seconds())
+	pw.sample(name+"_count", float64(h.n))
+}
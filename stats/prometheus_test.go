@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/ios"
+)
+
+func TestPrometheusNames(t *testing.T) {
+	tests := []struct{ name, expected string }{
+		{promCounterName(GetCount), "get_total"},
+		{promCounterName(GetColdSize), "get_cold_bytes_total"},
+		{promCounterName(ErrGetCount), "err_get_total"},
+		{promLatencyName(GetLatency), "get_latency_seconds"},
+		{promLatencyName(KeepAliveMinLatency), "kalive_latency_min_seconds"},
+		{promName("%util"), "pct_util"},
+		{promName("rMB/s"), "rmb_per_sec"},
+		{promName("aqu-sz"), "aqu_sz"},
+	}
+	for _, test := range tests {
+		if test.name != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, test.name)
+		}
+	}
+}
+
+func TestPrometheusTarget(t *testing.T) {
+	r := &Trunner{
+		Core:        &targetCoreStats{},
+		Capacity:    map[string]*fscapacity{"/mp1": {Total: 1000, Used: 400, Avail: 600, Usedpct: 40}},
+		Mountpaths:  map[string]*mpathStats{"/mp1": {LruEvictCount: 3}},
+		CPUidle:     "97.5",
+		Disk:        map[string]cmn.SimpleKVs{"sda": {"%util": "12.5", "rMB/s": "3"}},
+		Filesystem:  map[string]ios.FSMetrics{"/dev/sda1": {Disks: 1, UtilMax: 12.5, IOPS: 100}},
+		statsrunner: statsrunner{starttime: time.Now()},
+	}
+	r.Setconf(&cmn.Config{})
+	r.Core.initStatsTracker()
+	r.Core.Sink = NopSink{}
+
+	r.Core.doAdd(GetCount, 2)
+	r.Core.doAdd(GetColdSize, 1024)
+	r.Core.doAdd(AtimeMapSize, 5)
+	r.Core.doAdd(AtimeMapSize, -2)
+	for _, ms := range []int64{1, 20, 20000, 60000} {
+		r.Core.doAdd(GetLatency, ms*int64(time.Millisecond))
+	}
+	r.Core.doAdd(KeepAliveMaxLatency, int64(250*time.Millisecond))
+	r.Core.Tracker.resetLatencies() // does not affect the histograms
+
+	buf := &bytes.Buffer{}
+	if err := r.WritePrometheus(buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, expected := range []string{
+		"# TYPE dfc_get_total counter\ndfc_get_total 2\n",
+		"dfc_get_cold_bytes_total 1024\n",
+		"# TYPE dfc_atime_map_entries gauge\ndfc_atime_map_entries 3\n",
+		"# TYPE dfc_get_latency_seconds histogram\n",
+		`dfc_get_latency_seconds_bucket{le="0.001"} 1` + "\n",
+		`dfc_get_latency_seconds_bucket{le="0.025"} 2` + "\n",
+		`dfc_get_latency_seconds_bucket{le="30"} 3` + "\n",
+		`dfc_get_latency_seconds_bucket{le="+Inf"} 4` + "\n",
+		"dfc_get_latency_seconds_sum 80.021\n",
+		"dfc_get_latency_seconds_count 4\n",
+		"# TYPE dfc_kalive_latency_max_seconds gauge\ndfc_kalive_latency_max_seconds 0\n",
+		"# TYPE dfc_uptime_seconds gauge\n",
+		`dfc_capacity_used_percent{mountpath="/mp1"} 40` + "\n",
+		`dfc_capacity_avail_bytes{mountpath="/mp1"} 600` + "\n",
+		`dfc_mountpath_lru_evict_total{mountpath="/mp1"} 3` + "\n",
+		"dfc_cpu_idle_percent 97.5\n",
+		`dfc_disk_pct_util{disk="sda"} 12.5` + "\n",
+		`dfc_disk_rmb_per_sec{disk="sda"} 3` + "\n",
+		`dfc_fs_iops{filesystem="/dev/sda1"} 100` + "\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "dfc_uptime_latency") {
+		t.Errorf("uptime must be reported as a gauge only:\n%s", out)
+	}
+	// each family is declared once
+	for _, ln := range strings.Split(out, "\n") {
+		if strings.HasPrefix(ln, "# TYPE ") && strings.Count(out, ln+"\n") != 1 {
+			t.Errorf("%q is declared more than once", ln)
+		}
+	}
+}
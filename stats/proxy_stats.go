@@ -6,46 +6,96 @@ package stats
 import (
 	"time"
 
-	"github.com/NVIDIA/dfcpub/3rdparty/glog"
 	"github.com/NVIDIA/dfcpub/cmn"
 	"github.com/NVIDIA/dfcpub/stats/statsd"
 	jsoniter "github.com/json-iterator/go"
 )
 
+// Proxy-only stats
+const (
+	ListWaitLatency    = "lst.wait.lat"   // list-bucket requests waiting for their turn (endpoint_limits)
+	StatsWaitLatency   = "stats.wait.lat" // ditto, cluster stats
+	XactionWaitLatency = "xact.wait.lat"  // ditto, xaction stats
+	ErrLimitCount      = "err.limit.n"    // requests that timed out waiting
+)
+
 type (
 	ProxyCoreStats struct {
 		Tracker statsTracker
 		// omitempty
-		StatsdC *statsd.Client
-		logged  bool
+		Sink        MetricsSink    // see sink.go
+		LatencyUnit *time.Duration // output unit of the latency stats (default: microsecond)
+		logged      bool
+		buckets     bucketTrackers // per-bucket breakdown - see bucket_breakdown.go
+		bucket      string         // the bucket of the stat being added, if any: tagged in StatsD
 	}
 	Prunner struct {
 		statsrunner
-		Core *ProxyCoreStats `json:"core"`
+		Core   *ProxyCoreStats `json:"core"`
+		StatsD *statsd.Backlog `json:"statsd,omitempty"` // metrics not sent to unreachable statsd
 	}
 	ClusterStats struct {
-		Proxy  *ProxyCoreStats     `json:"proxy"`
-		Target map[string]*Trunner `json:"target"`
+		Proxy   *ProxyCoreStats     `json:"proxy"`
+		Target  map[string]*Trunner `json:"target"`
+		Buckets cmn.StatsPerBucket  `json:"buckets,omitempty"` // summed over all targets (buckets=true)
 	}
 	ClusterStatsRaw struct {
-		Proxy  *ProxyCoreStats                `json:"proxy"`
-		Target map[string]jsoniter.RawMessage `json:"target"`
+		Proxy   *ProxyCoreStats                `json:"proxy"`
+		Target  map[string]jsoniter.RawMessage `json:"target"`
+		Buckets cmn.StatsPerBucket             `json:"buckets,omitempty"`
 	}
 )
 
 func (p *ProxyCoreStats) initStatsTracker() {
 	p.Tracker = statsTracker(map[string]*statsInstance{})
 	p.Tracker.registerCommonStats()
+	p.Tracker.register(ListWaitLatency, statsKindLatency)
+	p.Tracker.register(StatsWaitLatency, statsKindLatency)
+	p.Tracker.register(XactionWaitLatency, statsKindLatency)
+	p.Tracker.register(ErrLimitCount, statsKindCounter)
+}
+
+func (p *ProxyCoreStats) latencyUnit() time.Duration {
+	if p.LatencyUnit == nil || *p.LatencyUnit == 0 {
+		return time.Microsecond
+	}
+	return *p.LatencyUnit
 }
 
 func (p *ProxyCoreStats) MarshalJSON() ([]byte, error) {
-	return jsoniter.Marshal(p.Tracker)
+	return p.Tracker.marshal(p.latencyUnit())
 }
 
 func (p *ProxyCoreStats) UnmarshalJSON(b []byte) error {
 	return jsoniter.Unmarshal(b, &p.Tracker)
 }
 
+// sendPercentiles sends the percentiles of the stats period to the sink, as timers;
+// under lock, prior to resetting the latencies
+func (p *ProxyCoreStats) sendPercentiles() {
+	for name, stat := range p.Tracker {
+		if stat.kind != statsKindHistogram || stat.hdr.n == 0 {
+			continue
+		}
+		metrics := make([]metric, 0, len(histPercentiles))
+		for _, pct := range histPercentiles {
+			ms := float64(stat.hdr.percentile(pct.q)) / float64(time.Millisecond)
+			metrics = append(metrics, metric{Type: statsd.Timer, Name: pct.suffix[1:], Value: ms})
+		}
+		p.Sink.Send(name, metrics...)
+	}
+}
+
+// send sends the metrics to the sink tagged with the bucket, if any (and if configured - see
+// statsd.Args.TagFormat and cmn.GraphiteConf)
+func (p *ProxyCoreStats) send(name string, metrics ...metric) {
+	if p.bucket == "" {
+		p.Sink.Send(name, metrics...)
+		return
+	}
+	p.Sink.SendTags(name, []statsd.Tag{{Key: "bucket", Value: p.bucket}}, metrics...)
+}
+
 //
 // Prunner
 //
@@ -60,53 +110,71 @@ func (r *Prunner) Init() {
 // statslogger interface impl
 func (r *Prunner) log() (runlru bool) {
 	r.Lock()
+	r.StatsD = sinkBacklog(r.Core.Sink)
 	if r.Core.logged {
 		r.Unlock()
 		return
 	}
-	for _, v := range r.Core.Tracker {
-		if v.kind == statsKindLatency && v.associatedVal > 0 {
-			v.Value /= v.associatedVal
-		}
-	}
-	b, err := jsoniter.Marshal(r.Core)
-
-	// reset all the latency stats only
-	for _, v := range r.Core.Tracker {
-		if v.kind == statsKindLatency {
-			v.Value = 0
-			v.associatedVal = 0
+	var (
+		lines  []string
+		config = r.Getconf()
+		rec    = r.slog.begin(&config.Periodic, "proxy")
+	)
+	if out := r.slog.compactCore(&config.Periodic, r.Core.Tracker.outputs(r.Core.latencyUnit())); out != nil {
+		rec.Core = out
+		if b, err := jsoniter.Marshal(out); err == nil {
+			lines = append(lines, string(b))
 		}
 	}
+	r.Core.sendPercentiles()
+	r.Core.Tracker.resetLatencies()
+	r.Core.logged = true
+	lines = r.slog.output(&config.Periodic, rec, lines)
 	r.Unlock()
 
-	if err == nil {
-		glog.Infoln(string(b))
-		r.Core.logged = true
-	}
+	r.slog.write(config, lines)
 	return
 }
 
-func (r *Prunner) doAdd(nv NamedVal64) {
+func (r *Prunner) doAdd(nv NamedVal64, bucket string) {
 	r.Lock()
 	s := r.Core
+	s.bucket = bucket
 	s.doAdd(nv.Name, nv.Val)
+	s.bucket = ""
+	if bucket != "" {
+		s.buckets.add(bucket, nv.Name, nv.Val)
+	}
+	r.track(nv.Name, nv.Val)
 	r.Unlock()
 }
 
+// History returns the proxy's stats history - see Trunner.History
+func (r *Prunner) History(names []string, res, within, until time.Duration) *cmn.StatsHistory {
+	return r.history(r.Core.Tracker, r.Core.latencyUnit(), names, res, within, until)
+}
+
 func (s *ProxyCoreStats) doAdd(name string, val int64) {
 	if v, ok := s.Tracker[name]; !ok {
 		cmn.Assert(false, "Invalid stats name "+name)
-	} else if v.kind == statsKindLatency {
-		s.Tracker[name].associatedVal++
-		s.StatsdC.Send(name,
+	} else if v.kind == statsKindLatency || v.kind == statsKindHistogram {
+		v.associatedVal++
+		v.hist.observe(val)
+		if v.hdr != nil {
+			v.hdr.record(val)
+		}
+		s.send(name,
 			metric{statsd.Counter, "count", 1},
-			metric{statsd.Timer, "latency", float64(time.Duration(val) / time.Millisecond)})
-		val = int64(time.Duration(val) / time.Microsecond)
+			metric{statsd.Timer, "latency", float64(val) / float64(time.Millisecond)})
+	} else if v.kind == statsKindMinLatency || v.kind == statsKindMaxLatency {
+		// not sent to StatsD that computes its own lower/upper of the timers
+		v.minmax(val)
+		s.logged = false
+		return
 	} else {
 		switch name {
 		case PostCount, DeleteCount, RenameCount:
-			s.StatsdC.Send(name, metric{statsd.Counter, "count", val})
+			s.send(name, metric{statsd.Counter, "count", val})
 		}
 	}
 	s.Tracker[name].Value += val
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats/statsd"
+)
+
+// Metrics sinks: the proxy and the target send their metrics - the stats as they are
+// added, the latency percentiles, iostat, keepalive - to the MetricsSink selected by the
+// metrics.sink configuration: "statsd" (default), "graphite", or "none". Other sinks (e.g.,
+// an OpenTelemetry exporter) register with RegisterSink, from a file of their own (and
+// under a build tag of their own, if they bring in dependencies), and are selected by the
+// name they register under. The sink does not affect the logs, Prometheus (GET /metrics),
+// and the stats API.
+
+// Metrics sinks (metrics.sink)
+const (
+	SinkStatsD   = "statsd"
+	SinkGraphite = "graphite"
+	SinkNone     = "none"
+)
+
+type (
+	// MetricsSink receives the metrics, by name, e.g. Send("get", {Counter, "count", 1})
+	MetricsSink interface {
+		Send(name string, metrics ...statsd.Metric)
+		// SendTags is Send with the tags, e.g. the bucket; sinks that do not support
+		// tags ignore them
+		SendTags(name string, tags []statsd.Tag, metrics ...statsd.Metric)
+		Close() error
+	}
+	// SinkArgs are the arguments of the sink's constructor
+	SinkArgs struct {
+		Daemon   string // "dfcproxy" or "dfctarget"
+		DaemonID string
+		Config   *cmn.Config
+	}
+	// NewSinkFunc creates the sink; if the sink is usable despite the error (e.g., it
+	// keeps trying to reach its server), it returns both
+	NewSinkFunc func(args SinkArgs) (MetricsSink, error)
+
+	// NopSink discards the metrics
+	NopSink struct{}
+
+	// backlogger is implemented by the sinks that keep the metrics while their server is
+	// unreachable - see statsd.Client
+	backlogger interface {
+		Backlog() *statsd.Backlog
+	}
+)
+
+var (
+	sinksMtx sync.Mutex
+	sinks    = map[string]NewSinkFunc{
+		SinkStatsD:   newStatsDSink,
+		SinkGraphite: newGraphiteSink,
+		SinkNone:     func(SinkArgs) (MetricsSink, error) { return NopSink{}, nil },
+	}
+)
+
+func (NopSink) Send(string, ...statsd.Metric)                   {}
+func (NopSink) SendTags(string, []statsd.Tag, ...statsd.Metric) {}
+func (NopSink) Close() error                                    { return nil }
+
+// RegisterSink makes the sink selectable by name (metrics.sink); must be called before the
+// configuration is loaded, e.g. from init()
+func RegisterSink(name string, newSink NewSinkFunc) {
+	sinksMtx.Lock()
+	sinks[name] = newSink
+	sinksMtx.Unlock()
+}
+
+// SinkNames returns the names of the registered sinks, sorted
+func SinkNames() []string {
+	sinksMtx.Lock()
+	names := make([]string, 0, len(sinks))
+	for name := range sinks {
+		names = append(names, name)
+	}
+	sinksMtx.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// NewSink creates the named sink; empty name - StatsD. Never returns nil: an unknown sink
+// is NopSink, along with the error
+func NewSink(name string, args SinkArgs) (MetricsSink, error) {
+	if name == "" {
+		name = SinkStatsD
+	}
+	sinksMtx.Lock()
+	newSink, ok := sinks[name]
+	sinksMtx.Unlock()
+	if !ok {
+		return NopSink{}, fmt.Errorf("unknown metrics sink %q (expecting one of: %s)", name, strings.Join(SinkNames(), ", "))
+	}
+	sink, err := newSink(args)
+	if sink == nil {
+		sink = NopSink{}
+	}
+	return sink, err
+}
+
+// sinkBacklog returns the sink's backlog, if any
+func sinkBacklog(sink MetricsSink) *statsd.Backlog {
+	if bl, ok := sink.(backlogger); ok {
+		return bl.Backlog()
+	}
+	return nil
+}
+
+// daemonPrefix is the prefix of the daemon's metrics: with tags, the daemon's ID is sent as
+// the "daemon" tag rather than as part of the prefix
+func daemonPrefix(args SinkArgs, tagged bool) (prefix string, tags []statsd.Tag) {
+	if tagged {
+		return args.Daemon, []statsd.Tag{{Key: "daemon", Value: args.DaemonID}}
+	}
+	return args.Daemon + "." + strings.Replace(args.DaemonID, ":", "_", -1), nil
+}
+
+// newStatsDSink creates the StatsD client (StatsD on 8125 by default -
+// https://github.com/etsy/statsd) as per the statsd config
+func newStatsDSink(args SinkArgs) (MetricsSink, error) {
+	conf := &args.Config.StatsD
+	host, portStr, _ := net.SplitHostPort(conf.Address) // validated along with the config
+	port, _ := strconv.Atoi(portStr)
+	sargs := statsd.Args{
+		Host:            host,
+		Port:            port,
+		FlushInterval:   conf.FlushInterval,
+		MaxPacketSize:   conf.MaxPacketSize,
+		TagFormat:       conf.Tags,
+		ResolveInterval: conf.ResolveInterval,
+	}
+	sargs.Prefix, sargs.Tags = daemonPrefix(args, conf.Tags != statsd.TagsNone)
+	c, err := statsd.NewArgs(sargs)
+	if err != nil {
+		return c, fmt.Errorf("failed to resolve StatsD at %s (will keep trying), err: %v", conf.Address, err)
+	}
+	return c, nil
+}
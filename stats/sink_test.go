@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"bufio"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/stats/statsd"
+)
+
+type testSink struct {
+	NopSink
+	sent []string
+}
+
+func (s *testSink) Send(name string, metrics ...statsd.Metric) {
+	for _, m := range metrics {
+		s.sent = append(s.sent, name+"."+m.Name)
+	}
+}
+
+func TestMetricsSinks(t *testing.T) {
+	sink, err := NewSink("nonexisting", SinkArgs{})
+	if err == nil {
+		t.Error("expected unknown sink error")
+	}
+	if _, ok := sink.(NopSink); !ok {
+		t.Errorf("expected NopSink, got %T", sink)
+	}
+	if sink, err = NewSink(SinkNone, SinkArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	sink.Send("get", statsd.Metric{Type: statsd.Counter, Name: "count", Value: 1})
+	if sinkBacklog(sink) != nil {
+		t.Error("expected no backlog")
+	}
+
+	ts := &testSink{}
+	RegisterSink("test", func(SinkArgs) (MetricsSink, error) { return ts, nil })
+	names := SinkNames()
+	if i := sort.SearchStrings(names, "test"); i == len(names) || names[i] != "test" {
+		t.Fatalf("expected the registered sink in %v", names)
+	}
+	if sink, err = NewSink("test", SinkArgs{}); err != nil || sink != ts {
+		t.Fatalf("expected the registered sink, got %v, err: %v", sink, err)
+	}
+
+	p := &ProxyCoreStats{Sink: sink}
+	p.initStatsTracker()
+	p.doAdd(GetLatency, int64(time.Millisecond))
+	if len(ts.sent) == 0 || !strings.HasPrefix(ts.sent[0], "get.") {
+		t.Errorf("expected the stats sent to the registered sink, got %v", ts.sent)
+	}
+}
+
+func TestGraphiteSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	linesCh := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			linesCh <- nil
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		linesCh <- lines
+	}()
+
+	config := &cmn.Config{}
+	config.Metrics.Graphite = cmn.GraphiteConf{Address: ln.Addr().String(), Tags: true, FlushInterval: time.Hour}
+	sink, err := NewSink(SinkGraphite, SinkArgs{Daemon: "dfctarget", DaemonID: "t:8081", Config: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Send("get", statsd.Metric{Type: statsd.Counter, Name: "count", Value: int64(2)},
+		statsd.Metric{Type: statsd.Timer, Name: "latency", Value: 1.0})
+	sink.Send("get", statsd.Metric{Type: statsd.Counter, Name: "count", Value: int64(3)},
+		statsd.Metric{Type: statsd.Timer, Name: "latency", Value: 3.0})
+	sink.Send("iostat_sda", statsd.Metric{Type: statsd.Gauge, Name: "%util", Value: "10"},
+		statsd.Metric{Type: statsd.Gauge, Name: "%util", Value: "20"})
+	sink.SendTags("put", []statsd.Tag{{Key: "bucket", Value: "abc"}}, statsd.Metric{Type: statsd.Counter, Name: "count", Value: 1})
+	if err := sink.Close(); err != nil { // flushes
+		t.Fatal(err)
+	}
+
+	var lines []string
+	select {
+	case lines = <-linesCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the metrics")
+	}
+	values := make(map[string]string, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			t.Fatalf("invalid line %q", line)
+		}
+		values[fields[0]] = fields[1]
+	}
+	expected := map[string]string{
+		"dfctarget.get.count;daemon=t:8081":            "5",
+		"dfctarget.get.latency;daemon=t:8081":          "2",
+		"dfctarget.iostat_sda.%util;daemon=t:8081":     "20",
+		"dfctarget.put.count;daemon=t:8081;bucket=abc": "1",
+	}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d metrics, got %v", len(expected), lines)
+	}
+	for series, v := range expected {
+		if values[series] != v {
+			t.Errorf("%s: expected %s, got %q", series, v, values[series])
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// Service level objectives (slo.objectives) are evaluated out of the same named values
+// that update the stats: each objective classifies the values of its stats into good
+// and bad events and counts them per minute over the compliance window (slo.window).
+
+const sloSlotSize = time.Minute
+
+type (
+	sloTracker struct {
+		window     time.Duration
+		objectives []*sloObjective
+	}
+	sloObjective struct {
+		cmn.SLOObjective
+		slots []sloSlot // ring buffer of per-minute events that covers the compliance window
+	}
+	sloSlot struct {
+		cmn.SLOEvents
+		n int64 // minutes since the epoch
+	}
+)
+
+func newSLOTracker(conf *cmn.SLOConf) *sloTracker {
+	tr := &sloTracker{window: conf.Window, objectives: make([]*sloObjective, 0, len(conf.Objectives))}
+	nslots := int(conf.Window / sloSlotSize)
+	if nslots < 1 {
+		nslots = 1
+	}
+	for _, obj := range conf.Objectives {
+		tr.objectives = append(tr.objectives, &sloObjective{SLOObjective: obj, slots: make([]sloSlot, nslots)})
+	}
+	return tr
+}
+
+func (tr *sloTracker) add(name string, val int64, now time.Time) {
+	if tr == nil {
+		return
+	}
+	for _, obj := range tr.objectives {
+		var good, bad int64
+		switch {
+		case obj.Kind == cmn.SLOKindLatency && name == obj.Stat:
+			if time.Duration(val) < obj.Threshold {
+				good = 1
+			} else {
+				bad = 1
+			}
+		case obj.Kind == cmn.SLOKindErrorRate && name == obj.Stat:
+			good = val
+		case obj.Kind == cmn.SLOKindErrorRate && name == obj.ErrStat:
+			bad = val
+		default:
+			continue
+		}
+		slot := obj.slot(now)
+		slot.Good += good
+		slot.Bad += bad
+	}
+}
+
+func (tr *sloTracker) report(now time.Time) *cmn.SLOReport {
+	rep := &cmn.SLOReport{Time: now, Objectives: make([]cmn.SLOStatus, 0, 4)}
+	if tr == nil {
+		return rep
+	}
+	for _, obj := range tr.objectives {
+		status := cmn.SLOStatus{
+			Name:     obj.Name,
+			Target:   obj.Target,
+			Window:   tr.window,
+			Events:   obj.events(tr.window, now),
+			Events1h: obj.events(time.Hour, now),
+			Events5m: obj.events(5*time.Minute, now),
+		}
+		status.Eval()
+		rep.Objectives = append(rep.Objectives, status)
+	}
+	return rep
+}
+
+func (obj *sloObjective) slot(now time.Time) *sloSlot {
+	n := now.UnixNano() / int64(sloSlotSize)
+	slot := &obj.slots[n%int64(len(obj.slots))]
+	if slot.n != n {
+		*slot = sloSlot{n: n}
+	}
+	return slot
+}
+
+// events sums up the events within the given duration, the current minute included
+func (obj *sloObjective) events(within time.Duration, now time.Time) (ev cmn.SLOEvents) {
+	n := now.UnixNano() / int64(sloSlotSize)
+	from := n - int64(within/sloSlotSize)
+	for _, slot := range obj.slots {
+		if slot.n > from && slot.n <= n {
+			ev.Good += slot.Good
+			ev.Bad += slot.Bad
+		}
+	}
+	return
+}
+
+// SLO returns the current state of the daemon's service level objectives
+func (r *statsrunner) SLO() *cmn.SLOReport {
+	r.RLock()
+	defer r.RUnlock()
+	return r.slo.report(time.Now())
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestSLOTracker(t *testing.T) {
+	tr := newSLOTracker(&cmn.SLOConf{
+		Window: 2 * time.Hour,
+		Objectives: []cmn.SLOObjective{
+			{Name: "lat", Kind: cmn.SLOKindLatency, Stat: GetLatency, Threshold: 50 * time.Millisecond, Target: 0.9},
+			{Name: "err", Kind: cmn.SLOKindErrorRate, Stat: GetCount, ErrStat: ErrGetCount, Target: 0.99},
+		},
+	})
+	now := time.Now()
+	// 90 minutes ago: 10 slow GETs out of 20, all succeeded
+	past := now.Add(-90 * time.Minute)
+	for i := 0; i < 20; i++ {
+		lat := 10 * time.Millisecond
+		if i%2 == 0 {
+			lat = 100 * time.Millisecond
+		}
+		tr.add(GetLatency, int64(lat), past)
+		tr.add(GetCount, 1, past)
+	}
+	// just now: 80 fast GETs, 2 failed
+	for i := 0; i < 80; i++ {
+		tr.add(GetLatency, int64(time.Millisecond), now)
+	}
+	tr.add(GetCount, 80, now)
+	tr.add(ErrGetCount, 2, now)
+	tr.add(PutLatency, int64(time.Second), now) // not an objective's stat
+
+	rep := tr.report(now)
+	lat, errs := rep.Objectives[0], rep.Objectives[1]
+	if lat.Events != (cmn.SLOEvents{Good: 90, Bad: 10}) || lat.Events1h != (cmn.SLOEvents{Good: 80}) {
+		t.Errorf("unexpected latency events: %+v", lat)
+	}
+	if !lat.Met || lat.BudgetLeft > 1e-9 || lat.BurnRate5m != 0 {
+		t.Errorf("expected the latency objective met with the budget exhausted: %+v", lat)
+	}
+	if errs.Events != (cmn.SLOEvents{Good: 100, Bad: 2}) || errs.Met {
+		t.Errorf("expected the error rate objective not met: %+v", errs)
+	}
+	if burn := 2.0 / 82 / 0.01; errs.BurnRate5m < burn-1e-9 || errs.BurnRate5m > burn+1e-9 {
+		t.Errorf("expected 5m burn rate %v, got %v", burn, errs.BurnRate5m)
+	}
+
+	// the events fall out of the window
+	rep = tr.report(now.Add(3 * time.Hour))
+	if rep.Objectives[0].Events != (cmn.SLOEvents{}) || !rep.Objectives[0].Met || rep.Objectives[0].BudgetLeft != 1 {
+		t.Errorf("expected no events within the window: %+v", rep.Objectives[0])
+	}
+	// the ring buffer wraps around
+	later := now.Add(2 * time.Hour)
+	tr.add(GetLatency, int64(time.Second), later)
+	if ev := tr.report(later).Objectives[0].Events; ev != (cmn.SLOEvents{Bad: 1}) {
+		t.Errorf("expected a single bad event after the wrap-around, got %+v", ev)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+// Snapshots of the counters for the external pollers (GET /v1/daemon?what=stats&snapshot=true).
+// Unlike the stats that are logged - where the latencies are the averages of the current stats
+// period, reset by each log() - the snapshot's latencies are the number of samples and their
+// sum, cumulative (latencyHist), so that a poller never races the stats period. The snapshot
+// is taken under the runner's lock and is, therefore, consistent.
+//
+// Reset starts the snapshot's counters anew by moving the baseline the snapshot is counted
+// from; the daemon's own counters - logged, sent to StatsD, and exposed to Prometheus - remain
+// cumulative and are not affected.
+
+type statsSnapshot struct {
+	since     time.Time
+	counters  map[string]int64               // baseline: the counters at the last reset
+	latencies map[string]cmn.LatencySnapshot // ditto, latencies
+}
+
+// snapshot returns the snapshot of the tracker and, if requested, resets it; under lock
+func (r *statsrunner) snapshot(tracker statsTracker, reset bool) *cmn.StatsSnapshot {
+	var (
+		base = &r.snap
+		now  = time.Now()
+		out  = &cmn.StatsSnapshot{
+			Since:     base.since,
+			Until:     now,
+			Counters:  make(map[string]int64, len(tracker)),
+			Gauges:    make(map[string]int64, len(promGauges)),
+			Latencies: make(map[string]cmn.LatencySnapshot, 16),
+		}
+	)
+	if out.Since.IsZero() {
+		out.Since = r.starttime
+	}
+	for name, stat := range tracker {
+		switch {
+		case name == Uptime:
+		case stat.kind == statsKindCounter:
+			if _, ok := promGauges[name]; ok {
+				out.Gauges[name] = stat.Value
+			} else {
+				out.Counters[name] = stat.Value - base.counters[name]
+			}
+		case stat.hist != nil:
+			b := base.latencies[name]
+			out.Latencies[name] = cmn.LatencySnapshot{Count: stat.hist.n - b.Count, Sum: int64(stat.hist.sum) - b.Sum}
+		}
+	}
+	if reset {
+		base.since = now
+		if base.counters == nil {
+			base.counters = make(map[string]int64, len(out.Counters))
+			base.latencies = make(map[string]cmn.LatencySnapshot, len(out.Latencies))
+		}
+		for name, val := range out.Counters {
+			base.counters[name] += val
+		}
+		for name, l := range out.Latencies {
+			b := base.latencies[name]
+			base.latencies[name] = cmn.LatencySnapshot{Count: b.Count + l.Count, Sum: b.Sum + l.Sum}
+		}
+	}
+	return out
+}
+
+// GetSnapshot returns the snapshot of the proxy's counters; with reset, starts them anew
+func (r *Prunner) GetSnapshot(reset bool) *cmn.StatsSnapshot {
+	r.Lock()
+	defer r.Unlock()
+	return r.snapshot(r.Core.Tracker, reset)
+}
+
+// GetSnapshot returns the snapshot of the target's counters; with reset, starts them anew
+func (r *Trunner) GetSnapshot(reset bool) *cmn.StatsSnapshot {
+	r.Lock()
+	defer r.Unlock()
+	return r.snapshot(r.Core.Tracker, reset)
+}
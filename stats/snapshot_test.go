@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestSnapshot(t *testing.T) {
+	r := &Trunner{Core: &targetCoreStats{}}
+	r.Setconf(&cmn.Config{})
+	r.Core.initStatsTracker()
+	r.Core.Sink = NopSink{}
+	r.starttime = time.Now().Add(-time.Minute)
+
+	r.Core.doAdd(GetCount, 3)
+	r.Core.doAdd(GetLatency, int64(10*time.Millisecond))
+	r.Core.doAdd(GetLatency, int64(30*time.Millisecond))
+	r.Core.doAdd(AtimeMapSize, 100)
+
+	snap := r.GetSnapshot(false)
+	if !snap.Since.Equal(r.starttime) || snap.Counters[GetCount] != 3 || snap.Gauges[AtimeMapSize] != 100 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+	if l := snap.Latencies[GetLatency]; l.Count != 2 || l.Sum != int64(40*time.Millisecond) {
+		t.Fatalf("expected 2 GET latency samples totaling 40ms, got %+v", l)
+	}
+	if _, ok := snap.Counters[AtimeMapSize]; ok {
+		t.Errorf("expected the gauge %s not among the counters", AtimeMapSize)
+	}
+
+	// the stats period ends: the latencies of the snapshot are cumulative
+	r.Core.Tracker.resetLatencies()
+	snap = r.GetSnapshot(true)
+	if l := snap.Latencies[GetLatency]; l.Count != 2 || l.Sum != int64(40*time.Millisecond) {
+		t.Fatalf("expected the latencies unaffected by the stats period, got %+v", l)
+	}
+
+	// reset: the snapshot starts anew, the daemon's own counters do not
+	r.Core.doAdd(GetCount, 1)
+	r.Core.doAdd(GetLatency, int64(5*time.Millisecond))
+	r.Core.doAdd(AtimeMapSize, -40)
+	next := r.GetSnapshot(false)
+	if !next.Since.Equal(snap.Until) || next.Counters[GetCount] != 1 || next.Gauges[AtimeMapSize] != 60 {
+		t.Fatalf("unexpected snapshot after the reset %+v", next)
+	}
+	if l := next.Latencies[GetLatency]; l.Count != 1 || l.Sum != int64(5*time.Millisecond) {
+		t.Fatalf("expected a single 5ms sample after the reset, got %+v", l)
+	}
+	if r.Core.Tracker[GetCount].Value != 4 {
+		t.Errorf("expected the cumulative %s 4, got %d", GetCount, r.Core.Tracker[GetCount].Value)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+
+package statsd
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+)
+
+// When statsd is unreachable - the (connected) UDP socket reports a failure to send - the
+// client stops sending and aggregates the metrics instead: counters are summed up, gauges
+// keep the last value, and timers - the mean and the number of samples. The backlog is
+// bounded (maxBacklog distinct metrics; the samples of the metrics that do not fit are
+// counted as dropped) and is reported by the daemon's stats (see Client.Backlog) for as
+// long as statsd is down. The client probes statsd every retryInterval and, once it is
+// back, sends the backlog - a single packet per metric, timers with the sample rate that
+// makes statsd account for all the samples. The same applies while the statsd address is
+// yet to be resolved: the client resolves it every retryInterval - see NewArgs.
+
+const maxBacklog = 4096
+
+var (
+	retryInterval = 10 * time.Second
+	probeDelay    = 100 * time.Millisecond // the time for the ICMP "port unreachable" to arrive
+)
+
+type (
+	// Backlog is the summary of the metrics aggregated while statsd is unreachable
+	Backlog struct {
+		Since   time.Time          `json:"since"`   // statsd is unreachable since
+		Dropped int64              `json:"dropped"` // samples that did not fit
+		Metrics map[string]float64 `json:"metrics"` // counters: sum; gauges: last; timers: mean
+	}
+	backlog struct {
+		mtx     sync.Mutex
+		isdown  int32 // atomic
+		since   time.Time
+		dropped int64
+		aggs    map[aggKey]*aggregate
+		stopCh  chan struct{}
+		retry   time.Duration
+		delay   time.Duration
+	}
+	aggKey struct {
+		name string // bucket.name
+		tags string // formatted
+		typ  MetricType
+	}
+	aggregate struct {
+		sum, last float64
+		n         int64
+	}
+)
+
+func newBacklog() *backlog {
+	return &backlog{
+		aggs:   make(map[aggKey]*aggregate),
+		stopCh: make(chan struct{}),
+		retry:  retryInterval,
+		delay:  probeDelay,
+	}
+}
+
+func (bl *backlog) isDown() bool { return atomic.LoadInt32(&bl.isdown) != 0 }
+
+func (bl *backlog) down() {
+	bl.mtx.Lock()
+	if atomic.CompareAndSwapInt32(&bl.isdown, 0, 1) {
+		bl.since = time.Now()
+		glog.Warningln("statsd is unreachable: aggregating metrics until it is back")
+	}
+	bl.mtx.Unlock()
+}
+
+func (bl *backlog) stop() { close(bl.stopCh) }
+
+// add aggregates the metrics; returns false if statsd is back, and so the metrics
+// are to be sent
+func (bl *backlog) add(bucket, tags string, metrics []Metric) bool {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+	if !bl.isDown() {
+		return false
+	}
+	for _, m := range metrics {
+		v, ok := toFloat(m.Value)
+		if !ok || m.Type > Gauge {
+			continue
+		}
+		key := aggKey{name: bucket + "." + m.Name, tags: tags, typ: m.Type}
+		a, ok := bl.aggs[key]
+		if !ok {
+			if len(bl.aggs) >= maxBacklog {
+				bl.dropped++
+				continue
+			}
+			a = &aggregate{}
+			bl.aggs[key] = a
+		}
+		a.sum += v
+		a.last = v
+		a.n++
+	}
+	return true
+}
+
+func (a *aggregate) value(typ MetricType) float64 {
+	switch typ {
+	case Counter:
+		return a.sum
+	case Gauge:
+		return a.last
+	default:
+		return a.sum / float64(a.n)
+	}
+}
+
+func (c Client) packet(a *aggregate, key aggKey) []byte {
+	var (
+		v    = strconv.FormatFloat(a.value(key.typ), 'f', -1, 64)
+		rate string
+	)
+	if key.typ == Timer && a.n > 1 {
+		rate = fmt.Sprintf("@%g", 1/float64(a.n))
+	}
+	return c.s.line(c.prefix+"."+key.name, v, typeSuffix(key.typ), rate, key.tags)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	f, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+	return f, err == nil
+}
+
+// Backlog returns the metrics aggregated while statsd is unreachable - nil if it is not
+func (c Client) Backlog() *Backlog {
+	if !c.opened || !c.bl.isDown() {
+		return nil
+	}
+	c.bl.mtx.Lock()
+	defer c.bl.mtx.Unlock()
+	out := &Backlog{Since: c.bl.since, Dropped: c.bl.dropped, Metrics: make(map[string]float64, len(c.bl.aggs))}
+	for key, a := range c.bl.aggs {
+		name := key.name
+		if key.tags != "" {
+			name += "," + key.tags
+		}
+		out.Metrics[name] = a.value(key.typ)
+	}
+	return out
+}
+
+// retry probes the unreachable statsd and, once it is back, sends the backlog; resolves
+// the statsd address when due - see NewArgs
+func (c Client) retry() {
+	ticker := time.NewTicker(c.bl.retry)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if c.s.needsResolve() {
+				if err := c.s.resolve(); err != nil {
+					glog.Warningf("Failed to resolve statsd address, err: %v", err)
+				}
+			}
+			if c.bl.isDown() && c.probe() {
+				c.flush()
+			}
+		case <-c.bl.stopCh:
+			return
+		}
+	}
+}
+
+// probe sends two zero counters: statsd is up if neither send fails
+func (c Client) probe() bool {
+	b := []byte(fmt.Sprintf("%s.statsd.probe:0|c", c.prefix))
+	if err := c.s.write(b); err != nil {
+		return false
+	}
+	time.Sleep(c.bl.delay)
+	return c.s.write(b) == nil
+}
+
+func (c Client) flush() {
+	bl := c.bl
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+	n := len(bl.aggs)
+	for key, a := range bl.aggs {
+		if err := c.s.write(c.packet(a, key)); err != nil {
+			return // down again
+		}
+		delete(bl.aggs, key)
+	}
+	if bl.dropped > 0 {
+		glog.Warningf("statsd is back: sent %d aggregated metrics, dropped %d samples", n, bl.dropped)
+	} else {
+		glog.Infof("statsd is back: sent %d aggregated metrics", n)
+	}
+	bl.dropped = 0
+	atomic.StoreInt32(&bl.isdown, 0)
+}
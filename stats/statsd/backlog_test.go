@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ *
+ */
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBacklog(t *testing.T) {
+	retryInterval, probeDelay = 50*time.Millisecond, 10*time.Millisecond
+	// a port with no statsd
+	s, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := s.LocalAddr().(*net.UDPAddr)
+	s.Close()
+
+	c, err := New("127.0.0.1", addr.Port, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < 50 && c.Backlog() == nil; i++ {
+		c.Send("b", Metric{Counter, "x", 1})
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c.Backlog() == nil {
+		t.Fatal("expected statsd down")
+	}
+	c.Send("b", Metric{Counter, "c", 2}, Metric{Timer, "t", 10}, Metric{Gauge, "g", 5})
+	c.Send("b", Metric{Counter, "c", 3}, Metric{Timer, "t", 20.0}, Metric{Gauge, "g", int64(7)})
+	bl := c.Backlog()
+	if bl == nil || bl.Metrics["b.c"] != 5 || bl.Metrics["b.t"] != 15 || bl.Metrics["b.g"] != 7 {
+		t.Fatalf("unexpected backlog %+v", bl)
+	}
+
+	// statsd is back: the backlog is sent
+	if s, err = net.ListenUDP("udp", addr); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	expected := map[string]bool{"test.b.c:5|c": false, "test.b.t:15|ms|@0.5": false, "test.b.g:7|g": false}
+	buf := make([]byte, 256)
+	for n := 0; n < len(expected); {
+		s.SetReadDeadline(time.Now().Add(3 * time.Second))
+		l, _, err := s.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("expected backlog, got %v (%v)", err, expected)
+		}
+		msg := string(buf[:l])
+		if strings.HasPrefix(msg, "test.statsd.probe") || strings.HasPrefix(msg, "test.b.x") {
+			continue
+		}
+		if seen, ok := expected[msg]; !ok || seen {
+			t.Fatalf("unexpected %q", msg)
+		}
+		expected[msg] = true
+		n++
+	}
+	time.Sleep(10 * time.Millisecond)
+	if bl = c.Backlog(); bl != nil {
+		t.Errorf("expected no backlog, got %+v", bl)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	retryInterval, probeDelay = 50*time.Millisecond, 10*time.Millisecond
+	c, err := NewArgs(Args{Host: "statsd.invalid", Port: 8125, Prefix: "test"})
+	if err == nil {
+		t.Fatal("expected the address not to resolve")
+	}
+	defer c.Close()
+	c.Send("b", Metric{Counter, "c", 2})
+	if bl := c.Backlog(); bl == nil || bl.Metrics["b.c"] != 2 {
+		t.Fatalf("expected the metrics aggregated until resolved, got %+v", bl)
+	}
+
+	// statsd resolves
+	s, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c.s.mtx.Lock()
+	c.s.args.Host, c.s.args.Port = "127.0.0.1", s.LocalAddr().(*net.UDPAddr).Port
+	c.s.mtx.Unlock()
+	buf := make([]byte, 256)
+	for {
+		s.SetReadDeadline(time.Now().Add(3 * time.Second))
+		l, _, err := s.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("expected the backlog sent once resolved, got %v", err)
+		}
+		if msg := string(buf[:l]); msg == "test.b.c:2|c" {
+			break
+		} else if !strings.HasPrefix(msg, "test.statsd.probe") {
+			t.Fatalf("unexpected %q", msg)
+		}
+	}
+}
@@ -8,8 +8,15 @@
 package statsd
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
 )
 
 // MetricType is the type of statsd metric
@@ -24,12 +31,50 @@ const (
 	Gauge
 )
 
+// Tag formats (Args.TagFormat)
+const (
+	TagsNone      = ""          // no tags
+	TagsDogStatsD = "dogstatsd" // prefix.bucket.name:value|type|#key:value,...
+	TagsInfluxDB  = "influxdb"  // prefix.bucket.name,key=value,...:value|type
+)
+
+const (
+	// DefaultMaxPacketSize is the batch size that fits into a single Ethernet frame
+	DefaultMaxPacketSize = 1432
+	// DefaultResolveInterval is how often the statsd address is re-resolved
+	DefaultResolveInterval = time.Minute
+)
+
+var errUnresolved = errors.New("statsd address is not resolved")
+
 type (
 	// Client implements a statd client
 	Client struct {
-		conn   *net.UDPConn
+		s      *sender
 		prefix string
-		opened bool // true if the connection with statsd is successfully opened
+		opened bool     // true if the client has been created (see New), even if statsd is yet to be resolved
+		bl     *backlog // metrics aggregated while statsd is unreachable (see backlog.go)
+	}
+
+	// Args are the options of the client - see NewArgs
+	Args struct {
+		Host   string
+		Port   int
+		Prefix string
+		// Batching: the metrics are packed into newline-separated packets of up to MaxPacketSize
+		// bytes that are sent once full and every FlushInterval; zero FlushInterval - each metric
+		// is sent right away, in a packet of its own
+		FlushInterval   time.Duration
+		MaxPacketSize   int
+		TagFormat       string        // TagsNone, TagsDogStatsD, or TagsInfluxDB
+		Tags            []Tag         // sent with every metric, e.g. the daemon's ID
+		ResolveInterval time.Duration // default DefaultResolveInterval
+	}
+
+	// Tag is a key-value pair sent along with the metric (see Args.TagFormat)
+	Tag struct {
+		Key   string
+		Value string
 	}
 
 	// Metric is a generic structure for all type of statsd metrics
@@ -38,62 +83,258 @@ type (
 		Name  string     // Name for this particular metric
 		Value interface{}
 	}
+
+	// sender owns the connection - redialed once the address resolves differently - and the batch
+	sender struct {
+		mtx      sync.Mutex
+		args     Args
+		tags     string // Args.Tags, formatted
+		addr     string // resolved
+		conn     *net.UDPConn
+		resolved time.Time
+		buf      []byte
+		pending  []pendingMetric // the metrics in buf: aggregated in the backlog if the batch fails
+		stopCh   chan struct{}
+	}
+	pendingMetric struct {
+		bucket, tags string
+		m            Metric
+	}
 )
 
-// New returns a client after resolving server and self's address and dialed the server
-// Caller needs to call close
+// New returns a client of the statsd at ip:port that sends each metric right away, untagged -
+// see NewArgs. Caller needs to call close
 func New(ip string, port int, prefix string) (Client, error) {
-	server, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", ip, port))
-	if err != nil {
-		return Client{}, err
-	}
+	return NewArgs(Args{Host: ip, Port: port, Prefix: prefix})
+}
 
-	self, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
-	if err != nil {
-		return Client{}, err
+// NewArgs returns the client. If the statsd address does not resolve, the client returns
+// the error but remains usable: the metrics are aggregated in the backlog and the address is
+// resolved again, every retryInterval, until it does. Once resolved, the address is resolved
+// again every ResolveInterval, and the client redials if it has changed (e.g., statsd has
+// been rescheduled). Caller needs to call close
+func NewArgs(args Args) (Client, error) {
+	switch args.TagFormat {
+	case TagsNone, TagsDogStatsD, TagsInfluxDB:
+	default:
+		return Client{}, fmt.Errorf("invalid statsd tag format %q", args.TagFormat)
 	}
-
-	conn, err := net.DialUDP("udp", self, server)
+	if args.MaxPacketSize <= 0 {
+		args.MaxPacketSize = DefaultMaxPacketSize
+	}
+	if args.ResolveInterval <= 0 {
+		args.ResolveInterval = DefaultResolveInterval
+	}
+	s := &sender{args: args, stopCh: make(chan struct{})}
+	s.tags = s.formatTags(args.Tags, nil)
+	c := Client{s: s, prefix: args.Prefix, opened: true, bl: newBacklog()}
+	err := s.resolve()
 	if err != nil {
-		return Client{}, err
+		c.bl.down()
 	}
-
-	return Client{conn, prefix, true}, nil
+	go c.retry()
+	if args.FlushInterval > 0 {
+		go c.flushBatches()
+	}
+	return c, err
 }
 
 // Close closes the UDP connection
 func (c Client) Close() error {
-	if c.opened {
-		return c.conn.Close()
+	if !c.opened {
+		return nil
 	}
-
-	return nil
+	c.bl.stop()
+	close(c.s.stopCh)
+	c.sendBatch(c.s.take())
+	c.s.mtx.Lock()
+	defer c.s.mtx.Unlock()
+	if c.s.conn == nil {
+		return nil
+	}
+	return c.s.conn.Close()
 }
 
 // Send sends metrics to statsd server
-// Note: Sending error is ignored
+// Note: Sending error is not returned - the metrics that cannot be sent are aggregated
+// in the backlog, to be sent once statsd is back
 func (c Client) Send(bucket string, metrics ...Metric) {
+	c.SendTags(bucket, nil, metrics...)
+}
+
+// SendTags is Send with the tags (in addition to Args.Tags) - e.g., the bucket; the tags are
+// not sent with TagsNone
+func (c Client) SendTags(bucket string, tags []Tag, metrics ...Metric) {
 	if !c.opened {
 		return
 	}
+	ftags := c.s.tags
+	if len(tags) > 0 {
+		ftags = c.s.formatTags(c.s.args.Tags, tags)
+	}
 
-	var t string
-
-	for _, m := range metrics {
-		switch m.Type {
-		case Timer:
-			t = "ms"
-		case Counter:
-			t = "c"
-		case Gauge:
-			t = "g"
-		default:
-			t = ""
+	for i, m := range metrics {
+		if c.bl.isDown() && c.bl.add(bucket, ftags, metrics[i:]) {
+			return
+		}
+		t := typeSuffix(m.Type)
+		if t == "" {
 			// Do nothing
 			// Hopefully the caller will notice he/she's stats won't show up in Graphite or Datadog, etc
+			continue
+		}
+		line := c.s.line(c.prefix+"."+bucket+"."+m.Name, fmt.Sprint(m.Value), t, "", ftags)
+		if c.s.args.FlushInterval > 0 {
+			c.sendBatch(c.s.add(line, pendingMetric{bucket: bucket, tags: ftags, m: m}))
+			continue
+		}
+		if err := c.s.write(line); err != nil {
+			c.bl.down()
+			c.bl.add(bucket, ftags, metrics[i:])
+			return
+		}
+	}
+}
+
+func typeSuffix(typ MetricType) string {
+	switch typ {
+	case Timer:
+		return "ms"
+	case Counter:
+		return "c"
+	case Gauge:
+		return "g"
+	}
+	return ""
+}
+
+// sendBatch sends the batch; if it fails, aggregates its metrics in the backlog
+func (c Client) sendBatch(b []byte, pending []pendingMetric) {
+	if len(b) == 0 {
+		return
+	}
+	if err := c.s.write(b); err != nil {
+		c.bl.down()
+		for _, p := range pending {
+			c.bl.add(p.bucket, p.tags, []Metric{p.m})
 		}
-		if t != "" {
-			c.conn.Write([]byte(fmt.Sprintf("%s.%s.%s:%v|%s", c.prefix, bucket, m.Name, m.Value, t)))
+	}
+}
+
+// flushBatches sends the batch every FlushInterval
+func (c Client) flushBatches() {
+	ticker := time.NewTicker(c.s.args.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.sendBatch(c.s.take())
+		case <-c.s.stopCh:
+			return
 		}
 	}
 }
+
+//
+// sender
+//
+
+// resolve resolves the address and, if it has changed, redials
+func (s *sender) resolve() error {
+	server, err := net.ResolveUDPAddr("udp", net.JoinHostPort(s.args.Host, strconv.Itoa(s.args.Port)))
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.resolved = time.Now()
+	if err != nil {
+		return err
+	}
+	if s.conn != nil && server.String() == s.addr {
+		return nil
+	}
+	conn, err := net.DialUDP("udp", nil, server)
+	if err != nil {
+		return err
+	}
+	if s.conn != nil {
+		glog.Infof("statsd address has changed: %s => %s", s.addr, server)
+		s.conn.Close()
+	}
+	s.conn, s.addr = conn, server.String()
+	return nil
+}
+
+// needsResolve returns true if the address is yet to be resolved, or is due to be re-resolved
+func (s *sender) needsResolve() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.conn == nil || time.Since(s.resolved) >= s.args.ResolveInterval
+}
+
+func (s *sender) write(b []byte) error {
+	s.mtx.Lock()
+	conn := s.conn
+	s.mtx.Unlock()
+	if conn == nil {
+		return errUnresolved
+	}
+	_, err := conn.Write(b)
+	return err
+}
+
+// add appends the line to the batch; returns the previous batch if the line does not fit
+func (s *sender) add(line []byte, p pendingMetric) (full []byte, pending []pendingMetric) {
+	s.mtx.Lock()
+	if len(s.buf) > 0 && len(s.buf)+1+len(line) > s.args.MaxPacketSize {
+		full, pending = s.buf, s.pending
+		s.buf, s.pending = nil, nil
+	}
+	if len(s.buf) > 0 {
+		s.buf = append(s.buf, '\n')
+	}
+	s.buf = append(s.buf, line...)
+	s.pending = append(s.pending, p)
+	s.mtx.Unlock()
+	return
+}
+
+// take returns the batch and starts a new one
+func (s *sender) take() (b []byte, pending []pendingMetric) {
+	s.mtx.Lock()
+	b, pending = s.buf, s.pending
+	s.buf, s.pending = nil, nil
+	s.mtx.Unlock()
+	return
+}
+
+var tagEscaper = strings.NewReplacer(":", "_", "|", "_", ",", "_", "=", "_", "#", "_", " ", "_", "\n", "_")
+
+// formatTags formats the tags as per the tag format; empty with TagsNone
+func (s *sender) formatTags(common, tags []Tag) string {
+	if s.args.TagFormat == TagsNone || len(common)+len(tags) == 0 {
+		return ""
+	}
+	sep := ":"
+	if s.args.TagFormat == TagsInfluxDB {
+		sep = "="
+	}
+	parts := make([]string, 0, len(common)+len(tags))
+	for _, tag := range append(common[:len(common):len(common)], tags...) {
+		parts = append(parts, tagEscaper.Replace(tag.Key)+sep+tagEscaper.Replace(tag.Value))
+	}
+	return strings.Join(parts, ",")
+}
+
+// line formats the metric (the sample rate, if any, is "@rate") as per the tag format
+func (s *sender) line(name, value, typ, rate, tags string) []byte {
+	if rate != "" {
+		typ += "|" + rate
+	}
+	switch {
+	case tags == "":
+		return []byte(name + ":" + value + "|" + typ)
+	case s.args.TagFormat == TagsInfluxDB:
+		return []byte(name + "," + tags + ":" + value + "|" + typ)
+	default:
+		return []byte(name + ":" + value + "|" + typ + "|#" + tags)
+	}
+}
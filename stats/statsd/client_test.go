@@ -97,6 +97,60 @@ func TestClient(t *testing.T) {
 	checkMsg(t, s, "test.three.gauge.onemore:789|g")
 }
 
+func TestClientBatchTags(t *testing.T) {
+	s, err := net.ListenUDP(protocol, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal("Failed to start server", err)
+	}
+	defer s.Close()
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{statsd.TagsDogStatsD, "test.get.count:1|c|#daemon:t_1,bucket:b1\ntest.get.latency:2.5|ms|#daemon:t_1,bucket:b1\ntest.lru.n:3|g|#daemon:t_1"},
+		{statsd.TagsInfluxDB, "test.get.count,daemon=t_1,bucket=b1:1|c\ntest.get.latency,daemon=t_1,bucket=b1:2.5|ms\ntest.lru.n,daemon=t_1:3|g"},
+	}
+	for _, test := range tests {
+		c, err := statsd.NewArgs(statsd.Args{
+			Host:          "127.0.0.1",
+			Port:          s.LocalAddr().(*net.UDPAddr).Port,
+			Prefix:        prefix,
+			FlushInterval: 50 * time.Millisecond,
+			TagFormat:     test.format,
+			Tags:          []statsd.Tag{{Key: "daemon", Value: "t:1"}},
+		})
+		if err != nil {
+			t.Fatal("Failed to create client", err)
+		}
+		bucket := []statsd.Tag{{Key: "bucket", Value: "b1"}}
+		c.SendTags("get", bucket, statsd.Metric{Type: statsd.Counter, Name: "count", Value: 1},
+			statsd.Metric{Type: statsd.Timer, Name: "latency", Value: 2.5})
+		c.Send("lru", statsd.Metric{Type: statsd.Gauge, Name: "n", Value: 3})
+		// a single packet, once flushed
+		checkMsg(t, s, test.expected)
+		c.Close()
+	}
+
+	// the packets do not exceed MaxPacketSize
+	c, err := statsd.NewArgs(statsd.Args{
+		Host:          "127.0.0.1",
+		Port:          s.LocalAddr().(*net.UDPAddr).Port,
+		Prefix:        prefix,
+		FlushInterval: time.Hour,
+		MaxPacketSize: 30,
+	})
+	if err != nil {
+		t.Fatal("Failed to create client", err)
+	}
+	for i := 0; i < 3; i++ {
+		c.Send("b", statsd.Metric{Type: statsd.Counter, Name: "c", Value: i})
+	}
+	checkMsg(t, s, "test.b.c:0|c\ntest.b.c:1|c")
+	c.Close() // flushes the rest
+	checkMsg(t, s, "test.b.c:2|c")
+}
+
 // server is the UDP server routine used for testing
 // it receives UDP requests and throw them away
 // stops when a message is received from the stop channel
@@ -0,0 +1,314 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/3rdparty/glog"
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/ios"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// The periodic stats log: at scale, logging all of the stats every stats period floods
+// the logs. Compaction (periodic.stats_log_full = N > 1) logs only the stats that have
+// changed since they were last logged, and all of them every N-th logged period - so that
+// the full picture is never more than N periods away. Verbosity is per module (see
+// StatsLogModules): periodic.stats_log_level, e.g. "disk=0,core=2", turns a module off
+// (0), compacts it (1, default), or logs it in full every period (2).
+//
+// The core stats are compacted stat by stat; the other modules (per mountpath, device,
+// or filesystem) line by line. Only the logs are affected: StatsD, Prometheus, and the
+// stats API receive all of the stats, as always.
+//
+// The format (periodic.stats_log_format) is either text - the core stats and a line per
+// mountpath, device, etc. - or JSON: a single StatsRecord per stats period, versioned by
+// StatsLogSchema. Either way, the stats are logged into the daemon's log or, with
+// periodic.stats_log_file, into the dedicated stats log that is rotated by size.
+
+// Stats log levels (periodic.stats_log_level)
+const (
+	StatsLogOff     = 0 // not logged
+	StatsLogChanged = 1 // logged when changed, and every stats_log_full periods (default)
+	StatsLogAll     = 2 // logged every period
+)
+
+// Stats log modules
+const (
+	StatsLogCore     = "core"     // the core stats of the proxy and the target
+	StatsLogCapacity = "capacity" // target: used capacity, per mountpath
+	StatsLogQoS      = "qos"      // target: throttled mountpaths
+	StatsLogDisk     = "disk"     // target: iostat, per device
+	StatsLogFS       = "fs"       // target: iostat, per filesystem
+	StatsLogCPU      = "cpu"      // target: CPU idle
+)
+
+// Stats log formats (periodic.stats_log_format)
+const (
+	StatsLogText = "text" // default
+	StatsLogJSON = "json"
+)
+
+// StatsLogSchema is the version of StatsRecord: incremented upon incompatible changes,
+// i.e. removed or renamed fields and changed semantics, but not new fields
+const StatsLogSchema = 1
+
+const (
+	// DefaultStatsLogMaxSize is the size at which the dedicated stats log is rotated
+	DefaultStatsLogMaxSize = 64 * 1024 * 1024
+	statsLogBackups        = 3 // rotated stats logs kept: <stats_log_file>.1 (the latest) to .3
+)
+
+// StatsLogModules are the modules of periodic.stats_log_level
+var StatsLogModules = []string{StatsLogCore, StatsLogCapacity, StatsLogQoS, StatsLogDisk, StatsLogFS, StatsLogCPU}
+
+type (
+	statsLog struct {
+		periods int               // logged periods
+		full    bool              // the current period is logged in full
+		core    map[string]int64  // the core stats as last logged
+		lines   map[string]string // the lines of the other modules as last logged, by module and key
+		file    statsLogFile      // periodic.stats_log_file, if configured
+	}
+	// statsLogFile is the dedicated stats log
+	statsLogFile struct {
+		path string
+		f    *os.File
+		size int64
+		err  error // logged once, until written again
+	}
+
+	// StatsRecord is the record of the JSON stats log - one per logged stats period. The
+	// record is compacted the same way the text log is - see periodic.stats_log_full and
+	// stats_log_level: Full is true if all of the (enabled) stats are in; otherwise, only
+	// those that have changed since they were last logged
+	StatsRecord struct {
+		Schema     int                      `json:"schema"` // StatsLogSchema
+		Time       time.Time                `json:"time"`
+		Role       string                   `json:"role"` // "proxy" or "target"
+		Full       bool                     `json:"full"`
+		Core       map[string]int64         `json:"core,omitempty"`       // the core stats, by name
+		Capacity   map[string]*fscapacity   `json:"capacity,omitempty"`   // target: by mountpath
+		QoS        map[string]ios.QoSState  `json:"qos,omitempty"`        // target: throttled mountpaths
+		Disk       map[string]cmn.SimpleKVs `json:"disk,omitempty"`       // target: iostat, by device
+		Filesystem map[string]ios.FSMetrics `json:"filesystem,omitempty"` // target: iostat, by filesystem
+		CPUIdle    string                   `json:"cpuidle,omitempty"`    // target: percentage
+	}
+)
+
+// ParseStatsLogLevel parses periodic.stats_log_level: comma-separated module=level
+func ParseStatsLogLevel(s string) (map[string]int, error) {
+	levels := make(map[string]int, len(StatsLogModules))
+	if strings.TrimSpace(s) == "" {
+		return levels, nil
+	}
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+		if len(parts) != 2 || !isStatsLogModule(parts[0]) {
+			return nil, fmt.Errorf("invalid stats log level %q (expecting module=level, module one of: %s)",
+				kv, strings.Join(StatsLogModules, ", "))
+		}
+		level, err := strconv.Atoi(parts[1])
+		if err != nil || level < StatsLogOff || level > StatsLogAll {
+			return nil, fmt.Errorf("invalid stats log level %q (expecting level %d to %d)", kv, StatsLogOff, StatsLogAll)
+		}
+		levels[parts[0]] = level
+	}
+	return levels, nil
+}
+
+func isStatsLogModule(mod string) bool {
+	for _, m := range StatsLogModules {
+		if m == mod {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateStatsLogFormat validates periodic.stats_log_format
+func ValidateStatsLogFormat(format string) error {
+	switch format {
+	case "", StatsLogText, StatsLogJSON:
+		return nil
+	}
+	return fmt.Errorf("invalid stats log format %q (expecting %q or %q)", format, StatsLogText, StatsLogJSON)
+}
+
+// begin starts a logged period and returns its record; under lock
+func (l *statsLog) begin(conf *cmn.PeriodConf, role string) *StatsRecord {
+	l.full = conf.StatsLogFull <= 1 || l.periods%conf.StatsLogFull == 0
+	l.periods++
+	return &StatsRecord{Schema: StatsLogSchema, Time: time.Now(), Role: role, Full: l.full}
+}
+
+// output returns the lines to log: in text - as is, in JSON - the record; none if there is
+// nothing to log. Under lock, as the record references the stats
+func (l *statsLog) output(conf *cmn.PeriodConf, rec *StatsRecord, lines []string) []string {
+	if len(lines) == 0 {
+		return nil
+	}
+	if conf.StatsLogFormat != StatsLogJSON {
+		return lines
+	}
+	b, err := jsoniter.Marshal(rec)
+	if err != nil {
+		glog.Errorf("Failed to marshal stats record, err: %v", err)
+		return nil
+	}
+	return []string{string(b)}
+}
+
+// write logs the lines into the stats log; not under lock
+func (l *statsLog) write(config *cmn.Config, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	path := config.Periodic.StatsLogFile
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(config.Log.Dir, path)
+	}
+	if path != l.file.path {
+		l.file.close()
+		l.file.path = path
+	}
+	if path == "" {
+		for _, ln := range lines {
+			glog.Infoln(ln)
+		}
+		return
+	}
+	maxSize := config.Periodic.StatsLogMaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultStatsLogMaxSize
+	}
+	var (
+		b   []byte
+		now = time.Now().Format(time.RFC3339)
+	)
+	for _, ln := range lines {
+		if config.Periodic.StatsLogFormat != StatsLogJSON {
+			b = append(b, now+" "...) // (the record has its own time)
+		}
+		b = append(b, ln...)
+		b = append(b, '\n')
+	}
+	err := l.file.write(b, maxSize)
+	if err != nil && l.file.err == nil {
+		glog.Errorf("Failed to write stats log %s (logging the stats into the daemon's log), err: %v", path, err)
+	}
+	l.file.err = err
+	if err != nil {
+		for _, ln := range lines {
+			glog.Infoln(ln)
+		}
+	}
+}
+
+// write appends to the stats log, rotating it first if need be
+func (f *statsLogFile) write(b []byte, maxSize int64) (err error) {
+	if f.f == nil {
+		if err = f.open(); err != nil {
+			return
+		}
+	}
+	if f.size > 0 && f.size+int64(len(b)) > maxSize {
+		f.close()
+		f.rotate()
+		if err = f.open(); err != nil {
+			return
+		}
+	}
+	n, err := f.f.Write(b)
+	f.size += int64(n)
+	if err != nil {
+		f.close()
+	}
+	return
+}
+
+func (f *statsLogFile) open() (err error) {
+	if err = cmn.CreateDir(filepath.Dir(f.path)); err != nil {
+		return
+	}
+	if f.f, err = os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+		return
+	}
+	fi, err := f.f.Stat()
+	if err != nil {
+		f.close()
+		return
+	}
+	f.size = fi.Size()
+	return
+}
+
+// rotate shifts the stats log and its rotated copies by one: <path> => <path>.1 => <path>.2, etc.
+func (f *statsLogFile) rotate() {
+	for i := statsLogBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil && !os.IsNotExist(err) {
+		glog.Errorf("Failed to rotate stats log %s, err: %v", f.path, err)
+	}
+}
+
+func (f *statsLogFile) close() {
+	if f.f != nil {
+		f.f.Close()
+		f.f, f.size = nil, 0
+	}
+}
+
+func statsLogLevel(conf *cmn.PeriodConf, mod string) int {
+	if level, ok := conf.StatsLogLevel[mod]; ok {
+		return level
+	}
+	return StatsLogChanged
+}
+
+// compactCore returns the core stats to log: all of them or only those that have changed;
+// nil if none
+func (l *statsLog) compactCore(conf *cmn.PeriodConf, outputs map[string]int64) map[string]int64 {
+	level := statsLogLevel(conf, StatsLogCore)
+	if level == StatsLogOff {
+		return nil
+	}
+	prev := l.core
+	l.core = outputs
+	if l.full || level == StatsLogAll || prev == nil {
+		return outputs
+	}
+	changed := make(map[string]int64, 8)
+	for name, v := range outputs {
+		if pv, ok := prev[name]; !ok || pv != v {
+			changed[name] = v
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return changed
+}
+
+// logLine returns true if the module's line is to be logged
+func (l *statsLog) logLine(conf *cmn.PeriodConf, mod, key, line string) bool {
+	level := statsLogLevel(conf, mod)
+	if level == StatsLogOff {
+		return false
+	}
+	if l.lines == nil {
+		l.lines = make(map[string]string, 16)
+	}
+	key = mod + "/" + key
+	prev, ok := l.lines[key]
+	l.lines[key] = line
+	return l.full || level == StatsLogAll || !ok || prev != line
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+)
+
+func TestParseStatsLogLevel(t *testing.T) {
+	levels, err := ParseStatsLogLevel(" disk=0, core=2")
+	if err != nil || len(levels) != 2 || levels[StatsLogDisk] != StatsLogOff || levels[StatsLogCore] != StatsLogAll {
+		t.Fatalf("unexpected levels %v, err: %v", levels, err)
+	}
+	if levels, err = ParseStatsLogLevel(""); err != nil || len(levels) != 0 {
+		t.Fatalf("expected no levels, got %v, err: %v", levels, err)
+	}
+	for _, s := range []string{"disk", "disks=0", "disk=3", "disk=x"} {
+		if _, err := ParseStatsLogLevel(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestStatsLogCompaction(t *testing.T) {
+	var (
+		l    statsLog
+		conf = &cmn.PeriodConf{StatsLogFull: 3}
+	)
+	// period 0: full
+	l.begin(conf, "proxy")
+	if out := l.compactCore(conf, map[string]int64{GetCount: 1, PutCount: 2}); len(out) != 2 {
+		t.Fatalf("expected the first period in full, got %v", out)
+	}
+	if !l.logLine(conf, StatsLogDisk, "sda", `{"%util":"1"}`) {
+		t.Fatal("expected the first line logged")
+	}
+
+	// period 1: the changes only
+	l.begin(conf, "proxy")
+	out := l.compactCore(conf, map[string]int64{GetCount: 5, PutCount: 2})
+	if len(out) != 1 || out[GetCount] != 5 {
+		t.Fatalf("expected only %s, got %v", GetCount, out)
+	}
+	if l.logLine(conf, StatsLogDisk, "sda", `{"%util":"1"}`) || !l.logLine(conf, StatsLogDisk, "sdb", `{"%util":"1"}`) {
+		t.Fatal("expected only the new line logged")
+	}
+
+	// period 2: nothing has changed
+	l.begin(conf, "proxy")
+	if out = l.compactCore(conf, map[string]int64{GetCount: 5, PutCount: 2}); out != nil {
+		t.Fatalf("expected nothing logged, got %v", out)
+	}
+	if l.logLine(conf, StatsLogDisk, "sda", `{"%util":"1"}`) {
+		t.Fatal("expected the unchanged line not logged")
+	}
+
+	// period 3: full again
+	l.begin(conf, "proxy")
+	if out = l.compactCore(conf, map[string]int64{GetCount: 5, PutCount: 2}); len(out) != 2 {
+		t.Fatalf("expected the period in full, got %v", out)
+	}
+	if !l.logLine(conf, StatsLogDisk, "sda", `{"%util":"1"}`) {
+		t.Fatal("expected the unchanged line logged in full period")
+	}
+
+	// per-module levels
+	conf.StatsLogLevel = map[string]int{StatsLogDisk: StatsLogOff, StatsLogCore: StatsLogAll}
+	l.begin(conf, "proxy")
+	if out = l.compactCore(conf, map[string]int64{GetCount: 5, PutCount: 2}); len(out) != 2 {
+		t.Fatalf("expected the core stats in full, got %v", out)
+	}
+	if l.logLine(conf, StatsLogDisk, "sda", `{"%util":"9"}`) {
+		t.Fatal("expected the disk module off")
+	}
+	if l.logLine(conf, StatsLogFS, "/dev/sda1", `{}`); l.logLine(conf, StatsLogFS, "/dev/sda1", `{}`) {
+		t.Fatal("expected the unchanged fs line not logged")
+	}
+
+	// no compaction
+	conf = &cmn.PeriodConf{}
+	for i := 0; i < 2; i++ {
+		l.begin(conf, "proxy")
+		if out = l.compactCore(conf, map[string]int64{GetCount: 5, PutCount: 2}); len(out) != 2 {
+			t.Fatalf("expected no compaction, got %v", out)
+		}
+	}
+}
+
+// (the JSON record is not marshaled here: jsoniter maps and the test toolchain do not mix)
+func TestStatsLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		l      statsLog
+		config = &cmn.Config{}
+	)
+	config.Log.Dir = dir
+	config.Periodic = cmn.PeriodConf{StatsLogFull: 2, StatsLogFile: "stats.log", StatsLogMaxSize: 100}
+	defer l.file.close()
+
+	for i := 0; i < 7; i++ {
+		rec := l.begin(&config.Periodic, "target")
+		if rec.Schema != StatsLogSchema || rec.Role != "target" || rec.Full != (i%2 == 0) || rec.Time.IsZero() {
+			t.Fatalf("unexpected record %+v", rec)
+		}
+		lines := l.output(&config.Periodic, rec, []string{"CPU idle: " + strconv.Itoa(90+i) + "%"})
+		l.write(config, lines)
+	}
+	if lines := l.output(&config.Periodic, &StatsRecord{}, nil); lines != nil {
+		t.Errorf("expected nothing to log, got %v", lines)
+	}
+
+	// each line is about 40 bytes: 2 lines per (100 bytes) log; 3 rotated logs at most
+	path := filepath.Join(dir, "stats.log")
+	for i, expected := range []string{"96", "95", "93", "91"} {
+		name := path
+		if i > 0 {
+			name += "." + strconv.Itoa(i)
+		}
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		if last := lines[len(lines)-1]; !strings.HasSuffix(last, " CPU idle: "+expected+"%") {
+			t.Errorf("%s: expected CPU idle %s, got %q", name, expected, b)
+		}
+		if _, err := time.Parse(time.RFC3339, strings.Fields(lines[0])[0]); err != nil {
+			t.Errorf("%s: expected timestamped lines, got %q", name, b)
+		}
+	}
+	if _, err := os.Stat(path + ".4"); !os.IsNotExist(err) {
+		t.Errorf("expected at most %d rotated logs, err: %v", statsLogBackups, err)
+	}
+
+	for _, format := range []string{"", StatsLogText, StatsLogJSON} {
+		if err := ValidateStatsLogFormat(format); err != nil {
+			t.Error(err)
+		}
+	}
+	if ValidateStatsLogFormat("xml") == nil {
+		t.Error("expected invalid format")
+	}
+}
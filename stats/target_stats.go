@@ -9,6 +9,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,53 +23,119 @@ import (
 )
 
 const (
-	PutLatency       = "put.μs"
-	GetColdCount     = "get.cold.n"
-	GetColdSize      = "get.cold.size"
-	LruEvictSize     = "lru.evict.size"
-	LruEvictCount    = "lru.evict.n"
-	TxCount          = "tx.n"
-	TxSize           = "tx.size"
-	RxCount          = "rx.n"
-	RxSize           = "rx.size"
-	PrefetchCount    = "pre.n"
-	PrefetchSize     = "pre.size"
-	VerChangeCount   = "vchange.n"
-	VerChangeSize    = "vchange.size"
-	ErrCksumCount    = "err.cksum.n"
-	ErrCksumSize     = "err.cksum.size"
-	GetRedirLatency  = "get.redir.μs"
-	PutRedirLatency  = "put.redir.μs"
-	RebalGlobalCount = "reb.global.n"
-	RebalLocalCount  = "reb.local.n"
-	RebalGlobalSize  = "reb.global.size"
-	RebalLocalSize   = "reb.local.size"
-	ReplPutCount     = "replication.put.n"
-	ReplPutLatency   = "replication.put.µs"
+	PutLatency        = "put.lat"
+	GetColdCount      = "get.cold.n"
+	GetColdSize       = "get.cold.size"
+	LruEvictSize      = "lru.evict.size"
+	LruEvictCount     = "lru.evict.n"
+	TxCount           = "tx.n"
+	TxSize            = "tx.size"
+	RxCount           = "rx.n"
+	RxSize            = "rx.size"
+	PrefetchCount     = "pre.n"
+	PrefetchSize      = "pre.size"
+	VerChangeCount    = "vchange.n"
+	VerChangeSize     = "vchange.size"
+	ErrCksumCount     = "err.cksum.n"
+	ErrCksumSize      = "err.cksum.size"
+	GetRedirLatency   = "get.redir.lat"
+	PutRedirLatency   = "put.redir.lat"
+	RebalGlobalCount  = "reb.global.n"
+	RebalLocalCount   = "reb.local.n"
+	RebalGlobalSize   = "reb.global.size"
+	RebalLocalSize    = "reb.local.size"
+	ReplPutCount      = "replication.put.n"
+	ReplPutLatency    = "replication.put.lat"
+	CksumAsyncCount   = "cksum.async.n"   // checksums computed in the background (async_checksum_put)
+	CksumPendingCount = "cksum.pending.n" // objects PUT with async checksum that are yet to be checksummed
+	// parallel cold GET (cold_get.concurrency): the effective Cloud throughput is size / (n * lat)
+	GetColdParallelCount   = "get.cold.parallel.n"
+	GetColdParallelSize    = "get.cold.parallel.size"
+	GetColdParallelLatency = "get.cold.parallel.lat"
+	PutSyncLatency         = "put.sync.lat"        // time to flush PUT objects as per bucket's sync_policy
+	PrunedDirCount         = "prune.dir.n"         // empty directories removed by housekeeping (prune_dirs_time)
+	ListCacheHitCount      = "lst.cache.hit.n"     // Cloud bucket list pages served from the list cache
+	ListCacheMissCount     = "lst.cache.miss.n"    // Cloud bucket list pages requested from the Cloud
+	GetMmapHitCount        = "get.mmap.hit.n"      // GETs served out of the cached memory mappings (mmap_read)
+	GetMmapMissCount       = "get.mmap.miss.n"     // GETs that had to map the object first
+	GetFDCacheHitCount     = "get.fd.hit.n"        // GETs that have read the object via a cached open file (fd_cache)
+	GetFDCacheMissCount    = "get.fd.miss.n"       // GETs that had to open the object first
+	MDCacheHitCount        = "md.hit.n"            // object metadata served out of the cache (md_cache)
+	MDCacheMissCount       = "md.miss.n"           // object metadata read from the xattrs
+	CompressCount          = "compress.n"          // cold objects compressed at rest (compression)
+	CompressSavedSize      = "compress.saved.size" // disk space saved by compression, net of decompressions
+	DecompressCount        = "decompress.n"        // compressed objects decompressed back upon turning hot
+	PutDupCount            = "put.dup.n"           // PUTs not executed as retries of the completed ones (put_dedup)
+	ExpiredCount           = "expired.n"           // objects removed upon the expiration of their TTL
+	ExpiredSize            = "expired.size"        // ditto, bytes
+	AtimeFlushCount        = "atime.flush.n"       // access times flushed to disk
+	AtimeMapSize           = "atime.map.size"      // access times cached in memory, yet to be flushed
+	AtimeMissCount         = "atime.miss.n"        // access time lookups not found in memory
+	AtimeDropCount         = "atime.drop.n"        // touches of the objects outside of mountpaths
+	ErrPutCksumCount       = "err.put.cksum.n"     // PUTs rejected as not matching the client-supplied checksum
+	PrefetchCloudReqCount  = "pre.cloud.req.n"     // Cloud requests made by prefetch: list pages, GETs, version checks
+	PrefetchTrimCount      = "pre.trim.n"          // objects not prefetched as over the bucket's prefetch_budget
+	// replication traffic, including retries; per destination - see Trunner.ReplStats
+	ReplTxCount = "replication.tx.n"
+	ReplTxSize  = "replication.tx.size"
+	// GETs streamed from the Cloud without caching (bucket's passthrough_size); not counted as cold
+	GetPassthroughCount = "get.passthrough.n"
+	GetPassthroughSize  = "get.passthrough.size"
 )
 
 type (
 	fscapacity struct {
-		Used    uint64 `json:"used"`    // bytes
-		Avail   uint64 `json:"avail"`   // ditto
-		Usedpct int64  `json:"usedpct"` // reduntant ok
+		Total    uint64 `json:"total"`    // filesystem size, bytes - see fsResized
+		Used     uint64 `json:"used"`     // bytes
+		Avail    uint64 `json:"avail"`    // ditto
+		Usedpct  int64  `json:"usedpct"`  // reduntant ok
+		Reserved uint64 `json:"reserved"` // disk_config.reserved_space(_mpath), bytes
+		Headroom int64  `json:"headroom"` // avail - reserved: negative means that writes are refused
+		Pinned   int64  `json:"pinned"`   // pinned objects (not evictable), bytes - see SetPinned
+	}
+	// mpathStats is the per-mountpath breakdown of the LRU eviction and atime flush stats
+	mpathStats struct {
+		LruEvictCount   int64 `json:"lru.evict.n"`
+		LruEvictSize    int64 `json:"lru.evict.size"`
+		AtimeFlushCount int64 `json:"atime.flush.n"`
+	}
+	// DiskStats is implemented by ios.IostatRunner
+	DiskStats interface {
+		ios.DiskUtilProvider
+		Snapshot() (cpuidle string, disks map[string]cmn.SimpleKVs)
+		Filesystems() map[string]ios.FSMetrics
+		QoS() map[string]ios.QoSState
 	}
 	targetCoreStats struct {
 		ProxyCoreStats
+		repl cmn.ReplStats // replication traffic per destination URL
 	}
 	Trunner struct {
 		statsrunner
 		TargetRunner cluster.Target         `json:"-"`
-		Riostat      *ios.IostatRunner      `json:"-"`
+		Riostat      DiskStats              `json:"-"`
 		Core         *targetCoreStats       `json:"core"`
 		Capacity     map[string]*fscapacity `json:"capacity"`
+		Mountpaths   map[string]*mpathStats `json:"mountpaths"` // since the target's start - see AddMpath
 		// iostat
-		CPUidle string                   `json:"cpuidle"`
-		Disk    map[string]cmn.SimpleKVs `json:"disk"`
+		CPUidle    string                   `json:"cpuidle"`
+		Disk       map[string]cmn.SimpleKVs `json:"disk"`
+		Filesystem map[string]ios.FSMetrics `json:"filesystem"`       // the disks' metrics aggregated per filesystem
+		QoS        map[string]ios.QoSState  `json:"qos,omitempty"`    // mountpath => foreground load and throttling
+		StatsD     *statsd.Backlog          `json:"statsd,omitempty"` // metrics not sent to unreachable statsd
 		// omitempty
 		timeUpdatedCapacity time.Time
 		timeCheckedLogSizes time.Time
+		timePrunedDirs      time.Time
+		timeCompressed      time.Time
+		timeExpired         time.Time
+		timeSavedMD         time.Time
+		timeUpdatedPinned   time.Time
+		pinned              map[string]int64 // mpath => bytes
+		bucketIO            bucketIO         // bucket_stats.go
+		bucketCapacity      bucketCapacity   // bucket_capacity.go
 		fsmap               map[syscall.Fsid]string
+		emergency           int32 // capacity emergency mode (disk_config.critical_wm); atomic
 	}
 )
 
@@ -79,7 +146,7 @@ type (
 func (t *targetCoreStats) initStatsTracker() {
 	t.ProxyCoreStats.initStatsTracker()
 
-	t.Tracker.register(PutLatency, statsKindLatency)
+	t.Tracker.register(PutLatency, statsKindHistogram)
 	t.Tracker.register(GetColdCount, statsKindCounter)
 	t.Tracker.register(GetColdSize, statsKindCounter)
 	t.Tracker.register(LruEvictSize, statsKindCounter)
@@ -102,6 +169,39 @@ func (t *targetCoreStats) initStatsTracker() {
 	t.Tracker.register(RebalLocalSize, statsKindCounter)
 	t.Tracker.register(ReplPutCount, statsKindCounter)
 	t.Tracker.register(ReplPutLatency, statsKindLatency)
+	t.Tracker.register(CksumAsyncCount, statsKindCounter)
+	t.Tracker.register(CksumPendingCount, statsKindCounter)
+	t.Tracker.register(GetColdParallelCount, statsKindCounter)
+	t.Tracker.register(GetColdParallelSize, statsKindCounter)
+	t.Tracker.register(GetColdParallelLatency, statsKindLatency)
+	t.Tracker.register(PutSyncLatency, statsKindLatency)
+	t.Tracker.register(PrunedDirCount, statsKindCounter)
+	t.Tracker.register(ListCacheHitCount, statsKindCounter)
+	t.Tracker.register(ListCacheMissCount, statsKindCounter)
+	t.Tracker.register(GetMmapHitCount, statsKindCounter)
+	t.Tracker.register(GetMmapMissCount, statsKindCounter)
+	t.Tracker.register(GetFDCacheHitCount, statsKindCounter)
+	t.Tracker.register(GetFDCacheMissCount, statsKindCounter)
+	t.Tracker.register(MDCacheHitCount, statsKindCounter)
+	t.Tracker.register(MDCacheMissCount, statsKindCounter)
+	t.Tracker.register(CompressCount, statsKindCounter)
+	t.Tracker.register(CompressSavedSize, statsKindCounter)
+	t.Tracker.register(DecompressCount, statsKindCounter)
+	t.Tracker.register(PutDupCount, statsKindCounter)
+	t.Tracker.register(ExpiredCount, statsKindCounter)
+	t.Tracker.register(ExpiredSize, statsKindCounter)
+	t.Tracker.register(AtimeFlushCount, statsKindCounter)
+	t.Tracker.register(AtimeMapSize, statsKindCounter)
+	t.Tracker.register(AtimeMissCount, statsKindCounter)
+	t.Tracker.register(AtimeDropCount, statsKindCounter)
+	t.Tracker.register(ErrPutCksumCount, statsKindCounter)
+	t.Tracker.register(PrefetchCloudReqCount, statsKindCounter)
+	t.Tracker.register(PrefetchTrimCount, statsKindCounter)
+	t.Tracker.register(GetPassthroughCount, statsKindCounter)
+	t.Tracker.register(GetPassthroughSize, statsKindCounter)
+	t.Tracker.register(ReplTxCount, statsKindCounter)
+	t.Tracker.register(ReplTxSize, statsKindCounter)
+	t.repl = make(cmn.ReplStats)
 }
 
 func (t *targetCoreStats) doAdd(name string, val int64) {
@@ -114,38 +214,39 @@ func (t *targetCoreStats) doAdd(name string, val int64) {
 	case GetCount, PutCount, PostCount, DeleteCount, RenameCount, ListCount,
 		GetLatency, PutLatency, ListLatency,
 		KeepAliveLatency, KeepAliveMinLatency, KeepAliveMaxLatency,
+		GetRedirLatency, PutRedirLatency, ReplPutLatency, GetColdParallelLatency, PutSyncLatency,
 		ErrCount, ErrGetCount, ErrDeleteCount, ErrPostCount,
 		ErrPutCount, ErrHeadCount, ErrListCount, ErrRangeCount:
 		t.ProxyCoreStats.doAdd(name, val)
 		return
 	// target only
 	case GetColdSize:
-		t.StatsdC.Send("get.cold",
+		t.send("get.cold",
 			metric{statsd.Counter, "count", 1},
 			metric{statsd.Counter, "get.cold.size", val})
+	case GetPassthroughSize:
+		t.send("get.passthrough",
+			metric{Type: statsd.Counter, Name: "count", Value: 1},
+			metric{Type: statsd.Counter, Name: "get.passthrough.size", Value: val})
 	case VerChangeSize:
-		t.StatsdC.Send("get.cold",
+		t.send("get.cold",
 			metric{statsd.Counter, "vchanged", 1},
 			metric{statsd.Counter, "vchange.size", val})
-	case LruEvictSize, TxSize, RxSize, ErrCksumSize: // byte stats
-		t.StatsdC.Send(name, metric{statsd.Counter, "bytes", val})
-	case LruEvictCount, TxCount, RxCount: // files stats
-		t.StatsdC.Send(name, metric{statsd.Counter, "files", val})
-	case ErrCksumCount: // counter stats
-		t.StatsdC.Send(name, metric{statsd.Counter, "count", val})
-	case GetRedirLatency, PutRedirLatency: // latency stats
-		t.Tracker[name].associatedVal++
-		t.StatsdC.Send(name,
-			metric{statsd.Counter, "count", 1},
-			metric{statsd.Timer, "latency", float64(time.Duration(val) / time.Millisecond)})
-		val = int64(time.Duration(val) / time.Microsecond)
+	case LruEvictSize, TxSize, RxSize, ErrCksumSize, ReplTxSize: // byte stats
+		t.send(name, metric{statsd.Counter, "bytes", val})
+	case LruEvictCount, TxCount, RxCount, AtimeFlushCount: // files stats
+		t.send(name, metric{statsd.Counter, "files", val})
+	case ErrCksumCount, ErrPutCksumCount, AtimeMissCount, AtimeDropCount, PrefetchCloudReqCount, PrefetchTrimCount: // counter stats
+		t.send(name, metric{statsd.Counter, "count", val})
+	case AtimeMapSize: // reported as deltas
+		t.send(name, metric{statsd.Gauge, "entries", t.Tracker[name].Value + val})
 	}
 	t.Tracker[name].Value += val
 	t.logged = false
 }
 
 func (t *targetCoreStats) MarshalJSON() ([]byte, error) {
-	return jsoniter.Marshal(t.Tracker)
+	return t.Tracker.marshal(t.latencyUnit())
 }
 
 func (t *targetCoreStats) UnmarshalJSON(b []byte) error {
@@ -156,12 +257,17 @@ func (t *targetCoreStats) UnmarshalJSON(b []byte) error {
 // Trunner
 //
 
-func newFSCapacity(statfs *syscall.Statfs_t) *fscapacity {
-	pct := (statfs.Blocks - statfs.Bavail) * 100 / statfs.Blocks
+func newFSCapacity(mpath string, blocks, bavail uint64, bsize int64, config *cmn.Config) *fscapacity {
+	pct := (blocks - bavail) * 100 / blocks
+	avail := bavail * uint64(bsize)
+	reserved := config.Disk.ReservedBytes(mpath, blocks*uint64(bsize))
 	return &fscapacity{
-		Used:    (statfs.Blocks - statfs.Bavail) * uint64(statfs.Bsize),
-		Avail:   statfs.Bavail * uint64(statfs.Bsize),
-		Usedpct: int64(pct),
+		Total:    blocks * uint64(bsize),
+		Used:     (blocks - bavail) * uint64(bsize),
+		Avail:    avail,
+		Usedpct:  int64(pct),
+		Reserved: reserved,
+		Headroom: int64(avail) - int64(reserved),
 	}
 }
 
@@ -171,6 +277,7 @@ func (r *Trunner) Run() error {
 
 func (r *Trunner) Init() {
 	r.Disk = make(map[string]cmn.SimpleKVs, 8)
+	r.Mountpaths = make(map[string]*mpathStats, 8)
 	r.UpdateCapacity()
 	r.Core = &targetCoreStats{}
 	r.Core.initStatsTracker()
@@ -178,55 +285,78 @@ func (r *Trunner) Init() {
 
 func (r *Trunner) log() (runlru bool) {
 	r.Lock()
-	if r.Core.logged {
+	r.StatsD = sinkBacklog(r.Core.Sink)
+	if r.Core.logged && !r.Emergency() {
 		r.Unlock()
 		return
 	}
 	lines := make([]string, 0, 16)
+	config := r.Getconf()
+	rec := r.slog.begin(&config.Periodic, "target")
 	// core stats
-	for _, v := range r.Core.Tracker {
-		if v.kind == statsKindLatency && v.associatedVal > 0 {
-			v.Value /= v.associatedVal
-		}
-	}
-	r.Core.Tracker[Uptime].Value = int64(time.Since(r.starttime) / time.Microsecond)
+	r.Core.Tracker[Uptime].Value = int64(time.Since(r.starttime))
 
-	b, err := jsoniter.Marshal(r.Core)
-
-	// reset all the latency stats only
-	for _, v := range r.Core.Tracker {
-		if v.kind == statsKindLatency {
-			v.Value = 0
-			v.associatedVal = 0
+	if out := r.slog.compactCore(&config.Periodic, r.Core.Tracker.outputs(r.Core.latencyUnit())); out != nil {
+		rec.Core = out
+		if b, err := jsoniter.Marshal(out); err == nil {
+			lines = append(lines, string(b))
 		}
 	}
-	if err == nil {
-		lines = append(lines, string(b))
-	}
+	r.Core.sendPercentiles()
+	r.Core.Tracker.resetLatencies()
 	// capacity
-	config := r.Getconf()
-	if time.Since(r.timeUpdatedCapacity) >= config.LRU.CapacityUpdTime {
+	// (in emergency mode - every time, so as to exit the mode as soon as possible;
+	// ditto when a filesystem has been resized)
+	if time.Since(r.timeUpdatedCapacity) >= config.LRU.CapacityUpdTime || r.Emergency() || r.fsResized() {
 		runlru = r.UpdateCapacity()
 		r.timeUpdatedCapacity = time.Now()
 		for mpath, fsCapacity := range r.Capacity {
 			b, err := jsoniter.Marshal(fsCapacity)
-			if err == nil {
+			if err == nil && r.slog.logLine(&config.Periodic, StatsLogCapacity, mpath, string(b)) {
 				lines = append(lines, mpath+": "+string(b))
+				if rec.Capacity == nil {
+					rec.Capacity = make(map[string]*fscapacity, len(r.Capacity))
+				}
+				rec.Capacity[mpath] = fsCapacity
 			}
 		}
 	}
 
+	// QoS
+	if config.QoS.Enabled {
+		r.QoS = r.Riostat.QoS()
+		for mpath, state := range r.QoS {
+			if !state.Throttled {
+				continue
+			}
+			b, err := jsoniter.Marshal(state)
+			if err == nil && r.slog.logLine(&config.Periodic, StatsLogQoS, mpath, string(b)) {
+				lines = append(lines, mpath+": qos "+string(b))
+				if rec.QoS == nil {
+					rec.QoS = make(map[string]ios.QoSState, 4)
+				}
+				rec.QoS[mpath] = state
+			}
+		}
+	} else {
+		r.QoS = nil
+	}
+
 	// disk
-	r.Riostat.RLock()
-	r.CPUidle = r.Riostat.CPUidle
-	for dev, iometrics := range r.Riostat.Disk {
+	cpuidle, disks := r.Riostat.Snapshot()
+	r.CPUidle = cpuidle
+	for dev, iometrics := range disks {
 		r.Disk[dev] = iometrics
 		if r.Riostat.IsZeroUtil(dev) {
 			continue // skip zeros
 		}
 		b, err := jsoniter.Marshal(r.Disk[dev])
-		if err == nil {
+		if err == nil && r.slog.logLine(&config.Periodic, StatsLogDisk, dev, string(b)) {
 			lines = append(lines, dev+": "+string(b))
+			if rec.Disk == nil {
+				rec.Disk = make(map[string]cmn.SimpleKVs, len(disks))
+			}
+			rec.Disk[dev] = iometrics
 		}
 
 		stats := make([]metric, len(iometrics))
@@ -235,19 +365,43 @@ func (r *Trunner) log() (runlru bool) {
 			stats[idx] = metric{statsd.Gauge, k, v}
 			idx++
 		}
-		r.Core.StatsdC.Send("iostat_"+dev, stats...)
+		r.Core.Sink.Send("iostat_"+dev, stats...)
+	}
+	r.Filesystem = r.Riostat.Filesystems()
+	for fsname, m := range r.Filesystem {
+		if m.UtilMax == 0 && m.IOPS == 0 {
+			continue // skip idle
+		}
+		b, err := jsoniter.Marshal(m)
+		if err == nil && r.slog.logLine(&config.Periodic, StatsLogFS, fsname, string(b)) {
+			lines = append(lines, fsname+": "+string(b))
+			if rec.Filesystem == nil {
+				rec.Filesystem = make(map[string]ios.FSMetrics, len(r.Filesystem))
+			}
+			rec.Filesystem[fsname] = m
+		}
+		gauges := []metric{
+			{Type: statsd.Gauge, Name: "util.max", Value: m.UtilMax},
+			{Type: statsd.Gauge, Name: "util.avg", Value: m.UtilAvg},
+			{Type: statsd.Gauge, Name: "await.max", Value: m.AwaitMax},
+			{Type: statsd.Gauge, Name: "await.avg", Value: m.AwaitAvg},
+			{Type: statsd.Gauge, Name: "rMB/s", Value: m.ReadMBps},
+			{Type: statsd.Gauge, Name: "wMB/s", Value: m.WriteMBps},
+			{Type: statsd.Gauge, Name: "iops", Value: m.IOPS},
+		}
+		r.Core.Sink.Send("iostat_fs_"+statsdName(fsname), gauges...)
 	}
-	r.Riostat.RUnlock()
 
-	lines = append(lines, fmt.Sprintf("CPU idle: %s%%", r.CPUidle))
+	if cpu := fmt.Sprintf("CPU idle: %s%%", r.CPUidle); r.slog.logLine(&config.Periodic, StatsLogCPU, "", cpu) {
+		lines = append(lines, cpu)
+		rec.CPUIdle = r.CPUidle
+	}
 
 	r.Core.logged = true
+	lines = r.slog.output(&config.Periodic, rec, lines)
 	r.Unlock()
 
-	// log
-	for _, ln := range lines {
-		glog.Infoln(ln)
-	}
+	r.slog.write(config, lines)
 	return
 }
 
@@ -270,6 +424,39 @@ func (r *Trunner) housekeep(runlru bool) {
 		go r.removeLogs(config.Log.MaxTotal)
 		r.timeCheckedLogSizes = time.Now()
 	}
+
+	// remove empty object directories
+	if config.Disk.PruneDirsTime > 0 && time.Since(r.timePrunedDirs) >= config.Disk.PruneDirsTime {
+		go t.PruneEmptyDirs()
+		r.timePrunedDirs = time.Now()
+	}
+
+	// compress the cold objects
+	if config.Compression.Enabled && time.Since(r.timeCompressed) >= config.Compression.RunTime {
+		go t.RunCompress()
+		r.timeCompressed = time.Now()
+	}
+
+	// remove the expired objects
+	if config.ObjTTL.RunTime > 0 && time.Since(r.timeExpired) >= config.ObjTTL.RunTime {
+		go t.RunExpire()
+		r.timeExpired = time.Now()
+	}
+
+	// account for the pinned objects
+	if time.Since(r.timeUpdatedPinned) >= config.LRU.CapacityUpdTime {
+		go t.UpdatePinned()
+		r.timeUpdatedPinned = time.Now()
+	}
+
+	// forget the expired idempotency keys
+	t.PruneIdempotencyKeys()
+
+	// save the object metadata cache for the target to restart warm
+	if config.MDCache.Enabled && config.MDCache.SnapshotTime > 0 && time.Since(r.timeSavedMD) >= config.MDCache.SnapshotTime {
+		go t.SaveMDCache()
+		r.timeSavedMD = time.Now()
+	}
 }
 
 func (r *Trunner) removeLogs(maxtotal uint64) {
@@ -338,30 +525,172 @@ func (r *Trunner) UpdateCapacity() (runlru bool) {
 	availableMountpaths, _ := fs.Mountpaths.Get()
 	capacities := make(map[string]*fscapacity, len(availableMountpaths))
 	config := r.Getconf()
+	maxpct := int64(0)
 	for mpath := range availableMountpaths {
-		statfs := &syscall.Statfs_t{}
-		if err := syscall.Statfs(mpath, statfs); err != nil {
-			glog.Errorf("Failed to statfs mp %q, err: %v", mpath, err)
-			continue
+		blocks, bavail, bsize, err := ios.GetFSStats(mpath)
+		if err != nil {
+			continue // logged
+		}
+		fsCap := newFSCapacity(mpath, blocks, bavail, bsize, config)
+		fsCap.Pinned = r.pinned[mpath]
+		if prev, ok := r.Capacity[mpath]; ok && prev.Total != fsCap.Total {
+			if fsCap.Total < prev.Total {
+				glog.Errorf("ALERT: %s (%s) has shrunk from %s to %s, used %d%%", mpath, mpathFS(mpath),
+					cmn.B2S(int64(prev.Total), 1), cmn.B2S(int64(fsCap.Total), 1), fsCap.Usedpct)
+			} else {
+				glog.Infof("%s (%s) has grown from %s to %s, used %d%%", mpath, mpathFS(mpath),
+					cmn.B2S(int64(prev.Total), 1), cmn.B2S(int64(fsCap.Total), 1), fsCap.Usedpct)
+			}
 		}
-		fsCap := newFSCapacity(statfs)
 		capacities[mpath] = fsCap
 		if fsCap.Usedpct >= config.LRU.HighWM {
 			runlru = true
 		}
+		if fsCap.Usedpct > maxpct {
+			maxpct = fsCap.Usedpct
+		}
 	}
 
 	r.Capacity = capacities
+	r.updEmergency(maxpct, config)
 	return
 }
 
-func (r *Trunner) doAdd(nv NamedVal64) {
+// fsResized returns true if any mountpath's filesystem has been grown or shrunk online since
+// the last capacity update - a statfs per mountpath, cheap enough to run every stats_time
+// and get the capacity (and, therefore, LRU and the emergency mode) updated without waiting
+// for capacity_upd_time
+func (r *Trunner) fsResized() bool {
+	for mpath, fsCap := range r.Capacity {
+		blocks, _, bsize, err := ios.GetFSStats(mpath)
+		if err != nil {
+			continue
+		}
+		if blocks*uint64(bsize) != fsCap.Total {
+			return true
+		}
+	}
+	return false
+}
+
+// statsdName makes a StatsD metric name out of a device or path, e.g. /dev/sda1 => dev_sda1
+func statsdName(s string) string {
+	return strings.Trim(strings.NewReplacer("/", "_", ".", "_", ":", "_").Replace(s), "_")
+}
+
+func mpathFS(mpath string) string {
+	if mpathInfo, _ := fs.Mountpaths.Path2MpathInfo(mpath); mpathInfo != nil {
+		return mpathInfo.FileSystem
+	}
+	return "unknown filesystem"
+}
+
+// Emergency returns true if the target is in the capacity emergency mode
+func (r *Trunner) Emergency() bool {
+	return atomic.LoadInt32(&r.emergency) != 0
+}
+
+// updEmergency enters the capacity emergency mode when the most used mountpath reaches
+// disk_config.critical_wm, and exits the mode once all mountpaths are below lru_config.highwm
+func (r *Trunner) updEmergency(maxpct int64, config *cmn.Config) {
+	var (
+		cwm, hwm = config.Disk.CriticalWM, config.LRU.HighWM
+		on       = r.Emergency()
+	)
+	switch {
+	case !on && cwm > 0 && maxpct >= cwm:
+		atomic.StoreInt32(&r.emergency, 1)
+		glog.Errorf("ALERT: used capacity %d%% >= critical %d%%: entering emergency mode - "+
+			"refusing writes until below %d%%", maxpct, cwm, hwm)
+	case on && (cwm == 0 || maxpct < hwm):
+		atomic.StoreInt32(&r.emergency, 0)
+		glog.Infof("Used capacity %d%% < %d%%: exiting emergency mode", maxpct, hwm)
+	}
+}
+
+func (r *Trunner) doAdd(nv NamedVal64, bucket string) {
 	r.Lock()
 	s := r.Core
+	s.bucket = bucket
 	s.doAdd(nv.Name, nv.Val)
+	s.bucket = ""
+	if bucket != "" {
+		s.buckets.add(bucket, nv.Name, nv.Val)
+	}
+	r.track(nv.Name, nv.Val)
+	r.Unlock()
+}
+
+// History returns the target's stats history at the given resolution (one of the
+// HistoryResolutions): the samples taken within the given duration (all if zero) and
+// not later than until ago, limited to the given stats (all if none)
+func (r *Trunner) History(names []string, res, within, until time.Duration) *cmn.StatsHistory {
+	return r.history(r.Core.Tracker, r.Core.latencyUnit(), names, res, within, until)
+}
+
+// AddReplication accounts for the replication traffic sent to the destination
+func (r *Trunner) AddReplication(dst string, d *cmn.ReplDestStats) {
+	r.Lock()
+	r.Core.repl.Add(dst, d)
+	r.Core.doAdd(ReplTxCount, d.Requests)
+	r.Core.doAdd(ReplTxSize, d.Bytes)
+	r.track(ReplTxCount, d.Requests)
+	r.track(ReplTxSize, d.Bytes)
+	r.Unlock()
+}
+
+// ReplStats returns the replication traffic per destination since the target's start
+func (r *Trunner) ReplStats() cmn.ReplStats {
+	r.RLock()
+	defer r.RUnlock()
+	out := make(cmn.ReplStats, len(r.Core.repl))
+	for dst, d := range r.Core.repl {
+		out.Add(dst, d)
+	}
+	return out
+}
+
+// AddMpath accounts for the LRU evictions and atime flushes on the mountpath - in addition
+// to the target's totals - so that a disk that carries disproportionate load stands out
+func (r *Trunner) AddMpath(mpath string, nvs ...NamedVal64) {
+	r.Lock()
+	ms, ok := r.Mountpaths[mpath]
+	if !ok {
+		ms = &mpathStats{}
+		r.Mountpaths[mpath] = ms
+	}
+	for _, nv := range nvs {
+		switch nv.Name {
+		case LruEvictCount:
+			ms.LruEvictCount += nv.Val
+		case LruEvictSize:
+			ms.LruEvictSize += nv.Val
+		case AtimeFlushCount:
+			ms.AtimeFlushCount += nv.Val
+		default:
+			cmn.Assert(false, "Invalid per-mountpath stats name "+nv.Name)
+		}
+		r.Core.doAdd(nv.Name, nv.Val)
+		r.track(nv.Name, nv.Val)
+	}
+	r.Unlock()
+}
+
+// SetPinned sets the sizes of the pinned objects per mountpath, reported with the capacity
+func (r *Trunner) SetPinned(sizes map[string]int64) {
+	r.Lock()
+	r.pinned = sizes
+	for mpath, fsCap := range r.Capacity {
+		fsCap.Pinned = sizes[mpath]
+	}
 	r.Unlock()
 }
 
+// AddAtimeFlush implements atime.FlushTracker
+func (r *Trunner) AddAtimeFlush(mpath string, n int64) {
+	r.AddMpath(mpath, NamedVal64{Name: AtimeFlushCount, Val: n})
+}
+
 //
 // xaction
 //
@@ -379,10 +708,11 @@ func (r *Trunner) GetPrefetchStats(allXactionDetails []XactionDetails) []byte {
 	return jsonBytes
 }
 
-func (r *Trunner) GetRebalanceStats(allXactionDetails []XactionDetails) []byte {
+func (r *Trunner) GetRebalanceStats(allXactionDetails []XactionDetails, remaining []RebalanceSizeClass) []byte {
 	r.RLock()
 	rebalanceXactionStats := RebalanceTargetStats{
 		Xactions:     allXactionDetails,
+		Remaining:    remaining,
 		NumRecvBytes: r.Core.Tracker[RxSize].Value,
 		NumRecvFiles: r.Core.Tracker[RxCount].Value,
 		NumSentBytes: r.Core.Tracker[TxSize].Value,
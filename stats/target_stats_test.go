@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2018, NVIDIA CORPORATION. All rights reserved.
+ */
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/dfcpub/cmn"
+	"github.com/NVIDIA/dfcpub/fs"
+)
+
+func TestReplStats(t *testing.T) {
+	r := &Trunner{Core: &targetCoreStats{}}
+	r.Setconf(&cmn.Config{})
+	r.Core.initStatsTracker()
+	r.Core.Sink = NopSink{}
+
+	r.AddReplication("http://east", &cmn.ReplDestStats{Bytes: 100, Requests: 1})
+	r.AddReplication("http://east", &cmn.ReplDestStats{Bytes: 250, Requests: 2, Retries: 1, Errors: 1})
+	r.AddReplication("http://west", &cmn.ReplDestStats{Bytes: 10, Requests: 1})
+
+	stats := r.ReplStats()
+	if d := stats["http://east"]; d == nil || *d != (cmn.ReplDestStats{Bytes: 350, Requests: 3, Retries: 1, Errors: 1}) {
+		t.Errorf("unexpected east stats: %+v", d)
+	}
+	if d := stats["http://west"]; d == nil || d.Bytes != 10 || d.Requests != 1 {
+		t.Errorf("unexpected west stats: %+v", d)
+	}
+	if n, size := r.Core.Tracker[ReplTxCount].Value, r.Core.Tracker[ReplTxSize].Value; n != 4 || size != 360 {
+		t.Errorf("expected totals 4 requests, 360 bytes; got %d, %d", n, size)
+	}
+
+	// the returned stats are a copy
+	stats["http://west"].Bytes = 0
+	if d := r.ReplStats()["http://west"]; d.Bytes != 10 {
+		t.Errorf("expected west stats unchanged, got %+v", d)
+	}
+}
+
+func TestKeepaliveMinMax(t *testing.T) {
+	r := &Trunner{Core: &targetCoreStats{}}
+	r.Setconf(&cmn.Config{})
+	r.Core.initStatsTracker()
+	r.Core.Sink = NopSink{}
+
+	for _, ms := range []int64{20, 5, 50} {
+		lat := ms * int64(time.Millisecond)
+		r.Core.doAdd(KeepAliveLatency, lat)
+		r.Core.doAdd(KeepAliveMinLatency, lat)
+		r.Core.doAdd(KeepAliveMaxLatency, lat)
+	}
+	tracker := r.Core.Tracker
+	if avg, min, max := tracker[KeepAliveLatency].output(time.Millisecond), tracker[KeepAliveMinLatency].output(time.Millisecond),
+		tracker[KeepAliveMaxLatency].output(time.Millisecond); avg != 25 || min != 5 || max != 50 {
+		t.Errorf("expected avg 25ms, min 5ms, max 50ms; got %d, %d, %d", avg, min, max)
+	}
+
+	// next stats period
+	tracker.resetLatencies()
+	r.Core.doAdd(KeepAliveMaxLatency, int64(10*time.Millisecond))
+	r.Core.doAdd(KeepAliveMinLatency, int64(10*time.Millisecond))
+	if min, max := tracker[KeepAliveMinLatency].output(time.Millisecond), tracker[KeepAliveMaxLatency].output(time.Millisecond); min != 10 || max != 10 {
+		t.Errorf("expected min and max of the new period only (10ms), got %d, %d", min, max)
+	}
+}
+
+func TestMpathStats(t *testing.T) {
+	r := &Trunner{Core: &targetCoreStats{}, Mountpaths: make(map[string]*mpathStats)}
+	r.Setconf(&cmn.Config{})
+	r.Core.initStatsTracker()
+	r.Core.Sink = NopSink{}
+
+	r.AddMpath("/mp1", NamedVal64{Name: LruEvictCount, Val: 2}, NamedVal64{Name: LruEvictSize, Val: 200})
+	r.AddMpath("/mp1", NamedVal64{Name: LruEvictCount, Val: 1}, NamedVal64{Name: LruEvictSize, Val: 50})
+	r.AddMpath("/mp2", NamedVal64{Name: LruEvictCount, Val: 1}, NamedVal64{Name: LruEvictSize, Val: 10})
+	r.AddAtimeFlush("/mp2", 7)
+
+	if ms := r.Mountpaths["/mp1"]; ms == nil || *ms != (mpathStats{LruEvictCount: 3, LruEvictSize: 250}) {
+		t.Errorf("unexpected /mp1 stats: %+v", ms)
+	}
+	if ms := r.Mountpaths["/mp2"]; ms == nil || *ms != (mpathStats{LruEvictCount: 1, LruEvictSize: 10, AtimeFlushCount: 7}) {
+		t.Errorf("unexpected /mp2 stats: %+v", ms)
+	}
+	tracker := r.Core.Tracker
+	if n, size, flushed := tracker[LruEvictCount].Value, tracker[LruEvictSize].Value, tracker[AtimeFlushCount].Value; n != 4 || size != 260 || flushed != 7 {
+		t.Errorf("expected totals 4 evicted, 260 bytes, 7 flushed; got %d, %d, %d", n, size, flushed)
+	}
+}
+
+func TestEmergencyMode(t *testing.T) {
+	var (
+		r      = &Trunner{}
+		config = &cmn.Config{}
+	)
+	config.LRU.HighWM, config.Disk.CriticalWM = 90, 95
+
+	for _, step := range []struct {
+		maxpct    int64
+		emergency bool
+	}{
+		{80, false},
+		{94, false},
+		{95, true}, // critical
+		{92, true}, // below critical but above HWM: still in
+		{89, false},
+		{93, false}, // entering requires the critical watermark again
+	} {
+		r.updEmergency(step.maxpct, config)
+		if r.Emergency() != step.emergency {
+			t.Fatalf("used %d%%: expected emergency %t", step.maxpct, step.emergency)
+		}
+	}
+
+	// disabled: exits right away
+	r.updEmergency(99, config)
+	config.Disk.CriticalWM = 0
+	if r.updEmergency(99, config); r.Emergency() {
+		t.Fatal("expected emergency mode off when critical_wm is 0")
+	}
+}
+
+func TestFSResized(t *testing.T) {
+	mpath, err := ioutil.TempDir("", "resized")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mpath)
+	q := fs.Mountpaths
+	defer func() { fs.Mountpaths = q }()
+	fs.Mountpaths = fs.NewMountedFS("local", "cloud")
+	fs.Mountpaths.DisableFsIDCheck()
+	if err := fs.Mountpaths.Add(mpath); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Trunner{}
+	r.Setconf(&cmn.Config{})
+	r.UpdateCapacity()
+	if fsCap := r.Capacity[mpath]; fsCap == nil || fsCap.Total == 0 || fsCap.Total < fsCap.Used+fsCap.Avail {
+		t.Fatalf("unexpected capacity %+v", fsCap)
+	}
+	if r.fsResized() {
+		t.Fatal("expected no resize")
+	}
+	r.Capacity[mpath].Total++ // as if the filesystem has shrunk by a byte
+	if !r.fsResized() {
+		t.Fatal("expected the resize detected")
+	}
+	if r.UpdateCapacity(); r.fsResized() {
+		t.Error("expected the capacity updated")
+	}
+}
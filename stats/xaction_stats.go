@@ -26,6 +26,13 @@ type (
 		NumSentBytes int64            `json:"numSentBytes"`
 		NumRecvFiles int64            `json:"numRecvFiles"`
 		NumRecvBytes int64            `json:"numRecvBytes"`
+		// objects yet to be sent by the running rebalance, per size class (rebalance_conf.order)
+		Remaining []RebalanceSizeClass `json:"remaining,omitempty"`
+	}
+	RebalanceSizeClass struct {
+		Class   string `json:"class"` // small, medium, or large
+		Objects int64  `json:"objects"`
+		Bytes   int64  `json:"bytes"`
 	}
 	RebalanceStats struct {
 		Kind        string                          `json:"kind"`
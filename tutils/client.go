@@ -340,6 +340,12 @@ func ListBucket(proxyURL, bucket string, msg *cmn.GetMsg, objectCountLimit int)
 		}
 
 		reslist.Entries = append(reslist.Entries, page.Entries...)
+		for id, errstr := range page.Errors {
+			if reslist.Errors == nil {
+				reslist.Errors = make(map[string]string)
+			}
+			reslist.Errors[id] = errstr
+		}
 		if page.PageMarker == "" {
 			break
 		}